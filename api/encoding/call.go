@@ -140,3 +140,13 @@ func (c *Call) RoutingDelegate() string {
 	}
 	return c.ic.req.RoutingDelegate
 }
+
+// PeerIdentity returns the identity of the remote peer as verified by the
+// transport, for example from a TLS client certificate. Returns nil if the
+// transport did not authenticate the peer.
+func (c *Call) PeerIdentity() *transport.PeerIdentity {
+	if c == nil {
+		return nil
+	}
+	return c.ic.peerIdentity
+}
@@ -20,6 +20,8 @@
 
 package encoding
 
+import "time"
+
 // CallOption defines options that may be passed in at call sites to other
 // services.
 //
@@ -55,3 +57,21 @@ func WithRoutingKey(rk string) CallOption {
 func WithRoutingDelegate(rd string) CallOption {
 	return CallOption{func(o *OutboundCall) { o.routingDelegate = &rd }}
 }
+
+// WithDeliverAt asks oneway outbounds to deliver the request no earlier than
+// the given time.
+func WithDeliverAt(t time.Time) CallOption {
+	return CallOption{func(o *OutboundCall) {
+		o.deliverAt = &t
+		o.delay = nil
+	}}
+}
+
+// WithDelay asks oneway outbounds to deliver the request no earlier than the
+// given duration after the call is made.
+func WithDelay(d time.Duration) CallOption {
+	return CallOption{func(o *OutboundCall) {
+		o.delay = &d
+		o.deliverAt = nil
+	}}
+}
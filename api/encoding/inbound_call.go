@@ -32,8 +32,9 @@ import (
 // incoming request on the Context and send response headers through
 // WriteResponseHeader.
 type InboundCall struct {
-	resHeaders []keyValuePair
-	req        *transport.Request
+	resHeaders   []keyValuePair
+	req          *transport.Request
+	peerIdentity *transport.PeerIdentity
 }
 
 type inboundCallKey struct{} // context key for *InboundCall
@@ -43,6 +44,7 @@ type inboundCallKey struct{} // context key for *InboundCall
 // A request context is returned and must be used in place of the original.
 func NewInboundCall(ctx context.Context) (context.Context, *InboundCall) {
 	call := &InboundCall{}
+	call.peerIdentity, _ = transport.PeerIdentityFromContext(ctx)
 	return context.WithValue(ctx, inboundCallKey{}, call), call
 }
 
@@ -22,6 +22,8 @@ package encoding
 
 import (
 	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"sort"
 	"testing"
 
@@ -67,6 +69,21 @@ func TestInboundCallReadFromRequest(t *testing.T) {
 	headerNames := call.HeaderNames()
 	sort.Strings(headerNames)
 	assert.Equal(t, []string{"foo", "hello", "success"}, headerNames)
+	assert.Nil(t, call.PeerIdentity())
+}
+
+func TestInboundCallPeerIdentity(t *testing.T) {
+	id := &transport.PeerIdentity{
+		Certificates: []*x509.Certificate{
+			{Subject: pkix.Name{CommonName: "caller"}},
+		},
+	}
+	ctx, inboundCall := NewInboundCall(transport.WithPeerIdentity(context.Background(), id))
+	require.NoError(t, inboundCall.ReadFromRequest(&transport.Request{Caller: "caller"}))
+
+	call := CallFromContext(ctx)
+	assert.True(t, id == call.PeerIdentity(), "peer identity should match")
+	assert.Equal(t, "caller", call.PeerIdentity().Name())
 }
 
 func TestInboundCallWriteToResponse(t *testing.T) {
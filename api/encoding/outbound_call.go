@@ -22,6 +22,7 @@ package encoding
 
 import (
 	"context"
+	"time"
 
 	"go.uber.org/yarpc/api/transport"
 )
@@ -38,6 +39,10 @@ type OutboundCall struct {
 	routingKey      *string
 	routingDelegate *string
 
+	// delivery time of oneway requests, if delayed
+	deliverAt *time.Time
+	delay     *time.Duration
+
 	// If non-nil, response headers should be written here.
 	responseHeaders *map[string]string
 }
@@ -70,8 +75,15 @@ func (c *OutboundCall) WriteToRequest(ctx context.Context, req *transport.Reques
 		req.RoutingDelegate = *c.routingDelegate
 	}
 
-	// NB(abg): context and error are unused for now but we want to leave room
-	// for CallOptions which can fail or modify the context.
+	switch {
+	case c.deliverAt != nil:
+		ctx = transport.WithDeliverAt(ctx, *c.deliverAt)
+	case c.delay != nil:
+		ctx = transport.WithDeliverAt(ctx, time.Now().Add(*c.delay))
+	}
+
+	// NB(abg): error is unused for now but we want to leave room for
+	// CallOptions which can fail.
 	return ctx, nil
 }
 
@@ -23,6 +23,7 @@ package encoding
 import (
 	"context"
 	"testing"
+	"time"
 
 	"go.uber.org/yarpc/api/transport"
 
@@ -133,6 +134,64 @@ func TestOutboundCallWriteToRequest(t *testing.T) {
 	}
 }
 
+func TestOutboundCallDeliverAt(t *testing.T) {
+	deliverAt := time.Unix(1500000000, 0)
+
+	tests := []struct {
+		desc        string
+		giveOptions []CallOption
+		wantMin     time.Time
+		wantMax     time.Time
+		wantNone    bool
+	}{
+		{
+			desc:     "no options",
+			wantNone: true,
+		},
+		{
+			desc:        "deliver at",
+			giveOptions: []CallOption{WithDeliverAt(deliverAt)},
+			wantMin:     deliverAt,
+			wantMax:     deliverAt,
+		},
+		{
+			desc:        "delay",
+			giveOptions: []CallOption{WithDelay(time.Minute)},
+			wantMin:     time.Now().Add(time.Minute),
+			wantMax:     time.Now().Add(2 * time.Minute),
+		},
+		{
+			desc:        "delay overrides deliver at",
+			giveOptions: []CallOption{WithDeliverAt(deliverAt), WithDelay(time.Minute)},
+			wantMin:     time.Now().Add(time.Minute),
+			wantMax:     time.Now().Add(2 * time.Minute),
+		},
+		{
+			desc:        "deliver at overrides delay",
+			giveOptions: []CallOption{WithDelay(time.Minute), WithDeliverAt(deliverAt)},
+			wantMin:     deliverAt,
+			wantMax:     deliverAt,
+		},
+	}
+
+	for _, tt := range tests {
+		call := NewOutboundCall(tt.giveOptions...)
+
+		ctx, err := call.WriteToRequest(context.Background(), &transport.Request{})
+		require.NoError(t, err, tt.desc)
+
+		got, ok := transport.DeliverAtFromContext(ctx)
+		if tt.wantNone {
+			assert.False(t, ok, "%v: expected no delivery time", tt.desc)
+			continue
+		}
+		if assert.True(t, ok, "%v: expected a delivery time", tt.desc) {
+			assert.False(t, got.Before(tt.wantMin), "%v: delivery time %v is too early", tt.desc, got)
+			assert.False(t, got.After(tt.wantMax), "%v: delivery time %v is too late", tt.desc, got)
+		}
+	}
+}
+
 func TestOutboundCallReadFromResponse(t *testing.T) {
 	var headers map[string]string
 	call := NewOutboundCall(ResponseHeaders(&headers))
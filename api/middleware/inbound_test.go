@@ -80,3 +80,19 @@ func TestOnewayNopInboundMiddleware(t *testing.T) {
 
 	assert.Equal(t, err, wrappedH.HandleOneway(ctx, req))
 }
+
+type fakeStream struct{ transport.Stream }
+
+func TestStreamNopInboundMiddleware(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	h := transporttest.NewMockStreamHandler(mockCtrl)
+	wrappedH := middleware.ApplyStreamInbound(h, middleware.NopStreamInbound)
+
+	s := &fakeStream{}
+	err := errors.New("great sadness")
+	h.EXPECT().HandleStream(s).Return(err)
+
+	assert.Equal(t, err, wrappedH.HandleStream(s))
+}
@@ -86,3 +86,30 @@ func TestOnewayNopOutboundMiddleware(t *testing.T) {
 		assert.Equal(t, nil, got)
 	}
 }
+
+type fakeClientStream struct{ transport.ClientStream }
+
+func TestStreamNopOutboundMiddleware(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	o := transporttest.NewMockStreamOutbound(mockCtrl)
+	wrappedO := middleware.ApplyStreamOutbound(o, middleware.NopStreamOutbound)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	req := &transport.Request{
+		Caller:    "somecaller",
+		Service:   "someservice",
+		Encoding:  raw.Encoding,
+		Procedure: "hello",
+	}
+
+	stream := &fakeClientStream{}
+	o.EXPECT().CallStream(ctx, req).Return(stream, nil)
+
+	got, err := wrappedO.CallStream(ctx, req)
+	if assert.NoError(t, err) {
+		assert.Equal(t, stream, got)
+	}
+}
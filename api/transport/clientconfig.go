@@ -45,3 +45,17 @@ type ClientConfig interface {
 	GetUnaryOutbound() UnaryOutbound
 	GetOnewayOutbound() OnewayOutbound
 }
+
+// StreamClientConfig is a ClientConfig which is also able to provide a
+// StreamOutbound. ClientConfigs provided by a Dispatcher implement this
+// interface.
+type StreamClientConfig interface {
+	ClientConfig
+
+	// Returns a stream outbound to open streams through or panics if there
+	// is no stream outbound for this service.
+	//
+	// MAY be called multiple times for a request. The returned outbound MUST
+	// have already been started.
+	GetStreamOutbound() StreamOutbound
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package transport
+
+import "io"
+
+// Compressor compresses and decompresses the bodies of requests and
+// responses.
+//
+// Transports that support compression negotiate it per request using the
+// name of the Compressor, which is sent in the Content-Encoding and
+// Accept-Encoding headers over HTTP, in the grpc-encoding header over gRPC,
+// and in reserved application headers over TChannel. Both ends of an RPC
+// MUST use the same name for the same compression format.
+type Compressor interface {
+	// Name of the compression format, for example, "gzip".
+	Name() string
+
+	// Compress returns a writer that compresses everything written to it
+	// into w. The compressed data is not complete until the returned writer
+	// is closed. Closing it MUST NOT close w.
+	Compress(w io.Writer) (io.WriteCloser, error)
+
+	// Decompress returns a reader that decompresses the data read from r.
+	// Closing it MUST NOT close r.
+	Decompress(r io.Reader) (io.ReadCloser, error)
+}
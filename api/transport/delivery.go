@@ -0,0 +1,45 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package transport
+
+import (
+	"context"
+	"time"
+)
+
+type deliverAtKey struct{} // context key for time.Time
+
+// WithDeliverAt returns a copy of the context that asks oneway outbounds to
+// deliver the request no earlier than the given time.
+//
+// Oneway outbounds which support delayed delivery should check
+// DeliverAtFromContext and hold the request until it is due. Other
+// outbounds deliver the request immediately.
+func WithDeliverAt(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, deliverAtKey{}, t)
+}
+
+// DeliverAtFromContext returns the time at which the request should be
+// delivered, if the caller asked for delayed delivery.
+func DeliverAtFromContext(ctx context.Context) (time.Time, bool) {
+	t, ok := ctx.Value(deliverAtKey{}).(time.Time)
+	return t, ok && !t.IsZero()
+}
@@ -20,7 +20,10 @@
 
 package transport
 
-import "go.uber.org/yarpc/internal/errors"
+import (
+	"go.uber.org/yarpc/internal/errors"
+	"go.uber.org/yarpc/yarpcerrors"
+)
 
 // InboundBadRequestError builds an error which indicates that an inbound
 // cannot process a request because it is a bad request.
@@ -31,23 +34,42 @@ func InboundBadRequestError(err error) error {
 }
 
 // IsBadRequestError returns true if the request could not be processed
-// because it was invalid.
+// because it was invalid. This includes yarpcerrors.Status errors with
+// CodeInvalidArgument.
 func IsBadRequestError(err error) bool {
-	_, ok := err.(errors.BadRequestError)
-	return ok
+	if _, ok := err.(errors.BadRequestError); ok {
+		return true
+	}
+	return statusCode(err) == yarpcerrors.CodeInvalidArgument
 }
 
 // IsUnexpectedError returns true if the server panicked or failed to process
-// the request with an unhandled error.
+// the request with an unhandled error. This includes yarpcerrors.Status
+// errors with CodeInternal or CodeUnknown.
 func IsUnexpectedError(err error) bool {
-	_, ok := err.(errors.UnexpectedError)
-	return ok
+	if _, ok := err.(errors.UnexpectedError); ok {
+		return true
+	}
+	code := statusCode(err)
+	return code == yarpcerrors.CodeInternal || code == yarpcerrors.CodeUnknown
 }
 
-// IsTimeoutError return true if the given error is a TimeoutError.
+// IsTimeoutError return true if the given error is a TimeoutError. This
+// includes yarpcerrors.Status errors with CodeDeadlineExceeded.
 func IsTimeoutError(err error) bool {
-	_, ok := err.(errors.TimeoutError)
-	return ok
+	if _, ok := err.(errors.TimeoutError); ok {
+		return true
+	}
+	return statusCode(err) == yarpcerrors.CodeDeadlineExceeded
+}
+
+// statusCode returns the code of the given error if it is a
+// yarpcerrors.Status or CodeOK otherwise.
+func statusCode(err error) yarpcerrors.Code {
+	if s, ok := err.(*yarpcerrors.Status); ok {
+		return s.Code()
+	}
+	return yarpcerrors.CodeOK
 }
 
 // UnrecognizedProcedureError returns an error for the given request,
@@ -24,6 +24,8 @@ import (
 	"errors"
 	"testing"
 
+	"go.uber.org/yarpc/yarpcerrors"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -40,3 +42,30 @@ func TestUnrecognizedProcedureError(t *testing.T) {
 	assert.False(t, IsUnrecognizedProcedureError(errors.New("derp")))
 	assert.Equal(t, `unrecognized procedure "nyuck" for service "curly"`, err.Error())
 }
+
+func TestStatusErrors(t *testing.T) {
+	tests := []struct {
+		code           yarpcerrors.Code
+		wantBadRequest bool
+		wantUnexpected bool
+		wantTimeout    bool
+	}{
+		{code: yarpcerrors.CodeInvalidArgument, wantBadRequest: true},
+		{code: yarpcerrors.CodeInternal, wantUnexpected: true},
+		{code: yarpcerrors.CodeUnknown, wantUnexpected: true},
+		{code: yarpcerrors.CodeDeadlineExceeded, wantTimeout: true},
+		{code: yarpcerrors.CodeNotFound},
+		{code: yarpcerrors.CodeUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			err := yarpcerrors.Newf(tt.code, "great sadness")
+			assert.Equal(t, tt.wantBadRequest, IsBadRequestError(err), "IsBadRequestError")
+			assert.Equal(t, tt.wantUnexpected, IsUnexpectedError(err), "IsUnexpectedError")
+			assert.Equal(t, tt.wantTimeout, IsTimeoutError(err), "IsTimeoutError")
+		})
+	}
+
+	assert.False(t, IsUnexpectedError(errors.New("derp")))
+}
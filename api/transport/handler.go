@@ -40,6 +40,9 @@ const (
 	Unary Type = iota + 1
 	// Oneway types are fire and forget RPCs (no response)
 	Oneway
+	// Streaming types are long-lived RPCs which exchange any number of
+	// messages in both directions
+	Streaming
 )
 
 // HandlerSpec holds a handler and its Type
@@ -49,6 +52,7 @@ type HandlerSpec struct {
 
 	unaryHandler  UnaryHandler
 	onewayHandler OnewayHandler
+	streamHandler StreamHandler
 }
 
 // MarshalLogObject implements zap.ObjectMarshaler.
@@ -66,6 +70,9 @@ func (h HandlerSpec) Unary() UnaryHandler { return h.unaryHandler }
 // Oneway returns the Oneway Handler or nil
 func (h HandlerSpec) Oneway() OnewayHandler { return h.onewayHandler }
 
+// Stream returns the Stream Handler or nil
+func (h HandlerSpec) Stream() StreamHandler { return h.streamHandler }
+
 // NewUnaryHandlerSpec returns an new HandlerSpec with a UnaryHandler
 func NewUnaryHandlerSpec(handler UnaryHandler) HandlerSpec {
 	return HandlerSpec{t: Unary, unaryHandler: handler}
@@ -76,6 +83,11 @@ func NewOnewayHandlerSpec(handler OnewayHandler) HandlerSpec {
 	return HandlerSpec{t: Oneway, onewayHandler: handler}
 }
 
+// NewStreamHandlerSpec returns an new HandlerSpec with a StreamHandler
+func NewStreamHandlerSpec(handler StreamHandler) HandlerSpec {
+	return HandlerSpec{t: Streaming, streamHandler: handler}
+}
+
 // UnaryHandler handles a single, transport-level, unary request.
 type UnaryHandler interface {
 	// Handle the given request, writing the response to the given
@@ -140,3 +152,19 @@ func DispatchOnewayHandler(
 
 	return h.HandleOneway(ctx, req)
 }
+
+// DispatchStreamHandler calls the stream Handler, recovering panics and
+// returning them as errors.
+func DispatchStreamHandler(
+	h StreamHandler,
+	stream Stream,
+) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Stream handler panicked: %v\n%s", r, debug.Stack())
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return h.HandleStream(stream)
+}
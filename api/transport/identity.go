@@ -0,0 +1,73 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package transport
+
+import (
+	"context"
+	"crypto/x509"
+)
+
+// PeerIdentity is the identity of the remote end of an inbound request as
+// established by the transport, for example from a TLS client certificate
+// that was verified during the handshake.
+type PeerIdentity struct {
+	// Certificates is the verified certificate chain presented by the
+	// peer. The first element is the peer's own certificate and the rest
+	// lead up to a trusted root.
+	Certificates []*x509.Certificate
+}
+
+// Certificate returns the peer's own certificate or nil if the peer did not
+// present one.
+func (p *PeerIdentity) Certificate() *x509.Certificate {
+	if p == nil || len(p.Certificates) == 0 {
+		return nil
+	}
+	return p.Certificates[0]
+}
+
+// Name returns the common name of the peer's certificate or an empty string
+// if the peer did not present a certificate.
+func (p *PeerIdentity) Name() string {
+	if cert := p.Certificate(); cert != nil {
+		return cert.Subject.CommonName
+	}
+	return ""
+}
+
+type peerIdentityKey struct{} // context key for *PeerIdentity
+
+// WithPeerIdentity returns a copy of the context that carries the given
+// PeerIdentity.
+//
+// Inbound transport implementations should use this to attach the identity
+// of an authenticated peer to the request context before dispatching to the
+// handler.
+func WithPeerIdentity(ctx context.Context, id *PeerIdentity) context.Context {
+	return context.WithValue(ctx, peerIdentityKey{}, id)
+}
+
+// PeerIdentityFromContext returns the PeerIdentity attached to the context
+// by the inbound transport, if any.
+func PeerIdentityFromContext(ctx context.Context) (*PeerIdentity, bool) {
+	id, ok := ctx.Value(peerIdentityKey{}).(*PeerIdentity)
+	return id, ok && id != nil
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package transport
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeerIdentityFromContext(t *testing.T) {
+	_, ok := PeerIdentityFromContext(context.Background())
+	assert.False(t, ok, "expected no identity on an empty context")
+
+	_, ok = PeerIdentityFromContext(WithPeerIdentity(context.Background(), nil))
+	assert.False(t, ok, "expected no identity for a nil PeerIdentity")
+
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "myservice"}}
+	ctx := WithPeerIdentity(context.Background(), &PeerIdentity{
+		Certificates: []*x509.Certificate{cert},
+	})
+	id, ok := PeerIdentityFromContext(ctx)
+	if assert.True(t, ok, "expected an identity") {
+		assert.True(t, cert == id.Certificate(), "certificate should match")
+		assert.Equal(t, "myservice", id.Name())
+	}
+}
+
+func TestPeerIdentityEmpty(t *testing.T) {
+	var nilID *PeerIdentity
+	assert.Nil(t, nilID.Certificate())
+	assert.Equal(t, "", nilID.Name())
+
+	assert.Nil(t, (&PeerIdentity{}).Certificate())
+	assert.Equal(t, "", (&PeerIdentity{}).Name())
+}
@@ -36,3 +36,18 @@ type Inbound interface {
 	// An inbound may submit zero or more transports.
 	Transports() []Transport
 }
+
+// DrainableInbound is an Inbound that can stop accepting new requests before
+// it is stopped. When a Dispatcher that is configured to drain is stopped, it
+// drains these inbounds and waits for the requests already in flight to
+// finish before it stops them.
+type DrainableInbound interface {
+	Inbound
+
+	// Drain makes the inbound advertise that it is no longer ready to serve
+	// requests and stop accepting new requests where the transport allows
+	// it. Requests that were already accepted MUST still be handled.
+	//
+	// Drain MAY be called multiple times and is followed by a call to Stop.
+	Drain()
+}
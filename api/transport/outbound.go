@@ -32,7 +32,8 @@ type Outbound interface {
 	// Though most outbounds only use a single transport, composite outbounds
 	// may use multiple transport protocols, particularly for shadowing traffic
 	// across multiple transport protocols during a transport protocol
-	// migration.
+	// migration, like the outbounds of go.uber.org/yarpc/x/shadow and
+	// go.uber.org/yarpc/x/split.
 	Transports() []Transport
 }
 
@@ -64,11 +65,28 @@ type OnewayOutbound interface {
 	CallOneway(ctx context.Context, request *Request) (Ack, error)
 }
 
+// StreamOutbound is a transport that knows how to open streams for procedure
+// calls.
+type StreamOutbound interface {
+	Outbound
+
+	// CallStream opens a stream for the given request and returns the client
+	// side of the stream. The Body of the request is ignored.
+	//
+	// The stream lasts until the given context is cancelled, the server ends
+	// the stream, or an error occurs.
+	//
+	// This MUST NOT be called before Start() has been called successfully. This
+	// MAY panic if called without calling Start(). This MUST be safe to call
+	// concurrently.
+	CallStream(ctx context.Context, request *Request) (ClientStream, error)
+}
+
 // Outbounds encapsulates the outbound specification for a service.
 //
 // This includes the service name that will be used for outbound requests as
 // well as the Outbound that will be used to transport the request.  The
-// outbound will be one of Unary and Oneway.
+// outbound will be one of Unary, Oneway, and Stream.
 type Outbounds struct {
 	ServiceName string
 
@@ -79,4 +97,8 @@ type Outbounds struct {
 	// If set, this is the oneway outbound which sends the request and
 	// continues once the message has been delivered.
 	Oneway OnewayOutbound
+
+	// If set, this is the stream outbound which opens a stream of messages
+	// with the remote service.
+	Stream StreamOutbound
 }
@@ -0,0 +1,83 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package transport
+
+import (
+	"context"
+	"io"
+)
+
+// StreamMessage is a single message sent over a stream. Streams exchange
+// any number of messages in both directions over their lifetime.
+type StreamMessage struct {
+	// Message payload.
+	Body io.ReadCloser
+}
+
+// Stream is a bidirectional stream of messages between a client and a
+// server for a single streaming RPC.
+//
+// A stream is NOT thread-safe for concurrent sends or concurrent receives.
+// One goroutine MAY send messages while another receives them.
+type Stream interface {
+	// Context returns the context for the duration of the stream. The
+	// context is cancelled when the stream ends.
+	Context() context.Context
+
+	// Request returns the metadata of the request that opened this stream.
+	// The Body of the request is always nil; payloads are exchanged with
+	// SendMessage and ReceiveMessage.
+	Request() *Request
+
+	// SendMessage sends a message over the stream.
+	//
+	// Returns io.EOF if the other side of the stream has ended and no
+	// further messages can be sent.
+	SendMessage(*StreamMessage) error
+
+	// ReceiveMessage blocks until a message is available from the other side
+	// of the stream.
+	//
+	// Returns io.EOF when the other side of the stream has finished sending
+	// messages without error.
+	ReceiveMessage() (*StreamMessage, error)
+}
+
+// ClientStream is the client side of a Stream.
+type ClientStream interface {
+	Stream
+
+	// Close informs the server that the client has finished sending
+	// messages. The client MAY continue to receive messages until
+	// ReceiveMessage returns io.EOF or an error.
+	Close() error
+}
+
+// StreamHandler handles a single, transport-level, streaming request.
+type StreamHandler interface {
+	// HandleStream handles the given stream until the RPC is complete.
+	//
+	// The stream ends when HandleStream returns. A nil error indicates that
+	// the stream finished successfully.
+	//
+	// Handlers MUST NOT retain references to the Stream.
+	HandleStream(stream Stream) error
+}
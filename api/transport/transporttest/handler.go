@@ -19,7 +19,7 @@
 // THE SOFTWARE.
 
 // Automatically generated by MockGen. DO NOT EDIT!
-// Source: go.uber.org/yarpc/api/transport (interfaces: UnaryHandler,OnewayHandler)
+// Source: go.uber.org/yarpc/api/transport (interfaces: UnaryHandler,OnewayHandler,StreamHandler)
 
 package transporttest
 
@@ -90,3 +90,34 @@ func (_m *MockOnewayHandler) HandleOneway(_param0 context.Context, _param1 *tran
 func (_mr *_MockOnewayHandlerRecorder) HandleOneway(arg0, arg1 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "HandleOneway", arg0, arg1)
 }
+
+// Mock of StreamHandler interface
+type MockStreamHandler struct {
+	ctrl     *gomock.Controller
+	recorder *_MockStreamHandlerRecorder
+}
+
+// Recorder for MockStreamHandler (not exported)
+type _MockStreamHandlerRecorder struct {
+	mock *MockStreamHandler
+}
+
+func NewMockStreamHandler(ctrl *gomock.Controller) *MockStreamHandler {
+	mock := &MockStreamHandler{ctrl: ctrl}
+	mock.recorder = &_MockStreamHandlerRecorder{mock}
+	return mock
+}
+
+func (_m *MockStreamHandler) EXPECT() *_MockStreamHandlerRecorder {
+	return _m.recorder
+}
+
+func (_m *MockStreamHandler) HandleStream(_param0 transport.Stream) error {
+	ret := _m.ctrl.Call(_m, "HandleStream", _param0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockStreamHandlerRecorder) HandleStream(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "HandleStream", arg0)
+}
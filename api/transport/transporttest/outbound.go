@@ -19,7 +19,7 @@
 // THE SOFTWARE.
 
 // Automatically generated by MockGen. DO NOT EDIT!
-// Source: go.uber.org/yarpc/api/transport (interfaces: UnaryOutbound,OnewayOutbound)
+// Source: go.uber.org/yarpc/api/transport (interfaces: UnaryOutbound,OnewayOutbound,StreamOutbound)
 
 package transporttest
 
@@ -172,3 +172,75 @@ func (_m *MockOnewayOutbound) Transports() []transport.Transport {
 func (_mr *_MockOnewayOutboundRecorder) Transports() *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "Transports")
 }
+
+// Mock of StreamOutbound interface
+type MockStreamOutbound struct {
+	ctrl     *gomock.Controller
+	recorder *_MockStreamOutboundRecorder
+}
+
+// Recorder for MockStreamOutbound (not exported)
+type _MockStreamOutboundRecorder struct {
+	mock *MockStreamOutbound
+}
+
+func NewMockStreamOutbound(ctrl *gomock.Controller) *MockStreamOutbound {
+	mock := &MockStreamOutbound{ctrl: ctrl}
+	mock.recorder = &_MockStreamOutboundRecorder{mock}
+	return mock
+}
+
+func (_m *MockStreamOutbound) EXPECT() *_MockStreamOutboundRecorder {
+	return _m.recorder
+}
+
+func (_m *MockStreamOutbound) CallStream(_param0 context.Context, _param1 *transport.Request) (transport.ClientStream, error) {
+	ret := _m.ctrl.Call(_m, "CallStream", _param0, _param1)
+	ret0, _ := ret[0].(transport.ClientStream)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockStreamOutboundRecorder) CallStream(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "CallStream", arg0, arg1)
+}
+
+func (_m *MockStreamOutbound) IsRunning() bool {
+	ret := _m.ctrl.Call(_m, "IsRunning")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+func (_mr *_MockStreamOutboundRecorder) IsRunning() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "IsRunning")
+}
+
+func (_m *MockStreamOutbound) Start() error {
+	ret := _m.ctrl.Call(_m, "Start")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockStreamOutboundRecorder) Start() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Start")
+}
+
+func (_m *MockStreamOutbound) Stop() error {
+	ret := _m.ctrl.Call(_m, "Stop")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockStreamOutboundRecorder) Stop() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Stop")
+}
+
+func (_m *MockStreamOutbound) Transports() []transport.Transport {
+	ret := _m.ctrl.Call(_m, "Transports")
+	ret0, _ := ret[0].([]transport.Transport)
+	return ret0
+}
+
+func (_mr *_MockStreamOutboundRecorder) Transports() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Transports")
+}
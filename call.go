@@ -22,6 +22,7 @@ package yarpc
 
 import (
 	"context"
+	"time"
 
 	"go.uber.org/yarpc/api/encoding"
 	"go.uber.org/yarpc/api/transport"
@@ -81,6 +82,28 @@ func WithRoutingDelegate(rd string) CallOption {
 	return CallOption(encoding.WithRoutingDelegate(rd))
 }
 
+// WithDeliverAt asks for a oneway request to be delivered no earlier than the
+// given time.
+//
+// 	ack, err := client.Remind(ctx, reminder, yarpc.WithDeliverAt(dueDate))
+//
+// Only oneway outbounds which support delayed delivery hold the request until
+// it is due; other outbounds deliver it immediately. Unary requests are never
+// delayed.
+func WithDeliverAt(t time.Time) CallOption {
+	return CallOption(encoding.WithDeliverAt(t))
+}
+
+// WithDelay asks for a oneway request to be delivered no earlier than the
+// given duration after the call is made.
+//
+// 	ack, err := client.Remind(ctx, reminder, yarpc.WithDelay(10*time.Minute))
+//
+// See WithDeliverAt for the outbounds which honor it.
+func WithDelay(d time.Duration) CallOption {
+	return CallOption(encoding.WithDelay(d))
+}
+
 // Call provides information about the current request inside handlers. An
 // instance of Call for the current request can be obtained by calling
 // CallFromContext on the request context.
@@ -0,0 +1,136 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package gzip provides a transport.Compressor for the gzip compression
+// format.
+//
+// 	httpTransport.NewOutbound(chooser, http.OutboundCompressor(gzip.New()))
+package gzip
+
+import (
+	"compress/gzip"
+	"io"
+	"sync"
+
+	"go.uber.org/yarpc/api/transport"
+)
+
+// Name is the name of the gzip compression format.
+const Name = "gzip"
+
+var _ transport.Compressor = (*Compressor)(nil)
+
+// Option customizes the behavior of a gzip Compressor.
+type Option func(*Compressor)
+
+// Level specifies the compression level used by the Compressor. This MUST be
+// one of the levels defined by the compress/gzip package. Defaults to
+// gzip.DefaultCompression.
+func Level(level int) Option {
+	return func(c *Compressor) {
+		c.level = level
+	}
+}
+
+// Compressor is a transport.Compressor for the gzip compression format.
+type Compressor struct {
+	level   int
+	writers sync.Pool
+	readers sync.Pool
+}
+
+// New builds a new gzip Compressor.
+func New(opts ...Option) *Compressor {
+	c := &Compressor{level: gzip.DefaultCompression}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Name returns "gzip".
+func (*Compressor) Name() string { return Name }
+
+// Compress returns a writer that compresses data into w.
+func (c *Compressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	if zw, ok := c.writers.Get().(*gzip.Writer); ok {
+		zw.Reset(w)
+		return &writer{Writer: zw, pool: &c.writers}, nil
+	}
+
+	zw, err := gzip.NewWriterLevel(w, c.level)
+	if err != nil {
+		return nil, err
+	}
+	return &writer{Writer: zw, pool: &c.writers}, nil
+}
+
+// Decompress returns a reader that decompresses data read from r.
+func (c *Compressor) Decompress(r io.Reader) (io.ReadCloser, error) {
+	if zr, ok := c.readers.Get().(*gzip.Reader); ok {
+		if err := zr.Reset(r); err != nil {
+			c.readers.Put(zr)
+			return nil, err
+		}
+		return &reader{Reader: zr, pool: &c.readers}, nil
+	}
+
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return &reader{Reader: zr, pool: &c.readers}, nil
+}
+
+// writer returns its gzip.Writer to the pool when it is first closed.
+// Closing it again is a no-op, so that the gzip.Writer is not pooled twice.
+type writer struct {
+	*gzip.Writer
+
+	pool *sync.Pool
+}
+
+func (w *writer) Close() error {
+	if w.Writer == nil {
+		return nil
+	}
+	err := w.Writer.Close()
+	w.pool.Put(w.Writer)
+	w.Writer = nil
+	return err
+}
+
+// reader returns its gzip.Reader to the pool when it is first closed.
+// Closing it again is a no-op, so that the gzip.Reader is not pooled twice.
+type reader struct {
+	*gzip.Reader
+
+	pool *sync.Pool
+}
+
+func (r *reader) Close() error {
+	if r.Reader == nil {
+		return nil
+	}
+	err := r.Reader.Close()
+	r.pool.Put(r.Reader)
+	r.Reader = nil
+	return err
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package snappy provides a transport.Compressor for the framing format of
+// the snappy compression format.
+//
+// 	httpTransport.NewOutbound(chooser, http.OutboundCompressor(snappy.New()))
+package snappy
+
+import (
+	"io"
+	"io/ioutil"
+
+	"go.uber.org/yarpc/api/transport"
+
+	"github.com/golang/snappy"
+)
+
+// Name is the name of the snappy compression format.
+const Name = "snappy"
+
+var _ transport.Compressor = (*Compressor)(nil)
+
+// Compressor is a transport.Compressor for the snappy compression format.
+type Compressor struct{}
+
+// New builds a new snappy Compressor.
+func New() *Compressor {
+	return &Compressor{}
+}
+
+// Name returns "snappy".
+func (*Compressor) Name() string { return Name }
+
+// Compress returns a writer that compresses data into w.
+func (*Compressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return snappy.NewBufferedWriter(w), nil
+}
+
+// Decompress returns a reader that decompresses data read from r.
+func (*Compressor) Decompress(r io.Reader) (io.ReadCloser, error) {
+	return ioutil.NopCloser(snappy.NewReader(r)), nil
+}
@@ -36,10 +36,11 @@ import (
 )
 
 const (
-	// Sleep between pushes to Tally metrics. At some point, we may want this
-	// to be configurable.
-	_tallyPushInterval = 500 * time.Millisecond
-	_packageName       = "yarpc"
+	// Default sleep between pushes to Tally metrics.
+	_defaultTallyPushInterval = 500 * time.Millisecond
+	// Default interval over which logs are sampled.
+	_defaultLogSamplingTick = time.Second
+	_packageName            = "yarpc"
 )
 
 // LoggingConfig describes how logging should be configured.
@@ -50,13 +51,54 @@ type LoggingConfig struct {
 	// If supplied, ExtractContext is used to log request-scoped
 	// information carried on the context (e.g., trace and span IDs).
 	ContextExtractor func(context.Context) zapcore.Field
+
+	// If supplied, logs below this level are dropped even if Zap is enabled
+	// for them. By default, all logs allowed by Zap are emitted.
+	Level *zapcore.Level
+	// If supplied, logs are sampled to limit the number of logs emitted by
+	// the dispatcher.
+	Sampling *LogSamplingConfig
+	// Names of the request fields included in request logs, in order. This
+	// may be any of "source", "dest", "procedure", "encoding", "shardKey",
+	// "routingKey", and "routingDelegate". By default, all request fields
+	// are logged.
+	RequestFields []string
+}
+
+// LogSamplingConfig describes how logs should be sampled. Within each tick,
+// the first Initial logs with the same level and message are emitted and
+// every Thereafter-th log after that. Initial and Thereafter must be
+// positive.
+type LogSamplingConfig struct {
+	Initial    int
+	Thereafter int
+	// Defaults to one second.
+	Tick time.Duration
 }
 
 func (c LoggingConfig) logger(name string) *zap.Logger {
 	if c.Zap == nil {
 		return zap.NewNop()
 	}
-	return c.Zap.Named(_packageName).With(
+
+	logger := c.Zap
+	if c.Level != nil {
+		level := *c.Level
+		logger = logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return observability.NewLevelFilter(core, level)
+		}))
+	}
+	if s := c.Sampling; s != nil {
+		tick := s.Tick
+		if tick <= 0 {
+			tick = _defaultLogSamplingTick
+		}
+		logger = logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return zapcore.NewSampler(core, tick, s.Initial, s.Thereafter)
+		}))
+	}
+
+	return logger.Named(_packageName).With(
 		// Use a namespace to prevent key collisions with other libraries.
 		zap.Namespace(_packageName),
 		zap.String("dispatcher", name),
@@ -70,11 +112,21 @@ func (c LoggingConfig) extractor() observability.ContextExtractor {
 	return observability.ContextExtractor(c.ContextExtractor)
 }
 
+func (c LoggingConfig) middlewareOptions() []observability.MiddlewareOption {
+	if c.RequestFields == nil {
+		return nil
+	}
+	return []observability.MiddlewareOption{observability.RequestFields(c.RequestFields)}
+}
+
 // MetricsConfig describes how telemetry should be configured.
 type MetricsConfig struct {
 	// Tally scope used for pushing to M3 or StatsD-based systems. By
 	// default, metrics are collected in memory but not pushed.
 	Tally tally.Scope
+	// Interval between pushes to the Tally scope. Defaults to 500
+	// milliseconds.
+	TallyPushInterval time.Duration
 }
 
 func (c MetricsConfig) registry(name string, logger *zap.Logger) (*pally.Registry, context.CancelFunc) {
@@ -91,7 +143,12 @@ func (c MetricsConfig) registry(name string, logger *zap.Logger) (*pally.Registr
 		return r, func() {}
 	}
 
-	stop, err := r.Push(c.Tally, _tallyPushInterval)
+	interval := c.TallyPushInterval
+	if interval <= 0 {
+		interval = _defaultTallyPushInterval
+	}
+
+	stop, err := r.Push(c.Tally, interval)
 	if err != nil {
 		logger.Error("Failed to start pushing metrics to Tally.", zap.Error(err))
 		return r, func() {}
@@ -135,4 +192,11 @@ type Config struct {
 
 	// Configures telemetry.
 	Metrics MetricsConfig
+
+	// DrainTimeout is the maximum amount of time Dispatcher.Stop waits for
+	// requests in flight to finish before it stops the inbounds and
+	// outbounds. New requests are rejected while the Dispatcher drains.
+	//
+	// Defaults to zero, in which case the Dispatcher does not drain.
+	DrainTimeout time.Duration
 }
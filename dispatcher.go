@@ -24,11 +24,13 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"go.uber.org/yarpc/api/middleware"
 	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/internal"
 	"go.uber.org/yarpc/internal/clientconfig"
+	"go.uber.org/yarpc/internal/drain"
 	"go.uber.org/yarpc/internal/inboundmiddleware"
 	"go.uber.org/yarpc/internal/observability"
 	"go.uber.org/yarpc/internal/outboundmiddleware"
@@ -52,12 +54,14 @@ type Outbounds map[string]transport.Outbounds
 type OutboundMiddleware struct {
 	Unary  middleware.UnaryOutbound
 	Oneway middleware.OnewayOutbound
+	Stream middleware.StreamOutbound
 }
 
 // InboundMiddleware contains the different types of inbound middlewares.
 type InboundMiddleware struct {
 	Unary  middleware.UnaryInbound
 	Oneway middleware.OnewayInbound
+	Stream middleware.StreamInbound
 }
 
 // RouterMiddleware wraps the Router middleware
@@ -75,28 +79,81 @@ func NewDispatcher(cfg Config) *Dispatcher {
 	if err := internal.ValidateServiceName(cfg.Name); err != nil {
 		panic("yarpc.NewDispatcher expects a valid service name: " + err.Error())
 	}
+	if err := observability.ValidateRequestFields(cfg.Logging.RequestFields); err != nil {
+		panic("yarpc.NewDispatcher expects valid request fields to log: " + err.Error())
+	}
+	if s := cfg.Logging.Sampling; s != nil && (s.Initial <= 0 || s.Thereafter <= 0) {
+		panic("yarpc.NewDispatcher expects positive log sampling parameters")
+	}
+	if cfg.DrainTimeout < 0 {
+		panic("yarpc.NewDispatcher expects a non-negative drain timeout")
+	}
 
 	logger := cfg.Logging.logger(cfg.Name)
 	extractor := cfg.Logging.extractor()
 
 	registry, stopPush := cfg.Metrics.registry(cfg.Name, logger)
-	cfg = addObservingMiddleware(cfg, registry, logger, extractor)
+
+	var (
+		tracker *drain.Tracker
+		cutoffs pally.Counter
+	)
+	if cfg.DrainTimeout > 0 {
+		tracker = drain.NewTracker(cfg.Name)
+		cfg = addDrainMiddleware(cfg, tracker)
+		cutoffs = newDrainCutoffsCounter(registry, logger)
+	}
+
+	cfg = addObservingMiddleware(cfg, registry, logger, extractor, cfg.Logging.middlewareOptions()...)
+	outbounds, outboundTrackers := convertOutbounds(cfg.Outbounds, cfg.OutboundMiddleware)
 
 	return &Dispatcher{
-		name:              cfg.Name,
-		table:             middleware.ApplyRouteTable(NewMapRouter(cfg.Name), cfg.RouterMiddleware),
-		inbounds:          cfg.Inbounds,
-		outbounds:         convertOutbounds(cfg.Outbounds, cfg.OutboundMiddleware),
-		transports:        collectTransports(cfg.Inbounds, cfg.Outbounds),
-		inboundMiddleware: cfg.InboundMiddleware,
-		log:               logger,
-		registry:          registry,
-		stopRegistryPush:  stopPush,
+		name:               cfg.Name,
+		table:              middleware.ApplyRouteTable(NewMapRouter(cfg.Name), cfg.RouterMiddleware),
+		inbounds:           cfg.Inbounds,
+		outbounds:          outbounds,
+		outboundTrackers:   outboundTrackers,
+		transports:         collectTransports(cfg.Inbounds, cfg.Outbounds),
+		inboundMiddleware:  cfg.InboundMiddleware,
+		outboundMiddleware: cfg.OutboundMiddleware,
+		log:                logger,
+		registry:           registry,
+		stopRegistryPush:   stopPush,
+		drainTimeout:       cfg.DrainTimeout,
+		drainTracker:       tracker,
+		drainCutoffs:       cutoffs,
+	}
+}
+
+// addDrainMiddleware adds middleware that tracks the requests in flight so
+// that the Dispatcher may wait for them when it is stopped.
+func addDrainMiddleware(cfg Config, tracker *drain.Tracker) Config {
+	cfg.InboundMiddleware.Unary = inboundmiddleware.UnaryChain(tracker, cfg.InboundMiddleware.Unary)
+	cfg.InboundMiddleware.Oneway = inboundmiddleware.OnewayChain(tracker, cfg.InboundMiddleware.Oneway)
+	cfg.InboundMiddleware.Stream = inboundmiddleware.StreamChain(tracker, cfg.InboundMiddleware.Stream)
+	return cfg
+}
+
+func newDrainCutoffsCounter(registry *pally.Registry, logger *zap.Logger) pally.Counter {
+	c, err := registry.NewCounter(pally.Opts{
+		Name: "drain_cutoffs",
+		Help: "Number of requests still in flight when the drain timeout expired.",
+	})
+	if err != nil {
+		logger.Error("Failed to create drain cutoffs counter.", zap.Error(err))
+		return pally.NewNopCounter()
 	}
+	return c
 }
 
-func addObservingMiddleware(cfg Config, registry *pally.Registry, logger *zap.Logger, extractor observability.ContextExtractor) Config {
-	observer := observability.NewMiddleware(logger, registry, extractor)
+func addObservingMiddleware(
+	cfg Config,
+	registry *pally.Registry,
+	logger *zap.Logger,
+	extractor observability.ContextExtractor,
+	opts ...observability.MiddlewareOption,
+) Config {
+	observer := observability.NewMiddleware(logger, registry, extractor, opts...)
 
 	cfg.InboundMiddleware.Unary = inboundmiddleware.UnaryChain(observer, cfg.InboundMiddleware.Unary)
 	cfg.InboundMiddleware.Oneway = inboundmiddleware.OnewayChain(observer, cfg.InboundMiddleware.Oneway)
@@ -108,31 +165,46 @@ func addObservingMiddleware(cfg Config, registry *pally.Registry, logger *zap.Lo
 }
 
 // convertOutbounds applys outbound middleware and creates validator outbounds
-func convertOutbounds(outbounds Outbounds, mw OutboundMiddleware) Outbounds {
+func convertOutbounds(outbounds Outbounds, mw OutboundMiddleware) (Outbounds, map[string]*outboundTracker) {
 	outboundSpecs := make(Outbounds, len(outbounds))
+	trackers := make(map[string]*outboundTracker, len(outbounds))
 
 	for outboundKey, outs := range outbounds {
-		if outs.Unary == nil && outs.Oneway == nil {
+		if outs.Unary == nil && outs.Oneway == nil && outs.Stream == nil {
 			panic(fmt.Sprintf("no outbound set for outbound key %q in dispatcher", outboundKey))
 		}
 
 		var (
 			unaryOutbound  transport.UnaryOutbound
 			onewayOutbound transport.OnewayOutbound
+			streamOutbound transport.StreamOutbound
 		)
 		serviceName := outboundKey
 
+		// Calls in flight are tracked so that they may finish if the
+		// outbound is removed.
+		tracker := newOutboundTracker(outboundKey)
+		trackers[outboundKey] = tracker
+
 		// apply outbound middleware and create ValidatorOutbounds
 		if outs.Unary != nil {
 			unaryOutbound = middleware.ApplyUnaryOutbound(outs.Unary, mw.Unary)
+			unaryOutbound = middleware.ApplyUnaryOutbound(unaryOutbound, tracker)
 			unaryOutbound = request.UnaryValidatorOutbound{UnaryOutbound: unaryOutbound}
 		}
 
 		if outs.Oneway != nil {
 			onewayOutbound = middleware.ApplyOnewayOutbound(outs.Oneway, mw.Oneway)
+			onewayOutbound = middleware.ApplyOnewayOutbound(onewayOutbound, tracker)
 			onewayOutbound = request.OnewayValidatorOutbound{OnewayOutbound: onewayOutbound}
 		}
 
+		if outs.Stream != nil {
+			streamOutbound = middleware.ApplyStreamOutbound(outs.Stream, mw.Stream)
+			streamOutbound = middleware.ApplyStreamOutbound(streamOutbound, tracker)
+			streamOutbound = request.StreamValidatorOutbound{StreamOutbound: streamOutbound}
+		}
+
 		if outs.ServiceName != "" {
 			serviceName = outs.ServiceName
 		}
@@ -141,10 +213,11 @@ func convertOutbounds(outbounds Outbounds, mw OutboundMiddleware) Outbounds {
 			ServiceName: serviceName,
 			Unary:       unaryOutbound,
 			Oneway:      onewayOutbound,
+			Stream:      streamOutbound,
 		}
 	}
 
-	return outboundSpecs
+	return outboundSpecs, trackers
 }
 
 // collectTransports iterates over all inbounds and outbounds and collects all
@@ -170,6 +243,11 @@ func collectTransports(inbounds Inbounds, outbounds Outbounds) []transport.Trans
 				transports[transport] = struct{}{}
 			}
 		}
+		if stream := outbound.Stream; stream != nil {
+			for _, transport := range stream.Transports() {
+				transports[transport] = struct{}{}
+			}
+		}
 	}
 	keys := make([]transport.Transport, 0, len(transports))
 	for key := range transports {
@@ -181,17 +259,35 @@ func collectTransports(inbounds Inbounds, outbounds Outbounds) []transport.Trans
 // Dispatcher encapsulates a YARPC application. It acts as the entry point to
 // send and receive YARPC requests in a transport and encoding agnostic way.
 type Dispatcher struct {
-	table      transport.RouteTable
-	name       string
-	inbounds   Inbounds
-	outbounds  Outbounds
-	transports []transport.Transport
-
-	inboundMiddleware InboundMiddleware
+	table    transport.RouteTable
+	name     string
+	inbounds Inbounds
+
+	// lifecycleMu serializes Start, Stop, AddOutbound, and RemoveOutbound.
+	// Changes to outbounds and transports are made while holding both,
+	// lifecycleMu and outboundsMu.
+	lifecycleMu sync.Mutex
+	outboundsMu sync.RWMutex
+	outbounds   Outbounds
+	transports  []transport.Transport
+	running     bool
+
+	// Tracks the calls in flight through each outbound, keyed by outbound
+	// key, so that RemoveOutbound may wait for them.
+	outboundTrackers map[string]*outboundTracker
+
+	inboundMiddleware  InboundMiddleware
+	outboundMiddleware OutboundMiddleware
 
 	log              *zap.Logger
 	registry         *pally.Registry
 	stopRegistryPush context.CancelFunc
+
+	// Tracks the requests in flight if the Dispatcher drains when it is
+	// stopped. This is nil otherwise.
+	drainTimeout time.Duration
+	drainTracker *drain.Tracker
+	drainCutoffs pally.Counter
 }
 
 // Inbounds returns a copy of the list of inbounds for this RPC object.
@@ -212,12 +308,167 @@ func (d *Dispatcher) Inbounds() Inbounds {
 //
 // This function panics if the outboundKey is not known.
 func (d *Dispatcher) ClientConfig(outboundKey string) transport.ClientConfig {
-	if rs, ok := d.outbounds[outboundKey]; ok {
+	d.outboundsMu.RLock()
+	rs, ok := d.outbounds[outboundKey]
+	d.outboundsMu.RUnlock()
+
+	if ok {
 		return clientconfig.MultiOutbound(d.name, rs.ServiceName, rs)
 	}
 	panic(noOutboundForOutboundKey{OutboundKey: outboundKey})
 }
 
+// AddOutbound adds outbounds for the given outbound key to the Dispatcher.
+// The outbound middleware of the Dispatcher is applied to them.
+//
+// If the Dispatcher is running, the outbounds and any of their transports
+// not already used by the Dispatcher are started before the outbounds are
+// made available through ClientConfig. The Dispatcher stops them when it is
+// stopped.
+//
+// An error is returned if the Dispatcher already has outbounds for the
+// given key.
+func (d *Dispatcher) AddOutbound(outboundKey string, outbounds transport.Outbounds) error {
+	if outbounds.Unary == nil && outbounds.Oneway == nil && outbounds.Stream == nil {
+		return fmt.Errorf("no outbound set for outbound key %q", outboundKey)
+	}
+
+	d.lifecycleMu.Lock()
+	defer d.lifecycleMu.Unlock()
+
+	d.outboundsMu.RLock()
+	_, exists := d.outbounds[outboundKey]
+	d.outboundsMu.RUnlock()
+	if exists {
+		return fmt.Errorf("outbound key %q is already in use", outboundKey)
+	}
+
+	added := Outbounds{outboundKey: outbounds}
+	convertedOutbounds, trackers := convertOutbounds(added, d.outboundMiddleware)
+	converted := convertedOutbounds[outboundKey]
+
+	known := make(map[transport.Transport]struct{}, len(d.transports))
+	for _, t := range d.transports {
+		known[t] = struct{}{}
+	}
+	var newTransports []transport.Transport
+	for _, t := range collectTransports(nil, added) {
+		if _, ok := known[t]; !ok {
+			newTransports = append(newTransports, t)
+		}
+	}
+
+	if d.running {
+		if err := startOutbound(newTransports, converted); err != nil {
+			return err
+		}
+	}
+
+	d.outboundsMu.Lock()
+	d.outbounds[outboundKey] = converted
+	d.outboundTrackers[outboundKey] = trackers[outboundKey]
+	d.transports = append(d.transports, newTransports...)
+	d.outboundsMu.Unlock()
+
+	d.log.Info("Added outbound.", zap.String("outboundKey", outboundKey))
+	return nil
+}
+
+// startOutbound starts the given transports and then the given outbounds.
+// Everything that was started is stopped if any of them fail to start.
+func startOutbound(transports []transport.Transport, o transport.Outbounds) error {
+	var started []transport.Lifecycle
+	start := func(l transport.Lifecycle) error {
+		if err := l.Start(); err != nil {
+			for _, s := range started {
+				err = multierr.Append(err, s.Stop())
+			}
+			return err
+		}
+		started = append(started, l)
+		return nil
+	}
+
+	for _, t := range transports {
+		if err := start(t); err != nil {
+			return err
+		}
+	}
+	for _, l := range outboundLifecycles(o) {
+		if err := start(l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeOutboundTimeout is how long RemoveOutbound waits for calls in flight
+// through the removed outbounds.
+const removeOutboundTimeout = 5 * time.Second
+
+// RemoveOutbound removes the outbounds for the given outbound key from the
+// Dispatcher. ClientConfig will panic for the key afterwards.
+//
+// Clients that were already built for these outbounds fail to make new
+// requests with an Unavailable error once the outbounds are removed. If the
+// Dispatcher is running, RemoveOutbound waits for the requests already in
+// flight through the outbounds to finish, for up to five seconds, and then
+// stops the outbounds.
+// Transports used by the outbounds keep running until the Dispatcher is
+// stopped.
+//
+// An error is returned if the Dispatcher does not have outbounds for the
+// given key.
+func (d *Dispatcher) RemoveOutbound(outboundKey string) error {
+	d.lifecycleMu.Lock()
+	defer d.lifecycleMu.Unlock()
+
+	d.outboundsMu.Lock()
+	o, ok := d.outbounds[outboundKey]
+	tracker := d.outboundTrackers[outboundKey]
+	delete(d.outbounds, outboundKey)
+	delete(d.outboundTrackers, outboundKey)
+	d.outboundsMu.Unlock()
+
+	if !ok {
+		return noOutboundForOutboundKey{OutboundKey: outboundKey}
+	}
+
+	tracker.remove()
+	d.log.Info("Removed outbound.", zap.String("outboundKey", outboundKey))
+	if !d.running {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), removeOutboundTimeout)
+	defer cancel()
+	if n := tracker.wait(ctx); n > 0 {
+		d.log.Warn("Stopping removed outbound with calls still in flight.",
+			zap.String("outboundKey", outboundKey), zap.Int("calls", n))
+	}
+
+	var err error
+	for _, l := range outboundLifecycles(o) {
+		err = multierr.Append(err, l.Stop())
+	}
+	return err
+}
+
+// outboundLifecycles returns the non-nil outbounds in the given Outbounds.
+func outboundLifecycles(o transport.Outbounds) []transport.Lifecycle {
+	var ls []transport.Lifecycle
+	if o.Unary != nil {
+		ls = append(ls, o.Unary)
+	}
+	if o.Oneway != nil {
+		ls = append(ls, o.Oneway)
+	}
+	if o.Stream != nil {
+		ls = append(ls, o.Stream)
+	}
+	return ls
+}
+
 // InboundMiddleware returns the middleware applied to all inbound handlers.
 // Router middleware and fallback handlers can use the InboundMiddleware to
 // wrap custom handlers.
@@ -241,6 +492,10 @@ func (d *Dispatcher) Register(rs []transport.Procedure) {
 			h := middleware.ApplyOnewayInbound(r.HandlerSpec.Oneway(),
 				d.inboundMiddleware.Oneway)
 			r.HandlerSpec = transport.NewOnewayHandlerSpec(h)
+		case transport.Streaming:
+			h := middleware.ApplyStreamInbound(r.HandlerSpec.Stream(),
+				d.inboundMiddleware.Stream)
+			r.HandlerSpec = transport.NewStreamHandlerSpec(h)
 		default:
 			panic(fmt.Sprintf("unknown handler type %q for service %q, procedure %q",
 				r.HandlerSpec.Type(), r.Service, r.Name))
@@ -277,6 +532,9 @@ func (d *Dispatcher) Start() error {
 	// If the inbounds are started before the outbounds, an inbound request
 	// might result in an outbound call before the outbound is ready.
 
+	d.lifecycleMu.Lock()
+	defer d.lifecycleMu.Unlock()
+
 	var (
 		mu         sync.Mutex
 		allStarted []transport.Lifecycle
@@ -319,6 +577,11 @@ func (d *Dispatcher) Start() error {
 	}
 	d.log.Debug("Set router for inbounds.")
 
+	if d.drainTracker != nil {
+		// Accept requests again if the Dispatcher was drained before.
+		d.drainTracker.Resume()
+	}
+
 	// Start Transports
 	wait := intsync.ErrorWaiter{}
 	d.log.Debug("Starting transports.")
@@ -336,6 +599,7 @@ func (d *Dispatcher) Start() error {
 	for _, o := range d.outbounds {
 		wait.Submit(start(o.Unary))
 		wait.Submit(start(o.Oneway))
+		wait.Submit(start(o.Stream))
 	}
 	if errs := wait.Wait(); len(errs) != 0 {
 		return abort(errs)
@@ -357,6 +621,8 @@ func (d *Dispatcher) Start() error {
 	addDispatcherToDebugPages(d)
 	d.log.Debug("Registered debug pages.")
 
+	d.running = true
+
 	d.log.Info("Started up.")
 	return nil
 }
@@ -365,6 +631,12 @@ func (d *Dispatcher) Start() error {
 //
 // This stops all outbounds and inbounds owned by this Dispatcher.
 //
+// If the Dispatcher was configured with a DrainTimeout, it first drains:
+// inbounds that implement transport.DrainableInbound advertise that they are
+// not ready, new requests are rejected with an Unavailable error, and Stop
+// waits up to the DrainTimeout for requests in flight to finish before
+// stopping the inbounds and outbounds.
+//
 // This function returns after everything has been stopped.
 func (d *Dispatcher) Stop() error {
 	// NOTE: These MUST be stopped in the order inbounds, outbounds, and then
@@ -377,8 +649,16 @@ func (d *Dispatcher) Stop() error {
 	// If the transports are stopped before the outbounds, the peers contained
 	// in the outbound might be deleted from the transport's perspective and
 	// cause issues.
+	d.lifecycleMu.Lock()
+	defer d.lifecycleMu.Unlock()
+
 	var allErrs []error
 	d.log.Info("Starting shutdown.")
+	d.running = false
+
+	if d.drainTracker != nil {
+		d.drain()
+	}
 
 	// Stop Inbounds
 	d.log.Debug("Stopping inbounds.")
@@ -401,6 +681,9 @@ func (d *Dispatcher) Stop() error {
 		if o.Oneway != nil {
 			wait.Submit(o.Oneway.Stop)
 		}
+		if o.Stream != nil {
+			wait.Submit(o.Stream.Stop)
+		}
 	}
 	if errs := wait.Wait(); len(errs) > 0 {
 		allErrs = append(allErrs, errs...)
@@ -435,6 +718,27 @@ func (d *Dispatcher) Stop() error {
 	return nil
 }
 
+// drain makes the inbounds stop accepting new requests and waits up to the
+// drain timeout for the requests already in flight to finish.
+func (d *Dispatcher) drain() {
+	d.log.Info("Draining inbounds.", zap.Duration("timeout", d.drainTimeout))
+	for _, i := range d.inbounds {
+		if di, ok := i.(transport.DrainableInbound); ok {
+			di.Drain()
+		}
+	}
+	d.drainTracker.Drain()
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.drainTimeout)
+	defer cancel()
+	if n := d.drainTracker.Wait(ctx); n > 0 {
+		d.drainCutoffs.Add(int64(n))
+		d.log.Warn("Drain timeout expired with requests still in flight.", zap.Int("requests", n))
+		return
+	}
+	d.log.Info("Drained inbounds.")
+}
+
 // Router returns the procedure router.
 func (d *Dispatcher) Router() transport.Router {
 	return d.table
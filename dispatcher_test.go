@@ -21,22 +21,33 @@
 package yarpc_test
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"testing"
+	"time"
 
 	. "go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/middleware"
 	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/api/transport/transporttest"
 	"go.uber.org/yarpc/internal/observability"
 	"go.uber.org/yarpc/transport/http"
 	"go.uber.org/yarpc/transport/tchannel"
+	"go.uber.org/yarpc/transport/x/inmemory"
+	"go.uber.org/yarpc/yarpcerrors"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/uber-go/tally"
+	"go.uber.org/atomic"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func basicConfig(t testing.TB) Config {
@@ -373,6 +384,74 @@ func TestClientConfigWithOutboundServiceNameOverride(t *testing.T) {
 	assert.Equal(t, "my-real-service", cc.Service())
 }
 
+func TestStreaming(t *testing.T) {
+	var handled, called int
+	tr := inmemory.NewTransport()
+	dispatcher := NewDispatcher(Config{
+		Name:     "test",
+		Inbounds: Inbounds{tr.NewInbound("test")},
+		Outbounds: Outbounds{
+			"test": {Stream: tr.NewOutbound("test")},
+		},
+		InboundMiddleware: InboundMiddleware{
+			Stream: middleware.StreamInboundFunc(func(s transport.Stream, h transport.StreamHandler) error {
+				handled++
+				return h.HandleStream(s)
+			}),
+		},
+		OutboundMiddleware: OutboundMiddleware{
+			Stream: middleware.StreamOutboundFunc(func(ctx context.Context, req *transport.Request, o transport.StreamOutbound) (transport.ClientStream, error) {
+				called++
+				return o.CallStream(ctx, req)
+			}),
+		},
+	})
+	dispatcher.Register([]transport.Procedure{
+		{
+			Name: "hello",
+			HandlerSpec: transport.NewStreamHandlerSpec(streamHandlerFunc(func(s transport.Stream) error {
+				msg, err := s.ReceiveMessage()
+				if err != nil {
+					return err
+				}
+				return s.SendMessage(msg)
+			})),
+		},
+	})
+	require.NoError(t, dispatcher.Start())
+	defer func() { assert.NoError(t, dispatcher.Stop()) }()
+
+	cc, ok := dispatcher.ClientConfig("test").(transport.StreamClientConfig)
+	require.True(t, ok, "expected ClientConfig to support streaming")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	stream, err := cc.GetStreamOutbound().CallStream(ctx, &transport.Request{
+		Caller:    "test",
+		Service:   "test",
+		Encoding:  transport.Encoding("raw"),
+		Procedure: "hello",
+	})
+	require.NoError(t, err)
+	require.NoError(t, stream.SendMessage(&transport.StreamMessage{
+		Body: ioutil.NopCloser(bytes.NewBufferString("hi")),
+	}))
+	msg, err := stream.ReceiveMessage()
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(msg.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "hi", string(body))
+	_, err = stream.ReceiveMessage()
+	assert.Equal(t, io.EOF, err)
+
+	assert.Equal(t, 1, handled, "expected inbound stream middleware to be called")
+	assert.Equal(t, 1, called, "expected outbound stream middleware to be called")
+}
+
+type streamHandlerFunc func(transport.Stream) error
+
+func (f streamHandlerFunc) HandleStream(s transport.Stream) error { return f(s) }
+
 func TestObservabilityConfig(t *testing.T) {
 	// Validate that we can start a dispatcher with various logging and metrics
 	// configs.
@@ -381,10 +460,13 @@ func TestObservabilityConfig(t *testing.T) {
 		{Zap: zap.NewNop()},
 		{ContextExtractor: observability.NewNopContextExtractor()},
 		{Zap: zap.NewNop(), ContextExtractor: observability.NewNopContextExtractor()},
+		{Zap: zap.NewNop(), Sampling: &LogSamplingConfig{Initial: 100, Thereafter: 100}},
+		{Zap: zap.NewNop(), RequestFields: []string{"source", "procedure"}},
 	}
 	metricsCfgs := []MetricsConfig{
 		{},
 		{Tally: tally.NewTestScope("" /* prefix */, nil /* tags */)},
+		{Tally: tally.NewTestScope("" /* prefix */, nil /* tags */), TallyPushInterval: time.Second},
 	}
 
 	for _, l := range logCfgs {
@@ -400,3 +482,379 @@ func TestObservabilityConfig(t *testing.T) {
 		}
 	}
 }
+
+func TestDispatcherAddOutboundBeforeStart(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	trans := transporttest.NewMockTransport(mockCtrl)
+	out := transporttest.NewMockUnaryOutbound(mockCtrl)
+	out.EXPECT().Transports().Return([]transport.Transport{trans}).AnyTimes()
+
+	d := NewDispatcher(Config{Name: "test"})
+	require.NoError(t, d.AddOutbound("foo", transport.Outbounds{Unary: out}))
+	assert.Equal(t, "foo", d.ClientConfig("foo").Service())
+
+	gomock.InOrder(trans.EXPECT().Start().Return(nil), out.EXPECT().Start().Return(nil))
+	require.NoError(t, d.Start())
+
+	gomock.InOrder(out.EXPECT().Stop().Return(nil), trans.EXPECT().Stop().Return(nil))
+	require.NoError(t, d.Stop())
+}
+
+func TestDispatcherAddRemoveOutboundWhileRunning(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	d := NewDispatcher(Config{Name: "test"})
+	require.NoError(t, d.Start())
+
+	trans := transporttest.NewMockTransport(mockCtrl)
+	out := transporttest.NewMockUnaryOutbound(mockCtrl)
+	out.EXPECT().Transports().Return([]transport.Transport{trans}).AnyTimes()
+
+	gomock.InOrder(trans.EXPECT().Start().Return(nil), out.EXPECT().Start().Return(nil))
+	require.NoError(t, d.AddOutbound("foo", transport.Outbounds{ServiceName: "bar", Unary: out}))
+	assert.Equal(t, "bar", d.ClientConfig("foo").Service())
+
+	err := d.AddOutbound("foo", transport.Outbounds{Unary: out})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `outbound key "foo" is already in use`)
+
+	err = d.AddOutbound("baz", transport.Outbounds{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `no outbound set for outbound key "baz"`)
+
+	out.EXPECT().Stop().Return(nil)
+	require.NoError(t, d.RemoveOutbound("foo"))
+	assert.Panics(t, func() { d.ClientConfig("foo") })
+
+	err = d.RemoveOutbound("foo")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `no configured outbound transport for outbound key "foo"`)
+
+	// The transport keeps running until the dispatcher is stopped.
+	trans.EXPECT().Stop().Return(nil)
+	require.NoError(t, d.Stop())
+}
+
+func TestDispatcherRemoveOutboundWaitsForCalls(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	d := NewDispatcher(Config{Name: "test"})
+	require.NoError(t, d.Start())
+	defer d.Stop()
+
+	out := transporttest.NewMockUnaryOutbound(mockCtrl)
+	out.EXPECT().Transports().Return(nil).AnyTimes()
+	out.EXPECT().Start().Return(nil)
+	require.NoError(t, d.AddOutbound("foo", transport.Outbounds{Unary: out}))
+
+	var released atomic.Bool
+	started, release := make(chan struct{}), make(chan struct{})
+	out.EXPECT().Call(gomock.Any(), gomock.Any()).Do(func(context.Context, *transport.Request) {
+		close(started)
+		<-release
+		released.Store(true)
+	}).Return(&transport.Response{}, nil)
+	out.EXPECT().Stop().Do(func() {
+		assert.True(t, released.Load(), "outbound must not be stopped with calls in flight")
+	}).Return(nil)
+
+	client := d.ClientConfig("foo").GetUnaryOutbound()
+	req := &transport.Request{
+		Caller:    "test",
+		Service:   "foo",
+		Encoding:  "raw",
+		Procedure: "hello",
+	}
+	call := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_, err := client.Call(ctx, req)
+		return err
+	}
+
+	done := make(chan error)
+	go func() { done <- call() }()
+	<-started
+
+	removed := make(chan error)
+	go func() { removed <- d.RemoveOutbound("foo") }()
+
+	// Wait until the outbound is removed before making the second call.
+	hasOutbound := func() (ok bool) {
+		defer func() { ok = recover() == nil }()
+		d.ClientConfig("foo")
+		return
+	}
+	for hasOutbound() {
+		time.Sleep(time.Millisecond)
+	}
+	err := call()
+	require.Error(t, err, "new calls must be rejected once the outbound is removed")
+	assert.Equal(t, yarpcerrors.CodeUnavailable, yarpcerrors.FromError(err).Code())
+
+	close(release)
+	assert.NoError(t, <-done, "calls in flight must finish")
+	assert.NoError(t, <-removed)
+}
+
+func TestDispatcherAddOutboundStartFailure(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	d := NewDispatcher(Config{Name: "test"})
+	require.NoError(t, d.Start())
+	defer d.Stop()
+
+	trans := transporttest.NewMockTransport(mockCtrl)
+	out := transporttest.NewMockUnaryOutbound(mockCtrl)
+	out.EXPECT().Transports().Return([]transport.Transport{trans}).AnyTimes()
+
+	gomock.InOrder(
+		trans.EXPECT().Start().Return(nil),
+		out.EXPECT().Start().Return(errors.New("great sadness")),
+		trans.EXPECT().Stop().Return(nil),
+	)
+	err := d.AddOutbound("foo", transport.Outbounds{Unary: out})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "great sadness")
+	assert.Panics(t, func() { d.ClientConfig("foo") })
+}
+
+func TestObservabilityConfigLogging(t *testing.T) {
+	warn := zapcore.WarnLevel
+
+	tests := []struct {
+		desc     string
+		give     LoggingConfig
+		wantLogs int
+		// Keys of the logged fields, excluding the dispatcher fields.
+		wantKeys []string
+	}{
+		{
+			desc:     "default",
+			wantLogs: 3,
+			wantKeys: []string{
+				"source", "dest", "procedure", "encoding", "shardKey",
+				"routingKey", "routingDelegate", "rpcType", "latency",
+				"successful", "", "",
+			},
+		},
+		{
+			desc: "level",
+			give: LoggingConfig{Level: &warn},
+		},
+		{
+			desc:     "sampling",
+			give:     LoggingConfig{Sampling: &LogSamplingConfig{Initial: 1, Thereafter: 100}},
+			wantLogs: 1,
+		},
+		{
+			desc:     "request fields",
+			give:     LoggingConfig{RequestFields: []string{"procedure", "source"}},
+			wantLogs: 3,
+			wantKeys: []string{"procedure", "source", "rpcType", "latency", "successful", "", ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			out := transporttest.NewMockUnaryOutbound(mockCtrl)
+			out.EXPECT().Transports().AnyTimes()
+			out.EXPECT().Call(gomock.Any(), gomock.Any()).Return(&transport.Response{}, nil).Times(3)
+
+			core, logs := observer.New(zapcore.DebugLevel)
+			logging := tt.give
+			logging.Zap = zap.New(core)
+
+			d := NewDispatcher(Config{
+				Name:      "test",
+				Outbounds: Outbounds{"foo": {Unary: out}},
+				Logging:   logging,
+			})
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			ob := d.ClientConfig("foo").GetUnaryOutbound()
+			for i := 0; i < 3; i++ {
+				_, err := ob.Call(ctx, &transport.Request{
+					Caller:    "test",
+					Service:   "foo",
+					Encoding:  "raw",
+					Procedure: "bar",
+					Body:      bytes.NewReader(nil),
+				})
+				require.NoError(t, err)
+			}
+
+			entries := logs.FilterMessage("Made outbound call.").AllUntimed()
+			require.Len(t, entries, tt.wantLogs)
+			if len(entries) == 0 || tt.wantKeys == nil {
+				return
+			}
+
+			var keys []string
+			for _, f := range entries[0].Context {
+				if f.Key == "dispatcher" || f.Key == "yarpc" {
+					continue
+				}
+				keys = append(keys, f.Key)
+			}
+			assert.Equal(t, tt.wantKeys, keys)
+		})
+	}
+}
+
+func TestObservabilityConfigInvalid(t *testing.T) {
+	tests := []struct {
+		desc string
+		give LoggingConfig
+	}{
+		{
+			desc: "unknown request field",
+			give: LoggingConfig{RequestFields: []string{"body"}},
+		},
+		{
+			desc: "zero sampling",
+			give: LoggingConfig{Sampling: &LogSamplingConfig{Initial: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			assert.Panics(t, func() {
+				NewDispatcher(Config{Name: "test", Logging: tt.give})
+			})
+		})
+	}
+}
+
+type drainableInbound struct {
+	transport.Inbound
+
+	drained chan struct{}
+}
+
+func (i *drainableInbound) Drain() { close(i.drained) }
+
+type unaryHandlerFunc func(context.Context, *transport.Request, transport.ResponseWriter) error
+
+func (f unaryHandlerFunc) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter) error {
+	return f(ctx, req, resw)
+}
+
+// newDrainingDispatcher builds a Dispatcher with the given drain timeout
+// whose "block" procedure blocks until the returned channel is closed.
+//
+// Metrics are registered globally so each test must use a different service
+// name.
+func newDrainingDispatcher(name string, timeout time.Duration, scope tally.Scope) (*Dispatcher, *drainableInbound, chan struct{}, chan struct{}) {
+	inbound := &drainableInbound{
+		Inbound: inmemory.NewTransport().NewInbound("test"),
+		drained: make(chan struct{}),
+	}
+	d := NewDispatcher(Config{
+		Name:         name,
+		Inbounds:     Inbounds{inbound},
+		Metrics:      MetricsConfig{Tally: scope},
+		DrainTimeout: timeout,
+	})
+
+	started, release := make(chan struct{}), make(chan struct{})
+	d.Register([]transport.Procedure{
+		{
+			Name: "block",
+			HandlerSpec: transport.NewUnaryHandlerSpec(unaryHandlerFunc(
+				func(context.Context, *transport.Request, transport.ResponseWriter) error {
+					close(started)
+					<-release
+					return nil
+				})),
+		},
+	})
+	return d, inbound, started, release
+}
+
+func callDispatcher(ctx context.Context, d *Dispatcher) error {
+	req := &transport.Request{
+		Caller:    "test",
+		Service:   d.Name(),
+		Encoding:  transport.Encoding("raw"),
+		Procedure: "block",
+	}
+	spec, err := d.Router().Choose(ctx, req)
+	if err != nil {
+		return err
+	}
+	return spec.Unary().Handle(ctx, req, new(transporttest.FakeResponseWriter))
+}
+
+func TestDispatcherDrain(t *testing.T) {
+	d, inbound, started, release := newDrainingDispatcher("drain-test", time.Minute, nil)
+	require.NoError(t, d.Start())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	callErr := make(chan error)
+	go func() { callErr <- callDispatcher(ctx, d) }()
+	<-started
+
+	stopErr := make(chan error)
+	go func() { stopErr <- d.Stop() }()
+
+	select {
+	case <-inbound.drained:
+	case <-time.After(time.Second):
+		t.Fatal("inbound was not drained")
+	}
+
+	// New requests are rejected while draining.
+	err := callDispatcher(ctx, d)
+	require.Error(t, err)
+	assert.Equal(t, yarpcerrors.CodeUnavailable, yarpcerrors.FromError(err).Code())
+
+	select {
+	case <-stopErr:
+		t.Fatal("Stop must wait for requests in flight")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(release)
+	assert.NoError(t, <-callErr)
+	assert.NoError(t, <-stopErr)
+}
+
+func TestDispatcherDrainTimeout(t *testing.T) {
+	scope := tally.NewTestScope("" /* prefix */, nil /* tags */)
+	d, inbound, started, release := newDrainingDispatcher("drain-timeout-test", 10*time.Millisecond, scope)
+	defer close(release)
+	require.NoError(t, d.Start())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	go callDispatcher(ctx, d)
+	<-started
+
+	require.NoError(t, d.Stop())
+	select {
+	case <-inbound.drained:
+	default:
+		t.Fatal("inbound was not drained")
+	}
+
+	var cutoffs int64
+	for _, c := range scope.Snapshot().Counters() {
+		if c.Name() == "drain_cutoffs" {
+			cutoffs += c.Value()
+		}
+	}
+	assert.Equal(t, int64(1), cutoffs, "expected the request in flight to be cut off")
+}
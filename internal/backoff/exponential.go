@@ -0,0 +1,80 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package backoff provides strategies for deciding how long to wait between
+// attempts of an operation.
+package backoff
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Exponential computes exponentially increasing backoff durations with full
+// jitter.
+//
+// The duration for an attempt is picked uniformly at random between zero and
+// first*2^attempt, capped to max. Spreading the durations out this way
+// prevents callers that failed at the same time from retrying in lockstep.
+type Exponential struct {
+	first time.Duration
+	max   time.Duration
+
+	// Returns a random number in [0, n). Overridden in tests.
+	rand func(n int64) int64
+}
+
+// NewExponential builds a new Exponential backoff strategy which starts at
+// first and never exceeds max.
+func NewExponential(first, max time.Duration) *Exponential {
+	return &Exponential{first: first, max: max, rand: rand.Int63n}
+}
+
+// Duration returns the amount of time to wait before the given attempt.
+// Attempts are counted from zero.
+func (e *Exponential) Duration(attempt uint) time.Duration {
+	limit := e.limit(attempt)
+	if limit <= 0 {
+		return 0
+	}
+	return time.Duration(e.rand(int64(limit)))
+}
+
+// limit returns the upper bound of the duration for the given attempt.
+func (e *Exponential) limit(attempt uint) time.Duration {
+	if e.first <= 0 {
+		return 0
+	}
+
+	limit := e.first
+	for i := uint(0); i < attempt; i++ {
+		// Stop doubling once we're past the cap. This also protects us
+		// from overflowing.
+		if limit >= e.max {
+			break
+		}
+		limit *= 2
+	}
+
+	if limit > e.max {
+		limit = e.max
+	}
+	return limit
+}
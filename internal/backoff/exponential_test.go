@@ -0,0 +1,83 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package backoff
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExponentialLimit(t *testing.T) {
+	tests := []struct {
+		first, max time.Duration
+		attempt    uint
+		want       time.Duration
+	}{
+		{first: 10 * time.Millisecond, max: time.Second, attempt: 0, want: 10 * time.Millisecond},
+		{first: 10 * time.Millisecond, max: time.Second, attempt: 1, want: 20 * time.Millisecond},
+		{first: 10 * time.Millisecond, max: time.Second, attempt: 3, want: 80 * time.Millisecond},
+		{first: 10 * time.Millisecond, max: time.Second, attempt: 7, want: time.Second},
+		{first: 10 * time.Millisecond, max: time.Second, attempt: 1000, want: time.Second},
+		{first: 2 * time.Second, max: time.Second, attempt: 0, want: time.Second},
+		{first: 0, max: time.Second, attempt: 5, want: 0},
+	}
+
+	for _, tt := range tests {
+		e := NewExponential(tt.first, tt.max)
+		assert.Equal(t, tt.want, e.limit(tt.attempt),
+			"limit(%v) with first=%v max=%v", tt.attempt, tt.first, tt.max)
+	}
+}
+
+func TestExponentialDuration(t *testing.T) {
+	e := NewExponential(10*time.Millisecond, time.Second)
+
+	var gotN int64
+	e.rand = func(n int64) int64 {
+		gotN = n
+		return n / 2
+	}
+
+	assert.Equal(t, 20*time.Millisecond, e.Duration(2))
+	assert.Equal(t, int64(40*time.Millisecond), gotN)
+}
+
+func TestExponentialDurationJitter(t *testing.T) {
+	e := NewExponential(10*time.Millisecond, 100*time.Millisecond)
+	for attempt := uint(0); attempt < 10; attempt++ {
+		for i := 0; i < 100; i++ {
+			d := e.Duration(attempt)
+			assert.True(t, d >= 0 && d < e.limit(attempt),
+				"duration %v for attempt %v is out of range", d, attempt)
+		}
+	}
+}
+
+func TestExponentialZero(t *testing.T) {
+	e := NewExponential(0, 0)
+	e.rand = func(int64) int64 {
+		t.Fatal("rand must not be called")
+		return 0
+	}
+	assert.Equal(t, time.Duration(0), e.Duration(3))
+}
@@ -49,4 +49,7 @@ func TestClientConfigPanic(t *testing.T) {
 
 	assert.Panics(t, func() { c.GetOnewayOutbound() },
 		"expected ClientConfig to panic for nil OnewayOutbound")
+
+	assert.Panics(t, func() { c.(transport.StreamClientConfig).GetStreamOutbound() },
+		"expected ClientConfig to panic for nil StreamOutbound")
 }
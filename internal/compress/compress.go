@@ -0,0 +1,127 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package compress implements the parts of request and response compression
+// that are shared by all transports.
+package compress
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/iopool"
+
+	"go.uber.org/multierr"
+)
+
+// DefaultMinSize is the size, in bytes, under which bodies are sent
+// uncompressed unless configured otherwise.
+const DefaultMinSize = 1024
+
+// Identity is the name of the encoding of uncompressed bodies.
+const Identity = "identity"
+
+// Compress compresses body using the given compressor unless it is smaller
+// than minSize. Returns the body as it should be sent and whether it was
+// compressed.
+func Compress(c transport.Compressor, minSize int, body []byte) ([]byte, bool, error) {
+	if len(body) < minSize {
+		return body, false, nil
+	}
+
+	var buf bytes.Buffer
+	w, err := c.Compress(&buf)
+	if err != nil {
+		return nil, false, err
+	}
+	if _, err := w.Write(body); err != nil {
+		return nil, false, multierr.Append(err, w.Close())
+	}
+	if err := w.Close(); err != nil {
+		return nil, false, err
+	}
+	return buf.Bytes(), true, nil
+}
+
+// CompressReader reads the body from r and compresses it using the given
+// compressor unless it is smaller than minSize.
+func CompressReader(c transport.Compressor, minSize int, r io.Reader) ([]byte, bool, error) {
+	var buf bytes.Buffer
+	if _, err := iopool.Copy(&buf, r); err != nil {
+		return nil, false, err
+	}
+	return Compress(c, minSize, buf.Bytes())
+}
+
+// Decompress returns a reader that decompresses the data read from body
+// using the given compressor. Closing the returned reader closes body.
+func Decompress(c transport.Compressor, body io.ReadCloser) (io.ReadCloser, error) {
+	r, err := c.Decompress(body)
+	if err != nil {
+		return nil, err
+	}
+	return &readCloser{Reader: r, closers: []io.Closer{r, body}}, nil
+}
+
+type readCloser struct {
+	io.Reader
+
+	closers []io.Closer
+}
+
+func (r *readCloser) Close() (err error) {
+	for _, c := range r.closers {
+		err = multierr.Append(err, c.Close())
+	}
+	return err
+}
+
+// IsIdentity returns true if the given encoding, as found in a
+// Content-Encoding header or its equivalent, denotes an uncompressed body.
+func IsIdentity(encoding string) bool {
+	return encoding == "" || encoding == Identity
+}
+
+// Accepts returns true if the given list of encodings, formatted like the
+// value of an Accept-Encoding header, includes the named encoding.
+// Parameters such as quality values are ignored except that an encoding with
+// a quality value of zero is not accepted.
+func Accepts(accept, name string) bool {
+	for _, enc := range strings.Split(accept, ",") {
+		params := strings.Split(enc, ";")
+		if strings.TrimSpace(params[0]) != name {
+			continue
+		}
+
+		accepted := true
+		for _, p := range params[1:] {
+			p = strings.Replace(p, " ", "", -1)
+			if p == "q=0" || strings.HasPrefix(p, "q=0.") && strings.Trim(p[4:], "0") == "" {
+				accepted = false
+			}
+		}
+		if accepted {
+			return true
+		}
+	}
+	return false
+}
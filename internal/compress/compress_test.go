@@ -0,0 +1,98 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package compress
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"go.uber.org/yarpc/compressor/gzip"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompress(t *testing.T) {
+	body := []byte(strings.Repeat("hello world ", 100))
+
+	tests := []struct {
+		desc           string
+		minSize        int
+		wantCompressed bool
+	}{
+		{desc: "no minimum size", wantCompressed: true},
+		{desc: "larger than minimum size", minSize: 10, wantCompressed: true},
+		{desc: "exactly minimum size", minSize: len(body), wantCompressed: true},
+		{desc: "smaller than minimum size", minSize: len(body) + 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			c := gzip.New()
+			got, compressed, err := CompressReader(c, tt.minSize, bytes.NewReader(body))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantCompressed, compressed)
+			if !compressed {
+				assert.Equal(t, body, got)
+				return
+			}
+			assert.True(t, len(got) < len(body), "expected compressed body to be smaller")
+
+			r, err := Decompress(c, ioutil.NopCloser(bytes.NewReader(got)))
+			require.NoError(t, err)
+			decompressed, err := ioutil.ReadAll(r)
+			require.NoError(t, err)
+			assert.NoError(t, r.Close())
+			assert.Equal(t, body, decompressed)
+		})
+	}
+}
+
+func TestIsIdentity(t *testing.T) {
+	assert.True(t, IsIdentity(""))
+	assert.True(t, IsIdentity("identity"))
+	assert.False(t, IsIdentity("gzip"))
+}
+
+func TestAccepts(t *testing.T) {
+	tests := []struct {
+		accept string
+		name   string
+		want   bool
+	}{
+		{accept: "", name: "gzip"},
+		{accept: "gzip", name: "gzip", want: true},
+		{accept: "snappy", name: "gzip"},
+		{accept: "snappy, gzip", name: "gzip", want: true},
+		{accept: "gzip;q=0.5", name: "gzip", want: true},
+		{accept: "gzip; q=0", name: "gzip"},
+		{accept: "gzip;q=0.000", name: "gzip"},
+		{accept: "gzip;q=0, gzip", name: "gzip", want: true},
+		{accept: "gzipped", name: "gzip"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Accepts(tt.accept, tt.name),
+			"Accepts(%q, %q)", tt.accept, tt.name)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package drain provides inbound middleware that tracks the requests in
+// flight for a Dispatcher so that it may wait for them to finish before it
+// is stopped.
+package drain
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// Tracker is inbound middleware for all RPC types that counts the requests
+// in flight. Once the Tracker starts draining, it rejects new requests with
+// an Unavailable error.
+type Tracker struct {
+	service string
+
+	mu       sync.Mutex
+	inflight int
+	draining bool
+
+	// Closed when the number of requests in flight drops to zero. This is
+	// nil if nobody is waiting.
+	idle chan struct{}
+}
+
+// NewTracker builds a new Tracker for the service with the given name.
+func NewTracker(service string) *Tracker {
+	return &Tracker{service: service}
+}
+
+// Drain makes the Tracker reject new requests.
+func (t *Tracker) Drain() {
+	t.mu.Lock()
+	t.draining = true
+	t.mu.Unlock()
+}
+
+// Resume makes the Tracker accept new requests again.
+func (t *Tracker) Resume() {
+	t.mu.Lock()
+	t.draining = false
+	t.mu.Unlock()
+}
+
+// Inflight returns the number of requests currently in flight.
+func (t *Tracker) Inflight() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.inflight
+}
+
+// Wait blocks until there are no requests in flight or the context
+// finishes. It returns the number of requests still in flight.
+func (t *Tracker) Wait(ctx context.Context) int {
+	t.mu.Lock()
+	if t.inflight == 0 {
+		t.mu.Unlock()
+		return 0
+	}
+	if t.idle == nil {
+		t.idle = make(chan struct{})
+	}
+	idle := t.idle
+	t.mu.Unlock()
+
+	select {
+	case <-idle:
+		return 0
+	case <-ctx.Done():
+		return t.Inflight()
+	}
+}
+
+// begin records the start of a request. It returns an error if the Tracker
+// is draining.
+func (t *Tracker) begin(req *transport.Request) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.draining {
+		return yarpcerrors.Newf(yarpcerrors.CodeUnavailable,
+			"service %q is shutting down and cannot handle procedure %q", t.service, req.Procedure)
+	}
+	t.inflight++
+	return nil
+}
+
+func (t *Tracker) end() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.inflight--
+	if t.inflight == 0 && t.idle != nil {
+		close(t.idle)
+		t.idle = nil
+	}
+}
+
+// Handle implements middleware.UnaryInbound.
+func (t *Tracker) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter, h transport.UnaryHandler) error {
+	if err := t.begin(req); err != nil {
+		return err
+	}
+	defer t.end()
+	return h.Handle(ctx, req, resw)
+}
+
+// HandleOneway implements middleware.OnewayInbound.
+func (t *Tracker) HandleOneway(ctx context.Context, req *transport.Request, h transport.OnewayHandler) error {
+	if err := t.begin(req); err != nil {
+		return err
+	}
+	defer t.end()
+	return h.HandleOneway(ctx, req)
+}
+
+// HandleStream implements middleware.StreamInbound.
+func (t *Tracker) HandleStream(s transport.Stream, h transport.StreamHandler) error {
+	if err := t.begin(s.Request()); err != nil {
+		return err
+	}
+	defer t.end()
+	return h.HandleStream(s)
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package drain
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/yarpcerrors"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type unaryHandlerFunc func(context.Context, *transport.Request, transport.ResponseWriter) error
+
+func (f unaryHandlerFunc) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter) error {
+	return f(ctx, req, resw)
+}
+
+func TestTracker(t *testing.T) {
+	tracker := NewTracker("myservice")
+	req := &transport.Request{Procedure: "hello"}
+
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := unaryHandlerFunc(func(context.Context, *transport.Request, transport.ResponseWriter) error {
+		close(started)
+		<-release
+		return nil
+	})
+
+	done := make(chan error)
+	go func() {
+		done <- tracker.Handle(context.Background(), req, new(transporttest.FakeResponseWriter), handler)
+	}()
+	<-started
+	assert.Equal(t, 1, tracker.Inflight())
+
+	tracker.Drain()
+	err := tracker.Handle(context.Background(), req, new(transporttest.FakeResponseWriter), handler)
+	require.Error(t, err, "requests must be rejected while draining")
+	assert.Equal(t, yarpcerrors.CodeUnavailable, yarpcerrors.FromError(err).Code())
+	assert.Contains(t, err.Error(), `service "myservice" is shutting down`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, 1, tracker.Wait(ctx), "wait must time out with the request in flight")
+
+	close(release)
+	require.NoError(t, <-done)
+	assert.Equal(t, 0, tracker.Wait(context.Background()), "no requests must be in flight")
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	tracker.Resume()
+	oneway := transporttest.NewMockOnewayHandler(mockCtrl)
+	oneway.EXPECT().HandleOneway(gomock.Any(), req).Return(nil)
+	assert.NoError(t, tracker.HandleOneway(context.Background(), req, oneway), "requests must be accepted after resuming")
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package health defines the procedure through which transports query the
+// health service registered on a Dispatcher with x/yarpchealth. Transports
+// use it to expose the health of the service over their native health
+// checking protocols.
+package health
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go.uber.org/yarpc/api/transport"
+)
+
+const (
+	// Procedure is the name of the health procedure.
+	Procedure = "yarpc::health"
+
+	// Encoding is the encoding of the health procedure.
+	Encoding transport.Encoding = "json"
+
+	// Caller is the name of the caller used by transports when they query
+	// the health procedure.
+	Caller = "yarpc-health"
+)
+
+// Statuses reported by the health procedure.
+const (
+	Serving        = "SERVING"
+	NotServing     = "NOT_SERVING"
+	ServiceUnknown = "SERVICE_UNKNOWN"
+)
+
+// Request is the body of a request to the health procedure.
+type Request struct {
+	// Name of the service whose health is requested. If empty, the health
+	// of the Dispatcher as a whole is requested.
+	Service string `json:"service"`
+}
+
+// Response is the body of a response from the health procedure.
+type Response struct {
+	Status string `json:"status"`
+}
+
+// Registered returns true if the health procedure is registered with the
+// given router.
+func Registered(router transport.Router) bool {
+	_, ok := lookup(router)
+	return ok
+}
+
+// Check queries the health procedure registered with the given router for
+// the status of the given service. It returns false if the health procedure
+// is not registered.
+//
+// Transports should treat errors as the service not serving.
+func Check(ctx context.Context, router transport.Router, service string) (status string, ok bool, err error) {
+	p, ok := lookup(router)
+	if !ok {
+		return "", false, nil
+	}
+
+	body, err := json.Marshal(Request{Service: service})
+	if err != nil {
+		return "", true, err
+	}
+
+	req := &transport.Request{
+		Caller:    Caller,
+		Service:   p.Service,
+		Procedure: Procedure,
+		Encoding:  Encoding,
+		Body:      bytes.NewReader(body),
+	}
+	spec, err := router.Choose(ctx, req)
+	if err != nil {
+		return "", true, err
+	}
+	if spec.Type() != transport.Unary {
+		return "", true, fmt.Errorf("health procedure must be unary, found %v", spec.Type())
+	}
+
+	var resw responseWriter
+	if err := spec.Unary().Handle(ctx, req, &resw); err != nil {
+		return "", true, err
+	}
+	if resw.isApplicationError {
+		return "", true, fmt.Errorf("health procedure failed: %s", resw.body.String())
+	}
+
+	var res Response
+	if err := json.Unmarshal(resw.body.Bytes(), &res); err != nil {
+		return "", true, fmt.Errorf("failed to decode health response: %v", err)
+	}
+	return res.Status, true, nil
+}
+
+func lookup(router transport.Router) (transport.Procedure, bool) {
+	if router == nil {
+		return transport.Procedure{}, false
+	}
+	for _, p := range router.Procedures() {
+		if p.Name == Procedure && (p.Encoding == Encoding || p.Encoding == "") {
+			return p, true
+		}
+	}
+	return transport.Procedure{}, false
+}
+
+// responseWriter records the response of the health procedure.
+type responseWriter struct {
+	body               bytes.Buffer
+	isApplicationError bool
+}
+
+var _ transport.ResponseWriter = (*responseWriter)(nil)
+
+func (w *responseWriter) Write(b []byte) (int, error)  { return w.body.Write(b) }
+func (w *responseWriter) AddHeaders(transport.Headers) {}
+func (w *responseWriter) SetApplicationError()         { w.isApplicationError = true }
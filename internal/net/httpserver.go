@@ -21,6 +21,7 @@
 package net
 
 import (
+	"crypto/tls"
 	"errors"
 	"net"
 	"net/http"
@@ -64,7 +65,9 @@ func (h *HTTPServer) Listener() net.Listener {
 
 // ListenAndServe starts the given HTTP server up in the background and
 // returns immediately. The server listens on the configured Addr or ":http"
-// if unconfigured.
+// if unconfigured. Addr may refer to a unix domain socket as described in
+// Listen. If the server has a TLSConfig, connections accepted by the listener
+// are served over TLS.
 //
 // An error is returned if the server failed to start up, if the server was
 // already listening, or if the server was stopped with Stop().
@@ -86,10 +89,13 @@ func (h *HTTPServer) ListenAndServe() error {
 	}
 
 	var err error
-	h.listener, err = net.Listen("tcp", addr)
+	h.listener, err = Listen(addr)
 	if err != nil {
 		return err
 	}
+	if h.Server.TLSConfig != nil {
+		h.listener = tls.NewListener(h.listener, h.Server.TLSConfig)
+	}
 
 	go h.serve(h.listener)
 	return nil
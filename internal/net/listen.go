@@ -0,0 +1,109 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package net
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// unixPrefix is the prefix of addresses which refer to unix domain sockets,
+// as in "unix:///var/run/myservice.sock".
+const unixPrefix = "unix://"
+
+// staleSocketTimeout is the time allowed to connect to an existing unix
+// domain socket before it is considered stale.
+const staleSocketTimeout = time.Second
+
+// SplitAddress splits the given address into the network and address
+// expected by net.Dial and net.Listen.
+//
+// Addresses of the form "unix:///path/to/socket" refer to unix domain
+// sockets. All other addresses are TCP "host:port" addresses.
+func SplitAddress(addr string) (network, address string) {
+	if strings.HasPrefix(addr, unixPrefix) {
+		return "unix", strings.TrimPrefix(addr, unixPrefix)
+	}
+	return "tcp", addr
+}
+
+// IsUnixAddress returns true if the given address refers to a unix domain
+// socket.
+func IsUnixAddress(addr string) bool {
+	return strings.HasPrefix(addr, unixPrefix)
+}
+
+// Listen listens on the given address, which may be a TCP "host:port"
+// address or a "unix:///path/to/socket" address.
+//
+// If a unix domain socket already exists at the given path but nothing is
+// accepting connections on it, it was left behind by a process that did not
+// shut down cleanly and is removed before listening.
+func Listen(addr string) (net.Listener, error) {
+	network, address := SplitAddress(addr)
+	if network == "unix" {
+		if err := removeStaleSocket(address); err != nil {
+			return nil, err
+		}
+	}
+	return net.Listen(network, address)
+}
+
+// removeStaleSocket removes the unix domain socket at the given path if no
+// process is accepting connections on it.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("cannot listen on %q: file exists and is not a socket", path)
+	}
+
+	conn, err := net.DialTimeout("unix", path, staleSocketTimeout)
+	if err == nil {
+		// The socket is in use. Let Listen report the error.
+		return conn.Close()
+	}
+	if !isConnRefused(err) {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func isConnRefused(err error) bool {
+	oe, ok := err.(*net.OpError)
+	if !ok {
+		return false
+	}
+	se, ok := oe.Err.(*os.SyscallError)
+	return ok && se.Err == syscall.ECONNREFUSED
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package net
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitAddress(t *testing.T) {
+	tests := []struct {
+		give        string
+		wantNetwork string
+		wantAddress string
+	}{
+		{give: ":8080", wantNetwork: "tcp", wantAddress: ":8080"},
+		{give: "127.0.0.1:8080", wantNetwork: "tcp", wantAddress: "127.0.0.1:8080"},
+		{give: "unix:///var/run/foo.sock", wantNetwork: "unix", wantAddress: "/var/run/foo.sock"},
+		{give: "unix://foo.sock", wantNetwork: "unix", wantAddress: "foo.sock"},
+	}
+
+	for _, tt := range tests {
+		network, address := SplitAddress(tt.give)
+		assert.Equal(t, tt.wantNetwork, network, "network of %q", tt.give)
+		assert.Equal(t, tt.wantAddress, address, "address of %q", tt.give)
+		assert.Equal(t, tt.wantNetwork == "unix", IsUnixAddress(tt.give), "IsUnixAddress(%q)", tt.give)
+	}
+}
+
+func tempSocket(t *testing.T) (path string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "yarpc-unix")
+	require.NoError(t, err)
+	return filepath.Join(dir, "test.sock"), func() { os.RemoveAll(dir) }
+}
+
+func TestListenUnix(t *testing.T) {
+	path, cleanup := tempSocket(t)
+	defer cleanup()
+
+	l, err := Listen("unix://" + path)
+	require.NoError(t, err)
+	assert.Equal(t, "unix", l.Addr().Network())
+
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	_, err = Listen("unix://" + path)
+	assert.Error(t, err, "expected listening on a socket in use to fail")
+
+	require.NoError(t, l.Close())
+	_, err = os.Lstat(path)
+	assert.True(t, os.IsNotExist(err), "socket must be removed after the listener is closed")
+}
+
+func TestListenUnixStaleSocket(t *testing.T) {
+	path, cleanup := tempSocket(t)
+	defer cleanup()
+
+	// Leave a socket behind as a process that did not shut down cleanly
+	// would.
+	ul, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	require.NoError(t, err)
+	ul.SetUnlinkOnClose(false)
+	require.NoError(t, ul.Close())
+	_, err = os.Lstat(path)
+	require.NoError(t, err, "socket must still exist")
+
+	l, err := Listen("unix://" + path)
+	require.NoError(t, err, "expected stale socket to be removed")
+	assert.NoError(t, l.Close())
+}
+
+func TestListenUnixNotASocket(t *testing.T) {
+	path, cleanup := tempSocket(t)
+	defer cleanup()
+	require.NoError(t, ioutil.WriteFile(path, []byte("hello"), 0644))
+
+	_, err := Listen("unix://" + path)
+	assert.Error(t, err)
+
+	_, err = os.Lstat(path)
+	assert.NoError(t, err, "file must not be removed")
+}
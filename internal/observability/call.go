@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/yarpcerrors"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -101,6 +102,14 @@ func (c call) endStats(elapsed time.Duration, err error, isApplicationError bool
 	}
 	// For now, assume that all other errors are the server's fault.
 	c.edge.serverErrLatencies.Observe(elapsed)
+	// Requests rejected because the server is overloaded, for example, by
+	// concurrency limits.
+	if yarpcerrors.FromError(err).Code() == yarpcerrors.CodeResourceExhausted {
+		if counter, err := c.edge.serverFailures.Get("resource_exhausted"); err == nil {
+			counter.Inc()
+		}
+		return
+	}
 	if transport.IsUnexpectedError(err) {
 		if counter, err := c.edge.serverFailures.Get("unexpected"); err == nil {
 			counter.Inc()
@@ -136,6 +136,9 @@ type graph struct {
 	logger  *zap.Logger
 	extract ContextExtractor
 
+	// Names of the request fields included in request logs, in order.
+	fields []string
+
 	edgesMu sync.RWMutex
 	edges   map[string]*edge
 }
@@ -146,6 +149,7 @@ func newGraph(reg *pally.Registry, logger *zap.Logger, extract ContextExtractor)
 		reg:     reg,
 		logger:  logger,
 		extract: extract,
+		fields:  _defaultRequestFields,
 	}
 }
 
@@ -199,7 +203,7 @@ func (g *graph) createEdge(key []byte, req *transport.Request) *edge {
 		return e
 	}
 
-	e := newEdge(g.logger, g.reg, req)
+	e := newEdge(g.logger, g.reg, req, g.fields)
 	g.edges[string(key)] = e
 	return e
 }
@@ -221,7 +225,7 @@ type edge struct {
 
 // newEdge constructs a new edge. Since Registries enforce metric uniqueness,
 // edges should be cached and re-used for each RPC.
-func newEdge(logger *zap.Logger, reg *pally.Registry, req *transport.Request) *edge {
+func newEdge(logger *zap.Logger, reg *pally.Registry, req *transport.Request, fields []string) *edge {
 	labels := pally.Labels{
 		"source":           pally.ScrubLabelValue(req.Caller),
 		"dest":             pally.ScrubLabelValue(req.Service),
@@ -308,15 +312,7 @@ func newEdge(logger *zap.Logger, reg *pally.Registry, req *transport.Request) *e
 		logger.Error("Failed to create server failure latency distribution.", zap.Error(err))
 		serverErrLatencies = pally.NewNopLatencies()
 	}
-	logger = logger.With(
-		zap.String("source", req.Caller),
-		zap.String("dest", req.Service),
-		zap.String("procedure", req.Procedure),
-		zap.String("encoding", string(req.Encoding)),
-		zap.String("shardKey", req.ShardKey),
-		zap.String("routingKey", req.RoutingKey),
-		zap.String("routingDelegate", req.RoutingDelegate),
-	)
+	logger = logger.With(requestLogFields(req, fields)...)
 	return &edge{
 		logger:             logger,
 		calls:              calls,
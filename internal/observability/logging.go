@@ -0,0 +1,148 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package observability
+
+import (
+	"fmt"
+
+	"go.uber.org/yarpc/api/transport"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Names of the request fields added to request logs.
+const (
+	_sourceField          = "source"
+	_destField            = "dest"
+	_procedureField       = "procedure"
+	_encodingField        = "encoding"
+	_shardKeyField        = "shardKey"
+	_routingKeyField      = "routingKey"
+	_routingDelegateField = "routingDelegate"
+)
+
+// _defaultRequestFields is the order in which request fields are logged.
+var _defaultRequestFields = []string{
+	_sourceField,
+	_destField,
+	_procedureField,
+	_encodingField,
+	_shardKeyField,
+	_routingKeyField,
+	_routingDelegateField,
+}
+
+// ValidateRequestFields returns an error if any of the given names is not a
+// request field that may be logged.
+func ValidateRequestFields(fields []string) error {
+	for _, f := range fields {
+		if !isRequestField(f) {
+			return fmt.Errorf("unknown request field %q: must be one of %v", f, _defaultRequestFields)
+		}
+	}
+	return nil
+}
+
+// MiddlewareOption customizes the behavior of a Middleware.
+type MiddlewareOption func(*graph)
+
+// RequestFields specifies the fields of each request that are included in
+// request logs, in order. By default, all request fields are logged.
+//
+// Unknown field names are ignored. Use ValidateRequestFields to check the
+// list beforehand.
+func RequestFields(fields []string) MiddlewareOption {
+	return func(g *graph) {
+		g.fields = make([]string, 0, len(fields))
+		for _, f := range fields {
+			if isRequestField(f) {
+				g.fields = append(g.fields, f)
+			}
+		}
+	}
+}
+
+func isRequestField(name string) bool {
+	for _, f := range _defaultRequestFields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// requestLogFields builds the log fields for the given request.
+func requestLogFields(req *transport.Request, fields []string) []zapcore.Field {
+	out := make([]zapcore.Field, 0, len(fields))
+	for _, f := range fields {
+		out = append(out, requestLogField(req, f))
+	}
+	return out
+}
+
+func requestLogField(req *transport.Request, name string) zapcore.Field {
+	switch name {
+	case _sourceField:
+		return zap.String(name, req.Caller)
+	case _destField:
+		return zap.String(name, req.Service)
+	case _procedureField:
+		return zap.String(name, req.Procedure)
+	case _encodingField:
+		return zap.String(name, string(req.Encoding))
+	case _shardKeyField:
+		return zap.String(name, req.ShardKey)
+	case _routingKeyField:
+		return zap.String(name, req.RoutingKey)
+	case _routingDelegateField:
+		return zap.String(name, req.RoutingDelegate)
+	default:
+		return zap.Skip()
+	}
+}
+
+// NewLevelFilter wraps the given core so that it drops all logs below the
+// given level, even if the core is enabled for them.
+func NewLevelFilter(core zapcore.Core, level zapcore.Level) zapcore.Core {
+	return levelFilter{Core: core, level: level}
+}
+
+type levelFilter struct {
+	zapcore.Core
+
+	level zapcore.Level
+}
+
+func (c levelFilter) Enabled(lvl zapcore.Level) bool {
+	return lvl >= c.level && c.Core.Enabled(lvl)
+}
+
+func (c levelFilter) With(fields []zapcore.Field) zapcore.Core {
+	return levelFilter{Core: c.Core.With(fields), level: c.level}
+}
+
+func (c levelFilter) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level < c.level {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
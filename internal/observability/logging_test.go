@@ -0,0 +1,135 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package observability
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/internal/pally"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestValidateRequestFields(t *testing.T) {
+	assert.NoError(t, ValidateRequestFields(nil))
+	assert.NoError(t, ValidateRequestFields(_defaultRequestFields))
+
+	err := ValidateRequestFields([]string{"source", "body"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown request field "body"`)
+}
+
+func TestMiddlewareRequestFields(t *testing.T) {
+	defer stubTime()()
+	req := &transport.Request{
+		Caller:    "caller",
+		Service:   "service",
+		Encoding:  "raw",
+		Procedure: "procedure",
+		ShardKey:  "shard01",
+		Body:      strings.NewReader("body"),
+	}
+
+	tests := []struct {
+		desc       string
+		give       []string
+		wantFields []zapcore.Field
+	}{
+		{
+			desc:       "none",
+			give:       []string{},
+			wantFields: []zapcore.Field{},
+		},
+		{
+			desc: "subset in order",
+			give: []string{"procedure", "source", "unknown"},
+			wantFields: []zapcore.Field{
+				zap.String("procedure", req.Procedure),
+				zap.String("source", req.Caller),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			core, logs := observer.New(zapcore.DebugLevel)
+			mw := NewMiddleware(zap.New(core), pally.NewRegistry(), NewNopContextExtractor(), RequestFields(tt.give))
+
+			require.NoError(t, mw.Handle(
+				context.Background(),
+				req,
+				&transporttest.FakeResponseWriter{},
+				fakeHandler{},
+			))
+
+			entries := logs.TakeAll()
+			require.Len(t, entries, 1)
+
+			wantContext := append(tt.wantFields,
+				zap.String("rpcType", "Unary"),
+				zap.Duration("latency", 0),
+				zap.Bool("successful", true),
+				zap.Skip(),
+				zap.Skip(),
+			)
+			assert.Equal(t, wantContext, entries[0].Context)
+		})
+	}
+}
+
+func TestLevelFilter(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(NewLevelFilter(core, zapcore.WarnLevel)).With(zap.String("foo", "bar"))
+
+	assert.False(t, logger.Core().Enabled(zapcore.InfoLevel), "info must be disabled")
+	assert.True(t, logger.Core().Enabled(zapcore.ErrorLevel), "error must be enabled")
+
+	logger.Debug("debug")
+	logger.Info("info")
+	logger.Warn("warn")
+	logger.Error("error")
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "warn", entries[0].Entry.Message)
+	assert.Equal(t, "error", entries[1].Entry.Message)
+	assert.Equal(t, []zapcore.Field{zap.String("foo", "bar")}, entries[1].Context)
+}
+
+func TestLevelFilterRespectsCore(t *testing.T) {
+	core, logs := observer.New(zapcore.ErrorLevel)
+	logger := zap.New(NewLevelFilter(core, zapcore.DebugLevel))
+
+	logger.Debug("debug")
+	logger.Error("error")
+
+	entries := logs.TakeAll()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "error", entries[0].Entry.Message)
+}
@@ -64,8 +64,12 @@ type Middleware struct {
 }
 
 // NewMiddleware constructs a Middleware.
-func NewMiddleware(logger *zap.Logger, reg *pally.Registry, extract ContextExtractor) *Middleware {
-	return &Middleware{newGraph(reg, logger, extract)}
+func NewMiddleware(logger *zap.Logger, reg *pally.Registry, extract ContextExtractor, opts ...MiddlewareOption) *Middleware {
+	m := &Middleware{newGraph(reg, logger, extract)}
+	for _, opt := range opts {
+		opt(&m.graph)
+	}
+	return m
 }
 
 // Handle implements middleware.UnaryInbound.
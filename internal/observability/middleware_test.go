@@ -33,6 +33,7 @@ import (
 	"go.uber.org/yarpc/api/transport/transporttest"
 	"go.uber.org/yarpc/internal/pally"
 	"go.uber.org/yarpc/internal/pally/pallytest"
+	"go.uber.org/yarpc/yarpcerrors"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -299,3 +300,33 @@ func TestMiddlewareStats(t *testing.T) {
 
 	pallytest.AssertPrometheus(t, reg, strings.TrimSpace(string(expected)))
 }
+
+func TestMiddlewareStatsResourceExhausted(t *testing.T) {
+	defer stubTime()()
+	reg := pally.NewRegistry()
+	mw := NewMiddleware(zap.NewNop(), reg, NewNopContextExtractor())
+
+	err := mw.Handle(
+		context.Background(),
+		&transport.Request{
+			Caller:    "caller",
+			Service:   "service",
+			Encoding:  "raw",
+			Procedure: "procedure",
+			Body:      strings.NewReader("body"),
+		},
+		&transporttest.FakeResponseWriter{},
+		fakeHandler{yarpcerrors.Newf(yarpcerrors.CodeResourceExhausted, "too many requests"), false},
+	)
+	assert.Error(t, err, "Expected an error from middleware.")
+
+	_, body := pallytest.Scrape(t, reg)
+	var found bool
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, "server_failures{") && strings.Contains(line, `error="resource_exhausted"`) {
+			found = true
+			assert.True(t, strings.HasSuffix(line, " 1"), "Unexpected server failures count: %v", line)
+		}
+	}
+	assert.True(t, found, "Expected resource exhausted failures to be counted.")
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package peerupdate
+
+// Loop runs a refresh loop in its own goroutine until it is stopped.
+type Loop struct {
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// NewLoop builds a Loop which has not started yet.
+func NewLoop() *Loop {
+	return &Loop{
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// Go runs f in a new goroutine. f must return once the channel it is given
+// is closed.
+//
+// Go must be called at most once.
+func (l *Loop) Go(f func(stop <-chan struct{})) {
+	go func() {
+		defer close(l.stopped)
+		f(l.stop)
+	}()
+}
+
+// Stop asks the goroutine started by Go to return and waits until it has.
+//
+// Stop must be called at most once, and only after Go.
+func (l *Loop) Stop() {
+	close(l.stop)
+	<-l.stopped
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package peerupdate holds the pieces shared by peer list updaters which
+// periodically look up a set of peers and keep a peer list in sync with it.
+package peerupdate
+
+import (
+	"sort"
+
+	"go.uber.org/yarpc/api/peer"
+)
+
+// Peers tracks the peers that an updater has added to a peer list. It sends
+// the peer list only the peers that were added or removed.
+//
+// Peers is not safe for concurrent use; updaters guard it with their own
+// lock.
+type Peers struct {
+	pl    peer.List
+	peers map[string]peer.Identifier
+}
+
+// NewPeers builds a Peers for the given peer list, starting with no peers.
+func NewPeers(pl peer.List) *Peers {
+	return &Peers{pl: pl, peers: make(map[string]peer.Identifier)}
+}
+
+// Set sends the peer list the changes needed to go from the current peers to
+// the given ones.
+//
+// The current peers are replaced only if the peer list accepts the changes,
+// so the next call to Set retries a failed update.
+func (p *Peers) Set(pids []peer.Identifier) error {
+	peers := make(map[string]peer.Identifier, len(pids))
+	for _, pid := range pids {
+		peers[pid.Identifier()] = pid
+	}
+
+	updates := diff(p.peers, peers)
+	if len(updates.Additions) > 0 || len(updates.Removals) > 0 {
+		if err := p.pl.Update(updates); err != nil {
+			return err
+		}
+	}
+	p.peers = peers
+	return nil
+}
+
+// RemoveAll removes all current peers from the peer list.
+func (p *Peers) RemoveAll() error {
+	removals := make([]peer.Identifier, 0, len(p.peers))
+	for _, pid := range p.peers {
+		removals = append(removals, pid)
+	}
+	sort.Sort(byIdentifier(removals))
+
+	p.peers = make(map[string]peer.Identifier)
+	return p.pl.Update(peer.ListUpdates{Removals: removals})
+}
+
+// diff returns the updates needed to go from the old set of peers to the new
+// one. Removals and additions are sorted by identifier.
+func diff(old, new map[string]peer.Identifier) peer.ListUpdates {
+	var updates peer.ListUpdates
+	for id, pid := range old {
+		if _, ok := new[id]; !ok {
+			updates.Removals = append(updates.Removals, pid)
+		}
+	}
+	for id, pid := range new {
+		if _, ok := old[id]; !ok {
+			updates.Additions = append(updates.Additions, pid)
+		}
+	}
+	sort.Sort(byIdentifier(updates.Removals))
+	sort.Sort(byIdentifier(updates.Additions))
+	return updates
+}
+
+type byIdentifier []peer.Identifier
+
+func (ps byIdentifier) Len() int           { return len(ps) }
+func (ps byIdentifier) Swap(i, j int)      { ps[i], ps[j] = ps[j], ps[i] }
+func (ps byIdentifier) Less(i, j int) bool { return ps[i].Identifier() < ps[j].Identifier() }
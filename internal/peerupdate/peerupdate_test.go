@@ -0,0 +1,93 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package peerupdate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/peer/peertest"
+	"go.uber.org/yarpc/peer/hostport"
+)
+
+func pids(ids ...string) []peer.Identifier {
+	pids := make([]peer.Identifier, len(ids))
+	for i, id := range ids {
+		pids[i] = hostport.PeerIdentifier(id)
+	}
+	return pids
+}
+
+func TestPeersSet(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	pl := peertest.NewMockList(mockCtrl)
+	p := NewPeers(pl)
+
+	pl.EXPECT().Update(peer.ListUpdates{Additions: pids("a:80", "b:80")}).Return(nil)
+	assert.NoError(t, p.Set(pids("b:80", "a:80", "b:80")))
+
+	// No changes, so the peer list is left alone.
+	assert.NoError(t, p.Set(pids("a:80", "b:80")))
+
+	pl.EXPECT().Update(peer.ListUpdates{
+		Additions: pids("c:80", "d:80"),
+		Removals:  pids("a:80"),
+	}).Return(nil)
+	assert.NoError(t, p.Set(pids("d:80", "b:80", "c:80")))
+
+	pl.EXPECT().Update(peer.ListUpdates{Removals: pids("b:80", "c:80", "d:80")}).Return(nil)
+	assert.NoError(t, p.RemoveAll())
+}
+
+func TestPeersSetRetriesFailedUpdates(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	pl := peertest.NewMockList(mockCtrl)
+	p := NewPeers(pl)
+
+	pl.EXPECT().Update(peer.ListUpdates{Additions: pids("a:80")}).Return(nil)
+	assert.NoError(t, p.Set(pids("a:80")))
+
+	update := peer.ListUpdates{Additions: pids("b:80"), Removals: pids("a:80")}
+	gomock.InOrder(
+		pl.EXPECT().Update(update).Return(errors.New("great sadness")),
+		pl.EXPECT().Update(update).Return(nil),
+	)
+	assert.Error(t, p.Set(pids("b:80")))
+	assert.NoError(t, p.Set(pids("b:80")))
+}
+
+func TestLoop(t *testing.T) {
+	l := NewLoop()
+	stopped := false
+	l.Go(func(stop <-chan struct{}) {
+		<-stop
+		stopped = true
+	})
+	l.Stop()
+	assert.True(t, stopped, "Stop must wait for the goroutine to return")
+}
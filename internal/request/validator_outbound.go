@@ -70,3 +70,23 @@ func (o OnewayValidatorOutbound) Introspect() introspection.OutboundStatus {
 	}
 	return introspection.OutboundStatusNotSupported
 }
+
+// StreamValidatorOutbound wraps an Outbound to validate all outgoing streaming requests.
+type StreamValidatorOutbound struct{ transport.StreamOutbound }
+
+// CallStream starts the given stream, failing early if the request is invalid.
+func (o StreamValidatorOutbound) CallStream(ctx context.Context, request *transport.Request) (transport.ClientStream, error) {
+	if err := transport.ValidateRequest(request); err != nil {
+		return nil, err
+	}
+
+	return o.StreamOutbound.CallStream(ctx, request)
+}
+
+// Introspect returns the introspection status of the underlying outbound.
+func (o StreamValidatorOutbound) Introspect() introspection.OutboundStatus {
+	if o, ok := o.StreamOutbound.(introspection.IntrospectableOutbound); ok {
+		return o.Introspect()
+	}
+	return introspection.OutboundStatusNotSupported
+}
@@ -47,6 +47,8 @@ func (d *Dispatcher) Introspect() introspection.DispatcherStatus {
 		inbounds = append(inbounds, status)
 	}
 	var outbounds []introspection.OutboundStatus
+	d.outboundsMu.RLock()
+	defer d.outboundsMu.RUnlock()
 	for outboundKey, o := range d.outbounds {
 		if o.Unary != nil {
 			var status introspection.OutboundStatus
@@ -72,6 +74,18 @@ func (d *Dispatcher) Introspect() introspection.DispatcherStatus {
 			status.OutboundKey = outboundKey
 			outbounds = append(outbounds, status)
 		}
+		if o.Stream != nil {
+			var status introspection.OutboundStatus
+			if o, ok := o.Stream.(introspection.IntrospectableOutbound); ok {
+				status = o.Introspect()
+			} else {
+				status.Transport = "Introspection not supported"
+			}
+			status.RPCType = "stream"
+			status.Service = o.ServiceName
+			status.OutboundKey = outboundKey
+			outbounds = append(outbounds, status)
+		}
 	}
 	procedures := introspection.IntrospectProcedures(d.table.Procedures())
 	return introspection.DispatcherStatus{
@@ -0,0 +1,183 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package yarpc
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// outboundTracker is outbound middleware for all RPC types that counts the
+// calls in flight through an outbound so that RemoveOutbound may wait for
+// them. Once the outbound is removed, new calls fail with an Unavailable
+// error.
+type outboundTracker struct {
+	outboundKey string
+
+	mu       sync.Mutex
+	inflight int
+	removed  bool
+
+	// Closed when the number of calls in flight drops to zero. This is nil
+	// if nobody is waiting.
+	idle chan struct{}
+}
+
+func newOutboundTracker(outboundKey string) *outboundTracker {
+	return &outboundTracker{outboundKey: outboundKey}
+}
+
+// remove makes the tracker reject new calls.
+func (t *outboundTracker) remove() {
+	t.mu.Lock()
+	t.removed = true
+	t.mu.Unlock()
+}
+
+// wait blocks until there are no calls in flight or the context finishes. It
+// returns the number of calls still in flight.
+func (t *outboundTracker) wait(ctx context.Context) int {
+	t.mu.Lock()
+	if t.inflight == 0 {
+		t.mu.Unlock()
+		return 0
+	}
+	if t.idle == nil {
+		t.idle = make(chan struct{})
+	}
+	idle := t.idle
+	t.mu.Unlock()
+
+	select {
+	case <-idle:
+		return 0
+	case <-ctx.Done():
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		return t.inflight
+	}
+}
+
+func (t *outboundTracker) begin(req *transport.Request) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.removed {
+		return yarpcerrors.Newf(yarpcerrors.CodeUnavailable,
+			"outbound %q was removed and cannot call procedure %q", t.outboundKey, req.Procedure)
+	}
+	t.inflight++
+	return nil
+}
+
+func (t *outboundTracker) end() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.inflight--
+	if t.inflight == 0 && t.idle != nil {
+		close(t.idle)
+		t.idle = nil
+	}
+}
+
+// Call implements middleware.UnaryOutbound.
+func (t *outboundTracker) Call(ctx context.Context, req *transport.Request, out transport.UnaryOutbound) (*transport.Response, error) {
+	if err := t.begin(req); err != nil {
+		return nil, err
+	}
+	defer t.end()
+	return out.Call(ctx, req)
+}
+
+// CallOneway implements middleware.OnewayOutbound.
+func (t *outboundTracker) CallOneway(ctx context.Context, req *transport.Request, out transport.OnewayOutbound) (transport.Ack, error) {
+	if err := t.begin(req); err != nil {
+		return nil, err
+	}
+	defer t.end()
+	return out.CallOneway(ctx, req)
+}
+
+// CallStream implements middleware.StreamOutbound. The stream is in flight
+// until ReceiveMessage fails or reaches the end of the stream, the stream is
+// closed, or its context is done.
+func (t *outboundTracker) CallStream(ctx context.Context, req *transport.Request, out transport.StreamOutbound) (transport.ClientStream, error) {
+	if err := t.begin(req); err != nil {
+		return nil, err
+	}
+	s, err := out.CallStream(ctx, req)
+	if err != nil {
+		t.end()
+		return nil, err
+	}
+	cs := &trackedClientStream{ClientStream: s, end: t.end, done: make(chan struct{})}
+	go cs.watch(ctx)
+	return cs, nil
+}
+
+// trackedClientStream is a stream opened through a tracked outbound. It ends
+// the call once.
+type trackedClientStream struct {
+	transport.ClientStream
+
+	end     func()
+	endOnce sync.Once
+
+	// Closed when the stream has ended.
+	done chan struct{}
+}
+
+// watch ends the stream when its context is done. It returns as soon as the
+// stream has ended.
+func (s *trackedClientStream) watch(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+	case <-s.Context().Done():
+	case <-s.done:
+		return
+	}
+	s.finish()
+}
+
+func (s *trackedClientStream) finish() {
+	s.endOnce.Do(func() {
+		close(s.done)
+		s.end()
+	})
+}
+
+func (s *trackedClientStream) ReceiveMessage() (*transport.StreamMessage, error) {
+	msg, err := s.ClientStream.ReceiveMessage()
+	if err != nil {
+		// This includes io.EOF at the end of the stream.
+		s.finish()
+	}
+	return msg, err
+}
+
+func (s *trackedClientStream) Close() error {
+	defer s.finish()
+	return s.ClientStream.Close()
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package yarpc
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// fakeClientStream is a stream whose ReceiveMessage returns io.EOF.
+type fakeClientStream struct {
+	transport.ClientStream
+
+	ctx context.Context
+}
+
+func (s *fakeClientStream) Context() context.Context { return s.ctx }
+func (s *fakeClientStream) Close() error             { return nil }
+
+func (s *fakeClientStream) ReceiveMessage() (*transport.StreamMessage, error) {
+	return nil, io.EOF
+}
+
+func TestOutboundTracker(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	tracker := newOutboundTracker("bar")
+	req := &transport.Request{Procedure: "hello"}
+
+	started, release := make(chan struct{}), make(chan struct{})
+	out := transporttest.NewMockUnaryOutbound(mockCtrl)
+	out.EXPECT().Call(gomock.Any(), req).Do(func(context.Context, *transport.Request) {
+		close(started)
+		<-release
+	}).Return(&transport.Response{}, nil)
+
+	done := make(chan error)
+	go func() {
+		_, err := tracker.Call(context.Background(), req, out)
+		done <- err
+	}()
+	<-started
+	assert.Equal(t, 1, tracker.inflight)
+
+	tracker.remove()
+	_, err := tracker.Call(context.Background(), req, out)
+	require.Error(t, err, "calls must be rejected while draining")
+	assert.Equal(t, yarpcerrors.CodeUnavailable, yarpcerrors.FromError(err).Code())
+	assert.Contains(t, err.Error(), `outbound "bar" was removed`)
+
+	oneway := transporttest.NewMockOnewayOutbound(mockCtrl)
+	_, err = tracker.CallOneway(context.Background(), req, oneway)
+	assert.Error(t, err, "oneway calls must be rejected while draining")
+
+	close(release)
+	require.NoError(t, <-done)
+	assert.Equal(t, 0, tracker.wait(context.Background()), "no calls must be in flight")
+}
+
+func TestOutboundTrackerStream(t *testing.T) {
+	tests := []struct {
+		desc string
+		end  func(s transport.ClientStream, cancel context.CancelFunc)
+	}{
+		{
+			desc: "end of stream",
+			end: func(s transport.ClientStream, _ context.CancelFunc) {
+				_, err := s.ReceiveMessage()
+				assert.Equal(t, io.EOF, err)
+			},
+		},
+		{
+			desc: "close",
+			end: func(s transport.ClientStream, _ context.CancelFunc) {
+				assert.NoError(t, s.Close())
+			},
+		},
+		{
+			desc: "context done",
+			end: func(_ transport.ClientStream, cancel context.CancelFunc) {
+				cancel()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			tracker := newOutboundTracker("bar")
+			req := &transport.Request{Procedure: "hello"}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			out := transporttest.NewMockStreamOutbound(mockCtrl)
+			out.EXPECT().CallStream(ctx, req).Return(&fakeClientStream{ctx: ctx}, nil)
+
+			s, err := tracker.CallStream(ctx, req, out)
+			require.NoError(t, err)
+			assert.Equal(t, 1, tracker.inflight)
+
+			tt.end(s, cancel)
+			waitCtx, waitCancel := context.WithTimeout(context.Background(), time.Second)
+			defer waitCancel()
+			assert.Equal(t, 0, tracker.wait(waitCtx), "stream must not be in flight")
+
+			// Ending the stream again must not count it twice.
+			_, _ = s.ReceiveMessage()
+			assert.NoError(t, s.Close())
+			cancel()
+			time.Sleep(10 * time.Millisecond)
+			tracker.mu.Lock()
+			assert.Equal(t, 0, tracker.inflight, "stream must only end once")
+			tracker.mu.Unlock()
+		})
+	}
+}
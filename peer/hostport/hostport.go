@@ -29,6 +29,10 @@ import (
 )
 
 // PeerIdentifier uniquely references a host:port combination using a common interface
+//
+// Peers listening on unix domain sockets are identified by addresses of the
+// form "unix:///path/to/socket". The HTTP and gRPC transports connect to
+// these peers over the socket.
 type PeerIdentifier string
 
 // Identifier generates a (should be) unique identifier for this PeerIdentifier (to use in maps, etc)
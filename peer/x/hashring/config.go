@@ -0,0 +1,65 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package hashring
+
+import (
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/x/config"
+)
+
+// Config is the configuration for the consistent hash peer list.
+type Config struct {
+	// Number of virtual nodes per peer. Defaults to 100.
+	Replicas int `config:"replicas"`
+}
+
+// Spec returns a configuration specification for the consistent hash peer
+// list implementation, making it possible to send requests with the same
+// shard key to the same peer with transports that use outbound peer list
+// configuration (like HTTP).
+//
+//  cfg := config.New()
+//  cfg.MustRegisterPeerList(hashring.Spec())
+//
+// This enables the consistent-hash peer list:
+//
+//  outbounds:
+//    otherservice:
+//      unary:
+//        http:
+//          url: https://host:port/rpc
+//          consistent-hash:
+//            replicas: 200
+//            peers:
+//              - 127.0.0.1:8080
+//              - 127.0.0.1:8081
+func Spec() config.PeerListSpec {
+	return config.PeerListSpec{
+		Name: "consistent-hash",
+		BuildPeerList: func(c Config, t peer.Transport, k *config.Kit) (peer.ChooserList, error) {
+			var opts []ListOption
+			if c.Replicas != 0 {
+				opts = append(opts, Replicas(c.Replicas))
+			}
+			return New(t, opts...), nil
+		},
+	}
+}
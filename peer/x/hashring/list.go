@@ -0,0 +1,406 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package hashring provides a peer list which sends requests with the same
+// shard key to the same peer.
+//
+// Peers are placed on a consistent hash ring with a number of virtual nodes
+// each. A request goes to the first available peer at or after the position
+// of its shard key on the ring. When peers are added or removed, or become
+// unavailable, only the keys owned by those peers move to other peers.
+//
+// Requests without a shard key are spread over the available peers in a
+// round-robin fashion.
+package hashring
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/introspection"
+	ysync "go.uber.org/yarpc/internal/sync"
+
+	"go.uber.org/atomic"
+	"go.uber.org/multierr"
+)
+
+type listConfig struct {
+	capacity int
+	replicas int
+}
+
+var defaultListConfig = listConfig{
+	capacity: 10,
+	replicas: 100,
+}
+
+// ListOption customizes the behavior of a hash ring list.
+type ListOption func(*listConfig)
+
+// Capacity specifies the default capacity of the underlying
+// data structures for this list
+//
+// Defaults to 10.
+func Capacity(capacity int) ListOption {
+	return func(c *listConfig) {
+		c.capacity = capacity
+	}
+}
+
+// Replicas specifies the number of virtual nodes placed on the ring for each
+// peer. More virtual nodes spread keys more evenly between peers at the cost
+// of memory and slower updates.
+//
+// Defaults to 100.
+func Replicas(replicas int) ListOption {
+	return func(c *listConfig) {
+		c.replicas = replicas
+	}
+}
+
+// New creates a new consistent hash PeerList
+func New(transport peer.Transport, opts ...ListOption) *List {
+	cfg := defaultListConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+	if cfg.replicas < 1 {
+		cfg.replicas = 1
+	}
+
+	return &List{
+		once:               ysync.Once(),
+		uninitializedPeers: make(map[string]peer.Identifier, cfg.capacity),
+		peers:              make(map[string]peer.Peer, cfg.capacity),
+		ring:               newHashRing(cfg.replicas),
+		transport:          transport,
+		peerAvailableEvent: make(chan struct{}, 1),
+	}
+}
+
+// List is a PeerList which chooses peers based on the shard key of requests
+type List struct {
+	lock sync.RWMutex
+
+	shouldRetainPeers  atomic.Bool
+	uninitializedPeers map[string]peer.Identifier
+
+	// All retained peers, available or not.
+	peers map[string]peer.Peer
+	ring  *hashRing
+
+	// Retained peers sorted by identifier, used for requests without a
+	// shard key.
+	ordered []peer.Peer
+	next    atomic.Uint32
+
+	peerAvailableEvent chan struct{}
+	transport          peer.Transport
+
+	once ysync.LifecycleOnce
+}
+
+// Update applies the additions and removals of peer Identifiers to the list
+// it returns a multi-error result of every failure that happened without
+// circuit breaking due to failures.
+func (pl *List) Update(updates peer.ListUpdates) error {
+	if len(updates.Additions) == 0 && len(updates.Removals) == 0 {
+		return nil
+	}
+
+	pl.lock.Lock()
+	defer pl.lock.Unlock()
+
+	if pl.shouldRetainPeers.Load() {
+		return pl.updateInitialized(updates)
+	}
+	return pl.updateUninitialized(updates)
+}
+
+// updateInitialized applies peer list updates when the peer list is able to
+// retain peers, placing the added peers on the ring.
+//
+// Must be run inside a mutex.Lock()
+func (pl *List) updateInitialized(updates peer.ListUpdates) error {
+	var errs error
+	for _, peerID := range updates.Removals {
+		errs = multierr.Append(errs, pl.removePeerIdentifier(peerID))
+	}
+
+	for _, peerID := range updates.Additions {
+		errs = multierr.Append(errs, pl.addPeerIdentifier(peerID))
+	}
+
+	pl.reorder()
+	return errs
+}
+
+// updateUninitialized applies peer list updates when the peer list
+// is **not** able to retain peers, putting the updates into a single
+// uninitialized peer list.
+//
+// Must be run inside a mutex.Lock()
+func (pl *List) updateUninitialized(updates peer.ListUpdates) error {
+	var errs error
+	for _, peerID := range updates.Removals {
+		if _, ok := pl.uninitializedPeers[peerID.Identifier()]; ok {
+			delete(pl.uninitializedPeers, peerID.Identifier())
+		} else {
+			errs = multierr.Append(errs, peer.ErrPeerRemoveNotInList(peerID.Identifier()))
+		}
+	}
+	for _, peerID := range updates.Additions {
+		pl.uninitializedPeers[peerID.Identifier()] = peerID
+	}
+
+	return errs
+}
+
+// Must be run inside a mutex.Lock()
+func (pl *List) addPeerIdentifier(pid peer.Identifier) error {
+	if _, ok := pl.peers[pid.Identifier()]; ok {
+		return peer.ErrPeerAddAlreadyInList(pid.Identifier())
+	}
+
+	p, err := pl.transport.RetainPeer(pid, pl)
+	if err != nil {
+		return err
+	}
+
+	pl.peers[p.Identifier()] = p
+	pl.ring.Add(p)
+	if p.Status().ConnectionStatus == peer.Available {
+		pl.notifyPeerAvailable()
+	}
+	return nil
+}
+
+// removePeerIdentifier removes the peer from the ring and releases it from
+// the transport
+//
+// Must be run in a mutex.Lock()
+func (pl *List) removePeerIdentifier(pid peer.Identifier) error {
+	if _, ok := pl.peers[pid.Identifier()]; !ok {
+		return peer.ErrPeerRemoveNotInList(pid.Identifier())
+	}
+
+	delete(pl.peers, pid.Identifier())
+	pl.ring.Remove(pid)
+	return pl.transport.ReleasePeer(pid, pl)
+}
+
+// reorder rebuilds the list of peers used for requests without a shard key.
+//
+// Must be run in a mutex.Lock()
+func (pl *List) reorder() {
+	ordered := make([]peer.Peer, 0, len(pl.peers))
+	for _, p := range pl.peers {
+		ordered = append(ordered, p)
+	}
+	sort.Sort(byIdentifier(ordered))
+	pl.ordered = ordered
+}
+
+// Start notifies the List that requests will start coming
+func (pl *List) Start() error {
+	return pl.once.Start(pl.start)
+}
+
+func (pl *List) start() error {
+	pl.lock.Lock()
+	defer pl.lock.Unlock()
+
+	var errs error
+	for k, pid := range pl.uninitializedPeers {
+		errs = multierr.Append(errs, pl.addPeerIdentifier(pid))
+		delete(pl.uninitializedPeers, k)
+	}
+	pl.reorder()
+
+	pl.shouldRetainPeers.Store(true)
+
+	return errs
+}
+
+// Stop notifies the List that requests will stop coming
+func (pl *List) Stop() error {
+	return pl.once.Stop(pl.clearPeers)
+}
+
+// clearPeers will release all the peers from the list
+func (pl *List) clearPeers() error {
+	pl.lock.Lock()
+	defer pl.lock.Unlock()
+
+	var errs error
+	for id, p := range pl.peers {
+		errs = multierr.Append(errs, pl.transport.ReleasePeer(p, pl))
+		pl.uninitializedPeers[id] = p
+		delete(pl.peers, id)
+	}
+	pl.ring.RemoveAll()
+	pl.ordered = nil
+
+	pl.shouldRetainPeers.Store(false)
+
+	return errs
+}
+
+// IsRunning returns whether the peer list is running.
+func (pl *List) IsRunning() bool {
+	return pl.once.IsRunning()
+}
+
+// Choose selects the available peer which owns the shard key of the request,
+// or the next available peer if the request has no shard key.
+func (pl *List) Choose(ctx context.Context, req *transport.Request) (peer.Peer, func(error), error) {
+	if err := pl.once.WhenRunning(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	for {
+		if p := pl.choosePeer(req.ShardKey); p != nil {
+			p.StartRequest()
+			return p, pl.getOnFinishFunc(p), nil
+		}
+
+		if err := pl.waitForPeerAddedEvent(ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+// choosePeer returns the peer for the given shard key, or nil if no peers are
+// available.
+func (pl *List) choosePeer(shardKey string) peer.Peer {
+	pl.lock.RLock()
+	defer pl.lock.RUnlock()
+
+	if shardKey != "" {
+		return pl.ring.Get(shardKey, isAvailable)
+	}
+
+	n := len(pl.ordered)
+	if n == 0 {
+		return nil
+	}
+	start := int(pl.next.Inc() % uint32(n))
+	for i := 0; i < n; i++ {
+		if p := pl.ordered[(start+i)%n]; isAvailable(p) {
+			return p
+		}
+	}
+	return nil
+}
+
+func isAvailable(p peer.Peer) bool {
+	return p.Status().ConnectionStatus == peer.Available
+}
+
+// notifyPeerAvailable writes to a channel indicating that a Peer is currently
+// available for requests
+func (pl *List) notifyPeerAvailable() {
+	select {
+	case pl.peerAvailableEvent <- struct{}{}:
+	default:
+	}
+}
+
+// getOnFinishFunc creates a closure that will be run at the end of the request
+func (pl *List) getOnFinishFunc(p peer.Peer) func(error) {
+	return func(_ error) {
+		p.EndRequest()
+	}
+}
+
+// waitForPeerAddedEvent waits until a peer is added to the peer list or the
+// given context finishes.
+// Must NOT be run in a mutex.Lock()
+func (pl *List) waitForPeerAddedEvent(ctx context.Context) error {
+	if _, ok := ctx.Deadline(); !ok {
+		return peer.ErrChooseContextHasNoDeadline("HashRingList")
+	}
+
+	select {
+	case <-pl.peerAvailableEvent:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// NotifyStatusChanged when the peer's status changes
+//
+// Peers stay on the ring regardless of their status so that their keys come
+// back to them when they become available again.
+func (pl *List) NotifyStatusChanged(pid peer.Identifier) {
+	pl.lock.RLock()
+	p, ok := pl.peers[pid.Identifier()]
+	pl.lock.RUnlock()
+
+	if ok && isAvailable(p) {
+		pl.notifyPeerAvailable()
+	}
+}
+
+// Introspect returns a ChooserStatus with a summary of the Peers.
+func (pl *List) Introspect() introspection.ChooserStatus {
+	state := "Stopped"
+	if pl.IsRunning() {
+		state = "Running"
+	}
+
+	pl.lock.RLock()
+	peers := make([]peer.Peer, len(pl.ordered))
+	copy(peers, pl.ordered)
+	pl.lock.RUnlock()
+
+	available := 0
+	peersStatus := make([]introspection.PeerStatus, 0, len(peers))
+	for _, p := range peers {
+		ps := p.Status()
+		if ps.ConnectionStatus == peer.Available {
+			available++
+		}
+		peersStatus = append(peersStatus, introspection.PeerStatus{
+			Identifier: p.Identifier(),
+			State: fmt.Sprintf("%s, %d pending request(s)",
+				ps.ConnectionStatus.String(),
+				ps.PendingRequestCount),
+		})
+	}
+
+	return introspection.ChooserStatus{
+		Name: "HashRing",
+		State: fmt.Sprintf("%s (%d/%d available)", state, available,
+			len(peers)),
+		Peers: peersStatus,
+	}
+}
+
+type byIdentifier []peer.Peer
+
+func (ps byIdentifier) Len() int           { return len(ps) }
+func (ps byIdentifier) Swap(i, j int)      { ps[i], ps[j] = ps[j], ps[i] }
+func (ps byIdentifier) Less(i, j int) bool { return ps[i].Identifier() < ps[j].Identifier() }
@@ -0,0 +1,311 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package hashring
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/peer/hostport"
+)
+
+// fakeTransport retains hostport peers which are available until told
+// otherwise.
+type fakeTransport struct {
+	mu    sync.Mutex
+	peers map[string]*hostport.Peer
+}
+
+func newFakeTransport() *fakeTransport {
+	return &fakeTransport{peers: make(map[string]*hostport.Peer)}
+}
+
+func (t *fakeTransport) RetainPeer(pid peer.Identifier, sub peer.Subscriber) (peer.Peer, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.peers[pid.Identifier()]
+	if !ok {
+		p = hostport.NewPeer(hostport.PeerIdentifier(pid.Identifier()), t)
+		p.SetStatus(peer.Available)
+		t.peers[pid.Identifier()] = p
+	}
+	p.Subscribe(sub)
+	return p, nil
+}
+
+func (t *fakeTransport) ReleasePeer(pid peer.Identifier, sub peer.Subscriber) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.peers[pid.Identifier()]
+	if !ok {
+		return errors.New("unknown peer")
+	}
+	if err := p.Unsubscribe(sub); err != nil {
+		return err
+	}
+	if p.NumSubscribers() == 0 {
+		delete(t.peers, pid.Identifier())
+	}
+	return nil
+}
+
+func (t *fakeTransport) setStatus(id string, status peer.ConnectionStatus) {
+	t.mu.Lock()
+	p := t.peers[id]
+	t.mu.Unlock()
+	p.SetStatus(status)
+}
+
+func ids(hosts ...string) []peer.Identifier {
+	pids := make([]peer.Identifier, len(hosts))
+	for i, h := range hosts {
+		pids[i] = hostport.PeerIdentifier(h)
+	}
+	return pids
+}
+
+func hostNames(n int) []string {
+	hosts := make([]string, n)
+	for i := range hosts {
+		hosts[i] = "10.0.0." + strconv.Itoa(i) + ":80"
+	}
+	return hosts
+}
+
+func choose(t *testing.T, pl *List, shardKey string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	p, onFinish, err := pl.Choose(ctx, &transport.Request{ShardKey: shardKey})
+	require.NoError(t, err)
+	onFinish(nil)
+	return p.Identifier()
+}
+
+// owners returns the peer chosen for each of n keys.
+func owners(t *testing.T, pl *List, n int) []string {
+	result := make([]string, n)
+	for i := range result {
+		result[i] = choose(t, pl, "key-"+strconv.Itoa(i))
+	}
+	return result
+}
+
+func TestSameKeySamePeer(t *testing.T) {
+	pl := New(newFakeTransport())
+	require.NoError(t, pl.Update(peer.ListUpdates{Additions: ids(hostNames(5)...)}))
+	require.NoError(t, pl.Start())
+	defer pl.Stop()
+
+	for i := 0; i < 20; i++ {
+		key := "key-" + strconv.Itoa(i)
+		want := choose(t, pl, key)
+		for j := 0; j < 5; j++ {
+			assert.Equal(t, want, choose(t, pl, key), "key %q moved", key)
+		}
+	}
+}
+
+func TestKeysSpreadAcrossPeers(t *testing.T) {
+	pl := New(newFakeTransport())
+	require.NoError(t, pl.Start())
+	defer pl.Stop()
+	require.NoError(t, pl.Update(peer.ListUpdates{Additions: ids(hostNames(4)...)}))
+
+	counts := make(map[string]int)
+	for _, owner := range owners(t, pl, 4000) {
+		counts[owner]++
+	}
+
+	require.Len(t, counts, 4)
+	for id, n := range counts {
+		// Each peer should own roughly a quarter of the keys.
+		assert.InDelta(t, 1000, n, 400, "peer %v owns %d keys", id, n)
+	}
+}
+
+func TestAdditionMovesKeysOnlyToNewPeer(t *testing.T) {
+	pl := New(newFakeTransport())
+	require.NoError(t, pl.Start())
+	defer pl.Stop()
+
+	hosts := hostNames(11)
+	require.NoError(t, pl.Update(peer.ListUpdates{Additions: ids(hosts[:10]...)}))
+	before := owners(t, pl, 1000)
+
+	require.NoError(t, pl.Update(peer.ListUpdates{Additions: ids(hosts[10])}))
+	after := owners(t, pl, 1000)
+
+	moved := 0
+	for i := range before {
+		if before[i] != after[i] {
+			moved++
+			assert.Equal(t, hosts[10], after[i], "key %d moved to an old peer", i)
+		}
+	}
+	assert.True(t, moved > 0, "no keys moved to the new peer")
+	assert.True(t, moved < 200, "too many keys moved: %d", moved)
+}
+
+func TestRemovalMovesOnlyKeysOfRemovedPeer(t *testing.T) {
+	pl := New(newFakeTransport())
+	require.NoError(t, pl.Start())
+	defer pl.Stop()
+
+	hosts := hostNames(10)
+	require.NoError(t, pl.Update(peer.ListUpdates{Additions: ids(hosts...)}))
+	before := owners(t, pl, 1000)
+
+	require.NoError(t, pl.Update(peer.ListUpdates{Removals: ids(hosts[3])}))
+	after := owners(t, pl, 1000)
+
+	for i := range before {
+		if before[i] == hosts[3] {
+			assert.NotEqual(t, hosts[3], after[i])
+		} else {
+			assert.Equal(t, before[i], after[i], "key %d moved", i)
+		}
+	}
+}
+
+func TestUnavailablePeerKeysComeBack(t *testing.T) {
+	trans := newFakeTransport()
+	pl := New(trans)
+	require.NoError(t, pl.Start())
+	defer pl.Stop()
+
+	hosts := hostNames(5)
+	require.NoError(t, pl.Update(peer.ListUpdates{Additions: ids(hosts...)}))
+	before := owners(t, pl, 500)
+
+	trans.setStatus(hosts[0], peer.Unavailable)
+	during := owners(t, pl, 500)
+	for i := range before {
+		if before[i] == hosts[0] {
+			assert.NotEqual(t, hosts[0], during[i])
+		} else {
+			assert.Equal(t, before[i], during[i], "key %d moved", i)
+		}
+	}
+
+	trans.setStatus(hosts[0], peer.Available)
+	assert.Equal(t, before, owners(t, pl, 500))
+}
+
+func TestEmptyShardKeyRoundRobin(t *testing.T) {
+	trans := newFakeTransport()
+	pl := New(trans)
+	require.NoError(t, pl.Start())
+	defer pl.Stop()
+
+	hosts := hostNames(3)
+	require.NoError(t, pl.Update(peer.ListUpdates{Additions: ids(hosts...)}))
+
+	counts := make(map[string]int)
+	for i := 0; i < 30; i++ {
+		counts[choose(t, pl, "")]++
+	}
+	assert.Equal(t, map[string]int{hosts[0]: 10, hosts[1]: 10, hosts[2]: 10}, counts)
+
+	trans.setStatus(hosts[1], peer.Unavailable)
+	for i := 0; i < 10; i++ {
+		assert.NotEqual(t, hosts[1], choose(t, pl, ""))
+	}
+}
+
+func TestChooseWaitsForAvailablePeer(t *testing.T) {
+	trans := newFakeTransport()
+	pl := New(trans)
+	require.NoError(t, pl.Start())
+	defer pl.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, _, err := pl.Choose(ctx, &transport.Request{ShardKey: "foo"})
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	_, _, err = pl.Choose(context.Background(), &transport.Request{ShardKey: "foo"})
+	assert.Equal(t, peer.ErrChooseContextHasNoDeadline("HashRingList"), err)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		assert.NoError(t, pl.Update(peer.ListUpdates{Additions: ids("a:80")}))
+	}()
+	assert.Equal(t, "a:80", choose(t, pl, "foo"))
+}
+
+func TestUpdateErrors(t *testing.T) {
+	pl := New(newFakeTransport())
+
+	assert.Error(t, pl.Update(peer.ListUpdates{Removals: ids("a:80")}),
+		"removing unknown peer before start")
+
+	require.NoError(t, pl.Start())
+	defer pl.Stop()
+
+	require.NoError(t, pl.Update(peer.ListUpdates{Additions: ids("a:80")}))
+	assert.Equal(t, peer.ErrPeerAddAlreadyInList("a:80"),
+		pl.Update(peer.ListUpdates{Additions: ids("a:80")}))
+	assert.Equal(t, peer.ErrPeerRemoveNotInList("b:80"),
+		pl.Update(peer.ListUpdates{Removals: ids("b:80")}))
+}
+
+func TestStopReleasesPeers(t *testing.T) {
+	trans := newFakeTransport()
+	pl := New(trans)
+	require.NoError(t, pl.Update(peer.ListUpdates{Additions: ids("a:80", "b:80")}))
+	require.NoError(t, pl.Start())
+	assert.Len(t, trans.peers, 2)
+
+	require.NoError(t, pl.Stop())
+	assert.Empty(t, trans.peers)
+	assert.Equal(t, "Stopped (0/0 available)", pl.Introspect().State)
+}
+
+func TestIntrospect(t *testing.T) {
+	trans := newFakeTransport()
+	pl := New(trans)
+	require.NoError(t, pl.Update(peer.ListUpdates{Additions: ids("a:80", "b:80")}))
+	assert.Equal(t, "Stopped (0/0 available)", pl.Introspect().State)
+
+	require.NoError(t, pl.Start())
+	defer pl.Stop()
+	trans.setStatus("b:80", peer.Unavailable)
+
+	status := pl.Introspect()
+	assert.Equal(t, "HashRing", status.Name)
+	assert.Equal(t, "Running (1/2 available)", status.State)
+	require.Len(t, status.Peers, 2)
+	assert.Equal(t, "a:80", status.Peers[0].Identifier)
+	assert.Equal(t, "Available, 0 pending request(s)", status.Peers[0].State)
+	assert.Equal(t, "b:80", status.Peers[1].Identifier)
+	assert.Equal(t, "Unavailable, 0 pending request(s)", status.Peers[1].State)
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package hashring
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+
+	"go.uber.org/yarpc/api/peer"
+)
+
+// node is a virtual node on the hash ring.
+type node struct {
+	hash uint64
+	peer peer.Peer
+}
+
+// hashRing places every peer on a ring of 64-bit hashes at a number of
+// pseudo-random positions, called virtual nodes. A key belongs to the first
+// virtual node at or after the hash of the key.
+//
+// hashRing is NOT thread-safe, make sure to only call hashRing functions with
+// a lock.
+type hashRing struct {
+	replicas int
+
+	// Virtual nodes sorted by hash.
+	nodes []node
+}
+
+func newHashRing(replicas int) *hashRing {
+	return &hashRing{replicas: replicas}
+}
+
+// Add places the virtual nodes of the given peer on the ring.
+func (r *hashRing) Add(p peer.Peer) {
+	id := p.Identifier()
+	for i := 0; i < r.replicas; i++ {
+		r.nodes = append(r.nodes, node{
+			hash: hash(id + "#" + strconv.Itoa(i)),
+			peer: p,
+		})
+	}
+	sort.Sort(byHash(r.nodes))
+}
+
+// Remove removes the virtual nodes of the peer with the given identifier
+// from the ring.
+func (r *hashRing) Remove(pid peer.Identifier) {
+	id := pid.Identifier()
+	nodes := r.nodes[:0]
+	for _, n := range r.nodes {
+		if n.peer.Identifier() != id {
+			nodes = append(nodes, n)
+		}
+	}
+	// Don't hold on to removed peers.
+	for i := len(nodes); i < len(r.nodes); i++ {
+		r.nodes[i] = node{}
+	}
+	r.nodes = nodes
+}
+
+// RemoveAll removes all peers from the ring.
+func (r *hashRing) RemoveAll() {
+	r.nodes = nil
+}
+
+// Get returns the first peer at or after the position of the given key on
+// the ring which satisfies the given predicate, or nil if no peer does.
+//
+// Skipping peers this way means that the keys of an unavailable peer are
+// spread over the remaining peers while the keys of other peers stay put.
+func (r *hashRing) Get(key string, ok func(peer.Peer) bool) peer.Peer {
+	if len(r.nodes) == 0 {
+		return nil
+	}
+
+	h := hash(key)
+	start := sort.Search(len(r.nodes), func(i int) bool {
+		return r.nodes[i].hash >= h
+	})
+
+	for i := 0; i < len(r.nodes); i++ {
+		p := r.nodes[(start+i)%len(r.nodes)].peer
+		if ok(p) {
+			return p
+		}
+	}
+	return nil
+}
+
+type byHash []node
+
+func (ns byHash) Len() int      { return len(ns) }
+func (ns byHash) Swap(i, j int) { ns[i], ns[j] = ns[j], ns[i] }
+func (ns byHash) Less(i, j int) bool {
+	if ns[i].hash == ns[j].hash {
+		// Break ties deterministically so that all instances of a service
+		// agree on the owner of a key.
+		return ns[i].peer.Identifier() < ns[j].peer.Identifier()
+	}
+	return ns[i].hash < ns[j].hash
+}
+
+// hash hashes the given string with FNV-1a, followed by the MurmurHash3
+// finalizer to spread similar strings (like the virtual node names of a peer)
+// over the whole ring.
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	x := h.Sum64()
+
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
@@ -0,0 +1,385 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package outlier provides a peer list which ejects peers that fail too many
+// requests.
+//
+// Peer lists only stop sending requests to a peer when the transport reports
+// that the peer is no longer connected. A peer which accepts connections but
+// fails most requests keeps receiving its share of traffic. The List in this
+// package wraps another peer list and watches the outcome of every request
+// made through it. Peers which fail too many consecutive requests, or too
+// large a fraction of recent requests, are reported as unavailable to the
+// underlying list for a backoff window. Once the window has passed, the peer
+// is made available again to probe it: it is restored if its next request
+// succeeds, and ejected for longer if it fails.
+//
+// 	list := outlier.New(transport, func(t peer.Transport) peer.ChooserList {
+// 		return roundrobin.New(t)
+// 	}, outlier.ConsecutiveFailures(5))
+//
+// Only errors with codes that indicate a problem with the peer count as
+// failures. See FailureCodes.
+package outlier
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/introspection"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+type listConfig struct {
+	consecutiveFailures int
+	errorRate           float64
+	errorRateWindow     int
+	baseEjectionTime    time.Duration
+	maxEjectionTime     time.Duration
+	maxEjectionPercent  int
+	failureCodes        map[yarpcerrors.Code]struct{}
+}
+
+var defaultListConfig = listConfig{
+	consecutiveFailures: 5,
+	baseEjectionTime:    10 * time.Second,
+	maxEjectionTime:     5 * time.Minute,
+	maxEjectionPercent:  50,
+	failureCodes: codeSet(
+		yarpcerrors.CodeUnknown,
+		yarpcerrors.CodeDeadlineExceeded,
+		yarpcerrors.CodeInternal,
+		yarpcerrors.CodeUnavailable,
+		yarpcerrors.CodeDataLoss,
+	),
+}
+
+// ListOption customizes the behavior of an outlier detecting list.
+type ListOption func(*listConfig)
+
+// ConsecutiveFailures specifies the number of consecutive failed requests
+// after which a peer is ejected. Peers are not ejected for consecutive
+// failures if this is zero.
+//
+// Defaults to 5.
+func ConsecutiveFailures(n int) ListOption {
+	return func(c *listConfig) {
+		c.consecutiveFailures = n
+	}
+}
+
+// ErrorRate ejects peers if at least the given fraction of their last window
+// requests failed. Peers are not ejected based on their error rate unless
+// this option is specified.
+func ErrorRate(threshold float64, window int) ListOption {
+	return func(c *listConfig) {
+		c.errorRate = threshold
+		c.errorRateWindow = window
+	}
+}
+
+// EjectionTime specifies how long peers stay ejected. A peer is ejected for
+// the base time at first, and the time doubles every time the peer fails
+// again when it is probed, up to the given maximum.
+//
+// Defaults to 10 seconds and 5 minutes.
+func EjectionTime(base, max time.Duration) ListOption {
+	return func(c *listConfig) {
+		c.baseEjectionTime = base
+		c.maxEjectionTime = max
+	}
+}
+
+// MaxEjectionPercent specifies the maximum percentage of peers that may be
+// ejected at the same time. This prevents the list from ejecting all peers
+// when the problem lies with the requests rather than the peers.
+//
+// Defaults to 50.
+func MaxEjectionPercent(percent int) ListOption {
+	return func(c *listConfig) {
+		c.maxEjectionPercent = percent
+	}
+}
+
+// FailureCodes specifies the codes of errors which count as failed requests.
+// Errors without a code count as errors with CodeUnknown.
+//
+// Defaults to CodeUnknown, CodeDeadlineExceeded, CodeInternal,
+// CodeUnavailable, and CodeDataLoss.
+func FailureCodes(codes ...yarpcerrors.Code) ListOption {
+	return func(c *listConfig) {
+		c.failureCodes = codeSet(codes...)
+	}
+}
+
+func codeSet(codes ...yarpcerrors.Code) map[yarpcerrors.Code]struct{} {
+	set := make(map[yarpcerrors.Code]struct{}, len(codes))
+	for _, code := range codes {
+		set[code] = struct{}{}
+	}
+	return set
+}
+
+// List is a peer list which ejects peers that fail too many requests from
+// another peer list.
+type List struct {
+	cfg       listConfig
+	list      peer.ChooserList
+	transport peer.Transport
+
+	mu    sync.Mutex
+	peers map[string]*outlierPeer
+}
+
+var (
+	_ peer.ChooserList                    = (*List)(nil)
+	_ introspection.IntrospectableChooser = (*List)(nil)
+)
+
+// New builds a new outlier detecting peer list. The given function is used
+// to build the underlying list with a Transport that reports ejected peers as
+// unavailable. The underlying list must retain peers only through this
+// Transport.
+func New(t peer.Transport, newList func(peer.Transport) peer.ChooserList, opts ...ListOption) *List {
+	cfg := defaultListConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+
+	l := &List{
+		cfg:       cfg,
+		transport: t,
+		peers:     make(map[string]*outlierPeer),
+	}
+	l.list = newList(outlierTransport{l})
+	return l
+}
+
+// Start starts the underlying peer list.
+func (l *List) Start() error {
+	return l.list.Start()
+}
+
+// Stop stops the underlying peer list.
+func (l *List) Stop() error {
+	return l.list.Stop()
+}
+
+// IsRunning returns whether the underlying peer list is running.
+func (l *List) IsRunning() bool {
+	return l.list.IsRunning()
+}
+
+// Update applies the given updates to the underlying peer list.
+func (l *List) Update(updates peer.ListUpdates) error {
+	return l.list.Update(updates)
+}
+
+// Choose chooses a peer from the underlying list and records the outcome of
+// the request when it finishes.
+func (l *List) Choose(ctx context.Context, req *transport.Request) (peer.Peer, func(error), error) {
+	p, onFinish, err := l.list.Choose(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	op, ok := p.(*outlierPeer)
+	if !ok {
+		// The list retained a peer from somewhere else. There's nothing we
+		// can do about it.
+		return p, onFinish, nil
+	}
+
+	// Outbounds expect the peers created by their own transport.
+	return op.Peer, func(err error) {
+		onFinish(err)
+		l.observe(op, err)
+	}, nil
+}
+
+// isFailure returns true if the given error indicates a problem with the
+// peer.
+func (l *List) isFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	_, ok := l.cfg.failureCodes[yarpcerrors.FromError(err).Code()]
+	return ok
+}
+
+// observe records the outcome of a request to the given peer.
+func (l *List) observe(op *outlierPeer, err error) {
+	failed := l.isFailure(err)
+
+	l.mu.Lock()
+	if l.peers[op.Identifier()] != op || op.ejected.Load() {
+		// The peer was released or the request started before the peer was
+		// ejected.
+		l.mu.Unlock()
+		return
+	}
+
+	changed := false
+	if op.probing {
+		op.probing = false
+		if failed {
+			changed = l.eject(op)
+		} else {
+			op.restore()
+		}
+	} else {
+		op.record(failed)
+		if op.shouldEject(&l.cfg) {
+			changed = l.eject(op)
+		}
+	}
+	l.mu.Unlock()
+
+	if changed {
+		op.NotifyStatusChanged(op)
+	}
+}
+
+// eject ejects the given peer unless too many peers are ejected already.
+// Returns true if the peer was ejected.
+//
+// Must be run in a mutex.Lock()
+func (l *List) eject(op *outlierPeer) bool {
+	ejected := 0
+	for _, p := range l.peers {
+		if p.ejected.Load() {
+			ejected++
+		}
+	}
+	if (ejected+1)*100 > l.cfg.maxEjectionPercent*len(l.peers) {
+		return false
+	}
+
+	op.ejections++
+	op.ejected.Store(true)
+	op.reset()
+	op.timer = time.AfterFunc(l.ejectionTime(op.ejections), func() { l.probe(op) })
+	return true
+}
+
+// ejectionTime returns how long a peer is ejected for its nth consecutive
+// ejection.
+func (l *List) ejectionTime(n int) time.Duration {
+	d := l.cfg.baseEjectionTime
+	for i := 1; i < n && d < l.cfg.maxEjectionTime; i++ {
+		d *= 2
+	}
+	if d > l.cfg.maxEjectionTime {
+		d = l.cfg.maxEjectionTime
+	}
+	return d
+}
+
+// probe makes an ejected peer available again so that its next request
+// decides whether it is restored or ejected again.
+func (l *List) probe(op *outlierPeer) {
+	l.mu.Lock()
+	if l.peers[op.Identifier()] != op || !op.ejected.Load() {
+		l.mu.Unlock()
+		return
+	}
+	op.timer = nil
+	op.probing = true
+	op.ejected.Store(false)
+	l.mu.Unlock()
+
+	op.NotifyStatusChanged(op)
+}
+
+// Introspect returns the status of the underlying list with the outlier
+// detection state of each peer.
+func (l *List) Introspect() introspection.ChooserStatus {
+	var status introspection.ChooserStatus
+	if ic, ok := l.list.(introspection.IntrospectableChooser); ok {
+		status = ic.Introspect()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ejected := 0
+	for i, ps := range status.Peers {
+		op, ok := l.peers[ps.Identifier]
+		if !ok {
+			continue
+		}
+		if op.ejected.Load() {
+			ejected++
+		}
+		if state := op.state(); state != "" {
+			status.Peers[i].State = fmt.Sprintf("%s, %s", ps.State, state)
+		}
+	}
+	status.State = fmt.Sprintf("%s (%d ejected)", status.State, ejected)
+	return status
+}
+
+// outlierTransport is the Transport given to the underlying list. It wraps
+// the peers retained from the real transport so that the list sees ejected
+// peers as unavailable.
+type outlierTransport struct{ l *List }
+
+func (t outlierTransport) RetainPeer(pid peer.Identifier, sub peer.Subscriber) (peer.Peer, error) {
+	l := t.l
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if op, ok := l.peers[pid.Identifier()]; ok {
+		return op, nil
+	}
+
+	op := newOutlierPeer(sub, l.cfg.errorRateWindow)
+	p, err := l.transport.RetainPeer(pid, op)
+	if err != nil {
+		return nil, err
+	}
+	op.Peer = p
+	l.peers[pid.Identifier()] = op
+	return op, nil
+}
+
+func (t outlierTransport) ReleasePeer(pid peer.Identifier, sub peer.Subscriber) error {
+	l := t.l
+	l.mu.Lock()
+	op, ok := l.peers[pid.Identifier()]
+	if !ok {
+		l.mu.Unlock()
+		return peer.ErrTransportHasNoReferenceToPeer{
+			TransportName:  "outlier.List",
+			PeerIdentifier: pid.Identifier(),
+		}
+	}
+	delete(l.peers, pid.Identifier())
+	if op.timer != nil {
+		op.timer.Stop()
+	}
+	l.mu.Unlock()
+
+	return l.transport.ReleasePeer(pid, op)
+}
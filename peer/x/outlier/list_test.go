@@ -0,0 +1,353 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package outlier
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/introspection"
+	"go.uber.org/yarpc/peer/hostport"
+	"go.uber.org/yarpc/peer/roundrobin"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// fakeTransport retains hostport peers which are always available.
+type fakeTransport struct {
+	mu    sync.Mutex
+	peers map[string]*hostport.Peer
+}
+
+func newFakeTransport() *fakeTransport {
+	return &fakeTransport{peers: make(map[string]*hostport.Peer)}
+}
+
+func (t *fakeTransport) RetainPeer(pid peer.Identifier, sub peer.Subscriber) (peer.Peer, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.peers[pid.Identifier()]
+	if !ok {
+		p = hostport.NewPeer(hostport.PeerIdentifier(pid.Identifier()), t)
+		p.SetStatus(peer.Available)
+		t.peers[pid.Identifier()] = p
+	}
+	p.Subscribe(sub)
+	return p, nil
+}
+
+func (t *fakeTransport) ReleasePeer(pid peer.Identifier, sub peer.Subscriber) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.peers[pid.Identifier()]
+	if !ok {
+		return errors.New("unknown peer")
+	}
+	if err := p.Unsubscribe(sub); err != nil {
+		return err
+	}
+	if p.NumSubscribers() == 0 {
+		delete(t.peers, pid.Identifier())
+	}
+	return nil
+}
+
+func newRoundRobin(t peer.Transport) peer.ChooserList {
+	return roundrobin.New(t)
+}
+
+func newTestList(t *testing.T, hosts []string, opts ...ListOption) (*List, *fakeTransport) {
+	trans := newFakeTransport()
+	l := New(trans, newRoundRobin, opts...)
+	require.NoError(t, l.Start())
+
+	var add []peer.Identifier
+	for _, h := range hosts {
+		add = append(add, hostport.PeerIdentifier(h))
+	}
+	require.NoError(t, l.Update(peer.ListUpdates{Additions: add}))
+	return l, trans
+}
+
+// call chooses a peer and finishes the request with the error returned by
+// fn for that peer.
+func call(t *testing.T, l *List, fn func(string) error) string {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	p, onFinish, err := l.Choose(ctx, &transport.Request{})
+	require.NoError(t, err)
+	_, ok := p.(*hostport.Peer)
+	require.True(t, ok, "chosen peer must be the transport's peer")
+
+	onFinish(fn(p.Identifier()))
+	return p.Identifier()
+}
+
+func failing(bad string) func(string) error {
+	return func(id string) error {
+		if id == bad {
+			return yarpcerrors.Newf(yarpcerrors.CodeUnavailable, "down")
+		}
+		return nil
+	}
+}
+
+func peerState(l *List, id string) string {
+	for _, ps := range l.Introspect().Peers {
+		if ps.Identifier == id {
+			return ps.State
+		}
+	}
+	return ""
+}
+
+func waitFor(t *testing.T, msg string, cond func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %v", msg)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConsecutiveFailuresEject(t *testing.T) {
+	l, _ := newTestList(t, []string{"a", "b", "c"},
+		ConsecutiveFailures(2), EjectionTime(time.Hour, time.Hour))
+	defer l.Stop()
+
+	for i := 0; i < 6; i++ {
+		call(t, l, failing("a"))
+	}
+	assert.Contains(t, peerState(l, "a"), "ejected")
+	assert.Contains(t, l.Introspect().State, "(1 ejected)")
+
+	for i := 0; i < 10; i++ {
+		assert.NotEqual(t, "a", call(t, l, failing("a")), "ejected peer must not be chosen")
+	}
+}
+
+func TestSuccessResetsConsecutiveFailures(t *testing.T) {
+	l, _ := newTestList(t, []string{"a"},
+		ConsecutiveFailures(3), MaxEjectionPercent(100))
+	defer l.Stop()
+
+	fail := func(string) error { return yarpcerrors.Newf(yarpcerrors.CodeInternal, "great sadness") }
+	succeed := func(string) error { return nil }
+
+	call(t, l, fail)
+	call(t, l, fail)
+	assert.Contains(t, peerState(l, "a"), "2 consecutive failures")
+
+	call(t, l, succeed)
+	call(t, l, fail)
+	call(t, l, fail)
+	assert.NotContains(t, peerState(l, "a"), "ejected")
+
+	call(t, l, fail)
+	assert.Contains(t, peerState(l, "a"), "ejected")
+}
+
+func TestIgnoredErrors(t *testing.T) {
+	l, _ := newTestList(t, []string{"a"},
+		ConsecutiveFailures(1), MaxEjectionPercent(100))
+	defer l.Stop()
+
+	call(t, l, func(string) error {
+		return yarpcerrors.Newf(yarpcerrors.CodeInvalidArgument, "bad request")
+	})
+	assert.NotContains(t, peerState(l, "a"), "ejected")
+
+	// Errors without a code count as unknown errors.
+	call(t, l, func(string) error { return errors.New("great sadness") })
+	assert.Contains(t, peerState(l, "a"), "ejected")
+}
+
+func TestFailureCodes(t *testing.T) {
+	l, _ := newTestList(t, []string{"a"},
+		ConsecutiveFailures(1), MaxEjectionPercent(100),
+		FailureCodes(yarpcerrors.CodeResourceExhausted))
+	defer l.Stop()
+
+	call(t, l, func(string) error { return yarpcerrors.Newf(yarpcerrors.CodeUnavailable, "") })
+	assert.NotContains(t, peerState(l, "a"), "ejected")
+
+	call(t, l, func(string) error { return yarpcerrors.Newf(yarpcerrors.CodeResourceExhausted, "") })
+	assert.Contains(t, peerState(l, "a"), "ejected")
+}
+
+func TestErrorRateEject(t *testing.T) {
+	l, _ := newTestList(t, []string{"a"},
+		ConsecutiveFailures(0), ErrorRate(0.5, 4), MaxEjectionPercent(100))
+	defer l.Stop()
+
+	outcomes := []error{nil, errors.New("a"), nil}
+	for _, err := range outcomes {
+		call(t, l, func(string) error { return err })
+	}
+	// Only 3 of 4 requests were recorded.
+	assert.NotContains(t, peerState(l, "a"), "ejected")
+
+	call(t, l, func(string) error { return errors.New("b") })
+	assert.Contains(t, peerState(l, "a"), "ejected")
+}
+
+func TestErrorRateWindowSlides(t *testing.T) {
+	l, _ := newTestList(t, []string{"a"},
+		ConsecutiveFailures(0), ErrorRate(0.75, 4), MaxEjectionPercent(100))
+	defer l.Stop()
+
+	fail := func(string) error { return errors.New("great sadness") }
+	succeed := func(string) error { return nil }
+
+	// The window never holds more than 2 failures.
+	for _, fn := range []func(string) error{fail, fail, succeed, succeed, succeed, fail, fail} {
+		call(t, l, fn)
+		require.NotContains(t, peerState(l, "a"), "ejected")
+	}
+	// Window is now S S F F.
+	call(t, l, fail)
+	// Window is now S F F F.
+	assert.Contains(t, peerState(l, "a"), "ejected")
+}
+
+func TestMaxEjectionPercent(t *testing.T) {
+	l, _ := newTestList(t, []string{"a", "b"},
+		ConsecutiveFailures(1), MaxEjectionPercent(50), EjectionTime(time.Hour, time.Hour))
+	defer l.Stop()
+
+	fail := func(string) error { return errors.New("great sadness") }
+	for i := 0; i < 4; i++ {
+		call(t, l, fail)
+	}
+
+	status := l.Introspect()
+	assert.Contains(t, status.State, "(1 ejected)")
+}
+
+func TestProbeRestoresPeer(t *testing.T) {
+	l, _ := newTestList(t, []string{"a"},
+		ConsecutiveFailures(1), MaxEjectionPercent(100),
+		EjectionTime(10*time.Millisecond, time.Second))
+	defer l.Stop()
+
+	call(t, l, failing("a"))
+	assert.Contains(t, peerState(l, "a"), "ejected")
+
+	waitFor(t, "peer to be probed", func() bool {
+		return strings.Contains(peerState(l, "a"), "probing")
+	})
+
+	call(t, l, func(string) error { return nil })
+	assert.Equal(t, "Available, 0 pending request(s)", peerState(l, "a"))
+}
+
+func TestProbeFailureEjectsLonger(t *testing.T) {
+	l, _ := newTestList(t, []string{"a"},
+		ConsecutiveFailures(1), MaxEjectionPercent(100),
+		EjectionTime(10*time.Millisecond, time.Hour))
+	defer l.Stop()
+
+	call(t, l, failing("a"))
+	waitFor(t, "peer to be probed", func() bool {
+		return strings.Contains(peerState(l, "a"), "probing")
+	})
+
+	call(t, l, failing("a"))
+	assert.Contains(t, peerState(l, "a"), "ejected")
+
+	l.mu.Lock()
+	ejections := l.peers["a"].ejections
+	l.mu.Unlock()
+	assert.Equal(t, 2, ejections)
+}
+
+func TestEjectionTime(t *testing.T) {
+	l := New(newFakeTransport(), newRoundRobin, EjectionTime(time.Second, 5*time.Second))
+
+	tests := []struct {
+		n    int
+		want time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 5 * time.Second},
+		{100, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, l.ejectionTime(tt.n), "ejection %d", tt.n)
+	}
+}
+
+func TestReleaseEjectedPeer(t *testing.T) {
+	l, trans := newTestList(t, []string{"a", "b"},
+		ConsecutiveFailures(1), EjectionTime(10*time.Millisecond, time.Second))
+	defer l.Stop()
+
+	for i := 0; i < 2; i++ {
+		call(t, l, failing("a"))
+	}
+	require.Contains(t, peerState(l, "a"), "ejected")
+
+	require.NoError(t, l.Update(peer.ListUpdates{
+		Removals: []peer.Identifier{hostport.PeerIdentifier("a")},
+	}))
+
+	// The probe must not resurrect the released peer.
+	time.Sleep(20 * time.Millisecond)
+
+	l.mu.Lock()
+	_, ok := l.peers["a"]
+	l.mu.Unlock()
+	assert.False(t, ok)
+
+	trans.mu.Lock()
+	_, ok = trans.peers["a"]
+	trans.mu.Unlock()
+	assert.False(t, ok, "peer must be released from the transport")
+}
+
+func TestIntrospect(t *testing.T) {
+	l, _ := newTestList(t, []string{"a", "b"}, ConsecutiveFailures(3))
+	defer l.Stop()
+
+	var _ introspection.IntrospectableChooser = l
+	call(t, l, failing("a"))
+	call(t, l, failing("a"))
+
+	status := l.Introspect()
+	assert.Equal(t, "Single", status.Name)
+	assert.Contains(t, status.State, "(0 ejected)")
+	assert.Len(t, status.Peers, 2)
+	assert.Contains(t, peerState(l, "a"), "1 consecutive failures")
+	assert.Equal(t, "Available, 0 pending request(s)", peerState(l, "b"))
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package outlier
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/atomic"
+	"go.uber.org/yarpc/api/peer"
+)
+
+// outlierPeer wraps a peer retained from the real transport. It reports the
+// peer as unavailable while the peer is ejected.
+//
+// All fields except ejected are guarded by the List's mutex.
+type outlierPeer struct {
+	peer.Peer
+
+	// Subscriber from the underlying list.
+	sub peer.Subscriber
+
+	ejected atomic.Bool
+
+	// probing is set after an ejection ends, until the next request to the
+	// peer finishes.
+	probing bool
+
+	// Number of consecutive times the peer was ejected.
+	ejections int
+	timer     *time.Timer
+
+	consecutiveFailures int
+
+	// Ring buffer of the outcomes of the last requests to the peer.
+	results  []bool
+	next     int
+	recorded int
+	failures int
+}
+
+var (
+	_ peer.Peer       = (*outlierPeer)(nil)
+	_ peer.Subscriber = (*outlierPeer)(nil)
+)
+
+func newOutlierPeer(sub peer.Subscriber, window int) *outlierPeer {
+	op := &outlierPeer{sub: sub}
+	if window > 0 {
+		op.results = make([]bool, window)
+	}
+	return op
+}
+
+// Status returns the status of the underlying peer, reporting it as
+// unavailable while it is ejected.
+func (op *outlierPeer) Status() peer.Status {
+	status := op.Peer.Status()
+	if op.ejected.Load() {
+		status.ConnectionStatus = peer.Unavailable
+	}
+	return status
+}
+
+// NotifyStatusChanged forwards status changes of the underlying peer to the
+// underlying list.
+func (op *outlierPeer) NotifyStatusChanged(peer.Identifier) {
+	op.sub.NotifyStatusChanged(op)
+}
+
+// record records the outcome of a request.
+func (op *outlierPeer) record(failed bool) {
+	if failed {
+		op.consecutiveFailures++
+	} else {
+		op.consecutiveFailures = 0
+	}
+
+	if len(op.results) == 0 {
+		return
+	}
+	if op.recorded == len(op.results) {
+		if op.results[op.next] {
+			op.failures--
+		}
+	} else {
+		op.recorded++
+	}
+	op.results[op.next] = failed
+	if failed {
+		op.failures++
+	}
+	op.next = (op.next + 1) % len(op.results)
+}
+
+// shouldEject returns true if the recorded outcomes warrant ejecting the
+// peer.
+func (op *outlierPeer) shouldEject(cfg *listConfig) bool {
+	if cfg.consecutiveFailures > 0 && op.consecutiveFailures >= cfg.consecutiveFailures {
+		return true
+	}
+	if len(op.results) > 0 && op.recorded == len(op.results) {
+		return float64(op.failures) >= cfg.errorRate*float64(op.recorded)
+	}
+	return false
+}
+
+// reset forgets all recorded outcomes.
+func (op *outlierPeer) reset() {
+	op.consecutiveFailures = 0
+	op.next = 0
+	op.recorded = 0
+	op.failures = 0
+}
+
+// restore marks a probed peer as healthy.
+func (op *outlierPeer) restore() {
+	op.ejections = 0
+	op.reset()
+}
+
+// state describes the outlier detection state of the peer for
+// introspection.
+func (op *outlierPeer) state() string {
+	switch {
+	case op.ejected.Load():
+		return "ejected"
+	case op.probing:
+		return "probing"
+	case op.consecutiveFailures > 0:
+		return fmt.Sprintf("%d consecutive failures", op.consecutiveFailures)
+	default:
+		return ""
+	}
+}
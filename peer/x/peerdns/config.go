@@ -0,0 +1,136 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package peerdns
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/x/config"
+)
+
+// Config is the configuration for the DNS peer list updater.
+type Config struct {
+	// DNS name to resolve.
+	Name string `config:"name,interpolate"`
+
+	// Type of record to resolve: SRV, A, or AAAA. Defaults to SRV.
+	Record string `config:"record"`
+
+	// Port of peers resolved from A or AAAA records.
+	Port int `config:"port,interpolate"`
+
+	// host:port addresses of the DNS servers to query. Defaults to the
+	// servers listed in /etc/resolv.conf.
+	Servers []string `config:"servers"`
+
+	// Bounds for how long resolved peers are used. Default to 5 seconds and
+	// 5 minutes.
+	MinTTL time.Duration `config:"minTTL"`
+	MaxTTL time.Duration `config:"maxTTL"`
+
+	// How long to wait for a DNS server to answer. Defaults to 2 seconds.
+	Timeout time.Duration `config:"timeout"`
+}
+
+// Spec returns a configuration specification for the DNS peer list updater,
+// making it possible to keep a peer list up to date with the peers behind a
+// DNS name with transports that use outbound peer list configuration (like
+// HTTP).
+//
+//  cfg := config.New()
+//  cfg.MustRegisterPeerListUpdater(peerdns.Spec())
+//
+// This enables the dns peer list updater:
+//
+//  outbounds:
+//    otherservice:
+//      unary:
+//        http:
+//          url: https://host:port/rpc
+//          round-robin:
+//            dns:
+//              name: _otherservice._tcp.example.com
+//
+// A and AAAA records require a port:
+//
+//  round-robin:
+//    dns:
+//      name: otherservice.example.com
+//      record: A
+//      port: 8080
+//      maxTTL: 1m
+func Spec() config.PeerListUpdaterSpec {
+	return config.PeerListUpdaterSpec{
+		Name:                 "dns",
+		BuildPeerListUpdater: buildPeerListUpdater,
+	}
+}
+
+func buildPeerListUpdater(c Config, kit *config.Kit) (peer.Binder, error) {
+	if c.Name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	opts := []UpdaterOption{Identify(kit.Identify)}
+	switch record := strings.ToUpper(c.Record); record {
+	case "", RecordSRV:
+		if c.Port != 0 {
+			return nil, errors.New("port may not be specified for SRV records")
+		}
+	case RecordA, RecordAAAA:
+		if c.Port <= 0 || c.Port > 65535 {
+			return nil, fmt.Errorf("a valid port is required for %v records, got %d", record, c.Port)
+		}
+		opts = append(opts, Record(record), Port(c.Port))
+	default:
+		return nil, fmt.Errorf("unknown record type %q: must be %q, %q, or %q",
+			c.Record, RecordSRV, RecordA, RecordAAAA)
+	}
+
+	if len(c.Servers) > 0 {
+		opts = append(opts, Servers(c.Servers...))
+	}
+
+	minTTL, maxTTL := defaultUpdaterConfig.minTTL, defaultUpdaterConfig.maxTTL
+	if c.MinTTL != 0 {
+		minTTL = c.MinTTL
+	}
+	if c.MaxTTL != 0 {
+		maxTTL = c.MaxTTL
+	}
+	if minTTL <= 0 || maxTTL < minTTL {
+		return nil, fmt.Errorf("invalid TTL bounds: minTTL %v, maxTTL %v", minTTL, maxTTL)
+	}
+	opts = append(opts, TTL(minTTL, maxTTL))
+
+	if c.Timeout < 0 {
+		return nil, fmt.Errorf("timeout must not be negative: %v", c.Timeout)
+	}
+	if c.Timeout > 0 {
+		opts = append(opts, Timeout(c.Timeout))
+	}
+
+	return Bind(c.Name, opts...), nil
+}
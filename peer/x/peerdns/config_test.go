@@ -0,0 +1,121 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package peerdns
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/x/config"
+)
+
+func TestBuildPeerListUpdater(t *testing.T) {
+	server := newFakeDNS(t)
+	defer server.Close()
+	server.SetRecords(t,
+		"_foo._tcp.example.com. 30 IN SRV 0 0 8080 a.example.com.",
+		"foo.example.com. 30 IN A 10.0.0.1",
+	)
+
+	tests := []struct {
+		desc      string
+		give      Config
+		wantPeers []string
+		wantErr   string
+	}{
+		{
+			desc:      "SRV",
+			give:      Config{Name: "_foo._tcp.example.com", Servers: []string{server.Addr}},
+			wantPeers: []string{"a.example.com:8080"},
+		},
+		{
+			desc: "A with all options",
+			give: Config{
+				Name:    "foo.example.com",
+				Record:  "a",
+				Port:    80,
+				Servers: []string{server.Addr},
+				MinTTL:  time.Second,
+				MaxTTL:  time.Minute,
+				Timeout: time.Second,
+			},
+			wantPeers: []string{"10.0.0.1:80"},
+		},
+		{
+			desc:    "missing name",
+			give:    Config{Record: "SRV"},
+			wantErr: "name is required",
+		},
+		{
+			desc:    "port with SRV",
+			give:    Config{Name: "foo", Port: 80},
+			wantErr: "port may not be specified for SRV records",
+		},
+		{
+			desc:    "missing port",
+			give:    Config{Name: "foo", Record: "AAAA"},
+			wantErr: "a valid port is required for AAAA records, got 0",
+		},
+		{
+			desc:    "unknown record",
+			give:    Config{Name: "foo", Record: "MX"},
+			wantErr: `unknown record type "MX": must be "SRV", "A", or "AAAA"`,
+		},
+		{
+			desc:    "min TTL above max",
+			give:    Config{Name: "foo", MinTTL: time.Hour},
+			wantErr: "invalid TTL bounds: minTTL 1h0m0s, maxTTL 5m0s",
+		},
+		{
+			desc:    "negative timeout",
+			give:    Config{Name: "foo", Timeout: -time.Second},
+			wantErr: "timeout must not be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			bind, err := buildPeerListUpdater(tt.give, &config.Kit{})
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			pl := newRecordingList()
+			u := bind(pl)
+			require.NoError(t, u.Start())
+			assert.Equal(t, tt.wantPeers, pl.Peers())
+			require.NoError(t, u.Stop())
+		})
+	}
+}
+
+func TestSpec(t *testing.T) {
+	spec := Spec()
+	assert.Equal(t, "dns", spec.Name)
+
+	cfg := config.New()
+	require.NoError(t, cfg.RegisterPeerListUpdater(spec))
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package peerdns
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDNS is an in-process DNS server which answers queries from a fixed
+// set of records.
+type fakeDNS struct {
+	Addr string
+
+	mu       sync.Mutex
+	records  []dns.RR
+	rcode    int
+	truncate bool
+	queries  int
+
+	servers []*dns.Server
+}
+
+// newFakeDNS starts a DNS server listening on UDP and TCP on the same port of
+// the loopback interface.
+func newFakeDNS(t *testing.T) *fakeDNS {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	l, err := net.Listen("tcp", pc.LocalAddr().String())
+	require.NoError(t, err)
+
+	f := &fakeDNS{Addr: pc.LocalAddr().String()}
+	f.servers = []*dns.Server{
+		{PacketConn: pc, Handler: f},
+		{Listener: l, Handler: f},
+	}
+	for _, s := range f.servers {
+		started := make(chan struct{})
+		s.NotifyStartedFunc = func() { close(started) }
+		go s.ActivateAndServe()
+		<-started
+	}
+	return f
+}
+
+func (f *fakeDNS) Close() {
+	for _, s := range f.servers {
+		s.Shutdown()
+	}
+}
+
+// SetRecords replaces the records served by the server. Records are given in
+// zone file format.
+func (f *fakeDNS) SetRecords(t *testing.T, records ...string) {
+	rrs := make([]dns.RR, len(records))
+	for i, r := range records {
+		rr, err := dns.NewRR(r)
+		require.NoError(t, err, "invalid record %q", r)
+		rrs[i] = rr
+	}
+
+	f.mu.Lock()
+	f.records = rrs
+	f.rcode = dns.RcodeSuccess
+	f.mu.Unlock()
+}
+
+// SetRcode makes the server fail all queries with the given code.
+func (f *fakeDNS) SetRcode(rcode int) {
+	f.mu.Lock()
+	f.rcode = rcode
+	f.mu.Unlock()
+}
+
+// SetTruncate makes the server send truncated answers over UDP.
+func (f *fakeDNS) SetTruncate(truncate bool) {
+	f.mu.Lock()
+	f.truncate = truncate
+	f.mu.Unlock()
+}
+
+// Queries returns the number of queries received by the server.
+func (f *fakeDNS) Queries() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.queries
+}
+
+func (f *fakeDNS) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queries++
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Rcode = f.rcode
+
+	if f.truncate && w.RemoteAddr().Network() == "udp" {
+		m.Truncated = true
+		w.WriteMsg(m)
+		return
+	}
+
+	if f.rcode == dns.RcodeSuccess {
+		q := r.Question[0]
+		for _, rr := range f.records {
+			hdr := rr.Header()
+			if strings.EqualFold(hdr.Name, q.Name) && (hdr.Rrtype == q.Qtype || hdr.Rrtype == dns.TypeCNAME) {
+				m.Answer = append(m.Answer, rr)
+			}
+		}
+	}
+	w.WriteMsg(m)
+}
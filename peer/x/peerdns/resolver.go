@@ -0,0 +1,170 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package peerdns
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Supported record types.
+const (
+	// RecordSRV resolves SRV records, which provide both, the host and the
+	// port of each peer.
+	RecordSRV = "SRV"
+
+	// RecordA resolves IPv4 addresses. Peers use a fixed port.
+	RecordA = "A"
+
+	// RecordAAAA resolves IPv6 addresses. Peers use a fixed port.
+	RecordAAAA = "AAAA"
+)
+
+// _resolvConf is the file from which DNS servers are read if none were
+// specified.
+var _resolvConf = "/etc/resolv.conf"
+
+// result is the outcome of a successful resolution.
+type result struct {
+	// host:port pairs of the peers.
+	peers []string
+
+	// Smallest TTL of the records.
+	ttl time.Duration
+}
+
+// resolver queries DNS servers for the peers behind a name.
+type resolver struct {
+	udp     *dns.Client
+	tcp     *dns.Client
+	servers []string
+	record  string
+	port    int
+}
+
+func newResolver(servers []string, record string, port int, timeout time.Duration) *resolver {
+	return &resolver{
+		udp:     &dns.Client{Timeout: timeout},
+		tcp:     &dns.Client{Net: "tcp", Timeout: timeout},
+		servers: servers,
+		record:  record,
+		port:    port,
+	}
+}
+
+// defaultServers returns the DNS servers configured for the system.
+func defaultServers() ([]string, error) {
+	cc, err := dns.ClientConfigFromFile(_resolvConf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DNS servers from %q: %v", _resolvConf, err)
+	}
+
+	servers := make([]string, len(cc.Servers))
+	for i, s := range cc.Servers {
+		servers[i] = net.JoinHostPort(s, cc.Port)
+	}
+	return servers, nil
+}
+
+// Resolve resolves the given name into a list of peers.
+func (r *resolver) Resolve(name string) (result, error) {
+	var qtype uint16
+	switch r.record {
+	case RecordSRV:
+		qtype = dns.TypeSRV
+	case RecordA:
+		qtype = dns.TypeA
+	case RecordAAAA:
+		qtype = dns.TypeAAAA
+	default:
+		return result{}, fmt.Errorf("unknown record type %q", r.record)
+	}
+
+	answers, err := r.query(dns.Fqdn(name), qtype)
+	if err != nil {
+		return result{}, err
+	}
+
+	var res result
+	for _, rr := range answers {
+		hdr := rr.Header()
+		if hdr.Rrtype != qtype {
+			// CNAMEs and other records that led to the answers.
+			continue
+		}
+
+		ttl := time.Duration(hdr.Ttl) * time.Second
+		if len(res.peers) == 0 || ttl < res.ttl {
+			res.ttl = ttl
+		}
+
+		switch rr := rr.(type) {
+		case *dns.SRV:
+			host := strings.TrimSuffix(rr.Target, ".")
+			res.peers = append(res.peers, net.JoinHostPort(host, strconv.Itoa(int(rr.Port))))
+		case *dns.A:
+			res.peers = append(res.peers, net.JoinHostPort(rr.A.String(), strconv.Itoa(r.port)))
+		case *dns.AAAA:
+			res.peers = append(res.peers, net.JoinHostPort(rr.AAAA.String(), strconv.Itoa(r.port)))
+		}
+	}
+
+	if len(res.peers) == 0 {
+		return result{}, fmt.Errorf("no %v records found for %q", r.record, name)
+	}
+	return res, nil
+}
+
+// query sends the question to each server in turn until one of them answers.
+func (r *resolver) query(name string, qtype uint16) ([]dns.RR, error) {
+	if len(r.servers) == 0 {
+		return nil, errors.New("no DNS servers to query")
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(name, qtype)
+
+	var errs []string
+	for _, server := range r.servers {
+		resp, _, err := r.udp.Exchange(msg, server)
+		if err == dns.ErrTruncated || (err == nil && resp.Truncated) {
+			// The answer did not fit in a UDP packet.
+			resp, _, err = r.tcp.Exchange(msg, server)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%v: %v", server, err))
+			continue
+		}
+		if resp.Rcode != dns.RcodeSuccess {
+			errs = append(errs, fmt.Sprintf("%v: %v", server, dns.RcodeToString[resp.Rcode]))
+			continue
+		}
+		return resp.Answer, nil
+	}
+
+	return nil, fmt.Errorf("failed to resolve %q: %v", name, strings.Join(errs, "; "))
+}
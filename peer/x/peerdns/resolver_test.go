@@ -0,0 +1,154 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package peerdns
+
+import (
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	server := newFakeDNS(t)
+	defer server.Close()
+
+	server.SetRecords(t,
+		"_foo._tcp.example.com. 30 IN SRV 0 0 8080 a.example.com.",
+		"_foo._tcp.example.com. 10 IN SRV 0 0 8081 b.example.com.",
+		"foo.example.com. 60 IN A 10.0.0.1",
+		"foo.example.com. 20 IN A 10.0.0.2",
+		"foo.example.com. 60 IN AAAA ::1",
+		"bar.example.com. 5 IN CNAME foo.example.com.",
+	)
+
+	tests := []struct {
+		desc    string
+		name    string
+		record  string
+		port    int
+		want    result
+		wantErr string
+	}{
+		{
+			desc:   "SRV",
+			name:   "_foo._tcp.example.com",
+			record: RecordSRV,
+			want: result{
+				peers: []string{"a.example.com:8080", "b.example.com:8081"},
+				ttl:   10 * time.Second,
+			},
+		},
+		{
+			desc:   "A",
+			name:   "foo.example.com.",
+			record: RecordA,
+			port:   80,
+			want: result{
+				peers: []string{"10.0.0.1:80", "10.0.0.2:80"},
+				ttl:   20 * time.Second,
+			},
+		},
+		{
+			desc:   "AAAA",
+			name:   "foo.example.com",
+			record: RecordAAAA,
+			port:   80,
+			want: result{
+				peers: []string{"[::1]:80"},
+				ttl:   60 * time.Second,
+			},
+		},
+		{
+			desc:    "no records",
+			name:    "bar.example.com",
+			record:  RecordA,
+			port:    80,
+			wantErr: `no A records found for "bar.example.com"`,
+		},
+		{
+			desc:    "unknown record",
+			name:    "foo.example.com",
+			record:  "MX",
+			wantErr: `unknown record type "MX"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			r := newResolver([]string{server.Addr}, tt.record, tt.port, time.Second)
+			got, err := r.Resolve(tt.name)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestResolveFallsBackToNextServer(t *testing.T) {
+	bad := newFakeDNS(t)
+	defer bad.Close()
+	bad.SetRcode(dns.RcodeServerFailure)
+
+	good := newFakeDNS(t)
+	defer good.Close()
+	good.SetRecords(t, "foo.example.com. 60 IN A 10.0.0.1")
+
+	r := newResolver([]string{bad.Addr, good.Addr}, RecordA, 80, time.Second)
+	got, err := r.Resolve("foo.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1:80"}, got.peers)
+	assert.Equal(t, 1, bad.Queries())
+}
+
+func TestResolveAllServersFail(t *testing.T) {
+	server := newFakeDNS(t)
+	defer server.Close()
+	server.SetRcode(dns.RcodeNameError)
+
+	r := newResolver([]string{server.Addr}, RecordSRV, 0, time.Second)
+	_, err := r.Resolve("foo.example.com")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `failed to resolve "foo.example.com.": `+server.Addr+": NXDOMAIN")
+
+	r.servers = nil
+	_, err = r.Resolve("foo.example.com")
+	assert.EqualError(t, err, "no DNS servers to query")
+}
+
+func TestResolveTruncated(t *testing.T) {
+	server := newFakeDNS(t)
+	defer server.Close()
+	server.SetRecords(t, "foo.example.com. 60 IN A 10.0.0.1")
+	server.SetTruncate(true)
+
+	r := newResolver([]string{server.Addr}, RecordA, 80, time.Second)
+	got, err := r.Resolve("foo.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1:80"}, got.peers)
+	assert.Equal(t, 2, server.Queries(), "expected a UDP and a TCP query")
+}
@@ -0,0 +1,326 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package peerdns provides a peer list updater which periodically resolves a
+// DNS name and keeps the peer list up to date with the results.
+//
+// SRV records provide both, the host and the port of each peer. A and AAAA
+// records provide addresses which are combined with a fixed port.
+//
+// 	list := roundrobin.New(transport)
+// 	chooser := peer.Bind(list, peerdns.Bind("_myservice._tcp.example.com"))
+//
+// Names are resolved again once the smallest TTL of the records has expired,
+// within configurable bounds. Only the peers that were added or removed since
+// the last resolution are sent to the peer list. If a resolution fails, the
+// peer list keeps the last known set of peers and the name is resolved again
+// with exponential backoff.
+package peerdns
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/peerupdate"
+	ysync "go.uber.org/yarpc/internal/sync"
+	"go.uber.org/yarpc/peer/hostport"
+
+	"go.uber.org/zap"
+)
+
+type updaterConfig struct {
+	record   string
+	port     int
+	servers  []string
+	minTTL   time.Duration
+	maxTTL   time.Duration
+	timeout  time.Duration
+	identify func(string) peer.Identifier
+	logger   *zap.Logger
+}
+
+var defaultUpdaterConfig = updaterConfig{
+	record:   RecordSRV,
+	minTTL:   5 * time.Second,
+	maxTTL:   5 * time.Minute,
+	timeout:  2 * time.Second,
+	identify: hostport.Identify,
+}
+
+// UpdaterOption customizes the behavior of a DNS peer list updater.
+type UpdaterOption func(*updaterConfig)
+
+// Record specifies the type of record to resolve: RecordSRV, RecordA, or
+// RecordAAAA. A and AAAA records require a Port.
+//
+// Defaults to RecordSRV.
+func Record(record string) UpdaterOption {
+	return func(c *updaterConfig) {
+		c.record = record
+	}
+}
+
+// Port specifies the port of peers resolved from A or AAAA records.
+func Port(port int) UpdaterOption {
+	return func(c *updaterConfig) {
+		c.port = port
+	}
+}
+
+// Servers specifies the host:port addresses of the DNS servers to query, in
+// order of preference.
+//
+// Defaults to the servers listed in /etc/resolv.conf.
+func Servers(servers ...string) UpdaterOption {
+	return func(c *updaterConfig) {
+		c.servers = servers
+	}
+}
+
+// TTL bounds how long the resolved peers are used before the name is
+// resolved again. The TTL of the records is used if it lies within these
+// bounds. Failed resolutions are retried after the minimum, backing off up
+// to the maximum.
+//
+// Defaults to 5 seconds and 5 minutes.
+func TTL(min, max time.Duration) UpdaterOption {
+	return func(c *updaterConfig) {
+		c.minTTL = min
+		c.maxTTL = max
+	}
+}
+
+// Timeout specifies how long to wait for a DNS server to answer.
+//
+// Defaults to 2 seconds.
+func Timeout(timeout time.Duration) UpdaterOption {
+	return func(c *updaterConfig) {
+		c.timeout = timeout
+	}
+}
+
+// Identify specifies how host:port pairs are converted into peer
+// identifiers.
+//
+// Defaults to hostport.Identify.
+func Identify(identify func(string) peer.Identifier) UpdaterOption {
+	return func(c *updaterConfig) {
+		c.identify = identify
+	}
+}
+
+// Logger specifies a logger for failed resolutions after the updater has
+// started.
+//
+// Defaults to a no-op logger.
+func Logger(logger *zap.Logger) UpdaterOption {
+	return func(c *updaterConfig) {
+		c.logger = logger
+	}
+}
+
+// Bind returns a binder (suitable as an argument to peer.Bind) that binds a
+// peer list to the peers behind the given DNS name for the duration of its
+// lifecycle.
+func Bind(name string, opts ...UpdaterOption) peer.Binder {
+	return func(pl peer.List) transport.Lifecycle {
+		return NewUpdater(pl, name, opts...)
+	}
+}
+
+// Updater keeps a peer list up to date with the peers behind a DNS name.
+type Updater struct {
+	once ysync.LifecycleOnce
+	pl   peer.List
+	name string
+	cfg  updaterConfig
+	log  *zap.Logger
+
+	loop *peerupdate.Loop
+
+	// Guards the fields below; held while updating the peer list.
+	lock     sync.Mutex
+	resolver *resolver
+	peers    *peerupdate.Peers
+	failures int
+}
+
+// NewUpdater builds an Updater which will send the peers behind the given
+// DNS name to the given peer list once started.
+func NewUpdater(pl peer.List, name string, opts ...UpdaterOption) *Updater {
+	cfg := defaultUpdaterConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+
+	log := cfg.logger
+	if log == nil {
+		log = zap.NewNop()
+	}
+
+	return &Updater{
+		once:  ysync.Once(),
+		pl:    pl,
+		name:  name,
+		cfg:   cfg,
+		log:   log.With(zap.String("name", name)),
+		loop:  peerupdate.NewLoop(),
+		peers: peerupdate.NewPeers(pl),
+	}
+}
+
+// Start resolves the name, adds the peers to the peer list and starts
+// resolving the name periodically. Start fails if the name cannot be
+// resolved.
+func (u *Updater) Start() error {
+	return u.once.Start(u.start)
+}
+
+func (u *Updater) start() error {
+	if err := u.validate(); err != nil {
+		return err
+	}
+
+	servers := u.cfg.servers
+	if len(servers) == 0 {
+		var err error
+		if servers, err = defaultServers(); err != nil {
+			return err
+		}
+	}
+
+	u.resolver = newResolver(servers, u.cfg.record, u.cfg.port, u.cfg.timeout)
+
+	ttl, err := u.refresh()
+	if err != nil {
+		return err
+	}
+	u.loop.Go(func(stop <-chan struct{}) { u.watch(stop, ttl) })
+	return nil
+}
+
+func (u *Updater) validate() error {
+	if u.name == "" {
+		return errors.New("name is required")
+	}
+	switch u.cfg.record {
+	case RecordSRV:
+	case RecordA, RecordAAAA:
+		if u.cfg.port <= 0 || u.cfg.port > 65535 {
+			return fmt.Errorf("a valid port is required for %v records, got %d", u.cfg.record, u.cfg.port)
+		}
+	default:
+		return fmt.Errorf("unknown record type %q: must be %q, %q, or %q",
+			u.cfg.record, RecordSRV, RecordA, RecordAAAA)
+	}
+	if u.cfg.minTTL <= 0 || u.cfg.maxTTL < u.cfg.minTTL {
+		return fmt.Errorf("invalid TTL bounds [%v, %v]", u.cfg.minTTL, u.cfg.maxTTL)
+	}
+	return nil
+}
+
+// Stop stops resolving the name and removes its peers from the peer list.
+func (u *Updater) Stop() error {
+	return u.once.Stop(u.stopResolving)
+}
+
+func (u *Updater) stopResolving() error {
+	u.loop.Stop()
+
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	return u.peers.RemoveAll()
+}
+
+// IsRunning returns whether the updater is resolving the name.
+func (u *Updater) IsRunning() bool {
+	return u.once.IsRunning()
+}
+
+func (u *Updater) watch(stop <-chan struct{}, next time.Duration) {
+	timer := time.NewTimer(next)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-timer.C:
+			next, err := u.refresh()
+			if err != nil {
+				u.log.Warn("Keeping previous peers: failed to refresh peers",
+					zap.Duration("retryIn", next), zap.Error(err))
+			}
+			timer.Reset(next)
+		}
+	}
+}
+
+// refresh resolves the name and sends any changes to the peer list. It
+// returns how long to wait before resolving the name again.
+func (u *Updater) refresh() (time.Duration, error) {
+	res, err := u.resolver.Resolve(u.name)
+
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	if err != nil {
+		u.failures++
+		return u.retryDelay(), err
+	}
+
+	pids := make([]peer.Identifier, len(res.peers))
+	for i, addr := range res.peers {
+		pids[i] = u.cfg.identify(addr)
+	}
+	if err := u.peers.Set(pids); err != nil {
+		u.failures++
+		return u.retryDelay(), err
+	}
+	u.failures = 0
+	return clamp(res.ttl, u.cfg.minTTL, u.cfg.maxTTL), nil
+}
+
+// retryDelay returns how long to wait before retrying after consecutive
+// failed resolutions.
+//
+// Must be run in a mutex.Lock()
+func (u *Updater) retryDelay() time.Duration {
+	d := u.cfg.minTTL
+	for i := 1; i < u.failures && d < u.cfg.maxTTL; i++ {
+		d *= 2
+	}
+	return clamp(d, u.cfg.minTTL, u.cfg.maxTTL)
+}
+
+func clamp(d, min, max time.Duration) time.Duration {
+	if d < min {
+		return min
+	}
+	if d > max {
+		return max
+	}
+	return d
+}
@@ -0,0 +1,311 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package peerdns
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/peer/hostport"
+)
+
+// recordingList is a peer.List which tracks the peers it was given.
+type recordingList struct {
+	mu      sync.Mutex
+	peers   map[string]struct{}
+	updates []peer.ListUpdates
+
+	// Number of updates to reject before accepting them again.
+	failures int
+}
+
+func newRecordingList() *recordingList {
+	return &recordingList{peers: make(map[string]struct{})}
+}
+
+func (l *recordingList) Update(u peer.ListUpdates) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.failures > 0 {
+		l.failures--
+		return errors.New("great sadness")
+	}
+	for _, pid := range u.Removals {
+		delete(l.peers, pid.Identifier())
+	}
+	for _, pid := range u.Additions {
+		l.peers[pid.Identifier()] = struct{}{}
+	}
+	l.updates = append(l.updates, u)
+	return nil
+}
+
+func (l *recordingList) Peers() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	peers := make([]string, 0, len(l.peers))
+	for id := range l.peers {
+		peers = append(peers, id)
+	}
+	sort.Strings(peers)
+	return peers
+}
+
+func (l *recordingList) Updates() []peer.ListUpdates {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]peer.ListUpdates(nil), l.updates...)
+}
+
+func waitFor(t *testing.T, msg string, cond func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %v", msg)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestUpdaterStartStop(t *testing.T) {
+	server := newFakeDNS(t)
+	defer server.Close()
+	server.SetRecords(t,
+		"_foo._tcp.example.com. 30 IN SRV 0 0 8080 a.example.com.",
+		"_foo._tcp.example.com. 30 IN SRV 0 0 8081 a.example.com.",
+	)
+
+	pl := newRecordingList()
+	u := NewUpdater(pl, "_foo._tcp.example.com", Servers(server.Addr))
+	require.NoError(t, u.Start())
+	assert.True(t, u.IsRunning())
+	assert.Equal(t, []string{"a.example.com:8080", "a.example.com:8081"}, pl.Peers())
+
+	require.NoError(t, u.Stop())
+	assert.False(t, u.IsRunning())
+	assert.Empty(t, pl.Peers())
+}
+
+func TestUpdaterSendsDiffs(t *testing.T) {
+	server := newFakeDNS(t)
+	defer server.Close()
+	server.SetRecords(t,
+		"foo.example.com. 0 IN A 10.0.0.1",
+		"foo.example.com. 0 IN A 10.0.0.2",
+	)
+
+	pl := newRecordingList()
+	u := NewUpdater(pl, "foo.example.com",
+		Servers(server.Addr), Record(RecordA), Port(80), TTL(time.Millisecond, time.Second))
+	require.NoError(t, u.Start())
+	defer u.Stop()
+
+	server.SetRecords(t,
+		"foo.example.com. 0 IN A 10.0.0.2",
+		"foo.example.com. 0 IN A 10.0.0.3",
+	)
+	waitFor(t, "peers to change", func() bool {
+		return len(pl.Updates()) == 2
+	})
+
+	assert.Equal(t, []string{"10.0.0.2:80", "10.0.0.3:80"}, pl.Peers())
+	assert.Equal(t, peer.ListUpdates{
+		Additions: []peer.Identifier{hostport.PeerIdentifier("10.0.0.3:80")},
+		Removals:  []peer.Identifier{hostport.PeerIdentifier("10.0.0.1:80")},
+	}, pl.Updates()[1])
+}
+
+func TestUpdaterHonorsTTL(t *testing.T) {
+	server := newFakeDNS(t)
+	defer server.Close()
+	server.SetRecords(t, "foo.example.com. 3600 IN A 10.0.0.1")
+
+	u := NewUpdater(newRecordingList(), "foo.example.com",
+		Servers(server.Addr), Record(RecordA), Port(80), TTL(time.Millisecond, time.Hour))
+	require.NoError(t, u.Start())
+	defer u.Stop()
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, 1, server.Queries(), "name must not be resolved again before the TTL expires")
+}
+
+func TestUpdaterKeepsPeersOnFailure(t *testing.T) {
+	server := newFakeDNS(t)
+	defer server.Close()
+	server.SetRecords(t, "foo.example.com. 0 IN A 10.0.0.1")
+
+	pl := newRecordingList()
+	u := NewUpdater(pl, "foo.example.com",
+		Servers(server.Addr), Record(RecordA), Port(80), TTL(time.Millisecond, 2*time.Millisecond))
+	require.NoError(t, u.Start())
+	defer u.Stop()
+
+	server.SetRcode(dns.RcodeServerFailure)
+	queries := server.Queries()
+	waitFor(t, "failed resolutions", func() bool {
+		return server.Queries() > queries+2
+	})
+	assert.Equal(t, []string{"10.0.0.1:80"}, pl.Peers())
+
+	// No records is a failure too.
+	server.SetRecords(t)
+	queries = server.Queries()
+	waitFor(t, "failed resolutions", func() bool {
+		return server.Queries() > queries+2
+	})
+	assert.Equal(t, []string{"10.0.0.1:80"}, pl.Peers())
+	assert.Len(t, pl.Updates(), 1)
+
+	server.SetRecords(t, "foo.example.com. 0 IN A 10.0.0.2")
+	waitFor(t, "peers to change", func() bool {
+		return len(pl.Updates()) == 2
+	})
+	assert.Equal(t, []string{"10.0.0.2:80"}, pl.Peers())
+}
+
+func TestUpdaterRetriesFailedUpdates(t *testing.T) {
+	server := newFakeDNS(t)
+	defer server.Close()
+	server.SetRecords(t, "foo.example.com. 0 IN A 10.0.0.1")
+
+	pl := newRecordingList()
+	u := NewUpdater(pl, "foo.example.com",
+		Servers(server.Addr), Record(RecordA), Port(80), TTL(time.Millisecond, 2*time.Millisecond))
+	require.NoError(t, u.Start())
+	defer u.Stop()
+
+	pl.mu.Lock()
+	pl.failures = 1
+	pl.mu.Unlock()
+
+	server.SetRecords(t, "foo.example.com. 0 IN A 10.0.0.2")
+	waitFor(t, "peers to change", func() bool {
+		return len(pl.Updates()) == 2
+	})
+	assert.Equal(t, []string{"10.0.0.2:80"}, pl.Peers())
+	assert.Equal(t, peer.ListUpdates{
+		Additions: []peer.Identifier{hostport.PeerIdentifier("10.0.0.2:80")},
+		Removals:  []peer.Identifier{hostport.PeerIdentifier("10.0.0.1:80")},
+	}, pl.Updates()[1])
+}
+
+func TestUpdaterRetryDelay(t *testing.T) {
+	u := NewUpdater(newRecordingList(), "foo", TTL(time.Second, 5*time.Second))
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 5 * time.Second},
+		{100, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		u.failures = tt.failures
+		assert.Equal(t, tt.want, u.retryDelay(), "after %d failures", tt.failures)
+	}
+}
+
+func TestUpdaterStartErrors(t *testing.T) {
+	server := newFakeDNS(t)
+	defer server.Close()
+	server.SetRcode(dns.RcodeNameError)
+
+	tests := []struct {
+		desc    string
+		name    string
+		opts    []UpdaterOption
+		wantErr string
+	}{
+		{
+			desc:    "no name",
+			wantErr: "name is required",
+		},
+		{
+			desc:    "missing port",
+			name:    "foo.example.com",
+			opts:    []UpdaterOption{Record(RecordA)},
+			wantErr: "a valid port is required for A records, got 0",
+		},
+		{
+			desc:    "unknown record",
+			name:    "foo.example.com",
+			opts:    []UpdaterOption{Record("TXT")},
+			wantErr: `unknown record type "TXT"`,
+		},
+		{
+			desc:    "bad TTL bounds",
+			name:    "foo.example.com",
+			opts:    []UpdaterOption{TTL(time.Minute, time.Second)},
+			wantErr: "invalid TTL bounds",
+		},
+		{
+			desc:    "resolution failure",
+			name:    "foo.example.com",
+			opts:    []UpdaterOption{Servers(server.Addr)},
+			wantErr: "NXDOMAIN",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			err := NewUpdater(newRecordingList(), tt.name, tt.opts...).Start()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestDefaultServers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peerdns")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	defer func(old string) { _resolvConf = old }(_resolvConf)
+
+	_resolvConf = filepath.Join(dir, "resolv.conf")
+	_, err = defaultServers()
+	assert.Error(t, err, "missing resolv.conf")
+
+	err = NewUpdater(newRecordingList(), "foo.example.com").Start()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read DNS servers from")
+
+	require.NoError(t, ioutil.WriteFile(_resolvConf,
+		[]byte("nameserver 10.0.0.1\nnameserver 10.0.0.2\n"), 0644))
+	servers, err := defaultServers()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1:53", "10.0.0.2:53"}, servers)
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package peerfile
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/x/config"
+)
+
+// Config is the configuration for the file peer list updater.
+type Config struct {
+	// Path to the file listing the peers.
+	Path string `config:"path,interpolate"`
+
+	// Format of the file: json or yaml. Inferred from the file extension if
+	// unset.
+	Format string `config:"format"`
+
+	// How often the file is checked for changes. Defaults to 5 seconds.
+	Interval time.Duration `config:"interval"`
+}
+
+// Spec returns a configuration specification for the file peer list updater,
+// making it possible to keep a peer list up to date with a file of peers with
+// transports that use outbound peer list configuration (like HTTP).
+//
+//  cfg := config.New()
+//  cfg.MustRegisterPeerListUpdater(peerfile.Spec())
+//
+// This enables the peers-file peer list updater:
+//
+//  outbounds:
+//    otherservice:
+//      unary:
+//        http:
+//          url: https://host:port/rpc
+//          round-robin:
+//            peers-file:
+//              path: /etc/otherservice/peers.yaml
+//              interval: 10s
+func Spec() config.PeerListUpdaterSpec {
+	return config.PeerListUpdaterSpec{
+		Name:                 "peers-file",
+		BuildPeerListUpdater: buildPeerListUpdater,
+	}
+}
+
+func buildPeerListUpdater(c Config, kit *config.Kit) (peer.Binder, error) {
+	if c.Path == "" {
+		return nil, errors.New("path is required")
+	}
+
+	opts := []UpdaterOption{Identify(kit.Identify)}
+	switch c.Format {
+	case "":
+	case FormatJSON, FormatYAML:
+		opts = append(opts, Format(c.Format))
+	default:
+		return nil, fmt.Errorf("unknown format %q: must be %q or %q", c.Format, FormatJSON, FormatYAML)
+	}
+	if c.Interval < 0 {
+		return nil, fmt.Errorf("interval must not be negative: %v", c.Interval)
+	}
+	if c.Interval > 0 {
+		opts = append(opts, Interval(c.Interval))
+	}
+
+	return Bind(c.Path, opts...), nil
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package peerfile
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/x/config"
+)
+
+func TestBuildPeerListUpdater(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	path := filepath.Join(dir, "peers")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"peers": ["a:80"]}`), 0644))
+
+	tests := []struct {
+		desc    string
+		give    Config
+		wantErr string
+	}{
+		{
+			desc: "minimal",
+			give: Config{Path: path},
+		},
+		{
+			desc: "all options",
+			give: Config{Path: path, Format: "json", Interval: time.Second},
+		},
+		{
+			desc:    "missing path",
+			give:    Config{Format: "json"},
+			wantErr: "path is required",
+		},
+		{
+			desc:    "bad format",
+			give:    Config{Path: path, Format: "xml"},
+			wantErr: `unknown format "xml": must be "json" or "yaml"`,
+		},
+		{
+			desc:    "negative interval",
+			give:    Config{Path: path, Interval: -time.Second},
+			wantErr: "interval must not be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			bind, err := buildPeerListUpdater(tt.give, &config.Kit{})
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			pl := newRecordingList()
+			u := bind(pl)
+			require.NoError(t, u.Start())
+			assert.Equal(t, []string{"a:80"}, pl.Peers())
+			require.NoError(t, u.Stop())
+		})
+	}
+}
+
+func TestSpec(t *testing.T) {
+	spec := Spec()
+	assert.Equal(t, "peers-file", spec.Name)
+
+	cfg := config.New()
+	require.NoError(t, cfg.RegisterPeerListUpdater(spec))
+}
@@ -0,0 +1,299 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package peerfile provides a peer list updater which reads peers from a
+// file and keeps the peer list up to date as the file changes.
+//
+// The file holds a list of peers in JSON or YAML, either at the top level or
+// under a "peers" key.
+//
+// 	["127.0.0.1:8080", "127.0.0.1:8081"]
+//
+// 	peers:
+// 	  - 127.0.0.1:8080
+// 	  - 127.0.0.1:8081
+//
+// The file is checked for changes periodically. When its contents change, the
+// peer list receives only the peers that were added or removed. If the file
+// disappears, is empty, or cannot be parsed, the change is ignored and the
+// peer list keeps the last known set of peers; this makes it safe for
+// deployment tooling to rewrite the file in place.
+package peerfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/peerupdate"
+	ysync "go.uber.org/yarpc/internal/sync"
+	"go.uber.org/yarpc/peer/hostport"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+)
+
+// Supported file formats.
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+type updaterConfig struct {
+	format   string
+	interval time.Duration
+	identify func(string) peer.Identifier
+	logger   *zap.Logger
+}
+
+var defaultUpdaterConfig = updaterConfig{
+	interval: 5 * time.Second,
+	identify: hostport.Identify,
+}
+
+// UpdaterOption customizes the behavior of a file peer list updater.
+type UpdaterOption func(*updaterConfig)
+
+// Format specifies the format of the file: FormatJSON or FormatYAML.
+//
+// Defaults to JSON for files with a .json extension and YAML otherwise.
+func Format(format string) UpdaterOption {
+	return func(c *updaterConfig) {
+		c.format = format
+	}
+}
+
+// Interval specifies how often the file is checked for changes.
+//
+// Defaults to 5 seconds.
+func Interval(interval time.Duration) UpdaterOption {
+	return func(c *updaterConfig) {
+		c.interval = interval
+	}
+}
+
+// Identify specifies how entries of the file are converted into peer
+// identifiers.
+//
+// Defaults to hostport.Identify.
+func Identify(identify func(string) peer.Identifier) UpdaterOption {
+	return func(c *updaterConfig) {
+		c.identify = identify
+	}
+}
+
+// Logger specifies a logger for problems encountered while reading the file
+// after the updater has started.
+//
+// Defaults to a no-op logger.
+func Logger(logger *zap.Logger) UpdaterOption {
+	return func(c *updaterConfig) {
+		c.logger = logger
+	}
+}
+
+// Bind returns a binder (suitable as an argument to peer.Bind) that binds a
+// peer list to the peers listed in the file at the given path for the
+// duration of its lifecycle.
+func Bind(path string, opts ...UpdaterOption) peer.Binder {
+	return func(pl peer.List) transport.Lifecycle {
+		return NewUpdater(pl, path, opts...)
+	}
+}
+
+// Updater keeps a peer list up to date with the peers listed in a file.
+type Updater struct {
+	once ysync.LifecycleOnce
+	pl   peer.List
+	path string
+	cfg  updaterConfig
+	log  *zap.Logger
+
+	loop *peerupdate.Loop
+
+	// Guards the fields below; held while updating the peer list.
+	lock     sync.Mutex
+	contents []byte
+	peers    *peerupdate.Peers
+}
+
+// NewUpdater builds an Updater which will send the peers listed in the file
+// at the given path to the given peer list once started.
+func NewUpdater(pl peer.List, path string, opts ...UpdaterOption) *Updater {
+	cfg := defaultUpdaterConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+
+	log := cfg.logger
+	if log == nil {
+		log = zap.NewNop()
+	}
+
+	return &Updater{
+		once:  ysync.Once(),
+		pl:    pl,
+		path:  path,
+		cfg:   cfg,
+		log:   log.With(zap.String("path", path)),
+		loop:  peerupdate.NewLoop(),
+		peers: peerupdate.NewPeers(pl),
+	}
+}
+
+// Start reads the file, adds the listed peers to the peer list and starts
+// watching the file for changes. Start fails if the file cannot be read.
+func (u *Updater) Start() error {
+	return u.once.Start(u.start)
+}
+
+func (u *Updater) start() error {
+	if err := u.refresh(); err != nil {
+		return err
+	}
+	u.loop.Go(u.watch)
+	return nil
+}
+
+// Stop stops watching the file and removes its peers from the peer list.
+func (u *Updater) Stop() error {
+	return u.once.Stop(u.stopWatching)
+}
+
+func (u *Updater) stopWatching() error {
+	u.loop.Stop()
+
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	u.contents = nil
+	return u.peers.RemoveAll()
+}
+
+// IsRunning returns whether the updater is watching the file.
+func (u *Updater) IsRunning() bool {
+	return u.once.IsRunning()
+}
+
+func (u *Updater) watch(stop <-chan struct{}) {
+	ticker := time.NewTicker(u.cfg.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := u.refresh(); err != nil {
+				u.log.Warn("Keeping previous peers: failed to refresh peers from file", zap.Error(err))
+			}
+		}
+	}
+}
+
+// refresh reads the file and sends any changes to the peer list.
+func (u *Updater) refresh() error {
+	contents, err := ioutil.ReadFile(u.path)
+	if err != nil {
+		return err
+	}
+
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	if u.contents != nil && bytes.Equal(contents, u.contents) {
+		return nil
+	}
+
+	names, err := parse(contents, u.format())
+	if err != nil {
+		return fmt.Errorf("failed to parse %q: %v", u.path, err)
+	}
+
+	pids := make([]peer.Identifier, len(names))
+	for i, name := range names {
+		pids[i] = u.cfg.identify(name)
+	}
+	if err := u.peers.Set(pids); err != nil {
+		// The file is read again on the next tick, which retries the
+		// update.
+		return err
+	}
+	u.contents = contents
+	return nil
+}
+
+func (u *Updater) format() string {
+	if u.cfg.format != "" {
+		return u.cfg.format
+	}
+	if strings.EqualFold(filepath.Ext(u.path), ".json") {
+		return FormatJSON
+	}
+	return FormatYAML
+}
+
+// peersFile is the object form of the file.
+type peersFile struct {
+	Peers []string `json:"peers" yaml:"peers"`
+}
+
+// parse parses the list of peers in the given file contents.
+func parse(contents []byte, format string) ([]string, error) {
+	if len(bytes.TrimSpace(contents)) == 0 {
+		// Most likely, the file is in the middle of being rewritten.
+		return nil, errors.New("file is empty")
+	}
+
+	var unmarshal func([]byte, interface{}) error
+	switch format {
+	case FormatJSON:
+		unmarshal = json.Unmarshal
+	case FormatYAML:
+		unmarshal = yaml.Unmarshal
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+
+	var peers []string
+	if err := unmarshal(contents, &peers); err != nil {
+		var f peersFile
+		if unmarshal(contents, &f) != nil || f.Peers == nil {
+			return nil, fmt.Errorf(
+				"expected a list of peers or an object with a %q key: %v", "peers", err)
+		}
+		peers = f.Peers
+	}
+
+	for i, p := range peers {
+		if strings.TrimSpace(p) == "" {
+			return nil, fmt.Errorf("peer %d is empty", i)
+		}
+	}
+	return peers, nil
+}
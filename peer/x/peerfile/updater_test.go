@@ -0,0 +1,346 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package peerfile
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/peer/hostport"
+)
+
+// recordingList is a peer.List which tracks the peers it was given.
+type recordingList struct {
+	mu      sync.Mutex
+	peers   map[string]struct{}
+	updates []peer.ListUpdates
+
+	// Number of updates to reject before accepting them again.
+	failures int
+}
+
+func newRecordingList() *recordingList {
+	return &recordingList{peers: make(map[string]struct{})}
+}
+
+func (l *recordingList) Update(u peer.ListUpdates) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.failures > 0 {
+		l.failures--
+		return errors.New("great sadness")
+	}
+	for _, pid := range u.Removals {
+		delete(l.peers, pid.Identifier())
+	}
+	for _, pid := range u.Additions {
+		l.peers[pid.Identifier()] = struct{}{}
+	}
+	l.updates = append(l.updates, u)
+	return nil
+}
+
+func (l *recordingList) Peers() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	peers := make([]string, 0, len(l.peers))
+	for id := range l.peers {
+		peers = append(peers, id)
+	}
+	sort.Strings(peers)
+	return peers
+}
+
+func (l *recordingList) Updates() []peer.ListUpdates {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]peer.ListUpdates(nil), l.updates...)
+}
+
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "peerfile")
+	require.NoError(t, err)
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func writeFile(t *testing.T, path, contents string) {
+	// Write and rename so that the updater never sees a partial file.
+	tmp := path + ".tmp"
+	require.NoError(t, ioutil.WriteFile(tmp, []byte(contents), 0644))
+	require.NoError(t, os.Rename(tmp, path))
+}
+
+func waitForPeers(t *testing.T, l *recordingList, want []string) {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if assert.ObjectsAreEqual(want, l.Peers()) {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, want, l.Peers(), "timed out waiting for peers")
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		desc     string
+		contents string
+		format   string
+		want     []string
+		wantErr  string
+	}{
+		{
+			desc:     "json list",
+			contents: `["127.0.0.1:8080", "127.0.0.1:8081"]`,
+			format:   FormatJSON,
+			want:     []string{"127.0.0.1:8080", "127.0.0.1:8081"},
+		},
+		{
+			desc:     "json object",
+			contents: `{"peers": ["127.0.0.1:8080"]}`,
+			format:   FormatJSON,
+			want:     []string{"127.0.0.1:8080"},
+		},
+		{
+			desc:     "json empty list",
+			contents: `[]`,
+			format:   FormatJSON,
+			want:     []string{},
+		},
+		{
+			desc:     "yaml list",
+			contents: "- 127.0.0.1:8080\n- 127.0.0.1:8081\n",
+			format:   FormatYAML,
+			want:     []string{"127.0.0.1:8080", "127.0.0.1:8081"},
+		},
+		{
+			desc:     "yaml object",
+			contents: "peers:\n  - 127.0.0.1:8080\n",
+			format:   FormatYAML,
+			want:     []string{"127.0.0.1:8080"},
+		},
+		{
+			desc:     "yaml accepts json",
+			contents: `["127.0.0.1:8080"]`,
+			format:   FormatYAML,
+			want:     []string{"127.0.0.1:8080"},
+		},
+		{
+			desc:     "empty file",
+			contents: " \n",
+			format:   FormatJSON,
+			wantErr:  "file is empty",
+		},
+		{
+			desc:     "truncated json",
+			contents: `["127.0.0.1:8080", "127.0`,
+			format:   FormatJSON,
+			wantErr:  `expected a list of peers or an object with a "peers" key`,
+		},
+		{
+			desc:     "object without peers",
+			contents: "hosts:\n  - 127.0.0.1:8080\n",
+			format:   FormatYAML,
+			wantErr:  `expected a list of peers or an object with a "peers" key`,
+		},
+		{
+			desc:     "empty peer",
+			contents: `["127.0.0.1:8080", ""]`,
+			format:   FormatJSON,
+			wantErr:  "peer 1 is empty",
+		},
+		{
+			desc:     "unknown format",
+			contents: `[]`,
+			format:   "toml",
+			wantErr:  `unknown format "toml"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, err := parse([]byte(tt.contents), tt.format)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestUpdaterStartStop(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	path := filepath.Join(dir, "peers.json")
+	writeFile(t, path, `["127.0.0.1:8080", "127.0.0.1:8081", "127.0.0.1:8080"]`)
+
+	pl := newRecordingList()
+	u := NewUpdater(pl, path, Interval(time.Hour))
+	require.NoError(t, u.Start())
+	assert.True(t, u.IsRunning())
+	assert.Equal(t, []string{"127.0.0.1:8080", "127.0.0.1:8081"}, pl.Peers())
+
+	require.NoError(t, u.Stop())
+	assert.False(t, u.IsRunning())
+	assert.Empty(t, pl.Peers())
+}
+
+func TestUpdaterStartMissingFile(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	u := NewUpdater(newRecordingList(), filepath.Join(dir, "peers.yaml"))
+	assert.Error(t, u.Start())
+}
+
+func TestUpdaterStartMalformedFile(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	path := filepath.Join(dir, "peers.yaml")
+	writeFile(t, path, "peers: {")
+
+	err := NewUpdater(newRecordingList(), path).Start()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse")
+}
+
+func TestUpdaterSendsDiffs(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	path := filepath.Join(dir, "peers.yaml")
+	writeFile(t, path, "- a:80\n- b:80\n")
+
+	pl := newRecordingList()
+	u := NewUpdater(pl, path, Interval(time.Millisecond))
+	require.NoError(t, u.Start())
+	defer u.Stop()
+
+	writeFile(t, path, "- b:80\n- c:80\n")
+	waitForPeers(t, pl, []string{"b:80", "c:80"})
+
+	updates := pl.Updates()
+	require.Len(t, updates, 2)
+	assert.Equal(t, peer.ListUpdates{
+		Additions: []peer.Identifier{hostport.PeerIdentifier("c:80")},
+		Removals:  []peer.Identifier{hostport.PeerIdentifier("a:80")},
+	}, updates[1])
+}
+
+func TestUpdaterKeepsPeersOnBadRewrite(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	path := filepath.Join(dir, "peers.json")
+	writeFile(t, path, `["a:80"]`)
+
+	pl := newRecordingList()
+	u := NewUpdater(pl, path, Interval(time.Millisecond))
+	require.NoError(t, u.Start())
+	defer u.Stop()
+
+	// Malformed, empty, and missing files are all ignored.
+	writeFile(t, path, `["a:80", `)
+	time.Sleep(10 * time.Millisecond)
+	writeFile(t, path, ``)
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, os.Remove(path))
+	time.Sleep(10 * time.Millisecond)
+
+	assert.Equal(t, []string{"a:80"}, pl.Peers())
+	assert.Len(t, pl.Updates(), 1)
+
+	writeFile(t, path, `["b:80"]`)
+	waitForPeers(t, pl, []string{"b:80"})
+}
+
+func TestUpdaterRetriesFailedUpdates(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	path := filepath.Join(dir, "peers.json")
+	writeFile(t, path, `["a:80"]`)
+
+	pl := newRecordingList()
+	u := NewUpdater(pl, path, Interval(time.Millisecond))
+	require.NoError(t, u.Start())
+	defer u.Stop()
+
+	pl.mu.Lock()
+	pl.failures = 1
+	pl.mu.Unlock()
+
+	writeFile(t, path, `["b:80"]`)
+	waitForPeers(t, pl, []string{"b:80"})
+}
+
+func TestUpdaterFormatOverride(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	// YAML with a .json extension.
+	path := filepath.Join(dir, "peers.json")
+	writeFile(t, path, "- a:80\n")
+
+	assert.Error(t, NewUpdater(newRecordingList(), path).Start())
+
+	pl := newRecordingList()
+	u := NewUpdater(pl, path, Format(FormatYAML))
+	require.NoError(t, u.Start())
+	defer u.Stop()
+	assert.Equal(t, []string{"a:80"}, pl.Peers())
+}
+
+type fakeIdentifier string
+
+func (i fakeIdentifier) Identifier() string { return "fake:" + string(i) }
+
+func TestUpdaterIdentify(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	path := filepath.Join(dir, "peers.yaml")
+	writeFile(t, path, "- a\n")
+
+	pl := newRecordingList()
+	u := Bind(path, Identify(func(name string) peer.Identifier {
+		return fakeIdentifier(name)
+	}))(pl)
+	require.NoError(t, u.Start())
+	defer u.Stop()
+	assert.Equal(t, []string{"fake:a"}, pl.Peers())
+}
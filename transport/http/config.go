@@ -21,7 +21,10 @@
 package http
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io/ioutil"
 	"time"
 
 	"go.uber.org/yarpc/api/transport"
@@ -88,6 +91,26 @@ type TransportConfig struct {
 	// Specifies the keep-alive period for all HTTP clients. This field is
 	// optional.
 	KeepAlive time.Duration `config:"keepAlive"`
+
+	// TLS configuration for requests made to "https" URLs. The certificate
+	// and key, if any, are presented to servers that require mutual TLS and
+	// the CA file replaces the system pool for verifying servers.
+	//
+	// 	transports:
+	// 	  http:
+	// 	    tls:
+	// 	      certFile: /etc/myservice/client.pem
+	// 	      keyFile: /etc/myservice/client-key.pem
+	// 	      caFile: /etc/myservice/ca.pem
+	TLS TLSConfig `config:"tls"`
+
+	// Send requests over HTTP/2 without TLS, assuming prior knowledge that
+	// peers support it. See ClientH2C.
+	//
+	// 	transports:
+	// 	  http:
+	// 	    h2c: true
+	H2C bool `config:"h2c"`
 }
 
 func (ts *transportSpec) buildTransport(tc *TransportConfig, k *config.Kit) (transport.Transport, error) {
@@ -95,6 +118,26 @@ func (ts *transportSpec) buildTransport(tc *TransportConfig, k *config.Kit) (tra
 	if tc.KeepAlive > 0 {
 		opts = append(opts, KeepAlive(tc.KeepAlive))
 	}
+	if tc.H2C {
+		opts = append(opts, ClientH2C())
+	}
+
+	cert, err := tc.TLS.certificate()
+	if err != nil {
+		return nil, fmt.Errorf("cannot load client certificate: %v", err)
+	}
+	if cert != nil {
+		opts = append(opts, ClientCertificate(*cert))
+	}
+
+	pool, err := tc.TLS.certPool()
+	if err != nil {
+		return nil, fmt.Errorf("cannot load root CAs: %v", err)
+	}
+	if pool != nil {
+		opts = append(opts, RootCAs(pool))
+	}
+
 	return NewTransport(opts...), nil
 }
 
@@ -105,14 +148,153 @@ func (ts *transportSpec) buildTransport(tc *TransportConfig, k *config.Kit) (tra
 // 	    address: ":80"
 type InboundConfig struct {
 	// Address to listen on. This field is required.
+	//
+	// The inbound listens on a unix domain socket if the address has the
+	// form "unix:///path/to/socket".
 	Address string `config:"address,interpolate"`
+
+	// TLS configuration for the inbound. If a certificate and key are
+	// provided, the inbound serves requests over TLS. If a CA file is also
+	// provided, clients must present a certificate signed by one of those
+	// authorities.
+	//
+	// 	inbounds:
+	// 	  http:
+	// 	    address: ":443"
+	// 	    tls:
+	// 	      certFile: /etc/myservice/server.pem
+	// 	      keyFile: /etc/myservice/server-key.pem
+	// 	      caFile: /etc/myservice/ca.pem
+	TLS TLSConfig `config:"tls"`
+
+	// Path on which the inbound answers GET requests with the readiness of
+	// the service. See HealthPath.
+	//
+	// 	inbounds:
+	// 	  http:
+	// 	    address: ":80"
+	// 	    healthPath: /health
+	HealthPath string `config:"healthPath,interpolate"`
+
+	// Accept HTTP/2 connections over cleartext in addition to HTTP/1.1. See
+	// ServerH2C.
+	//
+	// 	inbounds:
+	// 	  http:
+	// 	    address: ":80"
+	// 	    h2c: true
+	H2C bool `config:"h2c"`
+
+	// Name of the compressor with which request bodies may be compressed
+	// and with which response bodies are compressed for callers that accept
+	// it. See InboundCompressor.
+	//
+	// 	inbounds:
+	// 	  http:
+	// 	    address: ":80"
+	// 	    compressor: gzip
+	// 	    minCompressSize: 4096
+	Compressor string `config:"compressor,interpolate"`
+
+	// Size, in bytes, under which response bodies are sent uncompressed.
+	// Defaults to 1024.
+	MinCompressSize int `config:"minCompressSize"`
 }
 
 func (ts *transportSpec) buildInbound(ic *InboundConfig, t transport.Transport, k *config.Kit) (transport.Inbound, error) {
 	if ic.Address == "" {
 		return nil, fmt.Errorf("inbound address is required")
 	}
-	return t.(*Transport).NewInbound(ic.Address, ts.InboundOptions...), nil
+
+	opts := ts.InboundOptions
+	cert, err := ic.TLS.certificate()
+	if err != nil {
+		return nil, fmt.Errorf("cannot load server certificate: %v", err)
+	}
+	if cert != nil {
+		opts = append(opts, ServerCertificate(*cert))
+	}
+
+	pool, err := ic.TLS.certPool()
+	if err != nil {
+		return nil, fmt.Errorf("cannot load client CAs: %v", err)
+	}
+	if pool != nil {
+		if cert == nil {
+			return nil, fmt.Errorf("inbound TLS certFile and keyFile are required with caFile")
+		}
+		opts = append(opts, ClientCAs(pool))
+	}
+
+	if ic.HealthPath != "" {
+		opts = append(opts, HealthPath(ic.HealthPath))
+	}
+	if ic.H2C {
+		opts = append(opts, ServerH2C())
+	}
+	if ic.Compressor != "" {
+		c, err := k.Compressor(ic.Compressor)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, InboundCompressor(c))
+	}
+	if ic.MinCompressSize > 0 {
+		opts = append(opts, InboundMinCompressSize(ic.MinCompressSize))
+	}
+
+	return t.(*Transport).NewInbound(ic.Address, opts...), nil
+}
+
+// TLSConfig specifies PEM-encoded files used to configure TLS for HTTP
+// inbounds and outbounds.
+type TLSConfig struct {
+	// Path to the certificate chain presented to the other side of the
+	// connection. Requires KeyFile.
+	CertFile string `config:"certFile,interpolate"`
+
+	// Path to the private key for the certificate in CertFile.
+	KeyFile string `config:"keyFile,interpolate"`
+
+	// Path to the certificate authorities used to verify the other side of
+	// the connection.
+	CAFile string `config:"caFile,interpolate"`
+}
+
+// certificate loads the configured key pair or returns nil if one wasn't
+// configured.
+func (c TLSConfig) certificate() (*tls.Certificate, error) {
+	if c.CertFile == "" && c.KeyFile == "" {
+		return nil, nil
+	}
+	if c.CertFile == "" || c.KeyFile == "" {
+		return nil, fmt.Errorf("both certFile and keyFile are required")
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// certPool loads the configured certificate authorities or returns nil if a
+// CA file wasn't configured.
+func (c TLSConfig) certPool() (*x509.CertPool, error) {
+	if c.CAFile == "" {
+		return nil, nil
+	}
+
+	pem, err := ioutil.ReadFile(c.CAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %q", c.CAFile)
+	}
+	return pool, nil
 }
 
 // OutboundConfig configures an HTTP outbound.
@@ -146,6 +328,16 @@ func (ts *transportSpec) buildInbound(ic *InboundConfig, t transport.Transport,
 //            peers:
 //              - 127.0.0.1:8080
 //              - 127.0.0.1:8081
+//
+// Peers listening on unix domain sockets are identified by
+// "unix:///path/to/socket" addresses. Requests to these peers use the
+// "localhost" host.
+//
+//  outbounds:
+//    keyvalueservice:
+//      http:
+//        url: "http://localhost/rpc"
+//        peer: unix:///var/run/keyvalue.sock
 type OutboundConfig struct {
 	config.PeerChooser
 
@@ -162,6 +354,19 @@ type OutboundConfig struct {
 	//      X-Caller: myserice
 	//      X-Token: foo
 	AddHeaders map[string]string `config:"addHeaders"`
+
+	// Name of the compressor with which request bodies are compressed. See
+	// OutboundCompressor.
+	//
+	//  http:
+	//    url: "http://localhost:8080/yarpc"
+	//    compressor: gzip
+	//    minCompressSize: 4096
+	Compressor string `config:"compressor,interpolate"`
+
+	// Size, in bytes, under which request bodies are sent uncompressed.
+	// Defaults to 1024.
+	MinCompressSize int `config:"minCompressSize"`
 }
 
 func (ts *transportSpec) buildOutbound(oc *OutboundConfig, t transport.Transport, k *config.Kit) (*Outbound, error) {
@@ -173,6 +378,16 @@ func (ts *transportSpec) buildOutbound(oc *OutboundConfig, t transport.Transport
 			opts = append(opts, AddHeader(k, v))
 		}
 	}
+	if oc.Compressor != "" {
+		c, err := k.Compressor(oc.Compressor)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, OutboundCompressor(c))
+	}
+	if oc.MinCompressSize > 0 {
+		opts = append(opts, OutboundMinCompressSize(oc.MinCompressSize))
+	}
 
 	// Special case where the URL implies the single peer.
 	if oc.Empty() {
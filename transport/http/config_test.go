@@ -26,6 +26,10 @@ import (
 	"testing"
 	"time"
 
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/compressor/gzip"
+	"go.uber.org/yarpc/compressor/snappy"
+	"go.uber.org/yarpc/internal/compress"
 	"go.uber.org/yarpc/x/config"
 
 	"github.com/stretchr/testify/assert"
@@ -57,9 +61,16 @@ func TestTransportSpec(t *testing.T) {
 	}
 
 	type wantInbound struct {
-		Address    string
-		Mux        *http.ServeMux
-		MuxPattern string
+		Address      string
+		Mux          *http.ServeMux
+		MuxPattern   string
+		Certificates int
+		ClientCAs    bool
+		HealthPath   string
+		H2C          bool
+
+		Compressor      string
+		MinCompressSize int // defaults to compress.DefaultMinSize
 	}
 
 	type inboundTest struct {
@@ -77,6 +88,9 @@ func TestTransportSpec(t *testing.T) {
 	type wantOutbound struct {
 		URLTemplate string
 		Headers     http.Header
+
+		Compressor      string
+		MinCompressSize int // defaults to compress.DefaultMinSize
 	}
 
 	type outboundTest struct {
@@ -118,6 +132,31 @@ func TestTransportSpec(t *testing.T) {
 				MaxIdleConnsPerHost: 2,
 			},
 		},
+		{
+			desc: "transport tls config",
+			cfg: attrs{
+				"tls": attrs{
+					"certFile": "testdata/client.pem",
+					"keyFile":  "testdata/client-key.pem",
+					"caFile":   "testdata/ca.pem",
+				},
+			},
+			wantClient: &wantHTTPClient{
+				KeepAlive:           30 * time.Second,
+				MaxIdleConnsPerHost: 2,
+				ClientCertificates:  1,
+				RootCAs:             true,
+			},
+		},
+		{
+			desc: "transport h2c",
+			cfg:  attrs{"h2c": true},
+			wantClient: &wantHTTPClient{
+				KeepAlive:           30 * time.Second,
+				MaxIdleConnsPerHost: 2,
+				H2C:                 true,
+			},
+		},
 	}
 
 	serveMux := http.NewServeMux()
@@ -152,6 +191,82 @@ func TestTransportSpec(t *testing.T) {
 				MuxPattern: "/yarpc",
 			},
 		},
+		{
+			desc: "inbound tls",
+			cfg: attrs{
+				"address": ":8443",
+				"tls": attrs{
+					"certFile": "testdata/server.pem",
+					"keyFile":  "testdata/server-key.pem",
+				},
+			},
+			wantInbound: &wantInbound{Address: ":8443", Certificates: 1},
+		},
+		{
+			desc: "inbound mutual tls",
+			cfg: attrs{
+				"address": ":8443",
+				"tls": attrs{
+					"certFile": "testdata/server.pem",
+					"keyFile":  "testdata/server-key.pem",
+					"caFile":   "testdata/ca.pem",
+				},
+			},
+			wantInbound: &wantInbound{Address: ":8443", Certificates: 1, ClientCAs: true},
+		},
+		{
+			desc:        "inbound unix socket",
+			cfg:         attrs{"address": "unix:///var/run/myservice.sock"},
+			wantInbound: &wantInbound{Address: "unix:///var/run/myservice.sock"},
+		},
+		{
+			desc:        "inbound health path",
+			cfg:         attrs{"address": ":8080", "healthPath": "/health"},
+			wantInbound: &wantInbound{Address: ":8080", HealthPath: "/health"},
+		},
+		{
+			desc:        "inbound h2c",
+			cfg:         attrs{"address": ":8080", "h2c": true},
+			wantInbound: &wantInbound{Address: ":8080", H2C: true},
+		},
+		{
+			desc:        "inbound compressor",
+			cfg:         attrs{"address": ":8080", "compressor": "snappy"},
+			wantInbound: &wantInbound{Address: ":8080", Compressor: "snappy"},
+		},
+		{
+			desc: "inbound compressor minimum size",
+			cfg: attrs{
+				"address":         ":8080",
+				"compressor":      "gzip",
+				"minCompressSize": 42,
+			},
+			wantInbound: &wantInbound{Address: ":8080", Compressor: "gzip", MinCompressSize: 42},
+		},
+		{
+			desc:       "inbound unknown compressor",
+			cfg:        attrs{"address": ":8080", "compressor": "lz4"},
+			wantErrors: []string{`no recognized compressor "lz4"`},
+		},
+		{
+			desc: "inbound tls missing certificate file",
+			cfg: attrs{
+				"address": ":8443",
+				"tls": attrs{
+					"certFile": "testdata/does-not-exist.pem",
+					"keyFile":  "testdata/server-key.pem",
+				},
+			},
+			wantErrors: []string{"cannot load server certificate"},
+		},
+		{
+			desc: "inbound tls CA without certificate",
+			cfg: attrs{
+				"address": ":8443",
+				"tls":     attrs{"caFile": "testdata/ca.pem"},
+			},
+			wantErrors: []string{"inbound TLS certFile and keyFile are required with caFile"},
+		},
 	}
 
 	outboundTests := []outboundTest{
@@ -183,6 +298,35 @@ func TestTransportSpec(t *testing.T) {
 				},
 			},
 		},
+		{
+			desc: "outbound unix socket",
+			cfg: attrs{
+				"myservice": attrs{
+					"http": attrs{"url": "unix:///var/run/myservice.sock"},
+				},
+			},
+			wantOutbounds: map[string]wantOutbound{
+				"myservice": {
+					URLTemplate: "http://localhost",
+				},
+			},
+		},
+		{
+			desc: "outbound unix socket peer",
+			cfg: attrs{
+				"myservice": attrs{
+					"http": attrs{
+						"url":  "http://localhost/yarpc",
+						"peer": "unix:///var/run/myservice.sock",
+					},
+				},
+			},
+			wantOutbounds: map[string]wantOutbound{
+				"myservice": {
+					URLTemplate: "http://localhost/yarpc",
+				},
+			},
+		},
 		{
 			desc: "outbound url template option",
 			opts: []Option{
@@ -286,6 +430,55 @@ func TestTransportSpec(t *testing.T) {
 				},
 			},
 		},
+		{
+			desc: "outbound compressor",
+			cfg: attrs{
+				"myservice": attrs{
+					"http": attrs{
+						"url":             "http://localhost/yarpc",
+						"compressor":      "gzip",
+						"minCompressSize": 4096,
+					},
+				},
+			},
+			wantOutbounds: map[string]wantOutbound{
+				"myservice": {
+					URLTemplate:     "http://localhost/yarpc",
+					Compressor:      "gzip",
+					MinCompressSize: 4096,
+				},
+			},
+		},
+		{
+			desc: "outbound compressor with peer",
+			cfg: attrs{
+				"myservice": attrs{
+					"http": attrs{
+						"url":        "http://localhost/yarpc",
+						"peer":       "127.0.0.1:8080",
+						"compressor": "snappy",
+					},
+				},
+			},
+			wantOutbounds: map[string]wantOutbound{
+				"myservice": {
+					URLTemplate: "http://localhost/yarpc",
+					Compressor:  "snappy",
+				},
+			},
+		},
+		{
+			desc: "outbound unknown compressor",
+			cfg: attrs{
+				"myservice": attrs{
+					"http": attrs{
+						"url":        "http://localhost/yarpc",
+						"compressor": "lz4",
+					},
+				},
+			},
+			wantErrors: []string{`no recognized compressor "lz4"`},
+		},
 		{
 			desc: "outbound peer build error",
 			cfg: attrs{
@@ -337,6 +530,8 @@ func TestTransportSpec(t *testing.T) {
 			env[k] = v
 		}
 		configurator := config.New(config.InterpolationResolver(mapResolver(env)))
+		configurator.MustRegisterCompressor(gzip.New())
+		configurator.MustRegisterCompressor(snappy.New())
 
 		opts := append(append(trans.opts, inbound.opts...), outbound.opts...)
 		if trans.wantClient != nil {
@@ -377,6 +572,14 @@ func TestTransportSpec(t *testing.T) {
 					"inbound mux pattern should match")
 				assert.True(t, want.Mux == ib.mux, "inbound mux should match")
 				// == because we want it to be the same object
+				assert.Len(t, ib.certificates, want.Certificates,
+					"inbound certificates should match")
+				assert.Equal(t, want.ClientCAs, ib.clientCAs != nil,
+					"inbound client CAs should match")
+				assert.Equal(t, want.HealthPath, ib.healthPath,
+					"inbound health path should match")
+				assert.Equal(t, want.H2C, ib.h2c, "inbound h2c should match")
+				assertCompression(t, want.Compressor, want.MinCompressSize, ib.compressor, ib.minCompressSize)
 			}
 		}
 
@@ -389,6 +592,7 @@ func TestTransportSpec(t *testing.T) {
 
 				assert.Equal(t, want.URLTemplate, ob.urlTemplate.String(), "outbound URLTemplate should match")
 				assert.Equal(t, want.Headers, ob.headers, "outbound headers should match")
+				assertCompression(t, want.Compressor, want.MinCompressSize, ob.compressor, ob.minCompressSize)
 			}
 
 		}
@@ -412,6 +616,69 @@ func TestTransportSpec(t *testing.T) {
 	}
 }
 
+func TestTransportSpecTLSErrors(t *testing.T) {
+	// Failures to build the transport prevent any inbounds or outbounds from
+	// being built so these can't be part of the cross-product above.
+	type attrs map[string]interface{}
+
+	tests := []struct {
+		desc       string
+		tls        attrs
+		wantErrors []string
+	}{
+		{
+			desc: "missing key",
+			tls:  attrs{"certFile": "testdata/client.pem"},
+			wantErrors: []string{
+				"cannot load client certificate",
+				"both certFile and keyFile are required",
+			},
+		},
+		{
+			desc: "missing certificate file",
+			tls: attrs{
+				"certFile": "testdata/does-not-exist.pem",
+				"keyFile":  "testdata/client-key.pem",
+			},
+			wantErrors: []string{"cannot load client certificate"},
+		},
+		{
+			desc:       "missing CA file",
+			tls:        attrs{"caFile": "testdata/does-not-exist.pem"},
+			wantErrors: []string{"cannot load root CAs"},
+		},
+		{
+			desc: "invalid CA file",
+			tls:  attrs{"caFile": "testdata/client-key.pem"},
+			wantErrors: []string{
+				"cannot load root CAs",
+				`no certificates found in "testdata/client-key.pem"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			configurator := config.New()
+			require.NoError(t, configurator.RegisterTransport(TransportSpec()),
+				"failed to register transport spec")
+
+			_, err := configurator.LoadConfig("foo", attrs{
+				"transports": attrs{"http": attrs{"tls": tt.tls}},
+				"outbounds": attrs{
+					"myservice": attrs{
+						"http": attrs{"url": "https://localhost/yarpc"},
+					},
+				},
+			})
+			require.Error(t, err, "expected failure while loading config")
+			for _, msg := range tt.wantErrors {
+				assert.Contains(t, err.Error(), msg)
+			}
+		})
+	}
+}
+
 func mapResolver(m map[string]string) func(string) (string, bool) {
 	return func(k string) (v string, ok bool) {
 		if m != nil {
@@ -421,9 +688,27 @@ func mapResolver(m map[string]string) func(string) (string, bool) {
 	}
 }
 
+// assertCompression verifies the name of the configured compressor and the
+// minimum compression size, which defaults to compress.DefaultMinSize.
+func assertCompression(t *testing.T, wantName string, wantMinSize int, c transport.Compressor, minSize int) {
+	var name string
+	if c != nil {
+		name = c.Name()
+	}
+	assert.Equal(t, wantName, name, "compressor should match")
+
+	if wantMinSize == 0 {
+		wantMinSize = compress.DefaultMinSize
+	}
+	assert.Equal(t, wantMinSize, minSize, "minimum compression size should match")
+}
+
 type wantHTTPClient struct {
 	KeepAlive           time.Duration
 	MaxIdleConnsPerHost int
+	ClientCertificates  int
+	RootCAs             bool
+	H2C                 bool
 }
 
 // useFakeBuildClient verifies the configuration we use to build an HTTP
@@ -433,6 +718,11 @@ func useFakeBuildClient(t *testing.T, want *wantHTTPClient) TransportOption {
 		assert.Equal(t, want.KeepAlive, cfg.keepAlive, "http.Client: KeepAlive should match")
 		assert.Equal(t, want.MaxIdleConnsPerHost, cfg.maxIdleConnsPerHost,
 			"http.Client: MaxIdleConnsPerHost should match")
+		assert.Len(t, cfg.clientCertificates, want.ClientCertificates,
+			"http.Client: client certificates should match")
+		assert.Equal(t, want.RootCAs, cfg.rootCAs != nil,
+			"http.Client: RootCAs should match")
+		assert.Equal(t, want.H2C, cfg.h2c, "http.Client: H2C should match")
 		return buildHTTPClient(cfg)
 	})
 }
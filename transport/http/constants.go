@@ -58,6 +58,21 @@ const (
 
 	// Whether the response body contains an application error.
 	ApplicationStatusHeader = "Rpc-Status"
+
+	// Name of the yarpcerrors.Code of a failed request, for example,
+	// "not-found". The response body contains the error message.
+	ErrorCodeHeader = "Rpc-Error-Code"
+
+	// Base64-encoded details of a failed request, if any.
+	ErrorDetailsHeader = "Rpc-Error-Details"
+
+	// Name of the compressor used for the request or response body, if it
+	// was compressed, for example, "gzip".
+	ContentEncodingHeader = "Content-Encoding"
+
+	// Comma-separated names of the compressors that the caller accepts for
+	// the response body.
+	AcceptEncodingHeader = "Accept-Encoding"
 )
 
 // Valid values for the Rpc-Status header.
@@ -48,6 +48,29 @@
 // 		},
 // 	})
 //
+// TLS
+//
+// An HTTP inbound serves requests over TLS if it is given a certificate with
+// the ServerCertificate option. Mutual TLS is enabled with the ClientCAs
+// option, in which case clients must present a certificate signed by one of
+// the given certificate authorities.
+//
+// 	myInbound := httpTransport.NewInbound(":8443",
+// 		http.ServerCertificate(serverCert),
+// 		http.ClientCAs(clientCAPool),
+// 	)
+//
+// Handlers may inspect the verified identity of the client with the
+// PeerIdentity method on encoding.Call.
+//
+// Outbounds use TLS for "https" URLs. The certificate presented by the client
+// and the authorities used to verify servers are configured on the transport.
+//
+// 	httpTransport := http.NewTransport(
+// 		http.ClientCertificate(clientCert),
+// 		http.RootCAs(serverCAPool),
+// 	)
+//
 // Note that stopping an HTTP transport does NOT immediately terminate ongoing
 // requests. Connections will remain open until all clients have disconnected.
 //
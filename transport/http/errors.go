@@ -0,0 +1,111 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package http
+
+import (
+	"encoding/base64"
+	"net/http"
+
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// _statusClientClosedRequest is the non-standard status code used by some
+// proxies for requests cancelled by the client.
+const _statusClientClosedRequest = 499
+
+// _codeToHTTPStatusCode maps yarpcerrors codes to the HTTP status codes sent
+// with them. Because multiple codes share a status code, the code itself is
+// sent in the ErrorCodeHeader.
+var _codeToHTTPStatusCode = map[yarpcerrors.Code]int{
+	yarpcerrors.CodeCancelled:          _statusClientClosedRequest,
+	yarpcerrors.CodeUnknown:            http.StatusInternalServerError,
+	yarpcerrors.CodeInvalidArgument:    http.StatusBadRequest,
+	yarpcerrors.CodeDeadlineExceeded:   http.StatusGatewayTimeout,
+	yarpcerrors.CodeNotFound:           http.StatusNotFound,
+	yarpcerrors.CodeAlreadyExists:      http.StatusConflict,
+	yarpcerrors.CodePermissionDenied:   http.StatusForbidden,
+	yarpcerrors.CodeResourceExhausted:  http.StatusTooManyRequests,
+	yarpcerrors.CodeFailedPrecondition: http.StatusBadRequest,
+	yarpcerrors.CodeAborted:            http.StatusConflict,
+	yarpcerrors.CodeOutOfRange:         http.StatusBadRequest,
+	yarpcerrors.CodeUnimplemented:      http.StatusNotImplemented,
+	yarpcerrors.CodeInternal:           http.StatusInternalServerError,
+	yarpcerrors.CodeUnavailable:        http.StatusServiceUnavailable,
+	yarpcerrors.CodeDataLoss:           http.StatusInternalServerError,
+	yarpcerrors.CodeUnauthenticated:    http.StatusUnauthorized,
+}
+
+// _httpStatusCodeToCode is used for responses that don't specify an
+// ErrorCodeHeader, for example, responses from servers that don't use YARPC.
+var _httpStatusCodeToCode = map[int]yarpcerrors.Code{
+	_statusClientClosedRequest:     yarpcerrors.CodeCancelled,
+	http.StatusBadRequest:          yarpcerrors.CodeInvalidArgument,
+	http.StatusUnauthorized:        yarpcerrors.CodeUnauthenticated,
+	http.StatusForbidden:           yarpcerrors.CodePermissionDenied,
+	http.StatusNotFound:            yarpcerrors.CodeNotFound,
+	http.StatusConflict:            yarpcerrors.CodeAlreadyExists,
+	http.StatusTooManyRequests:     yarpcerrors.CodeResourceExhausted,
+	http.StatusInternalServerError: yarpcerrors.CodeUnknown,
+	http.StatusNotImplemented:      yarpcerrors.CodeUnimplemented,
+	http.StatusServiceUnavailable:  yarpcerrors.CodeUnavailable,
+	http.StatusGatewayTimeout:      yarpcerrors.CodeDeadlineExceeded,
+}
+
+// writeStatus writes the given Status to the HTTP response.
+func writeStatus(w http.ResponseWriter, status *yarpcerrors.Status) {
+	statusCode, ok := _codeToHTTPStatusCode[status.Code()]
+	if !ok {
+		statusCode = http.StatusInternalServerError
+	}
+
+	w.Header().Set(ErrorCodeHeader, status.Code().String())
+	if details := status.Details(); len(details) > 0 {
+		w.Header().Set(ErrorDetailsHeader, base64.StdEncoding.EncodeToString(details))
+	}
+	http.Error(w, status.Message(), statusCode)
+}
+
+// readStatus builds a Status from an HTTP error response with the given
+// message.
+func readStatus(response *http.Response, message string) *yarpcerrors.Status {
+	var code yarpcerrors.Code
+	if err := code.UnmarshalText([]byte(response.Header.Get(ErrorCodeHeader))); err != nil {
+		code = statusCodeToCode(response.StatusCode)
+	}
+
+	status := yarpcerrors.Newf(code, "%s", message)
+	if encoded := response.Header.Get(ErrorDetailsHeader); encoded != "" {
+		if details, err := base64.StdEncoding.DecodeString(encoded); err == nil {
+			status = status.WithDetails(details)
+		}
+	}
+	return status
+}
+
+func statusCodeToCode(statusCode int) yarpcerrors.Code {
+	if code, ok := _httpStatusCodeToCode[statusCode]; ok {
+		return code
+	}
+	if statusCode >= 400 && statusCode < 500 {
+		return yarpcerrors.CodeInvalidArgument
+	}
+	return yarpcerrors.CodeUnknown
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/encoding/raw"
+	"go.uber.org/yarpc/yarpcerrors"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusRoundTrip(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	httpTransport := NewTransport()
+	i := httpTransport.NewInbound("127.0.0.1:0")
+	h := transporttest.NewMockUnaryHandler(mockCtrl)
+	router := transporttest.NewMockRouter(mockCtrl)
+	router.EXPECT().Choose(gomock.Any(), gomock.Any()).
+		Return(transport.NewUnaryHandlerSpec(h), nil).AnyTimes()
+	i.SetRouter(router)
+	require.NoError(t, i.Start(), "failed to start inbound")
+	defer i.Stop()
+
+	o := httpTransport.NewSingleOutbound(fmt.Sprintf("http://%v", i.Addr()))
+	require.NoError(t, o.Start(), "failed to start outbound")
+	defer o.Stop()
+
+	for code := range _codeToHTTPStatusCode {
+		t.Run(code.String(), func(t *testing.T) {
+			want := yarpcerrors.Newf(code, "great sadness").WithDetails([]byte{0x00, 0xff})
+			h.EXPECT().Handle(gomock.Any(), gomock.Any(), gomock.Any()).Return(want)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			_, err := o.Call(ctx, &transport.Request{
+				Caller:    "caller",
+				Service:   "service",
+				Procedure: "hello",
+				Encoding:  raw.Encoding,
+				Body:      bytes.NewReader([]byte("world")),
+			})
+			require.Error(t, err)
+
+			got := yarpcerrors.FromError(err)
+			assert.Equal(t, code, got.Code())
+			assert.Equal(t, "great sadness", got.Message())
+			assert.Equal(t, []byte{0x00, 0xff}, got.Details())
+		})
+	}
+}
+
+func TestStatusWithoutErrorCodeHeader(t *testing.T) {
+	tests := []struct {
+		statusCode int
+		want       yarpcerrors.Code
+	}{
+		{http.StatusBadRequest, yarpcerrors.CodeInvalidArgument},
+		{http.StatusNotFound, yarpcerrors.CodeNotFound},
+		{http.StatusMethodNotAllowed, yarpcerrors.CodeInvalidArgument},
+		{http.StatusTooManyRequests, yarpcerrors.CodeResourceExhausted},
+		{http.StatusInternalServerError, yarpcerrors.CodeUnknown},
+		{http.StatusBadGateway, yarpcerrors.CodeUnknown},
+		{http.StatusServiceUnavailable, yarpcerrors.CodeUnavailable},
+		{http.StatusGatewayTimeout, yarpcerrors.CodeDeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.statusCode), func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			http.Error(recorder, "great sadness", tt.statusCode)
+
+			status := readStatus(recorder.Result(), "great sadness")
+			assert.Equal(t, tt.want, status.Code())
+			assert.Equal(t, "great sadness", status.Message())
+			assert.Nil(t, status.Details())
+		})
+	}
+}
+
+func TestWriteStatus(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	writeStatus(recorder, yarpcerrors.Newf(yarpcerrors.CodeNotFound, "no such user"))
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Equal(t, "not-found", recorder.Header().Get(ErrorCodeHeader))
+	assert.Equal(t, "", recorder.Header().Get(ErrorDetailsHeader))
+	assert.Equal(t, "no such user\n", recorder.Body.String())
+}
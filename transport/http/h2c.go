@@ -0,0 +1,305 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/internal/backoff"
+	intnet "go.uber.org/yarpc/internal/net"
+	"go.uber.org/yarpc/peer/hostport"
+
+	"golang.org/x/net/http2"
+)
+
+// prefaceRequest is the part of the HTTP/2 connection preface which the
+// HTTP/1 server parses as a "PRI * HTTP/2.0" request.
+const prefaceRequest = "PRI * HTTP/2.0\r\n\r\n"
+
+var errH2CConnClosed = errors.New("the HTTP/2 connection was closed")
+
+// Bounds of the time waited between attempts to connect to a peer in h2c
+// mode.
+const (
+	h2cBackoffFirst = 100 * time.Millisecond
+	h2cBackoffMax   = 10 * time.Second
+)
+
+// h2cHandler serves connections on which the client starts speaking HTTP/2
+// over cleartext with prior knowledge and passes all other requests to the
+// next handler.
+type h2cHandler struct {
+	server *http2.Server
+	next   http.Handler
+}
+
+func (h h2cHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "PRI" || req.URL.Path != "*" || req.ProtoMajor != 2 || len(req.Header) != 0 {
+		h.next.ServeHTTP(w, req)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "HTTP/2 over cleartext is not supported", http.StatusHTTPVersionNotSupported)
+		return
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	// The HTTP/1 server has consumed the start of the connection preface.
+	// Replay it so that the HTTP/2 server sees the complete preface.
+	h.server.ServeConn(&prefacedConn{
+		Conn: conn,
+		r:    io.MultiReader(strings.NewReader(prefaceRequest), rw),
+	}, &http2.ServeConnOpts{Handler: h.next})
+}
+
+// prefacedConn is a net.Conn which reads from r instead of the connection.
+type prefacedConn struct {
+	net.Conn
+
+	r io.Reader
+}
+
+func (c *prefacedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// h2cConn maintains the HTTP/2 connection over which all requests to a peer
+// are multiplexed when the Transport is in h2c mode.
+//
+// The connection status of the peer follows the state of the connection: the
+// peer is Connecting while the connection is being established, Available
+// while it is open, and Unavailable after an attempt to connect failed until
+// the next attempt.
+type h2cConn struct {
+	peer    *hostport.Peer
+	network string
+	address string
+	dialer  *net.Dialer
+	t2      *http2.Transport
+	backoff *backoff.Exponential
+	client  *http.Client
+
+	lock   sync.Mutex
+	cc     *http2.ClientConn
+	conn   net.Conn
+	closed bool
+
+	// Closed when a connection is established. Replaced with a new channel
+	// when the connection is lost.
+	ready chan struct{}
+
+	// Receives a value when the current connection stops accepting new
+	// requests, as it does after the server sends a GOAWAY frame.
+	reconnect chan struct{}
+	stop      chan struct{}
+}
+
+func newH2CConn(p *hostport.Peer, keepAlive time.Duration) *h2cConn {
+	network, address := intnet.SplitAddress(p.HostPort())
+	c := &h2cConn{
+		peer:      p,
+		network:   network,
+		address:   address,
+		dialer:    &net.Dialer{Timeout: 30 * time.Second, KeepAlive: keepAlive},
+		t2:        &http2.Transport{AllowHTTP: true},
+		backoff:   backoff.NewExponential(h2cBackoffFirst, h2cBackoffMax),
+		ready:     make(chan struct{}),
+		reconnect: make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+	}
+	c.client = &http.Client{Transport: c}
+	p.SetStatus(peer.Connecting)
+	go c.maintain()
+	return c
+}
+
+// RoundTrip sends the request over the current connection, waiting for one
+// to be established if necessary.
+//
+// This implements http.RoundTripper.
+func (c *h2cConn) RoundTrip(req *http.Request) (*http.Response, error) {
+	for {
+		c.lock.Lock()
+		cc, ready, closed := c.cc, c.ready, c.closed
+		c.lock.Unlock()
+
+		if closed {
+			return nil, errH2CConnClosed
+		}
+
+		if cc != nil {
+			if cc.CanTakeNewRequest() {
+				return cc.RoundTrip(req)
+			}
+			// Requests in flight on the old connection may finish. The
+			// server closes it once they have.
+			c.forget(cc)
+			select {
+			case c.reconnect <- struct{}{}:
+			default:
+			}
+			continue
+		}
+
+		select {
+		case <-ready:
+		case <-c.stop:
+			return nil, errH2CConnClosed
+		case <-req.Context().Done():
+			return nil, fmt.Errorf(
+				"no HTTP/2 connection to %v: %v", c.peer.HostPort(), req.Context().Err())
+		}
+	}
+}
+
+// maintain keeps a connection to the peer open until close is called.
+func (c *h2cConn) maintain() {
+	var attempt uint
+	for {
+		cc, closed, err := c.connect()
+		if err != nil {
+			c.setStatus(peer.Unavailable)
+			select {
+			case <-time.After(c.backoff.Duration(attempt)):
+				attempt++
+				c.setStatus(peer.Connecting)
+				continue
+			case <-c.stop:
+				return
+			}
+		}
+
+		attempt = 0
+		c.setStatus(peer.Available)
+		select {
+		case <-closed:
+		case <-c.reconnect:
+		case <-c.stop:
+			return
+		}
+
+		c.forget(cc)
+		c.setStatus(peer.Connecting)
+	}
+}
+
+// forget stops using the given connection for new requests if it is still
+// the current connection.
+func (c *h2cConn) forget(cc *http2.ClientConn) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.cc != cc {
+		return
+	}
+	c.cc = nil
+	c.conn = nil
+	c.ready = make(chan struct{})
+}
+
+// connect establishes a new connection to the peer. The returned channel is
+// closed when the connection is closed.
+func (c *h2cConn) connect() (*http2.ClientConn, <-chan struct{}, error) {
+	conn, err := c.dialer.Dial(c.network, c.address)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	closed := make(chan struct{})
+	conn = &trackedConn{Conn: conn, onClose: func() { close(closed) }}
+	cc, err := c.t2.NewClientConn(conn)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed {
+		conn.Close()
+		return nil, nil, errH2CConnClosed
+	}
+	c.cc = cc
+	c.conn = conn
+	close(c.ready)
+	return cc, closed, nil
+}
+
+// setStatus updates the status of the peer unless the connection was
+// closed.
+func (c *h2cConn) setStatus(status peer.ConnectionStatus) {
+	c.lock.Lock()
+	closed := c.closed
+	c.lock.Unlock()
+	if !closed {
+		c.peer.SetStatus(status)
+	}
+}
+
+// close closes the connection to the peer and stops reconnecting. It does
+// not wait for the connection to be re-established.
+func (c *h2cConn) close() error {
+	c.lock.Lock()
+	if c.closed {
+		c.lock.Unlock()
+		return nil
+	}
+	c.closed = true
+	conn := c.conn
+	c.cc = nil
+	c.conn = nil
+	c.lock.Unlock()
+
+	close(c.stop)
+	c.peer.SetStatus(peer.Unavailable)
+	if conn == nil {
+		return nil
+	}
+	return conn.Close()
+}
+
+// trackedConn is a net.Conn which reports when it was closed.
+type trackedConn struct {
+	net.Conn
+
+	once    sync.Once
+	onClose func()
+}
+
+func (c *trackedConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.onClose)
+	return err
+}
@@ -0,0 +1,199 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/peer/peertest"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/encoding/raw"
+	"go.uber.org/yarpc/peer/hostport"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+)
+
+func TestH2CCall(t *testing.T) {
+	router := yarpc.NewMapRouter("service")
+	router.Register(raw.Procedure("hello", func(ctx context.Context, body []byte) ([]byte, error) {
+		return append(body, " over h2c"...), nil
+	}))
+
+	httpTransport := NewTransport(ClientH2C())
+	require.NoError(t, httpTransport.Start(), "failed to start transport")
+	defer httpTransport.Stop()
+
+	inbound := httpTransport.NewInbound("127.0.0.1:0", ServerH2C())
+	inbound.SetRouter(router)
+	require.NoError(t, inbound.Start(), "failed to start inbound")
+	defer inbound.Stop()
+
+	out := httpTransport.NewSingleOutbound("http://" + inbound.Addr().String())
+	require.NoError(t, out.Start(), "failed to start outbound")
+	defer out.Stop()
+
+	// Multiple calls must share the same connection.
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		res, err := out.Call(ctx, &transport.Request{
+			Caller:    "caller",
+			Service:   "service",
+			Encoding:  raw.Encoding,
+			Procedure: "hello",
+			Body:      bytes.NewReader([]byte("hello")),
+		})
+		require.NoError(t, err)
+
+		body, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		assert.NoError(t, res.Body.Close())
+		assert.Equal(t, "hello over h2c", string(body))
+		cancel()
+	}
+
+	httpTransport.lock.Lock()
+	assert.Len(t, httpTransport.h2cConns, 1, "expected exactly one HTTP/2 connection")
+	httpTransport.lock.Unlock()
+}
+
+func TestH2CHandler(t *testing.T) {
+	server := httptest.NewServer(h2cHandler{
+		server: &http2.Server{},
+		next: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(req.Proto))
+		}),
+	})
+	defer server.Close()
+
+	tests := []struct {
+		desc   string
+		client *http.Client
+		want   string
+	}{
+		{
+			desc:   "HTTP/1.1",
+			client: http.DefaultClient,
+			want:   "HTTP/1.1",
+		},
+		{
+			desc: "HTTP/2 with prior knowledge",
+			client: &http.Client{Transport: &http2.Transport{
+				AllowHTTP: true,
+				DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+					return net.Dial(network, addr)
+				},
+			}},
+			want: "HTTP/2.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			res, err := tt.client.Get(server.URL)
+			require.NoError(t, err)
+			defer res.Body.Close()
+
+			body, err := ioutil.ReadAll(res.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(body))
+		})
+	}
+}
+
+func TestH2CPeerStatus(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	// Reserve an address and close the listener so that nothing accepts
+	// connections on it until we start serving.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	httpTransport := NewTransport(ClientH2C())
+	require.NoError(t, httpTransport.Start(), "failed to start transport")
+	defer httpTransport.Stop()
+
+	pid := hostport.PeerIdentifier(addr)
+	sub := peertest.NewMockSubscriber(mockCtrl)
+	sub.EXPECT().NotifyStatusChanged(gomock.Any()).AnyTimes()
+
+	p, err := httpTransport.RetainPeer(pid, sub)
+	require.NoError(t, err)
+	waitForConnectionStatus(t, p, peer.Unavailable)
+
+	listener, err = net.Listen("tcp", addr)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	conns := make(chan net.Conn, 2)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conns <- conn
+			go (&http2.Server{}).ServeConn(conn, &http2.ServeConnOpts{
+				Handler: http.NotFoundHandler(),
+			})
+		}
+	}()
+	waitForConnectionStatus(t, p, peer.Available)
+
+	// The peer reconnects after the server drops the connection.
+	(<-conns).Close()
+	waitForConnectionStatus(t, p, peer.Available)
+	select {
+	case <-conns:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the peer to reconnect")
+	}
+
+	require.NoError(t, httpTransport.ReleasePeer(pid, sub))
+	assert.Equal(t, peer.Unavailable, p.Status().ConnectionStatus,
+		"expected peer to be unavailable after it was released")
+}
+
+func waitForConnectionStatus(t *testing.T, p peer.Peer, want peer.ConnectionStatus) {
+	deadline := time.Now().Add(time.Second)
+	for p.Status().ConnectionStatus != want {
+		if time.Now().After(deadline) {
+			require.Fail(t, "timed out waiting for connection status",
+				"wanted %v, got %v", want, p.Status().ConnectionStatus)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -23,13 +23,16 @@ package http
 import (
 	"bytes"
 	"context"
+	"io"
 	"net/http"
 	"time"
 
 	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/compress"
 	"go.uber.org/yarpc/internal/errors"
 	"go.uber.org/yarpc/internal/iopool"
 	"go.uber.org/yarpc/internal/request"
+	"go.uber.org/yarpc/yarpcerrors"
 
 	"github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
@@ -45,6 +48,11 @@ func popHeader(h http.Header, n string) string {
 type handler struct {
 	router transport.Router
 	tracer opentracing.Tracer
+
+	// Compressor supported for request and response bodies, if any, and the
+	// size under which response bodies are sent uncompressed.
+	compressor      transport.Compressor
+	minCompressSize int
 }
 
 func (h handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
@@ -64,14 +72,10 @@ func (h handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	err = errors.AsHandlerError(service, procedure, err)
-	status := http.StatusInternalServerError
-	if transport.IsBadRequestError(err) {
-		status = http.StatusBadRequest
-	} else if transport.IsTimeoutError(err) {
-		status = http.StatusGatewayTimeout
+	if !yarpcerrors.IsStatus(err) {
+		err = errors.AsHandlerError(service, procedure, err)
 	}
-	http.Error(w, err.Error(), status)
+	writeStatus(w, yarpcerrors.FromError(err))
 }
 
 func (h handler) callHandler(w http.ResponseWriter, req *http.Request, start time.Time) error {
@@ -87,7 +91,17 @@ func (h handler) callHandler(w http.ResponseWriter, req *http.Request, start tim
 		return err
 	}
 
+	body, err := h.decompressBody(req)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	treq.Body = body
+
 	ctx := req.Context()
+	if id := peerIdentity(req); id != nil {
+		ctx = transport.WithPeerIdentity(ctx, id)
+	}
 	ctx, cancel, parseTTLErr := parseTTL(ctx, treq, popHeader(req.Header, TTLMSHeader))
 	// parseTTLErr != nil is a problem only if the request is unary.
 	defer cancel()
@@ -109,10 +123,18 @@ func (h handler) callHandler(w http.ResponseWriter, req *http.Request, start tim
 		if err := request.ValidateUnaryContext(ctx); err != nil {
 			return err
 		}
-		err = transport.DispatchUnaryHandler(ctx, spec.Unary(), start, treq, newResponseWriter(w))
+		rw := newResponseWriter(w)
+		if h.compressor != nil && compress.Accepts(req.Header.Get(AcceptEncodingHeader), h.compressor.Name()) {
+			rw.compressor = h.compressor
+			rw.minCompressSize = h.minCompressSize
+		}
+		err = transport.DispatchUnaryHandler(ctx, spec.Unary(), start, treq, rw)
+		if err == nil {
+			err = rw.flush()
+		}
 
 	case transport.Oneway:
-		err = handleOnewayRequest(span, treq, spec.Oneway())
+		err = handleOnewayRequest(ctx, span, treq, spec.Oneway())
 
 	default:
 		err = errors.UnsupportedTypeError{Transport: "HTTP", Type: spec.Type().String()}
@@ -122,7 +144,27 @@ func (h handler) callHandler(w http.ResponseWriter, req *http.Request, start tim
 	return err
 }
 
+// decompressBody returns the decompressed body of the request.
+func (h handler) decompressBody(req *http.Request) (io.ReadCloser, error) {
+	contentEncoding := popHeader(req.Header, ContentEncodingHeader)
+	if compress.IsIdentity(contentEncoding) {
+		return req.Body, nil
+	}
+	if h.compressor == nil || contentEncoding != h.compressor.Name() {
+		return nil, yarpcerrors.Newf(yarpcerrors.CodeUnimplemented,
+			"unsupported content encoding %q", contentEncoding)
+	}
+
+	body, err := compress.Decompress(h.compressor, req.Body)
+	if err != nil {
+		return nil, yarpcerrors.Newf(yarpcerrors.CodeInvalidArgument,
+			"cannot decompress request body: %v", err)
+	}
+	return body, nil
+}
+
 func handleOnewayRequest(
+	reqCtx context.Context,
 	span opentracing.Span,
 	treq *transport.Request,
 	onewayHandler transport.OnewayHandler,
@@ -138,6 +180,9 @@ func handleOnewayRequest(
 	// create a new context for oneway requests since the HTTP handler cancels
 	// http.Request's context when ServeHTTP returns
 	ctx := opentracing.ContextWithSpan(context.Background(), span)
+	if id, ok := transport.PeerIdentityFromContext(reqCtx); ok {
+		ctx = transport.WithPeerIdentity(ctx, id)
+	}
 
 	go func() {
 		// ensure the span lasts for length of the handler in case of errors
@@ -149,6 +194,15 @@ func handleOnewayRequest(
 	return nil
 }
 
+// peerIdentity returns the identity of the client if it presented a
+// certificate that was verified during the TLS handshake.
+func peerIdentity(req *http.Request) *transport.PeerIdentity {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 {
+		return nil
+	}
+	return &transport.PeerIdentity{Certificates: req.TLS.VerifiedChains[0]}
+}
+
 func updateSpanWithErr(span opentracing.Span, err error) {
 	if err != nil {
 		span.SetTag("error", true)
@@ -183,21 +237,47 @@ func (h handler) createSpan(ctx context.Context, req *http.Request, treq *transp
 // responseWriter adapts a http.ResponseWriter into a transport.ResponseWriter.
 type responseWriter struct {
 	w http.ResponseWriter
+
+	// If compressor is set, the body is buffered until flush is called and
+	// compressed if it is at least minCompressSize bytes long.
+	compressor      transport.Compressor
+	minCompressSize int
+	buffer          bytes.Buffer
 }
 
-func newResponseWriter(w http.ResponseWriter) responseWriter {
+func newResponseWriter(w http.ResponseWriter) *responseWriter {
 	w.Header().Set(ApplicationStatusHeader, ApplicationSuccessStatus)
-	return responseWriter{w: w}
+	return &responseWriter{w: w}
 }
 
-func (rw responseWriter) Write(s []byte) (int, error) {
+func (rw *responseWriter) Write(s []byte) (int, error) {
+	if rw.compressor != nil {
+		return rw.buffer.Write(s)
+	}
 	return rw.w.Write(s)
 }
 
-func (rw responseWriter) AddHeaders(h transport.Headers) {
+// flush writes the buffered body, if any, compressing it if necessary.
+func (rw *responseWriter) flush() error {
+	if rw.compressor == nil {
+		return nil
+	}
+
+	body, compressed, err := compress.Compress(rw.compressor, rw.minCompressSize, rw.buffer.Bytes())
+	if err != nil {
+		return err
+	}
+	if compressed {
+		rw.w.Header().Set(ContentEncodingHeader, rw.compressor.Name())
+	}
+	_, err = rw.w.Write(body)
+	return err
+}
+
+func (rw *responseWriter) AddHeaders(h transport.Headers) {
 	applicationHeaders.ToHTTPHeaders(h, rw.w.Header())
 }
 
-func (rw responseWriter) SetApplicationError() {
+func (rw *responseWriter) SetApplicationError() {
 	rw.w.Header().Set(ApplicationStatusHeader, ApplicationErrorStatus)
 }
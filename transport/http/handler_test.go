@@ -33,6 +33,7 @@ import (
 	yarpc "go.uber.org/yarpc"
 	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/compressor/gzip"
 	"go.uber.org/yarpc/encoding/raw"
 	"go.uber.org/yarpc/internal/routertest"
 
@@ -320,6 +321,33 @@ func TestHandlerInternalFailure(t *testing.T) {
 		httpResponse.Body.String())
 }
 
+func TestHandlerCorruptCompressedBody(t *testing.T) {
+	headers := make(http.Header)
+	headers.Set(CallerHeader, "somecaller")
+	headers.Set(EncodingHeader, "raw")
+	headers.Set(TTLMSHeader, "1000")
+	headers.Set(ProcedureHeader, "hello")
+	headers.Set(ServiceHeader, "fake")
+	headers.Set(ContentEncodingHeader, "gzip")
+
+	request := http.Request{
+		Method: "POST",
+		Header: headers,
+		Body:   ioutil.NopCloser(bytes.NewReader([]byte("not gzip"))),
+	}
+
+	httpHandler := handler{
+		router:     yarpc.NewMapRouter("fake"),
+		tracer:     &opentracing.NoopTracer{},
+		compressor: gzip.New(),
+	}
+	httpResponse := httptest.NewRecorder()
+	httpHandler.ServeHTTP(httpResponse, &request)
+
+	assert.Equal(t, http.StatusBadRequest, httpResponse.Code)
+	assert.Contains(t, httpResponse.Body.String(), "cannot decompress request body")
+}
+
 type panickedHandler struct{}
 
 func (th panickedHandler) Handle(context.Context, *transport.Request, transport.ResponseWriter) error {
@@ -0,0 +1,79 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package http
+
+import (
+	"io"
+	"net/http"
+
+	"go.uber.org/atomic"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/health"
+)
+
+// healthHandler answers GET requests to the health path of an inbound and
+// passes all other requests to the next handler.
+//
+// If a health service is registered with the router, the status of the
+// service named by the "service" query parameter is reported. Otherwise,
+// the inbound is reported healthy until it starts draining.
+type healthHandler struct {
+	path     string
+	router   transport.Router
+	draining *atomic.Bool
+	next     http.Handler
+}
+
+func (h healthHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != h.path {
+		h.next.ServeHTTP(w, req)
+		return
+	}
+
+	if req.Method != "GET" && req.Method != "HEAD" {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if h.draining.Load() {
+		http.Error(w, "draining", http.StatusServiceUnavailable)
+		return
+	}
+
+	status, ok, err := health.Check(req.Context(), h.router, req.URL.Query().Get("service"))
+	switch {
+	case !ok || status == health.Serving:
+		// Serving, or no health service is registered.
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	case status == health.ServiceUnknown:
+		http.Error(w, "unknown service", http.StatusNotFound)
+		return
+	default:
+		http.Error(w, "not serving", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, "OK\n")
+}
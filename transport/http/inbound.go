@@ -21,16 +21,22 @@
 package http
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"net"
 	"net/http"
 
 	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/compress"
 	"go.uber.org/yarpc/internal/errors"
 	"go.uber.org/yarpc/internal/introspection"
 	intnet "go.uber.org/yarpc/internal/net"
 	"go.uber.org/yarpc/internal/sync"
 
 	"github.com/opentracing/opentracing-go"
+	"go.uber.org/atomic"
+	"golang.org/x/net/http2"
 )
 
 // InboundOption customizes the behavior of an HTTP Inbound constructed with
@@ -51,14 +57,88 @@ func Mux(pattern string, mux *http.ServeMux) InboundOption {
 	}
 }
 
+// ServerCertificate configures the inbound to serve requests over TLS using
+// the given certificate. This option may be provided multiple times to serve
+// different certificates based on the server name requested by the client.
+func ServerCertificate(cert tls.Certificate) InboundOption {
+	return func(i *Inbound) {
+		i.certificates = append(i.certificates, cert)
+	}
+}
+
+// ClientCAs configures the inbound for mutual TLS. Clients will be required
+// to present a certificate signed by one of the certificate authorities in
+// the given pool. The verified identity of the client is made available to
+// handlers through encoding.Call.
+//
+// ServerCertificate must also be provided.
+func ClientCAs(pool *x509.CertPool) InboundOption {
+	return func(i *Inbound) {
+		i.clientCAs = pool
+	}
+}
+
+// HealthPath specifies that the inbound should answer GET requests to the
+// given path with the readiness of the service: 200 while it accepts
+// requests and 503 once it starts draining. Load balancers and orchestrators
+// may use this path to stop sending requests to the service before it shuts
+// down.
+//
+// If a health service is registered on the Dispatcher with x/yarpchealth,
+// the path reports the status of the service named by the "service" query
+// parameter, or of the Dispatcher as a whole if the parameter is absent:
+// 200 if it is serving, 503 if it is not, and 404 if the service is unknown.
+func HealthPath(path string) InboundOption {
+	return func(i *Inbound) {
+		i.healthPath = path
+	}
+}
+
+// ServerH2C specifies that the inbound accepts HTTP/2 connections over
+// cleartext from clients with prior knowledge that it supports them, such as
+// the outbounds of a Transport built with the ClientH2C option. HTTP/1.1
+// requests are still accepted.
+func ServerH2C() InboundOption {
+	return func(i *Inbound) {
+		i.h2c = true
+	}
+}
+
+// InboundCompressor specifies that the inbound accepts requests whose bodies
+// were compressed with the given compressor, as indicated by their
+// Content-Encoding header, and compresses the bodies of responses to callers
+// that accept that compressor in their Accept-Encoding header.
+//
+// Requests compressed in any other format are rejected.
+func InboundCompressor(c transport.Compressor) InboundOption {
+	return func(i *Inbound) {
+		i.compressor = c
+	}
+}
+
+// InboundMinCompressSize specifies the size, in bytes, under which the bodies
+// of responses are sent uncompressed when the InboundCompressor option is
+// used. Defaults to 1024 bytes.
+func InboundMinCompressSize(size int) InboundOption {
+	return func(i *Inbound) {
+		i.minCompressSize = size
+	}
+}
+
 // NewInbound builds a new HTTP inbound that listens on the given address and
 // sharing this transport.
+//
+// The address is either a TCP "host:port" address or a
+// "unix:///path/to/socket" address. A unix domain socket left behind at that
+// path by a process that did not shut down cleanly is removed when the
+// inbound starts.
 func (t *Transport) NewInbound(addr string, opts ...InboundOption) *Inbound {
 	i := &Inbound{
-		once:      sync.Once(),
-		addr:      addr,
-		tracer:    t.tracer,
-		transport: t,
+		once:            sync.Once(),
+		addr:            addr,
+		tracer:          t.tracer,
+		transport:       t,
+		minCompressSize: compress.DefaultMinSize,
 	}
 	for _, opt := range opts {
 		opt(i)
@@ -69,13 +149,21 @@ func (t *Transport) NewInbound(addr string, opts ...InboundOption) *Inbound {
 // Inbound receives YARPC requests using an HTTP server. It may be constructed
 // using the NewInbound method on the Transport.
 type Inbound struct {
-	addr       string
-	mux        *http.ServeMux
-	muxPattern string
-	server     *intnet.HTTPServer
-	router     transport.Router
-	tracer     opentracing.Tracer
-	transport  *Transport
+	addr         string
+	mux          *http.ServeMux
+	muxPattern   string
+	certificates []tls.Certificate
+	clientCAs    *x509.CertPool
+	healthPath   string
+	h2c          bool
+	draining     atomic.Bool
+	server       *intnet.HTTPServer
+	router       transport.Router
+	tracer       opentracing.Tracer
+	transport    *Transport
+
+	compressor      transport.Compressor
+	minCompressSize int
 
 	once sync.LifecycleOnce
 }
@@ -109,18 +197,40 @@ func (i *Inbound) start() error {
 		return errors.ErrNoRouter
 	}
 
+	tlsConfig, err := i.tlsConfig()
+	if err != nil {
+		return err
+	}
+
 	var httpHandler http.Handler = handler{
-		router: i.router,
-		tracer: i.tracer,
+		router:          i.router,
+		tracer:          i.tracer,
+		compressor:      i.compressor,
+		minCompressSize: i.minCompressSize,
 	}
 	if i.mux != nil {
 		i.mux.Handle(i.muxPattern, httpHandler)
 		httpHandler = i.mux
 	}
+	if i.healthPath != "" {
+		httpHandler = healthHandler{
+			path:     i.healthPath,
+			router:   i.router,
+			draining: &i.draining,
+			next:     httpHandler,
+		}
+	}
+	if i.h2c {
+		httpHandler = h2cHandler{
+			server: &http2.Server{},
+			next:   httpHandler,
+		}
+	}
 
 	i.server = intnet.NewHTTPServer(&http.Server{
-		Addr:    i.addr,
-		Handler: httpHandler,
+		Addr:      i.addr,
+		Handler:   httpHandler,
+		TLSConfig: tlsConfig,
 	})
 	if err := i.server.ListenAndServe(); err != nil {
 		return err
@@ -130,6 +240,39 @@ func (i *Inbound) start() error {
 	return nil
 }
 
+// tlsConfig builds the TLS configuration for the server or returns nil if
+// the inbound serves plaintext HTTP.
+func (i *Inbound) tlsConfig() (*tls.Config, error) {
+	if len(i.certificates) == 0 {
+		if i.clientCAs != nil {
+			return nil, fmt.Errorf("a server certificate is required to verify client certificates")
+		}
+		return nil, nil
+	}
+
+	cfg := &tls.Config{Certificates: i.certificates}
+	if i.clientCAs != nil {
+		cfg.ClientCAs = i.clientCAs
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
+
+// Drain makes the inbound report that it is not ready on its health path and
+// close connections after the requests on them are answered so that clients
+// reconnect to other peers.
+//
+// This satisfies the transport.DrainableInbound interface, and would be
+// called by a dispatcher before it stops the inbound.
+func (i *Inbound) Drain() {
+	if i.draining.Swap(true) {
+		return
+	}
+	if i.server != nil {
+		i.server.SetKeepAlivesEnabled(false)
+	}
+}
+
 // Stop the inbound, closing the listening socket.
 func (i *Inbound) Stop() error {
 	return i.once.Stop(i.stop)
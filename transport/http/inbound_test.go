@@ -32,10 +32,12 @@ import (
 	"testing"
 	"time"
 
+	"go.uber.org/yarpc"
 	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/api/transport/transporttest"
 	"go.uber.org/yarpc/encoding/raw"
 	"go.uber.org/yarpc/internal/routertest"
+	"go.uber.org/yarpc/x/yarpchealth"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -182,3 +184,74 @@ func TestInboundMux(t *testing.T) {
 		}
 	}
 }
+
+func TestInboundHealthPath(t *testing.T) {
+	x := NewTransport()
+	i := x.NewInbound(":0", HealthPath("/health"))
+	i.SetRouter(yarpc.NewMapRouter("myservice"))
+	require.NoError(t, i.Start())
+	defer i.Stop()
+
+	url := fmt.Sprintf("http://%v/health", i.Addr().String())
+	get := func(method string) (int, string) {
+		req, err := http.NewRequest(method, url, nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err, "%v %v failed", method, url)
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err, "failed to read response body")
+		return resp.StatusCode, string(body)
+	}
+
+	code, body := get("GET")
+	assert.Equal(t, http.StatusOK, code, "status code mismatch while serving")
+	assert.Equal(t, "OK\n", body, "body mismatch while serving")
+
+	code, _ = get("POST")
+	assert.Equal(t, http.StatusMethodNotAllowed, code, "POST to the health path must not be allowed")
+
+	i.Drain()
+	i.Drain() // drain is idempotent
+
+	code, body = get("GET")
+	assert.Equal(t, http.StatusServiceUnavailable, code, "status code mismatch while draining")
+	assert.Equal(t, "draining\n", body, "body mismatch while draining")
+}
+
+func TestInboundHealthService(t *testing.T) {
+	x := NewTransport()
+	i := x.NewInbound(":0", HealthPath("/health"))
+	disp := yarpc.NewDispatcher(yarpc.Config{
+		Name:     "health-test",
+		Inbounds: yarpc.Inbounds{i},
+	})
+	s := yarpchealth.Register(disp)
+	require.NoError(t, disp.Start())
+	defer disp.Stop()
+
+	get := func(service string) (int, string) {
+		url := fmt.Sprintf("http://%v/health?service=%v", i.Addr().String(), service)
+		resp, err := http.Get(url)
+		require.NoError(t, err, "GET %v failed", url)
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err, "failed to read response body")
+		return resp.StatusCode, string(body)
+	}
+
+	code, body := get("health-test")
+	assert.Equal(t, http.StatusOK, code, "status code mismatch while serving")
+	assert.Equal(t, "OK\n", body, "body mismatch while serving")
+
+	code, _ = get("unknown")
+	assert.Equal(t, http.StatusNotFound, code, "status code mismatch for unknown service")
+
+	s.SetServing("health-test", false)
+	code, body = get("health-test")
+	assert.Equal(t, http.StatusServiceUnavailable, code, "status code mismatch while not serving")
+	assert.Equal(t, "not serving\n", body, "body mismatch while not serving")
+
+	code, _ = get("")
+	assert.Equal(t, http.StatusServiceUnavailable, code, "dispatcher must not be serving")
+}
@@ -21,8 +21,10 @@
 package http
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -32,11 +34,14 @@ import (
 
 	"go.uber.org/yarpc/api/peer"
 	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/compress"
 	"go.uber.org/yarpc/internal/errors"
 	"go.uber.org/yarpc/internal/introspection"
+	intnet "go.uber.org/yarpc/internal/net"
 	"go.uber.org/yarpc/internal/sync"
 	peerchooser "go.uber.org/yarpc/peer"
 	"go.uber.org/yarpc/peer/hostport"
+	"go.uber.org/yarpc/yarpcerrors"
 
 	"github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
@@ -51,6 +56,9 @@ var (
 
 var defaultURLTemplate, _ = url.Parse("http://localhost")
 
+// unixSocketHost is the host of requests made over unix domain sockets.
+const unixSocketHost = "localhost"
+
 // OutboundOption customizes an HTTP Outbound.
 type OutboundOption func(*Outbound)
 
@@ -88,6 +96,28 @@ func AddHeader(key, value string) OutboundOption {
 	}
 }
 
+// OutboundCompressor specifies that the outbound compresses the bodies of
+// requests with the given compressor and asks for responses compressed in
+// the same format. Compressed requests carry the name of the compressor in
+// the Content-Encoding header.
+//
+// Inbounds that receive these requests MUST support the same compressor, as
+// HTTP inbounds do with the InboundCompressor option.
+func OutboundCompressor(c transport.Compressor) OutboundOption {
+	return func(o *Outbound) {
+		o.compressor = c
+	}
+}
+
+// OutboundMinCompressSize specifies the size, in bytes, under which the
+// bodies of requests are sent uncompressed when the OutboundCompressor option
+// is used. Defaults to 1024 bytes.
+func OutboundMinCompressSize(size int) OutboundOption {
+	return func(o *Outbound) {
+		o.minCompressSize = size
+	}
+}
+
 // NewOutbound builds an HTTP outbound which sends requests to peers supplied
 // by the given peer.Chooser. The URL template for used for the different
 // peers may be customized using the URLTemplate option.
@@ -95,13 +125,17 @@ func AddHeader(key, value string) OutboundOption {
 // Peer Choosers used with the HTTP outbound MUST yield *hostport.Peer
 // objects. Also note that the Chooser MUST have started before Outbound.Start
 // is called.
+//
+// Peers are identified by "host:port" addresses or, for servers listening on
+// unix domain sockets, by "unix:///path/to/socket" addresses.
 func (t *Transport) NewOutbound(chooser peer.Chooser, opts ...OutboundOption) *Outbound {
 	o := &Outbound{
-		once:        sync.Once(),
-		chooser:     chooser,
-		urlTemplate: defaultURLTemplate,
-		tracer:      t.tracer,
-		transport:   t,
+		once:            sync.Once(),
+		chooser:         chooser,
+		urlTemplate:     defaultURLTemplate,
+		tracer:          t.tracer,
+		transport:       t,
+		minCompressSize: compress.DefaultMinSize,
 	}
 	for _, opt := range opts {
 		opt(o)
@@ -123,6 +157,12 @@ func NewOutbound(chooser peer.Chooser, opts ...OutboundOption) *Outbound {
 // NewSingleOutbound builds an outbound which sends YARPC requests over HTTP
 // to the specified URL.
 //
+// The URL may also be the address of a unix domain socket, as in
+// "unix:///var/run/myservice.sock", in which case requests are sent to
+// "http://localhost" over that socket. Use NewOutbound with a
+// "unix:///var/run/myservice.sock" peer and the URLTemplate option to send
+// requests to a different URL over a unix domain socket.
+//
 // The URLTemplate option has no effect in this form.
 func (t *Transport) NewSingleOutbound(uri string, opts ...OutboundOption) *Outbound {
 	parsedURL, err := url.Parse(uri)
@@ -130,7 +170,13 @@ func (t *Transport) NewSingleOutbound(uri string, opts ...OutboundOption) *Outbo
 		panic(err.Error())
 	}
 
-	chooser := peerchooser.NewSingle(hostport.PeerIdentifier(parsedURL.Host), t)
+	pid := hostport.PeerIdentifier(parsedURL.Host)
+	if intnet.IsUnixAddress(uri) {
+		pid = hostport.PeerIdentifier(uri)
+		uri = defaultURLTemplate.String()
+	}
+
+	chooser := peerchooser.NewSingle(pid, t)
 	o := t.NewOutbound(chooser)
 	for _, opt := range opts {
 		opt(o)
@@ -153,6 +199,11 @@ type Outbound struct {
 	// Headers to add to all outgoing requests.
 	headers http.Header
 
+	// Compressor for request bodies, if any, and the size under which
+	// bodies are sent uncompressed.
+	compressor      transport.Compressor
+	minCompressSize int
+
 	once sync.LifecycleOnce
 }
 
@@ -242,12 +293,23 @@ func (o *Outbound) callWithPeer(
 	ttl time.Duration,
 	p *hostport.Peer,
 ) (*transport.Response, error) {
-	req, err := o.createRequest(p, treq)
+	body, contentEncoding, err := o.compressBody(treq)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := o.createRequest(p, body)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header = applicationHeaders.ToHTTPHeaders(treq.Headers, nil)
+	if o.compressor != nil {
+		req.Header.Set(AcceptEncodingHeader, o.compressor.Name())
+		if contentEncoding != "" {
+			req.Header.Set(ContentEncodingHeader, contentEncoding)
+		}
+	}
 	ctx, req, span, err := o.withOpentracingSpan(ctx, req, treq, start)
 	if err != nil {
 		return nil, err
@@ -277,8 +339,13 @@ func (o *Outbound) callWithPeer(
 			end := time.Now()
 			return nil, errors.ClientTimeoutError(treq.Service, treq.Procedure, end.Sub(start))
 		}
+		if err == context.Canceled {
+			return nil, err
+		}
 
-		return nil, err
+		// We failed to send the request or to receive a response, most
+		// likely because the peer could not be reached.
+		return nil, yarpcerrors.Newf(yarpcerrors.CodeUnavailable, "%v", err)
 	}
 
 	span.SetTag("http.status_code", response.StatusCode)
@@ -287,9 +354,14 @@ func (o *Outbound) callWithPeer(
 		appHeaders := applicationHeaders.FromHTTPHeaders(
 			response.Header, transport.NewHeaders())
 		appError := response.Header.Get(ApplicationStatusHeader) == ApplicationErrorStatus
+		resBody, err := o.decompressBody(treq, response)
+		if err != nil {
+			response.Body.Close()
+			return nil, err
+		}
 		return &transport.Response{
 			Headers:          appHeaders,
-			Body:             response.Body,
+			Body:             resBody,
 			ApplicationError: appError,
 		}, nil
 	}
@@ -314,10 +386,46 @@ func (o *Outbound) getPeerForRequest(ctx context.Context, treq *transport.Reques
 	return hpPeer, onFinish, nil
 }
 
-func (o *Outbound) createRequest(p *hostport.Peer, treq *transport.Request) (*http.Request, error) {
+// compressBody returns the body of the request as it should be sent and the
+// name of the compressor used for it, if any.
+func (o *Outbound) compressBody(treq *transport.Request) (io.Reader, string, error) {
+	if o.compressor == nil {
+		return treq.Body, "", nil
+	}
+
+	body, compressed, err := compress.CompressReader(o.compressor, o.minCompressSize, treq.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	if !compressed {
+		return bytes.NewReader(body), "", nil
+	}
+	return bytes.NewReader(body), o.compressor.Name(), nil
+}
+
+// decompressBody returns the decompressed body of a successful response.
+func (o *Outbound) decompressBody(treq *transport.Request, response *http.Response) (io.ReadCloser, error) {
+	contentEncoding := response.Header.Get(ContentEncodingHeader)
+	if o.compressor == nil || compress.IsIdentity(contentEncoding) {
+		return response.Body, nil
+	}
+	if contentEncoding != o.compressor.Name() {
+		return nil, yarpcerrors.Newf(yarpcerrors.CodeInternal,
+			"response to procedure %q of service %q uses unsupported content encoding %q",
+			treq.Procedure, treq.Service, contentEncoding)
+	}
+	return compress.Decompress(o.compressor, response.Body)
+}
+
+func (o *Outbound) createRequest(p *hostport.Peer, body io.Reader) (*http.Request, error) {
 	newURL := *o.urlTemplate
 	newURL.Host = p.HostPort()
-	return http.NewRequest("POST", newURL.String(), treq.Body)
+	if intnet.IsUnixAddress(newURL.Host) {
+		// The connection is made to the socket. The host is only used for
+		// the Host header.
+		newURL.Host = unixSocketHost
+	}
+	return http.NewRequest("POST", newURL.String(), body)
 }
 
 func (o *Outbound) withOpentracingSpan(ctx context.Context, req *http.Request, treq *transport.Request, start time.Time) (context.Context, *http.Request, opentracing.Span, error) {
@@ -392,7 +500,7 @@ func (o *Outbound) getHTTPClient(p *hostport.Peer) (*http.Client, error) {
 			ExpectedType: "*http.Transport",
 		}
 	}
-	return t.client, nil
+	return t.clientFor(p.HostPort()), nil
 }
 
 func getErrFromResponse(response *http.Response) error {
@@ -409,15 +517,7 @@ func getErrFromResponse(response *http.Response) error {
 	// Trim the trailing newline from HTTP error messages
 	message := strings.TrimSuffix(string(contents), "\n")
 
-	if response.StatusCode >= 400 && response.StatusCode < 500 {
-		return errors.RemoteBadRequestError(message)
-	}
-
-	if response.StatusCode == http.StatusGatewayTimeout {
-		return errors.RemoteTimeoutError(message)
-	}
-
-	return errors.RemoteUnexpectedError(message)
+	return readStatus(response, message)
 }
 
 // Introspect returns basic status about this outbound.
@@ -24,16 +24,28 @@ import (
 	"bytes"
 	"context"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"go.uber.org/yarpc"
 	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/compressor/gzip"
+	"go.uber.org/yarpc/compressor/snappy"
 	"go.uber.org/yarpc/encoding/raw"
+	"go.uber.org/yarpc/internal/compress"
+	"go.uber.org/yarpc/peer"
+	"go.uber.org/yarpc/peer/hostport"
+	"go.uber.org/yarpc/yarpcerrors"
 
+	"github.com/opentracing/opentracing-go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -92,6 +104,214 @@ func TestCallSuccess(t *testing.T) {
 	}
 }
 
+func TestCallUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "yarpc-http")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	addr := "unix://" + filepath.Join(dir, "http.sock")
+
+	router := yarpc.NewMapRouter("service")
+	router.Register(raw.Procedure("hello", func(ctx context.Context, body []byte) ([]byte, error) {
+		return append(body, " over unix"...), nil
+	}))
+
+	httpTransport := NewTransport()
+	inbound := httpTransport.NewInbound(addr)
+	inbound.SetRouter(router)
+	require.NoError(t, inbound.Start(), "failed to start inbound")
+	defer inbound.Stop()
+
+	tests := []struct {
+		desc string
+		out  *Outbound
+	}{
+		{
+			desc: "single outbound",
+			out:  httpTransport.NewSingleOutbound(addr),
+		},
+		{
+			desc: "peer chooser",
+			out: httpTransport.NewOutbound(
+				peer.NewSingle(hostport.PeerIdentifier(addr), httpTransport),
+				URLTemplate("http://localhost/yarpc"),
+			),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			require.NoError(t, tt.out.Start(), "failed to start outbound")
+			defer tt.out.Stop()
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			res, err := tt.out.Call(ctx, &transport.Request{
+				Caller:    "caller",
+				Service:   "service",
+				Encoding:  raw.Encoding,
+				Procedure: "hello",
+				Body:      bytes.NewReader([]byte("hello")),
+			})
+			require.NoError(t, err)
+			defer res.Body.Close()
+
+			body, err := ioutil.ReadAll(res.Body)
+			require.NoError(t, err)
+			assert.Equal(t, "hello over unix", string(body))
+		})
+	}
+}
+
+func TestCallCompression(t *testing.T) {
+	small := "hello"
+	large := strings.Repeat("hello ", 1000)
+
+	router := yarpc.NewMapRouter("service")
+	router.Register(raw.Procedure("echo", func(ctx context.Context, body []byte) ([]byte, error) {
+		return body, nil
+	}))
+
+	tests := []struct {
+		desc               string
+		outboundOpts       []OutboundOption
+		inboundCompressor  transport.Compressor
+		inboundMinSize     int
+		body               string
+		wantReqEncoding    string
+		wantResEncoding    string
+		wantCode           yarpcerrors.Code
+		wantAcceptEncoding string
+	}{
+		{
+			desc: "no compression",
+			body: large,
+		},
+		{
+			desc:               "gzip",
+			outboundOpts:       []OutboundOption{OutboundCompressor(gzip.New())},
+			inboundCompressor:  gzip.New(),
+			body:               large,
+			wantReqEncoding:    "gzip",
+			wantResEncoding:    "gzip",
+			wantAcceptEncoding: "gzip",
+		},
+		{
+			desc:               "snappy",
+			outboundOpts:       []OutboundOption{OutboundCompressor(snappy.New())},
+			inboundCompressor:  snappy.New(),
+			body:               large,
+			wantReqEncoding:    "snappy",
+			wantResEncoding:    "snappy",
+			wantAcceptEncoding: "snappy",
+		},
+		{
+			desc:               "smaller than default minimum size",
+			outboundOpts:       []OutboundOption{OutboundCompressor(gzip.New())},
+			inboundCompressor:  gzip.New(),
+			body:               small,
+			wantAcceptEncoding: "gzip",
+		},
+		{
+			desc: "custom minimum size",
+			outboundOpts: []OutboundOption{
+				OutboundCompressor(gzip.New()),
+				OutboundMinCompressSize(1),
+			},
+			inboundCompressor:  gzip.New(),
+			inboundMinSize:     len(small) + 1,
+			body:               small,
+			wantReqEncoding:    "gzip",
+			wantAcceptEncoding: "gzip",
+		},
+		{
+			desc:              "inbound does not compress for callers that do not accept it",
+			inboundCompressor: snappy.New(),
+			body:              large,
+		},
+		{
+			// net/http asks for gzip and decompresses the response if the
+			// caller did not ask for a specific encoding.
+			desc:              "inbound compresses for plain HTTP callers",
+			inboundCompressor: gzip.New(),
+			body:              large,
+			wantResEncoding:   "gzip",
+		},
+		{
+			desc:               "inbound without compressor",
+			outboundOpts:       []OutboundOption{OutboundCompressor(gzip.New())},
+			body:               large,
+			wantReqEncoding:    "gzip",
+			wantCode:           yarpcerrors.CodeUnimplemented,
+			wantAcceptEncoding: "gzip",
+		},
+		{
+			desc:               "inbound with a different compressor",
+			outboundOpts:       []OutboundOption{OutboundCompressor(gzip.New())},
+			inboundCompressor:  snappy.New(),
+			body:               large,
+			wantReqEncoding:    "gzip",
+			wantCode:           yarpcerrors.CodeUnimplemented,
+			wantAcceptEncoding: "gzip",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			minSize := tt.inboundMinSize
+			if minSize == 0 {
+				minSize = compress.DefaultMinSize
+			}
+			h := handler{
+				router:          router,
+				tracer:          &opentracing.NoopTracer{},
+				compressor:      tt.inboundCompressor,
+				minCompressSize: minSize,
+			}
+
+			var gotReqEncoding, gotAcceptEncoding, gotResEncoding string
+			server := httptest.NewServer(http.HandlerFunc(
+				func(w http.ResponseWriter, req *http.Request) {
+					gotReqEncoding = req.Header.Get(ContentEncodingHeader)
+					gotAcceptEncoding = req.Header.Get(AcceptEncodingHeader)
+					h.ServeHTTP(w, req)
+					gotResEncoding = w.Header().Get(ContentEncodingHeader)
+				},
+			))
+			defer server.Close()
+
+			out := NewTransport().NewSingleOutbound(server.URL, tt.outboundOpts...)
+			require.NoError(t, out.Start(), "failed to start outbound")
+			defer out.Stop()
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			res, err := out.Call(ctx, &transport.Request{
+				Caller:    "caller",
+				Service:   "service",
+				Encoding:  raw.Encoding,
+				Procedure: "echo",
+				Body:      bytes.NewReader([]byte(tt.body)),
+			})
+			assert.Equal(t, tt.wantReqEncoding, gotReqEncoding, "request encoding should match")
+			if tt.wantAcceptEncoding != "" {
+				assert.Equal(t, tt.wantAcceptEncoding, gotAcceptEncoding, "accepted encoding should match")
+			}
+			if tt.wantCode != yarpcerrors.CodeOK {
+				require.Error(t, err)
+				assert.Equal(t, tt.wantCode, yarpcerrors.FromError(err).Code())
+				return
+			}
+			require.NoError(t, err)
+			defer res.Body.Close()
+			assert.Equal(t, tt.wantResEncoding, gotResEncoding, "response encoding should match")
+
+			body, err := ioutil.ReadAll(res.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.body, string(body))
+		})
+	}
+}
+
 func TestAddReservedHeader(t *testing.T) {
 	tests := []string{
 		"Rpc-Foo",
@@ -290,6 +510,31 @@ func TestCallFailures(t *testing.T) {
 	}
 }
 
+func TestCallUnreachablePeer(t *testing.T) {
+	// Grab a free port and close the listener right away so that nothing
+	// is listening on it.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	out := NewTransport().NewSingleOutbound("http://" + addr)
+	require.NoError(t, out.Start(), "failed to start outbound")
+	defer out.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err = out.Call(ctx, &transport.Request{
+		Caller:    "caller",
+		Service:   "service",
+		Encoding:  raw.Encoding,
+		Procedure: "wat",
+		Body:      bytes.NewReader([]byte("huh")),
+	})
+	require.Error(t, err, "expected failure")
+	assert.Equal(t, yarpcerrors.CodeUnavailable, yarpcerrors.FromError(err).Code())
+}
+
 func TestStartMultiple(t *testing.T) {
 	httpTransport := NewTransport()
 	out := httpTransport.NewSingleOutbound("http://localhost:9999")
@@ -0,0 +1,157 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"go.uber.org/yarpc/api/encoding"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/encoding/raw"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCertificate loads the key pair with the given name from testdata.
+func testCertificate(t *testing.T, name string) tls.Certificate {
+	cert, err := tls.LoadX509KeyPair(
+		fmt.Sprintf("testdata/%v.pem", name),
+		fmt.Sprintf("testdata/%v-key.pem", name),
+	)
+	require.NoError(t, err, "failed to load %q certificate", name)
+	return cert
+}
+
+// testCertPool loads the test certificate authority from testdata.
+func testCertPool(t *testing.T) *x509.CertPool {
+	pem, err := ioutil.ReadFile("testdata/ca.pem")
+	require.NoError(t, err, "failed to read CA certificate")
+
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(pem), "failed to parse CA certificate")
+	return pool
+}
+
+func TestMutualTLS(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	serverTransport := NewTransport()
+	i := serverTransport.NewInbound("127.0.0.1:0",
+		ServerCertificate(testCertificate(t, "server")),
+		ClientCAs(testCertPool(t)),
+	)
+
+	h := transporttest.NewMockUnaryHandler(mockCtrl)
+	router := transporttest.NewMockRouter(mockCtrl)
+	router.EXPECT().Choose(gomock.Any(), gomock.Any()).
+		Return(transport.NewUnaryHandlerSpec(h), nil)
+	h.EXPECT().Handle(gomock.Any(), gomock.Any(), gomock.Any()).Do(
+		func(ctx context.Context, req *transport.Request, resw transport.ResponseWriter) {
+			ctx, call := encoding.NewInboundCall(ctx)
+			require.NoError(t, call.ReadFromRequest(req))
+
+			id := encoding.CallFromContext(ctx).PeerIdentity()
+			if assert.NotNil(t, id, "expected a verified peer identity") {
+				assert.Equal(t, "yarpc-test-client", id.Name())
+				assert.Len(t, id.Certificates, 2, "expected client and CA certificates")
+			}
+		}).Return(nil)
+
+	i.SetRouter(router)
+	require.NoError(t, i.Start(), "failed to start inbound")
+	defer i.Stop()
+
+	clientTransport := NewTransport(
+		ClientCertificate(testCertificate(t, "client")),
+		RootCAs(testCertPool(t)),
+	)
+	o := clientTransport.NewSingleOutbound(fmt.Sprintf("https://%v", i.Addr()))
+	require.NoError(t, o.Start(), "failed to start outbound")
+	defer o.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	res, err := o.Call(ctx, &transport.Request{
+		Caller:    "caller",
+		Service:   "service",
+		Procedure: "hello",
+		Encoding:  raw.Encoding,
+		Body:      bytes.NewReader([]byte("world")),
+	})
+	require.NoError(t, err, "expected call over mutual TLS to succeed")
+	assert.NoError(t, res.Body.Close())
+}
+
+func TestTLSClientCertificateRequired(t *testing.T) {
+	i := NewTransport().NewInbound("127.0.0.1:0",
+		ServerCertificate(testCertificate(t, "server")),
+		ClientCAs(testCertPool(t)),
+	)
+	i.SetRouter(new(transporttest.MockRouter))
+	require.NoError(t, i.Start(), "failed to start inbound")
+	defer i.Stop()
+
+	tests := []struct {
+		desc string
+		opts []TransportOption
+	}{
+		{desc: "no client certificate", opts: []TransportOption{RootCAs(testCertPool(t))}},
+		{desc: "untrusted server", opts: []TransportOption{ClientCertificate(testCertificate(t, "client"))}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			o := NewTransport(tt.opts...).NewSingleOutbound(fmt.Sprintf("https://%v", i.Addr()))
+			require.NoError(t, o.Start(), "failed to start outbound")
+			defer o.Stop()
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			_, err := o.Call(ctx, &transport.Request{
+				Caller:    "caller",
+				Service:   "service",
+				Procedure: "hello",
+				Encoding:  raw.Encoding,
+				Body:      bytes.NewReader([]byte("world")),
+			})
+			assert.Error(t, err, "expected the TLS handshake to fail")
+		})
+	}
+}
+
+func TestInboundClientCAsWithoutCertificate(t *testing.T) {
+	i := NewTransport().NewInbound("127.0.0.1:0", ClientCAs(testCertPool(t)))
+	i.SetRouter(new(transporttest.MockRouter))
+	err := i.Start()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "a server certificate is required")
+}
@@ -21,6 +21,9 @@
 package http
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"net"
 	"net/http"
 	"sync"
@@ -28,17 +31,27 @@ import (
 
 	"go.uber.org/yarpc/api/peer"
 	"go.uber.org/yarpc/api/transport"
+	intnet "go.uber.org/yarpc/internal/net"
 	intsync "go.uber.org/yarpc/internal/sync"
 	"go.uber.org/yarpc/peer/hostport"
 
 	"github.com/opentracing/opentracing-go"
+	"go.uber.org/multierr"
 )
 
 type transportConfig struct {
 	keepAlive           time.Duration
 	maxIdleConnsPerHost int
 	tracer              opentracing.Tracer
+	clientCertificates  []tls.Certificate
+	rootCAs             *x509.CertPool
 	buildClient         func(cfg *transportConfig) *http.Client
+
+	// If non-empty, clients built for this configuration connect to the
+	// unix domain socket at this path regardless of the URL of the request.
+	unixSocket string
+
+	h2c bool
 }
 
 var defaultTransportConfig = transportConfig{
@@ -82,6 +95,41 @@ func Tracer(tracer opentracing.Tracer) TransportOption {
 	}
 }
 
+// ClientCertificate specifies a certificate that the HTTP client presents to
+// servers that request one, as is required for mutual TLS. This option may be
+// provided multiple times.
+//
+// Outbounds use TLS only for "https" URLs.
+func ClientCertificate(cert tls.Certificate) TransportOption {
+	return func(c *transportConfig) {
+		c.clientCertificates = append(c.clientCertificates, cert)
+	}
+}
+
+// RootCAs specifies the certificate authorities used to verify the
+// certificates of servers called over "https" URLs.
+//
+// Defaults to the system's certificate pool.
+func RootCAs(pool *x509.CertPool) TransportOption {
+	return func(c *transportConfig) {
+		c.rootCAs = pool
+	}
+}
+
+// ClientH2C specifies that outbounds of the transport send requests over
+// HTTP/2 without TLS, assuming prior knowledge that their peers support it.
+// All requests to a peer are multiplexed over a single connection, and the
+// connection status of the peer follows the state of that connection.
+//
+// Peers must accept HTTP/2 over cleartext, as HTTP inbounds do with the
+// ServerH2C option. URLs with the "https" scheme are not supported in this
+// mode.
+func ClientH2C() TransportOption {
+	return func(c *transportConfig) {
+		c.h2c = true
+	}
+}
+
 // Hidden option to override the buildHTTPClient function. This is used only
 // for testing.
 func buildClient(f func(*transportConfig) *http.Client) TransportOption {
@@ -99,25 +147,47 @@ func NewTransport(opts ...TransportOption) *Transport {
 	}
 
 	return &Transport{
-		once:   intsync.Once(),
-		client: cfg.buildClient(&cfg),
-		peers:  make(map[string]*hostport.Peer),
-		tracer: cfg.tracer,
+		once:        intsync.Once(),
+		config:      cfg,
+		client:      cfg.buildClient(&cfg),
+		unixClients: make(map[string]*http.Client),
+		h2cConns:    make(map[string]*h2cConn),
+		peers:       make(map[string]*hostport.Peer),
+		tracer:      cfg.tracer,
 	}
 }
 
 func buildHTTPClient(cfg *transportConfig) *http.Client {
+	var tlsConfig *tls.Config
+	if len(cfg.clientCertificates) > 0 || cfg.rootCAs != nil {
+		tlsConfig = &tls.Config{
+			Certificates: cfg.clientCertificates,
+			RootCAs:      cfg.rootCAs,
+		}
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: cfg.keepAlive,
+	}
+	proxy := http.ProxyFromEnvironment
+	dial := dialer.DialContext
+	if path := cfg.unixSocket; path != "" {
+		proxy = nil
+		dial = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", path)
+		}
+	}
+
 	return &http.Client{
 		Transport: &http.Transport{
 			// options lifted from https://golang.org/src/net/http/transport.go
-			Proxy: http.ProxyFromEnvironment,
-			Dial: (&net.Dialer{
-				Timeout:   30 * time.Second,
-				KeepAlive: cfg.keepAlive,
-			}).Dial,
+			Proxy:                 proxy,
+			DialContext:           dial,
 			TLSHandshakeTimeout:   10 * time.Second,
 			ExpectContinueTimeout: 1 * time.Second,
 			MaxIdleConnsPerHost:   cfg.maxIdleConnsPerHost,
+			TLSClientConfig:       tlsConfig,
 		},
 	}
 }
@@ -129,9 +199,19 @@ type Transport struct {
 	lock sync.Mutex
 	once intsync.LifecycleOnce
 
+	config transportConfig
 	client *http.Client
 	peers  map[string]*hostport.Peer
 
+	// HTTP clients for peers listening on unix domain sockets, keyed by the
+	// path of the socket. Each socket needs its own client because clients
+	// pool connections by the host of the request URL, which is the same for
+	// all sockets.
+	unixClients map[string]*http.Client
+
+	// HTTP/2 connections to peers in h2c mode, keyed by peer identifier.
+	h2cConns map[string]*h2cConn
+
 	tracer opentracing.Tracer
 }
 
@@ -147,7 +227,18 @@ func (a *Transport) Start() error {
 // Stop stops the HTTP transport.
 func (a *Transport) Stop() error {
 	return a.once.Stop(func() error {
-		return nil // Nothing to do
+		a.lock.Lock()
+		conns := a.h2cConns
+		a.h2cConns = make(map[string]*h2cConn)
+		a.lock.Unlock()
+
+		// Closing a connection notifies the subscribers of its peer so this
+		// must happen without holding the lock.
+		var err error
+		for _, c := range conns {
+			err = multierr.Append(err, c.close())
+		}
+		return err
 	})
 }
 
@@ -181,7 +272,11 @@ func (a *Transport) getOrCreatePeer(pid hostport.PeerIdentifier) *hostport.Peer
 	}
 
 	p := hostport.NewPeer(pid, a)
-	p.SetStatus(peer.Available)
+	if a.config.h2c {
+		a.h2cConns[p.Identifier()] = newH2CConn(p, a.config.keepAlive)
+	} else {
+		p.SetStatus(peer.Available)
+	}
 
 	a.peers[p.Identifier()] = p
 
@@ -207,7 +302,51 @@ func (a *Transport) ReleasePeer(pid peer.Identifier, sub peer.Subscriber) error
 
 	if p.NumSubscribers() == 0 {
 		delete(a.peers, pid.Identifier())
+		if c, ok := a.h2cConns[pid.Identifier()]; ok {
+			delete(a.h2cConns, pid.Identifier())
+			if err := c.close(); err != nil {
+				return err
+			}
+		}
+		if network, path := intnet.SplitAddress(pid.Identifier()); network == "unix" {
+			if c, ok := a.unixClients[path]; ok {
+				delete(a.unixClients, path)
+				if t, ok := c.Transport.(*http.Transport); ok {
+					t.CloseIdleConnections()
+				}
+			}
+		}
 	}
 
 	return nil
 }
+
+// clientFor returns the HTTP client used to make requests to the given
+// address, which is either a "host:port" or a "unix:///path/to/socket"
+// address.
+func (a *Transport) clientFor(addr string) *http.Client {
+	if a.config.h2c {
+		a.lock.Lock()
+		c, ok := a.h2cConns[addr]
+		a.lock.Unlock()
+		if ok {
+			return c.client
+		}
+	}
+
+	network, path := intnet.SplitAddress(addr)
+	if network != "unix" {
+		return a.client
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	c, ok := a.unixClients[path]
+	if !ok {
+		cfg := a.config
+		cfg.unixSocket = path
+		c = cfg.buildClient(&cfg)
+		a.unixClients[path] = c
+	}
+	return c
+}
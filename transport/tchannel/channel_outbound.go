@@ -26,7 +26,6 @@ import (
 
 	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/internal/encoding"
-	"go.uber.org/yarpc/internal/errors"
 	"go.uber.org/yarpc/internal/introspection"
 	"go.uber.org/yarpc/internal/iopool"
 	"go.uber.org/yarpc/internal/sync"
@@ -214,14 +213,3 @@ func writeBody(body io.Reader, call *tchannel.OutboundCall) error {
 
 	return w.Close()
 }
-
-func fromSystemError(err tchannel.SystemError) error {
-	switch err.Code() {
-	case tchannel.ErrCodeCancelled, tchannel.ErrCodeBusy, tchannel.ErrCodeBadRequest:
-		return errors.RemoteBadRequestError(err.Message())
-	case tchannel.ErrCodeTimeout:
-		return errors.RemoteTimeoutError(err.Message())
-	default:
-		return errors.RemoteUnexpectedError(err.Message())
-	}
-}
@@ -45,10 +45,26 @@ type TransportConfig struct{}
 // 	    address: :4040
 //
 // At most one TChannel inbound may be defined in a single YARPC service.
+//
+// The inbound may accept compressed requests and compress responses with a
+// registered compressor.
+//
+// 	inbounds:
+// 	  tchannel:
+// 	    address: :4040
+// 	    compressor: gzip
+// 	    minCompressSize: 2048
 type InboundConfig struct {
 	// Address to listen on. Defaults to ":0" (all network interfaces and a
 	// random OS-assigned port).
 	Address string `config:"address,interpolate"`
+
+	// Name of the compressor used for request and response bodies, if any.
+	Compressor string `config:"compressor,interpolate"`
+
+	// Size in bytes under which responses are sent uncompressed. Defaults to
+	// 1024 bytes.
+	MinCompressSize int `config:"minCompressSize"`
 }
 
 // OutboundConfig configures a TChannel outbound.
@@ -57,8 +73,23 @@ type InboundConfig struct {
 // 	  myservice:
 // 	    tchannel:
 // 	      peer: 127.0.0.1:4040
+//
+// Request bodies may be compressed with a registered compressor.
+//
+// 	outbounds:
+// 	  myservice:
+// 	    tchannel:
+// 	      peer: 127.0.0.1:4040
+// 	      compressor: snappy
 type OutboundConfig struct {
 	config.PeerChooser
+
+	// Name of the compressor used for request and response bodies, if any.
+	Compressor string `config:"compressor,interpolate"`
+
+	// Size in bytes under which requests are sent uncompressed. Defaults to
+	// 1024 bytes.
+	MinCompressSize int `config:"minCompressSize"`
 }
 
 // TransportSpec returns a TransportSpec for the TChannel unary transport.
@@ -68,6 +99,10 @@ func TransportSpec(opts ...Option) config.TransportSpec {
 		switch opt := o.(type) {
 		case TransportOption:
 			ts.transportOptions = append(ts.transportOptions, opt)
+		case InboundOption:
+			ts.inboundOptions = append(ts.inboundOptions, opt)
+		case OutboundOption:
+			ts.outboundOptions = append(ts.outboundOptions, opt)
 		default:
 			panic(fmt.Sprintf("unknown option of type %T: %v", o, o))
 		}
@@ -81,6 +116,8 @@ func TransportSpec(opts ...Option) config.TransportSpec {
 // configuration.
 type transportSpec struct {
 	transportOptions []TransportOption
+	inboundOptions   []InboundOption
+	outboundOptions  []OutboundOption
 }
 
 func (ts *transportSpec) Spec() config.TransportSpec {
@@ -130,8 +167,20 @@ func (ts *transportSpec) buildInbound(c *InboundConfig, t transport.Transport, k
 		return nil, fmt.Errorf("at most one TChannel inbound may be specified")
 	}
 
+	opts := ts.inboundOptions
+	if c.Compressor != "" {
+		compressor, err := k.Compressor(c.Compressor)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, InboundCompressor(compressor))
+	}
+	if c.MinCompressSize > 0 {
+		opts = append(opts, InboundMinCompressSize(c.MinCompressSize))
+	}
+
 	trans.addr = c.Address
-	return trans.NewInbound(), nil
+	return trans.NewInbound(opts...), nil
 }
 
 func (ts *transportSpec) buildUnaryOutbound(oc *OutboundConfig, t transport.Transport, k *config.Kit) (transport.UnaryOutbound, error) {
@@ -140,5 +189,18 @@ func (ts *transportSpec) buildUnaryOutbound(oc *OutboundConfig, t transport.Tran
 	if err != nil {
 		return nil, err
 	}
-	return x.NewOutbound(chooser), nil
+
+	opts := ts.outboundOptions
+	if oc.Compressor != "" {
+		compressor, err := k.Compressor(oc.Compressor)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, OutboundCompressor(compressor))
+	}
+	if oc.MinCompressSize > 0 {
+		opts = append(opts, OutboundMinCompressSize(oc.MinCompressSize))
+	}
+
+	return x.NewOutbound(chooser, opts...), nil
 }
@@ -25,6 +25,8 @@ import (
 	"testing"
 
 	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/compressor/gzip"
+	"go.uber.org/yarpc/compressor/snappy"
 	"go.uber.org/yarpc/x/config"
 
 	"github.com/stretchr/testify/assert"
@@ -50,6 +52,11 @@ func TestTransportSpec(t *testing.T) {
 
 	type wantTransport struct {
 		Address string
+
+		// If set, the name of the compressor used by the transport and the
+		// minimum size of compressed responses.
+		Compressor      string
+		MinCompressSize int
 	}
 
 	type inboundTest struct {
@@ -77,6 +84,9 @@ func TestTransportSpec(t *testing.T) {
 
 		wantErrors    []string
 		wantOutbounds []string
+
+		// Names of compressors used by outbounds, indexed by service name.
+		wantCompressors map[string]string
 	}
 
 	inboundTests := []inboundTest{
@@ -128,6 +138,37 @@ func TestTransportSpec(t *testing.T) {
 			opts:       []Option{ListenAddr(":8080")},
 			wantErrors: []string{"TChannel TransportSpec does not accept ListenAddr"},
 		},
+		{
+			desc: "inbound compressor",
+			cfg: attrs{"tchannel": attrs{
+				"address":         ":4040",
+				"compressor":      "gzip",
+				"minCompressSize": 2048,
+			}},
+			wantTransport: &wantTransport{
+				Address:         ":4040",
+				Compressor:      "gzip",
+				MinCompressSize: 2048,
+			},
+		},
+		{
+			desc: "inbound compressor option",
+			cfg:  attrs{"tchannel": attrs{"address": ":4040"}},
+			opts: []Option{InboundCompressor(snappy.New())},
+			wantTransport: &wantTransport{
+				Address:         ":4040",
+				Compressor:      "snappy",
+				MinCompressSize: 1024,
+			},
+		},
+		{
+			desc: "inbound unknown compressor",
+			cfg: attrs{"tchannel": attrs{
+				"address":    ":4040",
+				"compressor": "lz4",
+			}},
+			wantErrors: []string{`no recognized compressor "lz4"`},
+		},
 	}
 
 	outboundTests := []outboundTest{
@@ -165,6 +206,34 @@ func TestTransportSpec(t *testing.T) {
 				`failed to read attribute "least-pending": wat`,
 			},
 		},
+		{
+			desc: "outbound compressor",
+			cfg: attrs{
+				"myservice": attrs{
+					"tchannel": attrs{
+						"peer":       "127.0.0.1:4040",
+						"compressor": "snappy",
+					},
+				},
+			},
+			wantOutbounds:   []string{"myservice"},
+			wantCompressors: map[string]string{"myservice": "snappy"},
+		},
+		{
+			desc: "outbound unknown compressor",
+			cfg: attrs{
+				"myservice": attrs{
+					"tchannel": attrs{
+						"peer":       "127.0.0.1:4040",
+						"compressor": "lz4",
+					},
+				},
+			},
+			wantErrors: []string{
+				`failed to configure unary outbound for "myservice"`,
+				`no recognized compressor "lz4"`,
+			},
+		},
 	}
 
 	runTest := func(t *testing.T, inbound inboundTest, outbound outboundTest) {
@@ -179,6 +248,8 @@ func TestTransportSpec(t *testing.T) {
 			env[k] = v
 		}
 		configurator := config.New(config.InterpolationResolver(mapResolver(env)))
+		configurator.MustRegisterCompressor(gzip.New())
+		configurator.MustRegisterCompressor(snappy.New())
 
 		opts := append(inbound.opts, outbound.opts...)
 		err := configurator.RegisterTransport(TransportSpec(opts...))
@@ -217,12 +288,21 @@ func TestTransportSpec(t *testing.T) {
 				trans := ib.transport
 				assert.Equal(t, "foo", trans.name, "service name must match")
 				assert.Equal(t, want.Address, trans.addr, "transport address must match")
+				if want.Compressor != "" && assert.NotNil(t, trans.compressor, "transport compressor must be set") {
+					assert.Equal(t, want.Compressor, trans.compressor.Name(), "transport compressor must match")
+					assert.Equal(t, want.MinCompressSize, trans.minCompressSize, "minimum compress size must match")
+				}
 			}
 		}
 
 		for _, svc := range outbound.wantOutbounds {
-			_, ok := cfg.Outbounds[svc].Unary.(*Outbound)
-			assert.True(t, ok, "expected *Outbound for %q, got %T", svc, cfg.Outbounds[svc].Unary)
+			ob, ok := cfg.Outbounds[svc].Unary.(*Outbound)
+			if !assert.True(t, ok, "expected *Outbound for %q, got %T", svc, cfg.Outbounds[svc].Unary) {
+				continue
+			}
+			if name, ok := outbound.wantCompressors[svc]; ok && assert.NotNil(t, ob.compressor, "compressor for %q must be set", svc) {
+				assert.Equal(t, name, ob.compressor.Name(), "compressor for %q must match", svc)
+			}
 		}
 
 		d := yarpc.NewDispatcher(cfg)
@@ -0,0 +1,140 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"go.uber.org/yarpc/yarpcerrors"
+
+	"github.com/uber/tchannel-go"
+)
+
+// TChannel system errors support fewer codes than yarpcerrors. Codes which
+// have a matching TChannel code are sent as-is. All other codes, and
+// Statuses with details, are sent with the closest TChannel code and a
+// message prefixed with the original code and details:
+//
+// 	[yarpc-code=not-found details=AP8=] user "foo" does not exist
+const (
+	_errorPrefix     = "[yarpc-code="
+	_errorDetailsKey = "details="
+)
+
+// _codeToTChannelCode maps yarpcerrors codes to the closest TChannel code.
+var _codeToTChannelCode = map[yarpcerrors.Code]tchannel.SystemErrCode{
+	yarpcerrors.CodeCancelled:          tchannel.ErrCodeCancelled,
+	yarpcerrors.CodeUnknown:            tchannel.ErrCodeUnexpected,
+	yarpcerrors.CodeInvalidArgument:    tchannel.ErrCodeBadRequest,
+	yarpcerrors.CodeDeadlineExceeded:   tchannel.ErrCodeTimeout,
+	yarpcerrors.CodeNotFound:           tchannel.ErrCodeBadRequest,
+	yarpcerrors.CodeAlreadyExists:      tchannel.ErrCodeBadRequest,
+	yarpcerrors.CodePermissionDenied:   tchannel.ErrCodeBadRequest,
+	yarpcerrors.CodeResourceExhausted:  tchannel.ErrCodeBusy,
+	yarpcerrors.CodeFailedPrecondition: tchannel.ErrCodeBadRequest,
+	yarpcerrors.CodeAborted:            tchannel.ErrCodeUnexpected,
+	yarpcerrors.CodeOutOfRange:         tchannel.ErrCodeBadRequest,
+	yarpcerrors.CodeUnimplemented:      tchannel.ErrCodeBadRequest,
+	yarpcerrors.CodeInternal:           tchannel.ErrCodeUnexpected,
+	yarpcerrors.CodeUnavailable:        tchannel.ErrCodeDeclined,
+	yarpcerrors.CodeDataLoss:           tchannel.ErrCodeUnexpected,
+	yarpcerrors.CodeUnauthenticated:    tchannel.ErrCodeBadRequest,
+}
+
+// _tchannelCodeToCode maps TChannel codes to yarpcerrors codes. Codes which
+// are the result of this mapping are sent without a prefix.
+var _tchannelCodeToCode = map[tchannel.SystemErrCode]yarpcerrors.Code{
+	tchannel.ErrCodeTimeout:    yarpcerrors.CodeDeadlineExceeded,
+	tchannel.ErrCodeCancelled:  yarpcerrors.CodeCancelled,
+	tchannel.ErrCodeBusy:       yarpcerrors.CodeResourceExhausted,
+	tchannel.ErrCodeDeclined:   yarpcerrors.CodeUnavailable,
+	tchannel.ErrCodeUnexpected: yarpcerrors.CodeInternal,
+	tchannel.ErrCodeBadRequest: yarpcerrors.CodeInvalidArgument,
+	tchannel.ErrCodeNetwork:    yarpcerrors.CodeUnavailable,
+	tchannel.ErrCodeProtocol:   yarpcerrors.CodeInternal,
+}
+
+// toSystemError converts a Status into a TChannel system error.
+func toSystemError(status *yarpcerrors.Status) error {
+	code, ok := _codeToTChannelCode[status.Code()]
+	if !ok {
+		code = tchannel.ErrCodeUnexpected
+	}
+
+	message := status.Message()
+	details := status.Details()
+	if _tchannelCodeToCode[code] != status.Code() || len(details) > 0 {
+		prefix := _errorPrefix + status.Code().String()
+		if len(details) > 0 {
+			prefix += " " + _errorDetailsKey + base64.StdEncoding.EncodeToString(details)
+		}
+		message = prefix + "] " + message
+	}
+	return tchannel.NewSystemError(code, "%s", message)
+}
+
+// fromSystemError converts a TChannel system error into a Status.
+func fromSystemError(err tchannel.SystemError) error {
+	code, ok := _tchannelCodeToCode[err.Code()]
+	if !ok {
+		code = yarpcerrors.CodeUnknown
+	}
+
+	message := err.Message()
+	var details []byte
+	if c, d, m, ok := parseErrorPrefix(message); ok {
+		code, details, message = c, d, m
+	}
+
+	return yarpcerrors.Newf(code, "%s", message).WithDetails(details)
+}
+
+// parseErrorPrefix parses the code and details from a message sent by
+// toSystemError. Returns false if the message does not have a valid prefix.
+func parseErrorPrefix(message string) (code yarpcerrors.Code, details []byte, rest string, ok bool) {
+	if !strings.HasPrefix(message, _errorPrefix) {
+		return code, nil, message, false
+	}
+	end := strings.Index(message, "] ")
+	if end < 0 {
+		return code, nil, message, false
+	}
+
+	fields := strings.Fields(message[len(_errorPrefix):end])
+	if len(fields) == 0 || len(fields) > 2 {
+		return code, nil, message, false
+	}
+	if err := code.UnmarshalText([]byte(fields[0])); err != nil || code == yarpcerrors.CodeOK {
+		return code, nil, message, false
+	}
+	if len(fields) == 2 {
+		if !strings.HasPrefix(fields[1], _errorDetailsKey) {
+			return code, nil, message, false
+		}
+		var err error
+		details, err = base64.StdEncoding.DecodeString(fields[1][len(_errorDetailsKey):])
+		if err != nil {
+			return code, nil, message, false
+		}
+	}
+	return code, details, message[end+2:], true
+}
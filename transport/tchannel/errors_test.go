@@ -0,0 +1,142 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tchannel
+
+import (
+	"testing"
+
+	"go.uber.org/yarpc/yarpcerrors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/tchannel-go"
+)
+
+func TestSystemErrorRoundTrip(t *testing.T) {
+	for code := range _codeToTChannelCode {
+		for _, details := range [][]byte{nil, {0x00, 0xff}} {
+			status := yarpcerrors.Newf(code, "great sadness").WithDetails(details)
+
+			err := toSystemError(status)
+			systemErr, ok := err.(tchannel.SystemError)
+			require.True(t, ok, "expected a tchannel.SystemError, got %T", err)
+			assert.Equal(t, _codeToTChannelCode[code], systemErr.Code())
+
+			got := yarpcerrors.FromError(fromSystemError(systemErr))
+			assert.Equal(t, code, got.Code())
+			assert.Equal(t, "great sadness", got.Message())
+			assert.Equal(t, details, got.Details())
+		}
+	}
+}
+
+func TestSystemErrorMessage(t *testing.T) {
+	tests := []struct {
+		desc string
+		give *yarpcerrors.Status
+		want string
+	}{
+		{
+			desc: "matching code",
+			give: yarpcerrors.Newf(yarpcerrors.CodeInvalidArgument, "missing service name"),
+			want: "missing service name",
+		},
+		{
+			desc: "closest code",
+			give: yarpcerrors.Newf(yarpcerrors.CodeNotFound, "no such user"),
+			want: "[yarpc-code=not-found] no such user",
+		},
+		{
+			desc: "details",
+			give: yarpcerrors.Newf(yarpcerrors.CodeInternal, "oops").WithDetails([]byte{0x00, 0xff}),
+			want: "[yarpc-code=internal details=AP8=] oops",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			err := toSystemError(tt.give).(tchannel.SystemError)
+			assert.Equal(t, tt.want, err.Message())
+		})
+	}
+}
+
+func TestFromSystemError(t *testing.T) {
+	tests := []struct {
+		desc        string
+		code        tchannel.SystemErrCode
+		message     string
+		wantCode    yarpcerrors.Code
+		wantMessage string
+	}{
+		{
+			desc:        "busy",
+			code:        tchannel.ErrCodeBusy,
+			message:     "slow down",
+			wantCode:    yarpcerrors.CodeResourceExhausted,
+			wantMessage: "slow down",
+		},
+		{
+			desc:        "network",
+			code:        tchannel.ErrCodeNetwork,
+			message:     "connection reset",
+			wantCode:    yarpcerrors.CodeUnavailable,
+			wantMessage: "connection reset",
+		},
+		{
+			desc:        "unknown code in prefix",
+			code:        tchannel.ErrCodeBadRequest,
+			message:     "[yarpc-code=great-sadness] oops",
+			wantCode:    yarpcerrors.CodeInvalidArgument,
+			wantMessage: "[yarpc-code=great-sadness] oops",
+		},
+		{
+			desc:        "ok code in prefix",
+			code:        tchannel.ErrCodeUnexpected,
+			message:     "[yarpc-code=ok] oops",
+			wantCode:    yarpcerrors.CodeInternal,
+			wantMessage: "[yarpc-code=ok] oops",
+		},
+		{
+			desc:        "invalid details in prefix",
+			code:        tchannel.ErrCodeUnexpected,
+			message:     "[yarpc-code=internal details=???] oops",
+			wantCode:    yarpcerrors.CodeInternal,
+			wantMessage: "[yarpc-code=internal details=???] oops",
+		},
+		{
+			desc:        "unterminated prefix",
+			code:        tchannel.ErrCodeBadRequest,
+			message:     "[yarpc-code=not-found",
+			wantCode:    yarpcerrors.CodeInvalidArgument,
+			wantMessage: "[yarpc-code=not-found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			err := tchannel.NewSystemError(tt.code, "%s", tt.message).(tchannel.SystemError)
+			status := yarpcerrors.FromError(fromSystemError(err))
+			assert.Equal(t, tt.wantCode, status.Code())
+			assert.Equal(t, tt.wantMessage, status.Message())
+		})
+	}
+}
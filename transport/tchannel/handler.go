@@ -21,14 +21,18 @@
 package tchannel
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"time"
 
 	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/compress"
 	"go.uber.org/yarpc/internal/encoding"
 	"go.uber.org/yarpc/internal/errors"
 	"go.uber.org/yarpc/internal/request"
+	"go.uber.org/yarpc/yarpcerrors"
 
 	"github.com/opentracing/opentracing-go"
 	"github.com/uber/tchannel-go"
@@ -80,6 +84,11 @@ type handler struct {
 	existing map[string]tchannel.Handler
 	router   transport.Router
 	tracer   opentracing.Tracer
+
+	// Compressor supported for request and response bodies, if any, and the
+	// size under which response bodies are sent uncompressed.
+	compressor      transport.Compressor
+	minCompressSize int
 }
 
 func (h handler) Handle(ctx ncontext.Context, call *tchannel.InboundCall) {
@@ -99,16 +108,12 @@ func (h handler) handle(ctx context.Context, call inboundCall) {
 		return
 	}
 
-	err = errors.AsHandlerError(call.ServiceName(), call.MethodString(), err)
-	status := tchannel.ErrCodeUnexpected
-	if transport.IsBadRequestError(err) {
-		status = tchannel.ErrCodeBadRequest
-	} else if transport.IsTimeoutError(err) {
-		status = tchannel.ErrCodeTimeout
+	if !yarpcerrors.IsStatus(err) {
+		err = errors.AsHandlerError(call.ServiceName(), call.MethodString(), err)
 	}
 
 	// TODO: log error
-	_ = call.Response().SendSystemError(tchannel.NewSystemError(status, err.Error()))
+	_ = call.Response().SendSystemError(toSystemError(yarpcerrors.FromError(err)))
 }
 
 func (h handler) callHandler(ctx context.Context, call inboundCall, start time.Time) error {
@@ -128,6 +133,10 @@ func (h handler) callHandler(ctx context.Context, call inboundCall, start time.T
 	if err != nil {
 		return encoding.RequestHeadersDecodeError(treq, err)
 	}
+	contentEncoding, _ := headers.Get(contentEncodingHeader)
+	acceptEncoding, _ := headers.Get(acceptEncodingHeader)
+	headers.Del(contentEncodingHeader)
+	headers.Del(acceptEncodingHeader)
 	treq.Headers = headers
 
 	if tcall, ok := call.(tchannelCall); ok {
@@ -142,7 +151,20 @@ func (h handler) callHandler(ctx context.Context, call inboundCall, start time.T
 	defer body.Close()
 	treq.Body = body
 
+	if !compress.IsIdentity(contentEncoding) {
+		r, err := h.decompress(contentEncoding, body)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		treq.Body = r
+	}
+
 	rw := newResponseWriter(treq, call)
+	if h.compressor != nil && compress.Accepts(acceptEncoding, h.compressor.Name()) {
+		rw.compressor = h.compressor
+		rw.minCompressSize = h.minCompressSize
+	}
 	defer rw.Close() // TODO(abg): log if this errors
 
 	if err := transport.ValidateRequest(treq); err != nil {
@@ -177,6 +199,22 @@ func (h handler) callHandler(ctx context.Context, call inboundCall, start time.T
 	return err
 }
 
+// decompress returns a reader for the request body compressed in the given
+// format.
+func (h handler) decompress(contentEncoding string, body io.Reader) (io.ReadCloser, error) {
+	if h.compressor == nil || contentEncoding != h.compressor.Name() {
+		return nil, yarpcerrors.Newf(yarpcerrors.CodeUnimplemented,
+			"unsupported content encoding %q", contentEncoding)
+	}
+
+	r, err := h.compressor.Decompress(body)
+	if err != nil {
+		return nil, yarpcerrors.Newf(yarpcerrors.CodeInvalidArgument,
+			"cannot decompress request body: %v", err)
+	}
+	return r, nil
+}
+
 type responseWriter struct {
 	treq         *transport.Request
 	failedWith   error
@@ -185,6 +223,12 @@ type responseWriter struct {
 	headers      transport.Headers
 	response     inboundCallResponse
 	wroteHeaders bool
+
+	// If compressor is set, the body is buffered until the writer is closed
+	// and compressed if it is at least minCompressSize bytes long.
+	compressor      transport.Compressor
+	minCompressSize int
+	buffer          bytes.Buffer
 }
 
 func newResponseWriter(treq *transport.Request, call inboundCall) *responseWriter {
@@ -234,6 +278,10 @@ func (rw *responseWriter) Write(s []byte) (int, error) {
 		return 0, rw.failedWith
 	}
 
+	if rw.compressor != nil {
+		return rw.buffer.Write(s)
+	}
+
 	if err := rw.ensureWroteHeaders(); err != nil {
 		return 0, err
 	}
@@ -254,7 +302,30 @@ func (rw *responseWriter) Write(s []byte) (int, error) {
 	return n, err
 }
 
+// flush writes the buffered body, compressing it if necessary. Errors are
+// recorded in failedWith.
+func (rw *responseWriter) flush() {
+	c := rw.compressor
+	rw.compressor = nil
+
+	body, compressed, err := compress.Compress(c, rw.minCompressSize, rw.buffer.Bytes())
+	if err != nil {
+		rw.failedWith = err
+		return
+	}
+	if compressed {
+		rw.headers = rw.headers.With(contentEncodingHeader, c.Name())
+	}
+	if len(body) > 0 {
+		rw.Write(body)
+	}
+}
+
 func (rw *responseWriter) Close() error {
+	if rw.compressor != nil && rw.failedWith == nil {
+		rw.flush()
+	}
+
 	err := rw.ensureWroteHeaders()
 
 	if rw.bodyWriter != nil {
@@ -31,6 +31,17 @@ import (
 	"github.com/uber/tchannel-go"
 )
 
+// Reserved application headers used to negotiate the compression of request
+// and response bodies.
+const (
+	// Name of the compressor used for the body, if it was compressed.
+	contentEncodingHeader = "$rpc$-content-encoding"
+
+	// Comma-separated names of the compressors that the caller accepts for
+	// the response body.
+	acceptEncodingHeader = "$rpc$-accept-encoding"
+)
+
 // readRequestHeaders reads headers and baggage from an incoming request.
 func readRequestHeaders(
 	ctx context.Context,
@@ -89,6 +100,24 @@ func writeRequestHeaders(
 	return writeHeaders(format, headers, getWriter)
 }
 
+// withCompressionHeaders returns a copy of the given headers with the
+// compression headers for a request sent by an outbound using the named
+// compressor. contentEncoding is empty if the body was not compressed.
+func withCompressionHeaders(headers map[string]string, compressor, contentEncoding string) map[string]string {
+	// The headers may belong to the transport.Request, which we must not
+	// modify.
+	newHeaders := make(map[string]string, len(headers)+2)
+	for k, v := range headers {
+		newHeaders[k] = v
+	}
+
+	newHeaders[acceptEncodingHeader] = compressor
+	if contentEncoding != "" {
+		newHeaders[contentEncodingHeader] = contentEncoding
+	}
+	return newHeaders
+}
+
 // writeHeaders writes the given headers using the given function to get the
 // arg writer.
 //
@@ -22,6 +22,7 @@ package tchannel
 
 import (
 	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/compress"
 	"go.uber.org/yarpc/internal/introspection"
 	"go.uber.org/yarpc/internal/sync"
 )
@@ -31,6 +32,9 @@ import (
 type Inbound struct {
 	once      sync.LifecycleOnce
 	transport *Transport
+
+	compressor      transport.Compressor
+	minCompressSize int
 }
 
 // NewInbound returns a new TChannel inbound backed by a shared TChannel
@@ -38,11 +42,20 @@ type Inbound struct {
 // There should only be one inbound for TChannel since all outbounds send the
 // listening port over non-ephemeral connections so a service can deduplicate
 // locally- and remotely-initiated persistent connections.
-func (t *Transport) NewInbound() *Inbound {
-	return &Inbound{
-		once:      sync.Once(),
-		transport: t,
+func (t *Transport) NewInbound(opts ...InboundOption) *Inbound {
+	i := &Inbound{
+		once:            sync.Once(),
+		transport:       t,
+		minCompressSize: compress.DefaultMinSize,
+	}
+	for _, opt := range opts {
+		opt(i)
 	}
+
+	// Requests are handled by the transport.
+	t.compressor = i.compressor
+	t.minCompressSize = i.minCompressSize
+	return i
 }
 
 // SetRouter configures a router to handle incoming requests.
@@ -23,6 +23,7 @@ package tchannel
 import (
 	"bytes"
 	"context"
+	"io"
 	"io/ioutil"
 	"testing"
 	"time"
@@ -30,7 +31,10 @@ import (
 	"go.uber.org/yarpc"
 	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/compressor/gzip"
+	"go.uber.org/yarpc/compressor/snappy"
 	"go.uber.org/yarpc/encoding/raw"
+	"go.uber.org/yarpc/yarpcerrors"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -194,3 +198,115 @@ func TestArbitraryInboundServiceOutboundCallerName(t *testing.T) {
 	require.NoError(t, i.Stop())
 	require.NoError(t, o.Stop())
 }
+
+type echohandler struct{}
+
+func (echohandler) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter) error {
+	_, err := io.Copy(resw, req.Body)
+	return err
+}
+
+func TestInboundCompression(t *testing.T) {
+	big := bytes.Repeat([]byte("hello world "), 1000)
+
+	tests := []struct {
+		desc               string
+		inboundCompressor  transport.Compressor
+		outboundCompressor transport.Compressor
+		body               []byte
+		wantErrCode        yarpcerrors.Code
+	}{
+		{
+			desc: "no compression",
+			body: big,
+		},
+		{
+			desc:               "gzip",
+			inboundCompressor:  gzip.New(),
+			outboundCompressor: gzip.New(),
+			body:               big,
+		},
+		{
+			desc:               "snappy below minimum size",
+			inboundCompressor:  snappy.New(),
+			outboundCompressor: snappy.New(),
+			body:               []byte("hello"),
+		},
+		{
+			desc:              "inbound compressor only",
+			inboundCompressor: gzip.New(),
+			body:              big,
+		},
+		{
+			desc:               "outbound compressor only",
+			outboundCompressor: gzip.New(),
+			body:               big,
+			wantErrCode:        yarpcerrors.CodeUnimplemented,
+		},
+		{
+			desc:               "mismatched compressors",
+			inboundCompressor:  snappy.New(),
+			outboundCompressor: gzip.New(),
+			body:               big,
+			wantErrCode:        yarpcerrors.CodeUnimplemented,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			it, err := NewTransport(ServiceName("myservice"), ListenAddr("localhost:0"))
+			require.NoError(t, err)
+
+			var inboundOpts []InboundOption
+			if tt.inboundCompressor != nil {
+				inboundOpts = append(inboundOpts, InboundCompressor(tt.inboundCompressor))
+			}
+			router := yarpc.NewMapRouter("myservice")
+			router.Register([]transport.Procedure{
+				{Name: "echo", HandlerSpec: transport.NewUnaryHandlerSpec(echohandler{})},
+			})
+			i := it.NewInbound(inboundOpts...)
+			i.SetRouter(router)
+			require.NoError(t, i.Start())
+			defer i.Stop()
+			require.NoError(t, it.Start())
+			defer it.Stop()
+
+			var outboundOpts []OutboundOption
+			if tt.outboundCompressor != nil {
+				outboundOpts = append(outboundOpts, OutboundCompressor(tt.outboundCompressor))
+			}
+			ot, err := NewTransport(ServiceName("caller"))
+			require.NoError(t, err)
+			o := ot.NewSingleOutbound(it.ListenAddr(), outboundOpts...)
+			require.NoError(t, o.Start())
+			defer o.Stop()
+			require.NoError(t, ot.Start())
+			defer ot.Stop()
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			res, err := o.Call(ctx, &transport.Request{
+				Caller:    "caller",
+				Service:   "myservice",
+				Procedure: "echo",
+				Encoding:  raw.Encoding,
+				Body:      bytes.NewReader(tt.body),
+			})
+			if tt.wantErrCode != yarpcerrors.CodeOK {
+				require.Error(t, err, "expected failure")
+				assert.Equal(t, tt.wantErrCode, yarpcerrors.FromError(err).Code(), "error code must match")
+				return
+			}
+			require.NoError(t, err, "failed to make call")
+			defer res.Body.Close()
+
+			body, err := ioutil.ReadAll(res.Body)
+			require.NoError(t, err, "failed to read response body")
+			assert.Equal(t, tt.body, body, "response body must match")
+
+			_, ok := res.Headers.Get(contentEncodingHeader)
+			assert.False(t, ok, "content encoding header must not be visible to callers")
+		})
+	}
+}
@@ -20,16 +20,24 @@
 
 package tchannel
 
-import "github.com/opentracing/opentracing-go"
+import (
+	"go.uber.org/yarpc/api/transport"
+
+	"github.com/opentracing/opentracing-go"
+)
 
 // Option allows customizing the YARPC TChannel transport.
-// TransportSpec() accepts any TransportOption, and may in the future also
-// accept inbound and outbound options.
+// TransportSpec() accepts any TransportOption, InboundOption, or
+// OutboundOption.
 type Option interface {
 	tchannelOption()
 }
 
-var _ Option = (TransportOption)(nil)
+var (
+	_ Option = (TransportOption)(nil)
+	_ Option = (InboundOption)(nil)
+	_ Option = (OutboundOption)(nil)
+)
 
 // transportConfig is suitable for conveying options to TChannel transport
 // constructors.
@@ -107,3 +115,60 @@ func ServiceName(name string) TransportOption {
 		t.name = name
 	}
 }
+
+// InboundOption customizes the behavior of a TChannel Inbound.
+type InboundOption func(*Inbound)
+
+// InboundOption makes all InboundOptions recognizeable as Option so
+// TransportSpec will accept them.
+func (InboundOption) tchannelOption() {}
+
+// InboundCompressor specifies that the inbound accepts requests whose bodies
+// were compressed with the given compressor and compresses the bodies of
+// responses to callers that accept that compressor.
+//
+// Compression is negotiated with the reserved "$rpc$-content-encoding" and
+// "$rpc$-accept-encoding" application headers. Requests compressed in any
+// other format are rejected.
+func InboundCompressor(c transport.Compressor) InboundOption {
+	return func(i *Inbound) {
+		i.compressor = c
+	}
+}
+
+// InboundMinCompressSize specifies the size, in bytes, under which the bodies
+// of responses are sent uncompressed when the InboundCompressor option is
+// used. Defaults to 1024 bytes.
+func InboundMinCompressSize(size int) InboundOption {
+	return func(i *Inbound) {
+		i.minCompressSize = size
+	}
+}
+
+// OutboundOption customizes the behavior of a TChannel Outbound.
+type OutboundOption func(*Outbound)
+
+// OutboundOption makes all OutboundOptions recognizeable as Option so
+// TransportSpec will accept them.
+func (OutboundOption) tchannelOption() {}
+
+// OutboundCompressor specifies that the outbound compresses the bodies of
+// requests with the given compressor and asks for responses compressed in
+// the same format.
+//
+// Inbounds that receive these requests MUST support the same compressor, as
+// TChannel inbounds do with the InboundCompressor option.
+func OutboundCompressor(c transport.Compressor) OutboundOption {
+	return func(o *Outbound) {
+		o.compressor = c
+	}
+}
+
+// OutboundMinCompressSize specifies the size, in bytes, under which the
+// bodies of requests are sent uncompressed when the OutboundCompressor option
+// is used. Defaults to 1024 bytes.
+func OutboundMinCompressSize(size int) OutboundOption {
+	return func(o *Outbound) {
+		o.minCompressSize = size
+	}
+}
@@ -21,15 +21,19 @@
 package tchannel
 
 import (
+	"bytes"
 	"context"
+	"io"
 
 	"go.uber.org/yarpc/api/peer"
 	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/compress"
 	"go.uber.org/yarpc/internal/encoding"
 	"go.uber.org/yarpc/internal/introspection"
 	intsync "go.uber.org/yarpc/internal/sync"
 	peerchooser "go.uber.org/yarpc/peer"
 	"go.uber.org/yarpc/peer/hostport"
+	"go.uber.org/yarpc/yarpcerrors"
 
 	"github.com/uber/tchannel-go"
 )
@@ -46,23 +50,31 @@ type Outbound struct {
 	transport *Transport
 	chooser   peer.Chooser
 	once      intsync.LifecycleOnce
+
+	compressor      transport.Compressor
+	minCompressSize int
 }
 
 // NewOutbound builds a new TChannel outbound that selects a peer for each
 // request using the given peer chooser.
-func (t *Transport) NewOutbound(chooser peer.Chooser) *Outbound {
-	return &Outbound{
-		once:      intsync.Once(),
-		transport: t,
-		chooser:   chooser,
+func (t *Transport) NewOutbound(chooser peer.Chooser, opts ...OutboundOption) *Outbound {
+	o := &Outbound{
+		once:            intsync.Once(),
+		transport:       t,
+		chooser:         chooser,
+		minCompressSize: compress.DefaultMinSize,
+	}
+	for _, opt := range opts {
+		opt(o)
 	}
+	return o
 }
 
 // NewSingleOutbound builds a new TChannel outbound always using the peer with
 // the given address.
-func (t *Transport) NewSingleOutbound(addr string) *Outbound {
+func (t *Transport) NewSingleOutbound(addr string, opts ...OutboundOption) *Outbound {
 	chooser := peerchooser.NewSingle(hostport.PeerIdentifier(addr), t)
-	return t.NewOutbound(chooser)
+	return t.NewOutbound(chooser, opts...)
 }
 
 // Chooser returns the outbound's peer chooser.
@@ -122,13 +134,23 @@ func (o *Outbound) callWithPeer(ctx context.Context, req *transport.Request, pee
 	// Inject tracing system baggage
 	reqHeaders := tchannel.InjectOutboundSpan(call.Response(), req.Headers.Items())
 
+	body := req.Body
+	if o.compressor != nil {
+		var contentEncoding string
+		body, contentEncoding, err = o.compressBody(req)
+		if err != nil {
+			return nil, err
+		}
+		reqHeaders = withCompressionHeaders(reqHeaders, o.compressor.Name(), contentEncoding)
+	}
+
 	if err := writeRequestHeaders(ctx, format, reqHeaders, call.Arg2Writer); err != nil {
 		// TODO(abg): This will wrap IO errors while writing headers as encode
 		// errors. We should fix that.
 		return nil, encoding.RequestHeadersEncodeError(req, err)
 	}
 
-	if err := writeBody(req.Body, call); err != nil {
+	if err := writeBody(body, call); err != nil {
 		return nil, err
 	}
 
@@ -143,6 +165,9 @@ func (o *Outbound) callWithPeer(ctx context.Context, req *transport.Request, pee
 		return nil, encoding.ResponseHeadersDecodeError(req, err)
 	}
 
+	contentEncoding, _ := headers.Get(contentEncodingHeader)
+	headers.Del(contentEncodingHeader)
+
 	resBody, err := res.Arg3Reader()
 	if err != nil {
 		if err, ok := err.(tchannel.SystemError); ok {
@@ -151,6 +176,13 @@ func (o *Outbound) callWithPeer(ctx context.Context, req *transport.Request, pee
 		return nil, err
 	}
 
+	if !compress.IsIdentity(contentEncoding) {
+		resBody, err = o.decompressBody(req, contentEncoding, resBody)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &transport.Response{
 		Headers:          headers,
 		Body:             resBody,
@@ -158,6 +190,38 @@ func (o *Outbound) callWithPeer(ctx context.Context, req *transport.Request, pee
 	}, nil
 }
 
+// compressBody returns the body of the request as it should be sent and the
+// name of the compressor used for it, if any.
+func (o *Outbound) compressBody(req *transport.Request) (io.Reader, string, error) {
+	body, compressed, err := compress.CompressReader(o.compressor, o.minCompressSize, req.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	if !compressed {
+		return bytes.NewReader(body), "", nil
+	}
+	return bytes.NewReader(body), o.compressor.Name(), nil
+}
+
+// decompressBody returns a reader for the decompressed body of a response
+// that was compressed in the given format. The body is closed if it cannot
+// be decompressed.
+func (o *Outbound) decompressBody(req *transport.Request, contentEncoding string, body io.ReadCloser) (io.ReadCloser, error) {
+	if o.compressor == nil || contentEncoding != o.compressor.Name() {
+		body.Close()
+		return nil, yarpcerrors.Newf(yarpcerrors.CodeInternal,
+			"response to procedure %q of service %q uses unsupported content encoding %q",
+			req.Procedure, req.Service, contentEncoding)
+	}
+
+	r, err := compress.Decompress(o.compressor, body)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
 func (o *Outbound) getPeerForRequest(ctx context.Context, treq *transport.Request) (*hostport.Peer, func(error), error) {
 	p, onFinish, err := o.chooser.Choose(ctx, treq)
 	if err != nil {
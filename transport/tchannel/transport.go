@@ -49,6 +49,10 @@ type Transport struct {
 	name   string
 	addr   string
 
+	// Compression settings of the inbound, if any.
+	compressor      transport.Compressor
+	minCompressSize int
+
 	peers map[string]*hostport.Peer
 }
 
@@ -157,8 +161,10 @@ func (t *Transport) start() error {
 	chopts := tchannel.ChannelOptions{
 		Tracer: t.tracer,
 		Handler: handler{
-			router: t.router,
-			tracer: t.tracer,
+			router:          t.router,
+			tracer:          t.tracer,
+			compressor:      t.compressor,
+			minCompressSize: t.minCompressSize,
 		},
 	}
 	ch, err := tchannel.NewChannel(t.name, &chopts)
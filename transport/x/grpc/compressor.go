@@ -0,0 +1,98 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"io"
+	"sync"
+
+	"go.uber.org/yarpc/api/transport"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// Compression is negotiated by gRPC itself. The grpc-encoding header of a
+// call names the compressor its messages were compressed with, gRPC looks
+// that compressor up by name to decompress them, and servers compress their
+// responses with the compressor of the request. This interoperates with any
+// gRPC client or server that supports the same compressor.
+
+var _ encoding.Compressor = grpcCompressor{}
+
+// registerLock serializes the registration of compressors with gRPC.
+var registerLock sync.Mutex
+
+// registerCompressor registers the compressor with gRPC under its name,
+// unless a compressor was already registered under that name.
+//
+// gRPC does not synchronize access to the compressors registered with it, so
+// compressors are registered when inbounds and outbounds are built, before
+// they make or handle any calls, and are never replaced.
+func registerCompressor(c transport.Compressor) {
+	registerLock.Lock()
+	defer registerLock.Unlock()
+	if encoding.GetCompressor(c.Name()) == nil {
+		encoding.RegisterCompressor(grpcCompressor{c})
+	}
+}
+
+// grpcCompressor adapts a transport.Compressor to an encoding.Compressor.
+type grpcCompressor struct {
+	c transport.Compressor
+}
+
+func (g grpcCompressor) Name() string {
+	return g.c.Name()
+}
+
+func (g grpcCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return g.c.Compress(w)
+}
+
+func (g grpcCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	dr, err := g.c.Decompress(r)
+	if err != nil {
+		return nil, err
+	}
+	return &closeAtEOF{ReadCloser: dr}, nil
+}
+
+// closeAtEOF closes the reader once it was read completely, since gRPC
+// never closes the readers returned by Decompress.
+type closeAtEOF struct {
+	io.ReadCloser
+
+	closed bool
+}
+
+func (r *closeAtEOF) Read(p []byte) (int, error) {
+	if r.closed {
+		return 0, io.EOF
+	}
+	n, err := r.ReadCloser.Read(p)
+	if err == io.EOF {
+		r.closed = true
+		if closeErr := r.ReadCloser.Close(); closeErr != nil {
+			return n, closeErr
+		}
+	}
+	return n, err
+}
@@ -21,10 +21,13 @@
 package grpc
 
 import (
+	"errors"
 	"fmt"
 	"net"
 
 	"go.uber.org/yarpc/api/transport"
+	intnet "go.uber.org/yarpc/internal/net"
+	"go.uber.org/yarpc/peer/hostport"
 	"go.uber.org/yarpc/x/config"
 )
 
@@ -64,7 +67,27 @@ type TransportConfig struct{}
 //     address: ":80"
 type InboundConfig struct {
 	// Address to listen on. This field is required.
+	//
+	// The inbound listens on a unix domain socket if the address has the
+	// form "unix:///path/to/socket". A socket left behind at that path by a
+	// process that did not shut down cleanly is removed when the inbound
+	// starts.
 	Address string `config:"address,interpolate"`
+
+	// Name of a registered compressor used to decompress requests and to
+	// compress the responses to requests compressed with it, if any.
+	//
+	// inbounds:
+	//   grpc:
+	//     address: ":80"
+	//     compressor: gzip
+	Compressor string `config:"compressor,interpolate"`
+
+	// Unlike HTTP and TChannel inbounds, gRPC inbounds do not support
+	// minCompressSize: gRPC compresses every response to a compressed
+	// request with the compressor of the request, whatever its size. The
+	// key is rejected rather than silently ignored.
+	MinCompressSize int `config:"minCompressSize"`
 }
 
 // OutboundConfig configures a gRPC Outbound.
@@ -73,9 +96,38 @@ type InboundConfig struct {
 //   myservice:
 //     grpc:
 //       address: ":80"
+//
+// A gRPC outbound can also configure a peer list instead of an address.
+//
+// outbounds:
+//   myservice:
+//     grpc:
+//       round-robin:
+//         peers:
+//           - 127.0.0.1:8080
+//           - 127.0.0.1:8081
+//
+// Servers listening on unix domain sockets are addressed as
+// "unix:///path/to/socket", both in the address field and in peer lists.
 type OutboundConfig struct {
-	// Address to connect to. This field is required.
+	config.PeerChooser
+
+	// Address to connect to. This field is required unless a peer list is
+	// configured, and may not be used together with one.
 	Address string `config:"address,interpolate"`
+
+	// Name of a registered compressor used to compress requests, if any.
+	//
+	// outbounds:
+	//   myservice:
+	//     grpc:
+	//       address: ":80"
+	//       compressor: gzip
+	Compressor string `config:"compressor,interpolate"`
+
+	// Size in bytes under which unary requests are sent uncompressed.
+	// Defaults to 1024 bytes.
+	MinCompressSize int `config:"minCompressSize"`
 }
 
 type transportSpec struct {
@@ -105,7 +157,7 @@ func (t *transportSpec) buildTransport(*TransportConfig, *config.Kit) (transport
 	return NewTransport(t.TransportOptions...), nil
 }
 
-func (t *transportSpec) buildInbound(inboundConfig *InboundConfig, tr transport.Transport, _ *config.Kit) (transport.Inbound, error) {
+func (t *transportSpec) buildInbound(inboundConfig *InboundConfig, tr transport.Transport, kit *config.Kit) (transport.Inbound, error) {
 	trans, ok := tr.(*Transport)
 	if !ok {
 		return nil, newTransportCastError(tr)
@@ -113,22 +165,56 @@ func (t *transportSpec) buildInbound(inboundConfig *InboundConfig, tr transport.
 	if inboundConfig.Address == "" {
 		return nil, newRequiredFieldMissingError("address")
 	}
-	listener, err := net.Listen("tcp", inboundConfig.Address)
-	if err != nil {
-		return nil, err
+	if inboundConfig.MinCompressSize != 0 {
+		return nil, errors.New("minCompressSize is not supported by gRPC inbounds: " +
+			"responses to compressed requests are always compressed")
 	}
-	return trans.NewInbound(listener, t.InboundOptions...), nil
+	options := t.InboundOptions
+	if inboundConfig.Compressor != "" {
+		compressor, err := kit.Compressor(inboundConfig.Compressor)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, InboundCompressor(compressor))
+	}
+	if network, address := intnet.SplitAddress(inboundConfig.Address); network == "tcp" {
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			return nil, err
+		}
+	}
+	return newAddressInbound(trans, inboundConfig.Address, options...), nil
 }
 
-func (t *transportSpec) buildUnaryOutbound(outboundConfig *OutboundConfig, tr transport.Transport, _ *config.Kit) (transport.UnaryOutbound, error) {
+func (t *transportSpec) buildUnaryOutbound(outboundConfig *OutboundConfig, tr transport.Transport, kit *config.Kit) (transport.UnaryOutbound, error) {
 	trans, ok := tr.(*Transport)
 	if !ok {
 		return nil, newTransportCastError(tr)
 	}
-	if outboundConfig.Address == "" {
-		return nil, newRequiredFieldMissingError("address")
+	options := t.OutboundOptions
+	if outboundConfig.Compressor != "" {
+		compressor, err := kit.Compressor(outboundConfig.Compressor)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, OutboundCompressor(compressor))
+	}
+	if outboundConfig.MinCompressSize > 0 {
+		options = append(options, OutboundMinCompressSize(outboundConfig.MinCompressSize))
+	}
+	if outboundConfig.Empty() {
+		if outboundConfig.Address == "" {
+			return nil, newRequiredFieldMissingError("address")
+		}
+		return trans.NewSingleOutbound(outboundConfig.Address, options...), nil
+	}
+	if outboundConfig.Address != "" {
+		return nil, fmt.Errorf("address cannot be used with a peer chooser: %s", outboundConfig.Address)
+	}
+	chooser, err := outboundConfig.BuildPeerChooser(trans, hostport.Identify, kit)
+	if err != nil {
+		return nil, fmt.Errorf("cannot configure peer chooser for gRPC outbound: %v", err)
 	}
-	return trans.NewSingleOutbound(outboundConfig.Address, t.OutboundOptions...), nil
+	return trans.NewOutbound(chooser, options...), nil
 }
 
 func newTransportCastError(tr transport.Transport) error {
@@ -21,8 +21,15 @@
 package grpc
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"go.uber.org/yarpc/compressor/gzip"
+	"go.uber.org/yarpc/compressor/snappy"
+	peerchooser "go.uber.org/yarpc/peer"
+	"go.uber.org/yarpc/peer/roundrobin"
 	"go.uber.org/yarpc/x/config"
 
 	"github.com/stretchr/testify/assert"
@@ -60,11 +67,15 @@ func TestTransportSpec(t *testing.T) {
 	type attrs map[string]interface{}
 
 	type wantInbound struct {
-		Address string
+		Address    string
+		Compressor string
 	}
 
 	type wantOutbound struct {
-		Address string
+		Address         string
+		ChooserListType interface{}
+		Compressor      string
+		MinCompressSize int
 	}
 
 	type test struct {
@@ -78,6 +89,11 @@ func TestTransportSpec(t *testing.T) {
 		wantErrors    []string
 	}
 
+	dir, err := ioutil.TempDir("", "yarpc-grpc")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "grpc.sock")
+
 	tests := []test{
 		{
 			desc:        "simple inbound",
@@ -90,11 +106,35 @@ func TestTransportSpec(t *testing.T) {
 			env:         map[string]string{"HOST": "127.0.0.1", "PORT": "34568"},
 			wantInbound: &wantInbound{Address: "127.0.0.1:34568"},
 		},
+		{
+			desc:        "unix socket inbound",
+			inboundCfg:  attrs{"address": "unix://" + socket},
+			wantInbound: &wantInbound{Address: socket},
+		},
 		{
 			desc:       "bad inbound address",
 			inboundCfg: attrs{"address": "derp"},
 			wantErrors: []string{"address derp"},
 		},
+		{
+			desc:        "inbound compressor",
+			inboundCfg:  attrs{"address": ":34569", "compressor": "gzip"},
+			wantInbound: &wantInbound{Address: ":34569", Compressor: "gzip"},
+		},
+		{
+			desc:       "inbound unknown compressor",
+			inboundCfg: attrs{"address": ":34570", "compressor": "lz4"},
+			wantErrors: []string{`no recognized compressor "lz4"`},
+		},
+		{
+			desc: "inbound compressor minimum size",
+			inboundCfg: attrs{
+				"address":         ":34571",
+				"compressor":      "gzip",
+				"minCompressSize": 42,
+			},
+			wantErrors: []string{"minCompressSize is not supported by gRPC inbounds"},
+		},
 		{
 			desc: "simple outbound",
 			outboundCfg: attrs{
@@ -108,6 +148,19 @@ func TestTransportSpec(t *testing.T) {
 				},
 			},
 		},
+		{
+			desc: "unix socket outbound",
+			outboundCfg: attrs{
+				"myservice": attrs{
+					transportName: attrs{"address": "unix:///var/run/myservice.sock"},
+				},
+			},
+			wantOutbounds: map[string]wantOutbound{
+				"myservice": {
+					Address: "unix:///var/run/myservice.sock",
+				},
+			},
+		},
 		{
 			desc: "outbound interpolation",
 			outboundCfg: attrs{
@@ -122,6 +175,84 @@ func TestTransportSpec(t *testing.T) {
 				},
 			},
 		},
+		{
+			desc: "outbound peer list",
+			outboundCfg: attrs{
+				"myservice": attrs{
+					transportName: attrs{
+						"round-robin": attrs{
+							"peers": []string{"127.0.0.1:8080", "127.0.0.1:8081"},
+						},
+					},
+				},
+			},
+			wantOutbounds: map[string]wantOutbound{
+				"myservice": {
+					ChooserListType: (*roundrobin.List)(nil),
+				},
+			},
+		},
+		{
+			desc: "outbound address and peer list",
+			outboundCfg: attrs{
+				"myservice": attrs{
+					transportName: attrs{
+						"address": "127.0.0.1:80",
+						"round-robin": attrs{
+							"peers": []string{"127.0.0.1:8080"},
+						},
+					},
+				},
+			},
+			wantErrors: []string{"address cannot be used with a peer chooser"},
+		},
+		{
+			desc: "outbound compressor",
+			outboundCfg: attrs{
+				"myservice": attrs{
+					transportName: attrs{
+						"address":         "localhost:4040",
+						"compressor":      "snappy",
+						"minCompressSize": 512,
+					},
+				},
+			},
+			wantOutbounds: map[string]wantOutbound{
+				"myservice": {
+					Address:         "localhost:4040",
+					Compressor:      "snappy",
+					MinCompressSize: 512,
+				},
+			},
+		},
+		{
+			desc: "outbound compressor option",
+			outboundCfg: attrs{
+				"myservice": attrs{
+					transportName: attrs{"address": "localhost:4040"},
+				},
+			},
+			opts: []Option{OutboundCompressor(gzip.New())},
+			wantOutbounds: map[string]wantOutbound{
+				"myservice": {
+					Address:         "localhost:4040",
+					Compressor:      "gzip",
+					MinCompressSize: 1024,
+				},
+			},
+		},
+		{
+			desc: "outbound unknown compressor",
+			outboundCfg: attrs{
+				"myservice": attrs{
+					transportName: attrs{
+						"address":    "localhost:4040",
+						"compressor": "lz4",
+					},
+				},
+			},
+			wantErrors: []string{`no recognized compressor "lz4"`},
+		},
 	}
 
 	for _, tt := range tests {
@@ -132,8 +263,11 @@ func TestTransportSpec(t *testing.T) {
 			}
 
 			configurator := config.New(config.InterpolationResolver(mapResolver(env)))
+			configurator.MustRegisterCompressor(gzip.New())
+			configurator.MustRegisterCompressor(snappy.New())
 			err := configurator.RegisterTransport(TransportSpec(tt.opts...))
 			require.NoError(t, err)
+			require.NoError(t, configurator.RegisterPeerList(roundrobin.Spec()))
 
 			cfgData := make(attrs)
 			if tt.inboundCfg != nil {
@@ -156,7 +290,11 @@ func TestTransportSpec(t *testing.T) {
 				require.Len(t, cfg.Inbounds, 1)
 				inbound, ok := cfg.Inbounds[0].(*Inbound)
 				require.True(t, ok, "expected *Inbound, got %T", cfg.Inbounds[0])
-				assert.Contains(t, inbound.listener.Addr().String(), tt.wantInbound.Address)
+				assert.Contains(t, inbound.address, tt.wantInbound.Address)
+				assert.Nil(t, inbound.listener, "inbounds must not listen until they start")
+				if tt.wantInbound.Compressor != "" && assert.NotNil(t, inbound.inboundOptions.compressor) {
+					assert.Equal(t, tt.wantInbound.Compressor, inbound.inboundOptions.compressor.Name())
+				}
 			} else {
 				assert.Len(t, cfg.Inbounds, 0)
 			}
@@ -165,7 +303,19 @@ func TestTransportSpec(t *testing.T) {
 				require.True(t, ok, "no outbounds for %s", svc)
 				outbound, ok := ob.Unary.(*Outbound)
 				require.True(t, ok, "expected *Outbound, got %T", ob)
-				assert.Equal(t, wantOutbound.Address, outbound.address)
+				if wantOutbound.Compressor != "" && assert.NotNil(t, outbound.outboundOptions.compressor) {
+					assert.Equal(t, wantOutbound.Compressor, outbound.outboundOptions.compressor.Name())
+					assert.Equal(t, wantOutbound.MinCompressSize, outbound.outboundOptions.minCompressSize)
+				}
+				if wantOutbound.ChooserListType != nil {
+					chooser, ok := outbound.Chooser().(*peerchooser.BoundChooser)
+					require.True(t, ok, "expected *peer.BoundChooser, got %T", outbound.Chooser())
+					assert.IsType(t, wantOutbound.ChooserListType, chooser.ChooserList())
+					continue
+				}
+				single, ok := outbound.Chooser().(*peerchooser.Single)
+				require.True(t, ok, "expected *peer.Single, got %T", outbound.Chooser())
+				assert.Equal(t, wantOutbound.Address, single.Introspect().Peers[0].Identifier)
 			}
 		})
 	}
@@ -0,0 +1,77 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/errors"
+	"go.uber.org/yarpc/transport/x/grpc/grpcheader"
+	"go.uber.org/yarpc/yarpcerrors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// The numeric values of yarpcerrors codes match gRPC status codes so codes
+// are converted directly.
+
+// handlerErrorToStatus converts an error returned while handling a request
+// into a yarpcerrors.Status. Statuses returned by handlers are used as-is.
+func handlerErrorToStatus(service, procedure string, err error) *yarpcerrors.Status {
+	if !yarpcerrors.IsStatus(err) {
+		err = errors.AsHandlerError(service, procedure, err)
+	}
+	return yarpcerrors.FromError(err)
+}
+
+// statusToGRPCError converts the given Status into the trailer carrying its
+// details, if any, and an error with the matching gRPC status code and
+// message.
+func statusToGRPCError(status *yarpcerrors.Status) (metadata.MD, error) {
+	var trailer metadata.MD
+	if details := status.Details(); len(details) > 0 {
+		trailer = metadata.Pairs(grpcheader.ErrorDetailsHeader, string(details))
+	}
+	return trailer, grpc.Errorf(codes.Code(status.Code()), "%s", status.Message())
+}
+
+// fromGRPCError converts an error returned by a gRPC call into a
+// yarpcerrors.Status, reading details from the given trailer.
+//
+// If the context deadline was exceeded, a client timeout error is returned
+// instead.
+func fromGRPCError(ctx context.Context, request *transport.Request, start time.Time, err error, trailer metadata.MD) error {
+	code := grpc.Code(err)
+	if code == codes.DeadlineExceeded && ctx.Err() == context.DeadlineExceeded {
+		deadline, _ := ctx.Deadline()
+		return errors.ClientTimeoutError(request.Service, request.Procedure, deadline.Sub(start))
+	}
+
+	status := yarpcerrors.Newf(yarpcerrors.Code(code), "%s", grpc.ErrorDesc(err))
+	if details := trailer[grpcheader.ErrorDetailsHeader]; len(details) > 0 {
+		status = status.WithDetails([]byte(details[0]))
+	}
+	return status
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/yarpcerrors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type streamHandlerFunc func(transport.Stream) error
+
+func (f streamHandlerFunc) HandleStream(stream transport.Stream) error {
+	return f(stream)
+}
+
+func TestStatusRoundTrip(t *testing.T) {
+	tests := []struct {
+		desc        string
+		give        error
+		wantCode    yarpcerrors.Code
+		wantMessage string
+		wantDetails []byte
+	}{
+		{
+			desc:        "status",
+			give:        yarpcerrors.Newf(yarpcerrors.CodeNotFound, "no such key"),
+			wantCode:    yarpcerrors.CodeNotFound,
+			wantMessage: "no such key",
+		},
+		{
+			desc:        "status with details",
+			give:        yarpcerrors.Newf(yarpcerrors.CodeResourceExhausted, "slow down").WithDetails([]byte{0x00, 0xff}),
+			wantCode:    yarpcerrors.CodeResourceExhausted,
+			wantMessage: "slow down",
+			wantDetails: []byte{0x00, 0xff},
+		},
+		{
+			desc:        "unknown error",
+			give:        fmt.Errorf("great sadness"),
+			wantCode:    yarpcerrors.CodeInternal,
+			wantMessage: "great sadness",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			tr := NewTransport()
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+
+			inbound := tr.NewInbound(listener)
+			inbound.SetRouter(newTestRouter([]transport.Procedure{
+				{
+					Name:    "Test::Fail",
+					Service: "example",
+					HandlerSpec: transport.NewUnaryHandlerSpec(unaryHandlerFunc(
+						func(context.Context, *transport.Request, transport.ResponseWriter) error {
+							return tt.give
+						},
+					)),
+				},
+				{
+					Name:    "Test::FailStream",
+					Service: "example",
+					HandlerSpec: transport.NewStreamHandlerSpec(streamHandlerFunc(
+						func(transport.Stream) error {
+							return tt.give
+						},
+					)),
+				},
+			}))
+			require.NoError(t, inbound.Start())
+			defer func() { assert.NoError(t, inbound.Stop()) }()
+
+			outbound := tr.NewSingleOutbound(listener.Addr().String())
+			require.NoError(t, outbound.Start())
+			defer func() { assert.NoError(t, outbound.Stop()) }()
+
+			check := func(err error) {
+				require.Error(t, err)
+				status := yarpcerrors.FromError(err)
+				assert.Equal(t, tt.wantCode, status.Code())
+				assert.Contains(t, status.Message(), tt.wantMessage)
+				assert.Equal(t, tt.wantDetails, status.Details())
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			_, err = outbound.Call(ctx, &transport.Request{
+				Caller:    "caller",
+				Service:   "example",
+				Encoding:  transport.Encoding("raw"),
+				Procedure: "Test::Fail",
+				Body:      bytes.NewReader(nil),
+			})
+			check(err)
+
+			stream, err := outbound.CallStream(ctx, &transport.Request{
+				Caller:    "caller",
+				Service:   "example",
+				Encoding:  transport.Encoding("raw"),
+				Procedure: "Test::FailStream",
+			})
+			require.NoError(t, err)
+			_, err = stream.ReceiveMessage()
+			check(err)
+		})
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	tr := NewTransport()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	inbound := tr.NewInbound(listener)
+	inbound.SetRouter(newTestRouter([]transport.Procedure{
+		{
+			Name:    "Test::Sleep",
+			Service: "example",
+			HandlerSpec: transport.NewUnaryHandlerSpec(unaryHandlerFunc(
+				func(ctx context.Context, _ *transport.Request, _ transport.ResponseWriter) error {
+					<-ctx.Done()
+					return ctx.Err()
+				},
+			)),
+		},
+	}))
+	require.NoError(t, inbound.Start())
+	defer func() { assert.NoError(t, inbound.Stop()) }()
+
+	outbound := tr.NewSingleOutbound(listener.Addr().String())
+	require.NoError(t, outbound.Start())
+	defer func() { assert.NoError(t, outbound.Stop()) }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = outbound.Call(ctx, &transport.Request{
+		Caller:    "caller",
+		Service:   "example",
+		Encoding:  transport.Encoding("raw"),
+		Procedure: "Test::Sleep",
+		Body:      bytes.NewReader(nil),
+	})
+	assert.True(t, transport.IsTimeoutError(err), "expected a timeout error, got %v", err)
+	assert.Equal(t, yarpcerrors.CodeDeadlineExceeded, yarpcerrors.FromError(err).Code())
+}
@@ -59,6 +59,11 @@ const (
 	// as the proto encoding.
 	// This header is required unless content-type is set properly.
 	EncodingHeader = "rpc-encoding"
+	// ErrorDetailsHeader is the trailer key for the details of a
+	// yarpcerrors.Status returned by a handler. The code and message of the
+	// Status are sent as the gRPC status code and message.
+	// This trailer is optional.
+	ErrorDetailsHeader = "rpc-error-details-bin"
 )
 
 var (
@@ -69,6 +74,7 @@ var (
 		RoutingKeyHeader:      true,
 		RoutingDelegateHeader: true,
 		EncodingHeader:        true,
+		ErrorDetailsHeader:    true,
 	}
 )
 
@@ -30,6 +30,7 @@ import (
 	"go.uber.org/yarpc/encoding/x/protobuf"
 	"go.uber.org/yarpc/internal/errors"
 	"go.uber.org/yarpc/internal/request"
+	"go.uber.org/yarpc/yarpcerrors"
 
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
@@ -65,10 +66,13 @@ func (h *handler) handle(
 ) (interface{}, error) {
 	transportRequest, err := h.getTransportRequest(ctx, decodeFunc)
 	if err != nil {
-		return nil, err
+		return nil, h.toGRPCError(transportRequest, err, func(md metadata.MD) {
+			_ = grpc.SetTrailer(ctx, md)
+		})
 	}
+	var response interface{}
 	if interceptor != nil {
-		return interceptor(
+		response, err = interceptor(
 			ctx,
 			transportRequest,
 			&grpc.UnaryServerInfo{
@@ -83,16 +87,19 @@ func (h *handler) handle(
 				return h.call(ctx, transportRequest)
 			},
 		)
+	} else {
+		response, err = h.call(ctx, transportRequest)
+	}
+	if err != nil {
+		return response, h.toGRPCError(transportRequest, err, func(md metadata.MD) {
+			_ = grpc.SetTrailer(ctx, md)
+		})
 	}
-	return h.call(ctx, transportRequest)
+	return response, nil
 }
 
 func (h *handler) getTransportRequest(ctx context.Context, decodeFunc func(interface{}) error) (*transport.Request, error) {
-	md, ok := metadata.FromContext(ctx)
-	if md == nil || !ok {
-		return nil, fmt.Errorf("cannot get metadata from ctx: %v", ctx)
-	}
-	transportRequest, err := metadataToTransportRequest(md)
+	transportRequest, err := h.getTransportRequestMetadata(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -106,6 +113,20 @@ func (h *handler) getTransportRequest(ctx context.Context, decodeFunc func(inter
 		return nil, err
 	}
 	transportRequest.Body = bytes.NewBuffer(data)
+	return transportRequest, nil
+}
+
+// getTransportRequestMetadata builds a validated transport.Request without a
+// body from the metadata in ctx.
+func (h *handler) getTransportRequestMetadata(ctx context.Context) (*transport.Request, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if md == nil || !ok {
+		return nil, fmt.Errorf("cannot get metadata from ctx: %v", ctx)
+	}
+	transportRequest, err := metadataToTransportRequest(md)
+	if err != nil {
+		return nil, err
+	}
 	procedure, err := procedureToName(h.grpcServiceName, h.grpcMethodName)
 	if err != nil {
 		return nil, err
@@ -138,7 +159,53 @@ func (h *handler) callUnary(ctx context.Context, transportRequest *transport.Req
 	// TODO: do we always want to return the data from responseWriter.Bytes, or return nil for the data if there is an error?
 	// For now, we are always returning the data
 	err := transport.DispatchUnaryHandler(ctx, unaryHandler, time.Now(), transportRequest, responseWriter)
+	if err != nil && responseWriter.isApplicationError {
+		// Application errors are sent to the caller as they are rather than
+		// as unexpected handler errors.
+		err = yarpcerrors.FromError(err)
+	}
 	err = multierr.Append(err, grpc.SendHeader(ctx, responseWriter.md))
 	data := responseWriter.Bytes()
 	return data, err
 }
+
+func (h *handler) handleStream(server interface{}, serverStream grpc.ServerStream) error {
+	transportRequest, err := h.getTransportRequestMetadata(serverStream.Context())
+	if err == nil {
+		err = h.callStream(transportRequest, serverStream)
+	}
+	if err != nil {
+		return h.toGRPCError(transportRequest, err, serverStream.SetTrailer)
+	}
+	return nil
+}
+
+func (h *handler) callStream(transportRequest *transport.Request, serverStream grpc.ServerStream) error {
+	ctx := serverStream.Context()
+	handlerSpec, err := h.router.Choose(ctx, transportRequest)
+	if err != nil {
+		return err
+	}
+	if handlerSpec.Type() != transport.Streaming {
+		return errors.UnsupportedTypeError{"grpc", handlerSpec.Type().String()}
+	}
+	return transport.DispatchStreamHandler(
+		handlerSpec.Stream(),
+		newServerStream(ctx, transportRequest, serverStream),
+	)
+}
+
+// toGRPCError converts an error returned while handling a request into a
+// gRPC error that carries the code and message of its yarpcerrors.Status.
+// Details of the Status, if any, are sent using setTrailer.
+func (h *handler) toGRPCError(transportRequest *transport.Request, err error, setTrailer func(metadata.MD)) error {
+	service, procedure := h.grpcServiceName, h.grpcMethodName
+	if transportRequest != nil {
+		service, procedure = transportRequest.Service, transportRequest.Procedure
+	}
+	trailer, err := statusToGRPCError(handlerErrorToStatus(service, procedure, err))
+	if trailer != nil {
+		setTrailer(trailer)
+	}
+	return err
+}
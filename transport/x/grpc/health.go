@@ -0,0 +1,114 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/health"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// healthServiceName is the name of the standard gRPC health checking
+// service.
+//
+// See https://github.com/grpc/grpc/blob/master/doc/health-checking.md.
+const healthServiceName = "grpc.health.v1.Health"
+
+// Serving statuses of the grpc.health.v1.HealthCheckResponse message.
+const (
+	healthStatusServing    int32 = 1
+	healthStatusNotServing int32 = 2
+)
+
+// healthCheckRequest is the grpc.health.v1.HealthCheckRequest message.
+type healthCheckRequest struct {
+	Service string `protobuf:"bytes,1,opt,name=service,proto3"`
+}
+
+func (m *healthCheckRequest) Reset()         { *m = healthCheckRequest{} }
+func (m *healthCheckRequest) String() string { return proto.CompactTextString(m) }
+func (*healthCheckRequest) ProtoMessage()    {}
+
+// healthCheckResponse is the grpc.health.v1.HealthCheckResponse message.
+type healthCheckResponse struct {
+	Status int32 `protobuf:"varint,1,opt,name=status,proto3"`
+}
+
+func (m *healthCheckResponse) Reset()         { *m = healthCheckResponse{} }
+func (m *healthCheckResponse) String() string { return proto.CompactTextString(m) }
+func (*healthCheckResponse) ProtoMessage()    {}
+
+// newHealthServiceDesc builds the description of a grpc.health.v1.Health
+// service which answers with the status reported by the health procedure
+// registered with the given router.
+//
+// Unlike other services, this service does not require YARPC metadata so
+// that standard gRPC health probes may query it.
+func newHealthServiceDesc(router transport.Router) *grpc.ServiceDesc {
+	return &grpc.ServiceDesc{
+		ServiceName: healthServiceName,
+		HandlerType: (*noopGrpcInterface)(nil),
+		Methods: []grpc.MethodDesc{
+			{
+				MethodName: "Check",
+				Handler: func(
+					server interface{},
+					ctx context.Context,
+					decodeFunc func(interface{}) error,
+					interceptor grpc.UnaryServerInterceptor,
+				) (interface{}, error) {
+					return handleHealthCheck(ctx, router, decodeFunc)
+				},
+			},
+		},
+	}
+}
+
+func handleHealthCheck(ctx context.Context, router transport.Router, decodeFunc func(interface{}) error) (interface{}, error) {
+	var data []byte
+	if err := decodeFunc(&data); err != nil {
+		return nil, err
+	}
+	var req healthCheckRequest
+	if err := proto.Unmarshal(data, &req); err != nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, "failed to decode health check request: %v", err)
+	}
+
+	status, _, err := health.Check(ctx, router, req.Service)
+	if err != nil {
+		return nil, grpc.Errorf(codes.Internal, "failed to check health: %v", err)
+	}
+
+	var res healthCheckResponse
+	switch status {
+	case health.Serving:
+		res.Status = healthStatusServing
+	case health.NotServing:
+		res.Status = healthStatusNotServing
+	default:
+		return nil, grpc.Errorf(codes.NotFound, "unknown service %q", req.Service)
+	}
+	return proto.Marshal(&res)
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/encoding/json"
+	"go.uber.org/yarpc/internal/health"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func TestHandleHealthCheck(t *testing.T) {
+	router := yarpc.NewMapRouter("myservice")
+	router.Register(json.Procedure(health.Procedure, func(ctx context.Context, req *health.Request) (*health.Response, error) {
+		switch req.Service {
+		case "", "myservice":
+			return &health.Response{Status: health.Serving}, nil
+		case "sad":
+			return &health.Response{Status: health.NotServing}, nil
+		default:
+			return &health.Response{Status: health.ServiceUnknown}, nil
+		}
+	}))
+
+	tests := []struct {
+		service    string
+		wantStatus int32
+		wantCode   codes.Code
+	}{
+		{service: "", wantStatus: healthStatusServing},
+		{service: "myservice", wantStatus: healthStatusServing},
+		{service: "sad", wantStatus: healthStatusNotServing},
+		{service: "unknown", wantCode: codes.NotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.service, func(t *testing.T) {
+			body, err := proto.Marshal(&healthCheckRequest{Service: tt.service})
+			require.NoError(t, err)
+
+			desc := newHealthServiceDesc(router)
+			require.Len(t, desc.Methods, 1)
+			res, err := desc.Methods[0].Handler(nil, context.Background(), func(v interface{}) error {
+				*v.(*[]byte) = body
+				return nil
+			}, nil)
+
+			if tt.wantCode != codes.OK {
+				assert.Equal(t, tt.wantCode, grpc.Code(err))
+				return
+			}
+			require.NoError(t, err)
+
+			var got healthCheckResponse
+			require.NoError(t, proto.Unmarshal(res.([]byte), &got))
+			assert.Equal(t, tt.wantStatus, got.Status)
+		})
+	}
+}
@@ -26,6 +26,8 @@ import (
 	"sync"
 
 	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/health"
+	intnet "go.uber.org/yarpc/internal/net"
 	internalsync "go.uber.org/yarpc/internal/sync"
 
 	"google.golang.org/grpc"
@@ -35,7 +37,8 @@ var (
 	errRouterNotSet          = errors.New("router not set")
 	errRouterHasNoProcedures = errors.New("router has no procedures")
 
-	_ transport.Inbound = (*Inbound)(nil)
+	_ transport.Inbound          = (*Inbound)(nil)
+	_ transport.DrainableInbound = (*Inbound)(nil)
 )
 
 // Inbound is a grpc transport.Inbound.
@@ -43,14 +46,36 @@ type Inbound struct {
 	once           internalsync.LifecycleOnce
 	lock           sync.Mutex
 	t              *Transport
+	address        string
 	listener       net.Listener
 	inboundOptions *inboundOptions
 	router         transport.Router
 	server         *grpc.Server
+
+	// Closed when the server has finished draining. This is nil if the
+	// inbound has not been drained.
+	drained chan struct{}
 }
 
 func newInbound(t *Transport, listener net.Listener, options ...InboundOption) *Inbound {
-	return &Inbound{internalsync.Once(), sync.Mutex{}, t, listener, newInboundOptions(options), nil, nil}
+	inboundOptions := newInboundOptions(options)
+	if c := inboundOptions.compressor; c != nil {
+		registerCompressor(c)
+	}
+	return &Inbound{
+		once:           internalsync.Once(),
+		t:              t,
+		listener:       listener,
+		inboundOptions: inboundOptions,
+	}
+}
+
+// newAddressInbound returns an Inbound which listens on the given address
+// when it starts, rather than on an existing listener.
+func newAddressInbound(t *Transport, address string, options ...InboundOption) *Inbound {
+	i := newInbound(t, nil, options...)
+	i.address = address
+	return i
 }
 
 // Start implements transport.Lifecycle#Start.
@@ -68,6 +93,26 @@ func (i *Inbound) IsRunning() bool {
 	return i.once.IsRunning()
 }
 
+// Drain implements transport.DrainableInbound#Drain.
+//
+// The server stops accepting new connections and tells clients on existing
+// connections to stop sending new requests, while the requests in flight
+// are allowed to finish.
+func (i *Inbound) Drain() {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if i.server == nil || i.drained != nil {
+		return
+	}
+
+	drained := make(chan struct{})
+	go func(server *grpc.Server) {
+		server.GracefulStop()
+		close(drained)
+	}(i.server)
+	i.drained = drained
+}
+
 // SetRouter implements transport.Inbound#SetRouter.
 func (i *Inbound) SetRouter(router transport.Router) {
 	i.lock.Lock()
@@ -90,6 +135,16 @@ func (i *Inbound) start() error {
 	if err != nil {
 		return err
 	}
+	if i.listener == nil {
+		// Stale unix domain sockets are removed here rather than when the
+		// inbound is built so that building a configuration has no side
+		// effects.
+		listener, err := intnet.Listen(i.address)
+		if err != nil {
+			return err
+		}
+		i.listener = listener
+	}
 	server := grpc.NewServer(
 		grpc.CustomCodec(customCodec{}),
 		// TODO: does this actually work for yarpc
@@ -102,7 +157,7 @@ func (i *Inbound) start() error {
 	for _, serviceDesc := range serviceDescs {
 		server.RegisterService(serviceDesc, noopGrpcStruct{})
 	}
-	go func() {
+	go func(listener net.Listener) {
 		// TODO there should be some mechanism to block here
 		// there is a race because the listener gets set in the grpc
 		// Server implementation and we should be able to block
@@ -113,8 +168,8 @@ func (i *Inbound) start() error {
 		//
 		// TODO Server always returns a non-nil error but should
 		// we do something with some or all errors?
-		_ = server.Serve(i.listener)
-	}()
+		_ = server.Serve(listener)
+	}(i.listener)
 	i.server = server
 	return nil
 }
@@ -122,9 +177,17 @@ func (i *Inbound) start() error {
 func (i *Inbound) stop() error {
 	i.lock.Lock()
 	defer i.lock.Unlock()
-	if i.server != nil {
+	if i.server == nil {
+		return nil
+	}
+	if i.drained == nil {
 		i.server.GracefulStop()
+		return nil
 	}
+	// The inbound was drained. Cut off the requests that are still in
+	// flight.
+	i.server.Stop()
+	<-i.drained
 	return nil
 }
 
@@ -136,40 +199,53 @@ func (i *Inbound) getServiceDescs() ([]*grpc.ServiceDesc, error) {
 		return nil, errRouterHasNoProcedures
 	}
 
-	serviceNameToMethodNames := make(map[string]map[string]bool)
+	serviceNameToMethodNameToType := make(map[string]map[string]transport.Type)
 	for _, procedure := range procedures {
 		serviceName, methodName, err := procedureNameToServiceNameMethodName(procedure.Name)
 		if err != nil {
 			return nil, err
 		}
-		methodNames, ok := serviceNameToMethodNames[serviceName]
+		methodNameToType, ok := serviceNameToMethodNameToType[serviceName]
 		if !ok {
-			methodNames = make(map[string]bool)
-			serviceNameToMethodNames[serviceName] = methodNames
+			methodNameToType = make(map[string]transport.Type)
+			serviceNameToMethodNameToType[serviceName] = methodNameToType
 		}
-		methodNames[methodName] = true
+		methodNameToType[methodName] = procedure.HandlerSpec.Type()
 	}
 
-	serviceDescs := make([]*grpc.ServiceDesc, 0, len(serviceNameToMethodNames))
-	for serviceName, methodNames := range serviceNameToMethodNames {
+	serviceDescs := make([]*grpc.ServiceDesc, 0, len(serviceNameToMethodNameToType))
+	for serviceName, methodNameToType := range serviceNameToMethodNameToType {
 		serviceDesc := &grpc.ServiceDesc{
 			ServiceName: serviceName,
 			HandlerType: (*noopGrpcInterface)(nil),
-			Methods:     make([]grpc.MethodDesc, 0, len(methodNames)),
+			Methods:     make([]grpc.MethodDesc, 0, len(methodNameToType)),
 		}
-		for methodName := range methodNames {
+		for methodName, rpcType := range methodNameToType {
+			handler := newHandler(serviceName, methodName, i.router)
+			if rpcType == transport.Streaming {
+				serviceDesc.Streams = append(serviceDesc.Streams, grpc.StreamDesc{
+					StreamName:    methodName,
+					Handler:       handler.handleStream,
+					ServerStreams: true,
+					ClientStreams: true,
+				})
+				continue
+			}
 			serviceDesc.Methods = append(serviceDesc.Methods, grpc.MethodDesc{
 				MethodName: methodName,
-				Handler: newHandler(
-					serviceName,
-					methodName,
-					i.router,
-				).handle,
+				Handler:    handler.handle,
 			})
 		}
 		serviceDescs = append(serviceDescs, serviceDesc)
 	}
 
+	// Expose the health service registered with x/yarpchealth over the
+	// standard gRPC health checking protocol unless a service of the same
+	// name was registered explicitly.
+	if _, ok := serviceNameToMethodNameToType[healthServiceName]; !ok && health.Registered(i.router) {
+		serviceDescs = append(serviceDescs, newHealthServiceDesc(i.router))
+	}
+
 	return serviceDescs, nil
 }
 
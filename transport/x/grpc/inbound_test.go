@@ -115,6 +115,35 @@ func TestGetServiceDescs(t *testing.T) {
 				},
 			},
 		},
+		{
+			Name: "Streaming Method",
+			Procedures: []transport.Procedure{
+				{
+					Name:    "KeyValue::GetValue",
+					Service: "Example",
+				},
+				{
+					Name:        "KeyValue::WatchValue",
+					Service:     "Example",
+					HandlerSpec: transport.NewStreamHandlerSpec(nil),
+				},
+			},
+			ExpectedServiceDescs: []*grpc.ServiceDesc{
+				{
+					ServiceName: "KeyValue",
+					Methods: []grpc.MethodDesc{
+						{
+							MethodName: "GetValue",
+						},
+					},
+					Streams: []grpc.StreamDesc{
+						{
+							StreamName: "WatchValue",
+						},
+					},
+				},
+			},
+		},
 		{
 			Name: "Default Service Name",
 			Procedures: []transport.Procedure{
@@ -134,6 +163,66 @@ func TestGetServiceDescs(t *testing.T) {
 				},
 			},
 		},
+		{
+			Name: "Health Service",
+			Procedures: []transport.Procedure{
+				{
+					Name:     "yarpc::health",
+					Service:  "Example",
+					Encoding: "json",
+				},
+			},
+			ExpectedServiceDescs: []*grpc.ServiceDesc{
+				{
+					ServiceName: "yarpc",
+					Methods: []grpc.MethodDesc{
+						{
+							MethodName: "health",
+						},
+					},
+				},
+				{
+					ServiceName: "grpc.health.v1.Health",
+					Methods: []grpc.MethodDesc{
+						{
+							MethodName: "Check",
+						},
+					},
+				},
+			},
+		},
+		{
+			Name: "Explicit Health Service",
+			Procedures: []transport.Procedure{
+				{
+					Name:     "yarpc::health",
+					Service:  "Example",
+					Encoding: "json",
+				},
+				{
+					Name:    "grpc.health.v1.Health::Watch",
+					Service: "Example",
+				},
+			},
+			ExpectedServiceDescs: []*grpc.ServiceDesc{
+				{
+					ServiceName: "yarpc",
+					Methods: []grpc.MethodDesc{
+						{
+							MethodName: "health",
+						},
+					},
+				},
+				{
+					ServiceName: "grpc.health.v1.Health",
+					Methods: []grpc.MethodDesc{
+						{
+							MethodName: "Watch",
+						},
+					},
+				},
+			},
+		},
 	} {
 		t.Run(tt.Name, func(t *testing.T) {
 			inbound := newInbound(nil, nil)
@@ -201,4 +290,19 @@ func testServiceDescMethodNamesCovered(t *testing.T, serviceDesc *grpc.ServiceDe
 		n[methodDesc.MethodName] = true
 	}
 	require.Equal(t, m, n)
+
+	streamDescs := serviceDesc.Streams
+	expectedStreamDescs := expectedServiceDesc.Streams
+	require.Equal(t, len(expectedStreamDescs), len(streamDescs))
+	m = make(map[string]bool)
+	n = make(map[string]bool)
+	for _, streamDesc := range expectedStreamDescs {
+		m[streamDesc.StreamName] = true
+	}
+	for _, streamDesc := range streamDescs {
+		n[streamDesc.StreamName] = true
+		require.True(t, streamDesc.ServerStreams)
+		require.True(t, streamDesc.ClientStreams)
+	}
+	require.Equal(t, m, n)
 }
@@ -23,12 +23,17 @@ package grpc
 import (
 	"context"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/compressor/gzip"
 	"go.uber.org/yarpc/encoding/x/protobuf"
 	"go.uber.org/yarpc/internal/clientconfig"
 	"go.uber.org/yarpc/internal/examples/protobuf/example"
@@ -37,6 +42,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
 	"go.uber.org/multierr"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
@@ -76,6 +82,137 @@ func TestYarpcMetadata(t *testing.T) {
 	})
 }
 
+func TestUnixSocket(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "yarpc-grpc")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "grpc.sock")
+	addr := "unix://" + socket
+
+	trans := NewTransport()
+	inbound := newAddressInbound(trans, addr)
+	inbound.SetRouter(newTestRouter(examplepb.BuildKeyValueYarpcProcedures(example.NewKeyValueYarpcServer())))
+	_, err = os.Stat(socket)
+	assert.True(t, os.IsNotExist(err), "inbounds must not listen until they start")
+	require.NoError(t, inbound.Start())
+	defer inbound.Stop()
+
+	outbound := trans.NewSingleOutbound(addr)
+	require.NoError(t, outbound.Start())
+	defer outbound.Stop()
+
+	client := examplepb.NewKeyValueYarpcClient(clientconfig.MultiOutbound(
+		"example-client",
+		"example",
+		transport.Outbounds{
+			ServiceName: "example-client",
+			Unary:       outbound,
+		},
+	))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err = client.SetValue(ctx, &examplepb.SetValueRequest{Key: "foo", Value: "bar"})
+	require.NoError(t, err)
+	response, err := client.GetValue(ctx, &examplepb.GetValueRequest{Key: "foo"})
+	require.NoError(t, err)
+	assert.Equal(t, "bar", response.Value)
+}
+
+func TestCompression(t *testing.T) {
+	t.Parallel()
+	value := strings.Repeat("bar", 1000)
+
+	t.Run("compressed", func(t *testing.T) {
+		compressor := newCountingCompressor()
+		doWithTestEnv(t,
+			[]InboundOption{InboundCompressor(compressor)},
+			[]OutboundOption{OutboundCompressor(compressor)},
+			func(t *testing.T, e *testEnv) {
+				assert.NoError(t, e.SetValueYarpc(context.Background(), "foo", value))
+				assert.Equal(t, int32(2), compressor.compressed.Load(), "large requests and their responses must be compressed")
+				assert.Equal(t, int32(2), compressor.decompressed.Load(), "large requests and their responses must be decompressed")
+
+				got, err := e.GetValueYarpc(context.Background(), "foo")
+				assert.NoError(t, err)
+				assert.Equal(t, value, got)
+				assert.Equal(t, int32(2), compressor.compressed.Load(), "small requests and their responses must not be compressed")
+				assert.Equal(t, int32(2), compressor.decompressed.Load(), "small requests and their responses must not be decompressed")
+			})
+	})
+
+	t.Run("gRPC client", func(t *testing.T) {
+		compressor := newCountingCompressor()
+		doWithTestEnv(t,
+			[]InboundOption{InboundCompressor(compressor)},
+			nil,
+			func(t *testing.T, e *testEnv) {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+				defer cancel()
+				_, err := e.KeyValueGRPCClient.SetValue(
+					e.ContextWrapper.Wrap(ctx),
+					&examplepb.SetValueRequest{Key: "foo", Value: value},
+					grpc.UseCompressor(compressor.Name()),
+				)
+				assert.NoError(t, err)
+				assert.Equal(t, int32(2), compressor.compressed.Load(), "requests of gRPC clients and their responses must be compressed")
+				assert.Equal(t, int32(2), compressor.decompressed.Load(), "requests of gRPC clients and their responses must be decompressed")
+			})
+	})
+
+	t.Run("not compressed", func(t *testing.T) {
+		compressor := newCountingCompressor()
+		doWithTestEnv(t,
+			[]InboundOption{InboundCompressor(compressor)},
+			nil,
+			func(t *testing.T, e *testEnv) {
+				assert.NoError(t, e.SetValueYarpc(context.Background(), "foo", value))
+				got, err := e.GetValueYarpc(context.Background(), "foo")
+				assert.NoError(t, err)
+				assert.Equal(t, value, got)
+				assert.Equal(t, int32(0), compressor.compressed.Load(), "responses to uncompressed requests must not be compressed")
+			})
+	})
+}
+
+// countingCompressors is the number of countingCompressors created so far.
+var countingCompressors atomic.Int32
+
+// countingCompressor is a gzip transport.Compressor that counts how many
+// messages it compressed and decompressed.
+//
+// Compressors are registered with gRPC by name, so every countingCompressor
+// has a name of its own.
+type countingCompressor struct {
+	transport.Compressor
+
+	name         string
+	compressed   atomic.Int32
+	decompressed atomic.Int32
+}
+
+func newCountingCompressor() *countingCompressor {
+	return &countingCompressor{
+		Compressor: gzip.New(),
+		name:       fmt.Sprintf("counting-gzip-%d", countingCompressors.Inc()),
+	}
+}
+
+func (c *countingCompressor) Name() string {
+	return c.name
+}
+
+func (c *countingCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	c.compressed.Inc()
+	return c.Compressor.Compress(w)
+}
+
+func (c *countingCompressor) Decompress(r io.Reader) (io.ReadCloser, error) {
+	c.decompressed.Inc()
+	return c.Compressor.Decompress(r)
+}
+
 func doWithTestEnv(t *testing.T, inboundOptions []InboundOption, outboundOptions []OutboundOption, f func(*testing.T, *testEnv)) {
 	testEnv, err := newTestEnv(inboundOptions, outboundOptions)
 	require.NoError(t, err)
@@ -21,6 +21,9 @@
 package grpc
 
 import (
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/compress"
+
 	"github.com/opentracing/opentracing-go"
 	"google.golang.org/grpc"
 )
@@ -64,6 +67,48 @@ func WithOutboundTracer(tracer opentracing.Tracer) OutboundOption {
 	}
 }
 
+// InboundCompressor specifies that the inbound accepts requests whose
+// messages were compressed with the given compressor, as reported by their
+// grpc-encoding header, and compresses the messages of the responses to
+// these requests with it.
+//
+// Unlike HTTP and TChannel inbounds, gRPC inbounds have no minimum
+// compression size: gRPC compresses every message of a response to a
+// compressed request, and does not let the inbound pick another encoding
+// for small responses.
+//
+// The compressor is registered with gRPC under its name, so the inbound
+// also accepts requests compressed with any other compressor registered
+// with gRPC, for example by an outbound using the OutboundCompressor option.
+func InboundCompressor(c transport.Compressor) InboundOption {
+	return func(inboundOptions *inboundOptions) {
+		inboundOptions.compressor = c
+	}
+}
+
+// OutboundCompressor specifies that the outbound compresses the messages of
+// requests with the given compressor, as reported by their grpc-encoding
+// header.
+//
+// Inbounds that receive these requests MUST support the same compressor, as
+// gRPC inbounds do with the InboundCompressor option. These inbounds
+// compress their responses the same way.
+func OutboundCompressor(c transport.Compressor) OutboundOption {
+	return func(outboundOptions *outboundOptions) {
+		outboundOptions.compressor = c
+	}
+}
+
+// OutboundMinCompressSize specifies the size, in bytes, under which the
+// bodies of unary requests are sent uncompressed when the OutboundCompressor
+// option is used. Defaults to 1024 bytes. Messages of streams are always
+// compressed.
+func OutboundMinCompressSize(size int) OutboundOption {
+	return func(outboundOptions *outboundOptions) {
+		outboundOptions.minCompressSize = size
+	}
+}
+
 type transportOptions struct{}
 
 func newTransportOptions(options []TransportOption) *transportOptions {
@@ -77,6 +122,7 @@ func newTransportOptions(options []TransportOption) *transportOptions {
 type inboundOptions struct {
 	tracer           opentracing.Tracer
 	unaryInterceptor grpc.UnaryServerInterceptor
+	compressor       transport.Compressor
 }
 
 func newInboundOptions(options []InboundOption) *inboundOptions {
@@ -102,11 +148,13 @@ func (i *inboundOptions) getUnaryInterceptor() grpc.UnaryServerInterceptor {
 }
 
 type outboundOptions struct {
-	tracer opentracing.Tracer
+	tracer          opentracing.Tracer
+	compressor      transport.Compressor
+	minCompressSize int
 }
 
 func newOutboundOptions(options []OutboundOption) *outboundOptions {
-	outboundOptions := &outboundOptions{}
+	outboundOptions := &outboundOptions{minCompressSize: compress.DefaultMinSize}
 	for _, option := range options {
 		option(outboundOptions)
 	}
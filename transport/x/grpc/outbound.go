@@ -24,16 +24,17 @@ import (
 	"bytes"
 	"context"
 	"io/ioutil"
-	"sync"
 	"time"
 
 	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/peer"
 	"go.uber.org/yarpc/api/transport"
-	"go.uber.org/yarpc/internal/errors"
+	"go.uber.org/yarpc/internal/introspection"
 	internalsync "go.uber.org/yarpc/internal/sync"
+	peerchooser "go.uber.org/yarpc/peer"
+	"go.uber.org/yarpc/peer/hostport"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 )
 
@@ -41,30 +42,47 @@ import (
 // http://www.grpc.io/docs/guides/wire.html#user-agents
 const UserAgent = "yarpc-go/" + yarpc.Version
 
-var _ transport.UnaryOutbound = (*Outbound)(nil)
+var (
+	_ transport.UnaryOutbound              = (*Outbound)(nil)
+	_ transport.StreamOutbound             = (*Outbound)(nil)
+	_ introspection.IntrospectableOutbound = (*Outbound)(nil)
+)
 
-// Outbound is a transport.UnaryOutbound.
+// Outbound is a transport.UnaryOutbound and transport.StreamOutbound.
+//
+// Requests are sent to peers supplied by the peer.Chooser of the Outbound.
 type Outbound struct {
 	once            internalsync.LifecycleOnce
-	lock            sync.Mutex
 	t               *Transport
-	address         string
+	chooser         peer.Chooser
 	outboundOptions *outboundOptions
-	clientConn      *grpc.ClientConn
 }
 
 func newSingleOutbound(t *Transport, address string, options ...OutboundOption) *Outbound {
-	return &Outbound{internalsync.Once(), sync.Mutex{}, t, address, newOutboundOptions(options), nil}
+	return newOutbound(t, peerchooser.NewSingle(hostport.PeerIdentifier(address), t), options...)
+}
+
+func newOutbound(t *Transport, chooser peer.Chooser, options ...OutboundOption) *Outbound {
+	outboundOptions := newOutboundOptions(options)
+	if c := outboundOptions.compressor; c != nil {
+		registerCompressor(c)
+	}
+	return &Outbound{
+		once:            internalsync.Once(),
+		t:               t,
+		chooser:         chooser,
+		outboundOptions: outboundOptions,
+	}
 }
 
 // Start implements transport.Lifecycle#Start.
 func (o *Outbound) Start() error {
-	return o.once.Start(o.start)
+	return o.once.Start(o.chooser.Start)
 }
 
 // Stop implements transport.Lifecycle#Stop.
 func (o *Outbound) Stop() error {
-	return o.once.Stop(o.stop)
+	return o.once.Stop(o.chooser.Stop)
 }
 
 // IsRunning implements transport.Lifecycle#IsRunning.
@@ -77,6 +95,11 @@ func (o *Outbound) Transports() []transport.Transport {
 	return []transport.Transport{o.t}
 }
 
+// Chooser returns the peer.Chooser of the Outbound.
+func (o *Outbound) Chooser() peer.Chooser {
+	return o.chooser
+}
+
 // Call implements transport.UnaryOutbound#Call.
 func (o *Outbound) Call(ctx context.Context, request *transport.Request) (*transport.Response, error) {
 	if err := o.once.WhenRunning(ctx); err != nil {
@@ -97,6 +120,56 @@ func (o *Outbound) Call(ctx context.Context, request *transport.Request) (*trans
 	}, nil
 }
 
+// CallStream implements transport.StreamOutbound#CallStream.
+//
+// The peer chosen for the stream is considered busy until the stream has
+// ended, that is, until ReceiveMessage returned an error or io.EOF.
+func (o *Outbound) CallStream(ctx context.Context, request *transport.Request) (transport.ClientStream, error) {
+	if err := o.once.WhenRunning(ctx); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	md, err := transportRequestToMetadata(request)
+	if err != nil {
+		return nil, err
+	}
+	fullMethod, err := procedureNameToFullMethod(request.Procedure)
+	if err != nil {
+		return nil, err
+	}
+	var callOptions []grpc.CallOption
+	if c := o.outboundOptions.compressor; c != nil {
+		// Every message of the stream is compressed, since the
+		// grpc-encoding header is sent before the first one.
+		callOptions = append(callOptions, grpc.UseCompressor(c.Name()))
+	}
+	grpcPeer, onFinish, err := o.getPeerForRequest(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	clientStream := newClientStream(ctx, request, start, onFinish)
+	stream, err := grpc.NewClientStream(
+		// The stats handler of the connection finishes the stream when gRPC
+		// ends its RPC.
+		withClientStream(metadata.NewOutgoingContext(ctx, md), clientStream),
+		&grpc.StreamDesc{
+			StreamName:    fullMethod,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		grpcPeer.clientConn,
+		fullMethod,
+		callOptions...,
+	)
+	if err != nil {
+		err = fromGRPCError(ctx, request, start, err, nil)
+		clientStream.finish(err)
+		return nil, err
+	}
+	clientStream.stream = stream
+	return clientStream, nil
+}
+
 func (o *Outbound) invoke(
 	ctx context.Context,
 	request *transport.Request,
@@ -117,66 +190,70 @@ func (o *Outbound) invoke(
 	if err != nil {
 		return err
 	}
-	var callOptions []grpc.CallOption
+	var trailer metadata.MD
+	callOptions := []grpc.CallOption{grpc.Trailer(&trailer)}
 	if responseMD != nil {
-		callOptions = []grpc.CallOption{grpc.Header(responseMD)}
+		callOptions = append(callOptions, grpc.Header(responseMD))
+	}
+	if c := o.outboundOptions.compressor; c != nil && len(requestBody) >= o.outboundOptions.minCompressSize {
+		// gRPC compresses the request and reports it in the grpc-encoding
+		// header. The response is compressed the same way by inbounds that
+		// support the compressor.
+		callOptions = append(callOptions, grpc.UseCompressor(c.Name()))
+	}
+	grpcPeer, onFinish, err := o.getPeerForRequest(ctx, request)
+	if err != nil {
+		return err
 	}
 	if err := grpc.Invoke(
-		metadata.NewContext(ctx, md),
+		metadata.NewOutgoingContext(ctx, md),
 		fullMethod,
 		requestBody,
 		responseBody,
-		o.clientConn,
+		grpcPeer.clientConn,
 		callOptions...,
 	); err != nil {
-		return errorToGRPCError(ctx, request, start, err)
+		err = fromGRPCError(ctx, request, start, err, trailer)
+		onFinish(err)
+		return err
 	}
+	onFinish(nil)
 	return nil
 }
 
-func (o *Outbound) start() error {
-	// TODO: redial
-	clientConn, err := grpc.Dial(
-		o.address,
-		grpc.WithInsecure(),
-		grpc.WithCodec(customCodec{}),
-		// TODO: does this actually work for yarpc
-		// this needs a lot of review
-		//grpc.WithUnaryInterceptor(otgrpc.OpenTracingClientInterceptor(o.outboundOptions.getTracer())),
-		grpc.WithUserAgent(UserAgent),
-	)
+func (o *Outbound) getPeerForRequest(ctx context.Context, request *transport.Request) (*grpcPeer, func(error), error) {
+	p, onFinish, err := o.chooser.Choose(ctx, request)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	o.lock.Lock()
-	defer o.lock.Unlock()
-	o.clientConn = clientConn
-	return nil
-}
-
-func (o *Outbound) stop() error {
-	o.lock.Lock()
-	defer o.lock.Unlock()
-	if o.clientConn != nil {
-		return o.clientConn.Close()
+	grpcPeer, ok := p.(*grpcPeer)
+	if !ok {
+		onFinish(nil)
+		return nil, nil, peer.ErrInvalidPeerConversion{
+			Peer:         p,
+			ExpectedType: "*grpcPeer",
+		}
 	}
-	return nil
+	return grpcPeer, onFinish, nil
 }
 
-func errorToGRPCError(ctx context.Context, request *transport.Request, start time.Time, err error) error {
-	deadline, _ := ctx.Deadline()
-	ttl := deadline.Sub(start)
-	switch grpc.Code(err) {
-	case codes.DeadlineExceeded:
-		return errors.ClientTimeoutError(request.Service, request.Procedure, ttl)
-	case codes.Unimplemented, codes.InvalidArgument, codes.NotFound:
-		return errors.RemoteBadRequestError(grpc.ErrorDesc(err))
-	case codes.Canceled, codes.AlreadyExists, codes.PermissionDenied,
-		codes.Unauthenticated, codes.ResourceExhausted, codes.FailedPrecondition,
-		codes.Aborted, codes.OutOfRange, codes.Internal,
-		codes.Unavailable, codes.DataLoss, codes.Unknown:
-		fallthrough
-	default:
-		return errors.RemoteUnexpectedError(grpc.ErrorDesc(err))
+// Introspect returns basic status about this outbound.
+func (o *Outbound) Introspect() introspection.OutboundStatus {
+	state := "Stopped"
+	if o.IsRunning() {
+		state = "Running"
+	}
+	var chooser introspection.ChooserStatus
+	if i, ok := o.chooser.(introspection.IntrospectableChooser); ok {
+		chooser = i.Introspect()
+	} else {
+		chooser = introspection.ChooserStatus{
+			Name: "Introspection not available",
+		}
+	}
+	return introspection.OutboundStatus{
+		Transport: transportName,
+		State:     state,
+		Chooser:   chooser,
 	}
 }
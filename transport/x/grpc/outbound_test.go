@@ -0,0 +1,116 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/peer/hostport"
+	"go.uber.org/yarpc/peer/roundrobin"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type unaryHandlerFunc func(context.Context, *transport.Request, transport.ResponseWriter) error
+
+func (f unaryHandlerFunc) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter) error {
+	return f(ctx, req, resw)
+}
+
+// startNamedInbound starts an Inbound which responds to "Name::Get" with the
+// given name.
+func startNamedInbound(t *testing.T, tr *Transport, name string) (*Inbound, string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	inbound := tr.NewInbound(listener)
+	inbound.SetRouter(newTestRouter([]transport.Procedure{
+		{
+			Name:    "Name::Get",
+			Service: "example",
+			HandlerSpec: transport.NewUnaryHandlerSpec(unaryHandlerFunc(
+				func(_ context.Context, _ *transport.Request, resw transport.ResponseWriter) error {
+					_, err := resw.Write([]byte(name))
+					return err
+				},
+			)),
+		},
+	}))
+	require.NoError(t, inbound.Start())
+	return inbound, listener.Addr().String()
+}
+
+func TestOutboundWithPeerChooser(t *testing.T) {
+	tr := NewTransport()
+	require.NoError(t, tr.Start())
+	defer func() { assert.NoError(t, tr.Stop()) }()
+
+	inbound1, addr1 := startNamedInbound(t, tr, "first")
+	defer func() { assert.NoError(t, inbound1.Stop()) }()
+	inbound2, addr2 := startNamedInbound(t, tr, "second")
+	defer func() { assert.NoError(t, inbound2.Stop()) }()
+
+	list := roundrobin.New(tr)
+	outbound := tr.NewOutbound(list)
+	require.NoError(t, outbound.Start())
+	defer func() { assert.NoError(t, outbound.Stop()) }()
+	require.NoError(t, list.Update(peer.ListUpdates{
+		Additions: []peer.Identifier{hostport.PeerIdentifier(addr1), hostport.PeerIdentifier(addr2)},
+	}))
+	for _, addr := range []string{addr1, addr2} {
+		tr.lock.Lock()
+		p := tr.peers[addr]
+		tr.lock.Unlock()
+		require.NotNil(t, p, "expected transport to retain peer %q", addr)
+		waitForConnectionStatus(t, p, peer.Available)
+	}
+
+	got := make(map[string]int)
+	for i := 0; i < 10; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		res, err := outbound.Call(ctx, &transport.Request{
+			Caller:    "caller",
+			Service:   "example",
+			Encoding:  transport.Encoding("raw"),
+			Procedure: "Name::Get",
+			Body:      bytes.NewReader(nil),
+		})
+		cancel()
+		require.NoError(t, err)
+		body, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		got[string(body)]++
+	}
+	assert.Equal(t, map[string]int{"first": 5, "second": 5}, got,
+		"expected requests to be spread evenly across peers")
+
+	status := outbound.Introspect()
+	assert.Equal(t, "grpc", status.Transport)
+	assert.Equal(t, "Running", status.State)
+	assert.Len(t, status.Chooser.Peers, 2)
+}
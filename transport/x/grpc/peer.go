@@ -0,0 +1,133 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"go.uber.org/yarpc/api/peer"
+	intnet "go.uber.org/yarpc/internal/net"
+	"go.uber.org/yarpc/peer/hostport"
+
+	"google.golang.org/grpc"
+)
+
+// grpcPeer is a hostport.Peer backed by its own grpc.ClientConn.
+//
+// The connection status of the peer follows the underlying connections
+// established by the grpc.ClientConn: the peer is Connecting until the first
+// connection is established, Available while at least one connection is open,
+// and Unavailable after a connection attempt fails or the peer is released.
+type grpcPeer struct {
+	*hostport.Peer
+
+	lock       sync.Mutex
+	clientConn *grpc.ClientConn
+	openConns  int
+	released   bool
+}
+
+func newPeer(pid hostport.PeerIdentifier, t *Transport) (*grpcPeer, error) {
+	p := &grpcPeer{Peer: hostport.NewPeer(pid, t)}
+	p.SetStatus(peer.Connecting)
+	clientConn, err := grpc.Dial(
+		// The passthrough resolver hands the address to dial as is, rather
+		// than parsing "unix://" addresses as resolver targets.
+		"passthrough:///"+pid.Identifier(),
+		grpc.WithInsecure(),
+		grpc.WithCodec(customCodec{}),
+		grpc.WithUserAgent(UserAgent),
+		grpc.WithDialer(p.dial),
+		grpc.WithStatsHandler(streamEndHandler{}),
+	)
+	if err != nil {
+		p.SetStatus(peer.Unavailable)
+		return nil, err
+	}
+	p.clientConn = clientConn
+	return p, nil
+}
+
+// dial is used by the grpc.ClientConn to establish connections to the peer.
+//
+// The address is either a "host:port" address or a "unix:///path/to/socket"
+// address.
+func (p *grpcPeer) dial(address string, timeout time.Duration) (net.Conn, error) {
+	network, address := intnet.SplitAddress(address)
+	conn, err := net.DialTimeout(network, address, timeout)
+	if err != nil {
+		p.setStatus(peer.Unavailable)
+		return nil, err
+	}
+
+	p.lock.Lock()
+	p.openConns++
+	p.lock.Unlock()
+	p.setStatus(peer.Available)
+	return &trackedConn{Conn: conn, onClose: p.connClosed}, nil
+}
+
+func (p *grpcPeer) connClosed() {
+	p.lock.Lock()
+	p.openConns--
+	openConns := p.openConns
+	p.lock.Unlock()
+	if openConns == 0 {
+		// The grpc.ClientConn will try to reconnect.
+		p.setStatus(peer.Connecting)
+	}
+}
+
+// setStatus updates the status of the peer unless it was already released.
+func (p *grpcPeer) setStatus(status peer.ConnectionStatus) {
+	p.lock.Lock()
+	released := p.released
+	p.lock.Unlock()
+	if !released {
+		p.SetStatus(status)
+	}
+}
+
+// release closes the grpc.ClientConn for this peer. The peer MUST NOT be used
+// afterwards.
+func (p *grpcPeer) release() error {
+	p.lock.Lock()
+	p.released = true
+	p.lock.Unlock()
+	p.SetStatus(peer.Unavailable)
+	return p.clientConn.Close()
+}
+
+// trackedConn is a net.Conn which reports when it was closed.
+type trackedConn struct {
+	net.Conn
+
+	once    sync.Once
+	onClose func()
+}
+
+func (c *trackedConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.onClose)
+	return err
+}
@@ -0,0 +1,204 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+	"go.uber.org/yarpc/api/transport"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+)
+
+var (
+	_ transport.Stream       = (*serverStream)(nil)
+	_ transport.ClientStream = (*clientStream)(nil)
+	_ stats.Handler          = streamEndHandler{}
+)
+
+// serverStream adapts a grpc.ServerStream into a transport.Stream.
+type serverStream struct {
+	ctx     context.Context
+	request *transport.Request
+	stream  grpc.ServerStream
+}
+
+func newServerStream(ctx context.Context, request *transport.Request, stream grpc.ServerStream) *serverStream {
+	return &serverStream{ctx: ctx, request: request, stream: stream}
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *serverStream) Request() *transport.Request {
+	return s.request
+}
+
+func (s *serverStream) SendMessage(msg *transport.StreamMessage) error {
+	data, err := readStreamMessage(msg)
+	if err != nil {
+		return err
+	}
+	return s.stream.SendMsg(data)
+}
+
+func (s *serverStream) ReceiveMessage() (*transport.StreamMessage, error) {
+	var data []byte
+	if err := s.stream.RecvMsg(&data); err != nil {
+		return nil, err
+	}
+	return newStreamMessage(data), nil
+}
+
+// clientStream adapts a grpc.ClientStream into a transport.ClientStream.
+//
+// onFinish is called once the stream has ended, as observed by
+// ReceiveMessage, or when gRPC ends the RPC because the server ended the
+// stream or its context is done, even if the stream was closed without being
+// read to the end.
+type clientStream struct {
+	ctx     context.Context
+	request *transport.Request
+	start   time.Time
+	stream  grpc.ClientStream
+
+	finishOnce sync.Once
+	onFinish   func(error)
+}
+
+// newClientStream builds a clientStream for a stream that is about to be
+// opened. The grpc.ClientStream must be set once it is open.
+func newClientStream(
+	ctx context.Context,
+	request *transport.Request,
+	start time.Time,
+	onFinish func(error),
+) *clientStream {
+	return &clientStream{ctx: ctx, request: request, start: start, onFinish: onFinish}
+}
+
+func (s *clientStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *clientStream) Request() *transport.Request {
+	return s.request
+}
+
+func (s *clientStream) SendMessage(msg *transport.StreamMessage) error {
+	data, err := readStreamMessage(msg)
+	if err != nil {
+		return err
+	}
+	if err := s.stream.SendMsg(data); err != nil {
+		return s.toError(err, nil)
+	}
+	return nil
+}
+
+func (s *clientStream) ReceiveMessage() (*transport.StreamMessage, error) {
+	var data []byte
+	if err := s.stream.RecvMsg(&data); err != nil {
+		// The trailer is only available once RecvMsg has failed.
+		err = s.toError(err, s.stream.Trailer())
+		s.finish(err)
+		return nil, err
+	}
+	return newStreamMessage(data), nil
+}
+
+func (s *clientStream) finish(err error) {
+	if err == io.EOF {
+		err = nil
+	}
+	s.finishOnce.Do(func() { s.onFinish(err) })
+}
+
+func (s *clientStream) Close() error {
+	return s.stream.CloseSend()
+}
+
+func (s *clientStream) toError(err error, trailer metadata.MD) error {
+	if err == io.EOF {
+		return err
+	}
+	return fromGRPCError(s.ctx, s.request, s.start, err, trailer)
+}
+
+type clientStreamKey struct{}
+
+// withClientStream returns a context for opening the given stream, through
+// which streamEndHandler finds it.
+func withClientStream(ctx context.Context, s *clientStream) context.Context {
+	return context.WithValue(ctx, clientStreamKey{}, s)
+}
+
+// streamEndHandler is a stats.Handler which finishes client streams when gRPC
+// ends their RPC.
+type streamEndHandler struct{}
+
+func (streamEndHandler) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (streamEndHandler) HandleRPC(ctx context.Context, rs stats.RPCStats) {
+	end, ok := rs.(*stats.End)
+	if !ok {
+		return
+	}
+	s, ok := ctx.Value(clientStreamKey{}).(*clientStream)
+	if !ok {
+		return
+	}
+	var err error
+	if end.Error != nil {
+		err = fromGRPCError(s.ctx, s.request, s.start, end.Error, nil)
+	}
+	s.finish(err)
+}
+
+func (streamEndHandler) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (streamEndHandler) HandleConn(context.Context, stats.ConnStats) {}
+
+func readStreamMessage(msg *transport.StreamMessage) (data []byte, err error) {
+	if msg == nil || msg.Body == nil {
+		return []byte{}, nil
+	}
+	defer func() { err = multierr.Append(err, msg.Body.Close()) }()
+	// TODO: use pooled buffers
+	return ioutil.ReadAll(msg.Body)
+}
+
+func newStreamMessage(data []byte) *transport.StreamMessage {
+	return &transport.StreamMessage{Body: ioutil.NopCloser(bytes.NewReader(data))}
+}
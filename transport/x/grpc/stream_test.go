@@ -0,0 +1,223 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	peerchooser "go.uber.org/yarpc/peer"
+	"go.uber.org/yarpc/peer/hostport"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type echoStreamHandler struct{}
+
+func (echoStreamHandler) HandleStream(stream transport.Stream) error {
+	for {
+		msg, err := stream.ReceiveMessage()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := stream.SendMessage(msg); err != nil {
+			return err
+		}
+	}
+}
+
+func TestStreamEcho(t *testing.T) {
+	t.Parallel()
+
+	t.Run("uncompressed", func(t *testing.T) {
+		testStreamEcho(t, nil, nil)
+	})
+
+	t.Run("compressed", func(t *testing.T) {
+		compressor := newCountingCompressor()
+		testStreamEcho(t,
+			[]InboundOption{InboundCompressor(compressor)},
+			[]OutboundOption{OutboundCompressor(compressor)})
+		assert.Equal(t, int32(6), compressor.compressed.Load(), "every request and response message must be compressed")
+		assert.Equal(t, int32(6), compressor.decompressed.Load(), "every request and response message must be decompressed")
+	})
+}
+
+func testStreamEcho(t *testing.T, inboundOptions []InboundOption, outboundOptions []OutboundOption) {
+	tr := NewTransport()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	inbound := tr.NewInbound(listener, inboundOptions...)
+	inbound.SetRouter(newTestRouter([]transport.Procedure{
+		{
+			Name:        "Echo::Stream",
+			Service:     "example",
+			HandlerSpec: transport.NewStreamHandlerSpec(echoStreamHandler{}),
+		},
+	}))
+	require.NoError(t, inbound.Start())
+	defer func() { assert.NoError(t, inbound.Stop()) }()
+
+	outbound := tr.NewSingleOutbound(listener.Addr().String(), outboundOptions...)
+	require.NoError(t, outbound.Start())
+	defer func() { assert.NoError(t, outbound.Stop()) }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	stream, err := outbound.CallStream(ctx, &transport.Request{
+		Caller:    "caller",
+		Service:   "example",
+		Encoding:  transport.Encoding("raw"),
+		Procedure: "Echo::Stream",
+	})
+	require.NoError(t, err)
+
+	for _, body := range []string{"foo", "bar", "baz"} {
+		require.NoError(t, stream.SendMessage(&transport.StreamMessage{
+			Body: ioutil.NopCloser(bytes.NewBufferString(body)),
+		}))
+		msg, err := stream.ReceiveMessage()
+		require.NoError(t, err)
+		got, err := ioutil.ReadAll(msg.Body)
+		require.NoError(t, err)
+		assert.Equal(t, body, string(got))
+	}
+
+	require.NoError(t, stream.Close())
+	_, err = stream.ReceiveMessage()
+	assert.Equal(t, io.EOF, err)
+}
+
+// finishRecorder is a peer.Chooser which reports when requests finish.
+type finishRecorder struct {
+	peer.Chooser
+
+	finished chan error
+}
+
+func (c *finishRecorder) Choose(ctx context.Context, req *transport.Request) (peer.Peer, func(error), error) {
+	p, onFinish, err := c.Chooser.Choose(ctx, req)
+	if err != nil {
+		return p, onFinish, err
+	}
+	return p, func(err error) {
+		onFinish(err)
+		c.finished <- err
+	}, nil
+}
+
+type blockingStreamHandler struct{}
+
+func (blockingStreamHandler) HandleStream(stream transport.Stream) error {
+	<-stream.Context().Done()
+	return stream.Context().Err()
+}
+
+func TestStreamFinish(t *testing.T) {
+	tests := []struct {
+		desc    string
+		handler transport.StreamHandler
+		end     func(transport.ClientStream, context.CancelFunc)
+		wantErr bool
+	}{
+		{
+			desc:    "closed without reading",
+			handler: echoStreamHandler{},
+			end: func(stream transport.ClientStream, _ context.CancelFunc) {
+				assert.NoError(t, stream.Close())
+			},
+		},
+		{
+			desc:    "context done",
+			handler: blockingStreamHandler{},
+			end: func(_ transport.ClientStream, cancel context.CancelFunc) {
+				cancel()
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			tr := NewTransport()
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+
+			inbound := tr.NewInbound(listener)
+			inbound.SetRouter(newTestRouter([]transport.Procedure{
+				{
+					Name:        "Echo::Stream",
+					Service:     "example",
+					HandlerSpec: transport.NewStreamHandlerSpec(tt.handler),
+				},
+			}))
+			require.NoError(t, inbound.Start())
+			defer func() { assert.NoError(t, inbound.Stop()) }()
+
+			chooser := &finishRecorder{
+				Chooser:  peerchooser.NewSingle(hostport.PeerIdentifier(listener.Addr().String()), tr),
+				finished: make(chan error, 2),
+			}
+			outbound := tr.NewOutbound(chooser)
+			require.NoError(t, outbound.Start())
+			defer func() { assert.NoError(t, outbound.Stop()) }()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			stream, err := outbound.CallStream(ctx, &transport.Request{
+				Caller:    "caller",
+				Service:   "example",
+				Encoding:  transport.Encoding("raw"),
+				Procedure: "Echo::Stream",
+			})
+			require.NoError(t, err)
+
+			tt.end(stream, cancel)
+			select {
+			case err := <-chooser.finished:
+				if tt.wantErr {
+					assert.Error(t, err, "expected the stream to fail")
+				} else {
+					assert.NoError(t, err, "expected the stream to succeed")
+				}
+			case <-time.After(time.Second):
+				t.Fatal("timed out waiting for the stream to finish")
+			}
+
+			// Reading the end of the stream must not finish it again.
+			_, err = stream.ReceiveMessage()
+			assert.Error(t, err)
+			assert.Empty(t, chooser.finished, "stream must only finish once")
+		})
+	}
+}
@@ -22,22 +22,39 @@ package grpc
 
 import (
 	"net"
+	"sync"
 
+	"go.uber.org/multierr"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
 	internalsync "go.uber.org/yarpc/internal/sync"
+	"go.uber.org/yarpc/peer/hostport"
+)
+
+var (
+	_ transport.Transport = (*Transport)(nil)
+	_ peer.Transport      = (*Transport)(nil)
 )
 
 // Transport is a grpc transport.Transport.
 //
-// This currently does not have any additional functionality over creating
-// an Inbound or Outbound separately, but may in the future.
+// Transport implements peer.Transport and maintains a grpc.ClientConn for
+// each peer retained by the peer lists of its outbounds.
 type Transport struct {
 	once             internalsync.LifecycleOnce
 	transportOptions *transportOptions
+
+	lock  sync.Mutex
+	peers map[string]*grpcPeer
 }
 
 // NewTransport returns a new Transport.
 func NewTransport(options ...TransportOption) *Transport {
-	return &Transport{internalsync.Once(), newTransportOptions(options)}
+	return &Transport{
+		once:             internalsync.Once(),
+		transportOptions: newTransportOptions(options),
+		peers:            make(map[string]*grpcPeer),
+	}
 }
 
 // Start implements transport.Lifecycle#Start.
@@ -47,7 +64,7 @@ func (t *Transport) Start() error {
 
 // Stop implements transport.Lifecycle#Stop.
 func (t *Transport) Stop() error {
-	return t.once.Stop(nil)
+	return t.once.Stop(t.stop)
 }
 
 // IsRunning implements transport.Lifecycle#IsRunning.
@@ -64,3 +81,89 @@ func (t *Transport) NewInbound(listener net.Listener, options ...InboundOption)
 func (t *Transport) NewSingleOutbound(address string, options ...OutboundOption) *Outbound {
 	return newSingleOutbound(t, address, options...)
 }
+
+// NewOutbound returns a new Outbound which sends requests to peers supplied
+// by the given peer.Chooser.
+//
+// Peer Choosers used with the gRPC outbound MUST retain peers from this
+// Transport.
+func (t *Transport) NewOutbound(chooser peer.Chooser, options ...OutboundOption) *Outbound {
+	return newOutbound(t, chooser, options...)
+}
+
+// RetainPeer retains the identified peer, creating a grpc.ClientConn for it
+// if necessary.
+//
+// This implements peer.Transport#RetainPeer.
+func (t *Transport) RetainPeer(pid peer.Identifier, sub peer.Subscriber) (peer.Peer, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	hppid, ok := pid.(hostport.PeerIdentifier)
+	if !ok {
+		return nil, peer.ErrInvalidPeerType{
+			ExpectedType:   "hostport.PeerIdentifier",
+			PeerIdentifier: pid,
+		}
+	}
+
+	p, err := t.getOrCreatePeer(hppid)
+	if err != nil {
+		return nil, err
+	}
+	p.Subscribe(sub)
+	return p, nil
+}
+
+// **NOTE** should only be called while the lock write mutex is acquired
+func (t *Transport) getOrCreatePeer(pid hostport.PeerIdentifier) (*grpcPeer, error) {
+	if p, ok := t.peers[pid.Identifier()]; ok {
+		return p, nil
+	}
+
+	p, err := newPeer(pid, t)
+	if err != nil {
+		return nil, err
+	}
+	t.peers[p.Identifier()] = p
+	return p, nil
+}
+
+// ReleasePeer releases the identified peer from the given subscriber. The
+// grpc.ClientConn of the peer is closed once no subscribers remain.
+//
+// This implements peer.Transport#ReleasePeer.
+func (t *Transport) ReleasePeer(pid peer.Identifier, sub peer.Subscriber) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	p, ok := t.peers[pid.Identifier()]
+	if !ok {
+		return peer.ErrTransportHasNoReferenceToPeer{
+			TransportName:  "grpc.Transport",
+			PeerIdentifier: pid.Identifier(),
+		}
+	}
+
+	if err := p.Unsubscribe(sub); err != nil {
+		return err
+	}
+
+	if p.NumSubscribers() == 0 {
+		delete(t.peers, pid.Identifier())
+		return p.release()
+	}
+	return nil
+}
+
+func (t *Transport) stop() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var err error
+	for id, p := range t.peers {
+		err = multierr.Append(err, p.release())
+		delete(t.peers, id)
+	}
+	return err
+}
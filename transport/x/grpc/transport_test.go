@@ -21,9 +21,18 @@
 package grpc
 
 import (
+	"net"
 	"testing"
+	"time"
 
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/peer/peertest"
+	"go.uber.org/yarpc/peer/hostport"
+
+	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
 )
 
 func TestTransportLifecycle(t *testing.T) {
@@ -33,3 +42,102 @@ func TestTransportLifecycle(t *testing.T) {
 	assert.NoError(t, transport.Stop())
 	assert.False(t, transport.IsRunning())
 }
+
+func TestTransportRetainWithInvalidPeerIdentifierType(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	transport := NewTransport()
+	pid := peertest.NewMockIdentifier(mockCtrl)
+
+	_, err := transport.RetainPeer(pid, peertest.NewMockSubscriber(mockCtrl))
+	assert.Equal(t, peer.ErrInvalidPeerType{
+		ExpectedType:   "hostport.PeerIdentifier",
+		PeerIdentifier: pid,
+	}, err)
+}
+
+func TestTransportReleaseUnknownPeer(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	transport := NewTransport()
+	err := transport.ReleasePeer(hostport.PeerIdentifier("127.0.0.1:1234"), peertest.NewMockSubscriber(mockCtrl))
+	assert.Equal(t, peer.ErrTransportHasNoReferenceToPeer{
+		TransportName:  "grpc.Transport",
+		PeerIdentifier: "127.0.0.1:1234",
+	}, err)
+}
+
+func TestTransportPeerLifecycle(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	go server.Serve(listener)
+	defer server.Stop()
+
+	transport := NewTransport()
+	require.NoError(t, transport.Start())
+	defer transport.Stop()
+
+	pid := hostport.PeerIdentifier(listener.Addr().String())
+	sub1 := peertest.NewMockSubscriber(mockCtrl)
+	sub1.EXPECT().NotifyStatusChanged(gomock.Any()).AnyTimes()
+	sub2 := peertest.NewMockSubscriber(mockCtrl)
+	sub2.EXPECT().NotifyStatusChanged(gomock.Any()).AnyTimes()
+
+	p1, err := transport.RetainPeer(pid, sub1)
+	require.NoError(t, err)
+	p2, err := transport.RetainPeer(pid, sub2)
+	require.NoError(t, err)
+	assert.Equal(t, p1, p2, "expected the same peer for the same identifier")
+
+	waitForConnectionStatus(t, p1, peer.Available)
+
+	require.NoError(t, transport.ReleasePeer(pid, sub1))
+	assert.Equal(t, peer.Available, p1.Status().ConnectionStatus,
+		"expected peer to remain available while it has subscribers")
+
+	require.NoError(t, transport.ReleasePeer(pid, sub2))
+	assert.Equal(t, peer.Unavailable, p1.Status().ConnectionStatus,
+		"expected peer to be unavailable after it was released")
+}
+
+func TestTransportPeerUnavailable(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	// Reserve an address and close the listener so that nothing accepts
+	// connections on it.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	require.NoError(t, listener.Close())
+
+	transport := NewTransport()
+	require.NoError(t, transport.Start())
+	defer transport.Stop()
+
+	pid := hostport.PeerIdentifier(listener.Addr().String())
+	sub := peertest.NewMockSubscriber(mockCtrl)
+	sub.EXPECT().NotifyStatusChanged(gomock.Any()).AnyTimes()
+
+	p, err := transport.RetainPeer(pid, sub)
+	require.NoError(t, err)
+	defer transport.ReleasePeer(pid, sub)
+
+	waitForConnectionStatus(t, p, peer.Unavailable)
+}
+
+func waitForConnectionStatus(t *testing.T, p peer.Peer, want peer.ConnectionStatus) {
+	deadline := time.Now().Add(time.Second)
+	for p.Status().ConnectionStatus != want {
+		if time.Now().After(deadline) {
+			require.Fail(t, "timed out waiting for connection status",
+				"wanted %v, got %v", want, p.Status().ConnectionStatus)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
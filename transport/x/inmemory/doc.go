@@ -0,0 +1,52 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package inmemory implements a transport that connects inbounds and
+// outbounds within the same process, without any network I/O.
+//
+// Outbounds are connected to the running inbound that was created on the same
+// Transport with a matching service name.
+//
+// 	t := inmemory.NewTransport()
+// 	inbound := t.NewInbound("keyvalue")
+// 	outbound := t.NewOutbound("keyvalue")
+//
+// Outbounds support unary, oneway, and streaming RPCs. Requests pass through
+// all middleware and encodings of both Dispatchers as they would over the
+// network, and the handlers of unary requests see the deadline of the
+// caller. This makes the transport suitable for fast integration tests of
+// services which share a process.
+//
+// 	server := yarpc.NewDispatcher(yarpc.Config{
+// 		Name:     "keyvalue",
+// 		Inbounds: yarpc.Inbounds{t.NewInbound("keyvalue")},
+// 	})
+// 	client := yarpc.NewDispatcher(yarpc.Config{
+// 		Name: "client",
+// 		Outbounds: yarpc.Outbounds{
+// 			"keyvalue": {
+// 				Unary:  t.NewOutbound("keyvalue"),
+// 				Oneway: t.NewOutbound("keyvalue"),
+// 			},
+// 		},
+// 	})
+//
+// This package is experimental and should not be used in production.
+package inmemory
@@ -0,0 +1,54 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package inmemory
+
+import (
+	"testing"
+
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/internal/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInboundRequiresRouter(t *testing.T) {
+	inbound := NewTransport().NewInbound("service")
+	assert.Equal(t, errors.ErrNoRouter, inbound.Start())
+}
+
+func TestInboundDuplicateService(t *testing.T) {
+	tr := NewTransport()
+
+	first := tr.NewInbound("service")
+	first.SetRouter(yarpc.NewMapRouter("service"))
+	require.NoError(t, first.Start())
+
+	second := tr.NewInbound("service")
+	second.SetRouter(yarpc.NewMapRouter("service"))
+	assert.Error(t, second.Start(), "expected duplicate inbound to fail")
+
+	require.NoError(t, first.Stop())
+	third := tr.NewInbound("service")
+	third.SetRouter(yarpc.NewMapRouter("service"))
+	assert.NoError(t, third.Start(), "expected inbound to start after the previous one stopped")
+	assert.NoError(t, third.Stop())
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package inmemory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/encoding/json"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type greeting struct {
+	Name    string `json:"name"`
+	Message string `json:"message,omitempty"`
+}
+
+func TestDispatchers(t *testing.T) {
+	tr := NewTransport()
+
+	var inboundCalls, outboundCalls int
+	server := yarpc.NewDispatcher(yarpc.Config{
+		Name:     "inmemory-server",
+		Inbounds: yarpc.Inbounds{tr.NewInbound("inmemory-server")},
+		InboundMiddleware: yarpc.InboundMiddleware{
+			Unary: middleware.UnaryInboundFunc(func(ctx context.Context, req *transport.Request, resw transport.ResponseWriter, h transport.UnaryHandler) error {
+				inboundCalls++
+				return h.Handle(ctx, req, resw)
+			}),
+		},
+	})
+	oneways := make(chan string, 1)
+	server.Register(json.Procedure("hello", func(ctx context.Context, req *greeting) (*greeting, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("handler context has no deadline")
+		}
+		return &greeting{Name: req.Name, Message: "hello " + req.Name}, nil
+	}))
+	server.Register(json.OnewayProcedure("notify", func(ctx context.Context, req *greeting) error {
+		oneways <- req.Name
+		return nil
+	}))
+
+	client := yarpc.NewDispatcher(yarpc.Config{
+		Name: "inmemory-client",
+		Outbounds: yarpc.Outbounds{
+			"inmemory-server": {
+				Unary:  tr.NewOutbound("inmemory-server"),
+				Oneway: tr.NewOutbound("inmemory-server"),
+			},
+		},
+		OutboundMiddleware: yarpc.OutboundMiddleware{
+			Unary: middleware.UnaryOutboundFunc(func(ctx context.Context, req *transport.Request, o transport.UnaryOutbound) (*transport.Response, error) {
+				outboundCalls++
+				return o.Call(ctx, req)
+			}),
+		},
+	})
+
+	require.NoError(t, server.Start())
+	defer server.Stop()
+	require.NoError(t, client.Start())
+	defer client.Stop()
+
+	c := json.New(client.ClientConfig("inmemory-server"))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var res greeting
+	require.NoError(t, c.Call(ctx, "hello", &greeting{Name: "world"}, &res))
+	assert.Equal(t, "hello world", res.Message)
+	assert.Equal(t, 1, inboundCalls, "inbound middleware must be called")
+	assert.Equal(t, 1, outboundCalls, "outbound middleware must be called")
+
+	_, err := c.CallOneway(ctx, "notify", &greeting{Name: "world"})
+	require.NoError(t, err)
+	select {
+	case name := <-oneways:
+		assert.Equal(t, "world", name)
+	case <-time.After(time.Second):
+		t.Fatal("oneway handler was not called")
+	}
+}
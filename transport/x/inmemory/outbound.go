@@ -0,0 +1,200 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package inmemory
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/errors"
+	"go.uber.org/yarpc/internal/iopool"
+	"go.uber.org/yarpc/internal/request"
+	internalsync "go.uber.org/yarpc/internal/sync"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+var (
+	_ transport.UnaryOutbound  = (*Outbound)(nil)
+	_ transport.OnewayOutbound = (*Outbound)(nil)
+	_ transport.StreamOutbound = (*Outbound)(nil)
+)
+
+// Outbound sends requests to the Inbound of the same Transport for its
+// service.
+type Outbound struct {
+	once    internalsync.LifecycleOnce
+	t       *Transport
+	service string
+}
+
+func newOutbound(t *Transport, service string) *Outbound {
+	return &Outbound{
+		once:    internalsync.Once(),
+		t:       t,
+		service: service,
+	}
+}
+
+// Start implements transport.Lifecycle#Start.
+func (o *Outbound) Start() error {
+	return o.once.Start(nil)
+}
+
+// Stop implements transport.Lifecycle#Stop.
+func (o *Outbound) Stop() error {
+	return o.once.Stop(nil)
+}
+
+// IsRunning implements transport.Lifecycle#IsRunning.
+func (o *Outbound) IsRunning() bool {
+	return o.once.IsRunning()
+}
+
+// Transports implements transport.Outbound#Transports.
+func (o *Outbound) Transports() []transport.Transport {
+	return []transport.Transport{o.t}
+}
+
+// Call implements transport.UnaryOutbound#Call.
+//
+// The request is handled on its own goroutine with a context that carries
+// the deadline of ctx. Call returns when the handler finishes or when ctx is
+// done, whichever comes first.
+func (o *Outbound) Call(ctx context.Context, req *transport.Request) (*transport.Response, error) {
+	if err := o.once.WhenRunning(ctx); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	if err := request.ValidateUnaryContext(ctx); err != nil {
+		return nil, err
+	}
+
+	treq, spec, err := o.route(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if spec.Type() != transport.Unary {
+		return nil, errors.UnsupportedTypeError{Transport: transportName, Type: spec.Type().String()}
+	}
+
+	hctx, cancel := newHandlerContext(ctx)
+	defer cancel()
+
+	resw := newResponseWriter()
+	done := make(chan error, 1)
+	go func() {
+		done <- transport.DispatchUnaryHandler(hctx, spec.Unary(), start, treq, resw)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return nil, toStatus(treq, err)
+		}
+		return resw.response(), nil
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			deadline, _ := ctx.Deadline()
+			return nil, errors.ClientTimeoutError(req.Service, req.Procedure, deadline.Sub(start))
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// CallOneway implements transport.OnewayOutbound#CallOneway.
+//
+// The request is acknowledged as soon as it has been routed. The handler runs
+// on its own goroutine with a context that is independent of ctx.
+func (o *Outbound) CallOneway(ctx context.Context, req *transport.Request) (transport.Ack, error) {
+	if err := o.once.WhenRunning(ctx); err != nil {
+		return nil, err
+	}
+	treq, spec, err := o.route(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if spec.Type() != transport.Oneway {
+		return nil, errors.UnsupportedTypeError{Transport: transportName, Type: spec.Type().String()}
+	}
+
+	hctx := opentracing.ContextWithSpan(context.Background(), opentracing.SpanFromContext(ctx))
+	go transport.DispatchOnewayHandler(hctx, spec.Oneway(), treq)
+	return time.Now(), nil
+}
+
+// CallStream implements transport.StreamOutbound#CallStream.
+//
+// The stream handler runs on its own goroutine and the returned stream is
+// connected to it directly.
+func (o *Outbound) CallStream(ctx context.Context, request *transport.Request) (transport.ClientStream, error) {
+	if err := o.once.WhenRunning(ctx); err != nil {
+		return nil, err
+	}
+	inbound, err := o.t.getInbound(o.service)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := inbound.getRouter().Choose(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if spec.Type() != transport.Streaming {
+		return nil, errors.UnsupportedTypeError{Transport: transportName, Type: spec.Type().String()}
+	}
+
+	client, server := newStreamPair(ctx, request)
+	go server.handle(spec.Stream())
+	return client, nil
+}
+
+// route copies the given request as it would be received by the inbound for
+// the service of this outbound and finds its handler.
+//
+// The body of the request is read in full so that the caller may not affect
+// the request after it was sent.
+func (o *Outbound) route(ctx context.Context, req *transport.Request) (*transport.Request, transport.HandlerSpec, error) {
+	inbound, err := o.t.getInbound(o.service)
+	if err != nil {
+		return nil, transport.HandlerSpec{}, err
+	}
+
+	treq := *req
+	treq.Headers = copyHeaders(req.Headers)
+	if req.Body != nil {
+		var body bytes.Buffer
+		if _, err := iopool.Copy(&body, req.Body); err != nil {
+			return nil, transport.HandlerSpec{}, err
+		}
+		treq.Body = &body
+	}
+	if err := transport.ValidateRequest(&treq); err != nil {
+		return nil, transport.HandlerSpec{}, toStatus(&treq, err)
+	}
+
+	spec, err := inbound.getRouter().Choose(ctx, &treq)
+	if err != nil {
+		return nil, transport.HandlerSpec{}, toStatus(&treq, err)
+	}
+	return &treq, spec, nil
+}
@@ -0,0 +1,347 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package inmemory
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/yarpcerrors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func echoStream(s transport.Stream) error {
+	for {
+		msg, err := s.ReceiveMessage()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.SendMessage(msg); err != nil {
+			return err
+		}
+	}
+}
+
+type streamHandlerFunc func(transport.Stream) error
+
+func (f streamHandlerFunc) HandleStream(s transport.Stream) error { return f(s) }
+
+type unaryHandlerFunc func(context.Context, *transport.Request, transport.ResponseWriter) error
+
+func (f unaryHandlerFunc) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter) error {
+	return f(ctx, req, resw)
+}
+
+type onewayHandlerFunc func(context.Context, *transport.Request) error
+
+func (f onewayHandlerFunc) HandleOneway(ctx context.Context, req *transport.Request) error {
+	return f(ctx, req)
+}
+
+func newTestRequest() *transport.Request {
+	return &transport.Request{
+		Caller:    "caller",
+		Service:   "service",
+		Encoding:  transport.Encoding("raw"),
+		Procedure: "echo",
+	}
+}
+
+func newTestUnaryRequest(body string) *transport.Request {
+	req := newTestRequest()
+	req.Headers = transport.NewHeaders().With("foo", "bar")
+	req.Body = bytes.NewBufferString(body)
+	return req
+}
+
+func startTestOutbound(t *testing.T, spec transport.HandlerSpec) (*Outbound, func()) {
+	tr := NewTransport()
+	inbound := tr.NewInbound("service")
+	router := yarpc.NewMapRouter("service")
+	router.Register([]transport.Procedure{
+		{Name: "echo", Service: "service", HandlerSpec: spec},
+	})
+	inbound.SetRouter(router)
+	outbound := tr.NewOutbound("service")
+	require.NoError(t, tr.Start())
+	require.NoError(t, inbound.Start())
+	require.NoError(t, outbound.Start())
+	return outbound, func() {
+		assert.NoError(t, outbound.Stop())
+		assert.NoError(t, inbound.Stop())
+		assert.NoError(t, tr.Stop())
+	}
+}
+
+func sendString(t *testing.T, s transport.Stream, body string) {
+	require.NoError(t, s.SendMessage(&transport.StreamMessage{
+		Body: ioutil.NopCloser(bytes.NewBufferString(body)),
+	}))
+}
+
+func receiveString(t *testing.T, s transport.Stream) string {
+	msg, err := s.ReceiveMessage()
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(msg.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestCallStreamEcho(t *testing.T) {
+	outbound, stop := startTestOutbound(t, transport.NewStreamHandlerSpec(streamHandlerFunc(echoStream)))
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	stream, err := outbound.CallStream(ctx, newTestRequest())
+	require.NoError(t, err)
+	assert.Equal(t, "echo", stream.Request().Procedure)
+
+	for _, body := range []string{"foo", "bar", "baz"} {
+		sendString(t, stream, body)
+		assert.Equal(t, body, receiveString(t, stream))
+	}
+
+	require.NoError(t, stream.Close())
+	_, err = stream.ReceiveMessage()
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, errStreamClosed, stream.SendMessage(&transport.StreamMessage{}))
+}
+
+func TestCallStreamHandlerError(t *testing.T) {
+	outbound, stop := startTestOutbound(t, transport.NewStreamHandlerSpec(streamHandlerFunc(
+		func(s transport.Stream) error {
+			if err := s.SendMessage(&transport.StreamMessage{
+				Body: ioutil.NopCloser(bytes.NewBufferString("hello")),
+			}); err != nil {
+				return err
+			}
+			return errors.New("great sadness")
+		},
+	)))
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	stream, err := outbound.CallStream(ctx, newTestRequest())
+	require.NoError(t, err)
+
+	assert.Equal(t, "hello", receiveString(t, stream))
+	_, err = stream.ReceiveMessage()
+	assert.EqualError(t, err, "great sadness")
+	assert.Equal(t, io.EOF, stream.SendMessage(&transport.StreamMessage{}))
+}
+
+func TestCallStreamContextCancelled(t *testing.T) {
+	handlerErr := make(chan error, 1)
+	outbound, stop := startTestOutbound(t, transport.NewStreamHandlerSpec(streamHandlerFunc(
+		func(s transport.Stream) error {
+			_, err := s.ReceiveMessage()
+			handlerErr <- err
+			return err
+		},
+	)))
+	defer stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := outbound.CallStream(ctx, newTestRequest())
+	require.NoError(t, err)
+	cancel()
+
+	assert.Equal(t, context.Canceled, <-handlerErr)
+	_, err = stream.ReceiveMessage()
+	assert.Error(t, err)
+}
+
+func TestCallStreamUnsupportedType(t *testing.T) {
+	outbound, stop := startTestOutbound(t, transport.NewOnewayHandlerSpec(nil))
+	defer stop()
+
+	_, err := outbound.CallStream(context.Background(), newTestRequest())
+	assert.Error(t, err)
+}
+
+func TestCallStreamNoInbound(t *testing.T) {
+	outbound := NewTransport().NewOutbound("service")
+	require.NoError(t, outbound.Start())
+	defer outbound.Stop()
+
+	_, err := outbound.CallStream(context.Background(), newTestRequest())
+	assert.EqualError(t, err, `no inbound is running for service "service"`)
+}
+
+func TestCallStreamNotRunning(t *testing.T) {
+	outbound := NewTransport().NewOutbound("service")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := outbound.CallStream(ctx, newTestRequest())
+	assert.Error(t, err)
+}
+
+func TestCallEcho(t *testing.T) {
+	outbound, stop := startTestOutbound(t, transport.NewUnaryHandlerSpec(unaryHandlerFunc(
+		func(ctx context.Context, req *transport.Request, resw transport.ResponseWriter) error {
+			if _, ok := ctx.Deadline(); !ok {
+				return errors.New("handler context has no deadline")
+			}
+			resw.AddHeaders(req.Headers)
+			_, err := io.Copy(resw, req.Body)
+			return err
+		},
+	)))
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	res, err := outbound.Call(ctx, newTestUnaryRequest("hello"))
+	require.NoError(t, err)
+
+	body, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(body))
+	assert.Equal(t, transport.NewHeaders().With("foo", "bar"), res.Headers)
+	assert.False(t, res.ApplicationError)
+}
+
+func TestCallApplicationError(t *testing.T) {
+	outbound, stop := startTestOutbound(t, transport.NewUnaryHandlerSpec(unaryHandlerFunc(
+		func(ctx context.Context, req *transport.Request, resw transport.ResponseWriter) error {
+			resw.SetApplicationError()
+			_, err := io.WriteString(resw, "oops")
+			return err
+		},
+	)))
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	res, err := outbound.Call(ctx, newTestUnaryRequest("hello"))
+	require.NoError(t, err)
+	assert.True(t, res.ApplicationError)
+}
+
+func TestCallHandlerError(t *testing.T) {
+	outbound, stop := startTestOutbound(t, transport.NewUnaryHandlerSpec(unaryHandlerFunc(
+		func(context.Context, *transport.Request, transport.ResponseWriter) error {
+			return errors.New("great sadness")
+		},
+	)))
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := outbound.Call(ctx, newTestUnaryRequest("hello"))
+	require.Error(t, err)
+	assert.True(t, yarpcerrors.IsStatus(err), "expected a yarpcerrors.Status, got %T", err)
+	assert.Contains(t, err.Error(), "great sadness")
+}
+
+func TestCallUnknownProcedure(t *testing.T) {
+	outbound, stop := startTestOutbound(t, transport.NewUnaryHandlerSpec(nil))
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	req := newTestUnaryRequest("hello")
+	req.Procedure = "unknown"
+	_, err := outbound.Call(ctx, req)
+	require.Error(t, err)
+	assert.Equal(t, yarpcerrors.CodeInvalidArgument, yarpcerrors.FromError(err).Code())
+}
+
+func TestCallTimeout(t *testing.T) {
+	handlerDone := make(chan error, 1)
+	outbound, stop := startTestOutbound(t, transport.NewUnaryHandlerSpec(unaryHandlerFunc(
+		func(ctx context.Context, _ *transport.Request, _ transport.ResponseWriter) error {
+			<-ctx.Done()
+			handlerDone <- ctx.Err()
+			return ctx.Err()
+		},
+	)))
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := outbound.Call(ctx, newTestUnaryRequest("hello"))
+	require.Error(t, err)
+	assert.Equal(t, yarpcerrors.CodeDeadlineExceeded, yarpcerrors.FromError(err).Code())
+	assert.Error(t, <-handlerDone, "handler context must be done")
+}
+
+func TestCallRequiresDeadline(t *testing.T) {
+	outbound, stop := startTestOutbound(t, transport.NewUnaryHandlerSpec(nil))
+	defer stop()
+
+	_, err := outbound.Call(context.Background(), newTestUnaryRequest("hello"))
+	assert.Error(t, err)
+}
+
+func TestCallUnsupportedType(t *testing.T) {
+	outbound, stop := startTestOutbound(t, transport.NewStreamHandlerSpec(streamHandlerFunc(echoStream)))
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := outbound.Call(ctx, newTestUnaryRequest("hello"))
+	assert.Error(t, err)
+
+	_, err = outbound.CallOneway(ctx, newTestUnaryRequest("hello"))
+	assert.Error(t, err)
+}
+
+func TestCallOneway(t *testing.T) {
+	received := make(chan string, 1)
+	outbound, stop := startTestOutbound(t, transport.NewOnewayHandlerSpec(onewayHandlerFunc(
+		func(ctx context.Context, req *transport.Request) error {
+			body, err := ioutil.ReadAll(req.Body)
+			received <- string(body)
+			return err
+		},
+	)))
+	defer stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ack, err := outbound.CallOneway(ctx, newTestUnaryRequest("hello"))
+	require.NoError(t, err)
+	assert.NotNil(t, ack)
+
+	// The handler must not be affected by the context of the caller.
+	cancel()
+	select {
+	case body := <-received:
+		assert.Equal(t, "hello", body)
+	case <-time.After(time.Second):
+		t.Fatal("oneway handler was not called")
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"go.uber.org/yarpc/api/transport"
+)
+
+var (
+	errStreamClosed = errors.New("cannot send messages after the stream was closed")
+
+	_ transport.ClientStream = (*clientStream)(nil)
+	_ transport.Stream       = (*serverStream)(nil)
+)
+
+// streamPipe is the state shared by both ends of an in-memory stream.
+//
+// Messages are exchanged over unbuffered channels so that a sender blocks
+// until the other side has received its message.
+type streamPipe struct {
+	request *transport.Request
+
+	toServer chan *transport.StreamMessage
+	toClient chan *transport.StreamMessage
+
+	// clientDone is closed when the client has finished sending messages.
+	clientDone     chan struct{}
+	clientDoneOnce sync.Once
+
+	// serverDone is closed after the handler has returned. serverErr holds
+	// the error returned by the handler and may only be read after
+	// serverDone is closed.
+	serverDone chan struct{}
+	serverErr  error
+}
+
+func newStreamPair(ctx context.Context, request *transport.Request) (*clientStream, *serverStream) {
+	// Strip the body; streams exchange their payloads as messages.
+	req := *request
+	req.Body = nil
+
+	p := &streamPipe{
+		request:    &req,
+		toServer:   make(chan *transport.StreamMessage),
+		toClient:   make(chan *transport.StreamMessage),
+		clientDone: make(chan struct{}),
+		serverDone: make(chan struct{}),
+	}
+	serverCtx, cancel := context.WithCancel(ctx)
+	return &clientStream{ctx: ctx, pipe: p}, &serverStream{ctx: serverCtx, cancel: cancel, pipe: p}
+}
+
+// clientStream is the end of an in-memory stream held by the caller.
+type clientStream struct {
+	ctx  context.Context
+	pipe *streamPipe
+}
+
+func (s *clientStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *clientStream) Request() *transport.Request {
+	return s.pipe.request
+}
+
+func (s *clientStream) SendMessage(msg *transport.StreamMessage) error {
+	select {
+	case <-s.pipe.clientDone:
+		return errStreamClosed
+	default:
+	}
+
+	select {
+	case s.pipe.toServer <- msg:
+		return nil
+	case <-s.pipe.serverDone:
+		return io.EOF
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+func (s *clientStream) ReceiveMessage() (*transport.StreamMessage, error) {
+	select {
+	case msg := <-s.pipe.toClient:
+		return msg, nil
+	case <-s.pipe.serverDone:
+		if err := s.pipe.serverErr; err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	}
+}
+
+func (s *clientStream) Close() error {
+	s.pipe.clientDoneOnce.Do(func() { close(s.pipe.clientDone) })
+	return nil
+}
+
+// serverStream is the end of an in-memory stream given to the handler.
+type serverStream struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	pipe   *streamPipe
+}
+
+func (s *serverStream) handle(h transport.StreamHandler) {
+	defer s.cancel()
+	s.pipe.serverErr = transport.DispatchStreamHandler(h, s)
+	close(s.pipe.serverDone)
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *serverStream) Request() *transport.Request {
+	return s.pipe.request
+}
+
+func (s *serverStream) SendMessage(msg *transport.StreamMessage) error {
+	select {
+	case s.pipe.toClient <- msg:
+		return nil
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+func (s *serverStream) ReceiveMessage() (*transport.StreamMessage, error) {
+	select {
+	case msg := <-s.pipe.toServer:
+		return msg, nil
+	case <-s.pipe.clientDone:
+		return nil, io.EOF
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package inmemory
+
+import (
+	"fmt"
+	"sync"
+
+	"go.uber.org/yarpc/api/transport"
+	internalsync "go.uber.org/yarpc/internal/sync"
+)
+
+const transportName = "inmemory"
+
+var _ transport.Transport = (*Transport)(nil)
+
+// Transport connects in-memory inbounds and outbounds by service name.
+type Transport struct {
+	once internalsync.LifecycleOnce
+
+	lock     sync.RWMutex
+	inbounds map[string]*Inbound
+}
+
+// NewTransport returns a new in-memory Transport.
+func NewTransport() *Transport {
+	return &Transport{
+		once:     internalsync.Once(),
+		inbounds: make(map[string]*Inbound),
+	}
+}
+
+// Start implements transport.Lifecycle#Start.
+func (t *Transport) Start() error {
+	return t.once.Start(nil)
+}
+
+// Stop implements transport.Lifecycle#Stop.
+func (t *Transport) Stop() error {
+	return t.once.Stop(nil)
+}
+
+// IsRunning implements transport.Lifecycle#IsRunning.
+func (t *Transport) IsRunning() bool {
+	return t.once.IsRunning()
+}
+
+// NewInbound returns a new Inbound that receives requests sent to the given
+// service by Outbounds of this Transport.
+func (t *Transport) NewInbound(service string) *Inbound {
+	return newInbound(t, service)
+}
+
+// NewOutbound returns a new Outbound that sends requests to the Inbound of
+// this Transport for the given service.
+func (t *Transport) NewOutbound(service string) *Outbound {
+	return newOutbound(t, service)
+}
+
+func (t *Transport) register(i *Inbound) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if _, ok := t.inbounds[i.service]; ok {
+		return fmt.Errorf("an inbound for service %q is already running", i.service)
+	}
+	t.inbounds[i.service] = i
+	return nil
+}
+
+func (t *Transport) unregister(i *Inbound) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.inbounds[i.service] == i {
+		delete(t.inbounds, i.service)
+	}
+}
+
+func (t *Transport) getInbound(service string) (*Inbound, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	i, ok := t.inbounds[service]
+	if !ok {
+		return nil, fmt.Errorf("no inbound is running for service %q", service)
+	}
+	return i, nil
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package inmemory
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/errors"
+	"go.uber.org/yarpc/yarpcerrors"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+var _ transport.ResponseWriter = (*responseWriter)(nil)
+
+// responseWriter buffers the response written by a unary handler.
+type responseWriter struct {
+	headers          transport.Headers
+	body             bytes.Buffer
+	applicationError bool
+}
+
+func newResponseWriter() *responseWriter {
+	return &responseWriter{headers: transport.NewHeaders()}
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *responseWriter) AddHeaders(h transport.Headers) {
+	for k, v := range h.Items() {
+		w.headers = w.headers.With(k, v)
+	}
+}
+
+func (w *responseWriter) SetApplicationError() {
+	w.applicationError = true
+}
+
+func (w *responseWriter) response() *transport.Response {
+	return &transport.Response{
+		Headers:          w.headers,
+		Body:             ioutil.NopCloser(&w.body),
+		ApplicationError: w.applicationError,
+	}
+}
+
+// newHandlerContext returns the context with which the handler of a unary
+// request made with the given context is called. Like the context of a
+// request received over the network, it carries the deadline and the tracing
+// span of ctx but none of its other values.
+func newHandlerContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	hctx := opentracing.ContextWithSpan(context.Background(), opentracing.SpanFromContext(ctx))
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(hctx, deadline)
+	}
+	return context.WithCancel(hctx)
+}
+
+// copyHeaders returns a copy of the given headers.
+func copyHeaders(h transport.Headers) transport.Headers {
+	if h.Len() == 0 {
+		return h
+	}
+	headers := transport.NewHeadersWithCapacity(h.Len())
+	for k, v := range h.Items() {
+		headers = headers.With(k, v)
+	}
+	return headers
+}
+
+// toStatus converts an error returned while handling a request into the
+// yarpcerrors.Status that the caller would receive over the network.
+func toStatus(req *transport.Request, err error) error {
+	if !yarpcerrors.IsStatus(err) {
+		err = errors.AsHandlerError(req.Service, req.Procedure, err)
+	}
+	return yarpcerrors.FromError(err)
+}
@@ -32,6 +32,9 @@ type Client interface {
 
 	// LPush adds item to the queue
 	LPush(queue string, item []byte) error
+	// RPush adds item to the head of the queue, so that it is the next
+	// item to be popped.
+	RPush(queue string, item []byte) error
 	// This MUST return an error if the blocking call does not receive an item
 	// BRPopLPush moves an item from the primary queue into a processing list.
 	// within the timeout.
@@ -39,9 +42,52 @@ type Client interface {
 	// LRem removes one item from the queue key
 	LRem(queue string, item []byte) error
 
+	// BRPop removes the last item from the list, waiting for up to the
+	// timeout for an item to arrive. This MUST return a nil item and no
+	// error if no item arrived within the timeout.
+	BRPop(key string, timeout time.Duration) ([]byte, error)
+	// Expire deletes the key after the given duration.
+	Expire(key string, ttl time.Duration) error
+	// Del deletes the key.
+	Del(key string) error
+
+	// ZAdd adds the item to the sorted set with the given score.
+	ZAdd(key string, score float64, item []byte) error
+	// ZPopByScoreLPush atomically moves up to count items with a score of at
+	// most max from the sorted set to the head of the list, lowest scores
+	// first, and returns the number of items it moved.
+	ZPopByScoreLPush(from, to string, max float64, count int64) (int64, error)
+	// ZRem removes the item from the sorted set.
+	ZRem(key string, item []byte) error
+
+	// Lease atomically moves the item from the unleased list to the head of
+	// the to list and gives it a lease with the given score in the sorted
+	// set of leases. The item is leased even if it is no longer in the
+	// unleased list.
+	Lease(leases, unleased, to string, item []byte, lease float64) error
+	// RecoverExpired atomically moves up to count items whose lease in the
+	// sorted set of leases has a score of at most max from the from list
+	// to the head of the to list, and returns the number of items it moved.
+	// Expired leases are removed whether or not their item is still in the
+	// from list. Items left in the unleased list are moved to the from list
+	// and given a lease with the score lease.
+	RecoverExpired(leases, unleased, from, to string, max, lease float64, count int64) (int64, error)
+
 	// Endpoint returns the enpoint configured for this client.
 	Endpoint() string
 
 	// ConnectionState returns the status of the connection(s).
 	ConnectionState() string
 }
+
+// startClient starts the client, making up to the given number of attempts
+// with the given delay between them.
+func startClient(client Client, attempts int, delay time.Duration) error {
+	for attempt := 1; ; attempt++ {
+		err := client.Start()
+		if err == nil || attempt >= attempts {
+			return err
+		}
+		time.Sleep(delay)
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/yarpc/api/transport"
+	intsync "go.uber.org/yarpc/internal/sync"
+	"go.uber.org/yarpc/x/config"
+
+	"github.com/uber-go/tally"
+)
+
+const defaultTimeout = time.Second
+
+// TransportSpecOption configures the Redis TransportSpec.
+type TransportSpecOption func(*transportSpec)
+
+// newClient overrides the constructor of Redis clients for testing.
+func newClient(f func(addr string) Client) TransportSpecOption {
+	return func(ts *transportSpec) {
+		ts.newClient = f
+	}
+}
+
+// Metrics configures the scope to which Redis inbounds built from
+// configuration report failed, redelivered, dead-lettered, dropped,
+// recovered and scheduled requests.
+func Metrics(scope tally.Scope) TransportSpecOption {
+	return func(ts *transportSpec) {
+		ts.metrics = scope
+	}
+}
+
+// TransportSpec builds a TransportSpec for the Redis transport.
+//
+// 	configurator.MustRegisterTransport(redis.TransportSpec())
+//
+// See InboundConfig and OutboundConfig for details on the different
+// configuration parameters supported by this Transport.
+func TransportSpec(opts ...TransportSpecOption) config.TransportSpec {
+	ts := transportSpec{newClient: NewRedis5Client, metrics: tally.NoopScope}
+	for _, opt := range opts {
+		opt(&ts)
+	}
+	return ts.Spec()
+}
+
+// transportSpec holds the configurable parts of the Redis TransportSpec.
+type transportSpec struct {
+	// This is used to override the Redis client constructor for testing.
+	newClient func(addr string) Client
+
+	metrics tally.Scope
+}
+
+func (ts *transportSpec) Spec() config.TransportSpec {
+	return config.TransportSpec{
+		Name:                transportName,
+		BuildTransport:      ts.buildTransport,
+		BuildInbound:        ts.buildInbound,
+		BuildUnaryOutbound:  ts.buildUnaryOutbound,
+		BuildOnewayOutbound: ts.buildOnewayOutbound,
+	}
+}
+
+// TransportConfig configures the shared Redis transport. Every Redis inbound
+// and outbound maintains its own connections to Redis, so the transport has
+// no parameters at this time.
+type TransportConfig struct{}
+
+func (ts *transportSpec) buildTransport(*TransportConfig, *config.Kit) (transport.Transport, error) {
+	// The transport holds no resources.
+	return intsync.NewNopLifecycle(), nil
+}
+
+// InboundConfig configures a Redis inbound.
+//
+// 	inbounds:
+// 	  redis:
+// 	    address: 127.0.0.1:6379
+// 	    queueKey: myservice/queue
+// 	    processingKey: myservice/processing
+//
+// The address, queueKey, and processingKey attributes are required.
+type InboundConfig struct {
+	// Address of the Redis server.
+	Address string `config:"address,interpolate"`
+
+	// Key of the list from which requests are read.
+	QueueKey string `config:"queueKey,interpolate"`
+
+	// Key of the list in which requests are kept while they are being
+	// processed.
+	ProcessingKey string `config:"processingKey,interpolate"`
+
+	// How long the inbound waits for a request to arrive in the queue before
+	// trying again.
+	//
+	// 	timeout: 5s
+	//
+	// Defaults to 1 second.
+	Timeout time.Duration `config:"timeout"`
+
+	// Number of times the inbound tries to connect to Redis when it starts,
+	// and how long it waits between attempts.
+	//
+	// 	connectAttempts: 10
+	// 	connectRetryDelay: 100ms
+	//
+	// Defaults to 100 attempts, 10 milliseconds apart.
+	ConnectAttempts   int           `config:"connectAttempts"`
+	ConnectRetryDelay time.Duration `config:"connectRetryDelay"`
+
+	// Number of times the inbound attempts to handle a oneway request
+	// before giving up on it.
+	//
+	// 	maxAttempts: 3
+	//
+	// Defaults to a single attempt.
+	MaxAttempts int `config:"maxAttempts"`
+
+	// Key of the list to which oneway requests that ran out of attempts are
+	// moved. If unset, such requests are dropped.
+	//
+	// 	deadLetterKey: myservice/dead
+	DeadLetterKey string `config:"deadLetterKey,interpolate"`
+
+	// How often the inbound moves delayed requests which are due to the
+	// queue.
+	//
+	// 	schedulePollInterval: 1s
+	//
+	// Defaults to 100 milliseconds.
+	SchedulePollInterval time.Duration `config:"schedulePollInterval"`
+
+	// How long a request read from the queue is leased to the inbound before
+	// other inbounds sharing the processing key may move it back to the
+	// queue. Requests whose handlers take longer than this may be handled
+	// more than once.
+	//
+	// 	visibilityTimeout: 1m
+	//
+	// Defaults to 5 minutes.
+	VisibilityTimeout time.Duration `config:"visibilityTimeout"`
+}
+
+func (ts *transportSpec) buildInbound(ic *InboundConfig, t transport.Transport, kit *config.Kit) (transport.Inbound, error) {
+	if ic.Address == "" {
+		return nil, errors.New("inbound address is required")
+	}
+	if ic.QueueKey == "" {
+		return nil, errors.New("inbound queueKey is required")
+	}
+	if ic.ProcessingKey == "" {
+		return nil, errors.New("inbound processingKey is required")
+	}
+	if ic.QueueKey == ic.ProcessingKey {
+		return nil, fmt.Errorf("inbound queueKey and processingKey must differ: %q", ic.QueueKey)
+	}
+	if ic.DeadLetterKey != "" && (ic.DeadLetterKey == ic.QueueKey || ic.DeadLetterKey == ic.ProcessingKey) {
+		return nil, fmt.Errorf("inbound deadLetterKey must differ from queueKey and processingKey: %q", ic.DeadLetterKey)
+	}
+
+	timeout := ic.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+
+	i := NewInbound(ts.newClient(ic.Address), ic.QueueKey, ic.ProcessingKey, timeout).
+		WithDeadLetterKey(ic.DeadLetterKey).
+		WithMetrics(ts.metrics)
+	if ic.MaxAttempts > 0 {
+		i.maxAttempts = ic.MaxAttempts
+	}
+	if ic.SchedulePollInterval > 0 {
+		i.schedulePollInterval = ic.SchedulePollInterval
+	}
+	if ic.VisibilityTimeout > 0 {
+		i.visibilityTimeout = ic.VisibilityTimeout
+	}
+	if ic.ConnectAttempts > 0 {
+		i.connectAttempts = ic.ConnectAttempts
+	}
+	if ic.ConnectRetryDelay > 0 {
+		i.connectRetryDelay = ic.ConnectRetryDelay
+	}
+	return i, nil
+}
+
+// OutboundConfig configures a Redis outbound for unary and oneway requests.
+//
+// 	outbounds:
+// 	  myservice:
+// 	    redis:
+// 	      address: 127.0.0.1:6379
+// 	      queueKey: myservice/queue
+//
+// The address and queueKey attributes are required.
+type OutboundConfig struct {
+	// Address of the Redis server.
+	Address string `config:"address,interpolate"`
+
+	// Key of the list to which requests are written.
+	QueueKey string `config:"queueKey,interpolate"`
+
+	// Number of times the outbound tries to connect to Redis when it starts,
+	// and how long it waits between attempts.
+	//
+	// 	connectAttempts: 10
+	// 	connectRetryDelay: 100ms
+	//
+	// Defaults to a single attempt.
+	ConnectAttempts   int           `config:"connectAttempts"`
+	ConnectRetryDelay time.Duration `config:"connectRetryDelay"`
+}
+
+func (ts *transportSpec) buildUnaryOutbound(oc *OutboundConfig, t transport.Transport, kit *config.Kit) (transport.UnaryOutbound, error) {
+	o, err := ts.buildOutbound(oc)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (ts *transportSpec) buildOnewayOutbound(oc *OutboundConfig, t transport.Transport, kit *config.Kit) (transport.OnewayOutbound, error) {
+	o, err := ts.buildOutbound(oc)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (ts *transportSpec) buildOutbound(oc *OutboundConfig) (*Outbound, error) {
+	if oc.Address == "" {
+		return nil, errors.New("outbound address is required")
+	}
+	if oc.QueueKey == "" {
+		return nil, errors.New("outbound queueKey is required")
+	}
+
+	o := NewOutbound(ts.newClient(oc.Address), oc.QueueKey)
+	if oc.ConnectAttempts > 0 {
+		o.connectAttempts = oc.ConnectAttempts
+	}
+	if oc.ConnectRetryDelay > 0 {
+		o.connectRetryDelay = oc.ConnectRetryDelay
+	}
+	return o, nil
+}
@@ -0,0 +1,297 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/yarpc/transport/x/redis/redistest"
+	"go.uber.org/yarpc/x/config"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransportSpec(t *testing.T) {
+	type attrs map[string]interface{}
+
+	type wantInbound struct {
+		Address              string
+		QueueKey             string
+		ProcessingKey        string
+		Timeout              time.Duration
+		ConnectAttempts      int
+		ConnectRetryDelay    time.Duration
+		MaxAttempts          int
+		DeadLetterKey        string
+		SchedulePollInterval time.Duration
+		VisibilityTimeout    time.Duration
+	}
+
+	type wantOutbound struct {
+		Unary             bool // whether only a unary outbound is expected
+		Address           string
+		QueueKey          string
+		ConnectAttempts   int
+		ConnectRetryDelay time.Duration
+	}
+
+	tests := []struct {
+		desc          string
+		inboundCfg    attrs
+		outboundCfg   attrs
+		env           map[string]string
+		wantInbound   *wantInbound
+		wantOutbounds map[string]wantOutbound
+		wantErrors    []string
+	}{
+		{
+			desc: "simple inbound",
+			inboundCfg: attrs{
+				"address":       "127.0.0.1:6379",
+				"queueKey":      "queue",
+				"processingKey": "processing",
+			},
+			wantInbound: &wantInbound{
+				Address:              "127.0.0.1:6379",
+				QueueKey:             "queue",
+				ProcessingKey:        "processing",
+				Timeout:              time.Second,
+				ConnectAttempts:      100,
+				ConnectRetryDelay:    10 * time.Millisecond,
+				MaxAttempts:          1,
+				SchedulePollInterval: 100 * time.Millisecond,
+				VisibilityTimeout:    5 * time.Minute,
+			},
+		},
+		{
+			desc: "inbound with all attributes",
+			inboundCfg: attrs{
+				"address":              "${REDIS_HOST}:6379",
+				"queueKey":             "${SERVICE}/queue",
+				"processingKey":        "${SERVICE}/processing",
+				"timeout":              "5s",
+				"connectAttempts":      3,
+				"connectRetryDelay":    "100ms",
+				"maxAttempts":          5,
+				"deadLetterKey":        "${SERVICE}/dead",
+				"schedulePollInterval": "1s",
+				"visibilityTimeout":    "1m",
+			},
+			env: map[string]string{"REDIS_HOST": "redis.local", "SERVICE": "myservice"},
+			wantInbound: &wantInbound{
+				Address:              "redis.local:6379",
+				QueueKey:             "myservice/queue",
+				ProcessingKey:        "myservice/processing",
+				Timeout:              5 * time.Second,
+				ConnectAttempts:      3,
+				ConnectRetryDelay:    100 * time.Millisecond,
+				MaxAttempts:          5,
+				DeadLetterKey:        "myservice/dead",
+				SchedulePollInterval: time.Second,
+				VisibilityTimeout:    time.Minute,
+			},
+		},
+		{
+			desc:       "inbound without address",
+			inboundCfg: attrs{"queueKey": "queue", "processingKey": "processing"},
+			wantErrors: []string{"inbound address is required"},
+		},
+		{
+			desc:       "inbound without queueKey",
+			inboundCfg: attrs{"address": "127.0.0.1:6379", "processingKey": "processing"},
+			wantErrors: []string{"inbound queueKey is required"},
+		},
+		{
+			desc:       "inbound without processingKey",
+			inboundCfg: attrs{"address": "127.0.0.1:6379", "queueKey": "queue"},
+			wantErrors: []string{"inbound processingKey is required"},
+		},
+		{
+			desc: "inbound with the same queue and processing keys",
+			inboundCfg: attrs{
+				"address":       "127.0.0.1:6379",
+				"queueKey":      "queue",
+				"processingKey": "queue",
+			},
+			wantErrors: []string{`inbound queueKey and processingKey must differ: "queue"`},
+		},
+		{
+			desc: "inbound with the same dead-letter and processing keys",
+			inboundCfg: attrs{
+				"address":       "127.0.0.1:6379",
+				"queueKey":      "queue",
+				"processingKey": "processing",
+				"deadLetterKey": "processing",
+			},
+			wantErrors: []string{`inbound deadLetterKey must differ from queueKey and processingKey: "processing"`},
+		},
+		{
+			desc: "simple outbound",
+			outboundCfg: attrs{
+				"myservice": attrs{
+					transportName: attrs{"address": "127.0.0.1:6379", "queueKey": "queue"},
+				},
+			},
+			wantOutbounds: map[string]wantOutbound{
+				"myservice": {
+					Address:           "127.0.0.1:6379",
+					QueueKey:          "queue",
+					ConnectAttempts:   1,
+					ConnectRetryDelay: 10 * time.Millisecond,
+				},
+			},
+		},
+		{
+			desc: "outbound with connect retries",
+			outboundCfg: attrs{
+				"myservice": attrs{
+					transportName: attrs{
+						"address":         "127.0.0.1:6379",
+						"queueKey":        "queue",
+						"connectAttempts": 5,
+					},
+				},
+			},
+			wantOutbounds: map[string]wantOutbound{
+				"myservice": {
+					Address:           "127.0.0.1:6379",
+					QueueKey:          "queue",
+					ConnectAttempts:   5,
+					ConnectRetryDelay: 10 * time.Millisecond,
+				},
+			},
+		},
+		{
+			desc: "outbound without address",
+			outboundCfg: attrs{
+				"myservice": attrs{transportName: attrs{"queueKey": "queue"}},
+			},
+			wantErrors: []string{"outbound address is required"},
+		},
+		{
+			desc: "outbound without queueKey",
+			outboundCfg: attrs{
+				"myservice": attrs{transportName: attrs{"address": "127.0.0.1:6379"}},
+			},
+			wantErrors: []string{"outbound queueKey is required"},
+		},
+		{
+			desc: "unary outbound",
+			outboundCfg: attrs{
+				"myservice": attrs{
+					"unary": attrs{
+						transportName: attrs{"address": "127.0.0.1:6379", "queueKey": "queue"},
+					},
+				},
+			},
+			wantOutbounds: map[string]wantOutbound{
+				"myservice": {
+					Unary:             true,
+					Address:           "127.0.0.1:6379",
+					QueueKey:          "queue",
+					ConnectAttempts:   1,
+					ConnectRetryDelay: 10 * time.Millisecond,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			clients := make(map[Client]string)
+			spec := TransportSpec(newClient(func(addr string) Client {
+				client := redistest.NewMockClient(mockCtrl)
+				clients[client] = addr
+				return client
+			}))
+
+			configurator := config.New(config.InterpolationResolver(mapResolver(tt.env)))
+			require.NoError(t, configurator.RegisterTransport(spec))
+
+			cfgData := make(attrs)
+			if tt.inboundCfg != nil {
+				cfgData["inbounds"] = attrs{transportName: tt.inboundCfg}
+			}
+			if tt.outboundCfg != nil {
+				cfgData["outbounds"] = tt.outboundCfg
+			}
+			cfg, err := configurator.LoadConfig("foo", cfgData)
+			if len(tt.wantErrors) > 0 {
+				require.Error(t, err, "expected failure")
+				for _, msg := range tt.wantErrors {
+					assert.Contains(t, err.Error(), msg)
+				}
+				return
+			}
+			require.NoError(t, err, "expected success")
+
+			if want := tt.wantInbound; want != nil {
+				require.Len(t, cfg.Inbounds, 1, "expected exactly one inbound")
+				ib, ok := cfg.Inbounds[0].(*Inbound)
+				require.True(t, ok, "expected *Inbound, got %T", cfg.Inbounds[0])
+				assert.Equal(t, want.Address, clients[ib.client], "address must match")
+				assert.Equal(t, want.QueueKey, ib.queueKey, "queue key must match")
+				assert.Equal(t, want.ProcessingKey, ib.processingKey, "processing key must match")
+				assert.Equal(t, want.Timeout, ib.timeout, "timeout must match")
+				assert.Equal(t, want.ConnectAttempts, ib.connectAttempts, "connect attempts must match")
+				assert.Equal(t, want.ConnectRetryDelay, ib.connectRetryDelay, "connect retry delay must match")
+				assert.Equal(t, want.MaxAttempts, ib.maxAttempts, "max attempts must match")
+				assert.Equal(t, want.DeadLetterKey, ib.deadLetterKey, "dead-letter key must match")
+				assert.Equal(t, want.SchedulePollInterval, ib.schedulePollInterval, "schedule poll interval must match")
+				assert.Equal(t, want.VisibilityTimeout, ib.visibilityTimeout, "visibility timeout must match")
+			}
+
+			for svc, want := range tt.wantOutbounds {
+				var ok bool
+				var ob *Outbound
+				if want.Unary {
+					assert.Nil(t, cfg.Outbounds[svc].Oneway, "expected no oneway outbound for %q", svc)
+					ob, ok = cfg.Outbounds[svc].Unary.(*Outbound)
+					require.True(t, ok, "expected *Outbound for %q, got %T", svc, cfg.Outbounds[svc].Unary)
+				} else {
+					_, ok = cfg.Outbounds[svc].Unary.(*Outbound)
+					assert.True(t, ok, "expected unary *Outbound for %q, got %T", svc, cfg.Outbounds[svc].Unary)
+					ob, ok = cfg.Outbounds[svc].Oneway.(*Outbound)
+					require.True(t, ok, "expected *Outbound for %q, got %T", svc, cfg.Outbounds[svc].Oneway)
+				}
+				assert.Equal(t, want.Address, clients[ob.client], "address must match")
+				assert.Equal(t, want.QueueKey, ob.queueKey, "queue key must match")
+				assert.Equal(t, want.ConnectAttempts, ob.connectAttempts, "connect attempts must match")
+				assert.Equal(t, want.ConnectRetryDelay, ob.connectRetryDelay, "connect retry delay must match")
+			}
+		})
+	}
+}
+
+func mapResolver(m map[string]string) func(string) (string, bool) {
+	return func(k string) (v string, ok bool) {
+		if m != nil {
+			v, ok = m[k]
+		}
+		return
+	}
+}
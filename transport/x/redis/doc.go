@@ -25,8 +25,22 @@
 //  - the outbound uses `LPUSH` to place an RPC at rest onto the redis list
 //    that's acting as a queue
 //  - the inbound uses the atomic `BRPOPLPUSH` operation to dequeue items and
-//    place them in a processing list
-//  - processing failure/success cause a permanent removal of an item
+//    place them in a list of unleased items next to the processing list,
+//    then atomically moves them to the processing list as it leases them
+//  - a oneway request that fails is pushed back onto the queue with its
+//    attempt incremented until it runs out of attempts, after which it is
+//    pushed onto the dead-letter list, if any; see Inbound.WithMaxAttempts
+//    and Inbound.WithDeadLetterKey
+//  - items in the processing list are leased to the inbound handling them
+//    for a visibility timeout, in a sorted set next to the processing list;
+//    inbounds move items whose lease expired back onto the queue, so several
+//    inbounds may share a processing list; see Inbound.WithVisibilityTimeout
+//  - oneway requests delayed with yarpc.WithDeliverAt or yarpc.WithDelay are
+//    added to a sorted set next to the queue, scored by when they are due;
+//    inbounds atomically move due items from the sorted set onto the queue
+//  - unary requests carry a reply key unique to the call; the inbound pushes
+//    the response onto that key, which expires with the deadline of the
+//    caller, and the outbound waits for it with `BRPOP`
 //
 // Sample usage:
 //
@@ -59,7 +73,30 @@
 //                  })
 //
 // From here, standard Oneway RPCs made from the client to 'some-service' will
-// be transported to the server through a Redis queue.
+// be transported to the server through a Redis queue. Unary RPCs are
+// supported by outbounds built with redis.NewOutbound and used as the Unary
+// outbound of 'some-service'.
+//
+// Inbounds and outbounds may also be declared with x/config after
+// registering the TransportSpec:
+//
+//   configurator.MustRegisterTransport(redis.TransportSpec())
+//
+//   inbounds:
+//     redis:
+//       address: 127.0.0.1:6379
+//       queueKey: my-queue-key
+//       processingKey: my-processing-key
+//       maxAttempts: 3
+//       deadLetterKey: my-dead-letter-key
+//
+//   outbounds:
+//     some-service:
+//       redis:
+//         address: 127.0.0.1:6379
+//         queueKey: my-queue-key
+//
+// See InboundConfig and OutboundConfig for all supported attributes.
 //
 // USE OF THIS PACKAGE SHOULD BE FOR EXPERIMENTAL PURPOSES ONLY.
 // BEHAVIOR IS EXPECTED TO CHANGE.
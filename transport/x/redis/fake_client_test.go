@@ -0,0 +1,273 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package redis
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"time"
+)
+
+var _ Client = (*fakeClient)(nil)
+
+// fakeClient is an in-memory Client which supports the list operations used
+// by the inbound and outbound.
+type fakeClient struct {
+	sync.Mutex
+
+	lists map[string][][]byte
+	ttls  map[string]time.Duration
+	zsets map[string]map[string]float64
+
+	// brpopTimeouts records the timeout of every BRPop.
+	brpopTimeouts []time.Duration
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{
+		lists: make(map[string][][]byte),
+		ttls:  make(map[string]time.Duration),
+		zsets: make(map[string]map[string]float64),
+	}
+}
+
+func (c *fakeClient) Start() error            { return nil }
+func (c *fakeClient) Stop() error             { return nil }
+func (c *fakeClient) IsRunning() bool         { return true }
+func (c *fakeClient) Endpoint() string        { return "fake" }
+func (c *fakeClient) ConnectionState() string { return "connected" }
+
+func (c *fakeClient) LPush(key string, item []byte) error {
+	c.Lock()
+	defer c.Unlock()
+	c.lists[key] = append([][]byte{item}, c.lists[key]...)
+	return nil
+}
+
+func (c *fakeClient) RPush(key string, item []byte) error {
+	c.Lock()
+	defer c.Unlock()
+	c.lists[key] = append(c.lists[key], item)
+	return nil
+}
+
+func (c *fakeClient) BRPopLPush(from, to string, timeout time.Duration) ([]byte, error) {
+	item := c.waitForItem(from, timeout)
+	if item == nil {
+		return nil, errors.New("no item found in queue")
+	}
+	return item, c.LPush(to, item)
+}
+
+func (c *fakeClient) BRPop(key string, timeout time.Duration) ([]byte, error) {
+	c.Lock()
+	c.brpopTimeouts = append(c.brpopTimeouts, timeout)
+	c.Unlock()
+	return c.waitForItem(key, timeout), nil
+}
+
+// waitForItem pops the last item of the list, waiting for up to the timeout
+// for one to arrive.
+func (c *fakeClient) waitForItem(key string, timeout time.Duration) []byte {
+	deadline := time.Now().Add(timeout)
+	for {
+		c.Lock()
+		if list := c.lists[key]; len(list) > 0 {
+			item := list[len(list)-1]
+			c.lists[key] = list[:len(list)-1]
+			c.Unlock()
+			return item
+		}
+		c.Unlock()
+
+		if time.Now().After(deadline) {
+			return nil
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (c *fakeClient) LRem(key string, item []byte) error {
+	c.Lock()
+	defer c.Unlock()
+	list := c.lists[key]
+	for i, v := range list {
+		if bytes.Equal(v, item) {
+			c.lists[key] = append(list[:i], list[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("could not remove item from queue")
+}
+
+func (c *fakeClient) Expire(key string, ttl time.Duration) error {
+	c.Lock()
+	defer c.Unlock()
+	c.ttls[key] = ttl
+	return nil
+}
+
+func (c *fakeClient) Del(key string) error {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.lists, key)
+	delete(c.ttls, key)
+	return nil
+}
+
+func (c *fakeClient) ZAdd(key string, score float64, item []byte) error {
+	c.Lock()
+	defer c.Unlock()
+	if c.zsets[key] == nil {
+		c.zsets[key] = make(map[string]float64)
+	}
+	c.zsets[key][string(item)] = score
+	return nil
+}
+
+func (c *fakeClient) ZPopByScoreLPush(from, to string, max float64, count int64) (int64, error) {
+	c.Lock()
+	defer c.Unlock()
+	zset := c.zsets[from]
+	var moved int64
+	for ; moved < count; moved++ {
+		// Find the item with the lowest score that is due.
+		var item string
+		found := false
+		for k, score := range zset {
+			if score <= max && (!found || score < zset[item]) {
+				item, found = k, true
+			}
+		}
+		if !found {
+			break
+		}
+		delete(zset, item)
+		c.lists[to] = append([][]byte{[]byte(item)}, c.lists[to]...)
+	}
+	return moved, nil
+}
+
+func (c *fakeClient) ZRem(key string, item []byte) error {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.zsets[key], string(item))
+	return nil
+}
+
+func (c *fakeClient) Lease(leases, unleased, to string, item []byte, lease float64) error {
+	c.Lock()
+	defer c.Unlock()
+	for i, v := range c.lists[unleased] {
+		if bytes.Equal(v, item) {
+			c.lists[unleased] = append(c.lists[unleased][:i], c.lists[unleased][i+1:]...)
+			c.lists[to] = append([][]byte{v}, c.lists[to]...)
+			break
+		}
+	}
+	if c.zsets[leases] == nil {
+		c.zsets[leases] = make(map[string]float64)
+	}
+	c.zsets[leases][string(item)] = lease
+	return nil
+}
+
+func (c *fakeClient) RecoverExpired(leases, unleased, from, to string, max, lease float64, count int64) (int64, error) {
+	c.Lock()
+	defer c.Unlock()
+	zset := c.zsets[leases]
+	if zset == nil {
+		zset = make(map[string]float64)
+		c.zsets[leases] = zset
+	}
+
+	var moved int64
+	for expired := int64(0); expired < count; expired++ {
+		// Find the item with the lease that expired first.
+		var item string
+		found := false
+		for k, score := range zset {
+			if score <= max && (!found || score < zset[item]) {
+				item, found = k, true
+			}
+		}
+		if !found {
+			break
+		}
+		delete(zset, item)
+
+		list := c.lists[from]
+		for i, v := range list {
+			if string(v) == item {
+				c.lists[from] = append(list[:i], list[i+1:]...)
+				c.lists[to] = append([][]byte{v}, c.lists[to]...)
+				moved++
+				break
+			}
+		}
+	}
+
+	for list := c.lists[unleased]; len(list) > 0; list = list[:len(list)-1] {
+		v := list[len(list)-1]
+		c.lists[from] = append([][]byte{v}, c.lists[from]...)
+		zset[string(v)] = lease
+	}
+	delete(c.lists, unleased)
+	return moved, nil
+}
+
+// zset returns the number of items in the sorted set stored at the given
+// key.
+func (c *fakeClient) zset(key string) int {
+	c.Lock()
+	defer c.Unlock()
+	return len(c.zsets[key])
+}
+
+// score returns the score of the item in the sorted set stored at the given
+// key, if any.
+func (c *fakeClient) score(key string, item []byte) (float64, bool) {
+	c.Lock()
+	defer c.Unlock()
+	score, ok := c.zsets[key][string(item)]
+	return score, ok
+}
+
+// list returns a copy of the list stored at the given key.
+func (c *fakeClient) list(key string) [][]byte {
+	c.Lock()
+	defer c.Unlock()
+	return append([][]byte(nil), c.lists[key]...)
+}
+
+// keys returns the keys of all non-empty lists.
+func (c *fakeClient) keys() []string {
+	c.Lock()
+	defer c.Unlock()
+	var keys []string
+	for k, v := range c.lists {
+		if len(v) > 0 {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
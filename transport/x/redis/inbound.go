@@ -30,20 +30,36 @@ import (
 	"go.uber.org/yarpc/internal/introspection"
 	"go.uber.org/yarpc/internal/sync"
 	"go.uber.org/yarpc/serialize"
+	"go.uber.org/yarpc/yarpcerrors"
 
 	"github.com/opentracing/opentracing-go"
+	"github.com/uber-go/tally"
+	"go.uber.org/atomic"
 	"go.uber.org/multierr"
 )
 
 const transportName = "redis"
 
-const maxConnectRetries = 100
+const defaultInboundConnectAttempts = 100
 
-var connectRetryDelay = 10 * time.Millisecond
+const defaultConnectRetryDelay = 10 * time.Millisecond
+
+const defaultVisibilityTimeout = 5 * time.Minute
 
 // Inbound is a redis inbound that reads from the given queueKey. This will
 // wait for an item in the queue or until the timout is reached before trying
 // to read again.
+//
+// Oneway requests that fail are put back in the queue until they run out of
+// attempts, after which they are moved to the dead-letter key, if any.
+//
+// Requests in the processing key are leased to the inbound handling them for
+// the visibility timeout. Requests whose lease expired, because the inbound
+// handling them stopped or is taking too long, are moved back to the queue,
+// so several inbounds may share a processing key.
+//
+// Delayed oneway requests wait in a sorted set next to the queue until they
+// are due, when an inbound moves them to the queue.
 type Inbound struct {
 	router transport.Router
 	tracer opentracing.Tracer
@@ -53,7 +69,18 @@ type Inbound struct {
 	queueKey      string
 	processingKey string
 
-	stop chan struct{}
+	maxAttempts   int
+	deadLetterKey string
+	metrics       inboundMetrics
+
+	schedulePollInterval time.Duration
+	visibilityTimeout    time.Duration
+
+	connectAttempts   int
+	connectRetryDelay time.Duration
+
+	stop     chan struct{}
+	draining atomic.Bool
 
 	once sync.LifecycleOnce
 }
@@ -73,6 +100,15 @@ func NewInbound(client Client, queueKey, processingKey string, timeout time.Dura
 		queueKey:      queueKey,
 		processingKey: processingKey,
 
+		maxAttempts: 1,
+		metrics:     newInboundMetrics(tally.NoopScope, queueKey),
+
+		schedulePollInterval: defaultSchedulePollInterval,
+		visibilityTimeout:    defaultVisibilityTimeout,
+
+		connectAttempts:   defaultInboundConnectAttempts,
+		connectRetryDelay: defaultConnectRetryDelay,
+
 		stop: make(chan struct{}),
 	}
 }
@@ -89,6 +125,60 @@ func (i *Inbound) WithTracer(tracer opentracing.Tracer) *Inbound {
 	return i
 }
 
+// WithConnectRetries configures how many times the inbound tries to connect
+// to redis when it starts, and how long it waits between attempts. By
+// default, the inbound makes up to 100 attempts, 10 milliseconds apart.
+func (i *Inbound) WithConnectRetries(attempts int, delay time.Duration) *Inbound {
+	i.connectAttempts = attempts
+	i.connectRetryDelay = delay
+	return i
+}
+
+// WithMaxAttempts configures how many times the inbound attempts to handle a
+// oneway request before giving up on it. By default, failed requests are not
+// attempted again.
+func (i *Inbound) WithMaxAttempts(attempts int) *Inbound {
+	i.maxAttempts = attempts
+	return i
+}
+
+// WithDeadLetterKey configures the key for the list to which the inbound
+// moves oneway requests that ran out of attempts. By default, such requests
+// are dropped.
+func (i *Inbound) WithDeadLetterKey(deadLetterKey string) *Inbound {
+	i.deadLetterKey = deadLetterKey
+	return i
+}
+
+// WithSchedulePollInterval configures how often the inbound moves delayed
+// requests which are due to the queue. By default, or if the interval is not
+// positive, the inbound checks for due requests every 100 milliseconds.
+func (i *Inbound) WithSchedulePollInterval(interval time.Duration) *Inbound {
+	if interval > 0 {
+		i.schedulePollInterval = interval
+	}
+	return i
+}
+
+// WithVisibilityTimeout configures how long a request read from the queue is
+// leased to the inbound before other inbounds sharing the processing key may
+// move it back to the queue. Requests whose handlers take longer than this
+// may be handled more than once. By default, or if the timeout is not
+// positive, requests are leased for 5 minutes.
+func (i *Inbound) WithVisibilityTimeout(timeout time.Duration) *Inbound {
+	if timeout > 0 {
+		i.visibilityTimeout = timeout
+	}
+	return i
+}
+
+// WithMetrics configures a scope to which the inbound reports failed,
+// redelivered, dead-lettered, dropped, recovered and scheduled requests.
+func (i *Inbound) WithMetrics(scope tally.Scope) *Inbound {
+	i.metrics = newInboundMetrics(scope, i.queueKey)
+	return i
+}
+
 // WithRouter configures a router to handle incoming requests,
 // as a chained method for convenience.
 func (i *Inbound) WithRouter(router transport.Router) *Inbound {
@@ -113,19 +203,17 @@ func (i *Inbound) start() error {
 		return errors.ErrNoRouter
 	}
 
-	var err error
-	for attempt := 0; attempt < maxConnectRetries; attempt++ {
-		err = i.client.Start()
-		if err == nil {
-			break
-		}
-		time.Sleep(connectRetryDelay)
+	if err := startClient(i.client, i.connectAttempts, i.connectRetryDelay); err != nil {
+		return err
 	}
-	if err != nil {
+
+	if err := i.recoverExpired(); err != nil {
 		return err
 	}
 
 	go i.startLoop()
+	go i.scheduleLoop()
+	go i.recoverLoop()
 	return nil
 }
 
@@ -135,12 +223,24 @@ func (i *Inbound) startLoop() {
 		case <-i.stop:
 			return
 		default:
+			if i.draining.Load() {
+				return
+			}
 			// TODO: log error
 			_ = i.handle()
 		}
 	}
 }
 
+// Drain makes the inbound stop reading new requests from the queue. The
+// request being handled, if any, is allowed to finish.
+//
+// This satisfies the transport.DrainableInbound interface, and would be
+// called by a dispatcher before it stops the inbound.
+func (i *Inbound) Drain() {
+	i.draining.Store(true)
+}
+
 // Stop ends the connection to redis
 func (i *Inbound) Stop() error {
 	return i.once.Stop(i.stopClient)
@@ -156,21 +256,64 @@ func (i *Inbound) IsRunning() bool {
 	return i.once.IsRunning()
 }
 
-func (i *Inbound) handle() (err error) {
+func (i *Inbound) handle() error {
 	// TODO: logging
-	item, err := i.client.BRPopLPush(i.queueKey, i.processingKey, i.timeout)
+	item, err := i.client.BRPopLPush(i.queueKey, unleasedKey(i.processingKey), i.timeout)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		err = multierr.Append(err, i.client.LRem(i.queueKey, item))
-	}()
+	if i.draining.Load() {
+		// The item was read after the inbound started draining. Put it
+		// back at the head of the queue so that another inbound handles it
+		// next.
+		return multierr.Append(
+			i.client.RPush(i.queueKey, item),
+			i.client.LRem(unleasedKey(i.processingKey), item),
+		)
+	}
 
+	// TODO: log error
+	// A request that could not be leased is moved to the processing key and
+	// leased by the next inbound that recovers expired requests.
+	_ = i.lease(item)
+
+	failed, err := i.handleItem(item)
+	if failed {
+		i.metrics.failures.Inc(1)
+		if retryErr := i.retry(item); retryErr != nil {
+			// Leave the request in the processing key so that it is
+			// recovered when its lease expires.
+			return multierr.Append(err, retryErr)
+		}
+	}
+	return multierr.Append(err, i.release(item))
+}
+
+// handleItem handles a request read from the queue, and reports whether it
+// was a oneway request that failed and should be retried. Unary requests are
+// never retried since their errors are replied to the caller.
+func (i *Inbound) handleItem(item []byte) (failed bool, err error) {
 	start := time.Now()
 
 	spanContext, req, err := serialize.FromBytes(i.tracer, item)
 	if err != nil {
-		return err
+		return true, err
+	}
+
+	replyKey, deadline, err := popReplyHeaders(req.Headers)
+	if err != nil {
+		return true, err
+	}
+	if _, err := popAttempt(req.Headers); err != nil {
+		return true, err
+	}
+	req.Headers.Del(scheduleIDHeader)
+
+	ctx := context.Background()
+	if !deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
 	}
 
 	extractOpenTracingSpan := transport.ExtractOpenTracingSpan{
@@ -179,26 +322,97 @@ func (i *Inbound) handle() (err error) {
 		TransportName:     transportName,
 		StartTime:         start,
 	}
-	ctx, span := extractOpenTracingSpan.Do(context.Background(), req)
+	ctx, span := extractOpenTracingSpan.Do(ctx, req)
 	defer span.Finish()
 
+	if replyKey != "" {
+		return false, transport.UpdateSpanWithErr(span, i.handleUnary(ctx, start, req, replyKey, deadline))
+	}
+
+	if err := i.dispatchOneway(ctx, req); err != nil {
+		return true, transport.UpdateSpanWithErr(span, err)
+	}
+	return false, nil
+}
+
+func (i *Inbound) dispatchOneway(ctx context.Context, req *transport.Request) error {
 	if err := transport.ValidateRequest(req); err != nil {
-		return transport.UpdateSpanWithErr(span, err)
+		return err
 	}
 
 	spec, err := i.router.Choose(ctx, req)
 	if err != nil {
-		return transport.UpdateSpanWithErr(span, err)
+		return err
 	}
 
 	if spec.Type() != transport.Oneway {
-		err = errors.UnsupportedTypeError{Transport: transportName, Type: spec.Type().String()}
-		return transport.UpdateSpanWithErr(span, err)
+		return errors.UnsupportedTypeError{Transport: transportName, Type: spec.Type().String()}
 	}
 
 	return transport.DispatchOnewayHandler(ctx, spec.Oneway(), req)
 }
 
+// handleUnary handles a unary request and writes its response, or the error
+// it failed with, to the reply key.
+func (i *Inbound) handleUnary(ctx context.Context, start time.Time, req *transport.Request, replyKey string, deadline time.Time) error {
+	if deadline.IsZero() {
+		return fmt.Errorf("unary request for procedure %q of service %q has no deadline", req.Procedure, req.Service)
+	}
+	if !start.Before(deadline) {
+		// The caller stopped waiting for the reply.
+		return yarpcerrors.Newf(yarpcerrors.CodeDeadlineExceeded,
+			"unary request for procedure %q of service %q expired before it was handled", req.Procedure, req.Service)
+	}
+
+	resw := newResponseWriter()
+	err := i.dispatchUnary(ctx, start, req, resw)
+	r := resw.reply()
+	if err != nil {
+		if !yarpcerrors.IsStatus(err) {
+			err = errors.AsHandlerError(req.Service, req.Procedure, err)
+		}
+		status := yarpcerrors.FromError(err)
+		r = &reply{ErrorCode: status.Code(), ErrorMessage: status.Message()}
+	}
+	return multierr.Append(err, i.writeReply(replyKey, deadline, r))
+}
+
+func (i *Inbound) dispatchUnary(ctx context.Context, start time.Time, req *transport.Request, resw transport.ResponseWriter) error {
+	if err := transport.ValidateRequest(req); err != nil {
+		return err
+	}
+
+	spec, err := i.router.Choose(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	if spec.Type() != transport.Unary {
+		return errors.UnsupportedTypeError{Transport: transportName, Type: spec.Type().String()}
+	}
+
+	return transport.DispatchUnaryHandler(ctx, spec.Unary(), start, req, resw)
+}
+
+// writeReply writes the reply to the reply key, which expires with the
+// deadline of the caller.
+func (i *Inbound) writeReply(replyKey string, deadline time.Time, r *reply) error {
+	ttl := deadline.Sub(time.Now())
+	if ttl <= 0 {
+		// The caller stopped waiting for the reply.
+		return nil
+	}
+
+	item, err := replyToBytes(r)
+	if err != nil {
+		return err
+	}
+	if err := i.client.LPush(replyKey, item); err != nil {
+		return err
+	}
+	return i.client.Expire(replyKey, ttl)
+}
+
 // Introspect returns the state of the inbound for introspection purposes.
 func (i *Inbound) Introspect() introspection.InboundStatus {
 	return introspection.InboundStatus{
@@ -21,14 +21,25 @@
 package redis
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
 	"testing"
 	"time"
 
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/encoding/raw"
+	"go.uber.org/yarpc/serialize"
 	"go.uber.org/yarpc/transport/x/redis/redistest"
 
 	"github.com/golang/mock/gomock"
+	"github.com/opentracing/opentracing-go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally"
 )
 
 func TestOperationOrder(t *testing.T) {
@@ -39,8 +50,10 @@ func TestOperationOrder(t *testing.T) {
 	client := redistest.NewMockClient(mockCtrl)
 
 	gomock.InOrder(
-		client.EXPECT().BRPopLPush(queueKey, processingKey, timeout),
-		client.EXPECT().LRem(queueKey, gomock.Any()),
+		client.EXPECT().BRPopLPush(queueKey, processingKey+":unleased", timeout),
+		client.EXPECT().Lease(processingKey+":leases", processingKey+":unleased", processingKey, gomock.Any(), gomock.Any()),
+		client.EXPECT().LRem(processingKey, gomock.Any()),
+		client.EXPECT().ZRem(processingKey+":leases", gomock.Any()),
 	)
 
 	inbound := NewInbound(client, queueKey, processingKey, timeout)
@@ -54,3 +67,251 @@ func TestOperationOrder(t *testing.T) {
 	// number of messages handled.
 	inbound.handle()
 }
+
+func TestDrainRequeues(t *testing.T) {
+	queueKey, processingKey := "queueKey", "processingKey"
+	timeout := time.Second
+	item := []byte("item")
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	client := redistest.NewMockClient(mockCtrl)
+
+	gomock.InOrder(
+		client.EXPECT().BRPopLPush(queueKey, processingKey+":unleased", timeout).Return(item, nil),
+		client.EXPECT().RPush(queueKey, item),
+		client.EXPECT().LRem(processingKey+":unleased", item),
+	)
+
+	inbound := NewInbound(client, queueKey, processingKey, timeout)
+	inbound.SetRouter(&transporttest.MockRouter{})
+	inbound.Drain()
+
+	assert.NoError(t, inbound.handle())
+}
+
+func TestStartRetriesExhausted(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	client := redistest.NewMockClient(mockCtrl)
+
+	client.EXPECT().Start().Return(errors.New("connection refused")).Times(3)
+
+	inbound := NewInbound(client, "queueKey", "processingKey", time.Millisecond).
+		WithConnectRetries(3, time.Millisecond)
+	inbound.SetRouter(&transporttest.MockRouter{})
+
+	assert.EqualError(t, inbound.Start(), "connection refused")
+}
+
+func TestZeroIntervalsUseDefaults(t *testing.T) {
+	client := newFakeClient()
+	inbound := NewInbound(client, "queue", "processing", time.Millisecond).
+		WithSchedulePollInterval(0).
+		WithVisibilityTimeout(0).
+		WithRouter(yarpc.NewMapRouter("service"))
+
+	assert.Equal(t, defaultSchedulePollInterval, inbound.schedulePollInterval, "schedule poll interval must be the default")
+	assert.Equal(t, defaultVisibilityTimeout, inbound.visibilityTimeout, "visibility timeout must be the default")
+
+	require.NoError(t, inbound.Start(), "inbound must start")
+	assert.NoError(t, inbound.Stop())
+}
+
+func TestRedelivery(t *testing.T) {
+	tests := []struct {
+		desc          string
+		maxAttempts   int
+		deadLetterKey string
+		failures      int // number of attempts that fail
+
+		wantAttempts    int
+		wantDeadLetters int
+		wantCounters    map[string]int64
+	}{
+		{
+			desc:         "single attempt",
+			failures:     1,
+			wantAttempts: 1,
+			wantCounters: map[string]int64{"failures": 1, "dropped": 1},
+		},
+		{
+			desc:         "success on retry",
+			maxAttempts:  3,
+			failures:     1,
+			wantAttempts: 2,
+			wantCounters: map[string]int64{"failures": 1, "redeliveries": 1},
+		},
+		{
+			desc:            "attempts exhausted",
+			maxAttempts:     3,
+			deadLetterKey:   "dead",
+			failures:        3,
+			wantAttempts:    3,
+			wantDeadLetters: 1,
+			wantCounters:    map[string]int64{"failures": 3, "redeliveries": 2, "dead_letters": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			client := newFakeClient()
+			scope := tally.NewTestScope("" /* prefix */, nil /* tags */)
+
+			var attempts int
+			router := yarpc.NewMapRouter("service")
+			router.Register([]transport.Procedure{{
+				Name: "hello",
+				HandlerSpec: transport.NewOnewayHandlerSpec(onewayHandlerFunc(
+					func(_ context.Context, req *transport.Request) error {
+						attempts++
+						if _, ok := req.Headers.Get(attemptHeader); ok {
+							return errors.New("attempt must not be visible to handlers")
+						}
+						if attempts <= tt.failures {
+							return errors.New("great sadness")
+						}
+						return nil
+					})),
+			}})
+
+			inbound := NewInbound(client, "queue", "processing", time.Millisecond).
+				WithDeadLetterKey(tt.deadLetterKey).
+				WithMetrics(scope).
+				WithRouter(router)
+			if tt.maxAttempts > 0 {
+				inbound.WithMaxAttempts(tt.maxAttempts)
+			}
+
+			item := newOnewayItem(t, "hello")
+			require.NoError(t, client.LPush("queue", item))
+			for j := 0; j < 10 && len(client.list("queue")) > 0; j++ {
+				inbound.handle()
+			}
+
+			assert.Equal(t, tt.wantAttempts, attempts, "number of attempts must match")
+			assert.Empty(t, client.list("queue"), "queue must be empty")
+			assert.Empty(t, client.list("processing"), "processing key must be empty")
+			if tt.wantDeadLetters > 0 {
+				deadLetters := client.list("dead")
+				require.Len(t, deadLetters, tt.wantDeadLetters, "number of dead letters must match")
+
+				_, req, err := serialize.FromBytes(opentracing.GlobalTracer(), deadLetters[0])
+				require.NoError(t, err)
+				attempt, _ := req.Headers.Get(attemptHeader)
+				assert.Equal(t, strconv.Itoa(tt.maxAttempts), attempt, "dead letter must hold the last attempt")
+			}
+			assert.Equal(t, tt.wantCounters, counters(scope), "metrics must match")
+		})
+	}
+}
+
+func TestUnreadableRequestIsDeadLettered(t *testing.T) {
+	client := newFakeClient()
+	scope := tally.NewTestScope("" /* prefix */, nil /* tags */)
+	inbound := NewInbound(client, "queue", "processing", time.Millisecond).
+		WithMaxAttempts(3).
+		WithDeadLetterKey("dead").
+		WithMetrics(scope).
+		WithRouter(yarpc.NewMapRouter("service"))
+
+	require.NoError(t, client.LPush("queue", []byte("garbage")))
+	assert.Error(t, inbound.handle())
+
+	assert.Equal(t, [][]byte{[]byte("garbage")}, client.list("dead"))
+	assert.Empty(t, client.list("queue"), "queue must be empty")
+	assert.Empty(t, client.list("processing"), "processing key must be empty")
+	assert.Equal(t, map[string]int64{"failures": 1, "dead_letters": 1}, counters(scope))
+}
+
+func TestStartRecoversProcessing(t *testing.T) {
+	client := newFakeClient()
+	scope := tally.NewTestScope("" /* prefix */, nil /* tags */)
+
+	handled := make(chan string, 2)
+	router := yarpc.NewMapRouter("service")
+	for _, name := range []string{"first", "second"} {
+		router.Register([]transport.Procedure{{
+			Name: name,
+			HandlerSpec: transport.NewOnewayHandlerSpec(onewayHandlerFunc(
+				func(_ context.Context, req *transport.Request) error {
+					handled <- req.Procedure
+					return nil
+				})),
+		}})
+	}
+
+	// An inbound that stopped while handling these requests left them in
+	// the processing key, oldest at the tail, and their leases expired in
+	// the same order.
+	for i, name := range []string{"first", "second"} {
+		item := newOnewayItem(t, name)
+		expired := scheduleScore(time.Now().Add(time.Duration(i-2) * time.Second))
+		require.NoError(t, client.LPush("processing", item))
+		require.NoError(t, client.ZAdd("processing:leases", expired, item))
+	}
+
+	// Another inbound sharing the processing key is handling this request.
+	busy := newOnewayItem(t, "busy")
+	busyLease := scheduleScore(time.Now().Add(time.Minute))
+	require.NoError(t, client.LPush("processing", busy))
+	require.NoError(t, client.ZAdd("processing:leases", busyLease, busy))
+
+	// An inbound stopped before it leased this request.
+	orphan := newOnewayItem(t, "orphan")
+	require.NoError(t, client.LPush("processing:unleased", orphan))
+
+	inbound := NewInbound(client, "queue", "processing", time.Millisecond).
+		WithMetrics(scope).
+		WithRouter(router)
+	require.NoError(t, inbound.Start())
+	defer inbound.Stop()
+
+	score, ok := client.score("processing:leases", busy)
+	assert.True(t, ok && score == busyLease, "leases which did not expire must be kept")
+	_, ok = client.score("processing:leases", orphan)
+	assert.True(t, ok, "requests without a lease must be given one")
+
+	for _, want := range []string{"first", "second"} {
+		select {
+		case got := <-handled:
+			assert.Equal(t, want, got, "recovered requests must be handled in order")
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for %q to be handled", want)
+		}
+	}
+	assert.Equal(t, int64(2), counters(scope)["recovered"], "recovered requests must be counted")
+	assert.Equal(t, [][]byte{orphan, busy}, client.list("processing"),
+		"requests which are leased must be left in the processing key")
+}
+
+// newOnewayItem serializes a oneway request for the given procedure as an
+// outbound would.
+func newOnewayItem(t *testing.T, procedure string) []byte {
+	item, err := serialize.ToBytes(opentracing.GlobalTracer(), nil, &transport.Request{
+		Caller:    "caller",
+		Service:   "service",
+		Encoding:  raw.Encoding,
+		Procedure: procedure,
+		Body:      bytes.NewReader([]byte("hello")),
+	})
+	require.NoError(t, err)
+	return item
+}
+
+// counters returns the non-zero counters reported to the scope by name.
+func counters(scope tally.TestScope) map[string]int64 {
+	got := make(map[string]int64)
+	for _, c := range scope.Snapshot().Counters() {
+		if c.Value() != 0 {
+			got[c.Name()] += c.Value()
+		}
+	}
+	return got
+}
+
+type onewayHandlerFunc func(context.Context, *transport.Request) error
+
+func (f onewayHandlerFunc) HandleOneway(ctx context.Context, req *transport.Request) error {
+	return f(ctx, req)
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package redis
+
+import "github.com/uber-go/tally"
+
+// inboundMetrics counts what happens to requests that an inbound failed to
+// handle, and to delayed requests.
+type inboundMetrics struct {
+	// failures counts oneway requests that failed.
+	failures tally.Counter
+	// redeliveries counts failed requests put back in the queue.
+	redeliveries tally.Counter
+	// deadLetters counts failed requests moved to the dead-letter key.
+	deadLetters tally.Counter
+	// dropped counts failed requests discarded because they had no attempts
+	// left and no dead-letter key is configured.
+	dropped tally.Counter
+	// recovered counts requests moved from the processing key back to the
+	// queue because their lease expired.
+	recovered tally.Counter
+	// scheduled counts delayed requests moved to the queue when they were
+	// due.
+	scheduled tally.Counter
+}
+
+func newInboundMetrics(scope tally.Scope, queueKey string) inboundMetrics {
+	scope = scope.Tagged(map[string]string{
+		"transport": transportName,
+		"queue":     queueKey,
+	})
+	return inboundMetrics{
+		failures:     scope.Counter("failures"),
+		redeliveries: scope.Counter("redeliveries"),
+		deadLetters:  scope.Counter("dead_letters"),
+		dropped:      scope.Counter("dropped"),
+		recovered:    scope.Counter("recovered"),
+		scheduled:    scope.Counter("scheduled"),
+	}
+}
@@ -26,38 +26,62 @@ import (
 	"time"
 
 	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/errors"
 	"go.uber.org/yarpc/internal/introspection"
 	"go.uber.org/yarpc/internal/sync"
 	"go.uber.org/yarpc/serialize"
+	"go.uber.org/yarpc/yarpcerrors"
 
 	"github.com/opentracing/opentracing-go"
 )
 
+// replyPollInterval is how long an outbound waits for a reply at a time
+// before it checks whether the call was canceled.
+const replyPollInterval = time.Second
+
 var (
+	_ transport.UnaryOutbound              = (*Outbound)(nil)
 	_ transport.OnewayOutbound             = (*Outbound)(nil)
 	_ introspection.IntrospectableOutbound = (*Outbound)(nil)
 )
 
-// Outbound is a redis OnewayOutbound that puts an RPC into the given queue key
+// Outbound is a redis UnaryOutbound and OnewayOutbound that puts an RPC into
+// the given queue key.
+//
+// Unary requests carry a reply key unique to the call, to which the inbound
+// writes the response.
 type Outbound struct {
 	client   Client
 	tracer   opentracing.Tracer
 	queueKey string
 
+	connectAttempts   int
+	connectRetryDelay time.Duration
+
 	once sync.LifecycleOnce
 }
 
-// NewOnewayOutbound creates a redis Outbound that satisfies transport.OnewayOutbound
+// NewOutbound creates a redis Outbound that satisfies transport.UnaryOutbound
+// and transport.OnewayOutbound.
 // queueKey - key for the queue in redis
-func NewOnewayOutbound(client Client, queueKey string) *Outbound {
+func NewOutbound(client Client, queueKey string) *Outbound {
 	return &Outbound{
 		once:     sync.Once(),
 		client:   client,
 		tracer:   opentracing.GlobalTracer(),
 		queueKey: queueKey,
+
+		connectAttempts:   1,
+		connectRetryDelay: defaultConnectRetryDelay,
 	}
 }
 
+// NewOnewayOutbound creates a redis Outbound that satisfies transport.OnewayOutbound
+// queueKey - key for the queue in redis
+func NewOnewayOutbound(client Client, queueKey string) *Outbound {
+	return NewOutbound(client, queueKey)
+}
+
 // Transports returns nil for now
 func (o *Outbound) Transports() []transport.Transport {
 	// TODO
@@ -70,9 +94,20 @@ func (o *Outbound) WithTracer(tracer opentracing.Tracer) *Outbound {
 	return o
 }
 
+// WithConnectRetries configures how many times the outbound tries to connect
+// to redis when it starts, and how long it waits between attempts. By
+// default, the outbound makes a single attempt.
+func (o *Outbound) WithConnectRetries(attempts int, delay time.Duration) *Outbound {
+	o.connectAttempts = attempts
+	o.connectRetryDelay = delay
+	return o
+}
+
 // Start creates connection to the redis instance
 func (o *Outbound) Start() error {
-	return o.once.Start(o.client.Start)
+	return o.once.Start(func() error {
+		return startClient(o.client, o.connectAttempts, o.connectRetryDelay)
+	})
 }
 
 // Stop stops the redis connection
@@ -85,7 +120,124 @@ func (o *Outbound) IsRunning() bool {
 	return o.once.IsRunning()
 }
 
-// CallOneway makes a oneway request using redis
+// Call makes a unary request using redis. The response is read from a reply
+// key unique to this call, which expires with the deadline of the call.
+//
+// Redis waits for replies in increments of whole seconds, so calls may fail
+// up to a second after their deadline or after their context is canceled.
+func (o *Outbound) Call(ctx context.Context, req *transport.Request) (*transport.Response, error) {
+	if err := o.once.WhenRunning(ctx); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil, yarpcerrors.Newf(yarpcerrors.CodeInvalidArgument,
+			"unary request for procedure %q of service %q has no deadline", req.Procedure, req.Service)
+	}
+
+	createOpenTracingSpan := transport.CreateOpenTracingSpan{
+		Tracer:        o.tracer,
+		TransportName: transportName,
+		StartTime:     start,
+	}
+	_, span := createOpenTracingSpan.Do(ctx, req)
+	defer span.Finish()
+
+	replyKey, err := newReplyKey(o.queueKey, req.Caller)
+	if err != nil {
+		return nil, transport.UpdateSpanWithErr(span, err)
+	}
+
+	treq := *req
+	treq.Headers = withReplyHeaders(req.Headers, replyKey, deadline)
+	marshalledRPC, err := serialize.ToBytes(o.tracer, span.Context(), &treq)
+	if err != nil {
+		return nil, transport.UpdateSpanWithErr(span, err)
+	}
+
+	if err := o.client.LPush(o.queueKey, marshalledRPC); err != nil {
+		return nil, transport.UpdateSpanWithErr(span, err)
+	}
+
+	item, err := o.waitForReply(ctx, replyKey, deadline)
+	if err == context.Canceled {
+		// Nobody is waiting for the reply anymore.
+		_ = o.client.Del(replyKey)
+		return nil, transport.UpdateSpanWithErr(span, err)
+	}
+	if err != nil {
+		return nil, transport.UpdateSpanWithErr(span, err)
+	}
+	if item == nil {
+		// The reply may still arrive. The inbound sets it to expire with the
+		// deadline but we delete it eagerly.
+		_ = o.client.Del(replyKey)
+		err := errors.ClientTimeoutError(req.Service, req.Procedure, deadline.Sub(start))
+		return nil, transport.UpdateSpanWithErr(span, err)
+	}
+
+	r, err := replyFromBytes(item)
+	if err != nil {
+		return nil, transport.UpdateSpanWithErr(span, err)
+	}
+	res, err := r.response()
+	if err != nil {
+		return nil, transport.UpdateSpanWithErr(span, err)
+	}
+	return res, nil
+}
+
+// waitForReply waits for the reply on the reply key until it arrives, the
+// deadline passes, or the context is canceled, in which case it returns
+// context.Canceled. The item is nil if no reply arrived before the deadline.
+//
+// Redis cannot be interrupted while it waits, so the outbound waits for up to
+// replyPollInterval at a time and checks the context in between. Redis only
+// waits for whole seconds and treats a timeout of zero as no timeout at all,
+// so the outbound may wait past the deadline; replies which arrive after it
+// are dropped.
+func (o *Outbound) waitForReply(ctx context.Context, replyKey string, deadline time.Time) ([]byte, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.Canceled {
+				return nil, ctx.Err()
+			}
+			return nil, nil
+		default:
+		}
+
+		timeout := deadline.Sub(time.Now())
+		if timeout <= 0 {
+			return nil, nil
+		}
+		if timeout > replyPollInterval {
+			timeout = replyPollInterval
+		}
+		item, err := o.client.BRPop(replyKey, roundUpToSecond(timeout))
+		if err != nil {
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, nil
+		}
+		if item != nil {
+			return item, nil
+		}
+	}
+}
+
+// roundUpToSecond rounds the timeout up to a whole number of seconds, the
+// finest timeout Redis supports for blocking commands.
+func roundUpToSecond(timeout time.Duration) time.Duration {
+	return (timeout + time.Second - 1) / time.Second * time.Second
+}
+
+// CallOneway makes a oneway request using redis. Requests delayed with
+// yarpc.WithDeliverAt or yarpc.WithDelay wait in a sorted set next to the
+// queue until they are due.
 func (o *Outbound) CallOneway(ctx context.Context, req *transport.Request) (transport.Ack, error) {
 	if err := o.once.WhenRunning(ctx); err != nil {
 		return nil, err
@@ -99,6 +251,11 @@ func (o *Outbound) CallOneway(ctx context.Context, req *transport.Request) (tran
 	_, span := createOpenTracingSpan.Do(ctx, req)
 	defer span.Finish()
 
+	deliverAt, delayed := transport.DeliverAtFromContext(ctx)
+	if delayed && deliverAt.After(time.Now()) {
+		return o.schedule(span, req, deliverAt)
+	}
+
 	marshalledRPC, err := serialize.ToBytes(o.tracer, span.Context(), req)
 	if err != nil {
 		return nil, transport.UpdateSpanWithErr(span, err)
@@ -114,6 +271,31 @@ func (o *Outbound) CallOneway(ctx context.Context, req *transport.Request) (tran
 	return ack, nil
 }
 
+// schedule places a oneway request in the sorted set of the queue, from
+// which an inbound moves it to the queue when it is due.
+func (o *Outbound) schedule(span opentracing.Span, req *transport.Request, deliverAt time.Time) (transport.Ack, error) {
+	headers, err := withScheduleID(req.Headers)
+	if err != nil {
+		return nil, transport.UpdateSpanWithErr(span, err)
+	}
+
+	treq := *req
+	treq.Headers = headers
+	marshalledRPC, err := serialize.ToBytes(o.tracer, span.Context(), &treq)
+	if err != nil {
+		return nil, transport.UpdateSpanWithErr(span, err)
+	}
+
+	err = o.client.ZAdd(scheduledKey(o.queueKey), scheduleScore(deliverAt), marshalledRPC)
+	ack := time.Now()
+
+	if err != nil {
+		return nil, transport.UpdateSpanWithErr(span, err)
+	}
+
+	return ack, nil
+}
+
 // Introspect returns basic status about this outbound.
 func (o *Outbound) Introspect() introspection.OutboundStatus {
 	return introspection.OutboundStatus{
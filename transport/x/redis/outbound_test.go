@@ -23,17 +23,25 @@ package redis
 import (
 	"bytes"
 	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"go.uber.org/yarpc"
 	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/encoding/raw"
+	"go.uber.org/yarpc/serialize"
 	"go.uber.org/yarpc/transport/x/redis/redistest"
+	"go.uber.org/yarpc/yarpcerrors"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally"
 )
 
 func TestCall(t *testing.T) {
@@ -165,3 +173,310 @@ func TestCallWithoutDeadline(t *testing.T) {
 	assert.Nil(t, ack, "ack not nil")
 	assert.Error(t, err, "made call")
 }
+
+func TestStartRetries(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	client := redistest.NewMockClient(mockCtrl)
+
+	gomock.InOrder(
+		client.EXPECT().Start().Return(errors.New("connection refused")).Times(2),
+		client.EXPECT().Start().Return(nil),
+	)
+
+	out := NewOnewayOutbound(client, "queueKey").WithConnectRetries(3, time.Millisecond)
+	assert.NoError(t, out.Start())
+}
+
+type unaryHandlerFunc func(context.Context, *transport.Request, transport.ResponseWriter) error
+
+func (f unaryHandlerFunc) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter) error {
+	return f(ctx, req, resw)
+}
+
+func TestUnaryCall(t *testing.T) {
+	echo := unaryHandlerFunc(func(ctx context.Context, req *transport.Request, resw transport.ResponseWriter) error {
+		if _, ok := req.Headers.Get(replyKeyHeader); ok {
+			return errors.New("reply key must not be visible to handlers")
+		}
+		resw.AddHeaders(req.Headers)
+		_, err := io.Copy(resw, req.Body)
+		return err
+	})
+
+	tests := []struct {
+		desc      string
+		procedure string
+		timeout   time.Duration
+
+		wantBody    string
+		wantHeaders transport.Headers
+		wantErr     error
+		wantErrCode yarpcerrors.Code
+	}{
+		{
+			desc:        "success",
+			procedure:   "echo",
+			timeout:     time.Second,
+			wantBody:    "hello",
+			wantHeaders: transport.NewHeaders().With("foo", "bar"),
+		},
+		{
+			desc:      "handler error",
+			procedure: "fail",
+			timeout:   time.Second,
+			wantErr:   yarpcerrors.Newf(yarpcerrors.CodeNotFound, "great sadness"),
+		},
+		{
+			desc:        "unknown procedure",
+			procedure:   "unknown",
+			timeout:     time.Second,
+			wantErrCode: yarpcerrors.CodeInvalidArgument,
+		},
+		{
+			desc:        "deadline exceeded",
+			procedure:   "sleep",
+			timeout:     50 * time.Millisecond,
+			wantErrCode: yarpcerrors.CodeDeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			client := newFakeClient()
+			handled := make(chan struct{})
+
+			router := yarpc.NewMapRouter("service")
+			router.Register([]transport.Procedure{
+				{Name: "echo", HandlerSpec: transport.NewUnaryHandlerSpec(echo)},
+				{
+					Name: "fail",
+					HandlerSpec: transport.NewUnaryHandlerSpec(unaryHandlerFunc(
+						func(context.Context, *transport.Request, transport.ResponseWriter) error {
+							return yarpcerrors.Newf(yarpcerrors.CodeNotFound, "great sadness")
+						})),
+				},
+				{
+					Name: "sleep",
+					HandlerSpec: transport.NewUnaryHandlerSpec(unaryHandlerFunc(
+						func(ctx context.Context, _ *transport.Request, _ transport.ResponseWriter) error {
+							defer close(handled)
+							time.Sleep(100 * time.Millisecond)
+							return nil
+						})),
+				},
+			})
+
+			inbound := NewInbound(client, "queue", "processing", 10*time.Millisecond)
+			inbound.SetRouter(router)
+			require.NoError(t, inbound.Start())
+			defer inbound.Stop()
+
+			out := NewOutbound(client, "queue")
+			require.NoError(t, out.Start())
+			defer out.Stop()
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			res, err := out.Call(ctx, &transport.Request{
+				Caller:    "caller",
+				Service:   "service",
+				Encoding:  raw.Encoding,
+				Procedure: tt.procedure,
+				Headers:   transport.NewHeaders().With("foo", "bar"),
+				Body:      bytes.NewReader([]byte("hello")),
+			})
+
+			switch {
+			case tt.wantErr != nil:
+				assert.Equal(t, tt.wantErr, err)
+			case tt.wantErrCode != yarpcerrors.CodeOK:
+				require.Error(t, err, "expected failure")
+				assert.Equal(t, tt.wantErrCode, yarpcerrors.FromError(err).Code(), "error code must match")
+			default:
+				require.NoError(t, err, "call failed")
+				body, err := ioutil.ReadAll(res.Body)
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantBody, string(body), "body must match")
+				assert.Equal(t, tt.wantHeaders, res.Headers, "headers must match")
+			}
+
+			if tt.procedure == "sleep" {
+				// The reply is dropped after the caller stopped waiting.
+				<-handled
+				time.Sleep(10 * time.Millisecond)
+			}
+			for _, key := range client.keys() {
+				assert.False(t, strings.Contains(key, ":reply:"), "reply key %q must not be left behind", key)
+			}
+		})
+	}
+}
+
+func TestUnaryCallReplyExpires(t *testing.T) {
+	client := newFakeClient()
+
+	router := yarpc.NewMapRouter("service")
+	router.Register([]transport.Procedure{{
+		Name: "hello",
+		HandlerSpec: transport.NewUnaryHandlerSpec(unaryHandlerFunc(
+			func(context.Context, *transport.Request, transport.ResponseWriter) error { return nil })),
+	}})
+	inbound := NewInbound(client, "queue", "processing", time.Millisecond)
+	inbound.SetRouter(router)
+
+	out := NewOutbound(client, "queue")
+	require.NoError(t, out.Start())
+	defer out.Stop()
+
+	// Place the request in the queue without waiting for the reply.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+	req := &transport.Request{
+		Caller:    "caller",
+		Service:   "service",
+		Encoding:  raw.Encoding,
+		Procedure: "hello",
+		Headers:   withReplyHeaders(transport.NewHeaders(), "queue:reply:caller:1", deadline),
+		Body:      bytes.NewReader(nil),
+	}
+	item, err := serialize.ToBytes(out.tracer, nil, req)
+	require.NoError(t, err)
+	require.NoError(t, client.LPush("queue", item))
+
+	inbound.handle()
+
+	assert.Len(t, client.list("queue:reply:caller:1"), 1, "expected a reply")
+	ttl := client.ttls["queue:reply:caller:1"]
+	assert.True(t, ttl > 0 && ttl <= time.Second, "reply must expire with the deadline, got TTL %v", ttl)
+}
+
+func TestUnaryCallShortDeadline(t *testing.T) {
+	client := newFakeClient()
+	out := NewOutbound(client, "queue")
+	require.NoError(t, out.Start())
+	defer out.Stop()
+
+	// No inbound reads the queue, so the reply never arrives.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := out.Call(ctx, &transport.Request{
+		Caller:    "caller",
+		Service:   "service",
+		Encoding:  raw.Encoding,
+		Procedure: "hello",
+		Body:      bytes.NewReader(nil),
+	})
+	require.Error(t, err, "expected failure")
+	assert.Equal(t, yarpcerrors.CodeDeadlineExceeded, yarpcerrors.FromError(err).Code(), "error code must match")
+
+	// Redis would wait forever for a timeout shorter than a second.
+	client.Lock()
+	defer client.Unlock()
+	require.NotEmpty(t, client.brpopTimeouts, "expected the outbound to wait for the reply")
+	for _, timeout := range client.brpopTimeouts {
+		assert.Equal(t, time.Second, timeout, "timeout must be rounded up to a second")
+	}
+}
+
+func TestUnaryCallCanceled(t *testing.T) {
+	client := newFakeClient()
+	out := NewOutbound(client, "queue")
+	require.NoError(t, out.Start())
+	defer out.Stop()
+
+	// No inbound reads the queue, so the reply never arrives.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := out.Call(ctx, &transport.Request{
+		Caller:    "caller",
+		Service:   "service",
+		Encoding:  raw.Encoding,
+		Procedure: "hello",
+		Body:      bytes.NewReader(nil),
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.True(t, time.Since(start) < 2*replyPollInterval, "call must stop waiting for the reply when canceled")
+	assert.Equal(t, []string{"queue"}, client.keys(), "only the request must be left")
+}
+
+func TestDelayedCallOneway(t *testing.T) {
+	client := newFakeClient()
+	scope := tally.NewTestScope("" /* prefix */, nil /* tags */)
+
+	handled := make(chan time.Time, 2)
+	router := yarpc.NewMapRouter("service")
+	router.Register([]transport.Procedure{{
+		Name: "remind",
+		HandlerSpec: transport.NewOnewayHandlerSpec(onewayHandlerFunc(
+			func(_ context.Context, req *transport.Request) error {
+				if _, ok := req.Headers.Get(scheduleIDHeader); ok {
+					return errors.New("schedule ID must not be visible to handlers")
+				}
+				handled <- time.Now()
+				return nil
+			})),
+	}})
+
+	out := NewOnewayOutbound(client, "queue")
+	require.NoError(t, out.Start())
+	defer out.Stop()
+
+	deliverAt := time.Now().Add(50 * time.Millisecond)
+	ctx := transport.WithDeliverAt(context.Background(), deliverAt)
+	for j := 0; j < 2; j++ {
+		// Identical requests must not be merged.
+		_, err := out.CallOneway(ctx, &transport.Request{
+			Caller:    "caller",
+			Service:   "service",
+			Encoding:  raw.Encoding,
+			Procedure: "remind",
+			Body:      bytes.NewReader([]byte("hello")),
+		})
+		require.NoError(t, err)
+	}
+	assert.Empty(t, client.list("queue"), "delayed requests must not be queued")
+	assert.Equal(t, 2, client.zset("queue:scheduled"), "delayed requests must be scheduled")
+
+	inbound := NewInbound(client, "queue", "processing", time.Millisecond).
+		WithSchedulePollInterval(5 * time.Millisecond).
+		WithMetrics(scope).
+		WithRouter(router)
+	require.NoError(t, inbound.Start())
+	defer inbound.Stop()
+
+	for j := 0; j < 2; j++ {
+		select {
+		case at := <-handled:
+			assert.False(t, at.Before(deliverAt), "request handled at %v, before it was due at %v", at, deliverAt)
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for the delayed request")
+		}
+	}
+	assert.Equal(t, 0, client.zset("queue:scheduled"), "due requests must leave the sorted set")
+	assert.Equal(t, int64(2), counters(scope)["scheduled"], "due requests must be counted")
+}
+
+func TestPastDeliverAtIsQueued(t *testing.T) {
+	client := newFakeClient()
+	out := NewOnewayOutbound(client, "queue")
+	require.NoError(t, out.Start())
+	defer out.Stop()
+
+	ctx := transport.WithDeliverAt(context.Background(), time.Now().Add(-time.Second))
+	_, err := out.CallOneway(ctx, &transport.Request{
+		Caller:    "caller",
+		Service:   "service",
+		Encoding:  raw.Encoding,
+		Procedure: "remind",
+		Body:      bytes.NewReader([]byte("hello")),
+	})
+	require.NoError(t, err)
+
+	assert.Len(t, client.list("queue"), 1, "requests which are due must be queued")
+	assert.Equal(t, 0, client.zset("queue:scheduled"), "requests which are due must not be scheduled")
+}
@@ -0,0 +1,175 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package redis
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/serialize"
+
+	"github.com/opentracing/opentracing-go"
+	"go.uber.org/multierr"
+)
+
+// Oneway requests which were redelivered carry the number of the attempt in
+// this reserved header. Requests without it are on their first attempt.
+const attemptHeader = "$rpc$-attempt"
+
+// popAttempt removes the attempt header from the headers and returns the
+// attempt it held.
+func popAttempt(h transport.Headers) (int, error) {
+	a, ok := h.Get(attemptHeader)
+	h.Del(attemptHeader)
+	if !ok {
+		return 1, nil
+	}
+
+	attempt, err := strconv.Atoi(a)
+	if err != nil || attempt < 1 {
+		return 0, fmt.Errorf("invalid attempt %q", a)
+	}
+	return attempt, nil
+}
+
+// retry puts a request that failed back in the queue for another attempt, or
+// moves it to the dead-letter key if it has no attempts left.
+func (i *Inbound) retry(item []byte) error {
+	spanContext, req, err := serialize.FromBytes(i.tracer, item)
+	if err != nil {
+		// The request cannot be read, so every attempt would fail.
+		return i.deadLetter(item)
+	}
+	attempt, err := popAttempt(req.Headers)
+	if err != nil || attempt >= i.maxAttempts {
+		return i.deadLetter(item)
+	}
+
+	// The redelivered request follows from the attempt that failed. The span
+	// also gives it a span context that the tracer of the inbound can encode.
+	var opts []opentracing.StartSpanOption
+	if spanContext != nil {
+		opts = append(opts, opentracing.FollowsFrom(spanContext))
+	}
+	span := i.tracer.StartSpan(req.Procedure, opts...)
+	defer span.Finish()
+
+	req.Headers = req.Headers.With(attemptHeader, strconv.Itoa(attempt+1))
+	redelivery, err := serialize.ToBytes(i.tracer, span.Context(), req)
+	if err != nil {
+		return err
+	}
+	if err := i.client.LPush(i.queueKey, redelivery); err != nil {
+		return err
+	}
+	i.metrics.redeliveries.Inc(1)
+	return nil
+}
+
+// deadLetter moves a request that cannot be retried to the dead-letter key,
+// or drops it if the inbound has none.
+func (i *Inbound) deadLetter(item []byte) error {
+	if i.deadLetterKey == "" {
+		i.metrics.dropped.Inc(1)
+		return nil
+	}
+	if err := i.client.LPush(i.deadLetterKey, item); err != nil {
+		return err
+	}
+	i.metrics.deadLetters.Inc(1)
+	return nil
+}
+
+// leasesKey returns the key of the sorted set holding the leases of the
+// requests in the given processing key. Leases are scored by the time at
+// which they expire.
+func leasesKey(processingKey string) string {
+	return processingKey + ":leases"
+}
+
+// unleasedKey returns the key of the list holding the requests read from
+// the queue for the given processing key which were not leased yet.
+func unleasedKey(processingKey string) string {
+	return processingKey + ":unleased"
+}
+
+// lease moves a request read from the queue to the processing key and
+// leases it to the inbound for the visibility timeout.
+func (i *Inbound) lease(item []byte) error {
+	expiry := time.Now().Add(i.visibilityTimeout)
+	return i.client.Lease(
+		leasesKey(i.processingKey), unleasedKey(i.processingKey), i.processingKey,
+		item, scheduleScore(expiry))
+}
+
+// release removes a request that the inbound finished with from the
+// processing key, along with its lease.
+func (i *Inbound) release(item []byte) error {
+	return multierr.Append(
+		i.client.LRem(i.processingKey, item),
+		i.client.ZRem(leasesKey(i.processingKey), item),
+	)
+}
+
+// recoverLoop moves requests whose lease expired back to the queue until the
+// inbound stops or starts draining.
+func (i *Inbound) recoverLoop() {
+	interval := i.visibilityTimeout / 2
+	if interval <= 0 {
+		interval = i.visibilityTimeout
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-i.stop:
+			return
+		case <-ticker.C:
+			if i.draining.Load() {
+				return
+			}
+			// TODO: log error
+			_ = i.recoverExpired()
+		}
+	}
+}
+
+// recoverExpired moves the requests whose lease expired, because the inbound
+// handling them stopped or took longer than the visibility timeout, from the
+// processing key back to the queue. Recovered requests keep their attempt,
+// since they may not have been handled at all.
+func (i *Inbound) recoverExpired() error {
+	for {
+		now := time.Now()
+		moved, err := i.client.RecoverExpired(
+			leasesKey(i.processingKey), unleasedKey(i.processingKey), i.processingKey, i.queueKey,
+			scheduleScore(now), scheduleScore(now.Add(i.visibilityTimeout)), scheduleBatchSize)
+		if err != nil {
+			return err
+		}
+		i.metrics.recovered.Inc(moved)
+		if moved < scheduleBatchSize {
+			return nil
+		}
+	}
+}
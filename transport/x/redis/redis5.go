@@ -32,6 +32,52 @@ import (
 
 var errNotStarted = errors.New("redis5client not started")
 
+// zPopByScoreLPush moves items from a sorted set to a list in a single
+// transaction, so that every item is moved exactly once even if several
+// clients move items from the same sorted set.
+var zPopByScoreLPush = redis5.NewScript(`
+local items = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, item in ipairs(items) do
+	redis.call('ZREM', KEYS[1], item)
+	redis.call('LPUSH', KEYS[2], item)
+end
+return #items
+`)
+
+// lease moves an item read from the queue out of the unleased list and
+// leases it in a single transaction, so that the item is never left in the
+// processing list without a lease.
+var lease = redis5.NewScript(`
+if redis.call('LREM', KEYS[2], 1, ARGV[1]) > 0 then
+	redis.call('LPUSH', KEYS[3], ARGV[1])
+end
+redis.call('ZADD', KEYS[1], ARGV[2], ARGV[1])
+return redis.status_reply('OK')
+`)
+
+// recoverExpired moves items whose lease expired from a processing list back
+// to the queue in a single transaction, so that an item is recovered at most
+// once even if several clients recover items from the same list. Items left
+// in the unleased list, because their client stopped before leasing them,
+// are leased too so that they are eventually recovered.
+var recoverExpired = redis5.NewScript(`
+local items = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[3])
+local moved = 0
+for _, item in ipairs(items) do
+	redis.call('ZREM', KEYS[1], item)
+	if redis.call('LREM', KEYS[3], 1, item) > 0 then
+		redis.call('LPUSH', KEYS[4], item)
+		moved = moved + 1
+	end
+end
+local item = redis.call('RPOPLPUSH', KEYS[2], KEYS[3])
+while item do
+	redis.call('ZADD', KEYS[1], ARGV[2], item)
+	item = redis.call('RPOPLPUSH', KEYS[2], KEYS[3])
+end
+return moved
+`)
+
 type redis5Client struct {
 	addr   string
 	client *redis5.Client
@@ -80,6 +126,18 @@ func (c *redis5Client) LPush(queueKey string, item []byte) error {
 	return nil
 }
 
+func (c *redis5Client) RPush(queueKey string, item []byte) error {
+	if !c.started.Load() {
+		return errNotStarted
+	}
+
+	cmd := c.client.RPush(queueKey, item)
+	if cmd.Err() != nil {
+		return errors.New("could not push item onto queue")
+	}
+	return nil
+}
+
 func (c *redis5Client) BRPopLPush(queueKey, processingKey string, timeout time.Duration) ([]byte, error) {
 	if !c.started.Load() {
 		return nil, errNotStarted
@@ -109,6 +167,96 @@ func (c *redis5Client) LRem(key string, item []byte) error {
 	return nil
 }
 
+func (c *redis5Client) BRPop(key string, timeout time.Duration) ([]byte, error) {
+	if !c.started.Load() {
+		return nil, errNotStarted
+	}
+
+	items, err := c.client.BRPop(timeout, key).Result()
+	if err == redis5.Nil {
+		// We timed out waiting for an item.
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// BRPOP replies with the key and the item.
+	return []byte(items[1]), nil
+}
+
+func (c *redis5Client) Expire(key string, ttl time.Duration) error {
+	if !c.started.Load() {
+		return errNotStarted
+	}
+
+	return c.client.Expire(key, ttl).Err()
+}
+
+func (c *redis5Client) Del(key string) error {
+	if !c.started.Load() {
+		return errNotStarted
+	}
+
+	return c.client.Del(key).Err()
+}
+
+func (c *redis5Client) ZAdd(key string, score float64, item []byte) error {
+	if !c.started.Load() {
+		return errNotStarted
+	}
+
+	return c.client.ZAdd(key, redis5.Z{Score: score, Member: item}).Err()
+}
+
+func (c *redis5Client) ZPopByScoreLPush(from, to string, max float64, count int64) (int64, error) {
+	if !c.started.Load() {
+		return 0, errNotStarted
+	}
+
+	res, err := zPopByScoreLPush.Run(c.client, []string{from, to}, max, count).Result()
+	if err != nil {
+		return 0, err
+	}
+	moved, ok := res.(int64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected reply %v moving items from %q to %q", res, from, to)
+	}
+	return moved, nil
+}
+
+func (c *redis5Client) ZRem(key string, item []byte) error {
+	if !c.started.Load() {
+		return errNotStarted
+	}
+
+	return c.client.ZRem(key, item).Err()
+}
+
+func (c *redis5Client) Lease(leases, unleased, to string, item []byte, score float64) error {
+	if !c.started.Load() {
+		return errNotStarted
+	}
+
+	return lease.Run(c.client, []string{leases, unleased, to}, item, score).Err()
+}
+
+func (c *redis5Client) RecoverExpired(leases, unleased, from, to string, max, lease float64, count int64) (int64, error) {
+	if !c.started.Load() {
+		return 0, errNotStarted
+	}
+
+	res, err := recoverExpired.Run(c.client, []string{leases, unleased, from, to}, max, lease, count).Result()
+	if err != nil {
+		return 0, err
+	}
+	moved, ok := res.(int64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected reply %v recovering items from %q to %q", res, from, to)
+	}
+	return moved, nil
+}
+
 // Endpoint returns the endpoint configured for this client.
 func (c *redis5Client) Endpoint() string {
 	return c.addr
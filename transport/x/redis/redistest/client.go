@@ -49,6 +49,17 @@ func (_m *MockClient) EXPECT() *_MockClientRecorder {
 	return _m.recorder
 }
 
+func (_m *MockClient) BRPop(_param0 string, _param1 time.Duration) ([]byte, error) {
+	ret := _m.ctrl.Call(_m, "BRPop", _param0, _param1)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockClientRecorder) BRPop(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "BRPop", arg0, arg1)
+}
+
 func (_m *MockClient) BRPopLPush(_param0 string, _param1 string, _param2 time.Duration) ([]byte, error) {
 	ret := _m.ctrl.Call(_m, "BRPopLPush", _param0, _param1, _param2)
 	ret0, _ := ret[0].([]byte)
@@ -70,6 +81,16 @@ func (_mr *_MockClientRecorder) ConnectionState() *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "ConnectionState")
 }
 
+func (_m *MockClient) Del(_param0 string) error {
+	ret := _m.ctrl.Call(_m, "Del", _param0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockClientRecorder) Del(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Del", arg0)
+}
+
 func (_m *MockClient) Endpoint() string {
 	ret := _m.ctrl.Call(_m, "Endpoint")
 	ret0, _ := ret[0].(string)
@@ -80,6 +101,16 @@ func (_mr *_MockClientRecorder) Endpoint() *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "Endpoint")
 }
 
+func (_m *MockClient) Expire(_param0 string, _param1 time.Duration) error {
+	ret := _m.ctrl.Call(_m, "Expire", _param0, _param1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockClientRecorder) Expire(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Expire", arg0, arg1)
+}
+
 func (_m *MockClient) IsRunning() bool {
 	ret := _m.ctrl.Call(_m, "IsRunning")
 	ret0, _ := ret[0].(bool)
@@ -110,6 +141,37 @@ func (_mr *_MockClientRecorder) LRem(arg0, arg1 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "LRem", arg0, arg1)
 }
 
+func (_m *MockClient) Lease(_param0 string, _param1 string, _param2 string, _param3 []byte, _param4 float64) error {
+	ret := _m.ctrl.Call(_m, "Lease", _param0, _param1, _param2, _param3, _param4)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockClientRecorder) Lease(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Lease", arg0, arg1, arg2, arg3, arg4)
+}
+
+func (_m *MockClient) RPush(_param0 string, _param1 []byte) error {
+	ret := _m.ctrl.Call(_m, "RPush", _param0, _param1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockClientRecorder) RPush(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "RPush", arg0, arg1)
+}
+
+func (_m *MockClient) RecoverExpired(_param0 string, _param1 string, _param2 string, _param3 string, _param4 float64, _param5 float64, _param6 int64) (int64, error) {
+	ret := _m.ctrl.Call(_m, "RecoverExpired", _param0, _param1, _param2, _param3, _param4, _param5, _param6)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockClientRecorder) RecoverExpired(arg0, arg1, arg2, arg3, arg4, arg5, arg6 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "RecoverExpired", arg0, arg1, arg2, arg3, arg4, arg5, arg6)
+}
+
 func (_m *MockClient) Start() error {
 	ret := _m.ctrl.Call(_m, "Start")
 	ret0, _ := ret[0].(error)
@@ -129,3 +191,34 @@ func (_m *MockClient) Stop() error {
 func (_mr *_MockClientRecorder) Stop() *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "Stop")
 }
+
+func (_m *MockClient) ZAdd(_param0 string, _param1 float64, _param2 []byte) error {
+	ret := _m.ctrl.Call(_m, "ZAdd", _param0, _param1, _param2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockClientRecorder) ZAdd(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "ZAdd", arg0, arg1, arg2)
+}
+
+func (_m *MockClient) ZPopByScoreLPush(_param0 string, _param1 string, _param2 float64, _param3 int64) (int64, error) {
+	ret := _m.ctrl.Call(_m, "ZPopByScoreLPush", _param0, _param1, _param2, _param3)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockClientRecorder) ZPopByScoreLPush(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "ZPopByScoreLPush", arg0, arg1, arg2, arg3)
+}
+
+func (_m *MockClient) ZRem(_param0 string, _param1 []byte) error {
+	ret := _m.ctrl.Call(_m, "ZRem", _param0, _param1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockClientRecorder) ZRem(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "ZRem", arg0, arg1)
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package redis
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"time"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// Unary requests carry the key to which the reply must be written and the
+// deadline of the caller in these reserved headers.
+const (
+	replyKeyHeader = "$rpc$-reply-key"
+	deadlineHeader = "$rpc$-deadline"
+)
+
+// replyVersion indicates which serialization is used for replies.
+// '0' indicates JSON.
+const replyVersion = byte(0)
+
+// reply is the response to a unary request, as written to the reply key.
+type reply struct {
+	Headers          map[string]string `json:"headers,omitempty"`
+	Body             []byte            `json:"body,omitempty"`
+	ApplicationError bool              `json:"applicationError,omitempty"`
+
+	// Set if the request failed.
+	ErrorCode    yarpcerrors.Code `json:"errorCode,omitempty"`
+	ErrorMessage string           `json:"errorMessage,omitempty"`
+}
+
+func replyToBytes(r *reply) ([]byte, error) {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{replyVersion}, body...), nil
+}
+
+func replyFromBytes(b []byte) (*reply, error) {
+	if len(b) == 0 {
+		return nil, errors.New("cannot deserialize empty reply")
+	}
+	if b[0] != replyVersion {
+		return nil, fmt.Errorf("unsupported reply serialization version '%v'", b[0])
+	}
+
+	var r reply
+	if err := json.Unmarshal(b[1:], &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// response converts the reply into the response or error of the call.
+func (r *reply) response() (*transport.Response, error) {
+	if r.ErrorCode != yarpcerrors.CodeOK {
+		return nil, yarpcerrors.Newf(r.ErrorCode, "%s", r.ErrorMessage)
+	}
+	return &transport.Response{
+		Headers:          transport.HeadersFromMap(r.Headers),
+		Body:             ioutil.NopCloser(bytes.NewReader(r.Body)),
+		ApplicationError: r.ApplicationError,
+	}, nil
+}
+
+// newReplyKey returns a key, unique to a call made by the given caller, on
+// which the reply to a request placed in the given queue is received.
+func newReplyKey(queueKey, caller string) (string, error) {
+	id, err := newID()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:reply:%s:%s", queueKey, caller, id), nil
+}
+
+// newID returns a random hex-encoded identifier.
+func newID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// withReplyHeaders returns a copy of the headers with the reply key and the
+// deadline of a unary request.
+func withReplyHeaders(h transport.Headers, replyKey string, deadline time.Time) transport.Headers {
+	headers := transport.NewHeadersWithCapacity(h.Len() + 2)
+	for k, v := range h.Items() {
+		headers = headers.With(k, v)
+	}
+	return headers.
+		With(replyKeyHeader, replyKey).
+		With(deadlineHeader, strconv.FormatInt(deadline.UnixNano(), 10))
+}
+
+// popReplyHeaders removes the reply key and the deadline from the headers of
+// a request. Both are empty for oneway requests.
+func popReplyHeaders(h transport.Headers) (replyKey string, deadline time.Time, err error) {
+	replyKey, _ = h.Get(replyKeyHeader)
+	d, ok := h.Get(deadlineHeader)
+	h.Del(replyKeyHeader)
+	h.Del(deadlineHeader)
+	if !ok {
+		return replyKey, deadline, nil
+	}
+
+	nanos, err := strconv.ParseInt(d, 10, 64)
+	if err != nil {
+		return "", deadline, fmt.Errorf("invalid deadline %q: %v", d, err)
+	}
+	return replyKey, time.Unix(0, nanos), nil
+}
+
+var _ transport.ResponseWriter = (*responseWriter)(nil)
+
+// responseWriter buffers the response written by a unary handler.
+type responseWriter struct {
+	headers          transport.Headers
+	body             bytes.Buffer
+	applicationError bool
+}
+
+func newResponseWriter() *responseWriter {
+	return &responseWriter{headers: transport.NewHeaders()}
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *responseWriter) AddHeaders(h transport.Headers) {
+	for k, v := range h.Items() {
+		w.headers = w.headers.With(k, v)
+	}
+}
+
+func (w *responseWriter) SetApplicationError() {
+	w.applicationError = true
+}
+
+func (w *responseWriter) reply() *reply {
+	return &reply{
+		Headers:          w.headers.Items(),
+		Body:             w.body.Bytes(),
+		ApplicationError: w.applicationError,
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/yarpcerrors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplySerialization(t *testing.T) {
+	tests := []struct {
+		desc string
+		give *reply
+	}{
+		{desc: "empty", give: &reply{}},
+		{
+			desc: "response",
+			give: &reply{
+				Headers:          map[string]string{"foo": "bar"},
+				Body:             []byte("hello"),
+				ApplicationError: true,
+			},
+		},
+		{
+			desc: "error",
+			give: &reply{
+				ErrorCode:    yarpcerrors.CodeNotFound,
+				ErrorMessage: "great sadness",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			b, err := replyToBytes(tt.give)
+			require.NoError(t, err, "failed to serialize reply")
+
+			got, err := replyFromBytes(b)
+			require.NoError(t, err, "failed to deserialize reply")
+			assert.Equal(t, tt.give, got)
+		})
+	}
+}
+
+func TestReplyFromBytesFailures(t *testing.T) {
+	_, err := replyFromBytes(nil)
+	assert.EqualError(t, err, "cannot deserialize empty reply")
+
+	_, err = replyFromBytes([]byte{1, '{', '}'})
+	assert.EqualError(t, err, "unsupported reply serialization version '1'")
+
+	_, err = replyFromBytes([]byte{0, '{'})
+	assert.Error(t, err)
+}
+
+func TestReplyResponse(t *testing.T) {
+	res, err := (&reply{
+		Headers:          map[string]string{"foo": "bar"},
+		ApplicationError: true,
+	}).response()
+	require.NoError(t, err)
+	assert.Equal(t, transport.NewHeaders().With("foo", "bar"), res.Headers)
+	assert.True(t, res.ApplicationError)
+
+	_, err = (&reply{
+		ErrorCode:    yarpcerrors.CodeNotFound,
+		ErrorMessage: "great sadness",
+	}).response()
+	assert.Equal(t, yarpcerrors.Newf(yarpcerrors.CodeNotFound, "great sadness"), err)
+}
+
+func TestReplyHeaders(t *testing.T) {
+	deadline := time.Unix(1500000000, 123)
+	original := transport.NewHeaders().With("foo", "bar")
+
+	headers := withReplyHeaders(original, "reply-key", deadline)
+	assert.Equal(t, 1, original.Len(), "original headers must not be modified")
+
+	replyKey, gotDeadline, err := popReplyHeaders(headers)
+	require.NoError(t, err)
+	assert.Equal(t, "reply-key", replyKey)
+	assert.True(t, deadline.Equal(gotDeadline), "deadline must match")
+	assert.Equal(t, original, headers, "reply headers must be removed")
+
+	replyKey, gotDeadline, err = popReplyHeaders(transport.NewHeaders())
+	require.NoError(t, err)
+	assert.Empty(t, replyKey)
+	assert.True(t, gotDeadline.IsZero(), "oneway requests have no deadline")
+
+	_, _, err = popReplyHeaders(transport.NewHeaders().With(deadlineHeader, "soon"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package redis
+
+import (
+	"time"
+
+	"go.uber.org/yarpc/api/transport"
+)
+
+// Requests delivered with a delay carry an identifier unique to the call in
+// this reserved header, so that identical requests are not merged in the
+// sorted set holding them.
+const scheduleIDHeader = "$rpc$-schedule-id"
+
+const defaultSchedulePollInterval = 100 * time.Millisecond
+
+// scheduleBatchSize is the largest number of due requests that are moved to
+// the queue at once.
+const scheduleBatchSize = 100
+
+// scheduledKey returns the key of the sorted set holding requests for the
+// given queue until they are due. Requests are scored by the time at which
+// they are due.
+func scheduledKey(queueKey string) string {
+	return queueKey + ":scheduled"
+}
+
+// scheduleScore returns the score of a request which is due at the given
+// time, in milliseconds since the epoch.
+func scheduleScore(t time.Time) float64 {
+	return float64(t.UnixNano() / int64(time.Millisecond))
+}
+
+// withScheduleID returns a copy of the headers with a new schedule ID.
+func withScheduleID(h transport.Headers) (transport.Headers, error) {
+	id, err := newID()
+	if err != nil {
+		return h, err
+	}
+	headers := transport.NewHeadersWithCapacity(h.Len() + 1)
+	for k, v := range h.Items() {
+		headers = headers.With(k, v)
+	}
+	return headers.With(scheduleIDHeader, id), nil
+}
+
+// scheduleLoop moves requests which are due from the sorted set of the queue
+// to the queue until the inbound stops or starts draining.
+func (i *Inbound) scheduleLoop() {
+	ticker := time.NewTicker(i.schedulePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-i.stop:
+			return
+		case <-ticker.C:
+			if i.draining.Load() {
+				return
+			}
+			// TODO: log error
+			_ = i.moveDue()
+		}
+	}
+}
+
+// moveDue moves requests which are due from the sorted set of the queue to
+// the queue. Requests are moved atomically, so every request is delivered
+// even if the inbound stops, and only once even if several inbounds read
+// from the queue.
+func (i *Inbound) moveDue() error {
+	for {
+		moved, err := i.client.ZPopByScoreLPush(
+			scheduledKey(i.queueKey), i.queueKey, scheduleScore(time.Now()), scheduleBatchSize)
+		if err != nil {
+			return err
+		}
+		i.metrics.scheduled.Inc(moved)
+		if moved < scheduleBatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package concurrency
+
+import (
+	"math"
+	"time"
+)
+
+// _decreaseRatio is the factor by which a limit is multiplied when a request
+// is slower than the target latency.
+const _decreaseRatio = 0.9
+
+// adaptive adjusts limits using additive increase and multiplicative
+// decrease based on the latency of requests.
+type adaptive struct {
+	target time.Duration
+	min    float64
+}
+
+// adjust returns the new limit after a request with the given latency
+// finished.
+func (a *adaptive) adjust(limit, max float64, latency time.Duration) float64 {
+	min := math.Min(math.Max(a.min, 1), max)
+	if latency > a.target {
+		return math.Max(min, limit*_decreaseRatio)
+	}
+
+	// Increase by 1/limit so that the limit grows by about one for every
+	// limit-worth of requests that were fast enough.
+	return math.Min(max, limit+1/limit)
+}
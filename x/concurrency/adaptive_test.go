@@ -0,0 +1,58 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package concurrency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdaptiveAdjust(t *testing.T) {
+	a := &adaptive{target: 100 * time.Millisecond, min: 5}
+
+	tests := []struct {
+		desc       string
+		limit, max float64
+		latency    time.Duration
+		want       float64
+	}{
+		{desc: "slow", limit: 100, max: 100, latency: time.Second, want: 90},
+		{desc: "slow at minimum", limit: 5, max: 100, latency: time.Second, want: 5},
+		{desc: "slow near minimum", limit: 5.5, max: 100, latency: time.Second, want: 5},
+		{desc: "fast", limit: 50, max: 100, latency: time.Millisecond, want: 50.02},
+		{desc: "fast at maximum", limit: 100, max: 100, latency: time.Millisecond, want: 100},
+		{desc: "at target", limit: 10, max: 100, latency: 100 * time.Millisecond, want: 10.1},
+		{desc: "minimum above maximum", limit: 3, max: 3, latency: time.Second, want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			assert.InDelta(t, tt.want, a.adjust(tt.limit, tt.max, tt.latency), 1e-9)
+		})
+	}
+}
+
+func TestAdaptiveMinimumLimitIsOne(t *testing.T) {
+	a := &adaptive{target: time.Millisecond}
+	assert.Equal(t, float64(1), a.adjust(1, 10, time.Second))
+}
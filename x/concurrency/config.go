@@ -0,0 +1,156 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package concurrency
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/yarpc/x/config"
+
+	"go.uber.org/multierr"
+)
+
+// Config is the configuration for a Limiter. It is usually specified under
+// the top-level concurrency key of the configuration loaded with
+// go.uber.org/yarpc/x/config, once MiddlewareSpec is registered.
+//
+// 	concurrency:
+// 	  maxPerProcedure: 100
+// 	  maxPerCaller: 20
+// 	  procedures:
+// 	    KeyValue::getValue: 500
+// 	  callers:
+// 	    batch-job: 5
+// 	  adaptive:
+// 	    targetLatency: 50ms
+// 	    minLimit: 10
+//
+// Zero limits mean that requests are not limited.
+type Config struct {
+	// Limit on concurrent requests to each procedure.
+	MaxPerProcedure int `config:"maxPerProcedure"`
+
+	// Limit on concurrent requests from each caller.
+	MaxPerCaller int `config:"maxPerCaller"`
+
+	// Limits for individual procedures, overriding MaxPerProcedure.
+	Procedures map[string]int `config:"procedures"`
+
+	// Limits for individual callers, overriding MaxPerCaller.
+	Callers map[string]int `config:"callers"`
+
+	// If set, procedure limits are adjusted based on observed latency.
+	Adaptive *AdaptiveConfig `config:"adaptive"`
+}
+
+// AdaptiveConfig is the configuration for adaptive limits.
+type AdaptiveConfig struct {
+	// Requests slower than this cause the limit to be lowered.
+	TargetLatency time.Duration `config:"targetLatency"`
+
+	// The limit is never lowered below this value.
+	MinLimit int `config:"minLimit"`
+}
+
+// NewLimiter builds a Limiter from the given configuration.
+func (c Config) NewLimiter() (*Limiter, error) {
+	var errs error
+	if c.MaxPerProcedure < 0 {
+		errs = multierr.Append(errs, fmt.Errorf("maxPerProcedure must not be negative: %v", c.MaxPerProcedure))
+	}
+	if c.MaxPerCaller < 0 {
+		errs = multierr.Append(errs, fmt.Errorf("maxPerCaller must not be negative: %v", c.MaxPerCaller))
+	}
+
+	opts := []Option{MaxPerProcedure(c.MaxPerProcedure), MaxPerCaller(c.MaxPerCaller)}
+
+	hasProcedureLimits := c.MaxPerProcedure > 0
+	for _, procedure := range sortedKeys(c.Procedures) {
+		n := c.Procedures[procedure]
+		if n < 0 {
+			errs = multierr.Append(errs, fmt.Errorf("limit for procedure %q must not be negative: %v", procedure, n))
+			continue
+		}
+		hasProcedureLimits = hasProcedureLimits || n > 0
+		opts = append(opts, ProcedureLimit(procedure, n))
+	}
+
+	for _, caller := range sortedKeys(c.Callers) {
+		n := c.Callers[caller]
+		if n < 0 {
+			errs = multierr.Append(errs, fmt.Errorf("limit for caller %q must not be negative: %v", caller, n))
+			continue
+		}
+		opts = append(opts, CallerLimit(caller, n))
+	}
+
+	if a := c.Adaptive; a != nil {
+		if a.TargetLatency <= 0 {
+			errs = multierr.Append(errs, fmt.Errorf("adaptive targetLatency must be positive: %v", a.TargetLatency))
+		}
+		if a.MinLimit < 0 {
+			errs = multierr.Append(errs, fmt.Errorf("adaptive minLimit must not be negative: %v", a.MinLimit))
+		}
+		if !hasProcedureLimits {
+			errs = multierr.Append(errs, errors.New(
+				"adaptive limits require maxPerProcedure or limits for individual procedures"))
+		}
+		opts = append(opts, AdaptiveLimits(a.TargetLatency, a.MinLimit))
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+	return NewLimiter(opts...), nil
+}
+
+// MiddlewareSpec returns a configuration specification for the concurrency
+// limiting middleware, making the top-level concurrency key available and
+// allowing "concurrency" in the inbound middleware list. Both accept a
+// Config.
+//
+//  cfg := config.New()
+//  cfg.MustRegisterMiddleware(concurrency.MiddlewareSpec())
+//
+// This limits concurrent requests to each procedure:
+//
+//  concurrency:
+//    maxPerProcedure: 100
+func MiddlewareSpec() config.MiddlewareSpec {
+	return config.MiddlewareSpec{
+		Name: "concurrency",
+		BuildMiddleware: func(c Config, _ *config.Kit) (*Limiter, error) {
+			return c.NewLimiter()
+		},
+	}
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package concurrency
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/yarpc/internal/whitespace"
+	"go.uber.org/yarpc/x/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig(t *testing.T) {
+	l, err := Config{
+		MaxPerProcedure: 10,
+		MaxPerCaller:    5,
+		Procedures:      map[string]int{"hello": 20, "unlimited": 0},
+		Callers:         map[string]int{"batch": 1},
+		Adaptive:        &AdaptiveConfig{TargetLatency: time.Second, MinLimit: 2},
+	}.NewLimiter()
+	require.NoError(t, err)
+
+	assert.Equal(t, float64(10), l.procedures.get("other").max)
+	assert.Equal(t, float64(20), l.procedures.get("hello").max)
+	assert.Equal(t, float64(0), l.procedures.get("unlimited").max)
+	assert.Equal(t, float64(5), l.callers.get("web").max)
+	assert.Equal(t, float64(1), l.callers.get("batch").max)
+
+	assert.NotNil(t, l.procedures.get("hello").adaptive)
+	assert.Nil(t, l.callers.get("web").adaptive, "caller limits must not be adaptive")
+}
+
+func TestConfigErrors(t *testing.T) {
+	tests := []struct {
+		desc    string
+		give    Config
+		wantErr []string
+	}{
+		{
+			desc:    "negative limits",
+			give:    Config{MaxPerProcedure: -1, MaxPerCaller: -2},
+			wantErr: []string{"maxPerProcedure must not be negative: -1", "maxPerCaller must not be negative: -2"},
+		},
+		{
+			desc: "negative overrides",
+			give: Config{
+				Procedures: map[string]int{"hello": -1},
+				Callers:    map[string]int{"web": -1},
+			},
+			wantErr: []string{
+				`limit for procedure "hello" must not be negative: -1`,
+				`limit for caller "web" must not be negative: -1`,
+			},
+		},
+		{
+			desc: "adaptive without procedure limits",
+			give: Config{
+				MaxPerCaller: 10,
+				Adaptive:     &AdaptiveConfig{TargetLatency: time.Second},
+			},
+			wantErr: []string{"adaptive limits require maxPerProcedure or limits for individual procedures"},
+		},
+		{
+			desc: "invalid adaptive config",
+			give: Config{
+				MaxPerProcedure: 10,
+				Adaptive:        &AdaptiveConfig{MinLimit: -1},
+			},
+			wantErr: []string{
+				"adaptive targetLatency must be positive: 0s",
+				"adaptive minLimit must not be negative: -1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			_, err := tt.give.NewLimiter()
+			require.Error(t, err)
+			for _, msg := range tt.wantErr {
+				assert.Contains(t, err.Error(), msg)
+			}
+		})
+	}
+}
+
+func TestMiddlewareSpec(t *testing.T) {
+	tests := []struct {
+		desc string
+		give string
+	}{
+		{
+			desc: "concurrency key",
+			give: `
+				concurrency:
+					maxPerProcedure: 1
+			`,
+		},
+		{
+			desc: "inbound middleware",
+			give: `
+				middleware:
+					inbound:
+						- concurrency: {maxPerProcedure: 1}
+			`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			cfg := config.New()
+			cfg.MustRegisterMiddleware(MiddlewareSpec())
+
+			c, err := cfg.LoadConfigFromYAML("foo", strings.NewReader(whitespace.Expand(tt.give)))
+			require.NoError(t, err)
+
+			limiter, ok := c.InboundMiddleware.Unary.(*Limiter)
+			require.True(t, ok, "unary inbound middleware must be a limiter: %T", c.InboundMiddleware.Unary)
+			assert.Equal(t, limiter, c.InboundMiddleware.Oneway, "oneway inbound middleware must be the same limiter")
+		})
+	}
+}
+
+func TestMiddlewareSpecErrors(t *testing.T) {
+	cfg := config.New()
+	cfg.MustRegisterMiddleware(MiddlewareSpec())
+
+	_, err := cfg.LoadConfigFromYAML("foo", strings.NewReader(whitespace.Expand(`
+		concurrency:
+			maxPerCaller: -1
+	`)))
+	require.Error(t, err, "expected failure")
+	assert.Contains(t, err.Error(), "failed to load concurrency configuration")
+	assert.Contains(t, err.Error(), "maxPerCaller must not be negative: -1")
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package concurrency provides inbound middleware which limits the number of
+// requests handled concurrently by a Dispatcher.
+//
+// Limits may be placed on the number of in-flight requests to each procedure
+// and from each caller. Requests over the limit are rejected right away with
+// a yarpcerrors.CodeResourceExhausted error, which the Dispatcher counts in
+// the server_failures metric with the error "resource_exhausted".
+//
+// 	limiter := concurrency.NewLimiter(
+// 		concurrency.MaxPerProcedure(100),
+// 		concurrency.ProcedureLimit("KeyValue::getValue", 500),
+// 		concurrency.MaxPerCaller(20),
+// 	)
+// 	dispatcher := yarpc.NewDispatcher(yarpc.Config{
+// 		Name:     "keyvalue",
+// 		Inbounds: inbounds,
+// 		InboundMiddleware: yarpc.InboundMiddleware{
+// 			Unary:  limiter,
+// 			Oneway: limiter,
+// 		},
+// 	})
+//
+// Adaptive limits
+//
+// With AdaptiveLimits, the limit of each procedure is adjusted based on
+// observed latency. The limit is decreased multiplicatively whenever a
+// request takes longer than the target latency, and increased additively
+// back towards the configured limit as long as requests are faster than
+// the target.
+//
+// Configuration
+//
+// The limiter may also be configured through go.uber.org/yarpc/x/config by
+// registering MiddlewareSpec and using the top-level concurrency key. See
+// Config for details.
+package concurrency
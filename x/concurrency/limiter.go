@@ -0,0 +1,348 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package concurrency
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+var _timeNow = time.Now // for tests
+
+// Option customizes a Limiter.
+type Option func(*Limiter)
+
+// MaxPerProcedure limits the number of concurrent requests to each
+// procedure. Requests are not limited by procedure if this is zero.
+//
+// Use ProcedureLimit to override the limit for specific procedures.
+func MaxPerProcedure(n int) Option {
+	return func(l *Limiter) {
+		l.procedures.defaultLimit = n
+	}
+}
+
+// ProcedureLimit limits the number of concurrent requests to the given
+// procedure, overriding MaxPerProcedure. Requests to the procedure are not
+// limited if this is zero.
+func ProcedureLimit(procedure string, n int) Option {
+	return func(l *Limiter) {
+		l.procedures.limits[procedure] = n
+	}
+}
+
+// MaxPerCaller limits the number of concurrent requests from each caller.
+// Requests are not limited by caller if this is zero.
+//
+// Use CallerLimit to override the limit for specific callers.
+func MaxPerCaller(n int) Option {
+	return func(l *Limiter) {
+		l.callers.defaultLimit = n
+	}
+}
+
+// CallerLimit limits the number of concurrent requests from the given
+// caller, overriding MaxPerCaller. Requests from the caller are not limited
+// if this is zero.
+func CallerLimit(caller string, n int) Option {
+	return func(l *Limiter) {
+		l.callers.limits[caller] = n
+	}
+}
+
+// AdaptiveLimits adjusts the limit of each procedure based on the latency of
+// its requests. The limit is lowered when requests take longer than the
+// target latency, but never below min, and raised back up to the limit
+// specified with MaxPerProcedure or ProcedureLimit when they are faster.
+//
+// Only procedures which have a limit are adjusted.
+func AdaptiveLimits(target time.Duration, min int) Option {
+	return func(l *Limiter) {
+		l.procedures.adaptive = &adaptive{target: target, min: float64(min)}
+	}
+}
+
+// Limiter is inbound middleware which limits the number of requests that are
+// handled concurrently.
+type Limiter struct {
+	procedures counters
+	callers    counters
+}
+
+var (
+	_ middleware.UnaryInbound  = (*Limiter)(nil)
+	_ middleware.OnewayInbound = (*Limiter)(nil)
+)
+
+// NewLimiter builds a new Limiter. Requests are not limited unless limits
+// are specified with the given options.
+func NewLimiter(opts ...Option) *Limiter {
+	l := &Limiter{
+		procedures: newCounters(),
+		callers:    newCounters(),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Handle implements middleware.UnaryInbound.
+func (l *Limiter) Handle(ctx context.Context, req *transport.Request, w transport.ResponseWriter, h transport.UnaryHandler) error {
+	procedure, caller, err := l.acquire(req)
+	if err != nil {
+		return err
+	}
+
+	start := _timeNow()
+	defer func() { l.release(procedure, caller, _timeNow().Sub(start)) }()
+	return h.Handle(ctx, req, w)
+}
+
+// HandleOneway implements middleware.OnewayInbound.
+func (l *Limiter) HandleOneway(ctx context.Context, req *transport.Request, h transport.OnewayHandler) error {
+	procedure, caller, err := l.acquire(req)
+	if err != nil {
+		return err
+	}
+
+	start := _timeNow()
+	defer func() { l.release(procedure, caller, _timeNow().Sub(start)) }()
+	return h.HandleOneway(ctx, req)
+}
+
+// acquire reserves a slot for the request with the counters for its
+// procedure and caller. Returns a CodeResourceExhausted error if either of
+// them is at its limit.
+func (l *Limiter) acquire(req *transport.Request) (procedure, caller *counter, err error) {
+	procedure, ok := l.procedures.acquire(req.Procedure)
+	if !ok {
+		return nil, nil, yarpcerrors.Newf(yarpcerrors.CodeResourceExhausted,
+			"too many concurrent requests to procedure %q of service %q", req.Procedure, req.Service)
+	}
+
+	caller, ok = l.callers.acquire(req.Caller)
+	if !ok {
+		procedure.cancel()
+		return nil, nil, yarpcerrors.Newf(yarpcerrors.CodeResourceExhausted,
+			"too many concurrent requests from caller %q to service %q", req.Caller, req.Service)
+	}
+
+	return procedure, caller, nil
+}
+
+func (l *Limiter) release(procedure, caller *counter, latency time.Duration) {
+	procedure.release(latency)
+	caller.release(latency)
+}
+
+// maxEvictableCounters is the number of counters for keys without an
+// explicit limit above which idle counters are evicted. These keys come
+// from requests, so their number is not bounded otherwise.
+const maxEvictableCounters = 1000
+
+// unlimitedCounter is shared by all keys that are not limited.
+var unlimitedCounter = &counter{}
+
+// counters holds counters keyed by procedure or caller name. Counters are
+// created on first use and re-used for subsequent requests. Keys without a
+// limit have no counters of their own, and idle counters for keys without
+// an explicit limit are evicted once there are too many of them.
+type counters struct {
+	defaultLimit int
+	limits       map[string]int
+	adaptive     *adaptive
+
+	mu       sync.RWMutex
+	counters map[string]*counter
+
+	// Number of counters for keys without an explicit limit and the number
+	// above which idle ones are evicted.
+	evictable    int
+	maxEvictable int
+}
+
+func newCounters() counters {
+	return counters{
+		limits:       make(map[string]int),
+		counters:     make(map[string]*counter),
+		maxEvictable: maxEvictableCounters,
+	}
+}
+
+// acquire reserves a slot for a request with the counter for the given key.
+// Returns false if the counter is at its limit.
+func (cs *counters) acquire(key string) (*counter, bool) {
+	for {
+		c := cs.get(key)
+		if ok, evicted := c.acquire(); !evicted {
+			return c, ok
+		}
+		// The counter was evicted after we looked it up. Its replacement
+		// has the up-to-date number of requests in flight.
+	}
+}
+
+func (cs *counters) get(key string) *counter {
+	limit, explicit := cs.limits[key]
+	if !explicit {
+		limit = cs.defaultLimit
+	}
+	if limit <= 0 {
+		return unlimitedCounter
+	}
+
+	cs.mu.RLock()
+	c := cs.counters[key]
+	cs.mu.RUnlock()
+	if c != nil {
+		return c
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if c := cs.counters[key]; c != nil {
+		// Someone beat us to it.
+		return c
+	}
+
+	if !explicit {
+		if cs.evictable >= cs.maxEvictable {
+			cs.evictIdle()
+		}
+		cs.evictable++
+	}
+
+	c = newCounter(limit, cs.adaptive)
+	cs.counters[key] = c
+	return c
+}
+
+// evictIdle removes the counters for keys without an explicit limit which
+// have no requests in flight. cs.mu must be held.
+func (cs *counters) evictIdle() {
+	for key, c := range cs.counters {
+		if _, explicit := cs.limits[key]; explicit {
+			continue
+		}
+		if c.evict() {
+			delete(cs.counters, key)
+			cs.evictable--
+		}
+	}
+}
+
+// counter tracks the number of in-flight requests for a single procedure or
+// caller.
+type counter struct {
+	mu       sync.Mutex
+	inFlight int
+
+	// Current limit. This is fractional so that adaptive limits may grow
+	// slowly.
+	limit float64
+
+	// Configured limit. Requests are not limited if this is zero.
+	max float64
+
+	// Non-nil if the limit of this counter is adjusted based on latency.
+	adaptive *adaptive
+
+	// Whether the counter was evicted. Evicted counters are replaced by new
+	// counters for the same key and must not be used for new requests.
+	evicted bool
+}
+
+func newCounter(limit int, a *adaptive) *counter {
+	if limit <= 0 {
+		// There's nothing to adapt if there is no limit.
+		return &counter{}
+	}
+	return &counter{limit: float64(limit), max: float64(limit), adaptive: a}
+}
+
+// acquire reserves a slot for a request. Returns false if the counter is at
+// its limit. Returns evicted if the counter was evicted and another counter
+// must be used instead.
+func (c *counter) acquire() (ok, evicted bool) {
+	if c.max == 0 {
+		// Unlimited counters don't need to track anything.
+		return true, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.evicted {
+		return false, true
+	}
+	if float64(c.inFlight) >= c.limit {
+		return false, false
+	}
+	c.inFlight++
+	return true, false
+}
+
+// evict marks the counter as evicted if it has no requests in flight.
+// Returns whether it was evicted.
+func (c *counter) evict() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.inFlight > 0 {
+		return false
+	}
+	c.evicted = true
+	return true
+}
+
+// release releases the slot of a request which finished after the given
+// amount of time.
+func (c *counter) release(latency time.Duration) {
+	if c.max == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.inFlight--
+	if c.adaptive != nil {
+		c.limit = c.adaptive.adjust(c.limit, c.max, latency)
+	}
+}
+
+// cancel releases the slot of a request which was not handled.
+func (c *counter) cancel() {
+	if c.max == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	c.inFlight--
+	c.mu.Unlock()
+}
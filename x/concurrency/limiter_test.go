@@ -0,0 +1,265 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package concurrency
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/yarpcerrors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingHandler is a unary and oneway handler which blocks until it is
+// unblocked.
+type blockingHandler struct {
+	started chan struct{}
+	unblock chan struct{}
+}
+
+func newBlockingHandler() *blockingHandler {
+	return &blockingHandler{
+		started: make(chan struct{}, 100),
+		unblock: make(chan struct{}),
+	}
+}
+
+func (h *blockingHandler) Handle(context.Context, *transport.Request, transport.ResponseWriter) error {
+	h.started <- struct{}{}
+	<-h.unblock
+	return nil
+}
+
+func (h *blockingHandler) HandleOneway(context.Context, *transport.Request) error {
+	h.started <- struct{}{}
+	<-h.unblock
+	return nil
+}
+
+// startBlocked starts handling the given requests in the background and
+// waits until they have all reached the handler. The returned function
+// unblocks them and waits for them to finish.
+func startBlocked(t *testing.T, l *Limiter, h *blockingHandler, reqs ...*transport.Request) (finish func()) {
+	var wg sync.WaitGroup
+	for _, req := range reqs {
+		wg.Add(1)
+		go func(req *transport.Request) {
+			defer wg.Done()
+			assert.NoError(t, l.Handle(context.Background(), req, &transporttest.FakeResponseWriter{}, h))
+		}(req)
+	}
+
+	for range reqs {
+		select {
+		case <-h.started:
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for requests to start")
+		}
+	}
+
+	return func() {
+		close(h.unblock)
+		wg.Wait()
+	}
+}
+
+func assertResourceExhausted(t *testing.T, err error, msg string) {
+	require.Error(t, err, "expected request to be rejected")
+	assert.Equal(t, yarpcerrors.CodeResourceExhausted, yarpcerrors.FromError(err).Code())
+	assert.Contains(t, err.Error(), msg)
+}
+
+func TestLimiterPerProcedure(t *testing.T) {
+	l := NewLimiter(MaxPerProcedure(2), ProcedureLimit("unlimited", 0))
+	h := newBlockingHandler()
+
+	finish := startBlocked(t, l, h,
+		&transport.Request{Caller: "a", Service: "svc", Procedure: "hello"},
+		&transport.Request{Caller: "b", Service: "svc", Procedure: "hello"},
+		&transport.Request{Caller: "c", Service: "svc", Procedure: "unlimited"},
+		&transport.Request{Caller: "c", Service: "svc", Procedure: "unlimited"},
+		&transport.Request{Caller: "c", Service: "svc", Procedure: "unlimited"},
+	)
+
+	err := l.Handle(context.Background(),
+		&transport.Request{Caller: "c", Service: "svc", Procedure: "hello"},
+		&transporttest.FakeResponseWriter{}, h)
+	assertResourceExhausted(t, err, `too many concurrent requests to procedure "hello" of service "svc"`)
+
+	err = l.HandleOneway(context.Background(),
+		&transport.Request{Caller: "c", Service: "svc", Procedure: "hello"}, h)
+	assertResourceExhausted(t, err, `procedure "hello"`)
+
+	finish()
+
+	// Slots are released once the requests finish.
+	assert.NoError(t, l.Handle(context.Background(),
+		&transport.Request{Caller: "c", Service: "svc", Procedure: "hello"},
+		&transporttest.FakeResponseWriter{}, nopHandler{}))
+}
+
+func TestLimiterPerCaller(t *testing.T) {
+	l := NewLimiter(MaxPerCaller(1), CallerLimit("batch", 2), MaxPerProcedure(10))
+	h := newBlockingHandler()
+
+	finish := startBlocked(t, l, h,
+		&transport.Request{Caller: "web", Procedure: "hello"},
+		&transport.Request{Caller: "batch", Procedure: "hello"},
+		&transport.Request{Caller: "batch", Procedure: "hello"},
+	)
+	defer finish()
+
+	for _, caller := range []string{"web", "batch"} {
+		err := l.Handle(context.Background(),
+			&transport.Request{Caller: caller, Service: "svc", Procedure: "hello"},
+			&transporttest.FakeResponseWriter{}, h)
+		assertResourceExhausted(t, err, `too many concurrent requests from caller "`+caller+`" to service "svc"`)
+	}
+
+	// Requests rejected because of their caller must not hold on to their
+	// procedure slot.
+	assert.Equal(t, 3, l.procedures.get("hello").inFlight)
+}
+
+func TestLimiterUnlimited(t *testing.T) {
+	l := NewLimiter()
+	h := newBlockingHandler()
+
+	reqs := make([]*transport.Request, 50)
+	for i := range reqs {
+		reqs[i] = &transport.Request{Caller: "caller", Procedure: "hello"}
+	}
+	startBlocked(t, l, h, reqs...)()
+}
+
+func TestLimiterAdaptive(t *testing.T) {
+	now := time.Now()
+	defer func() { _timeNow = time.Now }()
+
+	var latency time.Duration
+	_timeNow = func() time.Time {
+		// Every request observes exactly the given latency.
+		now = now.Add(latency)
+		return now
+	}
+
+	l := NewLimiter(MaxPerProcedure(10), AdaptiveLimits(100*time.Millisecond, 2))
+	call := func() {
+		require.NoError(t, l.Handle(context.Background(),
+			&transport.Request{Caller: "caller", Procedure: "hello"},
+			&transporttest.FakeResponseWriter{}, nopHandler{}))
+	}
+
+	c := l.procedures.get("hello")
+
+	latency = time.Second
+	for i := 0; i < 100; i++ {
+		call()
+	}
+	assert.Equal(t, float64(2), c.limit, "limit must not go below the minimum")
+
+	latency = time.Millisecond
+	for i := 0; i < 1000; i++ {
+		call()
+	}
+	assert.Equal(t, float64(10), c.limit, "limit must recover up to the maximum")
+}
+
+func TestLimiterReleasesOnPanic(t *testing.T) {
+	l := NewLimiter(MaxPerProcedure(1))
+	req := &transport.Request{Caller: "caller", Service: "svc", Procedure: "hello"}
+
+	for i := 0; i < 3; i++ {
+		assert.Panics(t, func() {
+			l.Handle(context.Background(), req, &transporttest.FakeResponseWriter{}, panicHandler{})
+		})
+		assert.Panics(t, func() {
+			l.HandleOneway(context.Background(), req, panicHandler{})
+		})
+	}
+
+	assert.NoError(t, l.Handle(context.Background(), req, &transporttest.FakeResponseWriter{}, nopHandler{}),
+		"slots of requests whose handlers panicked must be released")
+}
+
+func TestLimiterDoesNotTrackUnlimitedKeys(t *testing.T) {
+	l := NewLimiter(MaxPerProcedure(1), ProcedureLimit("unlimited", 0))
+
+	for _, caller := range []string{"a", "b", "c"} {
+		for _, procedure := range []string{"hello", "unlimited"} {
+			assert.NoError(t, l.Handle(context.Background(),
+				&transport.Request{Caller: caller, Procedure: procedure},
+				&transporttest.FakeResponseWriter{}, nopHandler{}))
+		}
+	}
+
+	assert.Len(t, l.callers.counters, 0, "callers are not limited")
+	assert.Len(t, l.procedures.counters, 1, "only limited procedures have counters")
+}
+
+func TestLimiterEvictsIdleCounters(t *testing.T) {
+	l := NewLimiter(MaxPerCaller(1), CallerLimit("batch", 2))
+	l.callers.maxEvictable = 2
+	h := newBlockingHandler()
+
+	finish := startBlocked(t, l, h,
+		&transport.Request{Caller: "busy", Procedure: "hello"},
+		&transport.Request{Caller: "batch", Procedure: "hello"},
+	)
+	defer finish()
+
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, l.Handle(context.Background(),
+			&transport.Request{Caller: fmt.Sprint("caller-", i), Procedure: "hello"},
+			&transporttest.FakeResponseWriter{}, nopHandler{}))
+	}
+	assert.True(t, len(l.callers.counters) <= 4,
+		"idle counters must be evicted, got %d counters", len(l.callers.counters))
+
+	// Counters with requests in flight are kept.
+	err := l.Handle(context.Background(),
+		&transport.Request{Caller: "busy", Service: "svc", Procedure: "hello"},
+		&transporttest.FakeResponseWriter{}, nopHandler{})
+	assertResourceExhausted(t, err, `caller "busy"`)
+}
+
+type panicHandler struct{}
+
+func (panicHandler) Handle(context.Context, *transport.Request, transport.ResponseWriter) error {
+	panic("great sadness")
+}
+
+func (panicHandler) HandleOneway(context.Context, *transport.Request) error {
+	panic("great sadness")
+}
+
+type nopHandler struct{}
+
+func (nopHandler) Handle(context.Context, *transport.Request, transport.ResponseWriter) error {
+	return nil
+}
@@ -21,11 +21,16 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/middleware"
 	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/inboundmiddleware"
 	"go.uber.org/yarpc/internal/interpolate"
+	"go.uber.org/yarpc/internal/outboundmiddleware"
 
 	"go.uber.org/multierr"
 )
@@ -34,6 +39,11 @@ type buildableOutbounds struct {
 	Service string
 	Unary   *buildableOutbound
 	Oneway  *buildableOutbound
+
+	// Middleware applied to the unary and oneway outbounds after they are
+	// built.
+	UnaryMiddleware  []middleware.UnaryOutbound
+	OnewayMiddleware []middleware.OnewayOutbound
 }
 
 type buildableInbound struct {
@@ -44,6 +54,33 @@ type buildableInbound struct {
 type buildableOutbound struct {
 	TransportSpec *compiledTransportSpec
 	Value         *buildable
+
+	// If non-nil, the outbound splits requests between other outbounds and
+	// TransportSpec and Value are unset.
+	Split *buildableSplit
+}
+
+// buildableSplit is an outbound which splits requests between other
+// outbounds, built with a SplitSpec.
+type buildableSplit struct {
+	Spec    *SplitSpec
+	Targets []buildableSplitTarget
+	Options SplitOptions
+}
+
+type buildableSplitTarget struct {
+	Name     string
+	Weight   int
+	Outbound *buildableOutbound
+}
+
+// splitTargetSpec is a target of a split outbound with the spec of its
+// transport and its undecoded outbound configuration.
+type splitTargetSpec struct {
+	Name          string
+	Weight        int
+	TransportSpec *compiledTransportSpec
+	Attributes    attributeMap
 }
 
 type builder struct {
@@ -58,6 +95,32 @@ type builder struct {
 	inbounds   []buildableInbound
 	clients    map[string]*buildableOutbounds
 
+	// Middleware applied to all inbound requests, in order.
+	unaryInboundMiddleware  []middleware.UnaryInbound
+	onewayInboundMiddleware []middleware.OnewayInbound
+	streamInboundMiddleware []middleware.StreamInbound
+
+	// Middleware applied to all outbound requests, in order.
+	unaryOutboundMiddleware  []middleware.UnaryOutbound
+	onewayOutboundMiddleware []middleware.OnewayOutbound
+	streamOutboundMiddleware []middleware.StreamOutbound
+
+	// Logging and metrics configuration, excluding the logger and Tally
+	// scope which are provided by the user.
+	logging yarpc.LoggingConfig
+	metrics yarpc.MetricsConfig
+
+	// How long the Dispatcher waits for requests in flight when it is
+	// stopped.
+	drainTimeout time.Duration
+
+	// Middleware specified with the top-level 'concurrency' key, if any.
+	concurrency *builtConcurrency
+
+	// If non-nil, the configuration is built so that it may be reloaded
+	// and the parts that may be reloaded are recorded here.
+	reload *reloadState
+
 	// Used to resolve interpolated variables.
 	resolver interpolate.VariableResolver
 }
@@ -76,11 +139,66 @@ func newBuilder(name string, kit *Kit, resolver interpolate.VariableResolver) *b
 func (b *builder) Build() (yarpc.Config, error) {
 	var (
 		transports = make(map[string]transport.Transport)
-		cfg        = yarpc.Config{Name: b.Name}
-		errs       error
+		cfg        = yarpc.Config{
+			Name:         b.Name,
+			Logging:      b.logging,
+			Metrics:      b.metrics,
+			DrainTimeout: b.drainTimeout,
+		}
+		errs error
 	)
 
+	if err := b.buildTransports(transports); err != nil {
+		return yarpc.Config{}, err
+	}
+
+	for _, i := range b.inbounds {
+		ib, err := buildInbound(i.Value, transports[i.Transport], b.kit)
+		if err != nil {
+			errs = multierr.Append(errs, err)
+			continue
+		}
+		cfg.Inbounds = append(cfg.Inbounds, ib)
+	}
+
+	cfg.InboundMiddleware, cfg.OutboundMiddleware = b.buildMiddleware()
+	if b.reload != nil {
+		b.reload.Transports = transports
+		b.reload.Concurrency = b.concurrency
+		b.reload.Inbound.Store(cfg.InboundMiddleware)
+		b.reload.Outbound.Store(cfg.OutboundMiddleware)
+		cfg.InboundMiddleware = b.reload.Inbound.Middleware()
+		cfg.OutboundMiddleware = b.reload.Outbound.Middleware()
+	}
+
+	outbounds := make(yarpc.Outbounds, len(b.clients))
+	for ccname, c := range b.clients {
+		ob, ro, err := b.buildOutbounds(ccname, c, transports)
+		if err != nil {
+			errs = multierr.Append(errs, err)
+			continue
+		}
+		outbounds[ccname] = ob
+		if b.reload != nil {
+			b.reload.Outbounds[ccname] = ro
+		}
+	}
+	if len(outbounds) > 0 {
+		cfg.Outbounds = outbounds
+	}
+
+	return cfg, errs
+}
+
+// buildTransports builds the transports needed by the inbounds and outbounds
+// into the given map. Transports already present in the map are not built
+// again.
+func (b *builder) buildTransports(transports map[string]transport.Transport) error {
 	for name, spec := range b.needTransports {
+		if _, ok := transports[name]; ok {
+			continue
+		}
+
 		cv, ok := b.transports[name]
 
 		var err error
@@ -88,56 +206,113 @@ func (b *builder) Build() (yarpc.Config, error) {
 			// No configuration provided for the transport. Use an empty map.
 			cv, err = spec.Transport.Decode(attributeMap{}, interpolateWith(b.resolver))
 			if err != nil {
-				return yarpc.Config{}, err
+				return err
 			}
 		}
 
 		transports[name], err = buildTransport(cv, b.kit)
 		if err != nil {
-			return yarpc.Config{}, err
+			return err
 		}
 	}
+	return nil
+}
 
-	for _, i := range b.inbounds {
-		ib, err := buildInbound(i.Value, transports[i.Transport], b.kit)
-		if err != nil {
-			errs = multierr.Append(errs, err)
-			continue
-		}
-		cfg.Inbounds = append(cfg.Inbounds, ib)
+// buildMiddleware combines the middleware added to the builder for all
+// inbound and all outbound requests.
+func (b *builder) buildMiddleware() (yarpc.InboundMiddleware, yarpc.OutboundMiddleware) {
+	var (
+		in  yarpc.InboundMiddleware
+		out yarpc.OutboundMiddleware
+	)
+
+	if len(b.unaryInboundMiddleware) > 0 {
+		in.Unary = inboundmiddleware.UnaryChain(b.unaryInboundMiddleware...)
+	}
+	if len(b.onewayInboundMiddleware) > 0 {
+		in.Oneway = inboundmiddleware.OnewayChain(b.onewayInboundMiddleware...)
+	}
+	if len(b.streamInboundMiddleware) > 0 {
+		in.Stream = inboundmiddleware.StreamChain(b.streamInboundMiddleware...)
 	}
 
-	outbounds := make(yarpc.Outbounds, len(b.clients))
-	for ccname, c := range b.clients {
-		var err error
+	if len(b.unaryOutboundMiddleware) > 0 {
+		out.Unary = outboundmiddleware.UnaryChain(b.unaryOutboundMiddleware...)
+	}
+	if len(b.onewayOutboundMiddleware) > 0 {
+		out.Oneway = outboundmiddleware.OnewayChain(b.onewayOutboundMiddleware...)
+	}
+	if len(b.streamOutboundMiddleware) > 0 {
+		out.Stream = outboundmiddleware.StreamChain(b.streamOutboundMiddleware...)
+	}
 
-		var ob transport.Outbounds
-		if c.Service != ccname {
-			ob.ServiceName = c.Service
-		}
+	return in, out
+}
 
-		if o := c.Unary; o != nil {
-			ob.Unary, err = buildUnaryOutbound(o, transports[o.TransportSpec.Name], b.kit)
-			if err != nil {
-				errs = multierr.Append(errs, fmt.Errorf(`failed to configure unary outbound for %q: %v`, ccname, err))
-				continue
-			}
+// outboundMiddleware combines the middleware added to the given outbound.
+func (c *buildableOutbounds) outboundMiddleware() yarpc.OutboundMiddleware {
+	var mw yarpc.OutboundMiddleware
+	if len(c.UnaryMiddleware) > 0 {
+		mw.Unary = outboundmiddleware.UnaryChain(c.UnaryMiddleware...)
+	}
+	if len(c.OnewayMiddleware) > 0 {
+		mw.Oneway = outboundmiddleware.OnewayChain(c.OnewayMiddleware...)
+	}
+	return mw
+}
+
+// buildOutbounds builds the outbounds with the given key. If the builder is
+// building a reloadable configuration, the parts of the outbounds that may
+// be reloaded are returned as well.
+func (b *builder) buildOutbounds(
+	ccname string, c *buildableOutbounds, transports map[string]transport.Transport,
+) (transport.Outbounds, *reloadableOutbound, error) {
+	var (
+		ob  transport.Outbounds
+		ro  *reloadableOutbound
+		err error
+	)
+
+	if c.Service != ccname {
+		ob.ServiceName = c.Service
+	}
+
+	mw := c.outboundMiddleware()
+	unaryKit, onewayKit := b.kit, b.kit
+	if b.reload != nil {
+		ro = &reloadableOutbound{Middleware: newReloadableOutboundMiddleware()}
+		ro.Middleware.Store(mw)
+		mw = ro.Middleware.Middleware()
+		unaryKit = b.kit.withStaticPeersHook(func(p *staticPeers) { ro.UnaryPeers = p })
+		onewayKit = b.kit.withStaticPeersHook(func(p *staticPeers) { ro.OnewayPeers = p })
+	}
+
+	if o := c.Unary; o != nil {
+		ob.Unary, err = buildUnaryOutbound(o, transports, unaryKit)
+		if err != nil {
+			return ob, nil, fmt.Errorf(`failed to configure unary outbound for %q: %v`, ccname, err)
 		}
-		if o := c.Oneway; o != nil {
-			ob.Oneway, err = buildOnewayOutbound(o, transports[o.TransportSpec.Name], b.kit)
-			if err != nil {
-				errs = multierr.Append(errs, fmt.Errorf(`failed to configure oneway outbound for %q: %v`, ccname, err))
-				continue
-			}
+		if o.Split != nil && ro != nil {
+			ro.UnarySplit = ob.Unary.(SplitOutbound)
+		}
+		if mw.Unary != nil {
+			ob.Unary = middleware.ApplyUnaryOutbound(ob.Unary, mw.Unary)
 		}
-
-		outbounds[ccname] = ob
 	}
-	if len(outbounds) > 0 {
-		cfg.Outbounds = outbounds
+	if o := c.Oneway; o != nil {
+		ob.Oneway, err = buildOnewayOutbound(o, transports, onewayKit)
+		if err != nil {
+			return ob, nil, fmt.Errorf(`failed to configure oneway outbound for %q: %v`, ccname, err)
+		}
+		if o.Split != nil && ro != nil {
+			ro.OnewaySplit = ob.Oneway.(SplitOutbound)
+		}
+		if mw.Oneway != nil {
+			ob.Oneway = middleware.ApplyOnewayOutbound(ob.Oneway, mw.Oneway)
+		}
 	}
 
-	return cfg, errs
+	return ob, ro, nil
 }
 
 // buildTransport builds a Transport from the given value. This will panic if
@@ -162,8 +337,32 @@ func buildInbound(cv *buildable, t transport.Transport, k *Kit) (transport.Inbou
 
 // buildUnaryOutbound builds an UnaryOutbound from the given value. This will panic
 // if the output type for this is not transport.UnaryOutbound.
-func buildUnaryOutbound(o *buildableOutbound, t transport.Transport, k *Kit) (transport.UnaryOutbound, error) {
-	result, err := o.Value.Build(t, k.withTransportSpec(o.TransportSpec))
+func buildUnaryOutbound(
+	o *buildableOutbound, transports map[string]transport.Transport, k *Kit,
+) (transport.UnaryOutbound, error) {
+	if o.Split != nil {
+		// Peers of the targets are not reloaded.
+		tk := k.withStaticPeersHook(nil)
+		targets := make([]SplitTarget, len(o.Split.Targets))
+		for i, t := range o.Split.Targets {
+			out, err := buildUnaryOutbound(t.Outbound, transports, tk)
+			if err != nil {
+				return nil, fmt.Errorf("failed to build target %q: %v", t.Name, err)
+			}
+			targets[i] = SplitTarget{Name: t.Name, Weight: t.Weight, Unary: out}
+		}
+		out, err := o.Split.Spec.BuildSplit(targets, o.Split.Options)
+		if err != nil {
+			return nil, err
+		}
+		unary, ok := out.(transport.UnaryOutbound)
+		if !ok {
+			return nil, fmt.Errorf("split outbound %T does not support unary requests", out)
+		}
+		return unary, nil
+	}
+
+	result, err := o.Value.Build(transports[o.TransportSpec.Name], k.withTransportSpec(o.TransportSpec))
 	if err != nil {
 		return nil, err
 	}
@@ -172,8 +371,32 @@ func buildUnaryOutbound(o *buildableOutbound, t transport.Transport, k *Kit) (tr
 
 // buildOnewayOutbound builds an OnewayOutbound from the given value. This will
 // panic if the output type for this is not transport.OnewayOutbound.
-func buildOnewayOutbound(o *buildableOutbound, t transport.Transport, k *Kit) (transport.OnewayOutbound, error) {
-	result, err := o.Value.Build(t, k.withTransportSpec(o.TransportSpec))
+func buildOnewayOutbound(
+	o *buildableOutbound, transports map[string]transport.Transport, k *Kit,
+) (transport.OnewayOutbound, error) {
+	if o.Split != nil {
+		// Peers of the targets are not reloaded.
+		tk := k.withStaticPeersHook(nil)
+		targets := make([]SplitTarget, len(o.Split.Targets))
+		for i, t := range o.Split.Targets {
+			out, err := buildOnewayOutbound(t.Outbound, transports, tk)
+			if err != nil {
+				return nil, fmt.Errorf("failed to build target %q: %v", t.Name, err)
+			}
+			targets[i] = SplitTarget{Name: t.Name, Weight: t.Weight, Oneway: out}
+		}
+		out, err := o.Split.Spec.BuildSplit(targets, o.Split.Options)
+		if err != nil {
+			return nil, err
+		}
+		oneway, ok := out.(transport.OnewayOutbound)
+		if !ok {
+			return nil, fmt.Errorf("split outbound %T does not support oneway requests", out)
+		}
+		return oneway, nil
+	}
+
+	result, err := o.Value.Build(transports[o.TransportSpec.Name], k.withTransportSpec(o.TransportSpec))
 	if err != nil {
 		return nil, err
 	}
@@ -281,6 +504,139 @@ func (b *builder) AddOnewayOutbound(
 	return nil
 }
 
+// AddSplitOutbound adds an outbound which splits requests between the given
+// targets, built with the given SplitSpec. The outbound makes unary requests
+// if the transports of all targets support them, and oneway requests if the
+// transports of all targets support those.
+func (b *builder) AddSplitOutbound(
+	outboundKey, service string, targets []splitTargetSpec, split *SplitSpec, opts SplitOptions,
+) error {
+	unary := &buildableSplit{Spec: split, Options: opts}
+	oneway := &buildableSplit{Spec: split, Options: opts}
+	for _, t := range targets {
+		spec := t.TransportSpec
+		if !spec.SupportsUnaryOutbound() && !spec.SupportsOnewayOutbound() {
+			return fmt.Errorf("transport %q of target %q does not support outbound requests", spec.Name, t.Name)
+		}
+
+		if unary != nil && !spec.SupportsUnaryOutbound() {
+			unary = nil
+		}
+		if unary != nil {
+			b.needTransport(spec)
+			cv, err := spec.UnaryOutbound.Decode(t.Attributes, interpolateWith(b.resolver))
+			if err != nil {
+				return fmt.Errorf("failed to decode unary outbound configuration of target %q: %v", t.Name, err)
+			}
+			unary.Targets = append(unary.Targets, buildableSplitTarget{
+				Name:     t.Name,
+				Weight:   t.Weight,
+				Outbound: &buildableOutbound{TransportSpec: spec, Value: cv},
+			})
+		}
+
+		if oneway != nil && !spec.SupportsOnewayOutbound() {
+			oneway = nil
+		}
+		if oneway != nil {
+			b.needTransport(spec)
+			cv, err := spec.OnewayOutbound.Decode(t.Attributes, interpolateWith(b.resolver))
+			if err != nil {
+				return fmt.Errorf("failed to decode oneway outbound configuration of target %q: %v", t.Name, err)
+			}
+			oneway.Targets = append(oneway.Targets, buildableSplitTarget{
+				Name:     t.Name,
+				Weight:   t.Weight,
+				Outbound: &buildableOutbound{TransportSpec: spec, Value: cv},
+			})
+		}
+	}
+
+	if unary == nil && oneway == nil {
+		return errors.New("transports of the targets must all support unary requests or all support oneway requests")
+	}
+
+	cc, ok := b.clients[outboundKey]
+	if !ok {
+		cc = &buildableOutbounds{Service: service}
+		b.clients[outboundKey] = cc
+	}
+
+	if unary != nil {
+		cc.Unary = &buildableOutbound{Split: unary}
+	}
+	if oneway != nil {
+		cc.Oneway = &buildableOutbound{Split: oneway}
+	}
+	return nil
+}
+
+// AddInboundMiddleware adds middleware for all inbound requests. Any of the
+// middleware may be nil.
+func (b *builder) AddInboundMiddleware(
+	unary middleware.UnaryInbound, oneway middleware.OnewayInbound, stream middleware.StreamInbound,
+) {
+	if unary != nil {
+		b.unaryInboundMiddleware = append(b.unaryInboundMiddleware, unary)
+	}
+	if oneway != nil {
+		b.onewayInboundMiddleware = append(b.onewayInboundMiddleware, oneway)
+	}
+	if stream != nil {
+		b.streamInboundMiddleware = append(b.streamInboundMiddleware, stream)
+	}
+}
+
+// AddOutboundMiddleware adds middleware for all outbound requests. Any of
+// the middleware may be nil.
+func (b *builder) AddOutboundMiddleware(
+	unary middleware.UnaryOutbound, oneway middleware.OnewayOutbound, stream middleware.StreamOutbound,
+) {
+	if unary != nil {
+		b.unaryOutboundMiddleware = append(b.unaryOutboundMiddleware, unary)
+	}
+	if oneway != nil {
+		b.onewayOutboundMiddleware = append(b.onewayOutboundMiddleware, oneway)
+	}
+	if stream != nil {
+		b.streamOutboundMiddleware = append(b.streamOutboundMiddleware, stream)
+	}
+}
+
+// AddOutboundMiddlewareTo adds middleware to the outbound with the given
+// key. The outbound must have already been added. Either of the middleware
+// may be nil but at least one of them must apply to the outbound.
+func (b *builder) AddOutboundMiddlewareTo(
+	outboundKey string, unary middleware.UnaryOutbound, oneway middleware.OnewayOutbound,
+) error {
+	cc, ok := b.clients[outboundKey]
+	if !ok {
+		cc = &buildableOutbounds{}
+	}
+
+	applied := false
+	if unary != nil && cc.Unary != nil {
+		cc.UnaryMiddleware = append(cc.UnaryMiddleware, unary)
+		applied = true
+	}
+	if oneway != nil && cc.Oneway != nil {
+		cc.OnewayMiddleware = append(cc.OnewayMiddleware, oneway)
+		applied = true
+	}
+	if applied {
+		return nil
+	}
+
+	switch {
+	case oneway == nil:
+		return fmt.Errorf("outbound %q does not have a unary outbound", outboundKey)
+	case unary == nil:
+		return fmt.Errorf("outbound %q does not have a oneway outbound", outboundKey)
+	default:
+		return fmt.Errorf("outbound %q does not have a unary or oneway outbound", outboundKey)
+	}
+}
+
 func (b *builder) needTransport(spec *compiledTransportSpec) {
 	b.needTransports[spec.Name] = spec
 }
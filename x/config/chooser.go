@@ -249,6 +249,13 @@ func buildPeerListUpdater(c attributeMap, identify func(string) peer.Identifier,
 		return nil, err
 	}
 	if len(peers) > 0 {
+		if kit.staticPeersHook != nil {
+			// The list of peers may be changed if the configuration is
+			// reloaded.
+			p := newStaticPeers(peers, identify)
+			kit.staticPeersHook(p)
+			return p.Bind, nil
+		}
 		return peerbind.BindPeers(identifyAll(identify, peers)), nil
 	}
 	// TODO: Make peers a separate peer list updater that is registered by
@@ -300,7 +307,7 @@ func buildPeerListUpdater(c attributeMap, identify func(string) peer.Identifier,
 		return nil, err
 	}
 
-	result, err := peerListUpdaterBuilder.Build(kit)
+	result, err := peerListUpdaterBuilder.Build(kit.withIdentify(identify))
 	if err != nil {
 		return nil, err
 	}
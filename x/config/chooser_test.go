@@ -37,6 +37,7 @@ import (
 	"go.uber.org/yarpc/peer"
 	"go.uber.org/yarpc/peer/hostport"
 	"go.uber.org/yarpc/peer/roundrobin"
+	"go.uber.org/yarpc/peer/x/hashring"
 	"go.uber.org/yarpc/peer/x/peerheap"
 	"go.uber.org/yarpc/transport/http"
 	"go.uber.org/yarpc/transport/tchannel"
@@ -323,6 +324,26 @@ func TestChooserConfigurator(t *testing.T) {
 				_ = list
 			},
 		},
+		{
+			desc: "use consistent-hash chooser",
+			given: whitespace.Expand(`
+				outbounds:
+					their-service:
+						unary:
+							fake-transport:
+								consistent-hash:
+									replicas: 10
+									fake-updater: {}
+			`),
+			test: func(t *testing.T, c yarpc.Config) {
+				outbound := c.Outbounds["their-service"]
+				unary := outbound.Unary.(*yarpctest.FakeOutbound)
+				chooser := unary.Chooser().(*peer.BoundChooser)
+				list, ok := chooser.ChooserList().(*hashring.List)
+				require.True(t, ok, "use hash ring")
+				_ = list
+			},
+		},
 		{
 			desc: "HTTP single peer implied by URL",
 			given: whitespace.Expand(`
@@ -791,6 +812,7 @@ func TestChooserConfigurator(t *testing.T) {
 			configer.MustRegisterTransport(tchannel.TransportSpec(tchannel.Tracer(opentracing.NoopTracer{})))
 			configer.MustRegisterPeerList(peerheap.Spec())
 			configer.MustRegisterPeerList(roundrobin.Spec())
+			configer.MustRegisterPeerList(hashring.Spec())
 			configer.MustRegisterPeerList(invalidPeerListSpec())
 			configer.MustRegisterPeerListUpdater(invalidPeerListUpdaterSpec())
 
@@ -28,6 +28,7 @@ import (
 	"os"
 
 	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/internal/interpolate"
 
 	"go.uber.org/multierr"
@@ -39,21 +40,30 @@ import (
 // A new Configurator does not know about any transports, peer lists, or peer
 // list updaters. Inform it about them by using the RegisterTransport,
 // RegisterPeerList, and RegisterPeerListUpdater functions, or their Must*
-// variants.
+// variants. Middleware, including the retry and concurrency middleware of
+// go.uber.org/yarpc/x/retry and go.uber.org/yarpc/x/concurrency, must be
+// registered with RegisterMiddleware, compressors with RegisterCompressor,
+// and a SplitSpec with RegisterSplit before the configuration may use them.
 type Configurator struct {
 	knownTransports       map[string]*compiledTransportSpec
 	knownPeerLists        map[string]*compiledPeerListSpec
 	knownPeerListUpdaters map[string]*compiledPeerListUpdaterSpec
+	knownMiddleware       map[string]*compiledMiddlewareSpec
+	knownCompressors      map[string]transport.Compressor
+	splitSpec             *SplitSpec
 	resolver              interpolate.VariableResolver
 }
 
 // New sets up a new empty Configurator. The returned Configurator does not
-// know about any Transports, peer lists, or peer list updaters.
+// know about any Transports, peer lists, peer list updaters, middleware,
+// compressors, or split outbounds.
 func New(opts ...Option) *Configurator {
 	c := &Configurator{
 		knownTransports:       make(map[string]*compiledTransportSpec),
 		knownPeerLists:        make(map[string]*compiledPeerListSpec),
 		knownPeerListUpdaters: make(map[string]*compiledPeerListUpdaterSpec),
+		knownMiddleware:       make(map[string]*compiledMiddlewareSpec),
+		knownCompressors:      make(map[string]transport.Compressor),
 		resolver:              os.LookupEnv,
 	}
 
@@ -164,19 +174,109 @@ func (c *Configurator) MustRegisterPeerListUpdater(s PeerListUpdaterSpec) {
 	}
 }
 
+// RegisterMiddleware registers a MiddlewareSpec with the given
+// Configurator, teaching it how to build middleware of this kind from
+// configuration.
+//
+// Returns an error if the MiddlewareSpec is invalid. Use
+// MustRegisterMiddleware to panic if the registration fails.
+//
+// If a middleware with the same name already exists, it will be replaced.
+//
+// The 'retry' key of outbounds and the top-level 'concurrency' key use the
+// middleware registered as "retry" and "concurrency". See the MiddlewareSpec
+// functions of go.uber.org/yarpc/x/retry and go.uber.org/yarpc/x/concurrency.
+//
+// See MiddlewareSpec for details on how to integrate your own middleware
+// with the system.
+func (c *Configurator) RegisterMiddleware(s MiddlewareSpec) error {
+	if s.Name == "" {
+		return errors.New("name is required")
+	}
+
+	spec, err := compileMiddlewareSpec(&s)
+	if err != nil {
+		return fmt.Errorf("invalid MiddlewareSpec for %q: %v", s.Name, err)
+	}
+
+	c.knownMiddleware[s.Name] = spec
+	return nil
+}
+
+// MustRegisterMiddleware registers the given MiddlewareSpec with the
+// Configurator. This function panics if the MiddlewareSpec is invalid.
+func (c *Configurator) MustRegisterMiddleware(s MiddlewareSpec) {
+	if err := c.RegisterMiddleware(s); err != nil {
+		panic(err)
+	}
+}
+
+// RegisterSplit registers a SplitSpec with the given Configurator, teaching
+// it how to build outbounds configured with the 'split' key. Configuring a
+// split outbound fails until a SplitSpec is registered.
+//
+// Returns an error if the SplitSpec is invalid. Use MustRegisterSplit to
+// panic if the registration fails.
+//
+// A previously registered SplitSpec is replaced.
+//
+// 	cfg := config.New()
+// 	cfg.MustRegisterSplit(split.Spec())
+func (c *Configurator) RegisterSplit(s SplitSpec) error {
+	if s.BuildSplit == nil {
+		return errors.New("invalid SplitSpec: BuildSplit is required")
+	}
+
+	c.splitSpec = &s
+	return nil
+}
+
+// MustRegisterSplit registers the given SplitSpec with the Configurator.
+// This function panics if the SplitSpec is invalid.
+func (c *Configurator) MustRegisterSplit(s SplitSpec) {
+	if err := c.RegisterSplit(s); err != nil {
+		panic(err)
+	}
+}
+
+// RegisterCompressor registers a transport.Compressor with the given
+// Configurator under its name, making it available to the "compressor"
+// attribute of inbounds and outbounds of transports that support
+// compression.
+//
+// Returns an error if the compressor does not have a name. Use
+// MustRegisterCompressor to panic if the registration fails.
+//
+// If a compressor with the same name already exists, it will be replaced.
+//
+// 	cfg := config.New()
+// 	cfg.MustRegisterCompressor(gzip.New())
+func (c *Configurator) RegisterCompressor(comp transport.Compressor) error {
+	name := comp.Name()
+	if name == "" {
+		return errors.New("name is required")
+	}
+
+	c.knownCompressors[name] = comp
+	return nil
+}
+
+// MustRegisterCompressor registers the given transport.Compressor with the
+// Configurator. This function panics if the compressor does not have a name.
+func (c *Configurator) MustRegisterCompressor(comp transport.Compressor) {
+	if err := c.RegisterCompressor(comp); err != nil {
+		panic(err)
+	}
+}
+
 // LoadConfigFromYAML loads a yarpc.Config from YAML data. Use LoadConfig if
 // you have already parsed a map[string]interface{} or
 // map[interface{}]interface{}.
 func (c *Configurator) LoadConfigFromYAML(serviceName string, r io.Reader) (yarpc.Config, error) {
-	b, err := ioutil.ReadAll(r)
+	data, err := readYAML(r)
 	if err != nil {
 		return yarpc.Config{}, err
 	}
-
-	var data map[string]interface{}
-	if err := yaml.Unmarshal(b, &data); err != nil {
-		return yarpc.Config{}, err
-	}
 	return c.LoadConfig(serviceName, data)
 }
 
@@ -212,9 +312,20 @@ func (c *Configurator) NewDispatcher(serviceName string, data interface{}) (*yar
 	return yarpc.NewDispatcher(cfg), nil
 }
 
-func (c *Configurator) load(serviceName string, cfg *yarpcConfig) (_ yarpc.Config, err error) {
-	b := newBuilder(serviceName, &Kit{name: serviceName, c: c}, c.resolver)
+func (c *Configurator) load(serviceName string, cfg *yarpcConfig) (yarpc.Config, error) {
+	b := c.newBuilder(serviceName)
+	if err := c.populate(b, cfg); err != nil {
+		return yarpc.Config{}, err
+	}
+	return b.Build()
+}
+
+func (c *Configurator) newBuilder(serviceName string) *builder {
+	return newBuilder(serviceName, &Kit{name: serviceName, c: c}, c.resolver)
+}
 
+// populate loads the given configuration into the builder.
+func (c *Configurator) populate(b *builder, cfg *yarpcConfig) (err error) {
 	for _, inbound := range cfg.Inbounds {
 		if e := c.loadInboundInto(b, inbound); e != nil {
 			err = multierr.Append(err, e)
@@ -233,11 +344,28 @@ func (c *Configurator) load(serviceName string, cfg *yarpcConfig) (_ yarpc.Confi
 		}
 	}
 
-	if err != nil {
-		return yarpc.Config{}, err
+	if e := c.loadConcurrencyInto(b, cfg.Concurrency); e != nil {
+		err = multierr.Append(err, e)
 	}
 
-	return b.Build()
+	if e := c.loadMiddlewareInto(b, cfg.Middleware); e != nil {
+		err = multierr.Append(err, e)
+	}
+
+	if e := cfg.Logging.fill(&b.logging); e != nil {
+		err = multierr.Append(err, fmt.Errorf("failed to load logging configuration: %v", e))
+	}
+
+	if e := cfg.Metrics.fill(&b.metrics); e != nil {
+		err = multierr.Append(err, fmt.Errorf("failed to load metrics configuration: %v", e))
+	}
+
+	if cfg.DrainTimeout < 0 {
+		err = multierr.Append(err, fmt.Errorf("drainTimeout must not be negative: %v", cfg.DrainTimeout))
+	}
+	b.drainTimeout = cfg.DrainTimeout
+
+	return err
 }
 
 func (c *Configurator) loadInboundInto(b *builder, i inbound) error {
@@ -274,8 +402,18 @@ func (c *Configurator) loadOutboundInto(b *builder, name string, cfg outbounds)
 		return nil
 	}
 
+	if cfg.Split != nil {
+		if err := c.loadSplitInto(b, name, cfg.Service, cfg.Split); err != nil {
+			return err
+		}
+		return c.loadOutboundMiddlewareInto(b, name, cfg)
+	}
+
 	if implicit := cfg.Implicit; implicit != nil {
-		return loadUsing(implicit, b.AddImplicitOutbound)
+		if err := loadUsing(implicit, b.AddImplicitOutbound); err != nil {
+			return err
+		}
+		return c.loadOutboundMiddlewareInto(b, name, cfg)
 	}
 
 	if unary := cfg.Unary; unary != nil {
@@ -290,6 +428,109 @@ func (c *Configurator) loadOutboundInto(b *builder, name string, cfg outbounds)
 		}
 	}
 
+	return c.loadOutboundMiddlewareInto(b, name, cfg)
+}
+
+// loadOutboundMiddlewareInto adds the middleware for the outbound with the
+// given name. The "retry" middleware specified with the 'retry' key is
+// applied before any middleware listed under the 'middleware' key.
+func (c *Configurator) loadOutboundMiddlewareInto(b *builder, name string, cfg outbounds) error {
+	if err := c.loadRetryInto(b, name, cfg.Retry); err != nil {
+		return err
+	}
+
+	var errs error
+	for _, mc := range cfg.Middleware {
+		mw, err := c.buildMiddleware(b, mc, false /* inbound */)
+		if err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("failed to load middleware for outbound %q: %v", name, err))
+			continue
+		}
+
+		if err := b.AddOutboundMiddlewareTo(name, mw.UnaryOutbound, mw.OnewayOutbound); err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("failed to add middleware %q to outbound %q: %v", mc.Name, name, err))
+		}
+	}
+	return errs
+}
+
+func (c *Configurator) loadRetryInto(b *builder, name string, attrs attributeMap) error {
+	if attrs == nil {
+		return nil
+	}
+
+	mc := middlewareConfig{Name: "retry", Attributes: attrs}
+	mw, err := c.buildMiddleware(b, mc, false /* inbound */)
+	if err != nil {
+		return fmt.Errorf("failed to load retry configuration for outbound %q: %v", name, err)
+	}
+
+	if err := b.AddOutboundMiddlewareTo(name, mw.UnaryOutbound, mw.OnewayOutbound); err != nil {
+		return fmt.Errorf("failed to add retries to outbound %q: %v", name, err)
+	}
+
+	return nil
+}
+
+func (c *Configurator) loadSplitInto(b *builder, name, service string, cfg *splitConfig) error {
+	if c.splitSpec == nil {
+		return fmt.Errorf("failed to load split outbound %q: no SplitSpec is registered, see RegisterSplit", name)
+	}
+
+	var opts SplitOptions
+	switch cfg.StickyBy {
+	case "":
+	case "shardKey":
+		opts.StickyByShardKey = true
+	case "header":
+		if cfg.Header == "" {
+			return fmt.Errorf("split outbound %q must specify a header to be sticky by", name)
+		}
+		opts.StickyByHeader = cfg.Header
+	default:
+		return fmt.Errorf(`split outbound %q must be sticky by "shardKey" or "header", not %q`, name, cfg.StickyBy)
+	}
+
+	if len(cfg.Targets) == 0 {
+		return fmt.Errorf("split outbound %q must have at least one target", name)
+	}
+
+	var (
+		total   int
+		targets = make([]splitTargetSpec, 0, len(cfg.Targets))
+		seen    = make(map[string]struct{}, len(cfg.Targets))
+	)
+	for _, t := range cfg.Targets {
+		if t.Name == "" {
+			return fmt.Errorf("targets of split outbound %q must have names", name)
+		}
+		if _, ok := seen[t.Name]; ok {
+			return fmt.Errorf("targets of split outbound %q must have unique names: %q", name, t.Name)
+		}
+		seen[t.Name] = struct{}{}
+		if t.Weight < 0 {
+			return fmt.Errorf("weight of target %q of split outbound %q must not be negative: %d", t.Name, name, t.Weight)
+		}
+		total += t.Weight
+
+		spec, err := c.spec(t.Outbound.Type)
+		if err != nil {
+			return fmt.Errorf("failed to load configuration for target %q of outbound %q: %v", t.Name, name, err)
+		}
+		targets = append(targets, splitTargetSpec{
+			Name:          t.Name,
+			Weight:        t.Weight,
+			TransportSpec: spec,
+			Attributes:    t.Outbound.Attributes,
+		})
+	}
+	if total <= 0 {
+		return fmt.Errorf("at least one target of split outbound %q must have a positive weight", name)
+	}
+
+	if err := b.AddSplitOutbound(name, service, targets, c.splitSpec, opts); err != nil {
+		return fmt.Errorf("failed to add outbound %q: %v", name, err)
+	}
 	return nil
 }
 
@@ -302,6 +543,109 @@ func (c *Configurator) loadTransportInto(b *builder, name string, attrs attribut
 	return b.AddTransportConfig(spec, attrs)
 }
 
+// loadConcurrencyInto adds the "concurrency" middleware specified with the
+// top-level 'concurrency' key. The middleware built for the same
+// configuration by a previous load is reused so that it keeps counting the
+// requests in flight.
+func (c *Configurator) loadConcurrencyInto(b *builder, attrs attributeMap) error {
+	if attrs == nil {
+		return nil
+	}
+
+	mc := middlewareConfig{Name: "concurrency", Attributes: attrs}
+	cv, err := c.decodeMiddleware(mc, true /* inbound */)
+	if err != nil {
+		return fmt.Errorf("failed to load concurrency configuration: %v", err)
+	}
+
+	mw, ok := b.reload.concurrencyFor(cv)
+	if !ok {
+		mw, err = buildDecodedMiddleware(b, mc.Name, cv, true /* inbound */)
+		if err != nil {
+			return fmt.Errorf("failed to load concurrency configuration: %v", err)
+		}
+	}
+
+	b.concurrency = &builtConcurrency{Config: cv.inputData.Interface(), Middleware: mw}
+	b.AddInboundMiddleware(mw.UnaryInbound, mw.OnewayInbound, mw.StreamInbound)
+	return nil
+}
+
+func (c *Configurator) loadMiddlewareInto(b *builder, cfg middlewareConfigs) error {
+	var errs error
+
+	for _, mc := range cfg.Inbound {
+		mw, err := c.buildMiddleware(b, mc, true /* inbound */)
+		if err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("failed to load inbound middleware: %v", err))
+			continue
+		}
+		b.AddInboundMiddleware(mw.UnaryInbound, mw.OnewayInbound, mw.StreamInbound)
+	}
+
+	for _, mc := range cfg.Outbound {
+		mw, err := c.buildMiddleware(b, mc, false /* inbound */)
+		if err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("failed to load outbound middleware: %v", err))
+			continue
+		}
+		b.AddOutboundMiddleware(mw.UnaryOutbound, mw.OnewayOutbound, mw.StreamOutbound)
+	}
+
+	return errs
+}
+
+// buildMiddleware builds the middleware specified by the given
+// configuration. inbound specifies whether the middleware will be used for
+// inbound or outbound requests.
+func (c *Configurator) buildMiddleware(b *builder, mc middlewareConfig, inbound bool) (builtMiddleware, error) {
+	cv, err := c.decodeMiddleware(mc, inbound)
+	if err != nil {
+		return builtMiddleware{}, err
+	}
+	return buildDecodedMiddleware(b, mc.Name, cv, inbound)
+}
+
+// decodeMiddleware decodes the configuration of the middleware specified by
+// the given configuration. inbound specifies whether the middleware will be
+// used for inbound or outbound requests.
+func (c *Configurator) decodeMiddleware(mc middlewareConfig, inbound bool) (*buildable, error) {
+	spec, ok := c.knownMiddleware[mc.Name]
+	if !ok {
+		return nil, fmt.Errorf("unknown middleware %q", mc.Name)
+	}
+
+	if inbound && !spec.Inbound {
+		return nil, fmt.Errorf("middleware %q does not support inbound requests", mc.Name)
+	}
+	if !inbound && !spec.Outbound {
+		return nil, fmt.Errorf("middleware %q does not support outbound requests", mc.Name)
+	}
+
+	cv, err := spec.Middleware.Decode(mc.Attributes, interpolateWith(c.resolver))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode configuration for middleware %q: %v", mc.Name, err)
+	}
+	return cv, nil
+}
+
+// buildDecodedMiddleware builds the middleware with the given name from its
+// decoded configuration.
+func buildDecodedMiddleware(b *builder, name string, cv *buildable, inbound bool) (builtMiddleware, error) {
+	result, err := cv.Build(b.kit)
+	if err != nil {
+		return builtMiddleware{}, fmt.Errorf("failed to build middleware %q: %v", name, err)
+	}
+
+	mw := newBuiltMiddleware(result)
+	if inbound {
+		mw.UnaryOutbound, mw.OnewayOutbound, mw.StreamOutbound = nil, nil, nil
+	} else {
+		mw.UnaryInbound, mw.OnewayInbound, mw.StreamInbound = nil, nil, nil
+	}
+	return mw, nil
+}
+
 // Returns the compiled spec for the transport with the given name or an error
 func (c *Configurator) spec(name string) (*compiledTransportSpec, error) {
 	spec, ok := c.knownTransports[name]
@@ -310,3 +654,17 @@ func (c *Configurator) spec(name string) (*compiledTransportSpec, error) {
 	}
 	return spec, nil
 }
+
+// readYAML reads YAML data from the given reader.
+func readYAML(r io.Reader) (map[string]interface{}, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var data map[string]interface{}
+	if err := yaml.Unmarshal(b, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
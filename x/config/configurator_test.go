@@ -21,18 +21,22 @@
 package config
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"strings"
 	"testing"
 	"time"
 
 	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/api/transport/transporttest"
 	"go.uber.org/yarpc/internal/whitespace"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
 	"gopkg.in/yaml.v2"
 )
 
@@ -51,6 +55,12 @@ func TestConfigurator(t *testing.T) {
 		// List of TransportSpecs to register with the Configurator
 		specs []TransportSpec
 
+		// List of MiddlewareSpecs to register with the Configurator
+		middleware []MiddlewareSpec
+
+		// SplitSpec to register with the Configurator, if any
+		split *SplitSpec
+
 		// Name of the service or empty string to use the default
 		serviceName string
 
@@ -838,6 +848,341 @@ func TestConfigurator(t *testing.T) {
 				return
 			},
 		},
+		{
+			desc: "retry without unary outbound",
+			test: func(t *testing.T, mockCtrl *gomock.Controller) (tt testCase) {
+				type outboundConfig struct{ Queue string }
+				tt.give = whitespace.Expand(`
+					outbounds:
+						bar:
+							redis:
+								queue: requests
+							retry:
+								label: retry
+				`)
+
+				redis := mockTransportSpecBuilder{
+					Name:                 "redis",
+					TransportConfig:      _typeOfEmptyStruct,
+					OnewayOutboundConfig: reflect.TypeOf(&outboundConfig{}),
+				}.Build(mockCtrl)
+
+				tt.specs = []TransportSpec{redis.Spec()}
+				tt.middleware = legacyMiddlewareSpecs(new([]string))
+				tt.wantErr = []string{
+					`failed to add retries to outbound "bar"`,
+					`outbound "bar" does not have a unary outbound`,
+				}
+				return
+			},
+		},
+		{
+			desc: "outbound middleware without unary outbound",
+			test: func(t *testing.T, mockCtrl *gomock.Controller) (tt testCase) {
+				type outboundConfig struct{ Queue string }
+				tt.give = whitespace.Expand(`
+					outbounds:
+						bar:
+							redis:
+								queue: requests
+							middleware:
+								- retry: {label: retry}
+				`)
+
+				redis := mockTransportSpecBuilder{
+					Name:                 "redis",
+					TransportConfig:      _typeOfEmptyStruct,
+					OnewayOutboundConfig: reflect.TypeOf(&outboundConfig{}),
+				}.Build(mockCtrl)
+
+				tt.specs = []TransportSpec{redis.Spec()}
+				tt.middleware = legacyMiddlewareSpecs(new([]string))
+				tt.wantErr = []string{
+					`failed to add middleware "retry" to outbound "bar"`,
+					`outbound "bar" does not have a unary outbound`,
+				}
+				return
+			},
+		},
+		{
+			desc: "unknown outbound middleware",
+			test: func(t *testing.T, mockCtrl *gomock.Controller) (tt testCase) {
+				type outboundConfig struct{ Queue string }
+				tt.give = whitespace.Expand(`
+					outbounds:
+						bar:
+							redis:
+								queue: requests
+							middleware:
+								- auth
+				`)
+
+				redis := mockTransportSpecBuilder{
+					Name:                 "redis",
+					TransportConfig:      _typeOfEmptyStruct,
+					OnewayOutboundConfig: reflect.TypeOf(&outboundConfig{}),
+				}.Build(mockCtrl)
+
+				tt.specs = []TransportSpec{redis.Spec()}
+				tt.wantErr = []string{
+					`failed to load middleware for outbound "bar"`,
+					`unknown middleware "auth"`,
+				}
+				return
+			},
+		},
+		{
+			desc: "split outbound with unary outbound",
+			test: func(t *testing.T, mockCtrl *gomock.Controller) (tt testCase) {
+				tt.give = whitespace.Expand(`
+					outbounds:
+						bar:
+							split:
+								targets:
+									- name: stable
+									  weight: 1
+									  http: {url: "http://localhost:8080/bar"}
+							unary:
+								http: {url: "http://localhost:8081/bar"}
+				`)
+				tt.wantErr = []string{"a split outbound cannot have unary or oneway outbounds"}
+				return
+			},
+		},
+		{
+			desc: "split outbound sticky by unknown key",
+			test: func(t *testing.T, mockCtrl *gomock.Controller) (tt testCase) {
+				type outboundConfig struct{ URL string }
+				tt.give = whitespace.Expand(`
+					outbounds:
+						bar:
+							split:
+								stickyBy: caller
+								targets:
+									- name: stable
+									  weight: 1
+									  http: {url: "http://localhost:8080/bar"}
+				`)
+
+				http := mockTransportSpecBuilder{
+					Name:                "http",
+					TransportConfig:     _typeOfEmptyStruct,
+					UnaryOutboundConfig: reflect.TypeOf(outboundConfig{}),
+				}.Build(mockCtrl)
+
+				tt.specs = []TransportSpec{http.Spec()}
+				tt.split = nopSplitSpec()
+				tt.wantErr = []string{
+					`split outbound "bar" must be sticky by "shardKey" or "header", not "caller"`,
+				}
+				return
+			},
+		},
+		{
+			desc: "split outbound duplicate targets",
+			test: func(t *testing.T, mockCtrl *gomock.Controller) (tt testCase) {
+				type outboundConfig struct{ URL string }
+				tt.give = whitespace.Expand(`
+					outbounds:
+						bar:
+							split:
+								targets:
+									- name: stable
+									  weight: 1
+									  http: {url: "http://localhost:8080/bar"}
+									- name: stable
+									  weight: 1
+									  http: {url: "http://localhost:8081/bar"}
+				`)
+
+				http := mockTransportSpecBuilder{
+					Name:                "http",
+					TransportConfig:     _typeOfEmptyStruct,
+					UnaryOutboundConfig: reflect.TypeOf(outboundConfig{}),
+				}.Build(mockCtrl)
+
+				tt.specs = []TransportSpec{http.Spec()}
+				tt.split = nopSplitSpec()
+				tt.wantErr = []string{
+					`targets of split outbound "bar" must have unique names: "stable"`,
+				}
+				return
+			},
+		},
+		{
+			desc: "split outbound without weights",
+			test: func(t *testing.T, mockCtrl *gomock.Controller) (tt testCase) {
+				type outboundConfig struct{ URL string }
+				tt.give = whitespace.Expand(`
+					outbounds:
+						bar:
+							split:
+								targets:
+									- name: stable
+									  http: {url: "http://localhost:8080/bar"}
+				`)
+
+				http := mockTransportSpecBuilder{
+					Name:                "http",
+					TransportConfig:     _typeOfEmptyStruct,
+					UnaryOutboundConfig: reflect.TypeOf(outboundConfig{}),
+				}.Build(mockCtrl)
+
+				tt.specs = []TransportSpec{http.Spec()}
+				tt.split = nopSplitSpec()
+				tt.wantErr = []string{
+					`at least one target of split outbound "bar" must have a positive weight`,
+				}
+				return
+			},
+		},
+		{
+			desc: "split outbound without common RPC type",
+			test: func(t *testing.T, mockCtrl *gomock.Controller) (tt testCase) {
+				type httpOutboundConfig struct{ URL string }
+				type redisOutboundConfig struct{ Queue string }
+				tt.give = whitespace.Expand(`
+					outbounds:
+						bar:
+							split:
+								targets:
+									- name: http
+									  weight: 1
+									  http: {url: "http://localhost:8080/bar"}
+									- name: redis
+									  weight: 1
+									  redis: {queue: requests}
+				`)
+
+				http := mockTransportSpecBuilder{
+					Name:                "http",
+					TransportConfig:     _typeOfEmptyStruct,
+					UnaryOutboundConfig: reflect.TypeOf(httpOutboundConfig{}),
+				}.Build(mockCtrl)
+				redis := mockTransportSpecBuilder{
+					Name:                 "redis",
+					TransportConfig:      _typeOfEmptyStruct,
+					OnewayOutboundConfig: reflect.TypeOf(&redisOutboundConfig{}),
+				}.Build(mockCtrl)
+
+				tt.specs = []TransportSpec{http.Spec(), redis.Spec()}
+				tt.split = nopSplitSpec()
+				tt.wantErr = []string{
+					`failed to add outbound "bar"`,
+					"transports of the targets must all support unary requests or all support oneway requests",
+				}
+				return
+			},
+		},
+		{
+			desc: "split outbound without SplitSpec",
+			test: func(t *testing.T, mockCtrl *gomock.Controller) (tt testCase) {
+				type outboundConfig struct{ URL string }
+				tt.give = whitespace.Expand(`
+					outbounds:
+						bar:
+							split:
+								targets:
+									- name: stable
+									  weight: 1
+									  http: {url: "http://localhost:8080/bar"}
+				`)
+
+				http := mockTransportSpecBuilder{
+					Name:                "http",
+					TransportConfig:     _typeOfEmptyStruct,
+					UnaryOutboundConfig: reflect.TypeOf(outboundConfig{}),
+				}.Build(mockCtrl)
+
+				tt.specs = []TransportSpec{http.Spec()}
+				tt.wantErr = []string{
+					`failed to load split outbound "bar": no SplitSpec is registered`,
+				}
+				return
+			},
+		},
+		{
+			desc: "retry build failure",
+			test: func(t *testing.T, mockCtrl *gomock.Controller) (tt testCase) {
+				type outboundConfig struct{ Address string }
+				tt.give = whitespace.Expand(`
+					outbounds:
+						bar:
+							tchannel:
+								address: localhost:4040
+							retry: {}
+				`)
+
+				tchan := mockTransportSpecBuilder{
+					Name:                "tchannel",
+					TransportConfig:     _typeOfEmptyStruct,
+					UnaryOutboundConfig: reflect.TypeOf(&outboundConfig{}),
+				}.Build(mockCtrl)
+
+				tt.specs = []TransportSpec{tchan.Spec()}
+				tt.middleware = legacyMiddlewareSpecs(new([]string))
+				tt.wantErr = []string{
+					`failed to load retry configuration for outbound "bar"`,
+					`failed to build middleware "retry": label is required`,
+				}
+				return
+			},
+		},
+		{
+			desc: "retry without registered middleware",
+			test: func(t *testing.T, mockCtrl *gomock.Controller) (tt testCase) {
+				type outboundConfig struct{ Address string }
+				tt.give = whitespace.Expand(`
+					outbounds:
+						bar:
+							tchannel:
+								address: localhost:4040
+							retry:
+								retries: 2
+				`)
+
+				tchan := mockTransportSpecBuilder{
+					Name:                "tchannel",
+					TransportConfig:     _typeOfEmptyStruct,
+					UnaryOutboundConfig: reflect.TypeOf(&outboundConfig{}),
+				}.Build(mockCtrl)
+
+				tt.specs = []TransportSpec{tchan.Spec()}
+				tt.wantErr = []string{
+					`failed to load retry configuration for outbound "bar"`,
+					`unknown middleware "retry"`,
+				}
+				return
+			},
+		},
+		{
+			desc: "concurrency build failure",
+			test: func(t *testing.T, mockCtrl *gomock.Controller) (tt testCase) {
+				tt.give = whitespace.Expand(`
+					concurrency: {}
+				`)
+				tt.middleware = legacyMiddlewareSpecs(new([]string))
+				tt.wantErr = []string{
+					"failed to load concurrency configuration",
+					`failed to build middleware "concurrency": label is required`,
+				}
+				return
+			},
+		},
+		{
+			desc: "concurrency without registered middleware",
+			test: func(t *testing.T, mockCtrl *gomock.Controller) (tt testCase) {
+				tt.give = whitespace.Expand(`
+					concurrency:
+						maxPerCaller: 1
+				`)
+				tt.wantErr = []string{
+					"failed to load concurrency configuration",
+					`unknown middleware "concurrency"`,
+				}
+				return
+			},
+		},
 		{
 			desc: "interpolated string",
 			test: func(t *testing.T, mockCtrl *gomock.Controller) (tt testCase) {
@@ -1095,6 +1440,12 @@ func TestConfigurator(t *testing.T) {
 					require.NoError(t, err, "failed to register transport %q", spec.Name)
 				}
 			}
+			for _, spec := range tt.middleware {
+				require.NoError(t, cfg.RegisterMiddleware(spec), "failed to register middleware %q", spec.Name)
+			}
+			if tt.split != nil {
+				require.NoError(t, cfg.RegisterSplit(*tt.split), "failed to register SplitSpec")
+			}
 
 			var (
 				gotConfig yarpc.Config
@@ -1122,3 +1473,418 @@ func TestConfigurator(t *testing.T) {
 		})
 	}
 }
+
+func TestReloadKeepsConcurrencyMiddleware(t *testing.T) {
+	var builds int
+	cfg := New()
+	require.NoError(t, cfg.RegisterMiddleware(MiddlewareSpec{
+		Name: "concurrency",
+		BuildMiddleware: func(c recordingConfig, _ *Kit) (*recordingInboundMiddleware, error) {
+			builds++
+			return &recordingInboundMiddleware{r: &recordingMiddleware{label: c.Label}}, nil
+		},
+	}))
+
+	c, reloader, err := cfg.LoadReloadableConfigFromYAML("foo", strings.NewReader(whitespace.Expand(`
+		concurrency:
+			label: a
+	`)))
+	require.NoError(t, err)
+	d := yarpc.NewDispatcher(c)
+
+	require.NotNil(t, reloader.state.Concurrency, "expected concurrency middleware")
+	mw := reloader.state.Concurrency.Middleware.UnaryInbound
+	require.NotNil(t, mw, "expected unary inbound middleware")
+
+	require.NoError(t, reloader.ReloadFromYAML(d, strings.NewReader(whitespace.Expand(`
+		concurrency:
+			label: a
+	`))))
+	assert.Equal(t, 1, builds, "middleware must be kept if its configuration did not change")
+	assert.True(t, mw == reloader.state.Concurrency.Middleware.UnaryInbound,
+		"middleware must be kept if its configuration did not change")
+
+	require.NoError(t, reloader.ReloadFromYAML(d, strings.NewReader(whitespace.Expand(`
+		concurrency:
+			label: b
+	`))))
+	assert.Equal(t, 2, builds, "middleware must be rebuilt if its configuration changed")
+	assert.False(t, mw == reloader.state.Concurrency.Middleware.UnaryInbound,
+		"middleware must be rebuilt if its configuration changed")
+	assert.Equal(t, reloader.state.Concurrency.Middleware.UnaryInbound, reloader.state.Inbound.load().Unary,
+		"new middleware must be used")
+}
+
+type recordingConfig struct {
+	Label string `config:"label"`
+}
+
+// recordingMiddleware is a unary inbound and unary outbound middleware that
+// records its label when it is called.
+type recordingMiddleware struct {
+	label string
+	log   *[]string
+}
+
+func recordingMiddlewareSpec(log *[]string) MiddlewareSpec {
+	return MiddlewareSpec{
+		Name: "record",
+		BuildMiddleware: func(c recordingConfig, _ *Kit) (*recordingMiddleware, error) {
+			if c.Label == "" {
+				return nil, errors.New("label is required")
+			}
+			return &recordingMiddleware{label: c.Label, log: log}, nil
+		},
+	}
+}
+
+func (m *recordingMiddleware) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter, h transport.UnaryHandler) error {
+	*m.log = append(*m.log, m.label)
+	return h.Handle(ctx, req, resw)
+}
+
+func (m *recordingMiddleware) Call(ctx context.Context, req *transport.Request, out transport.UnaryOutbound) (*transport.Response, error) {
+	*m.log = append(*m.log, m.label)
+	return out.Call(ctx, req)
+}
+
+// recordingInboundMiddleware and recordingOutboundMiddleware are
+// recordingMiddleware which support only inbound or only outbound requests.
+type recordingInboundMiddleware struct{ r *recordingMiddleware }
+
+func (m *recordingInboundMiddleware) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter, h transport.UnaryHandler) error {
+	return m.r.Handle(ctx, req, resw, h)
+}
+
+type recordingOutboundMiddleware struct{ r *recordingMiddleware }
+
+func (m *recordingOutboundMiddleware) Call(ctx context.Context, req *transport.Request, out transport.UnaryOutbound) (*transport.Response, error) {
+	return m.r.Call(ctx, req, out)
+}
+
+// legacyMiddlewareSpecs returns stand-ins for the MiddlewareSpecs of
+// go.uber.org/yarpc/x/retry and go.uber.org/yarpc/x/concurrency, which the
+// 'retry' and 'concurrency' keys use. Like those, "retry" supports only
+// outbound requests and "concurrency" only inbound requests.
+func legacyMiddlewareSpecs(log *[]string) []MiddlewareSpec {
+	return []MiddlewareSpec{
+		{
+			Name: "retry",
+			BuildMiddleware: func(c recordingConfig, _ *Kit) (*recordingOutboundMiddleware, error) {
+				if c.Label == "" {
+					return nil, errors.New("label is required")
+				}
+				return &recordingOutboundMiddleware{r: &recordingMiddleware{label: c.Label, log: log}}, nil
+			},
+		},
+		{
+			Name: "concurrency",
+			BuildMiddleware: func(c recordingConfig, _ *Kit) (*recordingInboundMiddleware, error) {
+				if c.Label == "" {
+					return nil, errors.New("label is required")
+				}
+				return &recordingInboundMiddleware{r: &recordingMiddleware{label: c.Label, log: log}}, nil
+			},
+		},
+	}
+}
+
+// nopSplitSpec returns a SplitSpec for tests which fail before split
+// outbounds are built.
+func nopSplitSpec() *SplitSpec {
+	return &SplitSpec{
+		BuildSplit: func([]SplitTarget, SplitOptions) (SplitOutbound, error) {
+			return nil, errors.New("split outbounds are not supported")
+		},
+	}
+}
+
+func TestConfiguratorRegisterMiddleware(t *testing.T) {
+	cfg := New()
+
+	err := cfg.RegisterMiddleware(MiddlewareSpec{})
+	require.Error(t, err, "expected failure")
+	assert.Contains(t, err.Error(), "name is required")
+
+	err = cfg.RegisterMiddleware(MiddlewareSpec{Name: "foo", BuildMiddleware: 42})
+	require.Error(t, err, "expected failure")
+	assert.Contains(t, err.Error(), `invalid MiddlewareSpec for "foo"`)
+
+	assert.Panics(t, func() {
+		cfg.MustRegisterMiddleware(MiddlewareSpec{Name: "foo"})
+	})
+}
+
+func TestConfiguratorMiddleware(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	type outboundConfig struct{ Address string }
+	tchan := mockTransportSpecBuilder{
+		Name:                "tchannel",
+		TransportConfig:     _typeOfEmptyStruct,
+		UnaryOutboundConfig: reflect.TypeOf(&outboundConfig{}),
+	}.Build(mockCtrl)
+
+	trans := transporttest.NewMockTransport(mockCtrl)
+	outbound := transporttest.NewMockUnaryOutbound(mockCtrl)
+	tchan.EXPECT().BuildTransport(struct{}{}, kitMatcher{ServiceName: "foo"}).Return(trans, nil)
+	tchan.EXPECT().
+		BuildUnaryOutbound(&outboundConfig{Address: "localhost:4040"}, trans, kitMatcher{ServiceName: "foo"}).
+		Return(outbound, nil)
+
+	var log []string
+	cfg := New()
+	require.NoError(t, cfg.RegisterTransport(tchan.Spec()))
+	require.NoError(t, cfg.RegisterMiddleware(recordingMiddlewareSpec(&log)))
+	for _, spec := range legacyMiddlewareSpecs(&log) {
+		require.NoError(t, cfg.RegisterMiddleware(spec))
+	}
+
+	c, err := cfg.LoadConfigFromYAML("foo", strings.NewReader(whitespace.Expand(`
+		middleware:
+			inbound:
+				- record: {label: in1}
+				- record: {label: in2}
+			outbound:
+				- record: {label: out}
+		concurrency:
+			label: limit
+		outbounds:
+			bar:
+				tchannel:
+					address: localhost:4040
+				retry:
+					label: retry
+				middleware:
+					- record: {label: bar1}
+					- record: {label: bar2}
+	`)))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	t.Run("inbound", func(t *testing.T) {
+		log = nil
+
+		// None of the middleware supports stream or oneway requests.
+		assert.Nil(t, c.InboundMiddleware.Oneway)
+		assert.Nil(t, c.InboundMiddleware.Stream)
+
+		h := transporttest.NewMockUnaryHandler(mockCtrl)
+		h.EXPECT().Handle(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+
+		req := &transport.Request{Caller: "baz", Procedure: "getValue"}
+		require.NoError(t, c.InboundMiddleware.Unary.Handle(ctx, req, nil, h))
+		assert.Equal(t, []string{"limit", "in1", "in2"}, log)
+	})
+
+	t.Run("outbound", func(t *testing.T) {
+		log = nil
+
+		assert.Nil(t, c.OutboundMiddleware.Oneway)
+		assert.Nil(t, c.OutboundMiddleware.Stream)
+
+		res := &transport.Response{}
+		outbound.EXPECT().Call(gomock.Any(), gomock.Any()).Return(res, nil)
+
+		got, err := c.OutboundMiddleware.Unary.Call(ctx, &transport.Request{}, c.Outbounds["bar"].Unary)
+		require.NoError(t, err)
+		assert.Equal(t, res, got)
+		assert.Equal(t, []string{"out", "retry", "bar1", "bar2"}, log)
+	})
+}
+
+func TestConfiguratorMiddlewareErrors(t *testing.T) {
+	tests := []struct {
+		desc    string
+		give    string
+		wantErr []string
+	}{
+		{
+			desc: "unknown middleware",
+			give: `
+				middleware:
+					inbound:
+						- auth
+			`,
+			wantErr: []string{
+				"failed to load inbound middleware",
+				`unknown middleware "auth"`,
+			},
+		},
+		{
+			desc: "inbound middleware used for outbound",
+			give: `
+				middleware:
+					outbound:
+						- concurrency
+			`,
+			wantErr: []string{
+				"failed to load outbound middleware",
+				`middleware "concurrency" does not support outbound requests`,
+			},
+		},
+		{
+			desc: "outbound middleware used for inbound",
+			give: `
+				middleware:
+					inbound:
+						- retry: {label: a}
+			`,
+			wantErr: []string{
+				"failed to load inbound middleware",
+				`middleware "retry" does not support inbound requests`,
+			},
+		},
+		{
+			desc: "too many names",
+			give: `
+				middleware:
+					inbound:
+						- record: {label: a}
+						  concurrency: {label: b}
+			`,
+			wantErr: []string{
+				"middleware must be a name or a mapping with exactly one name, found 2",
+			},
+		},
+		{
+			desc: "invalid attributes",
+			give: `
+				middleware:
+					inbound:
+						- record: {colour: red}
+			`,
+			wantErr: []string{
+				`failed to decode configuration for middleware "record"`,
+			},
+		},
+		{
+			desc: "build failure",
+			give: `
+				middleware:
+					outbound:
+						- record
+			`,
+			wantErr: []string{
+				`failed to build middleware "record": label is required`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			var log []string
+			cfg := New()
+			require.NoError(t, cfg.RegisterMiddleware(recordingMiddlewareSpec(&log)))
+			for _, spec := range legacyMiddlewareSpecs(&log) {
+				require.NoError(t, cfg.RegisterMiddleware(spec))
+			}
+
+			_, err := cfg.LoadConfigFromYAML("foo", strings.NewReader(whitespace.Expand(tt.give)))
+			require.Error(t, err, "expected failure")
+			for _, msg := range tt.wantErr {
+				assert.Contains(t, err.Error(), msg)
+			}
+		})
+	}
+}
+
+func TestConfiguratorObservability(t *testing.T) {
+	cfg, err := New().LoadConfigFromYAML("foo", strings.NewReader(whitespace.Expand(`
+		logging:
+			level: warn
+			sampling:
+				initial: 10
+				thereafter: 100
+			requestFields: [procedure, source]
+		metrics:
+			tallyPushInterval: 2s
+	`)))
+	require.NoError(t, err)
+
+	require.NotNil(t, cfg.Logging.Level, "level must be set")
+	assert.Equal(t, zapcore.WarnLevel, *cfg.Logging.Level)
+	assert.Equal(t, &yarpc.LogSamplingConfig{Initial: 10, Thereafter: 100}, cfg.Logging.Sampling)
+	assert.Equal(t, []string{"procedure", "source"}, cfg.Logging.RequestFields)
+	assert.Equal(t, 2*time.Second, cfg.Metrics.TallyPushInterval)
+
+	assert.Nil(t, cfg.Logging.Zap, "logger must be provided by the user")
+	assert.Nil(t, cfg.Metrics.Tally, "Tally scope must be provided by the user")
+}
+
+func TestConfiguratorObservabilityErrors(t *testing.T) {
+	tests := []struct {
+		desc    string
+		give    string
+		wantErr []string
+	}{
+		{
+			desc: "invalid level",
+			give: `
+				logging:
+					level: loud
+			`,
+			wantErr: []string{
+				"failed to load logging configuration",
+				`invalid level "loud"`,
+			},
+		},
+		{
+			desc: "invalid sampling",
+			give: `
+				logging:
+					sampling:
+						initial: 10
+			`,
+			wantErr: []string{
+				"failed to load logging configuration",
+				"sampling.thereafter must be positive: 0",
+			},
+		},
+		{
+			desc: "unknown request field",
+			give: `
+				logging:
+					requestFields: [body]
+			`,
+			wantErr: []string{
+				"failed to load logging configuration",
+				`unknown request field "body"`,
+			},
+		},
+		{
+			desc: "negative push interval",
+			give: `
+				metrics:
+					tallyPushInterval: -1s
+			`,
+			wantErr: []string{
+				"failed to load metrics configuration",
+				"tallyPushInterval must not be negative: -1s",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			_, err := New().LoadConfigFromYAML("foo", strings.NewReader(whitespace.Expand(tt.give)))
+			require.Error(t, err, "expected failure")
+			for _, msg := range tt.wantErr {
+				assert.Contains(t, err.Error(), msg)
+			}
+		})
+	}
+}
+
+func TestConfiguratorDrainTimeout(t *testing.T) {
+	cfg, err := New().LoadConfigFromYAML("foo", strings.NewReader("drainTimeout: 5s"))
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, cfg.DrainTimeout)
+
+	_, err = New().LoadConfigFromYAML("foo", strings.NewReader("drainTimeout: -1s"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "drainTimeout must not be negative: -1s")
+}
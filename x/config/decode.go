@@ -23,6 +23,7 @@ package config
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/uber-go/mapdecode"
 )
@@ -73,9 +74,15 @@ func (m attributeMap) Decode(dst interface{}, opts ...mapdecode.Option) error {
 }
 
 type yarpcConfig struct {
-	Inbounds   inbounds                `config:"inbounds"`
-	Outbounds  clientConfigs           `config:"outbounds"`
-	Transports map[string]attributeMap `config:"transports"`
+	Inbounds    inbounds                `config:"inbounds"`
+	Outbounds   clientConfigs           `config:"outbounds"`
+	Transports  map[string]attributeMap `config:"transports"`
+	Concurrency attributeMap            `config:"concurrency"`
+	Middleware  middlewareConfigs       `config:"middleware"`
+	Logging     loggingConfig           `config:"logging"`
+	Metrics     metricsConfig           `config:"metrics"`
+
+	DrainTimeout time.Duration `config:"drainTimeout"`
 }
 
 type inbounds []inbound
@@ -146,6 +153,16 @@ type outbounds struct {
 	Unary    *outbound
 	Oneway   *outbound
 	Implicit *outbound
+
+	// If set, requests are split between the targets listed in it and
+	// Unary, Oneway and Implicit are unset.
+	Split *splitConfig
+
+	// Configuration of the "retry" middleware for the outbound, if any.
+	Retry attributeMap
+
+	// Middleware applied to requests made through this outbound, in order.
+	Middleware middlewareChain
 }
 
 func (o *outbounds) Decode(into mapdecode.Into) error {
@@ -160,6 +177,19 @@ func (o *outbounds) Decode(into mapdecode.Into) error {
 		return fmt.Errorf("failed to read service name for outbound: %v", err)
 	}
 
+	if _, err := attrs.Pop("retry", &o.Retry); err != nil {
+		return fmt.Errorf("failed to read retry configuration for outbound: %v", err)
+	}
+
+	if _, err := attrs.Pop("middleware", &o.Middleware); err != nil {
+		return fmt.Errorf("failed to read middleware configuration for outbound: %v", err)
+	}
+
+	hasSplit, err := attrs.Pop("split", &o.Split)
+	if err != nil {
+		return fmt.Errorf("failed to read split configuration for outbound: %v", err)
+	}
+
 	hasUnary, err := attrs.Pop("unary", &o.Unary)
 	if err != nil {
 		return fmt.Errorf("failed to unary outbound configuration: %v", err)
@@ -170,7 +200,11 @@ func (o *outbounds) Decode(into mapdecode.Into) error {
 		return fmt.Errorf("failed to oneway outbound configuration: %v", err)
 	}
 
-	if hasUnary || hasOneway {
+	if hasSplit && (hasUnary || hasOneway) {
+		return errors.New("a split outbound cannot have unary or oneway outbounds")
+	}
+
+	if hasSplit || hasUnary || hasOneway {
 		// No more attributes should be remaining
 		var empty struct{}
 		if err := attrs.Decode(&empty); err != nil {
@@ -191,6 +225,48 @@ type outbound struct {
 	Attributes attributeMap
 }
 
+// splitConfig is the configuration of an outbound which splits requests
+// between other outbounds. See go.uber.org/yarpc/x/split.
+type splitConfig struct {
+	// Either empty, "shardKey" or "header".
+	StickyBy string `config:"stickyBy"`
+
+	// Header by which requests are sticky if StickyBy is "header".
+	Header string `config:"header"`
+
+	Targets []splitTarget `config:"targets"`
+}
+
+// splitTarget is a named and weighted outbound configuration with exactly
+// one transport, like an implicit outbound.
+type splitTarget struct {
+	Name     string
+	Weight   int
+	Outbound outbound
+}
+
+func (t *splitTarget) Decode(into mapdecode.Into) error {
+	var attrs attributeMap
+	if err := into(&attrs); err != nil {
+		return fmt.Errorf("failed to decode split target: %v", err)
+	}
+
+	var err error
+	t.Name, err = attrs.PopString("name")
+	if err != nil {
+		return fmt.Errorf("failed to read name of split target: %v", err)
+	}
+
+	if _, err := attrs.Pop("weight", &t.Weight); err != nil {
+		return fmt.Errorf("failed to read weight of split target %q: %v", t.Name, err)
+	}
+
+	if err := attrs.Decode(&t.Outbound); err != nil {
+		return fmt.Errorf("failed to decode split target %q: %v", t.Name, err)
+	}
+	return nil
+}
+
 func (o *outbound) Decode(into mapdecode.Into) error {
 	var cfg map[string]attributeMap
 	if err := into(&cfg); err != nil {
@@ -78,7 +78,7 @@
 // as long as the information provided is the same.
 //
 // The configuration accepts the following top-level attributes: transports,
-// inbounds, and outbounds.
+// inbounds, outbounds, concurrency, middleware, logging, and metrics.
 //
 // 	inbounds:
 // 	  # ...
@@ -86,9 +86,18 @@
 // 	  # ...
 // 	transports:
 // 	  # ...
+// 	concurrency:
+// 	  # ...
+// 	middleware:
+// 	  # ...
+// 	logging:
+// 	  # ...
+// 	metrics:
+// 	  # ...
 //
-// See the following sections for details on the transports, inbounds, and
-// outbounds keys in the configuration.
+// See the following sections for details on the transports, inbounds,
+// outbounds, concurrency, middleware, logging, and metrics keys in the
+// configuration.
 //
 // Inbound Configuration
 //
@@ -188,6 +197,60 @@
 // 	  oneway:
 // 	    # ...
 //
+// Unary requests made through an outbound may be retried by adding a 'retry'
+// key to its configuration. The key configures the middleware registered as
+// "retry", usually the MiddlewareSpec of go.uber.org/yarpc/x/retry, which
+// allows policies to be overridden for individual procedures. See that
+// package for all available options.
+//
+// 	keyvalue:
+// 	  http:
+// 	    url: http://127.0.0.1:8080/
+// 	  retry:
+// 	    retries: 2
+// 	    timeout: 100ms
+// 	    procedures:
+// 	      KeyValue::setValue:
+// 	        retries: 0
+//
+// Requests made through an outbound may be split by weight between several
+// outbounds, for example to send a share of traffic to a canary, by listing
+// them as targets under a 'split' key instead of specifying a transport. Each
+// target has a unique name, a weight, and exactly one transport, like an
+// implicit outbound. Requests are picked at random unless 'stickyBy' is
+// "shardKey" or "header", in which case requests with the same shard key or
+// value for the given header go to the same target. Changes to the weights
+// alone may be reloaded. Split outbounds are built with the SplitSpec
+// registered with RegisterSplit, usually the Spec of
+// go.uber.org/yarpc/x/split. See that package for details.
+//
+// 	keyvalue:
+// 	  split:
+// 	    stickyBy: header
+// 	    header: user-id
+// 	    targets:
+// 	      - name: stable
+// 	        weight: 95
+// 	        http:
+// 	          url: http://127.0.0.1:8080/
+// 	      - name: canary
+// 	        weight: 5
+// 	        tchannel:
+// 	          peer: 127.0.0.1:4040
+//
+// A split outbound makes unary requests if the transports of all of its
+// targets support them, and oneway requests if they all support those.
+//
+// Other middleware may be applied to the requests made through an outbound
+// with the 'middleware' key. See Middleware Configuration below for details.
+//
+// 	keyvalue:
+// 	  http:
+// 	    url: http://127.0.0.1:8080/
+// 	  middleware:
+// 	    - rate-limit:
+// 	        rps: 100
+//
 // Peer Configuration
 //
 // Transports that support peer management and selection through YARPC accept
@@ -245,11 +308,128 @@
 // (For details on the configuration parameters of individual transport types,
 // check the documentation for the corresponding transport package.)
 //
+// Concurrency Configuration
+//
+// The 'concurrency' attribute configures the middleware registered as
+// "concurrency", usually the MiddlewareSpec of
+// go.uber.org/yarpc/x/concurrency, which limits the number of requests
+// handled concurrently by the Dispatcher, per procedure and per caller.
+// Requests over the limit are rejected with a resource-exhausted error. See
+// that package for all available options.
+//
+// 	concurrency:
+// 	  maxPerProcedure: 100
+// 	  maxPerCaller: 20
+// 	  procedures:
+// 	    KeyValue::getValue: 500
+//
+// Middleware Configuration
+//
+// The 'middleware' attribute specifies ordered lists of middleware applied to
+// all inbound requests and to all outbound requests. Middleware earlier in a
+// list sees the request first. Each entry is either the name of a middleware
+// or a mapping from the name of a middleware to its configuration.
+//
+// 	middleware:
+// 	  inbound:
+// 	    - auth:
+// 	        keys: /etc/myservice/keys
+// 	  outbound:
+// 	    - tracing
+// 	    - retry:
+// 	        retries: 1
+//
+// Middleware must be registered with the Configurator using
+// RegisterMiddleware before it may be used. This includes the "retry" and
+// "concurrency" middleware, which accept the same options as the 'retry'
+// and 'concurrency' keys.
+//
+// Middleware specified with the 'concurrency' key runs before the inbound
+// middleware list, and middleware specified with the 'retry' key of an
+// outbound runs before the 'middleware' list of that outbound.
+//
+// Logging and Metrics Configuration
+//
+// The 'logging' attribute controls the logs emitted by the Dispatcher. The
+// minimum log level, log sampling, and the request fields included in
+// request logs may be specified.
+//
+// 	logging:
+// 	  level: info
+// 	  sampling:
+// 	    initial: 100
+// 	    thereafter: 100
+// 	    tick: 1s
+// 	  requestFields: [source, dest, procedure]
+//
+// The request fields may be any of source, dest, procedure, encoding,
+// shardKey, routingKey, and routingDelegate. All of them are logged by
+// default.
+//
+// The 'metrics' attribute controls how metrics are reported.
+//
+// 	metrics:
+// 	  tallyPushInterval: 1s
+//
+// The logger and Tally scope are not part of the configuration. Set them on
+// the yarpc.Config returned by LoadConfig before building the Dispatcher.
+//
+// 	c, err := cfg.LoadConfigFromYAML("myservice", yamlConfig)
+// 	if err != nil {
+// 		log.Fatal(err)
+// 	}
+// 	c.Logging.Zap = logger
+// 	c.Metrics.Tally = scope
+// 	dispatcher := yarpc.NewDispatcher(c)
+//
+// Drain Configuration
+//
+// The 'drainTimeout' attribute specifies how long the Dispatcher waits for
+// requests in flight to finish when it is stopped. New requests are rejected
+// while the Dispatcher drains. The Dispatcher does not drain by default.
+//
+// 	drainTimeout: 10s
+//
+// HTTP inbounds may use the 'healthPath' attribute to report that they are
+// no longer ready while the Dispatcher drains.
+//
+// 	inbounds:
+// 	  http:
+// 	    address: ":80"
+// 	    healthPath: /health
+//
+// Reloading Configuration
+//
+// Configuration loaded with LoadReloadableConfigFromYAML may be changed after
+// the Dispatcher has been built and started. The returned Reloader applies
+// the new configuration to the running Dispatcher.
+//
+// 	c, reloader, err := cfg.LoadReloadableConfigFromYAML("myservice", yamlConfig)
+// 	if err != nil {
+// 		log.Fatal(err)
+// 	}
+// 	dispatcher := yarpc.NewDispatcher(c)
+// 	if err := dispatcher.Start(); err != nil {
+// 		log.Fatal(err)
+// 	}
+// 	// ...
+// 	if err := reloader.ReloadFromYAML(dispatcher, newYAMLConfig); err != nil {
+// 		log.Print(err)
+// 	}
+//
+// Middleware, concurrency limits, and retries may be changed; outbounds may
+// be added or removed; and the explicit list of 'peers' of an outbound may be
+// changed. Requests already in flight are not affected. Any other change,
+// such as changes to inbounds, transports, the drain timeout, or the logging
+// and metrics configuration, is rejected with a ReloadError listing those changes and
+// nothing is applied.
+//
 // Customizing Configuration
 //
-// When building your own TransportSpec, PeerListSpec, or PeerListUpdaterSpec,
-// you will define functions accepting structs or pointers to structs which
-// define the different configuration parameters needed to build that entity.
+// When building your own TransportSpec, PeerListSpec, PeerListUpdaterSpec, or
+// MiddlewareSpec, you will define functions accepting structs or pointers to
+// structs which define the different configuration parameters needed to
+// build that entity.
 // These configuration parameters will be decoded from the user-specified
 // configuration using a case-insensitive match on the field names.
 //
@@ -26,6 +26,10 @@ import (
 	"reflect"
 	"sort"
 	"strings"
+
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/peer/hostport"
 )
 
 // Kit is an opaque object that carries context for the Configurator. Build
@@ -37,6 +41,14 @@ type Kit struct {
 
 	// TransportSpec currently being used. This may or may not be set.
 	transportSpec *compiledTransportSpec
+
+	// Function used by the outbound currently being built to convert peer
+	// names into peer identifiers. This may or may not be set.
+	identify func(string) peer.Identifier
+
+	// Function called with the explicit list of peers built for the outbound
+	// currently being built, if any. This may or may not be set.
+	staticPeersHook func(*staticPeers)
 }
 
 // Returns a shallow copy of this Kit with spec set to the given value.
@@ -46,10 +58,55 @@ func (k *Kit) withTransportSpec(spec *compiledTransportSpec) *Kit {
 	return &newK
 }
 
+// Returns a shallow copy of this Kit with identify set to the given value.
+func (k *Kit) withIdentify(identify func(string) peer.Identifier) *Kit {
+	newK := *k
+	newK.identify = identify
+	return &newK
+}
+
+// Returns a shallow copy of this Kit with staticPeersHook set to the given
+// value.
+func (k *Kit) withStaticPeersHook(hook func(*staticPeers)) *Kit {
+	newK := *k
+	newK.staticPeersHook = hook
+	return &newK
+}
+
 // ServiceName returns the name of the service for which components are being
 // built.
 func (k *Kit) ServiceName() string { return k.name }
 
+// Identify converts the given peer name into a peer identifier for the
+// transport of the outbound being built. Peer list updaters should use this
+// to identify the peers they discover.
+//
+// Peer names are treated as host:port pairs if the transport did not specify
+// otherwise.
+func (k *Kit) Identify(name string) peer.Identifier {
+	if k.identify == nil {
+		return hostport.Identify(name)
+	}
+	return k.identify(name)
+}
+
+// Compressor returns the compressor registered with the given name.
+// Transports that support compression should use this to look up the
+// compressor named by the "compressor" attribute of their inbounds and
+// outbounds.
+func (k *Kit) Compressor(name string) (transport.Compressor, error) {
+	if c := k.c.knownCompressors[name]; c != nil {
+		return c, nil
+	}
+
+	msg := fmt.Sprintf("no recognized compressor %q", name)
+	if available := k.compressorNames(); len(available) > 0 {
+		msg = fmt.Sprintf("%s; need one of %s", msg, strings.Join(available, ", "))
+	}
+
+	return nil, errors.New(msg)
+}
+
 var _typeOfKit = reflect.TypeOf((*Kit)(nil))
 
 func (k *Kit) peerListSpec(name string) (*compiledPeerListSpec, error) {
@@ -108,3 +165,11 @@ func (k *Kit) peerListUpdaterSpecNames() (names []string) {
 	sort.Strings(names)
 	return
 }
+
+func (k *Kit) compressorNames() (names []string) {
+	for name := range k.c.knownCompressors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
@@ -23,7 +23,14 @@ package config
 import (
 	"testing"
 
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/compressor/gzip"
+	"go.uber.org/yarpc/compressor/snappy"
+	"go.uber.org/yarpc/peer/hostport"
+
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestKitWithTransportSpec(t *testing.T) {
@@ -40,3 +47,51 @@ func TestKitWithTransportSpec(t *testing.T) {
 	assert.Equal(t, "foo", root.ServiceName())
 	assert.Equal(t, "bar", child.ServiceName())
 }
+
+type fakeIdentifier string
+
+func (i fakeIdentifier) Identifier() string { return "fake:" + string(i) }
+
+func TestKitWithIdentify(t *testing.T) {
+	root := &Kit{name: "foo"}
+	assert.Equal(t, hostport.PeerIdentifier("127.0.0.1:80"), root.Identify("127.0.0.1:80"))
+
+	child := root.withIdentify(func(name string) peer.Identifier {
+		return fakeIdentifier(name)
+	})
+	assert.Equal(t, fakeIdentifier("foo"), child.Identify("foo"))
+	assert.Equal(t, hostport.PeerIdentifier("foo"), root.Identify("foo"))
+}
+
+type fakeCompressor struct{ transport.Compressor }
+
+func (fakeCompressor) Name() string { return "fake" }
+
+type namelessCompressor struct{ transport.Compressor }
+
+func (namelessCompressor) Name() string { return "" }
+
+func TestKitCompressor(t *testing.T) {
+	c := New()
+	k := &Kit{c: c, name: "foo"}
+
+	_, err := k.Compressor("gzip")
+	assert.EqualError(t, err, `no recognized compressor "gzip"`, "no compressors must be registered by default")
+
+	require.NoError(t, c.RegisterCompressor(gzip.New()))
+	require.NoError(t, c.RegisterCompressor(snappy.New()))
+
+	gz, err := k.Compressor("gzip")
+	require.NoError(t, err)
+	assert.Equal(t, "gzip", gz.Name())
+
+	_, err = k.Compressor("fake")
+	assert.EqualError(t, err, `no recognized compressor "fake"; need one of gzip, snappy`)
+
+	require.NoError(t, c.RegisterCompressor(fakeCompressor{}))
+	fake, err := k.Compressor("fake")
+	require.NoError(t, err)
+	assert.Equal(t, fakeCompressor{}, fake)
+
+	assert.Error(t, c.RegisterCompressor(namelessCompressor{}), "expected failure without a name")
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package config
+
+import (
+	"fmt"
+
+	"go.uber.org/yarpc/api/middleware"
+
+	"github.com/uber-go/mapdecode"
+)
+
+// middlewareConfigs is the top-level 'middleware' section of the
+// configuration.
+type middlewareConfigs struct {
+	Inbound  middlewareChain `config:"inbound"`
+	Outbound middlewareChain `config:"outbound"`
+}
+
+// middlewareChain is an ordered list of middleware configurations. The first
+// middleware in the list sees the request first.
+type middlewareChain []middlewareConfig
+
+// middlewareConfig is a single entry in a middlewareChain. It is either the
+// name of the middleware,
+//
+// 	- tracing
+//
+// Or a mapping from the name of the middleware to its attributes.
+//
+// 	- rate-limit:
+// 	    rps: 100
+type middlewareConfig struct {
+	Name       string
+	Attributes attributeMap
+}
+
+func (m *middlewareConfig) Decode(into mapdecode.Into) error {
+	if err := into(&m.Name); err == nil {
+		m.Attributes = attributeMap{}
+		return nil
+	}
+
+	var items map[string]attributeMap
+	if err := into(&items); err != nil {
+		return fmt.Errorf("failed to decode middleware: %v", err)
+	}
+
+	if len(items) != 1 {
+		return fmt.Errorf("middleware must be a name or a mapping with exactly one name, found %d", len(items))
+	}
+
+	for name, attrs := range items {
+		m.Name = name
+		m.Attributes = attrs
+	}
+	if m.Attributes == nil {
+		m.Attributes = attributeMap{}
+	}
+	return nil
+}
+
+// builtMiddleware holds the different kinds of middleware implemented by the
+// object built from a MiddlewareSpec. Fields for unsupported kinds are nil.
+type builtMiddleware struct {
+	UnaryInbound   middleware.UnaryInbound
+	OnewayInbound  middleware.OnewayInbound
+	StreamInbound  middleware.StreamInbound
+	UnaryOutbound  middleware.UnaryOutbound
+	OnewayOutbound middleware.OnewayOutbound
+	StreamOutbound middleware.StreamOutbound
+}
+
+func newBuiltMiddleware(v interface{}) builtMiddleware {
+	var m builtMiddleware
+	m.UnaryInbound, _ = v.(middleware.UnaryInbound)
+	m.OnewayInbound, _ = v.(middleware.OnewayInbound)
+	m.StreamInbound, _ = v.(middleware.StreamInbound)
+	m.UnaryOutbound, _ = v.(middleware.UnaryOutbound)
+	m.OnewayOutbound, _ = v.(middleware.OnewayOutbound)
+	m.StreamOutbound, _ = v.(middleware.StreamOutbound)
+	return m
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/internal/observability"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// loggingConfig is the top-level 'logging' section of the configuration.
+type loggingConfig struct {
+	// Minimum level of logs emitted by the Dispatcher.
+	Level string `config:"level"`
+
+	// If set, logs emitted by the Dispatcher will be sampled.
+	Sampling *logSamplingConfig `config:"sampling"`
+
+	// Request fields included in request logs. Defaults to all fields.
+	RequestFields []string `config:"requestFields"`
+}
+
+type logSamplingConfig struct {
+	Initial    int           `config:"initial"`
+	Thereafter int           `config:"thereafter"`
+	Tick       time.Duration `config:"tick"`
+}
+
+// fill fills the given yarpc.LoggingConfig using this configuration. The
+// logger itself is left for the caller to provide.
+func (c loggingConfig) fill(cfg *yarpc.LoggingConfig) error {
+	if c.Level != "" {
+		var level zapcore.Level
+		if err := level.UnmarshalText([]byte(c.Level)); err != nil {
+			return fmt.Errorf("invalid level %q: %v", c.Level, err)
+		}
+		cfg.Level = &level
+	}
+
+	if s := c.Sampling; s != nil {
+		if s.Initial <= 0 {
+			return fmt.Errorf("sampling.initial must be positive: %v", s.Initial)
+		}
+		if s.Thereafter <= 0 {
+			return fmt.Errorf("sampling.thereafter must be positive: %v", s.Thereafter)
+		}
+		if s.Tick < 0 {
+			return fmt.Errorf("sampling.tick must not be negative: %v", s.Tick)
+		}
+		cfg.Sampling = &yarpc.LogSamplingConfig{
+			Initial:    s.Initial,
+			Thereafter: s.Thereafter,
+			Tick:       s.Tick,
+		}
+	}
+
+	if c.RequestFields != nil {
+		if err := observability.ValidateRequestFields(c.RequestFields); err != nil {
+			return err
+		}
+		cfg.RequestFields = c.RequestFields
+	}
+
+	return nil
+}
+
+// metricsConfig is the top-level 'metrics' section of the configuration.
+type metricsConfig struct {
+	// Interval between pushes to Tally.
+	TallyPushInterval time.Duration `config:"tallyPushInterval"`
+}
+
+// fill fills the given yarpc.MetricsConfig using this configuration. The
+// Tally scope itself is left for the caller to provide.
+func (c metricsConfig) fill(cfg *yarpc.MetricsConfig) error {
+	if c.TallyPushInterval < 0 {
+		return fmt.Errorf("tallyPushInterval must not be negative: %v", c.TallyPushInterval)
+	}
+	cfg.TallyPushInterval = c.TallyPushInterval
+	return nil
+}
@@ -0,0 +1,807 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+
+	"go.uber.org/multierr"
+)
+
+// Reloader applies changes in configuration to a running Dispatcher that
+// was built from a configuration returned by LoadReloadableConfig or
+// LoadReloadableConfigFromYAML.
+//
+// The following changes may be applied without rebuilding the Dispatcher:
+//
+//  - Changes to the middleware, including the concurrency limits and the
+//    retry policies of outbounds
+//  - Adding or removing outbounds
+//  - Changes to the explicit list of peers of an outbound
+//  - Changes to the weights of the targets of a split outbound
+//
+// All other changes are rejected with a ReloadError.
+type Reloader struct {
+	mu sync.Mutex
+
+	c           *Configurator
+	serviceName string
+
+	// Configuration that was last applied.
+	snapshot *configSnapshot
+	state    *reloadState
+}
+
+// ReloadError is returned by Reloader if the new configuration differs from
+// the running configuration in ways that cannot be applied to a running
+// Dispatcher.
+type ReloadError struct {
+	// Descriptions of the parts of the configuration that changed and
+	// cannot be reloaded, in sorted order.
+	Changes []string
+}
+
+func (e *ReloadError) Error() string {
+	return "configuration changes cannot be reloaded: " + strings.Join(e.Changes, "; ")
+}
+
+// LoadReloadableConfigFromYAML loads a yarpc.Config from YAML data like
+// LoadConfigFromYAML. The returned Reloader may be used to apply changes to
+// the configuration to the Dispatcher built from this yarpc.Config.
+func (c *Configurator) LoadReloadableConfigFromYAML(serviceName string, r io.Reader) (yarpc.Config, *Reloader, error) {
+	data, err := readYAML(r)
+	if err != nil {
+		return yarpc.Config{}, nil, err
+	}
+	return c.LoadReloadableConfig(serviceName, data)
+}
+
+// LoadReloadableConfig loads a yarpc.Config from a map[string]interface{} or
+// map[interface{}]interface{} like LoadConfig. The returned Reloader may be
+// used to apply changes to the configuration to the Dispatcher built from
+// this yarpc.Config.
+//
+// 	c, reloader, err := cfg.LoadReloadableConfig("myservice", data)
+// 	if err != nil {
+// 		log.Fatal(err)
+// 	}
+// 	dispatcher := yarpc.NewDispatcher(c)
+// 	// ...
+// 	if err := reloader.Reload(dispatcher, newData); err != nil {
+// 		log.Print(err)
+// 	}
+func (c *Configurator) LoadReloadableConfig(serviceName string, data interface{}) (yarpc.Config, *Reloader, error) {
+	var cfg yarpcConfig
+	if err := decodeInto(&cfg, data); err != nil {
+		return yarpc.Config{}, nil, err
+	}
+
+	// The snapshot must be taken before the configuration is loaded
+	// because loading may modify it.
+	snapshot := newConfigSnapshot(&cfg)
+
+	b := c.newBuilder(serviceName)
+	b.reload = newReloadState()
+	if err := c.populate(b, &cfg); err != nil {
+		return yarpc.Config{}, nil, err
+	}
+
+	ycfg, err := b.Build()
+	if err != nil {
+		return yarpc.Config{}, nil, err
+	}
+
+	return ycfg, &Reloader{
+		c:           c,
+		serviceName: serviceName,
+		snapshot:    snapshot,
+		state:       b.reload,
+	}, nil
+}
+
+// ReloadFromYAML applies the configuration in the given YAML data to the
+// given Dispatcher. See Reload for details.
+func (r *Reloader) ReloadFromYAML(d *yarpc.Dispatcher, rd io.Reader) error {
+	data, err := readYAML(rd)
+	if err != nil {
+		return err
+	}
+	return r.Reload(d, data)
+}
+
+// Reload applies the given configuration to the given Dispatcher. The
+// Dispatcher MUST have been built from the yarpc.Config returned with this
+// Reloader.
+//
+// A ReloadError listing the changes that cannot be applied is returned if
+// the new configuration cannot be applied to a running Dispatcher. Nothing
+// is changed in that case, or if the new configuration is invalid.
+//
+// Outbounds are added, removed, and updated before the middleware is
+// changed. If some of them fail, the remaining changes are still applied and
+// the errors are returned; outbounds which could not be added are added
+// again by the next Reload.
+//
+// Requests that are already in flight are not affected by the changes. The
+// concurrency limits keep counting them if their configuration did not
+// change.
+func (r *Reloader) Reload(d *yarpc.Dispatcher, data interface{}) error {
+	var cfg yarpcConfig
+	if err := decodeInto(&cfg, data); err != nil {
+		return err
+	}
+	snapshot := newConfigSnapshot(&cfg)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changes, peerUpdates, weightUpdates := r.snapshot.diff(snapshot, r.state)
+	if len(changes) > 0 {
+		return &ReloadError{Changes: changes}
+	}
+
+	b := r.c.newBuilder(r.serviceName)
+	b.reload = r.state
+	if err := r.c.populate(b, &cfg); err != nil {
+		return err
+	}
+
+	// Build everything that is new before changing anything.
+	transports := make(map[string]transport.Transport, len(r.state.Transports))
+	for name, t := range r.state.Transports {
+		transports[name] = t
+	}
+	if err := b.buildTransports(transports); err != nil {
+		return err
+	}
+
+	type addedOutbound struct {
+		Outbounds transport.Outbounds
+		Reload    *reloadableOutbound
+	}
+	added := make(map[string]addedOutbound)
+	for name, c := range b.clients {
+		if _, ok := r.state.Outbounds[name]; ok {
+			continue
+		}
+
+		ob, ro, err := b.buildOutbounds(name, c, transports)
+		if err != nil {
+			return err
+		}
+		added[name] = addedOutbound{Outbounds: ob, Reload: ro}
+	}
+
+	var errs error
+	for name := range r.state.Outbounds {
+		if _, ok := b.clients[name]; ok {
+			continue
+		}
+		if err := d.RemoveOutbound(name); err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("failed to remove outbound %q: %v", name, err))
+		}
+		delete(r.state.Outbounds, name)
+	}
+
+	for _, u := range peerUpdates {
+		if err := u.Peers.Update(u.Names); err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("failed to update peers of outbound %q: %v", u.Outbound, err))
+		}
+	}
+
+	for _, u := range weightUpdates {
+		if err := u.Split.SetWeights(u.Weights); err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("failed to update weights of outbound %q: %v", u.Outbound, err))
+		}
+	}
+
+	for name, a := range added {
+		if err := d.AddOutbound(name, a.Outbounds); err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("failed to add outbound %q: %v", name, err))
+			// Forget the outbound so that the next Reload adds it again.
+			delete(snapshot.Outbounds, name)
+			continue
+		}
+		r.state.Outbounds[name] = a.Reload
+	}
+
+	// Changing the middleware cannot fail.
+	in, out := b.buildMiddleware()
+	r.state.Inbound.Store(in)
+	r.state.Outbound.Store(out)
+	for name, ro := range r.state.Outbounds {
+		ro.Middleware.Store(b.clients[name].outboundMiddleware())
+	}
+	r.state.Transports = transports
+	r.state.Concurrency = b.concurrency
+
+	r.snapshot = snapshot
+	return errs
+}
+
+// reloadState holds the parts of a configuration built by a Configurator
+// that may be changed after the Dispatcher has been built.
+type reloadState struct {
+	// Transports that were built, keyed by name.
+	Transports map[string]transport.Transport
+
+	// Middleware for all inbound and all outbound requests.
+	Inbound  *reloadableInboundMiddleware
+	Outbound *reloadableOutboundMiddleware
+
+	// Middleware specified with the top-level 'concurrency' key and included
+	// in the inbound middleware, if any.
+	Concurrency *builtConcurrency
+
+	// Outbounds keyed by outbound key.
+	Outbounds map[string]*reloadableOutbound
+}
+
+func newReloadState() *reloadState {
+	return &reloadState{
+		Transports: make(map[string]transport.Transport),
+		Inbound:    newReloadableInboundMiddleware(),
+		Outbound:   newReloadableOutboundMiddleware(),
+		Outbounds:  make(map[string]*reloadableOutbound),
+	}
+}
+
+// concurrencyFor returns the current middleware specified with the
+// 'concurrency' key if it was built from the same configuration as the
+// given decoded configuration. This may be called on a nil reloadState.
+func (s *reloadState) concurrencyFor(cv *buildable) (builtMiddleware, bool) {
+	if s == nil || s.Concurrency == nil || !reflect.DeepEqual(s.Concurrency.Config, cv.inputData.Interface()) {
+		return builtMiddleware{}, false
+	}
+	return s.Concurrency.Middleware, true
+}
+
+// builtConcurrency is the middleware specified with the 'concurrency' key
+// and the decoded configuration it was built from.
+type builtConcurrency struct {
+	Config     interface{}
+	Middleware builtMiddleware
+}
+
+// reloadableOutbound holds the parts of an outbound that may be changed
+// after it has been built.
+type reloadableOutbound struct {
+	Middleware *reloadableOutboundMiddleware
+
+	// Explicit list of peers of the unary and oneway outbounds, if any.
+	UnaryPeers  *staticPeers
+	OnewayPeers *staticPeers
+
+	// Unary and oneway outbounds which split requests between targets, if
+	// the outbound is a split outbound.
+	UnarySplit  SplitOutbound
+	OnewaySplit SplitOutbound
+}
+
+// peerUpdate is a change to the explicit list of peers of an outbound.
+type peerUpdate struct {
+	Outbound string
+	Peers    *staticPeers
+	Names    []string
+}
+
+// weightUpdate is a change to the weights of the targets of a split
+// outbound.
+type weightUpdate struct {
+	Outbound string
+	Split    SplitOutbound
+	Weights  map[string]int
+}
+
+// reloadableInboundMiddleware is inbound middleware for all RPC types that
+// calls into the middleware most recently stored in it.
+type reloadableInboundMiddleware struct {
+	v atomic.Value // yarpc.InboundMiddleware
+}
+
+func newReloadableInboundMiddleware() *reloadableInboundMiddleware {
+	m := &reloadableInboundMiddleware{}
+	m.Store(yarpc.InboundMiddleware{})
+	return m
+}
+
+// Store changes the middleware used for new requests.
+func (m *reloadableInboundMiddleware) Store(mw yarpc.InboundMiddleware) {
+	m.v.Store(mw)
+}
+
+// Middleware returns this object as a yarpc.InboundMiddleware.
+func (m *reloadableInboundMiddleware) Middleware() yarpc.InboundMiddleware {
+	return yarpc.InboundMiddleware{Unary: m, Oneway: m, Stream: m}
+}
+
+func (m *reloadableInboundMiddleware) load() yarpc.InboundMiddleware {
+	return m.v.Load().(yarpc.InboundMiddleware)
+}
+
+func (m *reloadableInboundMiddleware) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter, h transport.UnaryHandler) error {
+	if mw := m.load().Unary; mw != nil {
+		return mw.Handle(ctx, req, resw, h)
+	}
+	return h.Handle(ctx, req, resw)
+}
+
+func (m *reloadableInboundMiddleware) HandleOneway(ctx context.Context, req *transport.Request, h transport.OnewayHandler) error {
+	if mw := m.load().Oneway; mw != nil {
+		return mw.HandleOneway(ctx, req, h)
+	}
+	return h.HandleOneway(ctx, req)
+}
+
+func (m *reloadableInboundMiddleware) HandleStream(s transport.Stream, h transport.StreamHandler) error {
+	if mw := m.load().Stream; mw != nil {
+		return mw.HandleStream(s, h)
+	}
+	return h.HandleStream(s)
+}
+
+// reloadableOutboundMiddleware is outbound middleware for all RPC types
+// that calls into the middleware most recently stored in it.
+type reloadableOutboundMiddleware struct {
+	v atomic.Value // yarpc.OutboundMiddleware
+}
+
+func newReloadableOutboundMiddleware() *reloadableOutboundMiddleware {
+	m := &reloadableOutboundMiddleware{}
+	m.Store(yarpc.OutboundMiddleware{})
+	return m
+}
+
+// Store changes the middleware used for new requests.
+func (m *reloadableOutboundMiddleware) Store(mw yarpc.OutboundMiddleware) {
+	m.v.Store(mw)
+}
+
+// Middleware returns this object as a yarpc.OutboundMiddleware.
+func (m *reloadableOutboundMiddleware) Middleware() yarpc.OutboundMiddleware {
+	return yarpc.OutboundMiddleware{Unary: m, Oneway: m, Stream: m}
+}
+
+func (m *reloadableOutboundMiddleware) load() yarpc.OutboundMiddleware {
+	return m.v.Load().(yarpc.OutboundMiddleware)
+}
+
+func (m *reloadableOutboundMiddleware) Call(ctx context.Context, req *transport.Request, out transport.UnaryOutbound) (*transport.Response, error) {
+	if mw := m.load().Unary; mw != nil {
+		return mw.Call(ctx, req, out)
+	}
+	return out.Call(ctx, req)
+}
+
+func (m *reloadableOutboundMiddleware) CallOneway(ctx context.Context, req *transport.Request, out transport.OnewayOutbound) (transport.Ack, error) {
+	if mw := m.load().Oneway; mw != nil {
+		return mw.CallOneway(ctx, req, out)
+	}
+	return out.CallOneway(ctx, req)
+}
+
+func (m *reloadableOutboundMiddleware) CallStream(ctx context.Context, req *transport.Request, out transport.StreamOutbound) (transport.ClientStream, error) {
+	if mw := m.load().Stream; mw != nil {
+		return mw.CallStream(ctx, req, out)
+	}
+	return out.CallStream(ctx, req)
+}
+
+// staticPeers is a peer list updater for an explicit list of peers which
+// may be changed while it is running.
+type staticPeers struct {
+	mu sync.Mutex
+
+	identify func(string) peer.Identifier
+	names    []string
+	pl       peer.List
+	running  bool
+}
+
+func newStaticPeers(names []string, identify func(string) peer.Identifier) *staticPeers {
+	return &staticPeers{names: names, identify: identify}
+}
+
+// Bind implements peer.Binder.
+func (p *staticPeers) Bind(pl peer.List) transport.Lifecycle {
+	p.pl = pl
+	return p
+}
+
+func (p *staticPeers) Start() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.running {
+		return nil
+	}
+	p.running = true
+	return p.pl.Update(peer.ListUpdates{Additions: identifyAll(p.identify, p.names)})
+}
+
+func (p *staticPeers) Stop() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.running {
+		return nil
+	}
+	p.running = false
+	return p.pl.Update(peer.ListUpdates{Removals: identifyAll(p.identify, p.names)})
+}
+
+func (p *staticPeers) IsRunning() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.running
+}
+
+// Update changes the list of peers. If the peer list updater is running,
+// only the peers that were added or removed are sent to the peer list.
+func (p *staticPeers) Update(names []string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	additions, removals := diffNames(p.names, names)
+	p.names = names
+	if !p.running || (len(additions) == 0 && len(removals) == 0) {
+		return nil
+	}
+
+	return p.pl.Update(peer.ListUpdates{
+		Additions: identifyAll(p.identify, additions),
+		Removals:  identifyAll(p.identify, removals),
+	})
+}
+
+// diffNames returns the names that are in new but not in old, and the names
+// that are in old but not in new.
+func diffNames(old, new []string) (additions, removals []string) {
+	oldSet := make(map[string]struct{}, len(old))
+	for _, n := range old {
+		oldSet[n] = struct{}{}
+	}
+	newSet := make(map[string]struct{}, len(new))
+	for _, n := range new {
+		newSet[n] = struct{}{}
+		if _, ok := oldSet[n]; !ok {
+			additions = append(additions, n)
+		}
+	}
+	for _, n := range old {
+		if _, ok := newSet[n]; !ok {
+			removals = append(removals, n)
+		}
+	}
+	return additions, removals
+}
+
+// configSnapshot is a copy of a decoded configuration that may be compared
+// with other snapshots to find the differences between them.
+type configSnapshot struct {
+	Inbounds   []interface{}
+	Transports map[string]interface{}
+	Logging    loggingConfig
+	Metrics    metricsConfig
+	Drain      time.Duration
+	Outbounds  map[string]outboundsSnapshot
+}
+
+type outboundsSnapshot struct {
+	Service  string
+	Unary    *outboundSnapshot
+	Oneway   *outboundSnapshot
+	Implicit *outboundSnapshot
+	Split    *splitSnapshot
+}
+
+type outboundSnapshot struct {
+	Type string
+
+	// Attributes of the outbound without the explicit list of peers.
+	Attributes map[string]interface{}
+
+	// Explicit list of peers, if any.
+	Peers []string
+}
+
+type splitSnapshot struct {
+	StickyBy string
+	Header   string
+	Targets  []splitTargetSnapshot
+
+	// Weights of the targets by name.
+	Weights map[string]int
+}
+
+type splitTargetSnapshot struct {
+	Name     string
+	Outbound *outboundSnapshot
+}
+
+func newConfigSnapshot(cfg *yarpcConfig) *configSnapshot {
+	s := configSnapshot{
+		Transports: make(map[string]interface{}, len(cfg.Transports)),
+		Logging:    cfg.Logging,
+		Metrics:    cfg.Metrics,
+		Drain:      cfg.DrainTimeout,
+		Outbounds:  make(map[string]outboundsSnapshot, len(cfg.Outbounds)),
+	}
+
+	for _, i := range cfg.Inbounds {
+		s.Inbounds = append(s.Inbounds, normalize(map[string]interface{}{
+			"type":       i.Type,
+			"disabled":   i.Disabled,
+			"attributes": map[string]interface{}(i.Attributes),
+		}))
+	}
+
+	for name, attrs := range cfg.Transports {
+		s.Transports[name] = normalize(map[string]interface{}(attrs))
+	}
+
+	for name, o := range cfg.Outbounds {
+		s.Outbounds[name] = outboundsSnapshot{
+			Service:  o.Service,
+			Unary:    newOutboundSnapshot(o.Unary),
+			Oneway:   newOutboundSnapshot(o.Oneway),
+			Implicit: newOutboundSnapshot(o.Implicit),
+			Split:    newSplitSnapshot(o.Split),
+		}
+	}
+
+	return &s
+}
+
+func newSplitSnapshot(cfg *splitConfig) *splitSnapshot {
+	if cfg == nil {
+		return nil
+	}
+
+	s := splitSnapshot{
+		StickyBy: cfg.StickyBy,
+		Header:   cfg.Header,
+		Weights:  make(map[string]int, len(cfg.Targets)),
+	}
+	for _, t := range cfg.Targets {
+		o := t.Outbound
+		s.Targets = append(s.Targets, splitTargetSnapshot{
+			Name:     t.Name,
+			Outbound: newOutboundSnapshot(&o),
+		})
+		s.Weights[t.Name] = t.Weight
+	}
+	return &s
+}
+
+func newOutboundSnapshot(o *outbound) *outboundSnapshot {
+	if o == nil {
+		return nil
+	}
+
+	s := outboundSnapshot{Type: o.Type}
+	s.Attributes, _ = normalize(map[string]interface{}(o.Attributes)).(map[string]interface{})
+
+	// The explicit list of peers is the 'peers' key of the peer list
+	// configuration. See PeerChooser.
+	for key, v := range s.Attributes {
+		pl, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ps, ok := pl["peers"]
+		if !ok {
+			continue
+		}
+
+		var names []string
+		if err := decodeInto(&names, ps); err != nil {
+			continue
+		}
+
+		rest := make(map[string]interface{}, len(pl))
+		for k, v := range pl {
+			if k != "peers" {
+				rest[k] = v
+			}
+		}
+		s.Attributes[key] = rest
+		s.Peers = names
+		break
+	}
+
+	return &s
+}
+
+// diff returns descriptions of the differences between this snapshot and
+// the given snapshot that cannot be reloaded and the changes to explicit
+// peer lists and to the weights of split outbounds that can.
+func (s *configSnapshot) diff(
+	other *configSnapshot, state *reloadState,
+) (changes []string, updates []peerUpdate, weightUpdates []weightUpdate) {
+	if !sameElements(s.Inbounds, other.Inbounds) {
+		changes = append(changes, "inbounds")
+	}
+
+	for _, name := range unionKeys(s.Transports, other.Transports) {
+		if !reflect.DeepEqual(s.Transports[name], other.Transports[name]) {
+			changes = append(changes, fmt.Sprintf("transport %q", name))
+		}
+	}
+
+	if !reflect.DeepEqual(s.Logging, other.Logging) {
+		changes = append(changes, "logging")
+	}
+	if !reflect.DeepEqual(s.Metrics, other.Metrics) {
+		changes = append(changes, "metrics")
+	}
+	if s.Drain != other.Drain {
+		changes = append(changes, "drainTimeout")
+	}
+
+	for name, o := range s.Outbounds {
+		n, ok := other.Outbounds[name]
+		if !ok {
+			// Removed outbounds can be reloaded.
+			continue
+		}
+
+		if o.Service != n.Service {
+			changes = append(changes, fmt.Sprintf("outbound %q: service", name))
+		}
+
+		ro, ok := state.Outbounds[name]
+		if !ok {
+			// Nothing was built for the outbound so nothing of it may be
+			// reloaded.
+			ro = &reloadableOutbound{}
+		}
+
+		if o.Split != nil || n.Split != nil {
+			change := fmt.Sprintf("outbound %q: split configuration", name)
+			if o.Split == nil || n.Split == nil || o.Split.StickyBy != n.Split.StickyBy ||
+				o.Split.Header != n.Split.Header || !reflect.DeepEqual(o.Split.Targets, n.Split.Targets) {
+				changes = append(changes, change)
+			} else if !reflect.DeepEqual(o.Split.Weights, n.Split.Weights) {
+				for _, s := range []SplitOutbound{ro.UnarySplit, ro.OnewaySplit} {
+					if s != nil {
+						weightUpdates = append(weightUpdates, weightUpdate{Outbound: name, Split: s, Weights: n.Split.Weights})
+					}
+				}
+			}
+		}
+
+		kinds := []struct {
+			Name     string
+			Old, New *outboundSnapshot
+			Peers    []*staticPeers
+		}{
+			{"unary", o.Unary, n.Unary, []*staticPeers{ro.UnaryPeers}},
+			{"oneway", o.Oneway, n.Oneway, []*staticPeers{ro.OnewayPeers}},
+			{"implicit", o.Implicit, n.Implicit, []*staticPeers{ro.UnaryPeers, ro.OnewayPeers}},
+		}
+		for _, k := range kinds {
+			if k.Old == nil && k.New == nil {
+				continue
+			}
+
+			change := fmt.Sprintf("outbound %q: %s configuration", name, k.Name)
+			if k.Old == nil || k.New == nil || k.Old.Type != k.New.Type ||
+				!reflect.DeepEqual(k.Old.Attributes, k.New.Attributes) ||
+				(k.Old.Peers == nil) != (k.New.Peers == nil) {
+				changes = append(changes, change)
+				continue
+			}
+
+			if reflect.DeepEqual(k.Old.Peers, k.New.Peers) {
+				continue
+			}
+
+			var found bool
+			for _, p := range k.Peers {
+				if p != nil {
+					found = true
+					updates = append(updates, peerUpdate{Outbound: name, Peers: p, Names: k.New.Peers})
+				}
+			}
+			if !found {
+				// The peers key did not belong to a peer list that we
+				// built.
+				changes = append(changes, change)
+			}
+		}
+	}
+
+	sort.Strings(changes)
+	return changes, updates, weightUpdates
+}
+
+// normalize returns a deep copy of the given configuration value where all
+// maps are map[string]interface{} and all slices are []interface{} so that
+// configurations decoded from different sources can be compared.
+func normalize(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map:
+		m := make(map[string]interface{}, rv.Len())
+		for _, k := range rv.MapKeys() {
+			m[fmt.Sprint(k.Interface())] = normalize(rv.MapIndex(k).Interface())
+		}
+		return m
+	case reflect.Slice:
+		s := make([]interface{}, rv.Len())
+		for i := range s {
+			s[i] = normalize(rv.Index(i).Interface())
+		}
+		return s
+	default:
+		return v
+	}
+}
+
+// sameElements returns true if the two lists have the same elements,
+// ignoring order.
+func sameElements(a, b []interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	used := make([]bool, len(b))
+	for _, x := range a {
+		found := false
+		for i, y := range b {
+			if !used[i] && reflect.DeepEqual(x, y) {
+				used[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func unionKeys(a, b map[string]interface{}) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,425 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package config_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/yarpc"
+	peerapi "go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/introspection"
+	"go.uber.org/yarpc/internal/whitespace"
+	"go.uber.org/yarpc/peer/roundrobin"
+	"go.uber.org/yarpc/x/config"
+	"go.uber.org/yarpc/x/split"
+	"go.uber.org/yarpc/yarpctest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type labelConfig struct {
+	Label string `config:"label"`
+}
+
+// labelMiddleware records its label for each outbound request.
+type labelMiddleware struct {
+	label string
+	log   *labelLog
+}
+
+type labelLog struct {
+	sync.Mutex
+	labels []string
+}
+
+func (l *labelLog) Take() []string {
+	l.Lock()
+	defer l.Unlock()
+	labels := l.labels
+	l.labels = nil
+	return labels
+}
+
+func (m *labelMiddleware) Call(ctx context.Context, req *transport.Request, out transport.UnaryOutbound) (*transport.Response, error) {
+	m.log.Lock()
+	m.log.labels = append(m.log.labels, m.label)
+	m.log.Unlock()
+	return out.Call(ctx, req)
+}
+
+type recordingListConfig struct{}
+
+func newReloadConfigurator(log *labelLog) *config.Configurator {
+	c := yarpctest.NewFakeConfigurator()
+	c.MustRegisterPeerList(roundrobin.Spec())
+	c.MustRegisterSplit(split.Spec())
+	c.MustRegisterMiddleware(config.MiddlewareSpec{
+		Name: "label",
+		BuildMiddleware: func(c *labelConfig, _ *config.Kit) (*labelMiddleware, error) {
+			return &labelMiddleware{label: c.Label, log: log}, nil
+		},
+	})
+	return c
+}
+
+func callOutbound(t *testing.T, d *yarpc.Dispatcher, key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	out := d.ClientConfig(key).GetUnaryOutbound()
+	// The fake outbound always fails.
+	_, err := out.Call(ctx, &transport.Request{
+		Caller:    "foo",
+		Service:   key,
+		Encoding:  "raw",
+		Procedure: "hello",
+	})
+	require.Error(t, err)
+}
+
+func TestReloaderMiddleware(t *testing.T) {
+	var log labelLog
+	c := newReloadConfigurator(&log)
+
+	cfg, reloader, err := c.LoadReloadableConfigFromYAML("foo", strings.NewReader(whitespace.Expand(`
+		middleware:
+			outbound:
+				- label: {label: global1}
+		outbounds:
+			bar:
+				unary:
+					fake-transport: {peer: "127.0.0.1:80"}
+				middleware:
+					- label: {label: bar1}
+	`)))
+	require.NoError(t, err)
+
+	d := yarpc.NewDispatcher(cfg)
+	require.NoError(t, d.Start())
+	defer func() { assert.NoError(t, d.Stop()) }()
+
+	callOutbound(t, d, "bar")
+	assert.Equal(t, []string{"global1", "bar1"}, log.Take())
+
+	require.NoError(t, reloader.ReloadFromYAML(d, strings.NewReader(whitespace.Expand(`
+		middleware:
+			outbound:
+				- label: {label: global2}
+				- label: {label: global3}
+		outbounds:
+			bar:
+				unary:
+					fake-transport: {peer: "127.0.0.1:80"}
+	`))))
+
+	callOutbound(t, d, "bar")
+	assert.Equal(t, []string{"global2", "global3"}, log.Take())
+}
+
+func TestReloaderPeers(t *testing.T) {
+	c := newReloadConfigurator(new(labelLog))
+
+	var list *roundrobin.List
+	c.MustRegisterPeerList(config.PeerListSpec{
+		Name: "recording-list",
+		BuildPeerList: func(_ *recordingListConfig, t peerapi.Transport, _ *config.Kit) (peerapi.ChooserList, error) {
+			list = roundrobin.New(t)
+			return list, nil
+		},
+	})
+
+	given := func(peers ...string) string {
+		return whitespace.Expand(`
+			outbounds:
+				bar:
+					unary:
+						fake-transport:
+							recording-list:
+								peers: ["` + strings.Join(peers, `", "`) + `"]
+		`)
+	}
+
+	cfg, reloader, err := c.LoadReloadableConfigFromYAML("foo", strings.NewReader(given("1.1.1.1:80", "2.2.2.2:80")))
+	require.NoError(t, err)
+
+	require.NotNil(t, list, "peer list must be built")
+	listPeers := func() []string {
+		var names []string
+		for _, p := range list.Introspect().Peers {
+			names = append(names, p.Identifier)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	d := yarpc.NewDispatcher(cfg)
+
+	// Changes before the dispatcher is started are applied on Start.
+	require.NoError(t, reloader.ReloadFromYAML(d, strings.NewReader(given("2.2.2.2:80", "3.3.3.3:80"))))
+	require.NoError(t, d.Start())
+	defer func() { assert.NoError(t, d.Stop()) }()
+	assert.Equal(t, []string{"2.2.2.2:80", "3.3.3.3:80"}, listPeers())
+
+	require.NoError(t, reloader.ReloadFromYAML(d, strings.NewReader(given("3.3.3.3:80", "4.4.4.4:80", "5.5.5.5:80"))))
+	assert.Equal(t, []string{"3.3.3.3:80", "4.4.4.4:80", "5.5.5.5:80"}, listPeers())
+}
+
+func TestReloaderOutbounds(t *testing.T) {
+	c := newReloadConfigurator(new(labelLog))
+
+	cfg, reloader, err := c.LoadReloadableConfigFromYAML("foo", strings.NewReader(whitespace.Expand(`
+		outbounds:
+			bar:
+				unary:
+					fake-transport: {peer: "127.0.0.1:80"}
+	`)))
+	require.NoError(t, err)
+
+	d := yarpc.NewDispatcher(cfg)
+	require.NoError(t, d.Start())
+	defer func() { assert.NoError(t, d.Stop()) }()
+
+	bar := cfg.Outbounds["bar"].Unary
+	assert.True(t, bar.IsRunning())
+
+	require.NoError(t, reloader.ReloadFromYAML(d, strings.NewReader(whitespace.Expand(`
+		outbounds:
+			baz:
+				service: qux
+				unary:
+					fake-transport: {peer: "127.0.0.1:81"}
+	`))))
+
+	assert.False(t, bar.IsRunning(), "removed outbound must be stopped")
+	assert.Panics(t, func() { d.ClientConfig("bar") }, "removed outbound must not be available")
+
+	cc := d.ClientConfig("baz")
+	assert.Equal(t, "qux", cc.Service())
+	assert.True(t, cc.GetUnaryOutbound().IsRunning(), "added outbound must be started")
+}
+
+// flakyOutbound is a unary outbound which fails to start if it is the
+// first of the outbounds sharing the counter to be started.
+type flakyOutbound struct {
+	transport.UnaryOutbound
+
+	starts  *int
+	running bool
+}
+
+func (o *flakyOutbound) Transports() []transport.Transport { return nil }
+func (o *flakyOutbound) IsRunning() bool                   { return o.running }
+func (o *flakyOutbound) Stop() error                       { return nil }
+
+func (o *flakyOutbound) Start() error {
+	*o.starts++
+	if *o.starts == 1 {
+		return errors.New("great sadness")
+	}
+	o.running = true
+	return nil
+}
+
+func TestReloaderRetriesFailedOutbounds(t *testing.T) {
+	var starts int
+	c := newReloadConfigurator(new(labelLog))
+	c.MustRegisterTransport(config.TransportSpec{
+		Name: "flaky",
+		BuildTransport: func(struct{}, *config.Kit) (transport.Transport, error) {
+			return yarpctest.NewFakeTransport(), nil
+		},
+		BuildUnaryOutbound: func(struct{}, transport.Transport, *config.Kit) (transport.UnaryOutbound, error) {
+			return &flakyOutbound{starts: &starts}, nil
+		},
+	})
+
+	cfg, reloader, err := c.LoadReloadableConfigFromYAML("foo", strings.NewReader("{}"))
+	require.NoError(t, err)
+
+	d := yarpc.NewDispatcher(cfg)
+	require.NoError(t, d.Start())
+	defer func() { assert.NoError(t, d.Stop()) }()
+
+	withBar := whitespace.Expand(`
+		outbounds:
+			bar:
+				unary:
+					flaky: {}
+	`)
+	err = reloader.ReloadFromYAML(d, strings.NewReader(withBar))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `failed to add outbound "bar"`)
+	assert.Panics(t, func() { d.ClientConfig("bar") })
+
+	require.NoError(t, reloader.ReloadFromYAML(d, strings.NewReader(withBar)),
+		"outbounds that failed to be added must be added again")
+	assert.True(t, d.ClientConfig("bar").GetUnaryOutbound().IsRunning())
+}
+
+func TestReloaderSplitWeights(t *testing.T) {
+	c := newReloadConfigurator(new(labelLog))
+
+	splitConfig := func(stable, canary int) string {
+		return whitespace.Expand(fmt.Sprintf(`
+			outbounds:
+				bar:
+					split:
+						stickyBy: shardKey
+						targets:
+							- name: stable
+							  weight: %d
+							  fake-transport: {nop: stable, peer: "127.0.0.1:80"}
+							- name: canary
+							  weight: %d
+							  fake-transport: {nop: canary, peer: "127.0.0.1:81"}
+		`, stable, canary))
+	}
+	weights := func(o transport.UnaryOutbound) []string {
+		var states []string
+		for _, p := range o.(introspection.IntrospectableOutbound).Introspect().Chooser.Peers {
+			states = append(states, p.Identifier+": "+p.State)
+		}
+		return states
+	}
+
+	cfg, reloader, err := c.LoadReloadableConfigFromYAML("foo", strings.NewReader(splitConfig(9, 1)))
+	require.NoError(t, err)
+
+	d := yarpc.NewDispatcher(cfg)
+	require.NoError(t, d.Start())
+	defer func() { assert.NoError(t, d.Stop()) }()
+
+	bar := cfg.Outbounds["bar"].Unary
+	assert.True(t, bar.IsRunning())
+	assert.Equal(t, []string{"stable: weight 9 of 10", "canary: weight 1 of 10"}, weights(bar))
+
+	require.NoError(t, reloader.ReloadFromYAML(d, strings.NewReader(splitConfig(5, 5))))
+	assert.Equal(t, []string{"stable: weight 5 of 10", "canary: weight 5 of 10"}, weights(bar))
+	assert.True(t, bar.IsRunning(), "outbound must not be rebuilt")
+
+	err = reloader.ReloadFromYAML(d, strings.NewReader(whitespace.Expand(`
+		outbounds:
+			bar:
+				split:
+					stickyBy: shardKey
+					targets:
+						- name: stable
+						  weight: 5
+						  fake-transport: {nop: stable, peer: "127.0.0.1:80"}
+						- name: canary
+						  weight: 5
+						  fake-transport: {nop: canary, peer: "127.0.0.1:82"}
+	`)))
+	assert.EqualError(t, err, `configuration changes cannot be reloaded: outbound "bar": split configuration`)
+	assert.Equal(t, []string{"stable: weight 5 of 10", "canary: weight 5 of 10"}, weights(bar))
+}
+
+func TestReloaderErrors(t *testing.T) {
+	c := newReloadConfigurator(new(labelLog))
+
+	cfg, reloader, err := c.LoadReloadableConfigFromYAML("foo", strings.NewReader(whitespace.Expand(`
+		transports:
+			fake-transport: {nop: ":1234"}
+		logging:
+			level: info
+		outbounds:
+			bar:
+				unary:
+					fake-transport:
+						nop: a
+						round-robin:
+							peers: ["127.0.0.1:80"]
+	`)))
+	require.NoError(t, err)
+	d := yarpc.NewDispatcher(cfg)
+
+	tests := []struct {
+		desc    string
+		give    string
+		wantErr []string
+	}{
+		{
+			desc: "non-reloadable changes",
+			give: whitespace.Expand(`
+				transports:
+					fake-transport: {nop: ":4321"}
+				logging:
+					level: debug
+				outbounds:
+					bar:
+						service: baz
+						unary:
+							fake-transport:
+								nop: b
+								round-robin:
+									peers: ["127.0.0.1:81"]
+			`),
+			wantErr: []string{
+				"configuration changes cannot be reloaded: " +
+					"logging; " +
+					`outbound "bar": service; ` +
+					`outbound "bar": unary configuration; ` +
+					`transport "fake-transport"`,
+			},
+		},
+		{
+			desc: "invalid middleware",
+			give: whitespace.Expand(`
+				transports:
+					fake-transport: {nop: ":1234"}
+				logging:
+					level: info
+				middleware:
+					outbound: [foo]
+				outbounds:
+					bar:
+						unary:
+							fake-transport:
+								nop: a
+								round-robin:
+									peers: ["127.0.0.1:80"]
+			`),
+			wantErr: []string{`unknown middleware "foo"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			err := reloader.ReloadFromYAML(d, strings.NewReader(tt.give))
+			require.Error(t, err)
+			for _, msg := range tt.wantErr {
+				assert.Contains(t, err.Error(), msg)
+			}
+		})
+	}
+
+	// Nothing was changed by the failed reloads.
+	assert.NotPanics(t, func() { d.ClientConfig("bar") })
+}
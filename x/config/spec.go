@@ -26,6 +26,7 @@ import (
 	"reflect"
 	"strings"
 
+	"go.uber.org/yarpc/api/middleware"
 	"go.uber.org/yarpc/api/peer"
 	"go.uber.org/yarpc/api/transport"
 
@@ -216,6 +217,108 @@ type PeerListUpdaterSpec struct {
 	BuildPeerListUpdater interface{}
 }
 
+// MiddlewareSpec specifies the configuration parameters for a middleware.
+// These specifications are registered against a Configurator to teach it how
+// to parse the configuration for that middleware and build instances of it.
+//
+// Middleware may be added to the chain applied to all inbound requests, the
+// chain applied to all outbound requests, or the chain applied to the
+// requests made through a specific outbound.
+//
+// 	middleware:
+// 	  inbound:
+// 	    - auth:
+// 	        keys: /etc/myservice/keys
+// 	  outbound:
+// 	    - tracing
+// 	outbounds:
+// 	  keyvalue:
+// 	    http:
+// 	      url: http://127.0.0.1:8080/
+// 	    middleware:
+// 	      - rate-limit:
+// 	          rps: 100
+type MiddlewareSpec struct {
+	// Name of the middleware.
+	Name string
+
+	// A function in the shape,
+	//
+	//  func(C, *config.Kit) (M, error)
+	//
+	// Where C is a struct or pointer to a struct defining the configuration
+	// parameters accepted by this middleware, and M is a type implementing
+	// one or more of middleware.UnaryInbound, middleware.OnewayInbound,
+	// middleware.StreamInbound, middleware.UnaryOutbound,
+	// middleware.OnewayOutbound, and middleware.StreamOutbound.
+	//
+	// The middleware is used for all RPC types it supports in the chain it
+	// was added to. It is an error to add a middleware to a chain for which
+	// it does not support any RPC type.
+	//
+	// BuildMiddleware is required.
+	BuildMiddleware interface{}
+}
+
+// SplitSpec teaches a Configurator how to build outbounds which split
+// requests between other outbounds by weight. Split outbounds are
+// configured with the split key of an outbound and require a SplitSpec to
+// be registered with RegisterSplit.
+//
+// 	outbounds:
+// 	  keyvalue:
+// 	    split:
+// 	      stickyBy: shardKey
+// 	      targets:
+// 	        - name: stable
+// 	          weight: 95
+// 	          http:
+// 	            url: http://127.0.0.1:8080
+// 	        - name: canary
+// 	          weight: 5
+// 	          http:
+// 	            url: http://127.0.0.1:8081
+type SplitSpec struct {
+	// BuildSplit builds an outbound which splits requests between the given
+	// targets. The targets either all have unary outbounds or all have
+	// oneway outbounds, and the returned outbound must support the same RPC
+	// type.
+	//
+	// BuildSplit is required.
+	BuildSplit func([]SplitTarget, SplitOptions) (SplitOutbound, error)
+}
+
+// SplitTarget is one of the outbounds between which a split outbound
+// splits requests.
+type SplitTarget struct {
+	Name   string
+	Weight int
+
+	// Exactly one of Unary and Oneway is set.
+	Unary  transport.UnaryOutbound
+	Oneway transport.OnewayOutbound
+}
+
+// SplitOptions holds the options of a split outbound other than its
+// targets.
+type SplitOptions struct {
+	// If true, requests with the same shard key go to the same target.
+	StickyByShardKey bool
+
+	// If non-empty, requests with the same value for this header go to the
+	// same target.
+	StickyByHeader string
+}
+
+// SplitOutbound is an outbound built by a SplitSpec.
+type SplitOutbound interface {
+	transport.Outbound
+
+	// SetWeights changes the weights of the targets, keyed by their names.
+	// Reloader calls it when only the weights of a split outbound change.
+	SetWeights(map[string]int) error
+}
+
 var (
 	_typeOfError           = reflect.TypeOf((*error)(nil)).Elem()
 	_typeOfTransport       = reflect.TypeOf((*transport.Transport)(nil)).Elem()
@@ -226,6 +329,13 @@ var (
 	_typeOfPeerChooserList = reflect.TypeOf((*peer.ChooserList)(nil)).Elem()
 	_typeOfPeerChooser     = reflect.TypeOf((*peer.Chooser)(nil)).Elem()
 	_typeOfBinder          = reflect.TypeOf((*peer.Binder)(nil)).Elem()
+
+	_typeOfUnaryInboundMiddleware   = reflect.TypeOf((*middleware.UnaryInbound)(nil)).Elem()
+	_typeOfOnewayInboundMiddleware  = reflect.TypeOf((*middleware.OnewayInbound)(nil)).Elem()
+	_typeOfStreamInboundMiddleware  = reflect.TypeOf((*middleware.StreamInbound)(nil)).Elem()
+	_typeOfUnaryOutboundMiddleware  = reflect.TypeOf((*middleware.UnaryOutbound)(nil)).Elem()
+	_typeOfOnewayOutboundMiddleware = reflect.TypeOf((*middleware.OnewayOutbound)(nil)).Elem()
+	_typeOfStreamOutboundMiddleware = reflect.TypeOf((*middleware.StreamOutbound)(nil)).Elem()
 )
 
 // Compiled internal representation of a user-specified TransportSpec.
@@ -576,6 +686,66 @@ func compilePeerListUpdaterConfig(name string, build interface{}) (*configSpec,
 	return &configSpec{inputType: t.In(0), factory: v}, nil
 }
 
+// Compiled internal representation of a user-specified MiddlewareSpec.
+type compiledMiddlewareSpec struct {
+	Name       string
+	Middleware *configSpec
+
+	// Whether the middleware may be used with inbound or outbound requests.
+	Inbound, Outbound bool
+}
+
+func compileMiddlewareSpec(spec *MiddlewareSpec) (*compiledMiddlewareSpec, error) {
+	out := compiledMiddlewareSpec{Name: spec.Name}
+
+	if spec.Name == "" {
+		return nil, errors.New("Name is required")
+	}
+
+	if spec.BuildMiddleware == nil {
+		return nil, errors.New("BuildMiddleware is required")
+	}
+
+	v := reflect.ValueOf(spec.BuildMiddleware)
+	t := v.Type()
+
+	var err error
+	switch {
+	case t.Kind() != reflect.Func:
+		err = errors.New("must be a function")
+	case t.NumIn() != 2:
+		err = fmt.Errorf("must accept exactly two arguments, found %v", t.NumIn())
+	case !isDecodable(t.In(0)):
+		err = fmt.Errorf("must accept a struct or struct pointer as its first argument, found %v", t.In(0))
+	case t.In(1) != _typeOfKit:
+		err = fmt.Errorf("must accept a %v as its second argument, found %v", _typeOfKit, t.In(1))
+	case t.NumOut() != 2:
+		err = fmt.Errorf("must return exactly two results, found %v", t.NumOut())
+	case t.Out(1) != _typeOfError:
+		err = fmt.Errorf("must return an error as its second result, found %v", t.Out(1))
+	}
+
+	if err == nil {
+		m := t.Out(0)
+		out.Inbound = m.Implements(_typeOfUnaryInboundMiddleware) ||
+			m.Implements(_typeOfOnewayInboundMiddleware) ||
+			m.Implements(_typeOfStreamInboundMiddleware)
+		out.Outbound = m.Implements(_typeOfUnaryOutboundMiddleware) ||
+			m.Implements(_typeOfOnewayOutboundMiddleware) ||
+			m.Implements(_typeOfStreamOutboundMiddleware)
+		if !out.Inbound && !out.Outbound {
+			err = fmt.Errorf("must return an inbound or outbound middleware as its first result, found %v", m)
+		}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid BuildMiddleware %v: %v", t, err)
+	}
+
+	out.Middleware = &configSpec{inputType: t.In(0), factory: v}
+	return &out, nil
+}
+
 // Validated representation of a configuration function specified by the user.
 type configSpec struct {
 	// Type of object expected by the factory function
@@ -26,11 +26,13 @@ import (
 	"reflect"
 	"testing"
 
+	"go.uber.org/yarpc/api/middleware"
 	"go.uber.org/yarpc/api/peer"
 	"go.uber.org/yarpc/api/transport"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var _typeOfEmptyStruct = reflect.TypeOf(struct{}{})
@@ -782,6 +784,115 @@ func TestCompilePeerListUpdaterSpec(t *testing.T) {
 	}
 }
 
+func TestCompileMiddlewareSpec(t *testing.T) {
+	tests := []struct {
+		desc         string
+		spec         MiddlewareSpec
+		wantName     string
+		wantInbound  bool
+		wantOutbound bool
+		wantErr      string
+	}{
+		{
+			desc:    "missing name",
+			wantErr: "Name is required",
+		},
+		{
+			desc: "missing BuildMiddleware",
+			spec: MiddlewareSpec{
+				Name: "random",
+			},
+			wantErr: "BuildMiddleware is required",
+		},
+		{
+			desc: "not a function",
+			spec: MiddlewareSpec{
+				Name:            "much sadness",
+				BuildMiddleware: 10,
+			},
+			wantErr: "invalid BuildMiddleware int: must be a function",
+		},
+		{
+			desc: "wrong kind of second argument",
+			spec: MiddlewareSpec{
+				Name:            "much sadness",
+				BuildMiddleware: func(a struct{}, b int) {},
+			},
+			wantErr: "invalid BuildMiddleware func(struct {}, int): must accept a *config.Kit as its second argument, found int",
+		},
+		{
+			desc: "wrong type of first return",
+			spec: MiddlewareSpec{
+				Name: "much sadness",
+				BuildMiddleware: func(a struct{}, b *Kit) (int, error) {
+					return 0, nil
+				},
+			},
+			wantErr: "invalid BuildMiddleware func(struct {}, *config.Kit) (int, error): must return an inbound or outbound middleware as its first result, found int",
+		},
+		{
+			desc: "wrong type of second return",
+			spec: MiddlewareSpec{
+				Name: "much sadness",
+				BuildMiddleware: func(a struct{}, b *Kit) (middleware.UnaryInbound, int) {
+					return nil, 0
+				},
+			},
+			wantErr: "invalid BuildMiddleware func(struct {}, *config.Kit) (middleware.UnaryInbound, int): must return an error as its second result, found int",
+		},
+		{
+			desc: "inbound",
+			spec: MiddlewareSpec{
+				Name: "inbound",
+				BuildMiddleware: func(a struct{}, b *Kit) (middleware.OnewayInbound, error) {
+					return nil, nil
+				},
+			},
+			wantName:    "inbound",
+			wantInbound: true,
+		},
+		{
+			desc: "outbound",
+			spec: MiddlewareSpec{
+				Name: "outbound",
+				BuildMiddleware: func(a *struct{}, b *Kit) (middleware.StreamOutbound, error) {
+					return nil, nil
+				},
+			},
+			wantName:     "outbound",
+			wantOutbound: true,
+		},
+		{
+			desc: "inbound and outbound",
+			spec: MiddlewareSpec{
+				Name: "both",
+				BuildMiddleware: func(a struct{}, b *Kit) (*recordingMiddleware, error) {
+					return nil, nil
+				},
+			},
+			wantName:     "both",
+			wantInbound:  true,
+			wantOutbound: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			s, err := compileMiddlewareSpec(&tt.spec)
+			if tt.wantErr != "" {
+				require.Error(t, err, "expected failure")
+				assert.Equal(t, tt.wantErr, err.Error(), "expected error")
+				return
+			}
+
+			require.NoError(t, err, "expected success")
+			assert.Equal(t, tt.wantName, s.Name, "expected name")
+			assert.Equal(t, tt.wantInbound, s.Inbound, "inbound support did not match")
+			assert.Equal(t, tt.wantOutbound, s.Outbound, "outbound support did not match")
+		})
+	}
+}
+
 func TestCompilePeerChooserPreset(t *testing.T) {
 	tests := []struct {
 		desc     string
@@ -0,0 +1,48 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package delay provides a oneway outbound which holds requests delayed with
+// yarpc.WithDeliverAt or yarpc.WithDelay until they are due, for transports
+// which cannot delay requests themselves.
+//
+// Delayed requests are kept in a Store. The outbound checks the Store for
+// requests which are due and sends them through the wrapped outbound.
+//
+// 	store, err := delay.NewFileStore("/var/lib/myservice/delayed")
+// 	if err != nil {
+// 		log.Fatal(err)
+// 	}
+// 	outbound := delay.NewOnewayOutbound(
+// 		http.NewTransport().NewSingleOutbound("http://127.0.0.1:8080"),
+// 		store,
+// 	)
+//
+// 	ack, err := client.Remind(ctx, reminder, yarpc.WithDelay(10*time.Minute))
+//
+// Requests are removed from the Store only after they were delivered, so
+// delivery is at-least-once: a request whose delivery fails is attempted
+// again, and a request whose delivery succeeded may be delivered again if the
+// process stops before the request was removed. MemoryStore loses requests
+// when the process stops; FileStore keeps them on disk so they are delivered
+// when the outbound starts again.
+//
+// Delayed requests do not carry the tracing span of the call which delayed
+// them.
+package delay
@@ -0,0 +1,141 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package delay
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var _ Store = (*FileStore)(nil)
+
+// FileStore is a Store which keeps every delayed request in its own file in
+// a directory, so that requests survive restarts of the process.
+//
+// Files are named after the time at which their request is due, followed by
+// a random suffix. Only one FileStore may use a directory at a time.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore builds a FileStore which keeps requests in the given
+// directory, creating it if necessary. Requests left in the directory by a
+// previous FileStore are delivered when they are due.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Put holds the serialized request until the given time.
+//
+// The request is written to a temporary file which is renamed once complete,
+// so that partially written requests are never delivered.
+func (s *FileStore) Put(deliverAt time.Time, request []byte) error {
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	nanos := deliverAt.UnixNano()
+	if nanos < 0 {
+		// Requests due before the epoch are due now.
+		nanos = 0
+	}
+	name := fmt.Sprintf("%020d-%s", nanos, hex.EncodeToString(suffix))
+
+	tmp, err := ioutil.TempFile(s.dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(request); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(s.dir, name))
+}
+
+// Due returns the entries which are due at the given time, earliest first.
+func (s *FileStore) Due(now time.Time) ([]Entry, error) {
+	// ReadDir sorts files by name, and so by the time at which they are due.
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		deliverAt, err := parseDeliverAt(name)
+		if err != nil {
+			// Not a file written by the store.
+			continue
+		}
+		if deliverAt.After(now) {
+			break
+		}
+
+		request, err := ioutil.ReadFile(filepath.Join(s.dir, name))
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, Entry{ID: name, DeliverAt: deliverAt, Request: request})
+	}
+	return entries, nil
+}
+
+// Delete removes an entry returned by Due.
+func (s *FileStore) Delete(e Entry) error {
+	err := os.Remove(filepath.Join(s.dir, e.ID))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// parseDeliverAt parses the time at which a request is due from the name of
+// its file.
+func parseDeliverAt(name string) (time.Time, error) {
+	i := strings.IndexByte(name, '-')
+	if i < 0 {
+		return time.Time{}, fmt.Errorf("invalid file name %q", name)
+	}
+	nanos, err := strconv.ParseInt(name[:i], 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid file name %q: %v", name, err)
+	}
+	return time.Unix(0, nanos), nil
+}
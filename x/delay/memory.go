@@ -0,0 +1,98 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package delay
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var _ Store = (*MemoryStore)(nil)
+
+// MemoryStore is a Store which holds delayed requests in memory. Requests are
+// lost if the process stops before they are delivered.
+type MemoryStore struct {
+	sync.Mutex
+
+	lastID  uint64
+	entries []Entry // sorted by DeliverAt
+}
+
+// NewMemoryStore builds a new, empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Put holds the serialized request until the given time.
+func (s *MemoryStore) Put(deliverAt time.Time, request []byte) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.lastID++
+	e := Entry{
+		ID:        strconv.FormatUint(s.lastID, 10),
+		DeliverAt: deliverAt,
+		Request:   request,
+	}
+
+	// Entries due at the same time keep the order in which they were put.
+	i := sort.Search(len(s.entries), func(i int) bool {
+		return s.entries[i].DeliverAt.After(deliverAt)
+	})
+	s.entries = append(s.entries, Entry{})
+	copy(s.entries[i+1:], s.entries[i:])
+	s.entries[i] = e
+	return nil
+}
+
+// Due returns the entries which are due at the given time, earliest first.
+func (s *MemoryStore) Due(now time.Time) ([]Entry, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	n := sort.Search(len(s.entries), func(i int) bool {
+		return s.entries[i].DeliverAt.After(now)
+	})
+	return append([]Entry(nil), s.entries[:n]...), nil
+}
+
+// Delete removes an entry returned by Due.
+func (s *MemoryStore) Delete(e Entry) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for i, v := range s.entries {
+		if v.ID == e.ID {
+			s.entries = append(s.entries[:i], s.entries[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+// Len returns the number of requests held by the store.
+func (s *MemoryStore) Len() int {
+	s.Lock()
+	defer s.Unlock()
+	return len(s.entries)
+}
@@ -0,0 +1,219 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package delay
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/introspection"
+	intsync "go.uber.org/yarpc/internal/sync"
+	"go.uber.org/yarpc/serialize"
+
+	"github.com/opentracing/opentracing-go"
+	"go.uber.org/multierr"
+)
+
+const (
+	defaultPollInterval    = 100 * time.Millisecond
+	defaultDeliveryTimeout = time.Second
+)
+
+var (
+	_ transport.OnewayOutbound             = (*OnewayOutbound)(nil)
+	_ introspection.IntrospectableOutbound = (*OnewayOutbound)(nil)
+)
+
+// OutboundOption customizes the behavior of a delaying OnewayOutbound.
+type OutboundOption func(*OnewayOutbound)
+
+// PollInterval specifies how often the outbound checks its Store for
+// requests which are due.
+//
+// Defaults to 100 milliseconds.
+func PollInterval(d time.Duration) OutboundOption {
+	return func(o *OnewayOutbound) {
+		o.pollInterval = d
+	}
+}
+
+// DeliveryTimeout specifies how long the outbound waits for each delayed
+// request to be sent through the wrapped outbound before trying again later.
+//
+// Defaults to 1 second.
+func DeliveryTimeout(d time.Duration) OutboundOption {
+	return func(o *OnewayOutbound) {
+		o.deliveryTimeout = d
+	}
+}
+
+// OnewayOutbound is a oneway outbound which holds delayed requests in a
+// Store until they are due, and sends all other requests through the wrapped
+// outbound immediately.
+type OnewayOutbound struct {
+	out   transport.OnewayOutbound
+	store Store
+
+	pollInterval    time.Duration
+	deliveryTimeout time.Duration
+
+	stop chan struct{}
+	done chan struct{}
+	once intsync.LifecycleOnce
+}
+
+// NewOnewayOutbound builds a oneway outbound which delays requests for the
+// given outbound using the given Store.
+//
+// The wrapped outbound is started and stopped with the returned outbound.
+func NewOnewayOutbound(out transport.OnewayOutbound, store Store, opts ...OutboundOption) *OnewayOutbound {
+	o := &OnewayOutbound{
+		out:             out,
+		store:           store,
+		pollInterval:    defaultPollInterval,
+		deliveryTimeout: defaultDeliveryTimeout,
+		stop:            make(chan struct{}),
+		done:            make(chan struct{}),
+		once:            intsync.Once(),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Transports returns the transports used by the wrapped outbound.
+func (o *OnewayOutbound) Transports() []transport.Transport {
+	return o.out.Transports()
+}
+
+// Start starts the wrapped outbound and the delivery of delayed requests.
+func (o *OnewayOutbound) Start() error {
+	return o.once.Start(o.start)
+}
+
+func (o *OnewayOutbound) start() error {
+	if err := o.out.Start(); err != nil {
+		return err
+	}
+	go o.deliverLoop()
+	return nil
+}
+
+// Stop stops the delivery of delayed requests and the wrapped outbound.
+// Requests which are not due yet stay in the Store.
+func (o *OnewayOutbound) Stop() error {
+	return o.once.Stop(o.stopOutbound)
+}
+
+func (o *OnewayOutbound) stopOutbound() error {
+	close(o.stop)
+	<-o.done
+	return o.out.Stop()
+}
+
+// IsRunning returns whether the outbound is running.
+func (o *OnewayOutbound) IsRunning() bool {
+	return o.once.IsRunning()
+}
+
+// CallOneway holds the request in the Store if it was delayed past the
+// current time, and sends it through the wrapped outbound otherwise.
+func (o *OnewayOutbound) CallOneway(ctx context.Context, req *transport.Request) (transport.Ack, error) {
+	if err := o.once.WhenRunning(ctx); err != nil {
+		return nil, err
+	}
+
+	deliverAt, delayed := transport.DeliverAtFromContext(ctx)
+	if !delayed || !deliverAt.After(time.Now()) {
+		return o.out.CallOneway(ctx, req)
+	}
+
+	request, err := serialize.ToBytes(opentracing.NoopTracer{}, nil, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.store.Put(deliverAt, request); err != nil {
+		return nil, err
+	}
+	return time.Now(), nil
+}
+
+func (o *OnewayOutbound) deliverLoop() {
+	defer close(o.done)
+
+	ticker := time.NewTicker(o.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-o.stop:
+			return
+		case <-ticker.C:
+			// TODO: log error
+			_ = o.deliverDue()
+		}
+	}
+}
+
+// deliverDue sends the requests which are due through the wrapped outbound.
+// Requests are removed from the Store only once they were delivered.
+func (o *OnewayOutbound) deliverDue() error {
+	entries, err := o.store.Due(time.Now())
+	if err != nil {
+		return err
+	}
+
+	var errs error
+	for _, e := range entries {
+		select {
+		case <-o.stop:
+			return errs
+		default:
+		}
+		errs = multierr.Append(errs, o.deliver(e))
+	}
+	return errs
+}
+
+func (o *OnewayOutbound) deliver(e Entry) error {
+	_, req, err := serialize.FromBytes(opentracing.NoopTracer{}, e.Request)
+	if err != nil {
+		// The request can never be delivered.
+		return multierr.Append(err, o.store.Delete(e))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), o.deliveryTimeout)
+	defer cancel()
+	if _, err := o.out.CallOneway(ctx, req); err != nil {
+		return err
+	}
+	return o.store.Delete(e)
+}
+
+// Introspect returns the status of the wrapped outbound, if it supports
+// introspection.
+func (o *OnewayOutbound) Introspect() introspection.OutboundStatus {
+	if i, ok := o.out.(introspection.IntrospectableOutbound); ok {
+		return i.Introspect()
+	}
+	return introspection.OutboundStatusNotSupported
+}
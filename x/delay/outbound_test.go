@@ -0,0 +1,151 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package delay
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/encoding/raw"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+)
+
+// fakeOutbound is a oneway outbound which reports the body of every request
+// it delivers, and fails the given number of deliveries first.
+type fakeOutbound struct {
+	transport.Outbound
+
+	failures  atomic.Int32
+	delivered chan string
+}
+
+func newFakeOutbound(failures int32) *fakeOutbound {
+	o := &fakeOutbound{delivered: make(chan string, 10)}
+	o.failures.Store(failures)
+	return o
+}
+
+func (o *fakeOutbound) Start() error { return nil }
+func (o *fakeOutbound) Stop() error  { return nil }
+
+func (o *fakeOutbound) CallOneway(ctx context.Context, req *transport.Request) (transport.Ack, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		return nil, errors.New("deliveries must have a deadline")
+	}
+	if o.failures.Dec() >= 0 {
+		return nil, errors.New("great sadness")
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	o.delivered <- string(body)
+	return time.Now(), nil
+}
+
+func newRequest(body string) *transport.Request {
+	return &transport.Request{
+		Caller:    "caller",
+		Service:   "service",
+		Encoding:  raw.Encoding,
+		Procedure: "remind",
+		Body:      bytes.NewReader([]byte(body)),
+	}
+}
+
+func TestOnewayOutbound(t *testing.T) {
+	tests := []struct {
+		desc     string
+		delay    time.Duration
+		failures int32
+	}{
+		{desc: "not delayed"},
+		{desc: "delayed", delay: 50 * time.Millisecond},
+		{desc: "delivery fails", delay: 10 * time.Millisecond, failures: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			out := newFakeOutbound(tt.failures)
+			store := NewMemoryStore()
+			o := NewOnewayOutbound(out, store, PollInterval(5*time.Millisecond), DeliveryTimeout(time.Second))
+			require.NoError(t, o.Start())
+			defer o.Stop()
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			deliverAt := time.Now().Add(tt.delay)
+			if tt.delay > 0 {
+				ctx = transport.WithDeliverAt(ctx, deliverAt)
+			} else {
+				// Without a delay, failures are returned to the caller.
+				out.failures.Store(0)
+			}
+
+			_, err := o.CallOneway(ctx, newRequest("hello"))
+			require.NoError(t, err)
+			if tt.delay > 0 {
+				assert.Equal(t, 1, store.Len(), "delayed request must be stored")
+			}
+
+			select {
+			case body := <-out.delivered:
+				assert.Equal(t, "hello", body)
+				assert.False(t, time.Now().Before(deliverAt), "request delivered before it was due")
+			case <-time.After(time.Second):
+				t.Fatal("timed out waiting for delivery")
+			}
+			assert.Equal(t, 0, store.Len(), "delivered request must leave the store")
+		})
+	}
+}
+
+func TestOnewayOutboundStopKeepsRequests(t *testing.T) {
+	out := newFakeOutbound(0)
+	store := NewMemoryStore()
+	o := NewOnewayOutbound(out, store)
+	require.NoError(t, o.Start())
+
+	ctx := transport.WithDeliverAt(context.Background(), time.Now().Add(time.Hour))
+	_, err := o.CallOneway(ctx, newRequest("hello"))
+	require.NoError(t, err)
+	require.NoError(t, o.Stop())
+
+	assert.Equal(t, 1, store.Len(), "requests which are not due must stay in the store")
+	assert.Empty(t, out.delivered, "requests which are not due must not be delivered")
+}
+
+func TestOnewayOutboundNotRunning(t *testing.T) {
+	o := NewOnewayOutbound(newFakeOutbound(0), NewMemoryStore())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := o.CallOneway(ctx, newRequest("hello"))
+	assert.Error(t, err, "calls must fail before the outbound starts")
+}
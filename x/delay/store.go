@@ -0,0 +1,50 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package delay
+
+import "time"
+
+// Entry is a delayed request held by a Store.
+type Entry struct {
+	// ID identifies the entry within its Store.
+	ID string
+
+	// DeliverAt is the time at which the request is due.
+	DeliverAt time.Time
+
+	// Request is the request serialized with go.uber.org/yarpc/serialize.
+	Request []byte
+}
+
+// Store holds delayed requests until they are due.
+//
+// Implementations MUST be safe to use concurrently.
+type Store interface {
+	// Put holds the serialized request until the given time.
+	Put(deliverAt time.Time, request []byte) error
+
+	// Due returns the entries which are due at the given time, earliest
+	// first.
+	Due(now time.Time) ([]Entry, error)
+
+	// Delete removes an entry returned by Due.
+	Delete(Entry) error
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package delay
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStores(t *testing.T) {
+	dir, err := ioutil.TempDir("", "delay")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fileStore, err := NewFileStore(dir)
+	require.NoError(t, err)
+
+	stores := map[string]Store{
+		"memory": NewMemoryStore(),
+		"file":   fileStore,
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			require.NoError(t, store.Put(now.Add(time.Minute), []byte("later")))
+			require.NoError(t, store.Put(now.Add(-time.Second), []byte("second")))
+			require.NoError(t, store.Put(now.Add(-time.Minute), []byte("first")))
+
+			entries, err := store.Due(now)
+			require.NoError(t, err)
+			require.Len(t, entries, 2, "expected two entries to be due")
+			assert.Equal(t, "first", string(entries[0].Request))
+			assert.Equal(t, "second", string(entries[1].Request))
+			assert.True(t, entries[0].DeliverAt.Equal(now.Add(-time.Minute)), "delivery time must match")
+
+			require.NoError(t, store.Delete(entries[0]))
+			require.NoError(t, store.Delete(entries[0]), "deleting an entry twice must succeed")
+
+			entries, err = store.Due(now.Add(time.Hour))
+			require.NoError(t, err)
+			require.Len(t, entries, 2, "expected two entries to be left")
+			assert.Equal(t, "second", string(entries[0].Request))
+			assert.Equal(t, "later", string(entries[1].Request))
+		})
+	}
+}
+
+func TestFileStoreSurvivesRestarts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "delay")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store, err := NewFileStore(dir)
+	require.NoError(t, err)
+	require.NoError(t, store.Put(time.Now(), []byte("hello")))
+
+	// Files the store did not write, or did not finish writing, are
+	// ignored.
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, ".tmp-123"), []byte("partial"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "README"), []byte("unrelated"), 0600))
+
+	store, err = NewFileStore(dir)
+	require.NoError(t, err)
+	entries, err := store.Due(time.Now())
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "expected the entry to survive")
+	assert.Equal(t, "hello", string(entries[0].Request))
+}
@@ -0,0 +1,193 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package retry
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/yarpc/x/config"
+	"go.uber.org/yarpc/yarpcerrors"
+
+	"go.uber.org/multierr"
+)
+
+// Config is the configuration for retrying requests made through an
+// outbound. It is usually specified under the retry key of an outbound
+// configured with go.uber.org/yarpc/x/config, once MiddlewareSpec is
+// registered.
+//
+// 	outbounds:
+// 	  keyvalue:
+// 	    http:
+// 	      url: http://127.0.0.1:8080
+// 	    retry:
+// 	      retries: 2
+// 	      timeout: 100ms
+// 	      backoff:
+// 	        first: 10ms
+// 	        max: 500ms
+// 	      codes: [unavailable, resource-exhausted]
+// 	      procedures:
+// 	        KeyValue::setValue:
+// 	          retries: 0
+//
+// Policies specified for individual procedures inherit all unspecified
+// fields from the policy for the outbound, which in turn uses the defaults
+// of NewPolicy for its unspecified fields.
+type Config struct {
+	PolicyConfig `config:",squash"`
+
+	// Policies for individual procedures of the outbound.
+	Procedures map[string]PolicyConfig `config:"procedures"`
+}
+
+// PolicyConfig is the configuration for a single retry Policy. Fields which
+// are unset are left at their defaults.
+type PolicyConfig struct {
+	// Maximum number of times a request is retried.
+	Retries *uint `config:"retries"`
+
+	// Timeout for each attempt.
+	Timeout *time.Duration `config:"timeout"`
+
+	// Range of the backoff between attempts.
+	Backoff *BackoffConfig `config:"backoff"`
+
+	// Names of the error codes for which requests are retried, for example,
+	// "unavailable".
+	Codes []string `config:"codes"`
+}
+
+// BackoffConfig is the configuration for the exponential backoff between
+// attempts.
+type BackoffConfig struct {
+	// Maximum backoff before the first retry.
+	First time.Duration `config:"first"`
+
+	// Maximum backoff before any retry.
+	Max time.Duration `config:"max"`
+}
+
+// NewUnaryMiddleware builds a retry middleware from the given
+// configuration.
+func (c Config) NewUnaryMiddleware() (*UnaryMiddleware, error) {
+	defaultPolicy, err := c.PolicyConfig.policy(PolicyConfig{})
+	if err != nil {
+		return nil, err
+	}
+
+	provider := NewProcedurePolicyProvider(defaultPolicy)
+
+	// Iterate in a deterministic order so that errors are reported
+	// consistently.
+	procedures := make([]string, 0, len(c.Procedures))
+	for procedure := range c.Procedures {
+		procedures = append(procedures, procedure)
+	}
+	sort.Strings(procedures)
+
+	var errs error
+	for _, procedure := range procedures {
+		p, err := c.Procedures[procedure].policy(c.PolicyConfig)
+		if err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("invalid retry policy for procedure %q: %v", procedure, err))
+			continue
+		}
+		provider.Register(procedure, p)
+	}
+	if errs != nil {
+		return nil, errs
+	}
+
+	return NewUnaryMiddleware(WithPolicyProvider(provider)), nil
+}
+
+// MiddlewareSpec returns a configuration specification for the retry
+// middleware, making the retry key of outbounds available and allowing
+// "retry" in their middleware lists. Both accept a Config.
+//
+//  cfg := config.New()
+//  cfg.MustRegisterMiddleware(retry.MiddlewareSpec())
+//
+// This enables retries for an outbound:
+//
+//  outbounds:
+//    keyvalue:
+//      http:
+//        url: http://127.0.0.1:8080
+//      retry:
+//        retries: 2
+func MiddlewareSpec() config.MiddlewareSpec {
+	return config.MiddlewareSpec{
+		Name: "retry",
+		BuildMiddleware: func(c Config, _ *config.Kit) (*UnaryMiddleware, error) {
+			return c.NewUnaryMiddleware()
+		},
+	}
+}
+
+// policy builds a Policy from this configuration, using the given parent
+// configuration for unspecified fields.
+func (pc PolicyConfig) policy(parent PolicyConfig) (*Policy, error) {
+	if pc.Retries == nil {
+		pc.Retries = parent.Retries
+	}
+	if pc.Timeout == nil {
+		pc.Timeout = parent.Timeout
+	}
+	if pc.Backoff == nil {
+		pc.Backoff = parent.Backoff
+	}
+	if pc.Codes == nil {
+		pc.Codes = parent.Codes
+	}
+
+	var opts []PolicyOption
+	if pc.Retries != nil {
+		opts = append(opts, Retries(*pc.Retries))
+	}
+	if pc.Timeout != nil {
+		if *pc.Timeout < 0 {
+			return nil, fmt.Errorf("timeout must not be negative: %v", *pc.Timeout)
+		}
+		opts = append(opts, PerAttemptTimeout(*pc.Timeout))
+	}
+	if b := pc.Backoff; b != nil {
+		if b.First < 0 || b.Max < b.First {
+			return nil, fmt.Errorf(
+				"backoff must satisfy 0 <= first <= max: got first %v and max %v", b.First, b.Max)
+		}
+		opts = append(opts, Backoff(b.First, b.Max))
+	}
+	if pc.Codes != nil {
+		codes := make([]yarpcerrors.Code, len(pc.Codes))
+		for i, name := range pc.Codes {
+			if err := codes[i].UnmarshalText([]byte(name)); err != nil {
+				return nil, err
+			}
+		}
+		opts = append(opts, RetryableCodes(codes...))
+	}
+
+	return NewPolicy(opts...), nil
+}
@@ -0,0 +1,215 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package retry
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/internal/whitespace"
+	"go.uber.org/yarpc/x/config"
+	"go.uber.org/yarpc/yarpcerrors"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig(t *testing.T) {
+	uintPtr := func(i uint) *uint { return &i }
+	durationPtr := func(d time.Duration) *time.Duration { return &d }
+
+	cfg := Config{
+		PolicyConfig: PolicyConfig{
+			Retries: uintPtr(2),
+			Timeout: durationPtr(100 * time.Millisecond),
+			Backoff: &BackoffConfig{First: 5 * time.Millisecond, Max: 50 * time.Millisecond},
+		},
+		Procedures: map[string]PolicyConfig{
+			"get": {Codes: []string{"unknown"}},
+			"set": {Retries: uintPtr(0), Timeout: durationPtr(0)},
+		},
+	}
+
+	mw, err := cfg.NewUnaryMiddleware()
+	require.NoError(t, err)
+
+	pp := mw.provider.(*ProcedurePolicyProvider)
+
+	defaultPolicy := pp.defaultPolicy
+	assert.Equal(t, uint(2), defaultPolicy.retries)
+	assert.Equal(t, 100*time.Millisecond, defaultPolicy.timeout)
+	assert.True(t, defaultPolicy.backoff.Duration(10) < 50*time.Millisecond, "backoff must be capped")
+	assert.True(t, defaultPolicy.isRetryable(yarpcerrors.Newf(yarpcerrors.CodeUnavailable, "")))
+
+	get := pp.procedures["get"]
+	assert.Equal(t, uint(2), get.retries, "must inherit retries")
+	assert.Equal(t, 100*time.Millisecond, get.timeout, "must inherit timeout")
+	assert.True(t, get.isRetryable(yarpcerrors.Newf(yarpcerrors.CodeUnknown, "")))
+	assert.False(t, get.isRetryable(yarpcerrors.Newf(yarpcerrors.CodeUnavailable, "")))
+
+	set := pp.procedures["set"]
+	assert.Equal(t, uint(0), set.retries)
+	assert.Equal(t, time.Duration(0), set.timeout)
+	assert.True(t, set.isRetryable(yarpcerrors.Newf(yarpcerrors.CodeUnavailable, "")), "must use default codes")
+}
+
+func TestConfigDefaults(t *testing.T) {
+	mw, err := Config{}.NewUnaryMiddleware()
+	require.NoError(t, err)
+
+	p := mw.provider.(*ProcedurePolicyProvider).defaultPolicy
+	assert.Equal(t, NewPolicy().retries, p.retries)
+	assert.Equal(t, NewPolicy().timeout, p.timeout)
+}
+
+func TestConfigErrors(t *testing.T) {
+	tests := []struct {
+		desc    string
+		give    Config
+		wantErr string
+	}{
+		{
+			desc: "negative timeout",
+			give: Config{PolicyConfig: PolicyConfig{Timeout: func() *time.Duration {
+				d := -time.Second
+				return &d
+			}()}},
+			wantErr: "timeout must not be negative: -1s",
+		},
+		{
+			desc:    "backoff max less than first",
+			give:    Config{PolicyConfig: PolicyConfig{Backoff: &BackoffConfig{First: time.Second, Max: time.Millisecond}}},
+			wantErr: "backoff must satisfy 0 <= first <= max: got first 1s and max 1ms",
+		},
+		{
+			desc:    "unknown code",
+			give:    Config{PolicyConfig: PolicyConfig{Codes: []string{"great-sadness"}}},
+			wantErr: `unknown code: "great-sadness"`,
+		},
+		{
+			desc: "procedure errors",
+			give: Config{Procedures: map[string]PolicyConfig{
+				"b": {Codes: []string{"foo"}},
+				"a": {Codes: []string{"bar"}},
+			}},
+			wantErr: `invalid retry policy for procedure "a": unknown code: "bar"; ` +
+				`invalid retry policy for procedure "b": unknown code: "foo"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			_, err := tt.give.NewUnaryMiddleware()
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+// newMiddlewareSpecConfigurator returns a Configurator with MiddlewareSpec
+// registered and a "mock" transport whose unary outbounds are the given
+// outbound.
+func newMiddlewareSpecConfigurator(mockCtrl *gomock.Controller, outbound transport.UnaryOutbound) *config.Configurator {
+	cfg := config.New()
+	cfg.MustRegisterMiddleware(MiddlewareSpec())
+	cfg.MustRegisterTransport(config.TransportSpec{
+		Name: "mock",
+		BuildTransport: func(struct{}, *config.Kit) (transport.Transport, error) {
+			return transporttest.NewMockTransport(mockCtrl), nil
+		},
+		BuildUnaryOutbound: func(struct{}, transport.Transport, *config.Kit) (transport.UnaryOutbound, error) {
+			return outbound, nil
+		},
+	})
+	return cfg
+}
+
+func TestMiddlewareSpec(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	outbound := transporttest.NewMockUnaryOutbound(mockCtrl)
+	c, err := newMiddlewareSpecConfigurator(mockCtrl, outbound).LoadConfigFromYAML("foo", strings.NewReader(whitespace.Expand(`
+		outbounds:
+			bar:
+				mock: {}
+				retry:
+					retries: 2
+					backoff:
+						first: 0s
+						max: 0s
+					procedures:
+						setValue:
+							retries: 0
+	`)))
+	require.NoError(t, err)
+
+	unary := c.Outbounds["bar"].Unary
+	require.NotNil(t, unary)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	unavailable := yarpcerrors.Newf(yarpcerrors.CodeUnavailable, "try again")
+
+	t.Run("getValue", func(t *testing.T) {
+		res := &transport.Response{}
+		gomock.InOrder(
+			outbound.EXPECT().Call(gomock.Any(), gomock.Any()).Return(nil, unavailable),
+			outbound.EXPECT().Call(gomock.Any(), gomock.Any()).Return(nil, unavailable),
+			outbound.EXPECT().Call(gomock.Any(), gomock.Any()).Return(res, nil),
+		)
+
+		got, err := unary.Call(ctx, &transport.Request{Procedure: "getValue"})
+		require.NoError(t, err)
+		assert.Equal(t, res, got)
+	})
+
+	t.Run("setValue", func(t *testing.T) {
+		outbound.EXPECT().Call(gomock.Any(), gomock.Any()).Return(nil, unavailable)
+
+		_, err := unary.Call(ctx, &transport.Request{Procedure: "setValue"})
+		assert.Equal(t, unavailable, err)
+	})
+}
+
+func TestMiddlewareSpecErrors(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	outbound := transporttest.NewMockUnaryOutbound(mockCtrl)
+	_, err := newMiddlewareSpecConfigurator(mockCtrl, outbound).LoadConfigFromYAML("foo", strings.NewReader(whitespace.Expand(`
+		outbounds:
+			bar:
+				mock: {}
+				retry:
+					procedures:
+						getValue:
+							codes: [unavailable, great-sadness]
+	`)))
+	require.Error(t, err, "expected failure")
+	assert.Contains(t, err.Error(), `failed to load retry configuration for outbound "bar"`)
+	assert.Contains(t, err.Error(), `invalid retry policy for procedure "getValue": unknown code: "great-sadness"`)
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package retry provides an outbound middleware which retries failed unary
+// requests.
+//
+// Requests are retried only if they failed with an error whose code the
+// Policy considers retryable. By default, these are errors with
+// yarpcerrors.CodeUnavailable and yarpcerrors.CodeResourceExhausted, and
+// attempts which exceeded their per-attempt timeout. Attempts are spaced out
+// using exponential backoff with jitter, and a request is never retried if
+// the backoff would take it past the deadline of its context.
+//
+// 	policy := retry.NewPolicy(
+// 		retry.Retries(2),
+// 		retry.PerAttemptTimeout(100*time.Millisecond),
+// 	)
+// 	provider := retry.NewProcedurePolicyProvider(policy)
+// 	provider.Register("KeyValue::setValue", retry.NewPolicy(retry.Retries(0)))
+//
+// 	outbound := middleware.ApplyUnaryOutbound(
+// 		http.NewTransport().NewSingleOutbound("http://127.0.0.1:8080"),
+// 		retry.NewUnaryMiddleware(retry.WithPolicyProvider(provider)),
+// 	)
+//
+// Request bodies are read into memory before the first attempt so that they
+// may be replayed on subsequent attempts.
+//
+// Retry policies may also be specified for outbounds configured with
+// go.uber.org/yarpc/x/config after registering MiddlewareSpec. See Config
+// for details.
+package retry
@@ -0,0 +1,183 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package retry
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"time"
+
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/iopool"
+)
+
+// MiddlewareOption customizes the behavior of the retry middleware.
+type MiddlewareOption func(*UnaryMiddleware)
+
+// WithPolicy specifies a Policy which applies to all requests.
+func WithPolicy(p *Policy) MiddlewareOption {
+	return WithPolicyProvider(NewProcedurePolicyProvider(p))
+}
+
+// WithPolicyProvider specifies how the Policy for each request is selected.
+//
+// Defaults to a provider which returns the default Policy for all requests.
+func WithPolicyProvider(pp PolicyProvider) MiddlewareOption {
+	return func(m *UnaryMiddleware) {
+		m.provider = pp
+	}
+}
+
+// UnaryMiddleware is a unary outbound middleware which retries failed
+// requests.
+type UnaryMiddleware struct {
+	provider PolicyProvider
+}
+
+var _ middleware.UnaryOutbound = (*UnaryMiddleware)(nil)
+
+// NewUnaryMiddleware builds a new retry middleware for unary outbounds.
+func NewUnaryMiddleware(opts ...MiddlewareOption) *UnaryMiddleware {
+	m := &UnaryMiddleware{provider: NewProcedurePolicyProvider(NewPolicy())}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Call implements middleware.UnaryOutbound.
+func (m *UnaryMiddleware) Call(ctx context.Context, req *transport.Request, out transport.UnaryOutbound) (*transport.Response, error) {
+	policy := m.provider.Policy(ctx, req)
+	if policy == nil || policy.retries == 0 {
+		return m.callAttempt(ctx, policy, req, out)
+	}
+
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := uint(0); ; attempt++ {
+		attemptReq := *req
+		if body != nil {
+			attemptReq.Body = bytes.NewReader(body)
+		}
+
+		res, err := m.callAttempt(ctx, policy, &attemptReq, out)
+		if err == nil || attempt >= policy.retries || !shouldRetry(ctx, policy, err) {
+			return res, err
+		}
+
+		if !wait(ctx, policy.backoff.Duration(attempt)) {
+			return nil, err
+		}
+	}
+}
+
+// callAttempt makes a single attempt of the request, applying the
+// per-attempt timeout of the policy, if any.
+func (m *UnaryMiddleware) callAttempt(ctx context.Context, policy *Policy, req *transport.Request, out transport.UnaryOutbound) (*transport.Response, error) {
+	if policy == nil || policy.timeout <= 0 {
+		return out.Call(ctx, req)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, policy.timeout)
+	res, err := out.Call(ctx, req)
+	if err != nil || res == nil || res.Body == nil {
+		cancel()
+		return res, err
+	}
+
+	// The response body may still be streaming from the peer so the
+	// context for this attempt cannot be cancelled until the body is
+	// closed.
+	res.Body = cancelOnClose{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+// shouldRetry returns true if a request which failed with the given error
+// should be retried.
+func shouldRetry(ctx context.Context, policy *Policy, err error) bool {
+	if ctx.Err() != nil {
+		// The request has been cancelled or it is past its deadline.
+		return false
+	}
+
+	if policy.timeout > 0 && transport.IsTimeoutError(err) {
+		// The context of the request is still alive so only the attempt
+		// timed out.
+		return true
+	}
+
+	return policy.isRetryable(err)
+}
+
+// wait blocks for the given duration. Returns false without waiting if the
+// context would expire before then, or if it expires while waiting.
+func wait(ctx context.Context, d time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && !time.Now().Add(d).Before(deadline) {
+		return false
+	}
+
+	if d <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// readBody reads the body of the request into memory so that it may be
+// replayed. Returns nil if the request has no body.
+func readBody(req *transport.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	if _, err := iopool.Copy(&buf, req.Body); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// cancelOnClose cancels the context of an attempt once the response body
+// has been closed.
+type cancelOnClose struct {
+	io.ReadCloser
+
+	cancel context.CancelFunc
+}
+
+func (c cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
@@ -0,0 +1,260 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package retry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/yarpcerrors"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// noBackoff is a PolicyOption which disables backoff between attempts.
+var noBackoff = Backoff(0, 0)
+
+func TestUnaryMiddleware(t *testing.T) {
+	unavailable := yarpcerrors.Newf(yarpcerrors.CodeUnavailable, "unavailable")
+	exhausted := yarpcerrors.Newf(yarpcerrors.CodeResourceExhausted, "exhausted")
+	notFound := yarpcerrors.Newf(yarpcerrors.CodeNotFound, "not found")
+	unknown := errors.New("great sadness")
+
+	tests := []struct {
+		desc     string
+		policy   *Policy
+		attempts []error
+		wantErr  error
+	}{
+		{
+			desc:     "success",
+			policy:   NewPolicy(noBackoff),
+			attempts: []error{nil},
+		},
+		{
+			desc:     "nil policy",
+			attempts: []error{unavailable},
+			wantErr:  unavailable,
+		},
+		{
+			desc:     "zero retries",
+			policy:   NewPolicy(Retries(0), noBackoff),
+			attempts: []error{unavailable},
+			wantErr:  unavailable,
+		},
+		{
+			desc:     "retry then success",
+			policy:   NewPolicy(Retries(3), noBackoff),
+			attempts: []error{unavailable, exhausted, nil},
+		},
+		{
+			desc:     "retries exhausted",
+			policy:   NewPolicy(Retries(2), noBackoff),
+			attempts: []error{unavailable, unavailable, exhausted},
+			wantErr:  exhausted,
+		},
+		{
+			desc:     "not retryable",
+			policy:   NewPolicy(Retries(2), noBackoff),
+			attempts: []error{unavailable, notFound},
+			wantErr:  notFound,
+		},
+		{
+			desc:     "unknown errors are not retried by default",
+			policy:   NewPolicy(Retries(2), noBackoff),
+			attempts: []error{unknown},
+			wantErr:  unknown,
+		},
+		{
+			desc:     "custom codes",
+			policy:   NewPolicy(Retries(2), noBackoff, RetryableCodes(yarpcerrors.CodeUnknown)),
+			attempts: []error{unknown, unavailable},
+			wantErr:  unavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			var bodies []string
+			out := transporttest.NewMockUnaryOutbound(mockCtrl)
+			var calls []*gomock.Call
+			for _, err := range tt.attempts {
+				err := err
+				calls = append(calls, out.EXPECT().Call(gomock.Any(), gomock.Any()).
+					Do(func(_ context.Context, req *transport.Request) {
+						body, rerr := ioutil.ReadAll(req.Body)
+						require.NoError(t, rerr)
+						bodies = append(bodies, string(body))
+					}).
+					Return(&transport.Response{}, err))
+			}
+			gomock.InOrder(calls...)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			mw := NewUnaryMiddleware(WithPolicy(tt.policy))
+			_, err := mw.Call(ctx, &transport.Request{
+				Procedure: "hello",
+				Body:      bytes.NewReader([]byte("world")),
+			}, out)
+			assert.Equal(t, tt.wantErr, err)
+
+			for i, body := range bodies {
+				assert.Equal(t, "world", body, "body of attempt %d did not match", i)
+			}
+		})
+	}
+}
+
+func TestUnaryMiddlewareNoBody(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	out := transporttest.NewMockUnaryOutbound(mockCtrl)
+	out.EXPECT().Call(gomock.Any(), &transport.Request{Procedure: "hello"}).
+		Return(nil, yarpcerrors.Newf(yarpcerrors.CodeUnavailable, "unavailable"))
+	out.EXPECT().Call(gomock.Any(), &transport.Request{Procedure: "hello"}).
+		Return(&transport.Response{}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	mw := NewUnaryMiddleware(WithPolicy(NewPolicy(noBackoff)))
+	_, err := mw.Call(ctx, &transport.Request{Procedure: "hello"}, out)
+	assert.NoError(t, err)
+}
+
+func TestUnaryMiddlewareDeadline(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	unavailable := yarpcerrors.Newf(yarpcerrors.CodeUnavailable, "unavailable")
+	out := transporttest.NewMockUnaryOutbound(mockCtrl)
+	out.EXPECT().Call(gomock.Any(), gomock.Any()).Return(nil, unavailable)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// The backoff is always longer than the time left for the request so
+	// we must give up right away.
+	mw := NewUnaryMiddleware(WithPolicy(NewPolicy(Retries(5), Backoff(time.Hour, time.Hour))))
+
+	start := time.Now()
+	_, err := mw.Call(ctx, &transport.Request{}, out)
+	assert.Equal(t, unavailable, err)
+	assert.True(t, time.Since(start) < 50*time.Millisecond, "must not wait for the deadline")
+}
+
+func TestUnaryMiddlewareCancelled(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	unavailable := yarpcerrors.Newf(yarpcerrors.CodeUnavailable, "unavailable")
+	out := transporttest.NewMockUnaryOutbound(mockCtrl)
+	out.EXPECT().Call(gomock.Any(), gomock.Any()).
+		Do(func(context.Context, *transport.Request) { cancel() }).
+		Return(nil, unavailable)
+
+	mw := NewUnaryMiddleware(WithPolicy(NewPolicy(Retries(5), noBackoff)))
+	_, err := mw.Call(ctx, &transport.Request{}, out)
+	assert.Equal(t, unavailable, err)
+}
+
+func TestUnaryMiddlewarePerAttemptTimeout(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var attemptCtx context.Context
+	out := transporttest.NewMockUnaryOutbound(mockCtrl)
+	gomock.InOrder(
+		out.EXPECT().Call(gomock.Any(), gomock.Any()).
+			Do(func(ctx context.Context, _ *transport.Request) { <-ctx.Done() }).
+			Return(nil, yarpcerrors.Newf(yarpcerrors.CodeDeadlineExceeded, "too slow")),
+		out.EXPECT().Call(gomock.Any(), gomock.Any()).
+			Do(func(ctx context.Context, _ *transport.Request) { attemptCtx = ctx }).
+			Return(&transport.Response{Body: ioutil.NopCloser(bytes.NewReader(nil))}, nil),
+	)
+
+	mw := NewUnaryMiddleware(WithPolicy(NewPolicy(
+		PerAttemptTimeout(10*time.Millisecond),
+		noBackoff,
+	)))
+	res, err := mw.Call(ctx, &transport.Request{}, out)
+	require.NoError(t, err)
+
+	deadline, ok := attemptCtx.Deadline()
+	require.True(t, ok, "attempt must have a deadline")
+	parentDeadline, _ := ctx.Deadline()
+	assert.True(t, deadline.Before(parentDeadline), "attempt deadline must be earlier than the request deadline")
+
+	// The attempt must stay alive until the response body is closed.
+	assert.NoError(t, attemptCtx.Err())
+	require.NoError(t, res.Body.Close())
+	assert.Equal(t, context.Canceled, attemptCtx.Err())
+}
+
+func TestUnaryMiddlewareReadBodyError(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	out := transporttest.NewMockUnaryOutbound(mockCtrl)
+	mw := NewUnaryMiddleware()
+
+	_, err := mw.Call(context.Background(), &transport.Request{Body: errReader{}}, out)
+	assert.EqualError(t, err, "great sadness")
+}
+
+func TestWait(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	assert.True(t, wait(ctx, 0))
+	assert.True(t, wait(ctx, time.Millisecond))
+	assert.False(t, wait(ctx, 2*time.Second))
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.False(t, wait(cancelled, time.Second))
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, errors.New("great sadness")
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package retry
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/backoff"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+const (
+	_defaultRetries      = 1
+	_defaultBackoffFirst = 10 * time.Millisecond
+	_defaultBackoffMax   = time.Second
+)
+
+var _defaultRetryableCodes = []yarpcerrors.Code{
+	yarpcerrors.CodeUnavailable,
+	yarpcerrors.CodeResourceExhausted,
+}
+
+// Policy specifies how failed requests are retried.
+type Policy struct {
+	retries   uint
+	timeout   time.Duration
+	backoff   *backoff.Exponential
+	retryable map[yarpcerrors.Code]struct{}
+}
+
+// PolicyOption customizes a Policy.
+type PolicyOption func(*Policy)
+
+// NewPolicy builds a new retry Policy.
+//
+// By default, a request is retried once with no per-attempt timeout, backing
+// off between 10 milliseconds and one second, if it fails with
+// CodeUnavailable or CodeResourceExhausted.
+func NewPolicy(opts ...PolicyOption) *Policy {
+	p := &Policy{
+		retries: _defaultRetries,
+		backoff: backoff.NewExponential(_defaultBackoffFirst, _defaultBackoffMax),
+	}
+	RetryableCodes(_defaultRetryableCodes...)(p)
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Retries specifies the maximum number of times a request is retried after
+// the first attempt fails. Requests are not retried if this is zero.
+func Retries(n uint) PolicyOption {
+	return func(p *Policy) {
+		p.retries = n
+	}
+}
+
+// PerAttemptTimeout specifies the timeout for each attempt. Attempts which
+// time out are retried if the deadline of the request has not been reached.
+//
+// If unset or zero, each attempt may use the remaining time of the request.
+func PerAttemptTimeout(d time.Duration) PolicyOption {
+	return func(p *Policy) {
+		p.timeout = d
+	}
+}
+
+// Backoff specifies the range of the exponential backoff between attempts.
+// The backoff before the first retry is at most first, and it doubles for
+// each subsequent retry until it reaches max.
+func Backoff(first, max time.Duration) PolicyOption {
+	return func(p *Policy) {
+		p.backoff = backoff.NewExponential(first, max)
+	}
+}
+
+// RetryableCodes specifies the error codes for which requests are retried.
+// Errors without a code are treated as errors with CodeUnknown.
+//
+// Defaults to CodeUnavailable and CodeResourceExhausted.
+func RetryableCodes(codes ...yarpcerrors.Code) PolicyOption {
+	return func(p *Policy) {
+		p.retryable = make(map[yarpcerrors.Code]struct{}, len(codes))
+		for _, code := range codes {
+			p.retryable[code] = struct{}{}
+		}
+	}
+}
+
+// isRetryable returns true if a request that failed with the given error may
+// be retried.
+func (p *Policy) isRetryable(err error) bool {
+	_, ok := p.retryable[yarpcerrors.FromError(err).Code()]
+	return ok
+}
+
+// PolicyProvider decides which Policy applies to a request.
+type PolicyProvider interface {
+	// Policy returns the Policy for the given request, or nil if the
+	// request must not be retried.
+	Policy(context.Context, *transport.Request) *Policy
+}
+
+// ProcedurePolicyProvider is a PolicyProvider which selects policies based
+// on the procedure being called.
+//
+// ProcedurePolicyProvider is not safe for concurrent use. Register all
+// policies before using it.
+type ProcedurePolicyProvider struct {
+	defaultPolicy *Policy
+	procedures    map[string]*Policy
+}
+
+var _ PolicyProvider = (*ProcedurePolicyProvider)(nil)
+
+// NewProcedurePolicyProvider builds a new ProcedurePolicyProvider which uses
+// the given Policy for procedures which don't have a policy registered.
+//
+// Requests to such procedures are not retried if the default Policy is nil.
+func NewProcedurePolicyProvider(defaultPolicy *Policy) *ProcedurePolicyProvider {
+	return &ProcedurePolicyProvider{
+		defaultPolicy: defaultPolicy,
+		procedures:    make(map[string]*Policy),
+	}
+}
+
+// Register registers the Policy for requests to the given procedure.
+//
+// Requests to the procedure are not retried if the Policy is nil.
+func (pp *ProcedurePolicyProvider) Register(procedure string, p *Policy) {
+	pp.procedures[procedure] = p
+}
+
+// Policy returns the Policy for the procedure of the given request.
+func (pp *ProcedurePolicyProvider) Policy(_ context.Context, req *transport.Request) *Policy {
+	if p, ok := pp.procedures[req.Procedure]; ok {
+		return p
+	}
+	return pp.defaultPolicy
+}
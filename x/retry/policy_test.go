@@ -0,0 +1,56 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package retry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/yarpcerrors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPolicyDefaults(t *testing.T) {
+	p := NewPolicy()
+	assert.Equal(t, uint(1), p.retries)
+	assert.Equal(t, time.Duration(0), p.timeout)
+	assert.True(t, p.isRetryable(yarpcerrors.Newf(yarpcerrors.CodeUnavailable, "")))
+	assert.True(t, p.isRetryable(yarpcerrors.Newf(yarpcerrors.CodeResourceExhausted, "")))
+	assert.False(t, p.isRetryable(yarpcerrors.Newf(yarpcerrors.CodeInternal, "")))
+	assert.False(t, p.isRetryable(context.Canceled))
+}
+
+func TestProcedurePolicyProvider(t *testing.T) {
+	defaultPolicy := NewPolicy()
+	getPolicy := NewPolicy(Retries(3))
+
+	pp := NewProcedurePolicyProvider(defaultPolicy)
+	pp.Register("get", getPolicy)
+	pp.Register("set", nil)
+
+	ctx := context.Background()
+	assert.Equal(t, getPolicy, pp.Policy(ctx, &transport.Request{Procedure: "get"}))
+	assert.Nil(t, pp.Policy(ctx, &transport.Request{Procedure: "set"}))
+	assert.Equal(t, defaultPolicy, pp.Policy(ctx, &transport.Request{Procedure: "delete"}))
+}
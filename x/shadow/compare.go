@@ -0,0 +1,137 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package shadow
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/iopool"
+	"go.uber.org/yarpc/yarpcerrors"
+
+	"go.uber.org/multierr"
+)
+
+// Result is the outcome of a unary call.
+type Result struct {
+	// Body of the response. Empty if the call failed.
+	Body []byte
+
+	// Whether the response was an application error.
+	ApplicationError bool
+
+	// Err is the error the call failed with, if any.
+	Err error
+}
+
+// CompareFunc reports whether the result of a shadow call matches the result
+// of the primary call.
+type CompareFunc func(primary, shadow Result) bool
+
+// Equal is a CompareFunc which matches results if both calls succeeded with
+// the same body and application error status, or both failed with errors of
+// the same code.
+func Equal(primary, shadow Result) bool {
+	if primary.Err != nil || shadow.Err != nil {
+		if primary.Err == nil || shadow.Err == nil {
+			return false
+		}
+		return yarpcerrors.FromError(primary.Err).Code() == yarpcerrors.FromError(shadow.Err).Code()
+	}
+	return primary.ApplicationError == shadow.ApplicationError &&
+		bytes.Equal(primary.Body, shadow.Body)
+}
+
+// readResult reads the response of a call completely.
+func readResult(res *transport.Response, err error) Result {
+	if err != nil {
+		return Result{Err: err}
+	}
+
+	r := Result{ApplicationError: res.ApplicationError}
+	if res.Body != nil {
+		var buf bytes.Buffer
+		_, err := iopool.Copy(&buf, res.Body)
+		r.Err = multierr.Append(err, res.Body.Close())
+		r.Body = buf.Bytes()
+	}
+	return r
+}
+
+// pendingResult is the result of the primary call, which shadow calls wait
+// for to compare their own results with.
+type pendingResult struct {
+	once     sync.Once
+	done     chan struct{}
+	result   Result
+	complete bool
+}
+
+func newPendingResult() *pendingResult {
+	return &pendingResult{done: make(chan struct{})}
+}
+
+// set records the result of the primary call.
+func (p *pendingResult) set(r Result) {
+	p.once.Do(func() {
+		p.result = r
+		p.complete = true
+		close(p.done)
+	})
+}
+
+// abandon records that the result of the primary call is unknown.
+func (p *pendingResult) abandon() {
+	p.once.Do(func() { close(p.done) })
+}
+
+// recordingBody records the response body of the primary call as the caller
+// reads it, and sets the pending result once the body is closed.
+type recordingBody struct {
+	io.ReadCloser
+
+	buf              bytes.Buffer
+	eof              bool
+	applicationError bool
+	result           *pendingResult
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if err == io.EOF {
+		b.eof = true
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	err := b.ReadCloser.Close()
+	if b.eof {
+		b.result.set(Result{Body: b.buf.Bytes(), ApplicationError: b.applicationError})
+	} else {
+		// The caller did not read the whole body.
+		b.result.abandon()
+	}
+	return err
+}
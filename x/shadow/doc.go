@@ -0,0 +1,46 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package shadow provides a unary outbound which copies a sample of requests
+// to shadow outbounds, for example to try out a new transport or a new
+// deployment of a service with production traffic.
+//
+// Every request is sent to the primary outbound, whose response is returned
+// to the caller. Sampled requests are also sent to every shadow outbound in
+// the background. The results of shadow calls are discarded, and shadow calls
+// never delay the primary call: a copy is dropped rather than queued if too
+// many shadow calls are in flight.
+//
+// 	outbound := shadow.NewUnaryOutbound(
+// 		tchannelTransport.NewSingleOutbound("127.0.0.1:4040"),
+// 		shadow.WithShadow("grpc", grpcTransport.NewSingleOutbound("127.0.0.1:5050")),
+// 		shadow.SampleRate(0.1),
+// 		shadow.Compare(shadow.Equal),
+// 		shadow.Metrics(scope),
+// 	)
+//
+// With Compare, the result of each shadow call is compared with the result
+// of the primary call, and matches and mismatches are counted in the scope
+// given to Metrics. The response body of the primary call is recorded as the
+// caller reads it, so it is compared only if the caller reads it completely.
+//
+// Request bodies of sampled requests are read into memory so that they may
+// be sent to every outbound.
+package shadow
@@ -0,0 +1,50 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package shadow
+
+import "github.com/uber-go/tally"
+
+// shadowMetrics counts what happens to the copies of requests sent to a
+// shadow outbound.
+type shadowMetrics struct {
+	// calls counts requests sent to the shadow.
+	calls tally.Counter
+	// failures counts shadow calls which failed.
+	failures tally.Counter
+	// dropped counts sampled requests which were not sent to the shadow
+	// because too many shadow calls were in flight.
+	dropped tally.Counter
+	// matches and mismatches count the results of shadow calls which
+	// matched or differed from the results of primary calls.
+	matches    tally.Counter
+	mismatches tally.Counter
+}
+
+func newShadowMetrics(scope tally.Scope, name string) shadowMetrics {
+	scope = scope.Tagged(map[string]string{"shadow": name})
+	return shadowMetrics{
+		calls:      scope.Counter("shadow_calls"),
+		failures:   scope.Counter("shadow_failures"),
+		dropped:    scope.Counter("shadow_dropped"),
+		matches:    scope.Counter("shadow_matches"),
+		mismatches: scope.Counter("shadow_mismatches"),
+	}
+}
@@ -0,0 +1,340 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package shadow
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/introspection"
+	"go.uber.org/yarpc/internal/iopool"
+	intsync "go.uber.org/yarpc/internal/sync"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/uber-go/tally"
+	"go.uber.org/multierr"
+)
+
+const (
+	defaultMaxInFlight = 100
+
+	// Shadow calls for requests without a deadline time out after this long.
+	defaultTimeout = time.Second
+)
+
+var (
+	_ transport.UnaryOutbound              = (*UnaryOutbound)(nil)
+	_ introspection.IntrospectableOutbound = (*UnaryOutbound)(nil)
+)
+
+// OutboundOption customizes the behavior of a shadowing UnaryOutbound.
+type OutboundOption func(*UnaryOutbound)
+
+// WithShadow adds an outbound to which copies of sampled requests are sent.
+// The name identifies the shadow in metrics.
+func WithShadow(name string, out transport.UnaryOutbound) OutboundOption {
+	return func(o *UnaryOutbound) {
+		o.shadows = append(o.shadows, &shadow{name: name, out: out})
+	}
+}
+
+// SampleRate specifies the fraction of requests, between 0 and 1, which are
+// copied to the shadow outbounds.
+//
+// Defaults to 1, copying every request.
+func SampleRate(rate float64) OutboundOption {
+	return func(o *UnaryOutbound) {
+		o.sampleRate = rate
+	}
+}
+
+// Compare specifies how the results of shadow calls are compared with the
+// results of primary calls. Results are not compared by default.
+func Compare(f CompareFunc) OutboundOption {
+	return func(o *UnaryOutbound) {
+		o.compare = f
+	}
+}
+
+// MaxInFlight specifies how many shadow calls may be in flight at once.
+// Copies of requests beyond this limit are dropped.
+//
+// Defaults to 100.
+func MaxInFlight(n int) OutboundOption {
+	return func(o *UnaryOutbound) {
+		o.maxInFlight = n
+	}
+}
+
+// Metrics specifies the scope to which the outbound reports shadow calls,
+// their failures, dropped copies, and the results of comparisons. Metrics
+// are tagged with the name of the shadow.
+func Metrics(scope tally.Scope) OutboundOption {
+	return func(o *UnaryOutbound) {
+		o.scope = scope
+	}
+}
+
+type shadow struct {
+	name    string
+	out     transport.UnaryOutbound
+	metrics shadowMetrics
+}
+
+// UnaryOutbound is a unary outbound which sends every request to a primary
+// outbound and copies of sampled requests to shadow outbounds.
+type UnaryOutbound struct {
+	primary transport.UnaryOutbound
+	shadows []*shadow
+
+	sampleRate  float64
+	compare     CompareFunc
+	maxInFlight int
+	scope       tally.Scope
+
+	inFlight chan struct{}
+
+	// Shadow calls in flight, which the outbound waits for when it stops.
+	lock    sync.Mutex
+	stopped bool
+	calls   sync.WaitGroup
+
+	once intsync.LifecycleOnce
+}
+
+// NewUnaryOutbound builds a unary outbound which sends requests to the
+// given primary outbound and copies them to the shadow outbounds given
+// with WithShadow.
+//
+// The primary and shadow outbounds are started and stopped with the
+// returned outbound.
+func NewUnaryOutbound(primary transport.UnaryOutbound, opts ...OutboundOption) *UnaryOutbound {
+	o := &UnaryOutbound{
+		primary:     primary,
+		sampleRate:  1,
+		maxInFlight: defaultMaxInFlight,
+		scope:       tally.NoopScope,
+		once:        intsync.Once(),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	o.inFlight = make(chan struct{}, o.maxInFlight)
+	for _, s := range o.shadows {
+		s.metrics = newShadowMetrics(o.scope, s.name)
+	}
+	return o
+}
+
+// Transports returns the transports used by the primary and shadow
+// outbounds.
+func (o *UnaryOutbound) Transports() []transport.Transport {
+	transports := o.primary.Transports()
+	for _, s := range o.shadows {
+		transports = append(transports, s.out.Transports()...)
+	}
+	return transports
+}
+
+// Start starts the primary and shadow outbounds.
+func (o *UnaryOutbound) Start() error {
+	return o.once.Start(o.start)
+}
+
+func (o *UnaryOutbound) start() error {
+	err := o.primary.Start()
+	for _, s := range o.shadows {
+		err = multierr.Append(err, s.out.Start())
+	}
+	return err
+}
+
+// Stop waits for shadow calls in flight to finish and stops the primary
+// and shadow outbounds.
+func (o *UnaryOutbound) Stop() error {
+	return o.once.Stop(o.stop)
+}
+
+func (o *UnaryOutbound) stop() error {
+	o.lock.Lock()
+	o.stopped = true
+	o.lock.Unlock()
+	o.calls.Wait()
+
+	err := o.primary.Stop()
+	for _, s := range o.shadows {
+		err = multierr.Append(err, s.out.Stop())
+	}
+	return err
+}
+
+// IsRunning returns whether the outbound is running.
+func (o *UnaryOutbound) IsRunning() bool {
+	return o.once.IsRunning()
+}
+
+// Call sends the request to the primary outbound and returns its response.
+// If the request is sampled, copies of it are sent to the shadow outbounds
+// in the background.
+func (o *UnaryOutbound) Call(ctx context.Context, req *transport.Request) (*transport.Response, error) {
+	if err := o.once.WhenRunning(ctx); err != nil {
+		return nil, err
+	}
+	if len(o.shadows) == 0 || rand.Float64() >= o.sampleRate {
+		return o.primary.Call(ctx, req)
+	}
+
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var primary *pendingResult
+	if o.compare != nil {
+		primary = newPendingResult()
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultTimeout)
+	}
+	for _, s := range o.shadows {
+		o.callShadow(ctx, s, req, body, deadline, primary)
+	}
+
+	preq := *req
+	preq.Body = bytes.NewReader(body)
+	res, err := o.primary.Call(ctx, &preq)
+	if primary != nil {
+		switch {
+		case err != nil:
+			primary.set(Result{Err: err})
+		case res.Body == nil:
+			primary.set(Result{ApplicationError: res.ApplicationError})
+		default:
+			res.Body = &recordingBody{
+				ReadCloser:       res.Body,
+				applicationError: res.ApplicationError,
+				result:           primary,
+			}
+		}
+	}
+	return res, err
+}
+
+// callShadow sends a copy of the request to the shadow in the background,
+// unless too many shadow calls are in flight or the outbound is stopping.
+// The result is compared with the result of the primary call if it is given.
+func (o *UnaryOutbound) callShadow(ctx context.Context, s *shadow, req *transport.Request, body []byte, deadline time.Time, primary *pendingResult) {
+	select {
+	case o.inFlight <- struct{}{}:
+	default:
+		s.metrics.dropped.Inc(1)
+		return
+	}
+
+	o.lock.Lock()
+	if o.stopped {
+		o.lock.Unlock()
+		<-o.inFlight
+		return
+	}
+	o.calls.Add(1)
+	o.lock.Unlock()
+
+	// The shadow call must outlive the primary call, so it gets a context
+	// of its own with the same deadline and tracing span.
+	sctx, cancel := context.WithDeadline(context.Background(), deadline)
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		sctx = opentracing.ContextWithSpan(sctx, span)
+	}
+
+	// Outbounds may modify the headers of the request, so the shadow must
+	// not share them with the primary call.
+	sreq := *req
+	sreq.Headers = copyHeaders(req.Headers)
+	sreq.Body = bytes.NewReader(body)
+
+	go func() {
+		defer o.calls.Done()
+		defer func() { <-o.inFlight }()
+		defer cancel()
+
+		s.metrics.calls.Inc(1)
+		result := readResult(s.out.Call(sctx, &sreq))
+		if result.Err != nil {
+			s.metrics.failures.Inc(1)
+		}
+
+		if primary == nil {
+			return
+		}
+		select {
+		case <-primary.done:
+		case <-sctx.Done():
+			// The primary response was not read in time.
+			return
+		}
+		if !primary.complete {
+			return
+		}
+		if o.compare(primary.result, result) {
+			s.metrics.matches.Inc(1)
+		} else {
+			s.metrics.mismatches.Inc(1)
+		}
+	}()
+}
+
+// Introspect returns the status of the primary outbound, if it supports
+// introspection.
+func (o *UnaryOutbound) Introspect() introspection.OutboundStatus {
+	if i, ok := o.primary.(introspection.IntrospectableOutbound); ok {
+		return i.Introspect()
+	}
+	return introspection.OutboundStatusNotSupported
+}
+
+// copyHeaders returns a copy of the headers which does not share storage
+// with them.
+func copyHeaders(h transport.Headers) transport.Headers {
+	headers := transport.NewHeadersWithCapacity(h.Len())
+	for k, v := range h.Items() {
+		headers = headers.With(k, v)
+	}
+	return headers
+}
+
+func readBody(req *transport.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	if _, err := iopool.Copy(&buf, req.Body); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
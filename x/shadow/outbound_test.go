@@ -0,0 +1,305 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package shadow
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/encoding/raw"
+	"go.uber.org/yarpc/yarpcerrors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally"
+)
+
+// fakeOutbound is a unary outbound which handles calls with a function.
+type fakeOutbound struct {
+	call func(context.Context, *transport.Request) (*transport.Response, error)
+}
+
+func (o *fakeOutbound) Transports() []transport.Transport { return nil }
+func (o *fakeOutbound) Start() error                      { return nil }
+func (o *fakeOutbound) Stop() error                       { return nil }
+func (o *fakeOutbound) IsRunning() bool                   { return true }
+
+func (o *fakeOutbound) Call(ctx context.Context, req *transport.Request) (*transport.Response, error) {
+	return o.call(ctx, req)
+}
+
+// respond builds a fakeOutbound which responds with the given body, or fails
+// with the given error.
+func respond(body string, err error) *fakeOutbound {
+	return &fakeOutbound{call: func(context.Context, *transport.Request) (*transport.Response, error) {
+		if err != nil {
+			return nil, err
+		}
+		return &transport.Response{Body: ioutil.NopCloser(bytes.NewReader([]byte(body)))}, nil
+	}}
+}
+
+func newRequest() *transport.Request {
+	return &transport.Request{
+		Caller:    "caller",
+		Service:   "service",
+		Encoding:  raw.Encoding,
+		Procedure: "hello",
+		Headers:   transport.NewHeaders().With("foo", "bar"),
+		Body:      bytes.NewReader([]byte("hello")),
+	}
+}
+
+// call makes a call through the outbound, reading the response body
+// completely unless told otherwise.
+func call(t *testing.T, o *UnaryOutbound, readBody bool) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	res, err := o.Call(ctx, newRequest())
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if !readBody {
+		return "", nil
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	return string(body), nil
+}
+
+// counters returns the non-zero counters reported to the scope by name.
+func counters(scope tally.TestScope) map[string]int64 {
+	got := make(map[string]int64)
+	for _, c := range scope.Snapshot().Counters() {
+		if c.Value() != 0 {
+			got[c.Name()] += c.Value()
+		}
+	}
+	return got
+}
+
+func TestShadowReceivesCopies(t *testing.T) {
+	received := make(chan *transport.Request, 1)
+	shadowOut := &fakeOutbound{call: func(ctx context.Context, req *transport.Request) (*transport.Response, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			return nil, errors.New("shadow calls must have a deadline")
+		}
+		received <- req
+		return &transport.Response{Body: ioutil.NopCloser(bytes.NewReader([]byte("ignored")))}, nil
+	}}
+
+	scope := tally.NewTestScope("" /* prefix */, nil /* tags */)
+	o := NewUnaryOutbound(respond("world", nil), WithShadow("shadow", shadowOut), Metrics(scope))
+	require.NoError(t, o.Start())
+
+	body, err := call(t, o, true)
+	require.NoError(t, err)
+	assert.Equal(t, "world", body, "primary response must be returned")
+
+	require.NoError(t, o.Stop())
+	req := <-received
+	assert.Equal(t, "hello", string(mustReadAll(t, req)), "shadow must receive the request body")
+	assert.Equal(t, transport.NewHeaders().With("foo", "bar"), req.Headers, "shadow must receive the request headers")
+	assert.Equal(t, map[string]int64{"shadow_calls": 1}, counters(scope))
+}
+
+func TestShadowHeadersNotShared(t *testing.T) {
+	primaryCalled := make(chan struct{})
+	primaryOut := &fakeOutbound{call: func(ctx context.Context, req *transport.Request) (*transport.Response, error) {
+		req.Headers.With("primary", "true")
+		close(primaryCalled)
+		return &transport.Response{Body: ioutil.NopCloser(bytes.NewReader([]byte("world")))}, nil
+	}}
+
+	received := make(chan transport.Headers, 1)
+	shadowOut := &fakeOutbound{call: func(ctx context.Context, req *transport.Request) (*transport.Response, error) {
+		<-primaryCalled
+		received <- req.Headers.With("shadow", "true")
+		return nil, errors.New("great sadness")
+	}}
+
+	o := NewUnaryOutbound(primaryOut, WithShadow("shadow", shadowOut))
+	require.NoError(t, o.Start())
+
+	_, err := call(t, o, true)
+	require.NoError(t, err)
+	require.NoError(t, o.Stop())
+
+	assert.Equal(t, transport.NewHeaders().With("foo", "bar").With("shadow", "true"), <-received,
+		"headers set by the primary call must not be seen by the shadow call")
+}
+
+func TestShadowDoesNotDelayPrimary(t *testing.T) {
+	release := make(chan struct{})
+	shadowOut := &fakeOutbound{call: func(context.Context, *transport.Request) (*transport.Response, error) {
+		<-release
+		return nil, errors.New("great sadness")
+	}}
+
+	scope := tally.NewTestScope("" /* prefix */, nil /* tags */)
+	o := NewUnaryOutbound(respond("world", nil),
+		WithShadow("shadow", shadowOut),
+		MaxInFlight(1),
+		Metrics(scope),
+	)
+	require.NoError(t, o.Start())
+
+	// The second copy is dropped since the first is still in flight.
+	for i := 0; i < 2; i++ {
+		body, err := call(t, o, true)
+		require.NoError(t, err)
+		assert.Equal(t, "world", body, "primary response must be returned")
+	}
+
+	close(release)
+	require.NoError(t, o.Stop())
+	assert.Equal(t, map[string]int64{
+		"shadow_calls":    1,
+		"shadow_failures": 1,
+		"shadow_dropped":  1,
+	}, counters(scope))
+}
+
+func TestSampleRate(t *testing.T) {
+	shadowOut := &fakeOutbound{call: func(context.Context, *transport.Request) (*transport.Response, error) {
+		return nil, errors.New("requests must not be sampled")
+	}}
+
+	scope := tally.NewTestScope("" /* prefix */, nil /* tags */)
+	o := NewUnaryOutbound(respond("world", nil), WithShadow("shadow", shadowOut), SampleRate(0), Metrics(scope))
+	require.NoError(t, o.Start())
+
+	for i := 0; i < 10; i++ {
+		_, err := call(t, o, true)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, o.Stop())
+	assert.Empty(t, counters(scope), "no copies must be sent")
+}
+
+func TestCompare(t *testing.T) {
+	notFound := yarpcerrors.Newf(yarpcerrors.CodeNotFound, "not found")
+
+	tests := []struct {
+		desc     string
+		primary  *fakeOutbound
+		shadow   *fakeOutbound
+		readBody bool
+
+		wantCounters map[string]int64
+	}{
+		{
+			desc:         "same body",
+			primary:      respond("world", nil),
+			shadow:       respond("world", nil),
+			readBody:     true,
+			wantCounters: map[string]int64{"shadow_calls": 1, "shadow_matches": 1},
+		},
+		{
+			desc:         "different body",
+			primary:      respond("world", nil),
+			shadow:       respond("mars", nil),
+			readBody:     true,
+			wantCounters: map[string]int64{"shadow_calls": 1, "shadow_mismatches": 1},
+		},
+		{
+			desc:         "same error code",
+			primary:      respond("", notFound),
+			shadow:       respond("", yarpcerrors.Newf(yarpcerrors.CodeNotFound, "no such key")),
+			wantCounters: map[string]int64{"shadow_calls": 1, "shadow_failures": 1, "shadow_matches": 1},
+		},
+		{
+			desc:         "shadow fails",
+			primary:      respond("world", nil),
+			shadow:       respond("", notFound),
+			readBody:     true,
+			wantCounters: map[string]int64{"shadow_calls": 1, "shadow_failures": 1, "shadow_mismatches": 1},
+		},
+		{
+			desc:         "primary body not read",
+			primary:      respond("world", nil),
+			shadow:       respond("mars", nil),
+			wantCounters: map[string]int64{"shadow_calls": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			scope := tally.NewTestScope("" /* prefix */, nil /* tags */)
+			o := NewUnaryOutbound(tt.primary, WithShadow("shadow", tt.shadow), Compare(Equal), Metrics(scope))
+			require.NoError(t, o.Start())
+
+			call(t, o, tt.readBody)
+
+			require.NoError(t, o.Stop())
+			assert.Equal(t, tt.wantCounters, counters(scope))
+		})
+	}
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		desc    string
+		primary Result
+		shadow  Result
+		want    bool
+	}{
+		{
+			desc:    "same body",
+			primary: Result{Body: []byte("hello")},
+			shadow:  Result{Body: []byte("hello")},
+			want:    true,
+		},
+		{
+			desc:    "different application error status",
+			primary: Result{Body: []byte("hello"), ApplicationError: true},
+			shadow:  Result{Body: []byte("hello")},
+		},
+		{
+			desc:    "different error codes",
+			primary: Result{Err: yarpcerrors.Newf(yarpcerrors.CodeNotFound, "")},
+			shadow:  Result{Err: yarpcerrors.Newf(yarpcerrors.CodeInternal, "")},
+		},
+		{
+			desc:    "primary fails",
+			primary: Result{Err: errors.New("great sadness")},
+			shadow:  Result{},
+		},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Equal(tt.primary, tt.shadow), tt.desc)
+	}
+}
+
+func mustReadAll(t *testing.T, req *transport.Request) []byte {
+	body, err := ioutil.ReadAll(req.Body)
+	require.NoError(t, err)
+	return body
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package split
+
+import "go.uber.org/yarpc/x/config"
+
+// Spec returns a configuration specification for split outbounds, making it
+// possible to split the requests of outbounds configured with the split key
+// between their targets.
+//
+//  cfg := config.New()
+//  cfg.MustRegisterSplit(split.Spec())
+//
+// This sends five percent of the requests to a canary:
+//
+//  outbounds:
+//    keyvalue:
+//      split:
+//        stickyBy: shardKey
+//        targets:
+//          - name: stable
+//            weight: 95
+//            http:
+//              url: http://127.0.0.1:8080
+//          - name: canary
+//            weight: 5
+//            http:
+//              url: http://127.0.0.1:8081
+func Spec() config.SplitSpec {
+	return config.SplitSpec{
+		BuildSplit: func(targets []config.SplitTarget, opts config.SplitOptions) (config.SplitOutbound, error) {
+			ts := make([]Target, len(targets))
+			for i, t := range targets {
+				ts[i] = Target{Name: t.Name, Weight: t.Weight, Unary: t.Unary, Oneway: t.Oneway}
+			}
+
+			var oo []OutboundOption
+			if opts.StickyByShardKey {
+				oo = append(oo, StickyByShardKey())
+			}
+			if opts.StickyByHeader != "" {
+				oo = append(oo, StickyByHeader(opts.StickyByHeader))
+			}
+
+			o, err := NewOutbound(ts, oo...)
+			if err != nil {
+				return nil, err
+			}
+			return o, nil
+		},
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package split
+
+import (
+	"fmt"
+	"testing"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/x/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpec(t *testing.T) {
+	targets, outs := newTargets(50, 50)
+	cfgTargets := make([]config.SplitTarget, len(targets))
+	for i, target := range targets {
+		cfgTargets[i] = config.SplitTarget{Name: target.Name, Weight: target.Weight, Unary: target.Unary}
+	}
+
+	built, err := Spec().BuildSplit(cfgTargets, config.SplitOptions{StickyByHeader: "user"})
+	require.NoError(t, err)
+	o, ok := built.(*Outbound)
+	require.True(t, ok, "Spec must build an *Outbound")
+	require.NoError(t, o.Start())
+	defer o.Stop()
+
+	for i := 0; i < 100; i++ {
+		req := &transport.Request{Headers: transport.NewHeaders().With("user", fmt.Sprint(i))}
+		want := call(t, o, req)
+		assert.Equal(t, want, call(t, o, req), "requests for %d must go to the same target", i)
+	}
+	assert.NotZero(t, outs[0].calls, "keys must be spread over all targets")
+	assert.NotZero(t, outs[1].calls, "keys must be spread over all targets")
+
+	require.NoError(t, built.SetWeights(map[string]int{"target-0": 0, "target-1": 1}))
+	assert.Equal(t, map[string]int{"target-0": 0, "target-1": 1}, o.Weights())
+}
+
+func TestSpecError(t *testing.T) {
+	built, err := Spec().BuildSplit(nil, config.SplitOptions{StickyByShardKey: true})
+	assert.Nil(t, built, "a failed build must not return an outbound")
+	assert.EqualError(t, err, "at least one target is required")
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package split provides an outbound which splits requests between other
+// outbounds by weight, for example to send a small share of traffic to a
+// canary deployment of a service or to try out a new transport.
+//
+// 	outbound, err := split.NewOutbound([]split.Target{
+// 		{Name: "stable", Weight: 95, Unary: httpTransport.NewSingleOutbound("http://127.0.0.1:8080")},
+// 		{Name: "canary", Weight: 5, Unary: httpTransport.NewSingleOutbound("http://127.0.0.1:8081")},
+// 	}, split.StickyByShardKey())
+//
+// Every request is sent to exactly one target. Targets are picked at random
+// in proportion to their weights, unless the outbound is sticky: with
+// StickyByShardKey or StickyByHeader, requests with the same shard key or
+// header value go to the same target for as long as the weights are
+// unchanged. Requests without a key are picked at random.
+//
+// Weights may be changed while the outbound is running with SetWeights, for
+// example to shift traffic to a canary step by step. As long as the total
+// weight stays the same, moving weight from one target to the next only
+// moves sticky requests between those two targets, so moving weight to the
+// last target never moves requests away from it.
+package split
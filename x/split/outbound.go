@@ -0,0 +1,341 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package split
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/introspection"
+	intsync "go.uber.org/yarpc/internal/sync"
+	"go.uber.org/yarpc/yarpcerrors"
+
+	"go.uber.org/multierr"
+)
+
+var (
+	_ transport.UnaryOutbound              = (*Outbound)(nil)
+	_ transport.OnewayOutbound             = (*Outbound)(nil)
+	_ introspection.IntrospectableOutbound = (*Outbound)(nil)
+)
+
+// Target is one of the outbounds between which an Outbound splits requests.
+type Target struct {
+	// Name of the target. Names identify targets in SetWeights and in
+	// introspection and must be unique within an Outbound.
+	Name string
+
+	// Weight of the target relative to the weights of the other targets.
+	// Targets with a weight of zero receive no requests.
+	Weight int
+
+	// Outbounds to which requests picked for this target are sent. An
+	// Outbound makes unary calls only if every target has a unary outbound,
+	// and oneway calls only if every target has a oneway outbound.
+	Unary  transport.UnaryOutbound
+	Oneway transport.OnewayOutbound
+}
+
+// OutboundOption customizes the behavior of a splitting Outbound.
+type OutboundOption func(*Outbound)
+
+// StickyByShardKey sends requests with the same shard key to the same
+// target.
+func StickyByShardKey() OutboundOption {
+	return func(o *Outbound) {
+		o.sticky = "shard key"
+		o.key = func(req *transport.Request) string { return req.ShardKey }
+	}
+}
+
+// StickyByHeader sends requests with the same value for the given header to
+// the same target.
+func StickyByHeader(name string) OutboundOption {
+	return func(o *Outbound) {
+		o.sticky = fmt.Sprintf("header %q", name)
+		o.key = func(req *transport.Request) string {
+			v, _ := req.Headers.Get(name)
+			return v
+		}
+	}
+}
+
+// Outbound is a unary and oneway outbound which sends every request to one
+// of its targets, picked by weight.
+type Outbound struct {
+	targets []Target
+	names   []string
+	unary   bool
+	oneway  bool
+
+	// Key of requests which are sent to the same target, if the outbound is
+	// sticky.
+	sticky string
+	key    func(*transport.Request) string
+
+	// Weight updates are serialized by lock. Calls load the current weights
+	// without locking.
+	lock    sync.Mutex
+	weights atomic.Value // *weights
+
+	once intsync.LifecycleOnce
+}
+
+// NewOutbound builds an outbound which splits requests between the given
+// targets.
+//
+// The outbounds of the targets are started and stopped with the returned
+// outbound.
+func NewOutbound(targets []Target, opts ...OutboundOption) (*Outbound, error) {
+	if len(targets) == 0 {
+		return nil, errors.New("at least one target is required")
+	}
+
+	o := &Outbound{
+		targets: targets,
+		names:   make([]string, len(targets)),
+		unary:   true,
+		oneway:  true,
+		once:    intsync.Once(),
+	}
+
+	seen := make(map[string]struct{}, len(targets))
+	values := make([]int, len(targets))
+	for i, t := range targets {
+		if t.Name == "" {
+			return nil, fmt.Errorf("target %d must have a name", i)
+		}
+		if _, ok := seen[t.Name]; ok {
+			return nil, fmt.Errorf("target names must be unique: %q", t.Name)
+		}
+		seen[t.Name] = struct{}{}
+		if t.Unary == nil && t.Oneway == nil {
+			return nil, fmt.Errorf("target %q must have a unary or oneway outbound", t.Name)
+		}
+
+		o.names[i] = t.Name
+		o.unary = o.unary && t.Unary != nil
+		o.oneway = o.oneway && t.Oneway != nil
+		values[i] = t.Weight
+	}
+	if !o.unary && !o.oneway {
+		return nil, errors.New("targets must all have unary outbounds or all have oneway outbounds")
+	}
+
+	w, err := newWeights(o.names, values)
+	if err != nil {
+		return nil, err
+	}
+	o.weights.Store(w)
+
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o, nil
+}
+
+// Weights returns the current weights of the targets, keyed by name.
+func (o *Outbound) Weights() map[string]int {
+	w := o.loadWeights()
+	weights := make(map[string]int, len(o.names))
+	for i, name := range o.names {
+		weights[name] = w.values[i]
+	}
+	return weights
+}
+
+// SetWeights changes the weights of the targets with the given names.
+// Targets not listed keep their weights. Requests already in flight are not
+// affected.
+//
+// The weights are left unchanged if any of the names is unknown or if the
+// new weights are invalid.
+func (o *Outbound) SetWeights(weights map[string]int) error {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	values := append([]int(nil), o.loadWeights().values...)
+	for name, weight := range weights {
+		i := o.indexOf(name)
+		if i < 0 {
+			return fmt.Errorf("unknown target %q", name)
+		}
+		values[i] = weight
+	}
+
+	w, err := newWeights(o.names, values)
+	if err != nil {
+		return err
+	}
+	o.weights.Store(w)
+	return nil
+}
+
+func (o *Outbound) indexOf(name string) int {
+	for i, n := range o.names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (o *Outbound) loadWeights() *weights {
+	return o.weights.Load().(*weights)
+}
+
+// Transports returns the transports used by the outbounds of all targets.
+func (o *Outbound) Transports() []transport.Transport {
+	var transports []transport.Transport
+	for _, out := range o.outbounds() {
+		transports = append(transports, out.Transports()...)
+	}
+	return transports
+}
+
+// outbounds returns the outbounds of all targets. An outbound used for both
+// unary and oneway calls is listed once.
+func (o *Outbound) outbounds() []transport.Outbound {
+	var outbounds []transport.Outbound
+	for _, t := range o.targets {
+		if t.Unary != nil {
+			outbounds = append(outbounds, t.Unary)
+		}
+		if t.Oneway != nil && transport.Outbound(t.Oneway) != transport.Outbound(t.Unary) {
+			outbounds = append(outbounds, t.Oneway)
+		}
+	}
+	return outbounds
+}
+
+// Start starts the outbounds of all targets.
+func (o *Outbound) Start() error {
+	return o.once.Start(o.start)
+}
+
+func (o *Outbound) start() error {
+	var err error
+	for _, out := range o.outbounds() {
+		err = multierr.Append(err, out.Start())
+	}
+	return err
+}
+
+// Stop stops the outbounds of all targets.
+func (o *Outbound) Stop() error {
+	return o.once.Stop(o.stop)
+}
+
+func (o *Outbound) stop() error {
+	var err error
+	for _, out := range o.outbounds() {
+		err = multierr.Append(err, out.Stop())
+	}
+	return err
+}
+
+// IsRunning returns whether the outbound is running.
+func (o *Outbound) IsRunning() bool {
+	return o.once.IsRunning()
+}
+
+// Call sends the request to the unary outbound of the target picked for it.
+func (o *Outbound) Call(ctx context.Context, req *transport.Request) (*transport.Response, error) {
+	if err := o.once.WhenRunning(ctx); err != nil {
+		return nil, err
+	}
+	if !o.unary {
+		return nil, yarpcerrors.Newf(yarpcerrors.CodeUnimplemented,
+			"not every target of the outbound supports unary calls")
+	}
+	return o.targets[o.pick(req)].Unary.Call(ctx, req)
+}
+
+// CallOneway sends the request to the oneway outbound of the target picked
+// for it.
+func (o *Outbound) CallOneway(ctx context.Context, req *transport.Request) (transport.Ack, error) {
+	if err := o.once.WhenRunning(ctx); err != nil {
+		return nil, err
+	}
+	if !o.oneway {
+		return nil, yarpcerrors.Newf(yarpcerrors.CodeUnimplemented,
+			"not every target of the outbound supports oneway calls")
+	}
+	return o.targets[o.pick(req)].Oneway.CallOneway(ctx, req)
+}
+
+// pick returns the index of the target to which the request is sent.
+func (o *Outbound) pick(req *transport.Request) int {
+	w := o.loadWeights()
+	if o.key != nil {
+		if key := o.key(req); key != "" {
+			return w.pick(w.hash(key))
+		}
+	}
+	return w.pick(rand.Intn(w.total))
+}
+
+// Introspect returns the status of the outbound. The targets are listed as
+// the peers of its chooser, with their weights and the transport and state
+// of their outbounds.
+func (o *Outbound) Introspect() introspection.OutboundStatus {
+	state := "Stopped"
+	if o.IsRunning() {
+		state = "Running"
+	}
+
+	chooser := introspection.ChooserStatus{
+		Name:  "split",
+		State: "random",
+	}
+	if o.key != nil {
+		chooser.State = "sticky by " + o.sticky
+	}
+
+	w := o.loadWeights()
+	for i, t := range o.targets {
+		var out transport.Outbound = t.Unary
+		if t.Unary == nil {
+			out = t.Oneway
+		}
+
+		peer := introspection.PeerStatus{
+			Identifier: t.Name,
+			State:      fmt.Sprintf("weight %d of %d", w.values[i], w.total),
+		}
+		if in, ok := out.(introspection.IntrospectableOutbound); ok {
+			status := in.Introspect()
+			peer.State += fmt.Sprintf(", %s %s (%s)", status.Transport, status.Endpoint, status.State)
+		}
+		chooser.Peers = append(chooser.Peers, peer)
+	}
+
+	return introspection.OutboundStatus{
+		Transport: "split",
+		State:     state,
+		Chooser:   chooser,
+	}
+}
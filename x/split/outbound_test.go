@@ -0,0 +1,371 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package split
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/introspection"
+	"go.uber.org/yarpc/yarpcerrors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOutbound is a unary and oneway outbound which counts the calls made
+// through it.
+type fakeOutbound struct {
+	name    string
+	calls   int
+	starts  int
+	stops   int
+	running bool
+}
+
+func (o *fakeOutbound) Transports() []transport.Transport { return nil }
+func (o *fakeOutbound) IsRunning() bool                   { return o.running }
+
+func (o *fakeOutbound) Start() error {
+	o.starts++
+	o.running = true
+	return nil
+}
+
+func (o *fakeOutbound) Stop() error {
+	o.stops++
+	o.running = false
+	return nil
+}
+
+func (o *fakeOutbound) Call(context.Context, *transport.Request) (*transport.Response, error) {
+	o.calls++
+	return &transport.Response{Headers: transport.NewHeaders().With("target", o.name)}, nil
+}
+
+func (o *fakeOutbound) CallOneway(context.Context, *transport.Request) (transport.Ack, error) {
+	o.calls++
+	return nil, nil
+}
+
+func (o *fakeOutbound) Introspect() introspection.OutboundStatus {
+	return introspection.OutboundStatus{Transport: "fake", Endpoint: o.name, State: "Running"}
+}
+
+// newTargets builds targets with the given names and weights which use the
+// same fakeOutbound for unary and oneway calls.
+func newTargets(weights ...int) ([]Target, []*fakeOutbound) {
+	var (
+		targets []Target
+		outs    []*fakeOutbound
+	)
+	for i, w := range weights {
+		out := &fakeOutbound{name: fmt.Sprintf("target-%d", i)}
+		outs = append(outs, out)
+		targets = append(targets, Target{Name: out.name, Weight: w, Unary: out, Oneway: out})
+	}
+	return targets, outs
+}
+
+func newStartedOutbound(t *testing.T, targets []Target, opts ...OutboundOption) *Outbound {
+	o, err := NewOutbound(targets, opts...)
+	require.NoError(t, err)
+	require.NoError(t, o.Start())
+	return o
+}
+
+// call makes a unary call through the outbound and returns the name of the
+// target that received it.
+func call(t *testing.T, o *Outbound, req *transport.Request) string {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	res, err := o.Call(ctx, req)
+	require.NoError(t, err)
+	target, _ := res.Headers.Get("target")
+	return target
+}
+
+func TestNewOutboundErrors(t *testing.T) {
+	out := &fakeOutbound{}
+
+	tests := []struct {
+		desc    string
+		targets []Target
+		wantErr string
+	}{
+		{
+			desc:    "no targets",
+			wantErr: "at least one target is required",
+		},
+		{
+			desc:    "no name",
+			targets: []Target{{Weight: 1, Unary: out}},
+			wantErr: "target 0 must have a name",
+		},
+		{
+			desc:    "duplicate names",
+			targets: []Target{{Name: "a", Weight: 1, Unary: out}, {Name: "a", Weight: 1, Unary: out}},
+			wantErr: `target names must be unique: "a"`,
+		},
+		{
+			desc:    "no outbounds",
+			targets: []Target{{Name: "a", Weight: 1}},
+			wantErr: `target "a" must have a unary or oneway outbound`,
+		},
+		{
+			desc: "no common RPC type",
+			targets: []Target{
+				{Name: "a", Weight: 1, Unary: out},
+				{Name: "b", Weight: 1, Oneway: out},
+			},
+			wantErr: "targets must all have unary outbounds or all have oneway outbounds",
+		},
+		{
+			desc:    "negative weight",
+			targets: []Target{{Name: "a", Weight: -1, Unary: out}, {Name: "b", Weight: 2, Unary: out}},
+			wantErr: `weight of target "a" must not be negative: -1`,
+		},
+		{
+			desc:    "zero total weight",
+			targets: []Target{{Name: "a", Unary: out}, {Name: "b", Unary: out}},
+			wantErr: "at least one target must have a positive weight",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			_, err := NewOutbound(tt.targets)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestSplitsByWeight(t *testing.T) {
+	targets, outs := newTargets(80, 20, 0)
+	o := newStartedOutbound(t, targets)
+	defer o.Stop()
+
+	const n = 2000
+	for i := 0; i < n; i++ {
+		call(t, o, &transport.Request{})
+	}
+
+	assert.InDelta(t, 0.8*n, outs[0].calls, 0.1*n, "calls to the first target")
+	assert.InDelta(t, 0.2*n, outs[1].calls, 0.1*n, "calls to the second target")
+	assert.Equal(t, 0, outs[2].calls, "targets without weight must not be called")
+}
+
+func TestCallOnewaySplitsByWeight(t *testing.T) {
+	targets, outs := newTargets(0, 1)
+	o := newStartedOutbound(t, targets)
+	defer o.Stop()
+
+	for i := 0; i < 10; i++ {
+		_, err := o.CallOneway(context.Background(), &transport.Request{})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 0, outs[0].calls)
+	assert.Equal(t, 10, outs[1].calls)
+}
+
+func TestSticky(t *testing.T) {
+	tests := []struct {
+		desc       string
+		opt        OutboundOption
+		newRequest func(key string) *transport.Request
+	}{
+		{
+			desc: "shard key",
+			opt:  StickyByShardKey(),
+			newRequest: func(key string) *transport.Request {
+				return &transport.Request{ShardKey: key}
+			},
+		},
+		{
+			desc: "header",
+			opt:  StickyByHeader("user"),
+			newRequest: func(key string) *transport.Request {
+				return &transport.Request{Headers: transport.NewHeaders().With("user", key)}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			targets, outs := newTargets(50, 50)
+			o := newStartedOutbound(t, targets, tt.opt)
+			defer o.Stop()
+
+			for i := 0; i < 100; i++ {
+				key := fmt.Sprint(i)
+				want := call(t, o, tt.newRequest(key))
+				for j := 0; j < 5; j++ {
+					assert.Equal(t, want, call(t, o, tt.newRequest(key)),
+						"requests for %q must go to the same target", key)
+				}
+			}
+			assert.NotZero(t, outs[0].calls, "keys must be spread over all targets")
+			assert.NotZero(t, outs[1].calls, "keys must be spread over all targets")
+		})
+	}
+}
+
+func TestStickyLargeWeights(t *testing.T) {
+	if strconv.IntSize < 64 {
+		t.Skip("weights above 32 bits need a 64-bit int")
+	}
+
+	// Weights of 2^32 each add up to a multiple of 2^32, which must neither
+	// be truncated nor divide by zero.
+	weight := 1 << 16
+	weight *= weight
+	targets, outs := newTargets(weight, weight)
+	o := newStartedOutbound(t, targets, StickyByShardKey())
+	defer o.Stop()
+
+	for i := 0; i < 100; i++ {
+		call(t, o, &transport.Request{ShardKey: fmt.Sprint(i)})
+	}
+	assert.NotZero(t, outs[0].calls, "keys must be spread over all targets")
+	assert.NotZero(t, outs[1].calls, "keys must be spread over all targets")
+}
+
+func TestStickyWithoutKey(t *testing.T) {
+	targets, outs := newTargets(50, 50)
+	o := newStartedOutbound(t, targets, StickyByShardKey())
+	defer o.Stop()
+
+	for i := 0; i < 200; i++ {
+		call(t, o, &transport.Request{})
+	}
+	assert.NotZero(t, outs[0].calls, "requests without a key must be picked at random")
+	assert.NotZero(t, outs[1].calls, "requests without a key must be picked at random")
+}
+
+func TestSetWeights(t *testing.T) {
+	targets, _ := newTargets(90, 10)
+	o := newStartedOutbound(t, targets, StickyByShardKey())
+	defer o.Stop()
+
+	before := make(map[string]string)
+	for i := 0; i < 200; i++ {
+		key := fmt.Sprint(i)
+		before[key] = call(t, o, &transport.Request{ShardKey: key})
+	}
+
+	require.NoError(t, o.SetWeights(map[string]int{"target-0": 50, "target-1": 50}))
+	assert.Equal(t, map[string]int{"target-0": 50, "target-1": 50}, o.Weights())
+
+	var moved int
+	for key, target := range before {
+		got := call(t, o, &transport.Request{ShardKey: key})
+		if target == "target-1" {
+			assert.Equal(t, "target-1", got, "keys must not move away from the target that gained weight")
+		} else if got != target {
+			moved++
+		}
+	}
+	assert.NotZero(t, moved, "some keys must move to the target that gained weight")
+
+	require.NoError(t, o.SetWeights(map[string]int{"target-0": 0}))
+	assert.Equal(t, map[string]int{"target-0": 0, "target-1": 50}, o.Weights())
+	for key := range before {
+		assert.Equal(t, "target-1", call(t, o, &transport.Request{ShardKey: key}))
+	}
+}
+
+func TestSetWeightsErrors(t *testing.T) {
+	targets, _ := newTargets(1, 1)
+	o, err := NewOutbound(targets)
+	require.NoError(t, err)
+
+	err = o.SetWeights(map[string]int{"target-0": 2, "unknown": 1})
+	assert.EqualError(t, err, `unknown target "unknown"`)
+
+	err = o.SetWeights(map[string]int{"target-0": 0, "target-1": 0})
+	assert.EqualError(t, err, "at least one target must have a positive weight")
+
+	assert.Equal(t, map[string]int{"target-0": 1, "target-1": 1}, o.Weights(),
+		"weights must not change if the update is invalid")
+}
+
+func TestLifecycle(t *testing.T) {
+	targets, outs := newTargets(1, 1)
+	separate := &fakeOutbound{name: "oneway"}
+	targets[1].Oneway = separate
+
+	o, err := NewOutbound(targets)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = o.Call(ctx, &transport.Request{})
+	assert.Error(t, err, "calls must fail until the outbound is started")
+
+	require.NoError(t, o.Start())
+	assert.True(t, o.IsRunning())
+	assert.Equal(t, 1, outs[0].starts, "outbounds used for both RPC types must be started once")
+	assert.Equal(t, 1, outs[1].starts)
+	assert.Equal(t, 1, separate.starts)
+
+	require.NoError(t, o.Stop())
+	assert.False(t, o.IsRunning())
+	assert.Equal(t, 1, outs[0].stops)
+	assert.Equal(t, 1, outs[1].stops)
+	assert.Equal(t, 1, separate.stops)
+}
+
+func TestUnsupportedRPCType(t *testing.T) {
+	targets, _ := newTargets(1, 1)
+	targets[1].Oneway = nil
+
+	o := newStartedOutbound(t, targets)
+	defer o.Stop()
+
+	_, err := o.CallOneway(context.Background(), &transport.Request{})
+	require.Error(t, err)
+	assert.Equal(t, yarpcerrors.CodeUnimplemented, yarpcerrors.FromError(err).Code())
+}
+
+func TestIntrospect(t *testing.T) {
+	targets, _ := newTargets(3, 1)
+	o, err := NewOutbound(targets, StickyByHeader("user"))
+	require.NoError(t, err)
+
+	assert.Equal(t, introspection.OutboundStatus{
+		Transport: "split",
+		State:     "Stopped",
+		Chooser: introspection.ChooserStatus{
+			Name:  "split",
+			State: `sticky by header "user"`,
+			Peers: []introspection.PeerStatus{
+				{Identifier: "target-0", State: "weight 3 of 4, fake target-0 (Running)"},
+				{Identifier: "target-1", State: "weight 1 of 4, fake target-1 (Running)"},
+			},
+		},
+	}, o.Introspect())
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package split
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+)
+
+// weights holds the weights of the targets of an Outbound. It is not
+// modified after it has been built.
+type weights struct {
+	// Weights of the targets, by index.
+	values []int
+
+	// Upper bounds of the ranges of [0, total) assigned to each target,
+	// by index.
+	bounds []int
+	total  int
+}
+
+func newWeights(names []string, values []int) (*weights, error) {
+	w := weights{
+		values: values,
+		bounds: make([]int, len(values)),
+	}
+	for i, v := range values {
+		if v < 0 {
+			return nil, fmt.Errorf("weight of target %q must not be negative: %d", names[i], v)
+		}
+		w.total += v
+		w.bounds[i] = w.total
+	}
+	if w.total <= 0 {
+		return nil, errors.New("at least one target must have a positive weight")
+	}
+	return &w, nil
+}
+
+// pick returns the index of the target whose range contains n, which must
+// be in [0, total).
+func (w *weights) pick(n int) int {
+	return sort.Search(len(w.bounds), func(i int) bool { return n < w.bounds[i] })
+}
+
+// hash maps the given key to [0, total).
+func (w *weights) hash(key string) int {
+	// total may not fit in 32 bits, so hash to 64 bits rather than
+	// truncating it.
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return int(h.Sum64() % uint64(w.total))
+}
@@ -0,0 +1,209 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package yarpchealth provides a health service which may be registered on
+// any Dispatcher.
+//
+// The health service reports whether each service served by the Dispatcher
+// is serving. Services may be marked as not serving explicitly with
+// SetServing, or through Checkers which are consulted every time the health
+// of a service is queried.
+//
+// 	health := yarpchealth.Register(dispatcher)
+// 	health.AddChecker("myservice", yarpchealth.CheckerFunc(pingDatabase))
+//
+// The health service is exposed over all inbounds of the Dispatcher. The
+// gRPC inbound serves it as the standard grpc.health.v1.Health service, the
+// HTTP inbound serves it over the path configured with http.HealthPath, and
+// all transports serve it as the JSON-encoded "yarpc::health" procedure.
+package yarpchealth
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/encoding/json"
+	"go.uber.org/yarpc/internal/health"
+)
+
+// Status is the health status of a service.
+type Status int
+
+const (
+	// ServiceUnknown indicates that the Dispatcher does not serve the
+	// requested service.
+	ServiceUnknown Status = iota
+
+	// Serving indicates that the service is healthy.
+	Serving
+
+	// NotServing indicates that the service is unhealthy.
+	NotServing
+)
+
+func (s Status) String() string {
+	switch s {
+	case Serving:
+		return health.Serving
+	case NotServing:
+		return health.NotServing
+	case ServiceUnknown:
+		return health.ServiceUnknown
+	default:
+		return fmt.Sprintf("Status(%d)", int(s))
+	}
+}
+
+// Checker checks the health of a service.
+type Checker interface {
+	// Check returns a non-nil error if the service is unhealthy.
+	Check(context.Context) error
+}
+
+// CheckerFunc is a function that implements Checker.
+type CheckerFunc func(context.Context) error
+
+// Check calls the underlying function.
+func (f CheckerFunc) Check(ctx context.Context) error { return f(ctx) }
+
+// Server is the health service registered on a Dispatcher.
+type Server struct {
+	disp *yarpc.Dispatcher
+
+	mu         sync.RWMutex
+	notServing map[string]bool
+	checkers   map[string][]Checker
+}
+
+// Register registers a health service on the given Dispatcher and returns
+// it. All services served by the Dispatcher are reported as serving until
+// they are marked otherwise.
+func Register(d *yarpc.Dispatcher) *Server {
+	s := &Server{
+		disp:       d,
+		notServing: make(map[string]bool),
+		checkers:   make(map[string][]Checker),
+	}
+	d.Register(s.Procedures())
+	return s
+}
+
+// SetServing marks the given service as serving or not serving. The health
+// of the Dispatcher as a whole is reported under the empty service name.
+//
+// Services which are not served by the Dispatcher become known to the
+// health service once their status is set.
+func (s *Server) SetServing(service string, serving bool) {
+	s.mu.Lock()
+	s.notServing[service] = !serving
+	s.mu.Unlock()
+}
+
+// AddChecker adds a Checker for the given service. The service is reported
+// as not serving if any of its Checkers fails.
+func (s *Server) AddChecker(service string, c Checker) {
+	s.mu.Lock()
+	s.checkers[service] = append(s.checkers[service], c)
+	s.mu.Unlock()
+}
+
+// Check returns the health status of the given service. The empty service
+// name refers to the Dispatcher as a whole, which is not serving if any of
+// the services it serves is not serving.
+func (s *Server) Check(ctx context.Context, service string) Status {
+	s.mu.RLock()
+	notServing, explicit := s.notServing[service]
+	checkers := s.checkers[service]
+	s.mu.RUnlock()
+
+	if !explicit && len(checkers) == 0 && !s.serves(service) {
+		return ServiceUnknown
+	}
+	if notServing {
+		return NotServing
+	}
+	for _, c := range checkers {
+		if err := c.Check(ctx); err != nil {
+			return NotServing
+		}
+	}
+
+	if service == "" {
+		for _, svc := range s.services() {
+			if s.Check(ctx, svc) == NotServing {
+				return NotServing
+			}
+		}
+	}
+	return Serving
+}
+
+// serves returns true if the Dispatcher serves the given service.
+func (s *Server) serves(service string) bool {
+	if service == "" || service == s.disp.Name() {
+		return true
+	}
+	for _, p := range s.disp.Router().Procedures() {
+		if p.Service == service {
+			return true
+		}
+	}
+	return false
+}
+
+// services returns the names of all non-empty services known to the health
+// service.
+func (s *Server) services() []string {
+	seen := map[string]struct{}{s.disp.Name(): {}}
+	for _, p := range s.disp.Router().Procedures() {
+		seen[p.Service] = struct{}{}
+	}
+
+	s.mu.RLock()
+	for svc := range s.notServing {
+		seen[svc] = struct{}{}
+	}
+	for svc := range s.checkers {
+		seen[svc] = struct{}{}
+	}
+	s.mu.RUnlock()
+
+	services := make([]string, 0, len(seen))
+	for svc := range seen {
+		if svc != "" {
+			services = append(services, svc)
+		}
+	}
+	return services
+}
+
+func (s *Server) check(ctx context.Context, req *health.Request) (*health.Response, error) {
+	return &health.Response{Status: s.Check(ctx, req.Service).String()}, nil
+}
+
+// Procedures returns the procedures to register on a dispatcher.
+func (s *Server) Procedures() []transport.Procedure {
+	p := json.Procedure(health.Procedure, s.check)[0]
+	p.Signature = `health({"service": "..."}) {"status": "SERVING|NOT_SERVING|SERVICE_UNKNOWN"}`
+	return []transport.Procedure{p}
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package yarpchealth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/encoding/json"
+	"go.uber.org/yarpc/internal/health"
+)
+
+func TestServerCheck(t *testing.T) {
+	disp := yarpc.NewDispatcher(yarpc.Config{Name: "myservice"})
+	disp.Register(json.Procedure("echo", func(ctx context.Context, body interface{}) (interface{}, error) {
+		return body, nil
+	}))
+	disp.Register([]transport.Procedure{{
+		Name:        "other",
+		Service:     "otherservice",
+		HandlerSpec: json.Procedure("other", func(context.Context, interface{}) (interface{}, error) { return nil, nil })[0].HandlerSpec,
+	}})
+	s := Register(disp)
+	ctx := context.Background()
+
+	assert.Equal(t, Serving, s.Check(ctx, ""))
+	assert.Equal(t, Serving, s.Check(ctx, "myservice"))
+	assert.Equal(t, Serving, s.Check(ctx, "otherservice"))
+	assert.Equal(t, ServiceUnknown, s.Check(ctx, "unknown"))
+
+	s.SetServing("otherservice", false)
+	assert.Equal(t, NotServing, s.Check(ctx, "otherservice"))
+	assert.Equal(t, Serving, s.Check(ctx, "myservice"))
+	assert.Equal(t, NotServing, s.Check(ctx, ""), "dispatcher must not be serving")
+
+	s.SetServing("otherservice", true)
+	assert.Equal(t, Serving, s.Check(ctx, ""))
+
+	var healthy bool
+	s.AddChecker("myservice", CheckerFunc(func(context.Context) error {
+		if healthy {
+			return nil
+		}
+		return errors.New("great sadness")
+	}))
+	assert.Equal(t, NotServing, s.Check(ctx, "myservice"))
+	assert.Equal(t, NotServing, s.Check(ctx, ""))
+	healthy = true
+	assert.Equal(t, Serving, s.Check(ctx, "myservice"))
+
+	s.AddChecker("external", CheckerFunc(func(context.Context) error { return nil }))
+	assert.Equal(t, Serving, s.Check(ctx, "external"))
+}
+
+func TestServerProcedure(t *testing.T) {
+	disp := yarpc.NewDispatcher(yarpc.Config{Name: "myservice"})
+	router := disp.Router()
+
+	_, ok, err := health.Check(context.Background(), router, "myservice")
+	require.NoError(t, err)
+	assert.False(t, ok, "health procedure must not be registered")
+
+	s := Register(disp)
+	assert.True(t, health.Registered(router))
+
+	tests := []struct {
+		service string
+		serving bool
+		want    string
+	}{
+		{service: "myservice", serving: true, want: "SERVING"},
+		{service: "myservice", serving: false, want: "NOT_SERVING"},
+		{service: "unknown", want: "SERVICE_UNKNOWN"},
+	}
+
+	for _, tt := range tests {
+		if tt.want != "SERVICE_UNKNOWN" {
+			s.SetServing(tt.service, tt.serving)
+		}
+		status, ok, err := health.Check(context.Background(), router, tt.service)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, tt.want, status, "status of %q", tt.service)
+	}
+}
+
+func TestStatusString(t *testing.T) {
+	assert.Equal(t, "SERVING", Serving.String())
+	assert.Equal(t, "NOT_SERVING", NotServing.String())
+	assert.Equal(t, "SERVICE_UNKNOWN", ServiceUnknown.String())
+	assert.Equal(t, "Status(42)", Status(42).String())
+}
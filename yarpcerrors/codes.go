@@ -0,0 +1,148 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package yarpcerrors
+
+import "fmt"
+
+// Code represents the category of an error.
+//
+// The numeric values of the codes match the status codes used by gRPC.
+type Code int
+
+const (
+	// CodeOK means no error; returned on success.
+	CodeOK Code = 0
+
+	// CodeCancelled means the operation was cancelled, typically by the
+	// caller.
+	CodeCancelled Code = 1
+
+	// CodeUnknown means an unknown error. Errors which don't carry a code
+	// are treated as unknown errors.
+	CodeUnknown Code = 2
+
+	// CodeInvalidArgument means the caller specified an invalid argument.
+	CodeInvalidArgument Code = 3
+
+	// CodeDeadlineExceeded means the operation expired before completion.
+	CodeDeadlineExceeded Code = 4
+
+	// CodeNotFound means a requested entity was not found.
+	CodeNotFound Code = 5
+
+	// CodeAlreadyExists means an attempt to create an entity failed because
+	// one already exists.
+	CodeAlreadyExists Code = 6
+
+	// CodePermissionDenied means the caller does not have permission to
+	// execute the specified operation.
+	CodePermissionDenied Code = 7
+
+	// CodeResourceExhausted means some resource has been exhausted, perhaps
+	// a per-caller quota or the capacity of the server.
+	CodeResourceExhausted Code = 8
+
+	// CodeFailedPrecondition means the operation was rejected because the
+	// system is not in a state required for its execution.
+	CodeFailedPrecondition Code = 9
+
+	// CodeAborted means the operation was aborted, typically due to a
+	// concurrency issue.
+	CodeAborted Code = 10
+
+	// CodeOutOfRange means the operation was attempted past the valid range.
+	CodeOutOfRange Code = 11
+
+	// CodeUnimplemented means the operation is not implemented or is not
+	// supported by the service.
+	CodeUnimplemented Code = 12
+
+	// CodeInternal means an internal invariant of the system was broken.
+	CodeInternal Code = 13
+
+	// CodeUnavailable means the service is currently unavailable. This is
+	// most likely a transient condition which may be corrected by retrying.
+	CodeUnavailable Code = 14
+
+	// CodeDataLoss means unrecoverable data loss or corruption.
+	CodeDataLoss Code = 15
+
+	// CodeUnauthenticated means the request does not have valid
+	// authentication credentials for the operation.
+	CodeUnauthenticated Code = 16
+)
+
+var (
+	_codeToString = map[Code]string{
+		CodeOK:                 "ok",
+		CodeCancelled:          "cancelled",
+		CodeUnknown:            "unknown",
+		CodeInvalidArgument:    "invalid-argument",
+		CodeDeadlineExceeded:   "deadline-exceeded",
+		CodeNotFound:           "not-found",
+		CodeAlreadyExists:      "already-exists",
+		CodePermissionDenied:   "permission-denied",
+		CodeResourceExhausted:  "resource-exhausted",
+		CodeFailedPrecondition: "failed-precondition",
+		CodeAborted:            "aborted",
+		CodeOutOfRange:         "out-of-range",
+		CodeUnimplemented:      "unimplemented",
+		CodeInternal:           "internal",
+		CodeUnavailable:        "unavailable",
+		CodeDataLoss:           "data-loss",
+		CodeUnauthenticated:    "unauthenticated",
+	}
+
+	_stringToCode = make(map[string]Code, len(_codeToString))
+)
+
+func init() {
+	for code, s := range _codeToString {
+		_stringToCode[s] = code
+	}
+}
+
+// String returns the name of the code, for example "not-found".
+func (c Code) String() string {
+	if s, ok := _codeToString[c]; ok {
+		return s
+	}
+	return fmt.Sprintf("Code(%d)", int(c))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (c Code) MarshalText() ([]byte, error) {
+	s, ok := _codeToString[c]
+	if !ok {
+		return nil, fmt.Errorf("unknown code: %d", int(c))
+	}
+	return []byte(s), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (c *Code) UnmarshalText(text []byte) error {
+	code, ok := _stringToCode[string(text)]
+	if !ok {
+		return fmt.Errorf("unknown code: %q", text)
+	}
+	*c = code
+	return nil
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package yarpcerrors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeText(t *testing.T) {
+	for code, name := range _codeToString {
+		assert.Equal(t, name, code.String())
+
+		text, err := code.MarshalText()
+		require.NoError(t, err, "failed to marshal %v", code)
+		assert.Equal(t, name, string(text))
+
+		var got Code
+		require.NoError(t, got.UnmarshalText(text), "failed to unmarshal %q", text)
+		assert.Equal(t, code, got)
+	}
+}
+
+func TestCodeTextUnknown(t *testing.T) {
+	assert.Equal(t, "Code(42)", Code(42).String())
+
+	_, err := Code(42).MarshalText()
+	assert.Error(t, err)
+
+	var code Code
+	err = code.UnmarshalText([]byte("great-sadness"))
+	if assert.Error(t, err) {
+		assert.Equal(t, `unknown code: "great-sadness"`, err.Error())
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package yarpcerrors provides structured errors which carry a code, a
+// message, and optional details across all YARPC transports.
+//
+// Handlers may return errors built with Newf to control the code received by
+// the caller.
+//
+// 	return nil, yarpcerrors.Newf(yarpcerrors.CodeNotFound, "user %q does not exist", id)
+//
+// Callers may inspect the code of any error returned by a YARPC client,
+// regardless of the transport used to make the request.
+//
+// 	res, err := client.GetUser(ctx, req)
+// 	if yarpcerrors.FromError(err).Code() == yarpcerrors.CodeNotFound {
+// 		// ...
+// 	}
+//
+// Each transport maps these codes to its own representation of errors in a
+// way that allows the code, message, and details to be recovered on the
+// other side.
+package yarpcerrors
@@ -0,0 +1,144 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package yarpcerrors
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/yarpc/internal/errors"
+)
+
+// Status is an error with a code, a message, and optional details.
+//
+// Statuses returned by handlers are sent to the caller as-is and the caller
+// receives a Status with the same code, message, and details.
+type Status struct {
+	code    Code
+	message string
+	details []byte
+}
+
+// Newf builds a new Status with the given code and a message formatted
+// according to the given format specifier.
+//
+// Returns nil if the code is CodeOK.
+func Newf(code Code, format string, args ...interface{}) *Status {
+	if code == CodeOK {
+		return nil
+	}
+	message := format
+	if len(args) > 0 {
+		message = fmt.Sprintf(format, args...)
+	}
+	return &Status{code: code, message: message}
+}
+
+// WithDetails returns a copy of the Status with the given details attached.
+//
+// Details are opaque to YARPC. They are carried alongside the code and
+// message and may be used to send additional machine-readable information
+// about the failure.
+func (s *Status) WithDetails(details []byte) *Status {
+	if s == nil {
+		return nil
+	}
+	return &Status{code: s.code, message: s.message, details: details}
+}
+
+// Code returns the code of the Status. Returns CodeOK if the Status is nil.
+func (s *Status) Code() Code {
+	if s == nil {
+		return CodeOK
+	}
+	return s.code
+}
+
+// Message returns the message of the Status.
+func (s *Status) Message() string {
+	if s == nil {
+		return ""
+	}
+	return s.message
+}
+
+// Details returns the details attached to the Status, if any.
+func (s *Status) Details() []byte {
+	if s == nil {
+		return nil
+	}
+	return s.details
+}
+
+// Error returns the message of the Status or the name of its code if the
+// message is empty.
+func (s *Status) Error() string {
+	if msg := s.Message(); msg != "" {
+		return msg
+	}
+	return s.Code().String()
+}
+
+// IsStatus returns true if the given error is a Status.
+func IsStatus(err error) bool {
+	_, ok := err.(*Status)
+	return ok
+}
+
+// FromError returns the Status for the given error.
+//
+// If the error is a Status, it is returned unchanged. Errors produced by
+// YARPC are converted to a Status with the closest matching code and all
+// other errors are converted to a Status with CodeUnknown. The message of
+// the Status is the message of the error.
+//
+// Returns nil if the error is nil.
+func FromError(err error) *Status {
+	if err == nil {
+		return nil
+	}
+	if s, ok := err.(*Status); ok {
+		return s
+	}
+	return &Status{code: codeOf(err), message: err.Error()}
+}
+
+func codeOf(err error) Code {
+	switch err {
+	case context.Canceled:
+		return CodeCancelled
+	case context.DeadlineExceeded:
+		return CodeDeadlineExceeded
+	}
+
+	switch err.(type) {
+	case errors.BadRequestError:
+		return CodeInvalidArgument
+	case errors.TimeoutError:
+		return CodeDeadlineExceeded
+	case errors.UnrecognizedProcedureError:
+		return CodeUnimplemented
+	case errors.UnexpectedError:
+		return CodeInternal
+	default:
+		return CodeUnknown
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package yarpcerrors
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.uber.org/yarpc/internal/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewf(t *testing.T) {
+	s := Newf(CodeNotFound, "user %q does not exist", "foo")
+	assert.Equal(t, CodeNotFound, s.Code())
+	assert.Equal(t, `user "foo" does not exist`, s.Message())
+	assert.Equal(t, `user "foo" does not exist`, s.Error())
+	assert.Nil(t, s.Details())
+	assert.True(t, IsStatus(s))
+
+	assert.Equal(t, "unavailable", Newf(CodeUnavailable, "").Error(),
+		"empty message should fall back to the code")
+}
+
+func TestNewfOK(t *testing.T) {
+	s := Newf(CodeOK, "everything is fine")
+	assert.Nil(t, s)
+	assert.Equal(t, CodeOK, s.Code())
+	assert.Equal(t, "", s.Message())
+	assert.Nil(t, s.WithDetails([]byte("foo")))
+}
+
+func TestWithDetails(t *testing.T) {
+	s := Newf(CodeInvalidArgument, "bad input")
+	withDetails := s.WithDetails([]byte("field: name"))
+
+	assert.Nil(t, s.Details(), "original Status must not be modified")
+	assert.Equal(t, []byte("field: name"), withDetails.Details())
+	assert.Equal(t, CodeInvalidArgument, withDetails.Code())
+	assert.Equal(t, "bad input", withDetails.Message())
+}
+
+func TestFromError(t *testing.T) {
+	status := Newf(CodeAborted, "try again")
+
+	tests := []struct {
+		desc        string
+		give        error
+		wantCode    Code
+		wantMessage string
+	}{
+		{
+			desc:        "status",
+			give:        status,
+			wantCode:    CodeAborted,
+			wantMessage: "try again",
+		},
+		{
+			desc:        "unknown error",
+			give:        fmt.Errorf("great sadness"),
+			wantCode:    CodeUnknown,
+			wantMessage: "great sadness",
+		},
+		{
+			desc:        "context cancelled",
+			give:        context.Canceled,
+			wantCode:    CodeCancelled,
+			wantMessage: "context canceled",
+		},
+		{
+			desc:        "context deadline exceeded",
+			give:        context.DeadlineExceeded,
+			wantCode:    CodeDeadlineExceeded,
+			wantMessage: "context deadline exceeded",
+		},
+		{
+			desc:        "bad request",
+			give:        errors.RemoteBadRequestError("missing service name"),
+			wantCode:    CodeInvalidArgument,
+			wantMessage: "missing service name",
+		},
+		{
+			desc:        "timeout",
+			give:        errors.ClientTimeoutError("foo", "bar", time.Second),
+			wantCode:    CodeDeadlineExceeded,
+			wantMessage: `client timeout for procedure "bar" of service "foo" after 1s`,
+		},
+		{
+			desc:        "unexpected",
+			give:        errors.HandlerUnexpectedError(fmt.Errorf("great sadness")),
+			wantCode:    CodeInternal,
+			wantMessage: "UnexpectedError: great sadness",
+		},
+		{
+			desc:        "unrecognized procedure",
+			give:        errors.RouterUnrecognizedProcedureError("foo", "bar"),
+			wantCode:    CodeUnimplemented,
+			wantMessage: `unrecognized procedure "bar" for service "foo"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			s := FromError(tt.give)
+			assert.Equal(t, tt.wantCode, s.Code())
+			assert.Equal(t, tt.wantMessage, s.Message())
+		})
+	}
+
+	assert.True(t, status == FromError(status), "Status must be returned unchanged")
+	assert.Nil(t, FromError(nil))
+	assert.Equal(t, CodeOK, FromError(nil).Code())
+}